@@ -78,7 +78,7 @@ func main() {
 	}
 
 	// 初始化应用
-	app, cleanup, err := server.InitializeApp(bc.Server, bc.Data, logger)
+	app, cleanup, err := server.InitializeApp(bc.Server, bc.Data, bc.Upload, bc.Profile, logger)
 	if err != nil {
 		panic(err)
 	}
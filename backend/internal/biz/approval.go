@@ -0,0 +1,1150 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 审批人类型
+const (
+	ApproverOrgLeader = "org_leader" // 文档所属组织向上若干层级的负责人
+	ApproverRole      = "role"       // 当前持有角色的全部启用用户
+	ApproverUser      = "user"       // 指定用户
+)
+
+// 步骤内多个审批人的审批方式
+const (
+	ApprovalModeAny = "any" // 任一审批人同意即通过
+	ApprovalModeAll = "all" // 全部审批人同意才通过
+)
+
+// 审批请求状态
+const (
+	ApprovalStatusPending   = "pending"
+	ApprovalStatusApproved  = "approved"
+	ApprovalStatusRejected  = "rejected"
+	ApprovalStatusCancelled = "cancelled"
+)
+
+// 审批任务状态
+const (
+	ApprovalTaskWaiting   = "waiting"   // 等待前序步骤完成
+	ApprovalTaskPending   = "pending"   // 待审批
+	ApprovalTaskApproved  = "approved"  // 已同意
+	ApprovalTaskRejected  = "rejected"  // 已驳回
+	ApprovalTaskSkipped   = "skipped"   // 步骤或请求已结束，无需审批
+	ApprovalTaskDelegated = "delegated" // 已转交他人
+	ApprovalTaskEscalated = "escalated" // 超时升级给上级负责人
+)
+
+// 审批过程中文档的工作流状态
+const (
+	WorkflowStatePendingApproval = "Pending Approval"
+	WorkflowStateRejected        = "Rejected"
+)
+
+// 审批操作日志的操作类型，资源为文档类型
+const (
+	ApprovalActionRequest  = "approval_request"
+	ApprovalActionDecision = "approval_decision"
+	ApprovalActionCancel   = "approval_cancel"
+	ApprovalActionDelegate = "approval_delegate"
+	ApprovalActionEscalate = "approval_escalate"
+)
+
+// 审批链的限制
+const (
+	maxApprovalSteps         = 20
+	maxApprovalStepLevels    = 20
+	maxApprovalTimeoutHours  = 24 * 30
+	maxApprovalCommentLength = 500
+
+	// maxApprovalUpdateRetries 并发审批导致请求版本变化时的重试次数
+	maxApprovalUpdateRetries = 3
+)
+
+// 错误定义
+var (
+	ErrApprovalChainNotFound      = errors.New("approval chain not found")
+	ErrInvalidApprovalChain       = errors.New("invalid approval chain")
+	ErrApprovalRequestNotFound    = errors.New("approval request not found")
+	ErrApprovalPending            = errors.New("document already has a pending approval request")
+	ErrApprovalRequestChanged     = errors.New("approval request was changed by another request")
+	ErrApproverNotFound           = errors.New("no approver found")
+	ErrApprovalTaskNotFound       = errors.New("approval task not found")
+	ErrApprovalTaskNotPending     = errors.New("approval task is not pending")
+	ErrApprovalNotAllowed         = errors.New("approval operation not allowed")
+	ErrDocumentUnderApproval      = errors.New("document status is controlled by approval chain")
+	ErrApprovalDelegationNotFound = errors.New("approval delegation not found")
+	ErrInvalidApprovalDelegation  = errors.New("invalid approval delegation")
+)
+
+// ApprovalChain 文档类型的审批链：步骤按顺序执行，全部通过后文档自动提交
+type ApprovalChain struct {
+	ID          int64           `json:"id"`
+	DocType     string          `json:"doc_type"`              // 文档类型
+	Name        string          `json:"name"`                  // 审批链名称
+	Description string          `json:"description,omitempty"` // 描述
+	IsActive    bool            `json:"is_active"`             // 是否启用
+	OrgField    string          `json:"org_field,omitempty"`   // 保存所属组织ID的文档字段，为空时取申请人的主组织
+	Steps       []*ApprovalStep `json:"steps"`                 // 审批步骤
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	CreatedBy   *int64          `json:"created_by,omitempty"`
+	UpdatedBy   *int64          `json:"updated_by,omitempty"`
+}
+
+// ApprovalStep 审批步骤。org_leader从文档所属组织向上Levels层开始查找负责人，OrgType不为空时
+// 取该层及以上最近的此类型组织；组织没有负责人或负责人是申请人时继续向上查找。
+// Condition不为空时仅在对文档成立时需要此步骤审批
+type ApprovalStep struct {
+	Name         string  `json:"name"`
+	ApproverType string  `json:"approver_type"`
+	Levels       int     `json:"levels,omitempty"`
+	OrgType      string  `json:"org_type,omitempty"`
+	Role         string  `json:"role,omitempty"`
+	UserIDs      []int64 `json:"user_ids,omitempty"`
+	Mode         string  `json:"mode"`
+	Condition    string  `json:"condition,omitempty"`
+	TimeoutHours int     `json:"timeout_hours,omitempty"` // 超时升级给上级负责人，0表示不超时
+}
+
+// ApprovalRequest 文档的一次审批过程，Steps为发起时审批链步骤的快照
+type ApprovalRequest struct {
+	ID          int64           `json:"id"`
+	CompanyID   int32           `json:"company_id"` // 文档所属公司
+	DocType     string          `json:"doc_type"`
+	DocName     string          `json:"doc_name"`
+	ChainID     *int64          `json:"chain_id,omitempty"`
+	ChainName   string          `json:"chain_name"`
+	Steps       []*ApprovalStep `json:"steps"`
+	OrgID       *int64          `json:"org_id,omitempty"` // 文档所属组织
+	Status      string          `json:"status"`
+	CurrentStep int             `json:"current_step"`
+	Version     int             `json:"-"` // 乐观锁版本
+	Comment     string          `json:"comment,omitempty"`
+	RequestedBy int64           `json:"requested_by"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Tasks       []*ApprovalTask `json:"tasks"`
+}
+
+// ApprovalTask 审批人在某个步骤的审批任务
+type ApprovalTask struct {
+	ID            int64      `json:"id"`
+	RequestID     int64      `json:"request_id"`
+	CompanyID     int32      `json:"company_id"` // 审批请求所属公司
+	DocType       string     `json:"doc_type,omitempty"`
+	DocName       string     `json:"doc_name,omitempty"`
+	Step          int        `json:"step"`
+	StepName      string     `json:"step_name"`
+	ApproverID    int64      `json:"approver_id"`
+	OrgID         *int64     `json:"org_id,omitempty"` // 审批人所代表的组织，超时升级从此组织向上查找
+	Status        string     `json:"status"`
+	DelegatedFrom *int64     `json:"delegated_from,omitempty"`
+	EscalatedFrom *int64     `json:"escalated_from,omitempty"`
+	DueAt         *time.Time `json:"due_at,omitempty"`
+	Comment       string     `json:"comment,omitempty"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ApprovalDelegation 审批委托：委托期内分配给委托人的审批任务改由受托人处理
+type ApprovalDelegation struct {
+	ID          int64     `json:"id"`
+	DelegatorID int64     `json:"delegator_id"`
+	DelegateID  int64     `json:"delegate_id"`
+	DocType     string    `json:"doc_type,omitempty"` // 为空表示全部文档类型
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	Reason      string    `json:"reason,omitempty"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Validate 验证审批链定义本身，文档类型、字段、角色和用户的检查在用例中进行
+func (c *ApprovalChain) Validate() error {
+	c.DocType = strings.TrimSpace(c.DocType)
+	c.Name = strings.TrimSpace(c.Name)
+	c.OrgField = strings.TrimSpace(c.OrgField)
+	if c.DocType == "" {
+		return fmt.Errorf("%w: doc type is required", ErrInvalidApprovalChain)
+	}
+	if c.Name == "" || len(c.Name) > 100 {
+		return fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidApprovalChain)
+	}
+	if len(c.Steps) == 0 || len(c.Steps) > maxApprovalSteps {
+		return fmt.Errorf("%w: 1-%d steps are required", ErrInvalidApprovalChain, maxApprovalSteps)
+	}
+
+	for i, step := range c.Steps {
+		if step == nil {
+			return fmt.Errorf("%w: step %d is empty", ErrInvalidApprovalChain, i+1)
+		}
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("%w: step %d: %v", ErrInvalidApprovalChain, i+1, err)
+		}
+	}
+	return nil
+}
+
+// validate 验证并规范化步骤，清除与审批人类型无关的设置
+func (s *ApprovalStep) validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.OrgType = strings.TrimSpace(s.OrgType)
+	s.Role = strings.TrimSpace(s.Role)
+	s.Condition = strings.TrimSpace(s.Condition)
+	if s.Name == "" || len(s.Name) > 100 {
+		return fmt.Errorf("name must be 1-100 characters")
+	}
+
+	switch s.ApproverType {
+	case ApproverOrgLeader:
+		if s.Levels < 0 || s.Levels > maxApprovalStepLevels {
+			return fmt.Errorf("levels must be 0-%d", maxApprovalStepLevels)
+		}
+		s.Role, s.UserIDs = "", nil
+	case ApproverRole:
+		if s.Role == "" {
+			return fmt.Errorf("role is required")
+		}
+		s.Levels, s.OrgType, s.UserIDs = 0, "", nil
+	case ApproverUser:
+		seen := make(map[int64]bool, len(s.UserIDs))
+		users := make([]int64, 0, len(s.UserIDs))
+		for _, id := range s.UserIDs {
+			if id <= 0 {
+				return fmt.Errorf("invalid user id %d", id)
+			}
+			if !seen[id] {
+				seen[id] = true
+				users = append(users, id)
+			}
+		}
+		if len(users) == 0 {
+			return fmt.Errorf("at least one user is required")
+		}
+		s.UserIDs = users
+		s.Levels, s.OrgType, s.Role = 0, "", ""
+	default:
+		return fmt.Errorf("approver type must be %s, %s or %s", ApproverOrgLeader, ApproverRole, ApproverUser)
+	}
+
+	if s.Mode == "" {
+		s.Mode = ApprovalModeAny
+	}
+	if s.Mode != ApprovalModeAny && s.Mode != ApprovalModeAll {
+		return fmt.Errorf("mode must be %s or %s", ApprovalModeAny, ApprovalModeAll)
+	}
+	if s.TimeoutHours < 0 || s.TimeoutHours > maxApprovalTimeoutHours {
+		return fmt.Errorf("timeout hours must be 0-%d", maxApprovalTimeoutHours)
+	}
+	if s.Condition != "" {
+		if _, err := ParsePermissionCondition(s.Condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dueAt 步骤开始后的超时时间，不超时返回nil
+func (s *ApprovalStep) dueAt(start time.Time) *time.Time {
+	if s.TimeoutHours <= 0 {
+		return nil
+	}
+	due := start.Add(time.Duration(s.TimeoutHours) * time.Hour)
+	return &due
+}
+
+// task 按ID查找请求的任务
+func (r *ApprovalRequest) task(id int64) *ApprovalTask {
+	for _, t := range r.Tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// stepTasks 指定步骤中处于给定状态的任务
+func (r *ApprovalRequest) stepTasks(step int, statuses ...string) []*ApprovalTask {
+	var tasks []*ApprovalTask
+	for _, t := range r.Tasks {
+		if t.Step != step {
+			continue
+		}
+		for _, status := range statuses {
+			if t.Status == status {
+				tasks = append(tasks, t)
+				break
+			}
+		}
+	}
+	return tasks
+}
+
+// involves 用户是否为申请人或任一任务的审批人、委托人
+func (r *ApprovalRequest) involves(userID int64) bool {
+	if r.RequestedBy == userID {
+		return true
+	}
+	for _, t := range r.Tasks {
+		if t.ApproverID == userID || (t.DelegatedFrom != nil && *t.DelegatedFrom == userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// complete 结束请求，未处理的任务标记为无需审批，返回状态变化的任务
+func (r *ApprovalRequest) complete(status string, now time.Time) []*ApprovalTask {
+	r.Status = status
+	r.CompletedAt = &now
+	var changed []*ApprovalTask
+	for _, t := range r.Tasks {
+		if t.Status == ApprovalTaskWaiting || t.Status == ApprovalTaskPending {
+			t.Status = ApprovalTaskSkipped
+			changed = append(changed, t)
+		}
+	}
+	return changed
+}
+
+// ApprovalRepo 审批仓储接口；审批请求和任务按公司隔离，审批链和审批委托在公司间共享
+type ApprovalRepo interface {
+	// SaveApprovalChain 按文档类型创建或替换审批链
+	SaveApprovalChain(ctx context.Context, chain *ApprovalChain) (*ApprovalChain, error)
+	GetApprovalChain(ctx context.Context, docType string) (*ApprovalChain, error)
+	ListApprovalChains(ctx context.Context) ([]*ApprovalChain, error)
+	DeleteApprovalChain(ctx context.Context, docType string) error
+
+	// CreateApprovalRequest 在当前公司创建审批请求及其全部任务，文档已有进行中的请求时返回ErrApprovalPending
+	CreateApprovalRequest(ctx context.Context, req *ApprovalRequest) (*ApprovalRequest, error)
+	// GetApprovalRequest 获取当前公司的审批请求及其任务
+	GetApprovalRequest(ctx context.Context, id int64) (*ApprovalRequest, error)
+	// GetPendingApprovalRequest 获取文档进行中的审批请求，没有时返回ErrApprovalRequestNotFound
+	GetPendingApprovalRequest(ctx context.Context, docType, docName string) (*ApprovalRequest, error)
+	// ListApprovalRequests 获取文档的全部审批请求及其任务，最近的在前
+	ListApprovalRequests(ctx context.Context, docType, docName string) ([]*ApprovalRequest, error)
+	// UpdateApprovalRequest 保存请求状态和变化的任务（ID为0的任务新建），
+	// 请求版本已不是req.Version时返回ErrApprovalRequestChanged
+	UpdateApprovalRequest(ctx context.Context, req *ApprovalRequest, tasks []*ApprovalTask) error
+
+	GetApprovalTask(ctx context.Context, id int64) (*ApprovalTask, error)
+	// ListApprovalTasks 获取审批人在当前公司的任务，status为空时返回全部状态
+	ListApprovalTasks(ctx context.Context, approverID int64, status string) ([]*ApprovalTask, error)
+	// ListOverdueApprovalTasks 获取所有公司截至指定时间已超时的待审批任务，由定时任务调用
+	ListOverdueApprovalTasks(ctx context.Context, now time.Time, limit int) ([]*ApprovalTask, error)
+
+	CreateApprovalDelegation(ctx context.Context, delegation *ApprovalDelegation) (*ApprovalDelegation, error)
+	GetApprovalDelegation(ctx context.Context, id int64) (*ApprovalDelegation, error)
+	ListApprovalDelegations(ctx context.Context, delegatorID int64) ([]*ApprovalDelegation, error)
+	RevokeApprovalDelegation(ctx context.Context, id int64) error
+	// GetActiveApprovalDelegation 获取委托人在指定时间对文档类型生效的委托，限定文档类型的优先；没有时返回nil
+	GetActiveApprovalDelegation(ctx context.Context, delegatorID int64, docType string, at time.Time) (*ApprovalDelegation, error)
+
+	// ListRoleUserIDs 获取当前有效持有角色的启用用户
+	ListRoleUserIDs(ctx context.Context, roleCode string) ([]int64, error)
+	// ListActiveUserIDs 从给定用户中筛选出启用的用户
+	ListActiveUserIDs(ctx context.Context, userIDs []int64) ([]int64, error)
+}
+
+// ApprovalUsecase 审批用例：按审批链和组织层级确定审批人，推进审批步骤，审批通过后提交文档
+type ApprovalUsecase struct {
+	repo       ApprovalRepo
+	documentUc *DocumentUsecase
+	orgRepo    OrganizationRepo
+	permRepo   PermissionRepo
+	roleRepo   RoleRepo
+	auditRepo  AuditRepo
+	tx         Transaction
+	log        *log.Helper
+}
+
+// NewApprovalUsecase 创建审批用例
+func NewApprovalUsecase(repo ApprovalRepo, documentUc *DocumentUsecase, orgRepo OrganizationRepo, permRepo PermissionRepo, roleRepo RoleRepo, auditRepo AuditRepo, tx Transaction, logger log.Logger) *ApprovalUsecase {
+	return &ApprovalUsecase{
+		repo:       repo,
+		documentUc: documentUc,
+		orgRepo:    orgRepo,
+		permRepo:   permRepo,
+		roleRepo:   roleRepo,
+		auditRepo:  auditRepo,
+		tx:         tx,
+		log:        log.NewHelper(logger),
+	}
+}
+
+// SaveApprovalChain 创建或替换文档类型的审批链：文档类型需支持提交且没有启用的工作流，
+// 组织字段需为整数或链接字段，角色需为已启用角色，指定用户需为启用用户，条件按文档字段类型做类型检查
+func (uc *ApprovalUsecase) SaveApprovalChain(ctx context.Context, chain *ApprovalChain) (*ApprovalChain, error) {
+	if err := chain.Validate(); err != nil {
+		return nil, err
+	}
+
+	docType, err := uc.permRepo.GetDocType(ctx, chain.DocType)
+	if err != nil {
+		return nil, err
+	}
+	if docType.IsChildTable || !docType.IsSubmittable {
+		return nil, fmt.Errorf("%w: %s must be a submittable doctype", ErrInvalidApprovalChain, docType.Name)
+	}
+	if chain.IsActive {
+		workflow, err := uc.documentUc.activeWorkflow(ctx, chain.DocType)
+		if err != nil {
+			return nil, err
+		}
+		if workflow != nil {
+			return nil, fmt.Errorf("%w: %s is controlled by workflow %s", ErrInvalidApprovalChain, docType.Name, workflow.Name)
+		}
+	}
+
+	if chain.OrgField != "" {
+		fields, err := uc.documentUc.fieldRepo.ListDocFields(ctx, chain.DocType)
+		if err != nil {
+			return nil, err
+		}
+		field := (&DocumentMeta{Fields: fields}).Field(chain.OrgField)
+		if field == nil || (field.FieldType != "Int" && field.FieldType != "Link") {
+			return nil, fmt.Errorf("%w: org field %s must be an Int or Link field of %s", ErrInvalidApprovalChain, chain.OrgField, docType.Name)
+		}
+	}
+
+	var enabled map[string]bool
+	var fieldTypes map[string]ConditionType
+	for i, step := range chain.Steps {
+		switch step.ApproverType {
+		case ApproverRole:
+			if enabled == nil {
+				roles, err := uc.roleRepo.GetEnabledRoles(ctx)
+				if err != nil {
+					return nil, err
+				}
+				enabled = make(map[string]bool, len(roles))
+				for _, role := range roles {
+					enabled[role.Code] = true
+				}
+			}
+			if !enabled[step.Role] {
+				return nil, fmt.Errorf("%w: step %d: role %s does not exist or is disabled", ErrInvalidApprovalChain, i+1, step.Role)
+			}
+		case ApproverUser:
+			active, err := uc.repo.ListActiveUserIDs(ctx, step.UserIDs)
+			if err != nil {
+				return nil, err
+			}
+			if len(active) != len(step.UserIDs) {
+				return nil, fmt.Errorf("%w: step %d: users must exist and be active", ErrInvalidApprovalChain, i+1)
+			}
+		}
+
+		if step.Condition == "" {
+			continue
+		}
+		if fieldTypes == nil {
+			if fieldTypes, err = uc.permRepo.GetConditionFieldTypes(ctx, chain.DocType); err != nil {
+				return nil, err
+			}
+		}
+		condition, err := ParsePermissionCondition(step.Condition)
+		if err == nil {
+			err = condition.Check(fieldTypes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: step %d: %v", ErrInvalidApprovalChain, i+1, err)
+		}
+	}
+
+	return uc.repo.SaveApprovalChain(ctx, chain)
+}
+
+// GetApprovalChain 获取文档类型的审批链
+func (uc *ApprovalUsecase) GetApprovalChain(ctx context.Context, docType string) (*ApprovalChain, error) {
+	return uc.repo.GetApprovalChain(ctx, docType)
+}
+
+// ListApprovalChains 获取全部审批链
+func (uc *ApprovalUsecase) ListApprovalChains(ctx context.Context) ([]*ApprovalChain, error) {
+	return uc.repo.ListApprovalChains(ctx)
+}
+
+// DeleteApprovalChain 删除文档类型的审批链，进行中的请求按发起时的步骤继续
+func (uc *ApprovalUsecase) DeleteApprovalChain(ctx context.Context, docType string) error {
+	return uc.repo.DeleteApprovalChain(ctx, docType)
+}
+
+// RequestApproval 为草稿文档发起审批：检查必填字段，确定各步骤的审批人并锁定文档；
+// 没有需要审批的步骤时直接提交文档
+func (uc *ApprovalUsecase) RequestApproval(ctx context.Context, docType, name, comment string, access *DocumentAccess) (*ApprovalRequest, error) {
+	comment = strings.TrimSpace(comment)
+	if len([]rune(comment)) > maxApprovalCommentLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidDocument, maxApprovalCommentLength)
+	}
+
+	chain, err := uc.documentUc.activeApprovalChain(ctx, docType)
+	if err != nil {
+		return nil, err
+	}
+	if chain == nil {
+		return nil, fmt.Errorf("%w: %s has no active approval chain", ErrApprovalChainNotFound, docType)
+	}
+
+	meta, doc, err := uc.documentUc.loadSubmittable(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+	if status := docStatusOf(doc); status != DocStatusDraft {
+		return nil, fmt.Errorf("%w: only draft documents can be sent for approval, current docstatus is %d", ErrInvalidDocumentTransition, status)
+	}
+	state, _ := doc["workflow_state"].(string)
+	if state == WorkflowStatePendingApproval {
+		return nil, ErrApprovalPending
+	}
+	if err := checkMandatory(meta.Fields, doc); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.permRepo.GetUserConditionAttributes(ctx, access.UserID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	orgID := documentOrgID(chain, doc, user)
+	tasks, err := uc.resolveTasks(ctx, chain, doc, user, orgID, access.UserID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	chainID := chain.ID
+	req := &ApprovalRequest{
+		DocType:     docType,
+		DocName:     name,
+		ChainID:     &chainID,
+		ChainName:   chain.Name,
+		Steps:       chain.Steps,
+		OrgID:       orgID,
+		Status:      ApprovalStatusPending,
+		Comment:     comment,
+		RequestedBy: access.UserID,
+		Tasks:       tasks,
+	}
+	if len(tasks) > 0 {
+		req.CurrentStep = tasks[0].Step
+	} else {
+		req.Status = ApprovalStatusApproved
+		req.CompletedAt = &now
+	}
+
+	// 请求和文档状态在同一事务中保存，文档已被他人修改时请求一并回滚
+	var created *ApprovalRequest
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if created, err = uc.repo.CreateApprovalRequest(ctx, req); err != nil {
+			return err
+		}
+		return uc.finishDocument(ctx, meta, created, state, access.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.writeLog(ctx, created, ApprovalActionRequest, access.UserID,
+		fmt.Sprintf("文档 %s 发起审批：%s", name, chain.Name), map[string]interface{}{"comment": comment})
+	uc.log.Infof("Approval requested: %s %s by user %d, %d tasks", docType, name, access.UserID, len(tasks))
+	return created, nil
+}
+
+// CancelApproval 撤回文档进行中的审批，文档恢复为可编辑的草稿；override为true时可撤回他人发起的审批
+func (uc *ApprovalUsecase) CancelApproval(ctx context.Context, docType, name, comment string, access *DocumentAccess, override bool) (*ApprovalRequest, error) {
+	comment = strings.TrimSpace(comment)
+	if len([]rune(comment)) > maxApprovalCommentLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidDocument, maxApprovalCommentLength)
+	}
+	meta, _, err := uc.documentUc.loadDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+
+	var req *ApprovalRequest
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		req, err = uc.updateRequest(ctx, func() (*ApprovalRequest, []*ApprovalTask, error) {
+			req, err := uc.repo.GetPendingApprovalRequest(ctx, docType, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			if req.RequestedBy != access.UserID && !override {
+				return nil, nil, fmt.Errorf("%w: only the requester can cancel the approval", ErrApprovalNotAllowed)
+			}
+			return req, req.complete(ApprovalStatusCancelled, time.Now()), nil
+		})
+		if err != nil {
+			return err
+		}
+		return uc.finishDocument(ctx, meta, req, WorkflowStatePendingApproval, access.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.writeLog(ctx, req, ApprovalActionCancel, access.UserID,
+		fmt.Sprintf("文档 %s 撤回审批", name), map[string]interface{}{"comment": comment})
+	return req, nil
+}
+
+// DecideTask 审批人同意或驳回待审批任务：驳回时请求结束；同意后步骤完成时进入下一步骤，
+// 全部步骤完成时提交文档
+func (uc *ApprovalUsecase) DecideTask(ctx context.Context, taskID int64, approve bool, comment string, userID int64) (*ApprovalRequest, error) {
+	comment = strings.TrimSpace(comment)
+	if len([]rune(comment)) > maxApprovalCommentLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidDocument, maxApprovalCommentLength)
+	}
+
+	// 请求结束时文档状态与请求在同一事务中更新，避免请求已结束而文档仍锁定为待审批
+	var decided *ApprovalTask
+	var req *ApprovalRequest
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		req, err = uc.updateRequest(ctx, func() (*ApprovalRequest, []*ApprovalTask, error) {
+			req, task, err := uc.loadPendingTask(ctx, taskID, userID)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			now := time.Now()
+			task.Comment = comment
+			task.DecidedAt = &now
+			decided = task
+			if !approve {
+				task.Status = ApprovalTaskRejected
+				return req, append([]*ApprovalTask{task}, req.complete(ApprovalStatusRejected, now)...), nil
+			}
+
+			task.Status = ApprovalTaskApproved
+			changed := []*ApprovalTask{task}
+			step := req.Steps[task.Step]
+			remaining := req.stepTasks(task.Step, ApprovalTaskPending)
+			if step.Mode == ApprovalModeAll && len(remaining) > 0 {
+				return req, changed, nil
+			}
+			for _, t := range remaining {
+				t.Status = ApprovalTaskSkipped
+				changed = append(changed, t)
+			}
+			return req, append(changed, uc.advance(req, now)...), nil
+		})
+		if err != nil || req.Status == ApprovalStatusPending {
+			return err
+		}
+		meta, err := uc.documentUc.loadMeta(ctx, req.DocType, true)
+		if err != nil {
+			return err
+		}
+		return uc.finishDocument(ctx, meta, req, WorkflowStatePendingApproval, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	decision := "同意"
+	if !approve {
+		decision = "驳回"
+	}
+	uc.writeLog(ctx, req, ApprovalActionDecision, userID,
+		fmt.Sprintf("文档 %s 审批步骤 %s：%s", req.DocName, decided.StepName, decision),
+		map[string]interface{}{"task_id": taskID, "approved": approve, "comment": comment, "status": req.Status})
+	uc.log.Infof("Approval task %d decided by user %d: approve=%v, request %d is %s", taskID, userID, approve, req.ID, req.Status)
+	return req, nil
+}
+
+// DelegateTask 审批人将待审批任务转交给其他启用用户，受托人不能是申请人或本步骤的其他审批人
+func (uc *ApprovalUsecase) DelegateTask(ctx context.Context, taskID, delegateID int64, comment string, userID int64) (*ApprovalRequest, error) {
+	comment = strings.TrimSpace(comment)
+	if len([]rune(comment)) > maxApprovalCommentLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidDocument, maxApprovalCommentLength)
+	}
+	active, err := uc.repo.ListActiveUserIDs(ctx, []int64{delegateID})
+	if err != nil {
+		return nil, err
+	}
+	if len(active) == 0 {
+		return nil, fmt.Errorf("%w: user %d does not exist or is disabled", ErrApprovalNotAllowed, delegateID)
+	}
+
+	req, err := uc.updateRequest(ctx, func() (*ApprovalRequest, []*ApprovalTask, error) {
+		req, task, err := uc.loadPendingTask(ctx, taskID, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if delegateID == req.RequestedBy || delegateID == userID {
+			return nil, nil, fmt.Errorf("%w: cannot delegate to the requester or yourself", ErrApprovalNotAllowed)
+		}
+		for _, t := range req.stepTasks(task.Step, ApprovalTaskWaiting, ApprovalTaskPending) {
+			if t.ApproverID == delegateID {
+				return nil, nil, fmt.Errorf("%w: user %d is already an approver of this step", ErrApprovalNotAllowed, delegateID)
+			}
+		}
+
+		now := time.Now()
+		task.Status = ApprovalTaskDelegated
+		task.Comment = comment
+		task.DecidedAt = &now
+		return req, []*ApprovalTask{task, {
+			RequestID:     req.ID,
+			Step:          task.Step,
+			StepName:      task.StepName,
+			ApproverID:    delegateID,
+			OrgID:         task.OrgID,
+			Status:        ApprovalTaskPending,
+			DelegatedFrom: &userID,
+			DueAt:         task.DueAt,
+		}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.writeLog(ctx, req, ApprovalActionDelegate, userID,
+		fmt.Sprintf("文档 %s 审批任务转交给用户 %d", req.DocName, delegateID),
+		map[string]interface{}{"task_id": taskID, "delegate_id": delegateID, "comment": comment})
+	return req, nil
+}
+
+// EscalateOverdueTasks 将超时的待审批任务升级给审批人所代表组织的上级负责人；
+// 找不到上级负责人时清除任务的超时时间，不再升级。任务跨公司查询，每个任务在所属公司内处理
+func (uc *ApprovalUsecase) EscalateOverdueTasks(ctx context.Context, now time.Time) (int, error) {
+	const batchSize = 500
+
+	overdue, err := uc.repo.ListOverdueApprovalTasks(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for _, overdueTask := range overdue {
+		ctx := WithCompany(ctx, overdueTask.CompanyID)
+		var target *ApprovalTask
+		req, err := uc.updateRequest(ctx, func() (*ApprovalRequest, []*ApprovalTask, error) {
+			req, err := uc.repo.GetApprovalRequest(ctx, overdueTask.RequestID)
+			if err != nil {
+				return nil, nil, err
+			}
+			task := req.task(overdueTask.ID)
+			if req.Status != ApprovalStatusPending || task == nil || task.Status != ApprovalTaskPending ||
+				task.DueAt == nil || task.DueAt.After(now) {
+				return nil, nil, nil
+			}
+
+			target = nil
+			leader, orgID, err := uc.escalationTarget(ctx, req, task, now)
+			if err != nil {
+				return nil, nil, err
+			}
+			if leader == 0 {
+				task.DueAt = nil
+				return req, []*ApprovalTask{task}, nil
+			}
+
+			task.Status = ApprovalTaskEscalated
+			task.DecidedAt = &now
+			target = &ApprovalTask{
+				RequestID:     req.ID,
+				Step:          task.Step,
+				StepName:      task.StepName,
+				ApproverID:    leader,
+				OrgID:         orgID,
+				Status:        ApprovalTaskPending,
+				EscalatedFrom: &task.ID,
+				DueAt:         req.Steps[task.Step].dueAt(now),
+			}
+			uc.applyDelegation(ctx, target, req.DocType, req.RequestedBy, now)
+			return req, []*ApprovalTask{task, target}, nil
+		})
+		if err != nil {
+			return escalated, err
+		}
+		if req == nil {
+			continue
+		}
+		if target == nil {
+			uc.log.Warnf("Approval task %d is overdue but has no higher leader to escalate to", overdueTask.ID)
+			continue
+		}
+
+		escalated++
+		entry := approvalLog(req, ApprovalActionEscalate,
+			fmt.Sprintf("文档 %s 审批任务超时，升级给用户 %d", req.DocName, target.ApproverID),
+			map[string]interface{}{"task_id": overdueTask.ID, "approver_id": target.ApproverID})
+		entry.Username = "system"
+		if err := uc.auditRepo.CreateOperationLog(ctx, entry); err != nil {
+			uc.log.Errorf("failed to write audit log for approval escalation of task %d: %v", overdueTask.ID, err)
+		}
+	}
+
+	return escalated, nil
+}
+
+// GetApprovalRequest 获取审批请求，非管理员只能查看自己发起或参与审批的请求
+func (uc *ApprovalUsecase) GetApprovalRequest(ctx context.Context, id, userID int64, override bool) (*ApprovalRequest, error) {
+	req, err := uc.repo.GetApprovalRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !override && !req.involves(userID) {
+		return nil, fmt.Errorf("%w: not involved in approval request %d", ErrApprovalNotAllowed, id)
+	}
+	return req, nil
+}
+
+// ListDocumentApprovals 获取数据范围内文档的审批历史
+func (uc *ApprovalUsecase) ListDocumentApprovals(ctx context.Context, docType, name string, access *DocumentAccess) ([]*ApprovalRequest, error) {
+	if _, _, err := uc.documentUc.loadDocument(ctx, docType, name, access); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListApprovalRequests(ctx, docType, name)
+}
+
+// ListTasks 获取审批人的任务
+func (uc *ApprovalUsecase) ListTasks(ctx context.Context, approverID int64, status string) ([]*ApprovalTask, error) {
+	return uc.repo.ListApprovalTasks(ctx, approverID, status)
+}
+
+// CreateDelegation 创建审批委托，受托人需为启用用户，委托期需尚未结束
+func (uc *ApprovalUsecase) CreateDelegation(ctx context.Context, delegation *ApprovalDelegation) (*ApprovalDelegation, error) {
+	delegation.DocType = strings.TrimSpace(delegation.DocType)
+	delegation.Reason = strings.TrimSpace(delegation.Reason)
+	if delegation.DelegateID == delegation.DelegatorID {
+		return nil, fmt.Errorf("%w: cannot delegate to yourself", ErrInvalidApprovalDelegation)
+	}
+	if !delegation.EndsAt.After(delegation.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidApprovalDelegation)
+	}
+	if !delegation.EndsAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: delegation period has already ended", ErrInvalidApprovalDelegation)
+	}
+	if len([]rune(delegation.Reason)) > maxApprovalCommentLength {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidApprovalDelegation, maxApprovalCommentLength)
+	}
+	if delegation.DocType != "" {
+		if _, err := uc.permRepo.GetDocType(ctx, delegation.DocType); err != nil {
+			return nil, err
+		}
+	}
+
+	active, err := uc.repo.ListActiveUserIDs(ctx, []int64{delegation.DelegateID})
+	if err != nil {
+		return nil, err
+	}
+	if len(active) == 0 {
+		return nil, fmt.Errorf("%w: user %d does not exist or is disabled", ErrInvalidApprovalDelegation, delegation.DelegateID)
+	}
+
+	delegation.IsActive = true
+	return uc.repo.CreateApprovalDelegation(ctx, delegation)
+}
+
+// ListDelegations 获取委托人的审批委托
+func (uc *ApprovalUsecase) ListDelegations(ctx context.Context, delegatorID int64) ([]*ApprovalDelegation, error) {
+	return uc.repo.ListApprovalDelegations(ctx, delegatorID)
+}
+
+// RevokeDelegation 撤销审批委托，已转交的任务不受影响；override为true时可撤销他人的委托
+func (uc *ApprovalUsecase) RevokeDelegation(ctx context.Context, id, userID int64, override bool) error {
+	delegation, err := uc.repo.GetApprovalDelegation(ctx, id)
+	if err != nil {
+		return err
+	}
+	if delegation.DelegatorID != userID && !override {
+		return fmt.Errorf("%w: only the delegator can revoke the delegation", ErrApprovalNotAllowed)
+	}
+	return uc.repo.RevokeApprovalDelegation(ctx, id)
+}
+
+// updateRequest 读取并修改审批请求后保存，请求被并发修改时重新读取重试；
+// load返回nil请求表示无需保存
+func (uc *ApprovalUsecase) updateRequest(ctx context.Context, load func() (*ApprovalRequest, []*ApprovalTask, error)) (*ApprovalRequest, error) {
+	for attempt := 0; ; attempt++ {
+		req, changed, err := load()
+		if err != nil || req == nil {
+			return nil, err
+		}
+		err = uc.repo.UpdateApprovalRequest(ctx, req, changed)
+		if err == nil {
+			return req, nil
+		}
+		if !errors.Is(err, ErrApprovalRequestChanged) || attempt+1 >= maxApprovalUpdateRetries {
+			return nil, err
+		}
+	}
+}
+
+// loadPendingTask 加载进行中请求里分配给用户的待审批任务
+func (uc *ApprovalUsecase) loadPendingTask(ctx context.Context, taskID, userID int64) (*ApprovalRequest, *ApprovalTask, error) {
+	task, err := uc.repo.GetApprovalTask(ctx, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if task.ApproverID != userID {
+		return nil, nil, fmt.Errorf("%w: task %d is assigned to another user", ErrApprovalNotAllowed, taskID)
+	}
+	req, err := uc.repo.GetApprovalRequest(ctx, task.RequestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	task = req.task(taskID)
+	if req.Status != ApprovalStatusPending || task == nil || task.Status != ApprovalTaskPending {
+		return nil, nil, fmt.Errorf("%w: task %d", ErrApprovalTaskNotPending, taskID)
+	}
+	return req, task, nil
+}
+
+// advance 当前步骤完成后开始下一个有任务的步骤，没有时请求审批通过
+func (uc *ApprovalUsecase) advance(req *ApprovalRequest, now time.Time) []*ApprovalTask {
+	for step := req.CurrentStep + 1; step < len(req.Steps); step++ {
+		waiting := req.stepTasks(step, ApprovalTaskWaiting)
+		if len(waiting) == 0 {
+			continue
+		}
+		req.CurrentStep = step
+		for _, t := range waiting {
+			t.Status = ApprovalTaskPending
+			t.DueAt = req.Steps[step].dueAt(now)
+		}
+		return waiting
+	}
+	return req.complete(ApprovalStatusApproved, now)
+}
+
+// finishDocument 按审批请求状态更新文档：进行中时锁定为待审批，通过时提交，驳回或撤回时恢复为草稿
+func (uc *ApprovalUsecase) finishDocument(ctx context.Context, meta *DocumentMeta, req *ApprovalRequest, fromState string, userID int64) error {
+	change := &DocumentTransition{From: DocStatusDraft, FromState: fromState, To: DocStatusDraft, UserID: userID}
+	switch req.Status {
+	case ApprovalStatusPending:
+		change.WorkflowState = WorkflowStatePendingApproval
+	case ApprovalStatusApproved:
+		change.To = DocStatusSubmitted
+		change.WorkflowState = WorkflowStateSubmitted
+		change.UserID = req.RequestedBy
+	case ApprovalStatusRejected:
+		change.WorkflowState = WorkflowStateRejected
+	default:
+		change.WorkflowState = WorkflowStateDraft
+	}
+	_, err := uc.documentUc.repo.TransitionDocument(ctx, meta, req.DocName, change)
+	return err
+}
+
+// resolveTasks 按审批链确定各步骤的审批任务，第一个有任务的步骤立即开始。
+// 条件不成立的步骤跳过；已在前序步骤审批的用户不重复审批，步骤因此没有审批人时跳过；
+// 需要审批但找不到审批人时返回ErrApproverNotFound
+func (uc *ApprovalUsecase) resolveTasks(ctx context.Context, chain *ApprovalChain, doc, user map[string]interface{}, orgID *int64, requesterID int64, now time.Time) ([]*ApprovalTask, error) {
+	var ancestors []*Organization
+	seen := make(map[int64]bool)
+	var tasks []*ApprovalTask
+
+	for i, step := range chain.Steps {
+		if step.Condition != "" {
+			condition, err := ParsePermissionCondition(step.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("%w: step %d: %v", ErrInvalidApprovalChain, i+1, err)
+			}
+			ok, err := condition.Evaluate(doc, user)
+			if err != nil {
+				// 条件无法求值时按需要审批处理
+				uc.log.Warnf("Approval step %s of %s requires approval because its condition failed: %v", step.Name, chain.DocType, err)
+				ok = true
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		if step.ApproverType == ApproverOrgLeader && ancestors == nil && orgID != nil {
+			var err error
+			if ancestors, err = uc.orgRepo.GetOrganizationAncestors(ctx, int32(*orgID)); err != nil {
+				return nil, err
+			}
+		}
+		candidates, err := uc.stepApprovers(ctx, step, ancestors, orgID, requesterID)
+		if err != nil {
+			return nil, err
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%w: step %s", ErrApproverNotFound, step.Name)
+		}
+
+		active := len(tasks) == 0
+		for _, task := range candidates {
+			uc.applyDelegation(ctx, task, chain.DocType, requesterID, now)
+			if seen[task.ApproverID] {
+				continue
+			}
+			seen[task.ApproverID] = true
+			task.Step = i
+			task.StepName = step.Name
+			task.Status = ApprovalTaskWaiting
+			if active {
+				task.Status = ApprovalTaskPending
+				task.DueAt = step.dueAt(now)
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// stepApprovers 步骤的候选审批任务，申请人不审批自己的文档
+func (uc *ApprovalUsecase) stepApprovers(ctx context.Context, step *ApprovalStep, ancestors []*Organization, orgID *int64, requesterID int64) ([]*ApprovalTask, error) {
+	var userIDs []int64
+	var err error
+	switch step.ApproverType {
+	case ApproverOrgLeader:
+		start := step.Levels
+		if step.OrgType != "" {
+			for start < len(ancestors) && ancestors[start].OrgType != step.OrgType {
+				start++
+			}
+		}
+		leader, org, err := uc.nearestLeader(ctx, ancestors, start, map[int64]bool{requesterID: true})
+		if err != nil || leader == 0 {
+			return nil, err
+		}
+		return []*ApprovalTask{{ApproverID: leader, OrgID: org}}, nil
+	case ApproverRole:
+		userIDs, err = uc.repo.ListRoleUserIDs(ctx, step.Role)
+	case ApproverUser:
+		userIDs, err = uc.repo.ListActiveUserIDs(ctx, step.UserIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*ApprovalTask, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != requesterID {
+			tasks = append(tasks, &ApprovalTask{ApproverID: id, OrgID: orgID})
+		}
+	}
+	return tasks, nil
+}
+
+// nearestLeader 从ancestors[start]开始向上查找第一个不在排除名单中的启用负责人，找不到时返回0
+func (uc *ApprovalUsecase) nearestLeader(ctx context.Context, ancestors []*Organization, start int, excluded map[int64]bool) (int64, *int64, error) {
+	for i := start; i < len(ancestors); i++ {
+		org := ancestors[i]
+		if org.LeaderID == nil || excluded[int64(*org.LeaderID)] {
+			continue
+		}
+		leader := int64(*org.LeaderID)
+		active, err := uc.repo.ListActiveUserIDs(ctx, []int64{leader})
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(active) > 0 {
+			orgID := int64(org.ID)
+			return leader, &orgID, nil
+		}
+	}
+	return 0, nil, nil
+}
+
+// escalationTarget 超时任务的升级对象：从任务所代表的组织向上查找负责人，
+// 跳过原审批人、委托人、申请人和本步骤的其他审批人
+func (uc *ApprovalUsecase) escalationTarget(ctx context.Context, req *ApprovalRequest, task *ApprovalTask, now time.Time) (int64, *int64, error) {
+	orgID := task.OrgID
+	if orgID == nil {
+		orgID = req.OrgID
+	}
+	if orgID == nil {
+		return 0, nil, nil
+	}
+	ancestors, err := uc.orgRepo.GetOrganizationAncestors(ctx, int32(*orgID))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	excluded := map[int64]bool{task.ApproverID: true, req.RequestedBy: true}
+	if task.DelegatedFrom != nil {
+		excluded[*task.DelegatedFrom] = true
+	}
+	for _, t := range req.stepTasks(task.Step, ApprovalTaskWaiting, ApprovalTaskPending) {
+		excluded[t.ApproverID] = true
+	}
+	return uc.nearestLeader(ctx, ancestors, 0, excluded)
+}
+
+// applyDelegation 审批人在当前时间有生效的委托时，任务改由受托人处理；受托人是申请人时不转交
+func (uc *ApprovalUsecase) applyDelegation(ctx context.Context, task *ApprovalTask, docType string, requesterID int64, now time.Time) {
+	delegation, err := uc.repo.GetActiveApprovalDelegation(ctx, task.ApproverID, docType, now)
+	if err != nil {
+		uc.log.Errorf("failed to get approval delegation of user %d: %v", task.ApproverID, err)
+		return
+	}
+	if delegation == nil || delegation.DelegateID == requesterID {
+		return
+	}
+	from := task.ApproverID
+	task.DelegatedFrom = &from
+	task.ApproverID = delegation.DelegateID
+}
+
+// writeLog 写入审批操作日志，失败时仅记录错误
+func (uc *ApprovalUsecase) writeLog(ctx context.Context, req *ApprovalRequest, action string, userID int64, description string, detail map[string]interface{}) {
+	entry := approvalLog(req, action, description, detail)
+	id := int32(userID)
+	entry.UserID = &id
+	if user, err := uc.permRepo.GetUserConditionAttributes(ctx, userID); err == nil {
+		entry.Username, _ = user["username"].(string)
+	}
+	if err := uc.auditRepo.CreateOperationLog(ctx, entry); err != nil {
+		uc.log.Errorf("failed to write audit log for approval request %d: %v", req.ID, err)
+	}
+}
+
+// documentOrgID 文档所属组织：审批链指定了组织字段时取文档字段值，否则取申请人的主组织
+func documentOrgID(chain *ApprovalChain, doc, user map[string]interface{}) *int64 {
+	if chain.OrgField != "" {
+		if id, err := coerceInt(doc[chain.OrgField]); err == nil && id > 0 {
+			orgID := int64(id)
+			return &orgID
+		}
+		return nil
+	}
+	if id, ok := user["org_id"].(int64); ok {
+		return &id
+	}
+	return nil
+}
+
+// approvalLog 构造审批操作日志
+func approvalLog(req *ApprovalRequest, action, description string, detail map[string]interface{}) *OperationLog {
+	data := map[string]interface{}{
+		"request_id": req.ID,
+		"doc_type":   req.DocType,
+		"name":       req.DocName,
+		"status":     req.Status,
+		"step":       req.CurrentStep,
+	}
+	for key, value := range detail {
+		data[key] = value
+	}
+	requestData, _ := json.Marshal(data)
+
+	return &OperationLog{
+		Action:      action,
+		Resource:    req.DocType,
+		ResourceID:  strconv.FormatInt(req.ID, 10),
+		Description: description,
+		RequestData: string(requestData),
+		Status:      "success",
+		CreatedAt:   time.Now(),
+	}
+}
@@ -0,0 +1,520 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubApprovalRepo 在内存中保存审批链、请求、任务和委托，读取时返回副本以模拟数据库的版本检查
+type stubApprovalRepo struct {
+	chains      map[string]*biz.ApprovalChain
+	requests    []*biz.ApprovalRequest
+	delegations []*biz.ApprovalDelegation
+	roleUsers   map[string][]int64
+	activeUsers map[int64]bool
+	nextID      int64
+}
+
+func (r *stubApprovalRepo) SaveApprovalChain(ctx context.Context, chain *biz.ApprovalChain) (*biz.ApprovalChain, error) {
+	if r.chains == nil {
+		r.chains = make(map[string]*biz.ApprovalChain)
+	}
+	r.nextID++
+	chain.ID = r.nextID
+	r.chains[chain.DocType] = chain
+	return chain, nil
+}
+
+func (r *stubApprovalRepo) GetApprovalChain(ctx context.Context, docType string) (*biz.ApprovalChain, error) {
+	if chain, ok := r.chains[docType]; ok {
+		return chain, nil
+	}
+	return nil, biz.ErrApprovalChainNotFound
+}
+
+func (r *stubApprovalRepo) ListApprovalChains(ctx context.Context) ([]*biz.ApprovalChain, error) {
+	var chains []*biz.ApprovalChain
+	for _, chain := range r.chains {
+		chains = append(chains, chain)
+	}
+	return chains, nil
+}
+
+func (r *stubApprovalRepo) DeleteApprovalChain(ctx context.Context, docType string) error {
+	if _, ok := r.chains[docType]; !ok {
+		return biz.ErrApprovalChainNotFound
+	}
+	delete(r.chains, docType)
+	return nil
+}
+
+func (r *stubApprovalRepo) CreateApprovalRequest(ctx context.Context, req *biz.ApprovalRequest) (*biz.ApprovalRequest, error) {
+	if _, err := r.GetPendingApprovalRequest(ctx, req.DocType, req.DocName); err == nil {
+		return nil, biz.ErrApprovalPending
+	}
+	r.nextID++
+	req.ID = r.nextID
+	req.CompanyID = biz.CompanyFromContext(ctx)
+	for _, task := range req.Tasks {
+		r.nextID++
+		task.ID = r.nextID
+		task.RequestID = req.ID
+		task.CompanyID = req.CompanyID
+		task.DocType, task.DocName = req.DocType, req.DocName
+	}
+	r.requests = append(r.requests, r.copyOf(req))
+	return req, nil
+}
+
+func (r *stubApprovalRepo) GetApprovalRequest(ctx context.Context, id int64) (*biz.ApprovalRequest, error) {
+	for _, req := range r.requests {
+		if req.ID == id && req.CompanyID == biz.CompanyFromContext(ctx) {
+			return r.copyOf(req), nil
+		}
+	}
+	return nil, biz.ErrApprovalRequestNotFound
+}
+
+func (r *stubApprovalRepo) GetPendingApprovalRequest(ctx context.Context, docType, docName string) (*biz.ApprovalRequest, error) {
+	for _, req := range r.requests {
+		if req.DocType == docType && req.DocName == docName && req.Status == biz.ApprovalStatusPending {
+			return r.copyOf(req), nil
+		}
+	}
+	return nil, biz.ErrApprovalRequestNotFound
+}
+
+func (r *stubApprovalRepo) ListApprovalRequests(ctx context.Context, docType, docName string) ([]*biz.ApprovalRequest, error) {
+	requests := []*biz.ApprovalRequest{}
+	for i := len(r.requests) - 1; i >= 0; i-- {
+		if r.requests[i].DocType == docType && r.requests[i].DocName == docName {
+			requests = append(requests, r.copyOf(r.requests[i]))
+		}
+	}
+	return requests, nil
+}
+
+func (r *stubApprovalRepo) UpdateApprovalRequest(ctx context.Context, req *biz.ApprovalRequest, tasks []*biz.ApprovalTask) error {
+	for i, stored := range r.requests {
+		if stored.ID != req.ID {
+			continue
+		}
+		if stored.Version != req.Version {
+			return biz.ErrApprovalRequestChanged
+		}
+		for _, task := range tasks {
+			if task.ID == 0 {
+				r.nextID++
+				task.ID = r.nextID
+				task.RequestID = req.ID
+				task.CompanyID = req.CompanyID
+				task.DocType, task.DocName = req.DocType, req.DocName
+				req.Tasks = append(req.Tasks, task)
+			}
+		}
+		req.Version++
+		r.requests[i] = r.copyOf(req)
+		return nil
+	}
+	return biz.ErrApprovalRequestNotFound
+}
+
+func (r *stubApprovalRepo) GetApprovalTask(ctx context.Context, id int64) (*biz.ApprovalTask, error) {
+	for _, req := range r.requests {
+		for _, task := range req.Tasks {
+			if task.ID == id {
+				copied := *task
+				return &copied, nil
+			}
+		}
+	}
+	return nil, biz.ErrApprovalTaskNotFound
+}
+
+func (r *stubApprovalRepo) ListApprovalTasks(ctx context.Context, approverID int64, status string) ([]*biz.ApprovalTask, error) {
+	tasks := []*biz.ApprovalTask{}
+	for _, req := range r.requests {
+		for _, task := range req.Tasks {
+			if task.ApproverID == approverID && (status == "" || task.Status == status) {
+				copied := *task
+				tasks = append(tasks, &copied)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+func (r *stubApprovalRepo) ListOverdueApprovalTasks(ctx context.Context, now time.Time, limit int) ([]*biz.ApprovalTask, error) {
+	var tasks []*biz.ApprovalTask
+	for _, req := range r.requests {
+		if req.Status != biz.ApprovalStatusPending {
+			continue
+		}
+		for _, task := range req.Tasks {
+			if task.Status == biz.ApprovalTaskPending && task.DueAt != nil && !task.DueAt.After(now) && len(tasks) < limit {
+				copied := *task
+				tasks = append(tasks, &copied)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+func (r *stubApprovalRepo) CreateApprovalDelegation(ctx context.Context, delegation *biz.ApprovalDelegation) (*biz.ApprovalDelegation, error) {
+	r.nextID++
+	delegation.ID = r.nextID
+	r.delegations = append(r.delegations, delegation)
+	return delegation, nil
+}
+
+func (r *stubApprovalRepo) GetApprovalDelegation(ctx context.Context, id int64) (*biz.ApprovalDelegation, error) {
+	for _, delegation := range r.delegations {
+		if delegation.ID == id {
+			return delegation, nil
+		}
+	}
+	return nil, biz.ErrApprovalDelegationNotFound
+}
+
+func (r *stubApprovalRepo) ListApprovalDelegations(ctx context.Context, delegatorID int64) ([]*biz.ApprovalDelegation, error) {
+	delegations := []*biz.ApprovalDelegation{}
+	for _, delegation := range r.delegations {
+		if delegation.DelegatorID == delegatorID {
+			delegations = append(delegations, delegation)
+		}
+	}
+	return delegations, nil
+}
+
+func (r *stubApprovalRepo) RevokeApprovalDelegation(ctx context.Context, id int64) error {
+	for _, delegation := range r.delegations {
+		if delegation.ID == id && delegation.IsActive {
+			delegation.IsActive = false
+			return nil
+		}
+	}
+	return biz.ErrApprovalDelegationNotFound
+}
+
+func (r *stubApprovalRepo) GetActiveApprovalDelegation(ctx context.Context, delegatorID int64, docType string, at time.Time) (*biz.ApprovalDelegation, error) {
+	for _, delegation := range r.delegations {
+		if delegation.DelegatorID == delegatorID && delegation.IsActive && r.activeUsers[delegation.DelegateID] &&
+			!delegation.StartsAt.After(at) && delegation.EndsAt.After(at) &&
+			(delegation.DocType == "" || delegation.DocType == docType) {
+			return delegation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *stubApprovalRepo) ListRoleUserIDs(ctx context.Context, roleCode string) ([]int64, error) {
+	return r.ListActiveUserIDs(ctx, r.roleUsers[roleCode])
+}
+
+func (r *stubApprovalRepo) ListActiveUserIDs(ctx context.Context, userIDs []int64) ([]int64, error) {
+	var active []int64
+	for _, id := range userIDs {
+		if r.activeUsers[id] {
+			active = append(active, id)
+		}
+	}
+	return active, nil
+}
+
+func (r *stubApprovalRepo) copyOf(req *biz.ApprovalRequest) *biz.ApprovalRequest {
+	copied := *req
+	copied.Tasks = make([]*biz.ApprovalTask, len(req.Tasks))
+	for i, task := range req.Tasks {
+		t := *task
+		copied.Tasks[i] = &t
+	}
+	return &copied
+}
+
+func TestApprovalChain_Validate(t *testing.T) {
+	valid := func() *biz.ApprovalChain {
+		return &biz.ApprovalChain{
+			DocType: "Order",
+			Name:    " Order Approval ",
+			Steps: []*biz.ApprovalStep{
+				{Name: "Leader", ApproverType: biz.ApproverOrgLeader, Levels: 1, Role: "IGNORED", TimeoutHours: 24},
+				{Name: "Finance", ApproverType: biz.ApproverRole, Role: "FINANCE", Mode: biz.ApprovalModeAll, Condition: "doc.amount > 10000"},
+				{Name: "CEO", ApproverType: biz.ApproverUser, UserIDs: []int64{10, 10}},
+			},
+		}
+	}
+	chain := valid()
+	assert.NoError(t, chain.Validate())
+	assert.Equal(t, "Order Approval", chain.Name)
+	assert.Equal(t, biz.ApprovalModeAny, chain.Steps[0].Mode)
+	assert.Empty(t, chain.Steps[0].Role)
+	assert.Equal(t, []int64{10}, chain.Steps[2].UserIDs)
+
+	cases := map[string]func(c *biz.ApprovalChain){
+		"no steps":          func(c *biz.ApprovalChain) { c.Steps = nil },
+		"nil step":          func(c *biz.ApprovalChain) { c.Steps[1] = nil },
+		"no name":           func(c *biz.ApprovalChain) { c.Name = " " },
+		"unnamed step":      func(c *biz.ApprovalChain) { c.Steps[0].Name = "" },
+		"unknown approver":  func(c *biz.ApprovalChain) { c.Steps[0].ApproverType = "manager" },
+		"negative levels":   func(c *biz.ApprovalChain) { c.Steps[0].Levels = -1 },
+		"missing role":      func(c *biz.ApprovalChain) { c.Steps[1].Role = "" },
+		"no users":          func(c *biz.ApprovalChain) { c.Steps[2].UserIDs = nil },
+		"invalid user":      func(c *biz.ApprovalChain) { c.Steps[2].UserIDs = []int64{0} },
+		"unknown mode":      func(c *biz.ApprovalChain) { c.Steps[1].Mode = "majority" },
+		"negative timeout":  func(c *biz.ApprovalChain) { c.Steps[0].TimeoutHours = -1 },
+		"invalid condition": func(c *biz.ApprovalChain) { c.Steps[1].Condition = "doc.amount >" },
+	}
+	for name, mutate := range cases {
+		c := valid()
+		mutate(c)
+		assert.ErrorIs(t, c.Validate(), biz.ErrInvalidApprovalChain, name)
+	}
+}
+
+func TestApprovalUsecase(t *testing.T) {
+	fieldRepo := &stubDocFieldRepo{fields: []*biz.DocField{
+		{DocType: "Order", FieldName: "customer", FieldType: "Data", IsMandatory: true},
+		{DocType: "Order", FieldName: "amount", FieldType: "Currency"},
+		{DocType: "Order", FieldName: "department", FieldType: "Int"},
+		{DocType: "Note", FieldName: "content", FieldType: "Text"},
+	}}
+	permRepo := &stubWorkflowPermissionRepo{
+		stubDocTypePermissionRepo: stubDocTypePermissionRepo{docTypes: map[string]*biz.DocType{
+			"Order": {Name: "Order", IsSubmittable: true},
+			"Note":  {Name: "Note"},
+		}},
+	}
+	roleRepo := &stubRoleRepo{roles: map[int32]*biz.Role{
+		1: {ID: 1, Code: "FINANCE", IsEnabled: true},
+		2: {ID: 2, Code: "RETIRED", IsEnabled: false},
+	}}
+	int32Ptr := func(v int32) *int32 { return &v }
+	// 集团(负责人10) > 销售部(负责人20) > 销售一组(无负责人)
+	orgRepo := &stubOrganizationRepo{orgs: map[int32]*biz.Organization{
+		1: {ID: 1, Name: "集团", LeaderID: int32Ptr(10)},
+		2: {ID: 2, Name: "销售部", ParentID: int32Ptr(1), LeaderID: int32Ptr(20)},
+		3: {ID: 3, Name: "销售一组", ParentID: int32Ptr(2)},
+	}}
+	approvalRepo := &stubApprovalRepo{
+		roleUsers:   map[string][]int64{"FINANCE": {30, 31, 32}},
+		activeUsers: map[int64]bool{1: true, 10: true, 20: true, 30: true, 31: true, 40: true},
+	}
+	auditRepo := &stubAuditRepo{}
+	repo := &stubDocumentRepo{}
+	documentUc := biz.NewDocumentUsecase(repo, fieldRepo, permRepo, &stubNamingSeriesRepo{}, &stubWorkflowRepo{}, approvalRepo, log.DefaultLogger)
+	tx := &stubTransaction{}
+	uc := biz.NewApprovalUsecase(approvalRepo, documentUc, orgRepo, permRepo, roleRepo, auditRepo, tx, log.DefaultLogger)
+	ctx := biz.WithCompany(context.Background(), 2)
+	clerk := &biz.DocumentAccess{UserID: 1}
+
+	chain := &biz.ApprovalChain{
+		DocType:  "Order",
+		Name:     "Order Approval",
+		IsActive: true,
+		OrgField: "department",
+		Steps: []*biz.ApprovalStep{
+			{Name: "部门负责人", ApproverType: biz.ApproverOrgLeader, TimeoutHours: 24},
+			{Name: "财务", ApproverType: biz.ApproverRole, Role: "FINANCE", Mode: biz.ApprovalModeAll, Condition: "doc.amount > 10000"},
+			{Name: "总经理", ApproverType: biz.ApproverUser, UserIDs: []int64{10}},
+		},
+	}
+
+	// 保存时检查文档类型、组织字段、角色、用户和条件字段类型
+	invalid := *chain
+	invalid.DocType = "Note"
+	_, err := uc.SaveApprovalChain(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidApprovalChain)
+	invalid = *chain
+	invalid.OrgField = "customer"
+	_, err = uc.SaveApprovalChain(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidApprovalChain)
+	invalid = *chain
+	invalid.Steps = []*biz.ApprovalStep{{Name: "x", ApproverType: biz.ApproverRole, Role: "RETIRED"}}
+	_, err = uc.SaveApprovalChain(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidApprovalChain)
+	invalid.Steps = []*biz.ApprovalStep{{Name: "x", ApproverType: biz.ApproverUser, UserIDs: []int64{10, 99}}}
+	_, err = uc.SaveApprovalChain(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidApprovalChain)
+	invalid.Steps = []*biz.ApprovalStep{{Name: "x", ApproverType: biz.ApproverUser, UserIDs: []int64{10}, Condition: "doc.amount == 'x'"}}
+	_, err = uc.SaveApprovalChain(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidApprovalChain)
+
+	_, err = uc.SaveApprovalChain(ctx, chain)
+	assert.NoError(t, err)
+
+	// 发起审批：销售一组没有负责人，向上找到销售部负责人；角色中停用的用户不审批
+	_, err = documentUc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-1", "customer": "ACME", "amount": 20000.0, "department": int64(3)}, clerk)
+	assert.NoError(t, err)
+	_, err = documentUc.SubmitDocument(ctx, "Order", "SO-1", clerk)
+	assert.ErrorIs(t, err, biz.ErrDocumentUnderApproval)
+
+	req, err := uc.RequestApproval(ctx, "Order", "SO-1", "请审批", clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.ApprovalStatusPending, req.Status)
+	assert.Equal(t, int64(3), *req.OrgID)
+	if assert.Len(t, req.Tasks, 4) {
+		assert.Equal(t, int64(20), req.Tasks[0].ApproverID)
+		assert.Equal(t, int64(2), *req.Tasks[0].OrgID)
+		assert.Equal(t, biz.ApprovalTaskPending, req.Tasks[0].Status)
+		assert.NotNil(t, req.Tasks[0].DueAt)
+		assert.Equal(t, biz.ApprovalTaskWaiting, req.Tasks[1].Status)
+		assert.Equal(t, int64(10), req.Tasks[3].ApproverID)
+	}
+
+	// 审批中文档被锁定，不能重复发起
+	doc, err := documentUc.GetDocument(ctx, "Order", "SO-1", clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.WorkflowStatePendingApproval, doc["workflow_state"])
+	_, err = documentUc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"amount": 1.0}, clerk)
+	assert.ErrorIs(t, err, biz.ErrDocumentNotEditable)
+	assert.ErrorIs(t, documentUc.DeleteDocument(ctx, "Order", "SO-1", clerk), biz.ErrDocumentNotEditable)
+	_, err = uc.RequestApproval(ctx, "Order", "SO-1", "", clerk)
+	assert.ErrorIs(t, err, biz.ErrApprovalPending)
+
+	// 只有任务的审批人可以处理，后续步骤的任务尚不能处理
+	_, err = uc.DecideTask(ctx, req.Tasks[0].ID, true, "", 10)
+	assert.ErrorIs(t, err, biz.ErrApprovalNotAllowed)
+	_, err = uc.DecideTask(ctx, req.Tasks[1].ID, true, "", 30)
+	assert.ErrorIs(t, err, biz.ErrApprovalTaskNotPending)
+
+	req, err = uc.DecideTask(ctx, req.Tasks[0].ID, true, "同意", 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, req.CurrentStep)
+	assert.Equal(t, biz.ApprovalTaskApproved, req.Tasks[0].Status)
+	assert.Equal(t, biz.ApprovalTaskPending, req.Tasks[1].Status)
+	assert.Equal(t, biz.ApprovalTaskPending, req.Tasks[2].Status)
+
+	// 全部审批方式需要每个审批人同意；转交后由受托人审批
+	financeA, financeB := req.Tasks[1].ID, req.Tasks[2].ID
+	req, err = uc.DecideTask(ctx, financeA, true, "", 30)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, req.CurrentStep)
+	_, err = uc.DelegateTask(ctx, financeB, 1, "", 31)
+	assert.ErrorIs(t, err, biz.ErrApprovalNotAllowed)
+	req, err = uc.DelegateTask(ctx, financeB, 40, "出差", 31)
+	assert.NoError(t, err)
+	delegated := req.Tasks[len(req.Tasks)-1]
+	assert.Equal(t, int64(40), delegated.ApproverID)
+	assert.Equal(t, int64(31), *delegated.DelegatedFrom)
+	req, err = uc.DecideTask(ctx, delegated.ID, true, "", 40)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, req.CurrentStep)
+
+	commits, rollbacks := tx.commits, tx.rollbacks
+	req, err = uc.DecideTask(ctx, req.Tasks[3].ID, true, "", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.ApprovalStatusApproved, req.Status)
+	assert.NotNil(t, req.CompletedAt)
+	assert.Equal(t, commits+1, tx.commits)
+	_, err = uc.DecideTask(ctx, req.Tasks[3].ID, true, "", 10)
+	assert.ErrorIs(t, err, biz.ErrApprovalTaskNotPending)
+	assert.Equal(t, rollbacks+1, tx.rollbacks)
+
+	// 审批通过后文档以申请人的名义提交
+	doc, err = documentUc.GetDocument(ctx, "Order", "SO-1", clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.DocStatusSubmitted, doc["docstatus"])
+	assert.Equal(t, biz.WorkflowStateSubmitted, doc["workflow_state"])
+	assert.Equal(t, int64(1), doc["updated_by"])
+
+	// 条件不成立的步骤跳过；驳回后其余任务无需审批，文档恢复可编辑
+	_, err = documentUc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-2", "customer": "ACME", "amount": 500.0, "department": int64(3)}, clerk)
+	assert.NoError(t, err)
+	req, err = uc.RequestApproval(ctx, "Order", "SO-2", "", clerk)
+	assert.NoError(t, err)
+	if assert.Len(t, req.Tasks, 2) {
+		assert.Equal(t, 2, req.Tasks[1].Step)
+	}
+	req, err = uc.DecideTask(ctx, req.Tasks[0].ID, false, "金额有误", 20)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.ApprovalStatusRejected, req.Status)
+	assert.Equal(t, biz.ApprovalTaskSkipped, req.Tasks[1].Status)
+	doc, err = documentUc.UpdateDocument(ctx, "Order", "SO-2", map[string]interface{}{"amount": 600.0}, clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.WorkflowStateRejected, doc["workflow_state"])
+
+	// 重新发起后只有申请人或管理员可以撤回
+	_, err = uc.RequestApproval(ctx, "Order", "SO-2", "已修改", clerk)
+	assert.NoError(t, err)
+	_, err = uc.CancelApproval(ctx, "Order", "SO-2", "", &biz.DocumentAccess{UserID: 20}, false)
+	assert.ErrorIs(t, err, biz.ErrApprovalNotAllowed)
+	req, err = uc.CancelApproval(ctx, "Order", "SO-2", "", clerk, false)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.ApprovalStatusCancelled, req.Status)
+	doc, err = documentUc.GetDocument(ctx, "Order", "SO-2", clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.WorkflowStateDraft, doc["workflow_state"])
+	history, err := uc.ListDocumentApprovals(ctx, "Order", "SO-2", clerk)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	// 只有申请人、审批人和管理员可以查看审批请求
+	_, err = uc.GetApprovalRequest(ctx, req.ID, 30, false)
+	assert.ErrorIs(t, err, biz.ErrApprovalNotAllowed)
+	_, err = uc.GetApprovalRequest(ctx, req.ID, 30, true)
+	assert.NoError(t, err)
+
+	// 其他公司看不到审批请求
+	_, err = uc.GetApprovalRequest(biz.WithCompany(context.Background(), 3), req.ID, 1, true)
+	assert.ErrorIs(t, err, biz.ErrApprovalRequestNotFound)
+
+	// 超时任务升级给上级组织的负责人；定时任务没有公司上下文，每个任务在所属公司内处理
+	_, err = documentUc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-3", "customer": "ACME", "amount": 500.0, "department": int64(3)}, clerk)
+	assert.NoError(t, err)
+	req, err = uc.RequestApproval(ctx, "Order", "SO-3", "", clerk)
+	assert.NoError(t, err)
+	escalated, err := uc.EscalateOverdueTasks(context.Background(), time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, escalated)
+	escalated, err = uc.EscalateOverdueTasks(context.Background(), time.Now().Add(25*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, escalated)
+	req, err = uc.GetApprovalRequest(ctx, req.ID, 1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.ApprovalTaskEscalated, req.Tasks[0].Status)
+	target := req.Tasks[len(req.Tasks)-1]
+	assert.Equal(t, int64(10), target.ApproverID)
+	assert.Equal(t, req.Tasks[0].ID, *target.EscalatedFrom)
+	assert.Equal(t, biz.ApprovalTaskPending, target.Status)
+	entry := auditRepo.logs[len(auditRepo.logs)-1]
+	assert.Equal(t, biz.ApprovalActionEscalate, entry.Action)
+	assert.Equal(t, "system", entry.Username)
+
+	// 没有更高的负责人时清除超时时间，不再升级
+	escalated, err = uc.EscalateOverdueTasks(context.Background(), time.Now().Add(50*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, escalated)
+	req, err = uc.GetApprovalRequest(ctx, req.ID, 1, false)
+	assert.NoError(t, err)
+	assert.Nil(t, req.Tasks[len(req.Tasks)-1].DueAt)
+
+	// 委托期内新分配给委托人的任务改由受托人处理，只有委托人可以撤销
+	_, err = uc.CreateDelegation(ctx, &biz.ApprovalDelegation{DelegatorID: 20, DelegateID: 20, StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour)})
+	assert.ErrorIs(t, err, biz.ErrInvalidApprovalDelegation)
+	_, err = uc.CreateDelegation(ctx, &biz.ApprovalDelegation{DelegatorID: 20, DelegateID: 40, StartsAt: time.Now(), EndsAt: time.Now().Add(-time.Hour)})
+	assert.ErrorIs(t, err, biz.ErrInvalidApprovalDelegation)
+	delegation, err := uc.CreateDelegation(ctx, &biz.ApprovalDelegation{DelegatorID: 20, DelegateID: 40, DocType: "Order", StartsAt: time.Now().Add(-time.Minute), EndsAt: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = documentUc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-4", "customer": "ACME", "amount": 500.0, "department": int64(3)}, clerk)
+	assert.NoError(t, err)
+	req, err = uc.RequestApproval(ctx, "Order", "SO-4", "", clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(40), req.Tasks[0].ApproverID)
+	assert.Equal(t, int64(20), *req.Tasks[0].DelegatedFrom)
+	tasks, err := uc.ListTasks(ctx, 40, biz.ApprovalTaskPending)
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "SO-4", tasks[0].DocName)
+	}
+
+	assert.ErrorIs(t, uc.RevokeDelegation(ctx, delegation.ID, 40, false), biz.ErrApprovalNotAllowed)
+	assert.NoError(t, uc.RevokeDelegation(ctx, delegation.ID, 20, false))
+	assert.ErrorIs(t, uc.RevokeDelegation(ctx, delegation.ID, 20, false), biz.ErrApprovalDelegationNotFound)
+}
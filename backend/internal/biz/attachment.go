@@ -0,0 +1,454 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// UserAvatarDocType 用户头像登记的文档类型，文档名称为用户ID
+const UserAvatarDocType = "User"
+
+// 附件下载的文件版本
+const (
+	AttachmentVariantOriginal  = "original"
+	AttachmentVariantThumbnail = "thumbnail"
+)
+
+// DefaultThumbnailSize 默认缩略图边长
+const DefaultThumbnailSize = 128
+
+// maxImagePixels 生成缩略图时允许解码的最大像素数，防止解码超大图片耗尽内存
+const maxImagePixels = 40000000
+
+// avatarContentTypes 头像允许的文件类型
+var avatarContentTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// contentTypeExtensions 识别出的文件类型对应的存储扩展名
+var contentTypeExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// Attachment 附件
+type Attachment struct {
+	ID           int64     `json:"id"`
+	DocType      string    `json:"doc_type"`
+	DocName      string    `json:"doc_name"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	FileSize     int64     `json:"file_size"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	UploadedBy   int64     `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	// URL 带签名的下载链接，在有效期内无需登录即可下载
+	URL          string     `json:"url,omitempty"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+// AttachmentConfig 附件上传限制和下载链接签名配置
+type AttachmentConfig struct {
+	MaxSize       int64
+	AllowedTypes  []string
+	SignKey       []byte
+	URLExpire     time.Duration
+	ThumbnailSize int
+}
+
+// 错误定义
+var (
+	ErrAttachmentNotFound         = errors.New("attachment not found")
+	ErrAttachmentEmpty            = errors.New("attachment is empty")
+	ErrAttachmentTooLarge         = errors.New("attachment exceeds the size limit")
+	ErrAttachmentTypeNotAllowed   = errors.New("attachment type is not allowed")
+	ErrAttachmentLimitExceeded    = errors.New("document attachment limit reached")
+	ErrAttachmentInvalidImage     = errors.New("invalid image")
+	ErrAttachmentURLExpired       = errors.New("attachment url expired")
+	ErrAttachmentInvalidSignature = errors.New("invalid attachment url signature")
+	ErrStorageObjectNotFound      = errors.New("storage object not found")
+)
+
+// FileStorage 文件存储接口，键为以/分隔的相对路径
+type FileStorage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// AttachmentRepo 附件仓储接口
+type AttachmentRepo interface {
+	// CreateAttachment 登记附件，maxCount大于0时文档附件数达到上限返回ErrAttachmentLimitExceeded
+	CreateAttachment(ctx context.Context, attachment *Attachment, maxCount int) (*Attachment, error)
+	// GetAttachment 按ID获取附件，不区分公司，供已签名的下载链接使用
+	GetAttachment(ctx context.Context, id int64) (*Attachment, error)
+	// ListAttachments 获取当前公司文档的附件，用户头像不属于任何公司
+	ListAttachments(ctx context.Context, docType, docName string) ([]*Attachment, error)
+	// DeleteAttachment 删除附件登记，附件属于其他公司时返回ErrAttachmentNotFound
+	DeleteAttachment(ctx context.Context, id int64) error
+	// SetUserAvatar 登记新头像并更新用户头像地址，返回被替换的旧头像
+	SetUserAvatar(ctx context.Context, userID int64, attachment *Attachment, avatarURL string) ([]*Attachment, error)
+}
+
+// AttachmentUsecase 附件用例
+type AttachmentUsecase struct {
+	repo     AttachmentRepo
+	storage  FileStorage
+	permRepo PermissionRepo
+	config   *AttachmentConfig
+	log      *log.Helper
+}
+
+// NewAttachmentUsecase 创建附件用例
+func NewAttachmentUsecase(repo AttachmentRepo, storage FileStorage, permRepo PermissionRepo, config *AttachmentConfig, logger log.Logger) *AttachmentUsecase {
+	return &AttachmentUsecase{
+		repo:     repo,
+		storage:  storage,
+		permRepo: permRepo,
+		config:   config,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// UploadAttachment 上传文档附件，按文件内容识别类型，并检查大小、类型和文档类型的附件数上限
+func (uc *AttachmentUsecase) UploadAttachment(ctx context.Context, docType, docName, fileName string, r io.Reader, uploadedBy int64) (*Attachment, error) {
+	data, contentType, err := uc.readFile(r, uc.config.AllowedTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	dt, err := uc.permRepo.GetDocType(ctx, docType)
+	if err != nil {
+		return nil, err
+	}
+	if dt.MaxAttachments > 0 {
+		attachments, err := uc.repo.ListAttachments(ctx, docType, docName)
+		if err != nil {
+			return nil, err
+		}
+		if len(attachments) >= dt.MaxAttachments {
+			return nil, ErrAttachmentLimitExceeded
+		}
+	}
+
+	attachment := &Attachment{
+		DocType:     docType,
+		DocName:     docName,
+		FileName:    cleanFileName(fileName, contentType),
+		ContentType: contentType,
+		FileSize:    int64(len(data)),
+		StorageKey:  newStorageKey("attachments/"+url.PathEscape(docType), contentTypeExtensions[contentType]),
+		UploadedBy:  uploadedBy,
+	}
+	if err := uc.storage.Put(ctx, attachment.StorageKey, contentType, data); err != nil {
+		return nil, err
+	}
+
+	created, err := uc.repo.CreateAttachment(ctx, attachment, dt.MaxAttachments)
+	if err != nil {
+		uc.deleteFiles(ctx, attachment)
+		return nil, err
+	}
+	return uc.SignAttachment(created, time.Now()), nil
+}
+
+// UploadAvatar 上传用户头像并生成缩略图，替换用户原有的头像
+func (uc *AttachmentUsecase) UploadAvatar(ctx context.Context, userID int64, fileName string, r io.Reader, uploadedBy int64) (*Attachment, error) {
+	data, contentType, err := uc.readFile(r, avatarContentTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	size := uc.config.ThumbnailSize
+	if size <= 0 {
+		size = DefaultThumbnailSize
+	}
+	thumbnail, err := makeThumbnail(data, size)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("avatars/%d", userID)
+	attachment := &Attachment{
+		DocType:      UserAvatarDocType,
+		DocName:      strconv.FormatInt(userID, 10),
+		FileName:     cleanFileName(fileName, contentType),
+		ContentType:  contentType,
+		FileSize:     int64(len(data)),
+		StorageKey:   newStorageKey(prefix, contentTypeExtensions[contentType]),
+		ThumbnailKey: newStorageKey(prefix, "_thumb.png"),
+		UploadedBy:   uploadedBy,
+	}
+	if err := uc.storage.Put(ctx, attachment.StorageKey, contentType, data); err != nil {
+		return nil, err
+	}
+	if err := uc.storage.Put(ctx, attachment.ThumbnailKey, "image/png", thumbnail); err != nil {
+		uc.deleteFiles(ctx, attachment)
+		return nil, err
+	}
+
+	replaced, err := uc.repo.SetUserAvatar(ctx, userID, attachment, UserAvatarURL(userID))
+	if err != nil {
+		uc.deleteFiles(ctx, attachment)
+		return nil, err
+	}
+	for _, old := range replaced {
+		uc.deleteFiles(ctx, old)
+	}
+	return uc.SignAttachment(attachment, time.Now()), nil
+}
+
+// GetAvatar 获取用户当前的头像
+func (uc *AttachmentUsecase) GetAvatar(ctx context.Context, userID int64) (*Attachment, error) {
+	attachments, err := uc.repo.ListAttachments(ctx, UserAvatarDocType, strconv.FormatInt(userID, 10))
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, ErrAttachmentNotFound
+	}
+	return uc.SignAttachment(attachments[len(attachments)-1], time.Now()), nil
+}
+
+// ListAttachments 获取文档附件及其下载链接
+func (uc *AttachmentUsecase) ListAttachments(ctx context.Context, docType, docName string) ([]*Attachment, error) {
+	attachments, err := uc.repo.ListAttachments(ctx, docType, docName)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, attachment := range attachments {
+		uc.SignAttachment(attachment, now)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment 删除文档附件及其文件
+func (uc *AttachmentUsecase) DeleteAttachment(ctx context.Context, docType, docName string, id int64) error {
+	attachment, err := uc.repo.GetAttachment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if attachment.DocType != docType || attachment.DocName != docName {
+		return ErrAttachmentNotFound
+	}
+
+	if err := uc.repo.DeleteAttachment(ctx, id); err != nil {
+		return err
+	}
+	uc.deleteFiles(ctx, attachment)
+	return nil
+}
+
+// OpenAttachment 校验下载链接签名后打开附件文件，调用方负责关闭返回的文件
+func (uc *AttachmentUsecase) OpenAttachment(ctx context.Context, id int64, variant string, expires int64, signature string, now time.Time) (*Attachment, io.ReadCloser, error) {
+	if variant == "" {
+		variant = AttachmentVariantOriginal
+	}
+	if !hmac.Equal([]byte(signature), []byte(uc.signature(id, variant, expires))) {
+		return nil, nil, ErrAttachmentInvalidSignature
+	}
+	if now.Unix() > expires {
+		return nil, nil, ErrAttachmentURLExpired
+	}
+
+	attachment, err := uc.repo.GetAttachment(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := attachment.StorageKey
+	if variant == AttachmentVariantThumbnail {
+		if attachment.ThumbnailKey == "" {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		key, attachment.ContentType = attachment.ThumbnailKey, "image/png"
+	}
+
+	file, err := uc.storage.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrStorageObjectNotFound) {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+	return attachment, file, nil
+}
+
+// SignAttachment 为附件生成带签名和有效期的下载链接
+func (uc *AttachmentUsecase) SignAttachment(attachment *Attachment, now time.Time) *Attachment {
+	expiresAt := now.Add(uc.config.URLExpire).Truncate(time.Second)
+	attachment.URL = uc.signedURL(attachment.ID, AttachmentVariantOriginal, expiresAt.Unix())
+	if attachment.ThumbnailKey != "" {
+		attachment.ThumbnailURL = uc.signedURL(attachment.ID, AttachmentVariantThumbnail, expiresAt.Unix())
+	}
+	attachment.URLExpiresAt = &expiresAt
+	return attachment
+}
+
+// UserAvatarURL 用户头像地址，访问时重定向到当前头像的签名下载链接
+func UserAvatarURL(userID int64) string {
+	return fmt.Sprintf("/api/v1/users/%d/avatar", userID)
+}
+
+func (uc *AttachmentUsecase) signedURL(id int64, variant string, expires int64) string {
+	query := url.Values{}
+	query.Set("variant", variant)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", uc.signature(id, variant, expires))
+	return fmt.Sprintf("/api/v1/attachments/%d/download?%s", id, query.Encode())
+}
+
+func (uc *AttachmentUsecase) signature(id int64, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, uc.config.SignKey)
+	fmt.Fprintf(mac, "%d:%s:%d", id, variant, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readFile 读取上传文件，超过大小上限时不再继续读取，返回按内容识别的文件类型
+func (uc *AttachmentUsecase) readFile(r io.Reader, allowedTypes []string) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, uc.config.MaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) == 0 {
+		return nil, "", ErrAttachmentEmpty
+	}
+	if int64(len(data)) > uc.config.MaxSize {
+		return nil, "", ErrAttachmentTooLarge
+	}
+
+	contentType := DetectContentType(data)
+	for _, allowed := range allowedTypes {
+		if strings.EqualFold(allowed, contentType) {
+			return data, contentType, nil
+		}
+	}
+	return nil, "", ErrAttachmentTypeNotAllowed
+}
+
+// deleteFiles 删除附件的文件，失败时只记录日志
+func (uc *AttachmentUsecase) deleteFiles(ctx context.Context, attachment *Attachment) {
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := uc.storage.Delete(ctx, key); err != nil {
+			uc.log.Errorf("failed to delete attachment file %s: %v", key, err)
+		}
+	}
+}
+
+// DetectContentType 按文件内容识别类型，去除charset等参数
+func DetectContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// cleanFileName 去除文件名中的路径，文件名为空时按类型生成
+func cleanFileName(fileName, contentType string) string {
+	fileName = path.Base(strings.ReplaceAll(strings.TrimSpace(fileName), "\\", "/"))
+	if fileName == "." || fileName == "/" || fileName == "" {
+		return "file" + contentTypeExtensions[contentType]
+	}
+	if len(fileName) > 255 {
+		fileName = fileName[len(fileName)-255:]
+	}
+	return fileName
+}
+
+// newStorageKey 生成随机的存储键
+func newStorageKey(prefix, suffix string) string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%s/%d%s", prefix, time.Now().UnixNano(), suffix)
+	}
+	return prefix + "/" + hex.EncodeToString(buf) + suffix
+}
+
+// makeThumbnail 按比例缩小图片到边长不超过size的PNG缩略图，小图不放大
+func makeThumbnail(data []byte, size int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrAttachmentInvalidImage
+	}
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, ErrAttachmentInvalidImage
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAttachmentInvalidImage
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	thumbWidth, thumbHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			thumbWidth, thumbHeight = size, maxInt(1, height*size/width)
+		} else {
+			thumbWidth, thumbHeight = maxInt(1, width*size/height), size
+		}
+	}
+
+	// 每个目标像素取对应源区域的平均值
+	dst := image.NewRGBA64(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0 := bounds.Min.Y + y*height/thumbHeight
+		y1 := maxInt(y0+1, bounds.Min.Y+(y+1)*height/thumbHeight)
+		for x := 0; x < thumbWidth; x++ {
+			x0 := bounds.Min.X + x*width/thumbWidth
+			x1 := maxInt(x0+1, bounds.Min.X+(x+1)*width/thumbWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
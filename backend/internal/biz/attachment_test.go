@@ -0,0 +1,189 @@
+package biz_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubAttachmentRepo struct {
+	biz.AttachmentRepo
+	attachments []*biz.Attachment
+	avatarURLs  map[int64]string
+}
+
+func (r *stubAttachmentRepo) CreateAttachment(ctx context.Context, attachment *biz.Attachment, maxCount int) (*biz.Attachment, error) {
+	attachment.ID = int64(len(r.attachments) + 1)
+	r.attachments = append(r.attachments, attachment)
+	return attachment, nil
+}
+
+func (r *stubAttachmentRepo) GetAttachment(ctx context.Context, id int64) (*biz.Attachment, error) {
+	for _, attachment := range r.attachments {
+		if attachment.ID == id {
+			copied := *attachment
+			return &copied, nil
+		}
+	}
+	return nil, biz.ErrAttachmentNotFound
+}
+
+func (r *stubAttachmentRepo) ListAttachments(ctx context.Context, docType, docName string) ([]*biz.Attachment, error) {
+	var attachments []*biz.Attachment
+	for _, attachment := range r.attachments {
+		if attachment.DocType == docType && attachment.DocName == docName {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, nil
+}
+
+func (r *stubAttachmentRepo) DeleteAttachment(ctx context.Context, id int64) error {
+	for i, attachment := range r.attachments {
+		if attachment.ID == id {
+			r.attachments = append(r.attachments[:i], r.attachments[i+1:]...)
+			return nil
+		}
+	}
+	return biz.ErrAttachmentNotFound
+}
+
+func (r *stubAttachmentRepo) SetUserAvatar(ctx context.Context, userID int64, attachment *biz.Attachment, avatarURL string) ([]*biz.Attachment, error) {
+	replaced, _ := r.ListAttachments(ctx, attachment.DocType, attachment.DocName)
+	for _, old := range replaced {
+		r.DeleteAttachment(ctx, old.ID)
+	}
+	r.avatarURLs[userID] = avatarURL
+	r.CreateAttachment(ctx, attachment, 0)
+	return replaced, nil
+}
+
+// stubFileStorage 内存文件存储
+type stubFileStorage struct {
+	files map[string][]byte
+}
+
+func (s *stubFileStorage) Put(ctx context.Context, key, contentType string, data []byte) error {
+	s.files[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *stubFileStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.files[key]
+	if !ok {
+		return nil, biz.ErrStorageObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *stubFileStorage) Delete(ctx context.Context, key string) error {
+	delete(s.files, key)
+	return nil
+}
+
+func TestAttachmentUsecase(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubAttachmentRepo{avatarURLs: map[int64]string{}}
+	storage := &stubFileStorage{files: map[string][]byte{}}
+	permRepo := &stubDocTypePermissionRepo{docTypes: map[string]*biz.DocType{
+		"Order": {Name: "Order", MaxAttachments: 2},
+	}}
+	uc := biz.NewAttachmentUsecase(repo, storage, permRepo, &biz.AttachmentConfig{
+		MaxSize:       1 << 10,
+		AllowedTypes:  []string{"application/pdf", "image/png"},
+		SignKey:       []byte("test-secret"),
+		URLExpire:     time.Minute,
+		ThumbnailSize: 32,
+	}, log.DefaultLogger)
+
+	// 类型按文件内容识别，不信任文件名
+	_, err := uc.UploadAttachment(ctx, "Order", "SO-0001", "evil.pdf", strings.NewReader("<html><script></script></html>"), 1)
+	assert.ErrorIs(t, err, biz.ErrAttachmentTypeNotAllowed)
+	_, err = uc.UploadAttachment(ctx, "Order", "SO-0001", "big.pdf", bytes.NewReader(append([]byte("%PDF-"), make([]byte, 1<<10)...)), 1)
+	assert.ErrorIs(t, err, biz.ErrAttachmentTooLarge)
+	_, err = uc.UploadAttachment(ctx, "Order", "SO-0001", "empty.pdf", strings.NewReader(""), 1)
+	assert.ErrorIs(t, err, biz.ErrAttachmentEmpty)
+	_, err = uc.UploadAttachment(ctx, "Unknown", "X-1", "a.pdf", strings.NewReader("%PDF-1.4"), 1)
+	assert.ErrorIs(t, err, biz.ErrDocTypeNotFound)
+
+	// 文档类型的附件数上限
+	attachment, err := uc.UploadAttachment(ctx, "Order", "SO-0001", "../../contract.pdf", strings.NewReader("%PDF-1.4"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "contract.pdf", attachment.FileName)
+	assert.Equal(t, "application/pdf", attachment.ContentType)
+	_, err = uc.UploadAttachment(ctx, "Order", "SO-0001", "b.pdf", strings.NewReader("%PDF-1.4"), 1)
+	assert.NoError(t, err)
+	_, err = uc.UploadAttachment(ctx, "Order", "SO-0001", "c.pdf", strings.NewReader("%PDF-1.4"), 1)
+	assert.ErrorIs(t, err, biz.ErrAttachmentLimitExceeded)
+
+	// 签名下载链接
+	signed, err := url.Parse(attachment.URL)
+	assert.NoError(t, err)
+	query := signed.Query()
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	now := time.Now()
+	found, file, err := uc.OpenAttachment(ctx, attachment.ID, query.Get("variant"), expires, query.Get("signature"), now)
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "%PDF-1.4", string(content))
+	assert.Equal(t, "contract.pdf", found.FileName)
+
+	_, _, err = uc.OpenAttachment(ctx, attachment.ID+1, query.Get("variant"), expires, query.Get("signature"), now)
+	assert.ErrorIs(t, err, biz.ErrAttachmentInvalidSignature)
+	_, _, err = uc.OpenAttachment(ctx, attachment.ID, query.Get("variant"), expires+60, query.Get("signature"), now)
+	assert.ErrorIs(t, err, biz.ErrAttachmentInvalidSignature)
+	_, _, err = uc.OpenAttachment(ctx, attachment.ID, query.Get("variant"), expires, query.Get("signature"), now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, biz.ErrAttachmentURLExpired)
+
+	// 删除时校验附件属于该文档
+	assert.ErrorIs(t, uc.DeleteAttachment(ctx, "Order", "SO-0002", attachment.ID), biz.ErrAttachmentNotFound)
+	assert.NoError(t, uc.DeleteAttachment(ctx, "Order", "SO-0001", attachment.ID))
+	_, err = storage.Open(ctx, attachment.StorageKey)
+	assert.ErrorIs(t, err, biz.ErrStorageObjectNotFound)
+
+	// 头像只允许图片，生成等比缩略图并替换原头像
+	_, err = uc.UploadAvatar(ctx, 7, "a.pdf", strings.NewReader("%PDF-1.4"), 7)
+	assert.ErrorIs(t, err, biz.ErrAttachmentTypeNotAllowed)
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 16)))
+	first, err := uc.UploadAvatar(ctx, 7, "me.png", bytes.NewReader(buf.Bytes()), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1/users/7/avatar", repo.avatarURLs[7])
+	assert.NotEmpty(t, first.ThumbnailURL)
+
+	thumbnail, err := storage.Open(ctx, first.ThumbnailKey)
+	assert.NoError(t, err)
+	config, err := png.DecodeConfig(thumbnail)
+	thumbnail.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, 32, config.Width)
+	assert.Equal(t, 8, config.Height)
+
+	second, err := uc.UploadAvatar(ctx, 7, "me.png", bytes.NewReader(buf.Bytes()), 7)
+	assert.NoError(t, err)
+	_, err = storage.Open(ctx, first.StorageKey)
+	assert.ErrorIs(t, err, biz.ErrStorageObjectNotFound)
+	avatar, err := uc.GetAvatar(ctx, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, second.ID, avatar.ID)
+	_, err = uc.GetAvatar(ctx, 8)
+	assert.ErrorIs(t, err, biz.ErrAttachmentNotFound)
+
+	// 无法解码的图片
+	_, err = uc.UploadAvatar(ctx, 7, "broken.png", bytes.NewReader(buf.Bytes()[:40]), 7)
+	assert.ErrorIs(t, err, biz.ErrAttachmentInvalidImage)
+}
@@ -0,0 +1,202 @@
+package biz
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 公司字段长度限制，与companies表一致
+const (
+	maxCompanyNameLength = 100
+	maxCompanyCodeLength = 50
+)
+
+// Company 公司；组织、系统配置、权限规则、用户权限、权限配置版本、文档、编号序列、审批请求、
+// 文档附件、操作日志和权限模板应用记录按公司隔离。用户、角色、角色分配、职责分离策略、
+// 权限模板定义，以及描述文档结构的文档类型、工作流和审批链在公司间共享
+type Company struct {
+	ID          int32     `json:"id"`
+	Name        string    `json:"name"`
+	Code        string    `json:"code"`
+	Description string    `json:"description"`
+	IsEnabled   bool      `json:"is_enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UserCompany 用户所属的公司，登录时进入默认公司
+type UserCompany struct {
+	UserID    int32     `json:"user_id"`
+	CompanyID int32     `json:"company_id"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Company *Company `json:"company,omitempty"`
+}
+
+type companyKey struct{}
+
+// WithCompany 在上下文中设置当前公司
+func WithCompany(ctx context.Context, companyID int32) context.Context {
+	return context.WithValue(ctx, companyKey{}, companyID)
+}
+
+// CompanyFromContext 获取上下文中的当前公司，未设置时返回0：
+// 按公司隔离的查询不匹配任何数据，写入被公司外键拒绝，不会落入其他公司
+func CompanyFromContext(ctx context.Context) int32 {
+	if companyID, ok := ctx.Value(companyKey{}).(int32); ok && companyID > 0 {
+		return companyID
+	}
+	return 0
+}
+
+// 公司相关错误
+var (
+	ErrCompanyNotFound       = &BizError{Code: 404, Message: "Company not found"}
+	ErrCompanyCodeExists     = &BizError{Code: 400, Message: "Company code already exists"}
+	ErrInvalidCompany        = &BizError{Code: 400, Message: "Invalid company"}
+	ErrCompanyForbidden      = &BizError{Code: 403, Message: "User is not a member of the company or the company is disabled"}
+	ErrCompanyMemberNotFound = &BizError{Code: 404, Message: "User is not a member of the company"}
+	ErrCompanyMemberUser     = &BizError{Code: 400, Message: "Company member must be an active user"}
+	ErrLastUserCompany       = &BizError{Code: 400, Message: "User must belong to at least one company"}
+)
+
+// CompanyRepo 公司仓储接口
+type CompanyRepo interface {
+	// CreateCompany 创建公司并将创建人加入公司；templateID不为0时复制该公司的权限规则
+	CreateCompany(ctx context.Context, company *Company, templateID, operatorID int32) (*Company, error)
+	UpdateCompany(ctx context.Context, company *Company) (*Company, error)
+	GetCompany(ctx context.Context, id int32) (*Company, error)
+	ListCompanies(ctx context.Context) ([]*Company, error)
+
+	// AddUserCompany 将用户加入公司，用户没有默认公司时该公司成为默认公司
+	AddUserCompany(ctx context.Context, userID, companyID, operatorID int32) (*UserCompany, error)
+	// RemoveUserCompany 将用户移出公司，移出默认公司时由最早加入的其他公司接替
+	RemoveUserCompany(ctx context.Context, userID, companyID int32) error
+	SetDefaultCompany(ctx context.Context, userID, companyID int32) error
+	GetUserCompany(ctx context.Context, userID, companyID int32) (*UserCompany, error)
+	ListUserCompanies(ctx context.Context, userID int32) ([]*UserCompany, error)
+	ListCompanyUsers(ctx context.Context, companyID int32) ([]*User, error)
+}
+
+// CompanyUsecase 公司用例
+type CompanyUsecase struct {
+	repo CompanyRepo
+	log  *log.Helper
+}
+
+// NewCompanyUsecase 创建公司用例
+func NewCompanyUsecase(repo CompanyRepo, logger log.Logger) *CompanyUsecase {
+	return &CompanyUsecase{
+		repo: repo,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// validateCompany 规范化并校验公司名称和编码
+func validateCompany(company *Company) error {
+	company.Name = strings.TrimSpace(company.Name)
+	company.Code = strings.TrimSpace(company.Code)
+	if company.Name == "" || company.Code == "" ||
+		len([]rune(company.Name)) > maxCompanyNameLength || len([]rune(company.Code)) > maxCompanyCodeLength {
+		return ErrInvalidCompany
+	}
+	return nil
+}
+
+// CreateCompany 创建公司，templateID不为0时以该公司的权限规则作为新公司的初始权限配置
+func (uc *CompanyUsecase) CreateCompany(ctx context.Context, company *Company, templateID, operatorID int32) (*Company, error) {
+	if err := validateCompany(company); err != nil {
+		return nil, err
+	}
+	if templateID != 0 {
+		if _, err := uc.repo.GetCompany(ctx, templateID); err != nil {
+			return nil, err
+		}
+	}
+	return uc.repo.CreateCompany(ctx, company, templateID, operatorID)
+}
+
+// UpdateCompany 更新公司
+func (uc *CompanyUsecase) UpdateCompany(ctx context.Context, company *Company) (*Company, error) {
+	if err := validateCompany(company); err != nil {
+		return nil, err
+	}
+	return uc.repo.UpdateCompany(ctx, company)
+}
+
+// GetCompany 获取公司
+func (uc *CompanyUsecase) GetCompany(ctx context.Context, id int32) (*Company, error) {
+	return uc.repo.GetCompany(ctx, id)
+}
+
+// ListCompanies 获取全部公司
+func (uc *CompanyUsecase) ListCompanies(ctx context.Context) ([]*Company, error) {
+	return uc.repo.ListCompanies(ctx)
+}
+
+// AddUser 将用户加入公司
+func (uc *CompanyUsecase) AddUser(ctx context.Context, userID, companyID, operatorID int32) (*UserCompany, error) {
+	if _, err := uc.repo.GetCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	return uc.repo.AddUserCompany(ctx, userID, companyID, operatorID)
+}
+
+// RemoveUser 将用户移出公司；已签发的令牌在过期或刷新前仍可访问该公司
+func (uc *CompanyUsecase) RemoveUser(ctx context.Context, userID, companyID int32) error {
+	return uc.repo.RemoveUserCompany(ctx, userID, companyID)
+}
+
+// SetDefaultCompany 设置用户登录时进入的默认公司
+func (uc *CompanyUsecase) SetDefaultCompany(ctx context.Context, userID, companyID int32) error {
+	return uc.repo.SetDefaultCompany(ctx, userID, companyID)
+}
+
+// ListUserCompanies 获取用户所属的公司
+func (uc *CompanyUsecase) ListUserCompanies(ctx context.Context, userID int32) ([]*UserCompany, error) {
+	return uc.repo.ListUserCompanies(ctx, userID)
+}
+
+// ListCompanyUsers 获取公司的用户
+func (uc *CompanyUsecase) ListCompanyUsers(ctx context.Context, companyID int32) ([]*User, error) {
+	if _, err := uc.repo.GetCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListCompanyUsers(ctx, companyID)
+}
+
+// ResolveCompany 确定用户要进入的公司：companyID为0时使用默认公司；
+// 用户必须是该公司成员且公司已启用
+func (uc *CompanyUsecase) ResolveCompany(ctx context.Context, userID, companyID int32) (*Company, error) {
+	if companyID == 0 {
+		companies, err := uc.repo.ListUserCompanies(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, membership := range companies {
+			if membership.IsDefault {
+				companyID = membership.CompanyID
+				break
+			}
+		}
+		if companyID == 0 {
+			return nil, ErrCompanyForbidden
+		}
+	}
+
+	membership, err := uc.repo.GetUserCompany(ctx, userID, companyID)
+	if err != nil {
+		if err == ErrCompanyMemberNotFound {
+			return nil, ErrCompanyForbidden
+		}
+		return nil, err
+	}
+	if membership.Company == nil || !membership.Company.IsEnabled {
+		return nil, ErrCompanyForbidden
+	}
+	return membership.Company, nil
+}
@@ -0,0 +1,104 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubCompanyRepo 在内存中保存公司和用户所属公司
+type stubCompanyRepo struct {
+	biz.CompanyRepo
+	companies   map[int32]*biz.Company
+	memberships []*biz.UserCompany
+	templateID  int32
+}
+
+func (r *stubCompanyRepo) CreateCompany(ctx context.Context, company *biz.Company, templateID, operatorID int32) (*biz.Company, error) {
+	company.ID = int32(len(r.companies) + 1)
+	r.companies[company.ID] = company
+	r.templateID = templateID
+	return company, nil
+}
+
+func (r *stubCompanyRepo) GetCompany(ctx context.Context, id int32) (*biz.Company, error) {
+	if company, ok := r.companies[id]; ok {
+		return company, nil
+	}
+	return nil, biz.ErrCompanyNotFound
+}
+
+func (r *stubCompanyRepo) ListUserCompanies(ctx context.Context, userID int32) ([]*biz.UserCompany, error) {
+	var memberships []*biz.UserCompany
+	for _, membership := range r.memberships {
+		if membership.UserID == userID {
+			membership.Company = r.companies[membership.CompanyID]
+			memberships = append(memberships, membership)
+		}
+	}
+	return memberships, nil
+}
+
+func (r *stubCompanyRepo) GetUserCompany(ctx context.Context, userID, companyID int32) (*biz.UserCompany, error) {
+	memberships, _ := r.ListUserCompanies(ctx, userID)
+	for _, membership := range memberships {
+		if membership.CompanyID == companyID {
+			return membership, nil
+		}
+	}
+	return nil, biz.ErrCompanyMemberNotFound
+}
+
+func TestCompanyUsecase(t *testing.T) {
+	repo := &stubCompanyRepo{
+		companies: map[int32]*biz.Company{
+			1: {ID: 1, Code: "DEFAULT", IsEnabled: true},
+			2: {ID: 2, Code: "EAST", IsEnabled: true},
+			3: {ID: 3, Code: "CLOSED", IsEnabled: false},
+		},
+		memberships: []*biz.UserCompany{
+			{UserID: 7, CompanyID: 1},
+			{UserID: 7, CompanyID: 2, IsDefault: true},
+			{UserID: 7, CompanyID: 3},
+		},
+	}
+	uc := biz.NewCompanyUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 未设置公司的上下文不属于任何公司
+	assert.Equal(t, int32(0), biz.CompanyFromContext(ctx))
+	assert.Equal(t, int32(0), biz.CompanyFromContext(biz.WithCompany(ctx, 0)))
+	assert.Equal(t, int32(2), biz.CompanyFromContext(biz.WithCompany(ctx, 2)))
+
+	// 未指定公司时进入用户的默认公司
+	company, err := uc.ResolveCompany(ctx, 7, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), company.ID)
+
+	company, err = uc.ResolveCompany(ctx, 7, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), company.ID)
+
+	// 停用的公司、不属于的公司和没有公司的用户都不能进入
+	_, err = uc.ResolveCompany(ctx, 7, 3)
+	assert.Equal(t, biz.ErrCompanyForbidden, err)
+	_, err = uc.ResolveCompany(ctx, 8, 1)
+	assert.Equal(t, biz.ErrCompanyForbidden, err)
+	_, err = uc.ResolveCompany(ctx, 8, 0)
+	assert.Equal(t, biz.ErrCompanyForbidden, err)
+
+	_, err = uc.CreateCompany(ctx, &biz.Company{Name: " ", Code: "WEST"}, 0, 7)
+	assert.Equal(t, biz.ErrInvalidCompany, err)
+	_, err = uc.CreateCompany(ctx, &biz.Company{Name: "西区", Code: "WEST"}, 99, 7)
+	assert.Equal(t, biz.ErrCompanyNotFound, err)
+
+	created, err := uc.CreateCompany(ctx, &biz.Company{Name: " 西区 ", Code: " WEST "}, 1, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "西区", created.Name)
+	assert.Equal(t, "WEST", created.Code)
+	assert.Equal(t, int32(1), repo.templateID)
+}
@@ -0,0 +1,495 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// DocFieldTypes 支持的字段类型
+var DocFieldTypes = []string{
+	"Data", "Text", "Long Text", "HTML", "Markdown",
+	"Int", "Float", "Currency", "Percent",
+	"Date", "Datetime", "Time",
+	"Select", "Link", "Dynamic Link", "Table", "Check",
+	"Small Text", "Text Editor", "Code", "Password",
+	"Attach", "Attach Image", "Signature", "Color",
+	"Barcode", "Geolocation", "Duration", "Rating",
+}
+
+// IsValidFieldType 判断字段类型是否受支持
+func IsValidFieldType(fieldType string) bool {
+	for _, validType := range DocFieldTypes {
+		if fieldType == validType {
+			return true
+		}
+	}
+	return false
+}
+
+// StandardDocFields 每个文档都具有的标准字段，可用作标题、搜索和排序字段，不能重复定义
+var StandardDocFields = []string{"id", "name", "created_at", "updated_at", "created_by", "updated_by"}
+
+// isStandardDocField 判断是否为标准字段
+func isStandardDocField(name string) bool {
+	for _, standard := range StandardDocFields {
+		if name == standard {
+			return true
+		}
+	}
+	return false
+}
+
+// docFieldNamePattern 字段名称只允许小写字母、数字和下划线，作为列名使用
+var docFieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// DocField 文档类型字段定义
+type DocField struct {
+	ID              int64     `json:"id"`
+	DocType         string    `json:"doc_type"`             // 文档类型
+	FieldName       string    `json:"field_name"`           // 字段名称
+	Label           string    `json:"label"`                // 显示名称
+	FieldType       string    `json:"field_type"`           // 字段类型，取自DocFieldTypes
+	Options         string    `json:"options,omitempty"`    // Select为换行分隔的可选值，Link/Table为目标文档类型，Dynamic Link为类型字段名
+	IsMandatory     bool      `json:"is_mandatory"`         // 是否必填
+	IsUnique        bool      `json:"is_unique"`            // 是否唯一
+	DefaultValue    string    `json:"default,omitempty"`    // 默认值
+	DependsOn       string    `json:"depends_on,omitempty"` // 显示依赖：字段名或条件表达式，如 doc.status == 'Open'
+	PermissionLevel int       `json:"permission_level"`     // 权限级别 (0-9)，同步到字段权限级别
+	Idx             int       `json:"idx"`                  // 显示顺序
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	CreatedBy       *int64    `json:"created_by,omitempty"`
+	UpdatedBy       *int64    `json:"updated_by,omitempty"`
+}
+
+// Validate 验证字段定义本身，引用其他字段和文档类型的检查在用例中进行
+func (f *DocField) Validate() error {
+	if strings.TrimSpace(f.DocType) == "" {
+		return fmt.Errorf("DocType is required")
+	}
+
+	if len(f.FieldName) > 63 || !docFieldNamePattern.MatchString(f.FieldName) {
+		return fmt.Errorf("field name must be lowercase letters, digits and underscores: %s", f.FieldName)
+	}
+	if isStandardDocField(f.FieldName) || isDocumentStateField(f.FieldName) {
+		return fmt.Errorf("field name %s is reserved for standard fields", f.FieldName)
+	}
+
+	if strings.TrimSpace(f.Label) == "" {
+		return fmt.Errorf("label is required")
+	}
+
+	if !IsValidFieldType(f.FieldType) {
+		return fmt.Errorf("invalid field type: %s", f.FieldType)
+	}
+
+	if f.PermissionLevel < 0 || f.PermissionLevel > 9 {
+		return fmt.Errorf("permission level must be between 0 and 9")
+	}
+
+	switch f.FieldType {
+	case "Select":
+		if len(f.SelectOptions()) == 0 {
+			return fmt.Errorf("select field %s requires options", f.FieldName)
+		}
+	case "Link", "Table", "Dynamic Link":
+		if strings.TrimSpace(f.Options) == "" {
+			return fmt.Errorf("%s field %s requires options", f.FieldType, f.FieldName)
+		}
+	}
+
+	if f.IsUnique && (f.FieldType == "Table" || f.FieldType == "Check") {
+		return fmt.Errorf("%s field %s cannot be unique", f.FieldType, f.FieldName)
+	}
+
+	if f.DefaultValue != "" {
+		if err := f.validateDefault(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateDefault 默认值需符合字段类型
+func (f *DocField) validateDefault() error {
+	switch f.FieldType {
+	case "Int", "Rating":
+		if _, err := strconv.Atoi(f.DefaultValue); err != nil {
+			return fmt.Errorf("default of %s must be an integer", f.FieldName)
+		}
+	case "Float", "Currency", "Percent", "Duration":
+		if _, err := strconv.ParseFloat(f.DefaultValue, 64); err != nil {
+			return fmt.Errorf("default of %s must be a number", f.FieldName)
+		}
+	case "Check":
+		if f.DefaultValue != "0" && f.DefaultValue != "1" {
+			return fmt.Errorf("default of %s must be 0 or 1", f.FieldName)
+		}
+	case "Select":
+		for _, option := range f.SelectOptions() {
+			if option == f.DefaultValue {
+				return nil
+			}
+		}
+		return fmt.Errorf("default of %s must be one of its options", f.FieldName)
+	case "Date":
+		if f.DefaultValue != "Today" {
+			if _, err := time.Parse("2006-01-02", f.DefaultValue); err != nil {
+				return fmt.Errorf("default of %s must be Today or YYYY-MM-DD", f.FieldName)
+			}
+		}
+	case "Table":
+		return fmt.Errorf("table field %s cannot have a default", f.FieldName)
+	}
+	return nil
+}
+
+// SelectOptions Select字段的可选值，按行拆分并去除空行
+func (f *DocField) SelectOptions() []string {
+	var options []string
+	for _, line := range strings.Split(f.Options, "\n") {
+		if option := strings.TrimSpace(line); option != "" {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// FieldPermissionLevel 字段定义对应的字段权限级别
+func (f *DocField) FieldPermissionLevel() *FieldPermissionLevel {
+	return &FieldPermissionLevel{
+		DocType:         f.DocType,
+		FieldName:       f.FieldName,
+		FieldLabel:      f.Label,
+		PermissionLevel: f.PermissionLevel,
+		FieldType:       f.FieldType,
+		IsMandatory:     f.IsMandatory,
+	}
+}
+
+// normalize 去除首尾空白，未指定时标签取字段名、类型取Data
+func (f *DocField) normalize() {
+	f.DocType = strings.TrimSpace(f.DocType)
+	f.FieldName = strings.TrimSpace(f.FieldName)
+	f.Label = strings.TrimSpace(f.Label)
+	f.FieldType = strings.TrimSpace(f.FieldType)
+	f.Options = strings.TrimSpace(f.Options)
+	f.DependsOn = strings.TrimSpace(f.DependsOn)
+	if f.Label == "" {
+		f.Label = f.FieldName
+	}
+	if f.FieldType == "" {
+		f.FieldType = "Data"
+	}
+}
+
+// dependsOnFields 显示依赖引用的字段，字段名直接返回，否则按条件表达式解析
+func (f *DocField) dependsOnFields() ([]string, *PermissionCondition, error) {
+	if f.DependsOn == "" {
+		return nil, nil, nil
+	}
+	if docFieldNamePattern.MatchString(f.DependsOn) {
+		return []string{f.DependsOn}, nil, nil
+	}
+	condition, err := ParsePermissionCondition(f.DependsOn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return condition.DocFields(), condition, nil
+}
+
+// 错误定义
+var (
+	ErrDocTypeNotFound  = errors.New("doctype not found")
+	ErrDocFieldNotFound = errors.New("doc field not found")
+	ErrDocFieldExists   = errors.New("doc field already exists")
+	ErrDocFieldInUse    = errors.New("doc field is in use")
+	ErrInvalidDocField  = errors.New("invalid doc field")
+)
+
+// DocFieldRepo 字段定义仓储接口，字段写入时同步字段权限级别
+type DocFieldRepo interface {
+	ListDocFields(ctx context.Context, docType string) ([]*DocField, error)
+	GetDocField(ctx context.Context, docType, fieldName string) (*DocField, error)
+	CreateDocField(ctx context.Context, field *DocField) (*DocField, error)
+	UpdateDocField(ctx context.Context, field *DocField) (*DocField, error)
+	DeleteDocField(ctx context.Context, docType, fieldName string) error
+
+	// SyncFieldPermissionLevels 按字段定义重写文档类型的字段权限级别，返回同步的字段数
+	SyncFieldPermissionLevels(ctx context.Context, docType string) (int, error)
+}
+
+// DocFieldUsecase 字段定义用例
+type DocFieldUsecase struct {
+	repo     DocFieldRepo
+	permRepo PermissionRepo
+	log      *log.Helper
+}
+
+// NewDocFieldUsecase 创建字段定义用例
+func NewDocFieldUsecase(repo DocFieldRepo, permRepo PermissionRepo, logger log.Logger) *DocFieldUsecase {
+	return &DocFieldUsecase{
+		repo:     repo,
+		permRepo: permRepo,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// ListFields 获取文档类型的字段定义，按显示顺序排列
+func (uc *DocFieldUsecase) ListFields(ctx context.Context, docType string) ([]*DocField, error) {
+	if _, err := uc.permRepo.GetDocType(ctx, docType); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListDocFields(ctx, docType)
+}
+
+// GetField 获取字段定义
+func (uc *DocFieldUsecase) GetField(ctx context.Context, docType, fieldName string) (*DocField, error) {
+	return uc.repo.GetDocField(ctx, docType, fieldName)
+}
+
+// CreateField 创建字段定义，未指定显示顺序时追加到末尾
+func (uc *DocFieldUsecase) CreateField(ctx context.Context, field *DocField) (*DocField, error) {
+	field.normalize()
+	if err := field.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocField, err)
+	}
+
+	fields, err := uc.ListFields(ctx, field.DocType)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range fields {
+		if existing.FieldName == field.FieldName {
+			return nil, ErrDocFieldExists
+		}
+	}
+
+	if err := uc.checkReferences(ctx, field, append(fields, field)); err != nil {
+		return nil, err
+	}
+
+	if field.Idx <= 0 {
+		field.Idx = len(fields) + 1
+	}
+	field.CreatedAt = time.Now()
+	field.UpdatedAt = time.Now()
+	return uc.repo.CreateDocField(ctx, field)
+}
+
+// UpdateField 更新字段定义，字段名称不可修改
+func (uc *DocFieldUsecase) UpdateField(ctx context.Context, field *DocField) (*DocField, error) {
+	existing, err := uc.repo.GetDocField(ctx, field.DocType, field.FieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	field.normalize()
+	if err := field.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocField, err)
+	}
+
+	fields, err := uc.repo.ListDocFields(ctx, field.DocType)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range fields {
+		if f.FieldName == field.FieldName {
+			fields[i] = field
+		}
+	}
+
+	if err := uc.checkReferences(ctx, field, fields); err != nil {
+		return nil, err
+	}
+
+	field.ID = existing.ID
+	field.CreatedAt = existing.CreatedAt
+	field.CreatedBy = existing.CreatedBy
+	if field.Idx <= 0 {
+		field.Idx = existing.Idx
+	}
+	field.UpdatedAt = time.Now()
+	return uc.repo.UpdateDocField(ctx, field)
+}
+
+// DeleteField 删除字段定义，仍被文档类型或其他字段引用时拒绝
+func (uc *DocFieldUsecase) DeleteField(ctx context.Context, docType, fieldName string) error {
+	if _, err := uc.repo.GetDocField(ctx, docType, fieldName); err != nil {
+		return err
+	}
+
+	dt, err := uc.permRepo.GetDocType(ctx, docType)
+	if err != nil {
+		return err
+	}
+	if dt.TitleField == fieldName || dt.SortField == fieldName {
+		return fmt.Errorf("%w: referenced by doctype %s title or sort field", ErrDocFieldInUse, docType)
+	}
+	for _, name := range dt.SearchFields {
+		if name == fieldName {
+			return fmt.Errorf("%w: referenced by doctype %s search fields", ErrDocFieldInUse, docType)
+		}
+	}
+	if rule, err := ParseNamingRule(dt.NamingRule); err == nil {
+		for _, name := range rule.Fields() {
+			if name == fieldName {
+				return fmt.Errorf("%w: referenced by doctype %s naming rule", ErrDocFieldInUse, docType)
+			}
+		}
+	}
+
+	fields, err := uc.repo.ListDocFields(ctx, docType)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.FieldName == fieldName {
+			continue
+		}
+		if f.FieldType == "Dynamic Link" && f.Options == fieldName {
+			return fmt.Errorf("%w: referenced by field %s options", ErrDocFieldInUse, f.FieldName)
+		}
+		refs, _, _ := f.dependsOnFields()
+		for _, ref := range refs {
+			if ref == fieldName {
+				return fmt.Errorf("%w: referenced by field %s depends_on", ErrDocFieldInUse, f.FieldName)
+			}
+		}
+	}
+
+	return uc.repo.DeleteDocField(ctx, docType, fieldName)
+}
+
+// SyncFieldPermissionLevels 按字段定义修复文档类型的字段权限级别
+func (uc *DocFieldUsecase) SyncFieldPermissionLevels(ctx context.Context, docType string) (int, error) {
+	if _, err := uc.permRepo.GetDocType(ctx, docType); err != nil {
+		return 0, err
+	}
+	return uc.repo.SyncFieldPermissionLevels(ctx, docType)
+}
+
+// ValidateDocTypeFields 检查文档类型的标题、搜索和排序字段是否为已定义字段或标准字段，命名规则引用的字段须已定义；
+// 尚未登记字段的文档类型不检查
+func (uc *DocFieldUsecase) ValidateDocTypeFields(ctx context.Context, docType *DocType) error {
+	fields, err := uc.repo.ListDocFields(ctx, docType.Name)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]*DocField, len(fields))
+	for _, f := range fields {
+		byName[f.FieldName] = f
+	}
+	check := func(role, name string) error {
+		if name == "" || isStandardDocField(name) {
+			return nil
+		}
+		f, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("%w: %s %s is not a field of %s", ErrInvalidDocField, role, name, docType.Name)
+		}
+		if f.FieldType == "Table" {
+			return fmt.Errorf("%w: %s %s cannot be a table field", ErrInvalidDocField, role, name)
+		}
+		return nil
+	}
+
+	if err := check("title field", docType.TitleField); err != nil {
+		return err
+	}
+	if err := check("sort field", docType.SortField); err != nil {
+		return err
+	}
+	for _, name := range docType.SearchFields {
+		if err := check("search field", name); err != nil {
+			return err
+		}
+	}
+
+	// 命名规则引用的字段须在创建文档时已有值，不能是标准字段
+	rule, err := ParseNamingRule(docType.NamingRule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDocField, err)
+	}
+	for _, name := range rule.Fields() {
+		if _, ok := byName[name]; !ok {
+			return fmt.Errorf("%w: naming rule field %s is not a field of %s", ErrInvalidDocField, name, docType.Name)
+		}
+		if err := check("naming rule field", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkReferences 检查字段引用的文档类型和同一文档类型中的其他字段
+func (uc *DocFieldUsecase) checkReferences(ctx context.Context, field *DocField, fields []*DocField) error {
+	byName := make(map[string]*DocField, len(fields))
+	fieldTypes := make(map[string]ConditionType, len(fields))
+	for _, f := range fields {
+		byName[f.FieldName] = f
+		fieldTypes[f.FieldName] = ConditionFieldType(f.FieldType)
+	}
+
+	switch field.FieldType {
+	case "Link", "Table":
+		target, err := uc.permRepo.GetDocType(ctx, field.Options)
+		if err != nil {
+			if errors.Is(err, ErrDocTypeNotFound) {
+				return fmt.Errorf("%w: %s field %s links to unknown doctype %s", ErrInvalidDocField, field.FieldType, field.FieldName, field.Options)
+			}
+			return err
+		}
+		if field.FieldType == "Table" && !target.IsChildTable {
+			return fmt.Errorf("%w: table field %s requires a child table doctype", ErrInvalidDocField, field.FieldName)
+		}
+	case "Dynamic Link":
+		// 动态链接的选项为同一文档类型中保存目标文档类型名称的字段
+		typeField, ok := byName[field.Options]
+		if !ok || (typeField.FieldType != "Link" && typeField.FieldType != "Select" && typeField.FieldType != "Data") {
+			return fmt.Errorf("%w: dynamic link field %s requires a doctype field in options", ErrInvalidDocField, field.FieldName)
+		}
+	}
+
+	// 字段类型变化可能使其他字段的显示依赖失效，逐个检查
+	for _, f := range fields {
+		if err := checkDependsOn(f, byName, fieldTypes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDependsOn 检查显示依赖引用的字段存在且表达式类型正确
+func checkDependsOn(field *DocField, byName map[string]*DocField, fieldTypes map[string]ConditionType) error {
+	refs, condition, err := field.dependsOnFields()
+	if err != nil {
+		return fmt.Errorf("%w: depends_on of %s: %v", ErrInvalidDocField, field.FieldName, err)
+	}
+	for _, ref := range refs {
+		if ref == field.FieldName {
+			return fmt.Errorf("%w: field %s cannot depend on itself", ErrInvalidDocField, field.FieldName)
+		}
+		if _, ok := byName[ref]; !ok && !isStandardDocField(ref) {
+			return fmt.Errorf("%w: depends_on of %s refers to unknown field %s", ErrInvalidDocField, field.FieldName, ref)
+		}
+	}
+	if condition != nil {
+		if err := condition.Check(fieldTypes); err != nil {
+			return fmt.Errorf("%w: depends_on of %s: %v", ErrInvalidDocField, field.FieldName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocField_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   biz.DocField
+		wantErr bool
+	}{
+		{"valid data field", biz.DocField{DocType: "Order", FieldName: "customer_name", Label: "客户名称", FieldType: "Data"}, false},
+		{"valid select with default", biz.DocField{DocType: "Order", FieldName: "status", Label: "状态", FieldType: "Select", Options: "Draft\nOpen\n\nClosed", DefaultValue: "Open"}, false},
+		{"invalid name", biz.DocField{DocType: "Order", FieldName: "Customer Name", Label: "客户", FieldType: "Data"}, true},
+		{"standard field", biz.DocField{DocType: "Order", FieldName: "created_at", Label: "创建时间", FieldType: "Datetime"}, true},
+		{"invalid type", biz.DocField{DocType: "Order", FieldName: "amount", Label: "金额", FieldType: "Money"}, true},
+		{"select without options", biz.DocField{DocType: "Order", FieldName: "status", Label: "状态", FieldType: "Select"}, true},
+		{"select default not in options", biz.DocField{DocType: "Order", FieldName: "status", Label: "状态", FieldType: "Select", Options: "Draft\nOpen", DefaultValue: "Closed"}, true},
+		{"link without target", biz.DocField{DocType: "Order", FieldName: "customer", Label: "客户", FieldType: "Link"}, true},
+		{"unique check", biz.DocField{DocType: "Order", FieldName: "is_paid", Label: "已付款", FieldType: "Check", IsUnique: true}, true},
+		{"invalid currency default", biz.DocField{DocType: "Order", FieldName: "amount", Label: "金额", FieldType: "Currency", DefaultValue: "abc"}, true},
+		{"permission level out of range", biz.DocField{DocType: "Order", FieldName: "amount", Label: "金额", FieldType: "Currency", PermissionLevel: 10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.field.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	field := &biz.DocField{DocType: "Order", FieldName: "amount", Label: "金额", FieldType: "Currency", PermissionLevel: 2, IsMandatory: true}
+	level := field.FieldPermissionLevel()
+	assert.Equal(t, "金额", level.FieldLabel)
+	assert.Equal(t, 2, level.PermissionLevel)
+	assert.NoError(t, level.Validate())
+}
+
+// stubDocFieldRepo 在内存中保存字段定义
+type stubDocFieldRepo struct {
+	fields []*biz.DocField
+}
+
+func (r *stubDocFieldRepo) ListDocFields(ctx context.Context, docType string) ([]*biz.DocField, error) {
+	var fields []*biz.DocField
+	for _, f := range r.fields {
+		if f.DocType == docType {
+			copied := *f
+			fields = append(fields, &copied)
+		}
+	}
+	return fields, nil
+}
+
+func (r *stubDocFieldRepo) GetDocField(ctx context.Context, docType, fieldName string) (*biz.DocField, error) {
+	for _, f := range r.fields {
+		if f.DocType == docType && f.FieldName == fieldName {
+			return f, nil
+		}
+	}
+	return nil, biz.ErrDocFieldNotFound
+}
+
+func (r *stubDocFieldRepo) CreateDocField(ctx context.Context, field *biz.DocField) (*biz.DocField, error) {
+	field.ID = int64(len(r.fields) + 1)
+	r.fields = append(r.fields, field)
+	return field, nil
+}
+
+func (r *stubDocFieldRepo) UpdateDocField(ctx context.Context, field *biz.DocField) (*biz.DocField, error) {
+	for i, f := range r.fields {
+		if f.DocType == field.DocType && f.FieldName == field.FieldName {
+			r.fields[i] = field
+		}
+	}
+	return field, nil
+}
+
+func (r *stubDocFieldRepo) DeleteDocField(ctx context.Context, docType, fieldName string) error {
+	for i, f := range r.fields {
+		if f.DocType == docType && f.FieldName == fieldName {
+			r.fields = append(r.fields[:i], r.fields[i+1:]...)
+			return nil
+		}
+	}
+	return biz.ErrDocFieldNotFound
+}
+
+func (r *stubDocFieldRepo) SyncFieldPermissionLevels(ctx context.Context, docType string) (int, error) {
+	fields, _ := r.ListDocFields(ctx, docType)
+	return len(fields), nil
+}
+
+// stubDocTypePermissionRepo 仅实现字段定义所需的文档类型查询
+type stubDocTypePermissionRepo struct {
+	biz.PermissionRepo
+	docTypes map[string]*biz.DocType
+}
+
+func (r *stubDocTypePermissionRepo) GetDocType(ctx context.Context, name string) (*biz.DocType, error) {
+	if docType, ok := r.docTypes[name]; ok {
+		return docType, nil
+	}
+	return nil, biz.ErrDocTypeNotFound
+}
+
+func TestDocFieldUsecase(t *testing.T) {
+	repo := &stubDocFieldRepo{}
+	permRepo := &stubDocTypePermissionRepo{docTypes: map[string]*biz.DocType{
+		"Order":      {Name: "Order", TitleField: "customer_name", SearchFields: []string{"customer_name"}},
+		"Customer":   {Name: "Customer"},
+		"Order Item": {Name: "Order Item", IsChildTable: true},
+	}}
+	uc := biz.NewDocFieldUsecase(repo, permRepo, log.DefaultLogger)
+	ctx := context.Background()
+
+	_, err := uc.ListFields(ctx, "Invoice")
+	assert.ErrorIs(t, err, biz.ErrDocTypeNotFound)
+
+	// 标签和类型取默认值，顺序追加到末尾
+	field, err := uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "customer_name"})
+	assert.NoError(t, err)
+	assert.Equal(t, "customer_name", field.Label)
+	assert.Equal(t, "Data", field.FieldType)
+	assert.Equal(t, 1, field.Idx)
+
+	_, err = uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "customer_name"})
+	assert.ErrorIs(t, err, biz.ErrDocFieldExists)
+	_, err = uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "customer", FieldType: "Link", Options: "Supplier"})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocField)
+	_, err = uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "items", FieldType: "Table", Options: "Customer"})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocField)
+	_, err = uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "discount", FieldType: "Currency", DependsOn: "doc.amount > 0"})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocField)
+
+	_, err = uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "items", FieldType: "Table", Options: "Order Item"})
+	assert.NoError(t, err)
+	_, err = uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "amount", FieldType: "Currency", PermissionLevel: 1})
+	assert.NoError(t, err)
+	_, err = uc.CreateField(ctx, &biz.DocField{DocType: "Order", FieldName: "discount", FieldType: "Currency", DependsOn: "doc.amount > 1000"})
+	assert.NoError(t, err)
+
+	// 字段名称不可修改，类型检查按更新后的字段进行
+	updated, err := uc.UpdateField(ctx, &biz.DocField{DocType: "Order", FieldName: "amount", Label: "金额", FieldType: "Currency", PermissionLevel: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, updated.Idx)
+	_, err = uc.UpdateField(ctx, &biz.DocField{DocType: "Order", FieldName: "amount", FieldType: "Data"})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocField)
+	_, err = uc.UpdateField(ctx, &biz.DocField{DocType: "Order", FieldName: "total", FieldType: "Currency"})
+	assert.ErrorIs(t, err, biz.ErrDocFieldNotFound)
+
+	// 标题、搜索、排序字段须为已定义字段或标准字段
+	assert.NoError(t, uc.ValidateDocTypeFields(ctx, &biz.DocType{Name: "Order", TitleField: "customer_name", SearchFields: []string{"name", "amount"}, SortField: "updated_at"}))
+	assert.ErrorIs(t, uc.ValidateDocTypeFields(ctx, &biz.DocType{Name: "Order", TitleField: "customer"}), biz.ErrInvalidDocField)
+	assert.ErrorIs(t, uc.ValidateDocTypeFields(ctx, &biz.DocType{Name: "Order", SearchFields: []string{"items"}}), biz.ErrInvalidDocField)
+	assert.NoError(t, uc.ValidateDocTypeFields(ctx, &biz.DocType{Name: "Customer", TitleField: "customer_name"}))
+
+	// 仍被引用的字段不能删除
+	assert.ErrorIs(t, uc.DeleteField(ctx, "Order", "customer_name"), biz.ErrDocFieldInUse)
+	assert.ErrorIs(t, uc.DeleteField(ctx, "Order", "amount"), biz.ErrDocFieldInUse)
+	assert.NoError(t, uc.DeleteField(ctx, "Order", "discount"))
+	assert.NoError(t, uc.DeleteField(ctx, "Order", "amount"))
+	assert.ErrorIs(t, uc.DeleteField(ctx, "Order", "amount"), biz.ErrDocFieldNotFound)
+
+	synced, err := uc.SyncFieldPermissionLevels(ctx, "Order")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, synced)
+}
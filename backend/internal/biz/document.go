@@ -0,0 +1,1029 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 文档状态
+const (
+	DocStatusDraft     = 0 // 草稿
+	DocStatusSubmitted = 1 // 已提交
+	DocStatusCancelled = 2 // 已取消
+)
+
+// DocumentStateFields 文档读取时附带的状态字段，取自document_workflow_states，不能直接写入；
+// amended_from为修订来源文档的名称
+var DocumentStateFields = []string{"docstatus", "workflow_state", "amended_from"}
+
+// maxDocumentNameLength 文档名称的最大长度，与document_workflow_states.doc_name一致
+const maxDocumentNameLength = 100
+
+// 文档列表分页
+const (
+	defaultDocumentPageSize = 20
+	maxDocumentPageSize     = 500
+
+	// scopedDocumentScanPageSize 数据范围无法转换为SQL时，内存过滤每次读取的文档数
+	scopedDocumentScanPageSize = 500
+)
+
+// 错误定义
+var (
+	ErrDocumentNotFound         = errors.New("document not found")
+	ErrDocumentExists           = errors.New("document already exists")
+	ErrDocumentForbidden        = errors.New("document access forbidden")
+	ErrDocumentNotEditable      = errors.New("document is not editable")
+	ErrInvalidDocument          = errors.New("invalid document")
+	ErrDocTypeNotStorable       = errors.New("doctype has no document storage")
+	ErrDocumentTableConflict    = errors.New("document table name conflict")
+	ErrDocumentTableMigration   = errors.New("document table migration failed")
+	ErrScopeNotTranslatable     = errors.New("permission scope cannot be translated to sql")
+	ErrInvalidDocumentListQuery = errors.New("invalid document list query")
+)
+
+// DocumentTable 文档类型的数据表
+type DocumentTable struct {
+	DocType   string    `json:"doc_type"`
+	TableName string    `json:"table_name"`
+	Signature string    `json:"signature"` // 最近一次迁移时的字段结构签名
+	SyncedAt  time.Time `json:"synced_at"`
+}
+
+// DocumentMeta 文档类型、字段定义和数据表，仓储按此读写文档
+type DocumentMeta struct {
+	DocType *DocType
+	Fields  []*DocField
+	Table   *DocumentTable
+}
+
+// Field 按名称查找字段定义
+func (m *DocumentMeta) Field(name string) *DocField {
+	for _, field := range m.Fields {
+		if field.FieldName == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// DocumentTableName 由文档类型名称生成数据表名称：小写字母数字，其余字符转为下划线，
+// 含非ASCII字符或过长时追加名称哈希以避免冲突
+func DocumentTableName(docType string) string {
+	var b strings.Builder
+	lastUnderscore := true
+	ascii := true
+	for _, r := range strings.ToLower(docType) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			lastUnderscore = false
+		default:
+			if r > 127 {
+				ascii = false
+			}
+			if !lastUnderscore {
+				b.WriteByte('_')
+				lastUnderscore = true
+			}
+		}
+	}
+	slug := strings.Trim(b.String(), "_")
+
+	if !ascii || slug == "" || len(slug) > 50 {
+		h := fnv.New32a()
+		h.Write([]byte(docType))
+		if len(slug) > 41 {
+			slug = strings.TrimRight(slug[:41], "_")
+		}
+		if slug != "" {
+			slug += "_"
+		}
+		slug += fmt.Sprintf("%08x", h.Sum32())
+	}
+	return "doc_" + slug
+}
+
+// DocumentTableSignature 字段结构签名，由字段名称、类型和唯一性组成，与顺序无关
+func DocumentTableSignature(fields []*DocField) string {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		part := field.FieldName + ":" + field.FieldType
+		if field.IsUnique {
+			part += ":unique"
+		}
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// DocumentQuery 文档列表查询
+type DocumentQuery struct {
+	Filters map[string]interface{} // 字段等值过滤，值已按字段类型转换
+	Search  string                 // 在名称、标题和搜索字段中模糊匹配
+	OrderBy string
+	Order   string // asc 或 desc
+	Page    int32
+	Size    int32
+
+	// Scope 数据范围，无法转换为SQL时仓储返回ErrScopeNotTranslatable
+	Scope *PermissionScope
+	// SearchFields 参与模糊匹配的字段，由用例按文档类型填充
+	SearchFields []string
+}
+
+// DocumentList 文档列表
+type DocumentList struct {
+	Documents []map[string]interface{} `json:"documents"`
+	Total     int32                    `json:"total"`
+	Page      int32                    `json:"page"`
+	Size      int32                    `json:"size"`
+}
+
+// DocumentAccess 文档操作的访问控制，由服务层按当前用户构造
+type DocumentAccess struct {
+	UserID int64
+	// Scope 该操作的数据范围，nil表示不限制
+	Scope *PermissionScope
+	// GuardWrites 检查即将写入的字段，返回需要丢弃的字段
+	GuardWrites func(ctx context.Context, fields []string) ([]string, error)
+	// UnreadableFields 返回给定字段中无读权限的字段，列表不能按这些字段过滤、排序和搜索
+	UnreadableFields func(ctx context.Context, fields []string) ([]string, error)
+	// MaskFields 按字段权限级别过滤返回的文档字段
+	MaskFields func(ctx context.Context, docs []map[string]interface{}) ([]map[string]interface{}, error)
+}
+
+// match 文档是否在数据范围内
+func (a *DocumentAccess) match(doc map[string]interface{}) bool {
+	return a.Scope == nil || a.Scope.Match(doc)
+}
+
+// guard 过滤无写权限的字段
+func (a *DocumentAccess) guard(ctx context.Context, values map[string]interface{}) error {
+	if a.GuardWrites == nil || len(values) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	dropped, err := a.GuardWrites(ctx, fields)
+	if err != nil {
+		return err
+	}
+	for _, field := range dropped {
+		delete(values, field)
+	}
+	return nil
+}
+
+// unreadable 无读权限的字段集合
+func (a *DocumentAccess) unreadable(ctx context.Context, fields []string) (map[string]bool, error) {
+	if a.UnreadableFields == nil || len(fields) == 0 {
+		return nil, nil
+	}
+	hidden, err := a.UnreadableFields(ctx, fields)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(hidden))
+	for _, field := range hidden {
+		set[field] = true
+	}
+	return set, nil
+}
+
+// present 去除密码字段并按字段权限级别过滤
+func (a *DocumentAccess) present(ctx context.Context, meta *DocumentMeta, docs []map[string]interface{}) ([]map[string]interface{}, error) {
+	for _, field := range meta.Fields {
+		if field.FieldType == "Password" {
+			for _, doc := range docs {
+				delete(doc, field.FieldName)
+			}
+		}
+	}
+	if a.MaskFields == nil || len(docs) == 0 {
+		return docs, nil
+	}
+	return a.MaskFields(ctx, docs)
+}
+
+// DocumentRepo 通用文档仓储接口，数据表按字段定义动态创建；
+// 文档按上下文中的当前公司隔离，名称在公司内唯一
+type DocumentRepo interface {
+	// GetDocumentTable 获取文档类型的数据表登记，尚未创建时返回nil
+	GetDocumentTable(ctx context.Context, docType string) (*DocumentTable, error)
+	// SyncDocumentTable 按字段定义创建或迁移数据表：补充缺少的列、调整列类型和唯一索引，不删除列
+	SyncDocumentTable(ctx context.Context, meta *DocumentMeta, signature string) (*DocumentTable, error)
+
+	// InsertDocument 写入文档并创建草稿状态记录，doc["amended_from"]不为空时关联修订来源
+	InsertDocument(ctx context.Context, meta *DocumentMeta, doc map[string]interface{}, userID int64) (map[string]interface{}, error)
+	GetDocument(ctx context.Context, meta *DocumentMeta, name string) (map[string]interface{}, error)
+	DocumentExists(ctx context.Context, meta *DocumentMeta, name string) (bool, error)
+	// UpdateDocument 更新草稿文档的字段，文档已不是草稿时返回ErrDocumentNotEditable
+	UpdateDocument(ctx context.Context, meta *DocumentMeta, name string, changes map[string]interface{}, userID int64) (map[string]interface{}, error)
+	// TransitionDocument 变更文档状态，文档已不处于transition.From状态时返回ErrInvalidDocumentTransition
+	TransitionDocument(ctx context.Context, meta *DocumentMeta, name string, transition *DocumentTransition) (map[string]interface{}, error)
+	// DeleteDocument 删除文档及其状态记录
+	DeleteDocument(ctx context.Context, meta *DocumentMeta, name string) error
+	ListDocuments(ctx context.Context, meta *DocumentMeta, query *DocumentQuery) ([]map[string]interface{}, int32, error)
+}
+
+// DocumentUsecase 通用文档用例，按文档类型的字段定义校验和转换字段值，按命名规则生成文档名称
+type DocumentUsecase struct {
+	repo         DocumentRepo
+	fieldRepo    DocFieldRepo
+	permRepo     PermissionRepo
+	seriesRepo   NamingSeriesRepo
+	workflowRepo WorkflowRepo
+	approvalRepo ApprovalRepo
+	log          *log.Helper
+}
+
+// NewDocumentUsecase 创建通用文档用例
+func NewDocumentUsecase(repo DocumentRepo, fieldRepo DocFieldRepo, permRepo PermissionRepo, seriesRepo NamingSeriesRepo, workflowRepo WorkflowRepo, approvalRepo ApprovalRepo, logger log.Logger) *DocumentUsecase {
+	return &DocumentUsecase{
+		repo:         repo,
+		fieldRepo:    fieldRepo,
+		permRepo:     permRepo,
+		seriesRepo:   seriesRepo,
+		workflowRepo: workflowRepo,
+		approvalRepo: approvalRepo,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// SyncTable 按当前字段定义迁移文档类型的数据表
+func (uc *DocumentUsecase) SyncTable(ctx context.Context, docType string) (*DocumentTable, error) {
+	meta, err := uc.loadMeta(ctx, docType, false)
+	if err != nil {
+		return nil, err
+	}
+	return uc.syncTable(ctx, meta)
+}
+
+// CreateDocument 创建文档：补充默认值、转换字段类型并检查必填字段，文档需在数据范围内
+func (uc *DocumentUsecase) CreateDocument(ctx context.Context, docType string, values map[string]interface{}, access *DocumentAccess) (map[string]interface{}, error) {
+	meta, err := uc.loadMeta(ctx, docType, true)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := documentNameValue(values)
+	if err != nil {
+		return nil, err
+	}
+	fieldValues, err := uc.writableValues(meta, values)
+	if err != nil {
+		return nil, err
+	}
+	if err := access.guard(ctx, fieldValues); err != nil {
+		return nil, err
+	}
+
+	doc, err := uc.prepareValues(ctx, meta.Fields, fieldValues, true)
+	if err != nil {
+		return nil, err
+	}
+	doc["name"] = name
+	doc["docstatus"] = DocStatusDraft
+	if doc["workflow_state"], err = uc.initialWorkflowState(ctx, meta); err != nil {
+		return nil, err
+	}
+	// 新文档的创建者是当前用户，仅创建者可访问的规则据此授予创建
+	doc["created_by"] = access.UserID
+	if !access.match(doc) {
+		return nil, ErrDocumentForbidden
+	}
+
+	// 权限检查通过后再生成名称，避免无权限的请求消耗编号
+	if doc["name"], err = uc.newDocumentName(ctx, meta, doc, name); err != nil {
+		return nil, err
+	}
+
+	created, err := uc.repo.InsertDocument(ctx, meta, doc, access.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.present(ctx, meta, created, access)
+}
+
+// GetDocument 获取文档，数据范围外的文档视为无权访问
+func (uc *DocumentUsecase) GetDocument(ctx context.Context, docType, name string, access *DocumentAccess) (map[string]interface{}, error) {
+	meta, doc, err := uc.loadDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+	return uc.present(ctx, meta, doc, access)
+}
+
+// UpdateDocument 更新草稿文档，仅写入实际变化的字段；更新前后文档都需在数据范围内
+func (uc *DocumentUsecase) UpdateDocument(ctx context.Context, docType, name string, values map[string]interface{}, access *DocumentAccess) (map[string]interface{}, error) {
+	meta, existing, err := uc.loadDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+	if docStatusOf(existing) != DocStatusDraft {
+		return nil, fmt.Errorf("%w: only draft documents can be modified", ErrDocumentNotEditable)
+	}
+	if err := checkNotPendingApproval(existing); err != nil {
+		return nil, err
+	}
+
+	if newName, err := documentNameValue(values); err != nil {
+		return nil, err
+	} else if newName != "" && newName != name {
+		return nil, fmt.Errorf("%w: document name cannot be changed", ErrInvalidDocument)
+	}
+	fieldValues, err := uc.writableValues(meta, values)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := uc.prepareValues(ctx, meta.Fields, fieldValues, false)
+	if err != nil {
+		return nil, err
+	}
+	for field, value := range changes {
+		if documentValuesEqual(existing[field], value) {
+			delete(changes, field)
+		}
+	}
+	if err := access.guard(ctx, changes); err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return uc.present(ctx, meta, existing, access)
+	}
+
+	merged := make(map[string]interface{}, len(existing))
+	for field, value := range existing {
+		merged[field] = value
+	}
+	for field, value := range changes {
+		merged[field] = value
+	}
+	if err := checkMandatory(meta.Fields, merged); err != nil {
+		return nil, err
+	}
+	if !access.match(merged) {
+		return nil, ErrDocumentForbidden
+	}
+
+	updated, err := uc.repo.UpdateDocument(ctx, meta, name, changes, access.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.present(ctx, meta, updated, access)
+}
+
+// DeleteDocument 删除文档，已提交的文档需先取消
+func (uc *DocumentUsecase) DeleteDocument(ctx context.Context, docType, name string, access *DocumentAccess) error {
+	meta, existing, err := uc.loadDocument(ctx, docType, name, access)
+	if err != nil {
+		return err
+	}
+	if docStatusOf(existing) == DocStatusSubmitted {
+		return fmt.Errorf("%w: submitted documents must be cancelled before deletion", ErrDocumentNotEditable)
+	}
+	if err := checkNotPendingApproval(existing); err != nil {
+		return err
+	}
+	return uc.repo.DeleteDocument(ctx, meta, name)
+}
+
+// ListDocuments 分页获取数据范围内的文档；数据范围无法转换为SQL时在内存中过滤
+func (uc *DocumentUsecase) ListDocuments(ctx context.Context, docType string, query *DocumentQuery, access *DocumentAccess) (*DocumentList, error) {
+	meta, err := uc.loadMeta(ctx, docType, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.normalizeQuery(ctx, meta, query, access); err != nil {
+		return nil, err
+	}
+
+	list := &DocumentList{Documents: []map[string]interface{}{}, Page: query.Page, Size: query.Size}
+	if access.Scope != nil && access.Scope.Denied() {
+		return list, nil
+	}
+
+	query.Scope = access.Scope
+	docs, total, err := uc.repo.ListDocuments(ctx, meta, query)
+	if errors.Is(err, ErrScopeNotTranslatable) {
+		docs, total, err = uc.listScopedInMemory(ctx, meta, query, access)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err = access.present(ctx, meta, docs)
+	if err != nil {
+		return nil, err
+	}
+	list.Documents = docs
+	list.Total = total
+	return list, nil
+}
+
+// listScopedInMemory 不带数据范围分页读取全部文档并逐条匹配，只保留请求页内的文档
+func (uc *DocumentUsecase) listScopedInMemory(ctx context.Context, meta *DocumentMeta, query *DocumentQuery, access *DocumentAccess) ([]map[string]interface{}, int32, error) {
+	scan := *query
+	scan.Scope = nil
+	scan.Size = scopedDocumentScanPageSize
+
+	start := (query.Page - 1) * query.Size
+	end := start + query.Size
+	page := make([]map[string]interface{}, 0, query.Size)
+	var matched int32
+	for scan.Page = 1; ; scan.Page++ {
+		docs, _, err := uc.repo.ListDocuments(ctx, meta, &scan)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, doc := range docs {
+			if !access.match(doc) {
+				continue
+			}
+			if matched >= start && matched < end {
+				page = append(page, doc)
+			}
+			matched++
+		}
+		if len(docs) < scopedDocumentScanPageSize {
+			return page, matched, nil
+		}
+	}
+}
+
+// normalizeQuery 校验过滤和排序字段，转换过滤值并补充分页和搜索字段；
+// 无读权限的字段不能用于过滤和排序，也不参与搜索
+func (uc *DocumentUsecase) normalizeQuery(ctx context.Context, meta *DocumentMeta, query *DocumentQuery, access *DocumentAccess) error {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Size <= 0 {
+		query.Size = defaultDocumentPageSize
+	}
+	if query.Size > maxDocumentPageSize {
+		query.Size = maxDocumentPageSize
+	}
+
+	filters := make(map[string]interface{}, len(query.Filters))
+	for name, value := range query.Filters {
+		switch {
+		case name == "docstatus":
+			status, err := coerceInt(value)
+			if err != nil || status < DocStatusDraft || status > DocStatusCancelled {
+				return fmt.Errorf("%w: docstatus must be 0, 1 or 2", ErrInvalidDocumentListQuery)
+			}
+			filters[name] = status
+		case isStandardDocField(name) || isDocumentStateField(name):
+			filters[name] = value
+		default:
+			field := meta.Field(name)
+			if field == nil || !isFilterableField(field) {
+				return fmt.Errorf("%w: cannot filter by %s", ErrInvalidDocumentListQuery, name)
+			}
+			coerced, err := coerceDocValue(field, value)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidDocumentListQuery, err)
+			}
+			filters[name] = coerced
+		}
+	}
+	query.Filters = filters
+
+	requestedOrder := query.OrderBy != ""
+	if !requestedOrder {
+		query.OrderBy, query.Order = meta.DocType.SortField, meta.DocType.SortOrder
+	}
+	if query.OrderBy == "" {
+		query.OrderBy = "updated_at"
+	}
+	if field := meta.Field(query.OrderBy); !isStandardDocField(query.OrderBy) && query.OrderBy != "docstatus" &&
+		(field == nil || !isFilterableField(field)) {
+		return fmt.Errorf("%w: cannot order by %s", ErrInvalidDocumentListQuery, query.OrderBy)
+	}
+	query.Order = strings.ToLower(query.Order)
+	if query.Order != "asc" {
+		query.Order = "desc"
+	}
+
+	searchFields := []string{"name"}
+	for _, name := range append([]string{meta.DocType.TitleField}, meta.DocType.SearchFields...) {
+		if field := meta.Field(name); field != nil && isFilterableField(field) && field.FieldType != "Password" {
+			searchFields = append(searchFields, name)
+		}
+	}
+
+	checked := append([]string{query.OrderBy}, searchFields...)
+	for name := range filters {
+		checked = append(checked, name)
+	}
+	hidden, err := access.unreadable(ctx, checked)
+	if err != nil {
+		return err
+	}
+	for name := range filters {
+		if hidden[name] {
+			return fmt.Errorf("%w: cannot filter by %s", ErrInvalidDocumentListQuery, name)
+		}
+	}
+	if hidden[query.OrderBy] {
+		// 文档类型默认的排序字段不可读时退回按更新时间排序
+		if requestedOrder {
+			return fmt.Errorf("%w: cannot order by %s", ErrInvalidDocumentListQuery, query.OrderBy)
+		}
+		query.OrderBy, query.Order = "updated_at", "desc"
+	}
+
+	query.Search = strings.TrimSpace(query.Search)
+	query.SearchFields = make([]string, 0, len(searchFields))
+	for _, name := range searchFields {
+		if !hidden[name] {
+			query.SearchFields = append(query.SearchFields, name)
+		}
+	}
+	return nil
+}
+
+// loadDocument 加载文档元数据和文档，并检查数据范围
+func (uc *DocumentUsecase) loadDocument(ctx context.Context, docType, name string, access *DocumentAccess) (*DocumentMeta, map[string]interface{}, error) {
+	meta, err := uc.loadMeta(ctx, docType, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := uc.repo.GetDocument(ctx, meta, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !access.match(doc) {
+		return nil, nil, ErrDocumentForbidden
+	}
+	return meta, doc, nil
+}
+
+// loadMeta 加载文档类型和字段定义；ensureTable为true时在字段结构变化后自动迁移数据表
+func (uc *DocumentUsecase) loadMeta(ctx context.Context, docType string, ensureTable bool) (*DocumentMeta, error) {
+	dt, err := uc.permRepo.GetDocType(ctx, docType)
+	if err != nil {
+		return nil, err
+	}
+	if dt.IsChildTable {
+		return nil, fmt.Errorf("%w: %s is a child table, access it through its parent", ErrDocTypeNotStorable, docType)
+	}
+
+	fields, err := uc.fieldRepo.ListDocFields(ctx, docType)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: %s has no fields defined", ErrDocTypeNotStorable, docType)
+	}
+
+	meta := &DocumentMeta{DocType: dt, Fields: fields}
+	if !ensureTable {
+		return meta, nil
+	}
+
+	table, err := uc.repo.GetDocumentTable(ctx, docType)
+	if err != nil {
+		return nil, err
+	}
+	if table != nil && table.Signature == DocumentTableSignature(fields) {
+		meta.Table = table
+		return meta, nil
+	}
+	if _, err := uc.syncTable(ctx, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// activeWorkflow 获取文档类型启用中的工作流，没有时返回nil
+func (uc *DocumentUsecase) activeWorkflow(ctx context.Context, docType string) (*Workflow, error) {
+	workflow, err := uc.workflowRepo.GetWorkflow(ctx, docType)
+	if errors.Is(err, ErrWorkflowNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !workflow.IsActive {
+		return nil, nil
+	}
+	return workflow, nil
+}
+
+// activeApprovalChain 获取文档类型启用中的审批链，没有时返回nil
+func (uc *DocumentUsecase) activeApprovalChain(ctx context.Context, docType string) (*ApprovalChain, error) {
+	chain, err := uc.approvalRepo.GetApprovalChain(ctx, docType)
+	if errors.Is(err, ErrApprovalChainNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !chain.IsActive {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// initialWorkflowState 新建文档的工作流状态：启用工作流时为其初始状态，否则为Draft
+func (uc *DocumentUsecase) initialWorkflowState(ctx context.Context, meta *DocumentMeta) (string, error) {
+	workflow, err := uc.activeWorkflow(ctx, meta.DocType.Name)
+	if err != nil {
+		return "", err
+	}
+	if workflow == nil {
+		return WorkflowStateDraft, nil
+	}
+	return workflow.States[0].State, nil
+}
+
+// syncTable 迁移数据表并记录到元数据
+func (uc *DocumentUsecase) syncTable(ctx context.Context, meta *DocumentMeta) (*DocumentTable, error) {
+	meta.Table = &DocumentTable{DocType: meta.DocType.Name, TableName: DocumentTableName(meta.DocType.Name)}
+	table, err := uc.repo.SyncDocumentTable(ctx, meta, DocumentTableSignature(meta.Fields))
+	if err != nil {
+		return nil, err
+	}
+	meta.Table = table
+	uc.log.Infof("Document table synced: %s -> %s", table.DocType, table.TableName)
+	return table, nil
+}
+
+// present 准备返回给调用方的文档
+func (uc *DocumentUsecase) present(ctx context.Context, meta *DocumentMeta, doc map[string]interface{}, access *DocumentAccess) (map[string]interface{}, error) {
+	docs, err := access.present(ctx, meta, []map[string]interface{}{doc})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return docs[0], nil
+}
+
+// writableValues 拆出字段值，拒绝未定义的字段；标准字段和状态字段由系统维护，忽略传入值
+func (uc *DocumentUsecase) writableValues(meta *DocumentMeta, values map[string]interface{}) (map[string]interface{}, error) {
+	fieldValues := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		if isStandardDocField(name) || isDocumentStateField(name) {
+			continue
+		}
+		if meta.Field(name) == nil {
+			return nil, fmt.Errorf("%w: unknown field %s", ErrInvalidDocument, name)
+		}
+		fieldValues[name] = value
+	}
+	return fieldValues, nil
+}
+
+// prepareValues 按字段类型转换字段值；创建时补充默认值并检查必填字段
+func (uc *DocumentUsecase) prepareValues(ctx context.Context, fields []*DocField, values map[string]interface{}, create bool) (map[string]interface{}, error) {
+	prepared := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, ok := values[field.FieldName]
+		if !ok {
+			if !create {
+				continue
+			}
+			defaultValue, err := field.defaultDocValue()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+			}
+			value = defaultValue
+		}
+
+		if field.FieldType == "Table" {
+			rows, err := uc.prepareChildRows(ctx, field, value)
+			if err != nil {
+				return nil, err
+			}
+			prepared[field.FieldName] = rows
+			continue
+		}
+
+		coerced, err := coerceDocValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+		}
+		prepared[field.FieldName] = coerced
+	}
+
+	if create {
+		if err := checkMandatory(fields, prepared); err != nil {
+			return nil, err
+		}
+	}
+	return prepared, nil
+}
+
+// prepareChildRows 按子表文档类型的字段定义校验子表行，行以数组形式保存在父文档中
+func (uc *DocumentUsecase) prepareChildRows(ctx context.Context, field *DocField, value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{}, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: table field %s must be an array", ErrInvalidDocument, field.FieldName)
+	}
+
+	childFields, err := uc.fieldRepo.ListDocFields(ctx, field.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]interface{}, 0, len(items))
+	for i, item := range items {
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: row %d of %s must be an object", ErrInvalidDocument, i+1, field.FieldName)
+		}
+		if len(childFields) == 0 {
+			// 子表尚未登记字段时按原样保存
+			rows = append(rows, values)
+			continue
+		}
+
+		for name := range values {
+			if name != "idx" && !containsDocField(childFields, name) {
+				return nil, fmt.Errorf("%w: unknown field %s in row %d of %s", ErrInvalidDocument, name, i+1, field.FieldName)
+			}
+		}
+		delete(values, "idx")
+		row, err := uc.prepareValues(ctx, childFields, values, true)
+		if err != nil {
+			return nil, fmt.Errorf("row %d of %s: %w", i+1, field.FieldName, err)
+		}
+		row["idx"] = i + 1
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// checkNotPendingApproval 审批中的文档已锁定，撤回审批后才能修改或删除
+func checkNotPendingApproval(doc map[string]interface{}) error {
+	if state, _ := doc["workflow_state"].(string); state == WorkflowStatePendingApproval {
+		return fmt.Errorf("%w: document is pending approval", ErrDocumentNotEditable)
+	}
+	return nil
+}
+
+// checkMandatory 检查必填字段
+func checkMandatory(fields []*DocField, doc map[string]interface{}) error {
+	var missing []string
+	for _, field := range fields {
+		if !field.IsMandatory {
+			continue
+		}
+		value := doc[field.FieldName]
+		if rows, ok := value.([]interface{}); value == nil || (ok && len(rows) == 0) {
+			missing = append(missing, field.FieldName)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: mandatory fields missing: %s", ErrInvalidDocument, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// defaultDocValue 字段默认值，日期支持Today，日期时间支持Now，复选框默认为false
+func (f *DocField) defaultDocValue() (interface{}, error) {
+	switch {
+	case f.FieldType == "Check" && f.DefaultValue == "":
+		return false, nil
+	case f.DefaultValue == "":
+		return nil, nil
+	case f.FieldType == "Date" && f.DefaultValue == "Today":
+		return time.Now().Format("2006-01-02"), nil
+	case f.FieldType == "Datetime" && f.DefaultValue == "Now":
+		return time.Now(), nil
+	}
+	return coerceDocValue(f, f.DefaultValue)
+}
+
+// coerceDocValue 将JSON值转换为字段类型对应的值，空字符串视为空值
+func coerceDocValue(field *DocField, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if s, ok := value.(string); ok && strings.TrimSpace(s) == "" && field.FieldType != "Check" {
+		return nil, nil
+	}
+
+	switch field.FieldType {
+	case "Int", "Rating":
+		n, err := coerceInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer", field.FieldName)
+		}
+		return int64(n), nil
+	case "Float", "Currency", "Percent", "Duration":
+		n, err := coerceFloat(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", field.FieldName)
+		}
+		return n, nil
+	case "Check":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+		case string:
+			if v == "0" || v == "1" || v == "" {
+				return v == "1", nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be a boolean", field.FieldName)
+	case "Date":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a date string", field.FieldName)
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", field.FieldName)
+		}
+		return s, nil
+	case "Datetime":
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+				if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+					return t, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%s must be a datetime in RFC3339 or YYYY-MM-DD HH:MM:SS format", field.FieldName)
+	case "Time":
+		s, ok := value.(string)
+		if ok {
+			for _, layout := range []string{"15:04:05", "15:04"} {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t.Format("15:04:05"), nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%s must be a time in HH:MM[:SS] format", field.FieldName)
+	case "Select":
+		s, ok := value.(string)
+		if ok {
+			for _, option := range field.SelectOptions() {
+				if option == s {
+					return s, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of: %s", field.FieldName, strings.Join(field.SelectOptions(), ", "))
+	case "Geolocation":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%s must be a GeoJSON object", field.FieldName)
+		}
+		return value, nil
+	case "Table":
+		if _, ok := value.([]interface{}); !ok {
+			return nil, fmt.Errorf("%s must be an array", field.FieldName)
+		}
+		return value, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", field.FieldName)
+	}
+	return s, nil
+}
+
+// coerceInt 将JSON数字或数字字符串转换为整数
+func coerceInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("not an integer: %v", v)
+		}
+		return int(v), nil
+	case string:
+		return strconv.Atoi(strings.TrimSpace(v))
+	}
+	return 0, fmt.Errorf("not an integer: %v", value)
+}
+
+// coerceFloat 将JSON数字或数字字符串转换为浮点数
+func coerceFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", value)
+}
+
+// documentNameValue 取出请求中的文档名称
+func documentNameValue(values map[string]interface{}) (string, error) {
+	raw, ok := values["name"]
+	if !ok || raw == nil {
+		return "", nil
+	}
+	name, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: name must be a string", ErrInvalidDocument)
+	}
+	if strings.TrimSpace(name) == "" {
+		return "", nil
+	}
+	return checkDocumentName(name)
+}
+
+// newDocumentHashName 生成10位随机十六进制名称
+func newDocumentHashName() string {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// docStatusOf 读取文档状态
+func docStatusOf(doc map[string]interface{}) int {
+	status, err := coerceInt(doc["docstatus"])
+	if err != nil {
+		return DocStatusDraft
+	}
+	return status
+}
+
+// documentValuesEqual 比较字段值是否相同，时间按时刻比较，数字按数值比较，其余按格式化结果比较
+func documentValuesEqual(a, b interface{}) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	_, aString := a.(string)
+	_, bString := b.(string)
+	if !aString && !bString {
+		if fa, err := coerceFloat(a); err == nil {
+			if fb, err := coerceFloat(b); err == nil {
+				return fa == fb
+			}
+		}
+	}
+	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+}
+
+// isDocumentStateField 判断是否为文档状态字段
+func isDocumentStateField(name string) bool {
+	for _, state := range DocumentStateFields {
+		if name == state {
+			return true
+		}
+	}
+	return false
+}
+
+// isFilterableField 子表和地理位置字段不能用于过滤和排序
+func isFilterableField(field *DocField) bool {
+	return field.FieldType != "Table" && field.FieldType != "Geolocation"
+}
+
+// containsDocField 判断字段定义中是否有指定字段
+func containsDocField(fields []*DocField, name string) bool {
+	for _, field := range fields {
+		if field.FieldName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 文档生命周期对应的工作流状态名称
+const (
+	WorkflowStateDraft     = "Draft"
+	WorkflowStateSubmitted = "Submitted"
+	WorkflowStateCancelled = "Cancelled"
+)
+
+// maxCancelReasonLength 取消原因的最大长度
+const maxCancelReasonLength = 500
+
+// 错误定义
+var (
+	ErrDocTypeNotSubmittable     = errors.New("doctype is not submittable")
+	ErrInvalidDocumentTransition = errors.New("invalid document status transition")
+)
+
+// amendedNamePattern 修订版本名称的序号后缀
+var amendedNamePattern = regexp.MustCompile(`^(.+)-([0-9]+)$`)
+
+// DocumentTransition 文档状态变更，仓储仅在文档仍处于From状态时写入；
+// FromState不为空时还要求工作流状态未被他人改变
+type DocumentTransition struct {
+	From          int
+	FromState     string
+	To            int
+	WorkflowState string
+	UserID        int64
+	// Reason 取消原因，仅取消时记录
+	Reason string
+}
+
+// SubmitDocument 提交草稿文档，提交前重新检查必填字段；提交后文档不可修改。
+// 启用工作流的文档类型只能通过工作流转换提交，启用审批链的文档类型审批通过后自动提交
+func (uc *DocumentUsecase) SubmitDocument(ctx context.Context, docType, name string, access *DocumentAccess) (map[string]interface{}, error) {
+	meta, doc, err := uc.loadSubmittable(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkNoWorkflow(ctx, docType); err != nil {
+		return nil, err
+	}
+	if err := uc.checkNoApprovalChain(ctx, docType); err != nil {
+		return nil, err
+	}
+	if status := docStatusOf(doc); status != DocStatusDraft {
+		return nil, fmt.Errorf("%w: only draft documents can be submitted, current docstatus is %d", ErrInvalidDocumentTransition, status)
+	}
+	if err := checkMandatory(meta.Fields, doc); err != nil {
+		return nil, err
+	}
+
+	submitted, err := uc.repo.TransitionDocument(ctx, meta, name, &DocumentTransition{
+		From:          DocStatusDraft,
+		To:            DocStatusSubmitted,
+		WorkflowState: WorkflowStateSubmitted,
+		UserID:        access.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.present(ctx, meta, submitted, access)
+}
+
+// CancelDocument 取消已提交的文档并记录取消原因；取消后的文档只能修订或删除。
+// 启用工作流的文档类型只能通过工作流转换取消
+func (uc *DocumentUsecase) CancelDocument(ctx context.Context, docType, name, reason string, access *DocumentAccess) (map[string]interface{}, error) {
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > maxCancelReasonLength {
+		return nil, fmt.Errorf("%w: cancel reason must be at most %d characters", ErrInvalidDocument, maxCancelReasonLength)
+	}
+
+	meta, doc, err := uc.loadSubmittable(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkNoWorkflow(ctx, docType); err != nil {
+		return nil, err
+	}
+	if status := docStatusOf(doc); status != DocStatusSubmitted {
+		return nil, fmt.Errorf("%w: only submitted documents can be cancelled, current docstatus is %d", ErrInvalidDocumentTransition, status)
+	}
+
+	cancelled, err := uc.repo.TransitionDocument(ctx, meta, name, &DocumentTransition{
+		From:          DocStatusSubmitted,
+		To:            DocStatusCancelled,
+		WorkflowState: WorkflowStateCancelled,
+		UserID:        access.UserID,
+		Reason:        reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.present(ctx, meta, cancelled, access)
+}
+
+// AmendDocument 由已取消的文档创建修订草稿，名称为原名称加序号后缀（-1、-2…）；
+// 唯一字段仍被原文档占用，不复制到修订版本，需在提交前重新填写
+func (uc *DocumentUsecase) AmendDocument(ctx context.Context, docType, name string, access *DocumentAccess) (map[string]interface{}, error) {
+	meta, source, err := uc.loadSubmittable(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+	if status := docStatusOf(source); status != DocStatusCancelled {
+		return nil, fmt.Errorf("%w: only cancelled documents can be amended, current docstatus is %d", ErrInvalidDocumentTransition, status)
+	}
+
+	doc := make(map[string]interface{}, len(meta.Fields)+4)
+	for _, field := range meta.Fields {
+		if value, ok := source[field.FieldName]; ok && !field.IsUnique {
+			doc[field.FieldName] = value
+		}
+	}
+	doc["docstatus"] = DocStatusDraft
+	if doc["workflow_state"], err = uc.initialWorkflowState(ctx, meta); err != nil {
+		return nil, err
+	}
+	doc["amended_from"] = name
+	if !access.match(doc) {
+		return nil, ErrDocumentForbidden
+	}
+
+	base, next := amendedNameBase(name, source["amended_from"])
+	if doc["name"], err = uc.retryName(ctx, meta, func() (string, error) {
+		amended := base + "-" + strconv.Itoa(next)
+		next++
+		return checkDocumentName(amended)
+	}); err != nil {
+		return nil, err
+	}
+
+	created, err := uc.repo.InsertDocument(ctx, meta, doc, access.UserID)
+	if err != nil {
+		return nil, err
+	}
+	uc.log.Infof("Document amended: %s %s -> %v", docType, name, doc["name"])
+	return uc.present(ctx, meta, created, access)
+}
+
+// loadSubmittable 加载文档并检查文档类型支持提交
+func (uc *DocumentUsecase) loadSubmittable(ctx context.Context, docType, name string, access *DocumentAccess) (*DocumentMeta, map[string]interface{}, error) {
+	meta, doc, err := uc.loadDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !meta.DocType.IsSubmittable {
+		return nil, nil, fmt.Errorf("%w: %s", ErrDocTypeNotSubmittable, docType)
+	}
+	return meta, doc, nil
+}
+
+// checkNoWorkflow 启用工作流时文档状态只能由工作流转换改变
+func (uc *DocumentUsecase) checkNoWorkflow(ctx context.Context, docType string) error {
+	workflow, err := uc.activeWorkflow(ctx, docType)
+	if err != nil {
+		return err
+	}
+	if workflow != nil {
+		return fmt.Errorf("%w: use the actions of workflow %s", ErrDocumentUnderWorkflow, workflow.Name)
+	}
+	return nil
+}
+
+// checkNoApprovalChain 启用审批链时文档只能通过审批提交
+func (uc *DocumentUsecase) checkNoApprovalChain(ctx context.Context, docType string) error {
+	chain, err := uc.activeApprovalChain(ctx, docType)
+	if err != nil {
+		return err
+	}
+	if chain != nil {
+		return fmt.Errorf("%w: request approval of chain %s", ErrDocumentUnderApproval, chain.Name)
+	}
+	return nil
+}
+
+// amendedNameBase 修订名称的基础名称和起始序号：原文档本身是修订版本时去掉其序号后缀并递增
+func amendedNameBase(name string, amendedFrom interface{}) (string, int) {
+	if from, _ := amendedFrom.(string); from != "" {
+		if match := amendedNamePattern.FindStringSubmatch(name); match != nil {
+			if n, err := strconv.Atoi(match[2]); err == nil {
+				return match[1], n + 1
+			}
+		}
+	}
+	return name, 1
+}
@@ -0,0 +1,97 @@
+package biz_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentUsecase_Lifecycle(t *testing.T) {
+	fieldRepo := &stubDocFieldRepo{fields: []*biz.DocField{
+		{DocType: "Order", FieldName: "customer", FieldType: "Data", IsMandatory: true},
+		{DocType: "Order", FieldName: "po_number", FieldType: "Data", IsUnique: true},
+		{DocType: "Order", FieldName: "amount", FieldType: "Currency"},
+		{DocType: "Note", FieldName: "content", FieldType: "Text"},
+	}}
+	docTypes := map[string]*biz.DocType{
+		"Order": {Name: "Order", IsSubmittable: true, NamingRule: "prompt"},
+		"Note":  {Name: "Note"},
+	}
+	repo := &stubDocumentRepo{}
+	uc := biz.NewDocumentUsecase(repo, fieldRepo, &stubDocTypePermissionRepo{docTypes: docTypes}, &stubNamingSeriesRepo{}, &stubWorkflowRepo{}, &stubApprovalRepo{}, log.DefaultLogger)
+	ctx := context.Background()
+	access := &biz.DocumentAccess{UserID: 3}
+
+	_, err := uc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-001", "customer": "ACME", "po_number": "PO-9", "amount": 100.0}, access)
+	assert.NoError(t, err)
+
+	// 只有已提交的文档可以取消，只有已取消的文档可以修订
+	_, err = uc.CancelDocument(ctx, "Order", "SO-001", "", access)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocumentTransition)
+	_, err = uc.AmendDocument(ctx, "Order", "SO-001", access)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocumentTransition)
+
+	submitted, err := uc.SubmitDocument(ctx, "Order", "SO-001", access)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.DocStatusSubmitted, submitted["docstatus"])
+	assert.Equal(t, biz.WorkflowStateSubmitted, submitted["workflow_state"])
+
+	// 已提交的文档不可修改、删除或再次提交
+	_, err = uc.SubmitDocument(ctx, "Order", "SO-001", access)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocumentTransition)
+	_, err = uc.UpdateDocument(ctx, "Order", "SO-001", map[string]interface{}{"amount": 50.0}, access)
+	assert.ErrorIs(t, err, biz.ErrDocumentNotEditable)
+	assert.ErrorIs(t, uc.DeleteDocument(ctx, "Order", "SO-001", access), biz.ErrDocumentNotEditable)
+
+	_, err = uc.CancelDocument(ctx, "Order", "SO-001", strings.Repeat("x", 501), access)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+	cancelled, err := uc.CancelDocument(ctx, "Order", "SO-001", " 客户撤单 ", access)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.DocStatusCancelled, cancelled["docstatus"])
+	assert.Equal(t, "客户撤单", cancelled["cancel_reason"])
+	_, err = uc.UpdateDocument(ctx, "Order", "SO-001", map[string]interface{}{"amount": 50.0}, access)
+	assert.ErrorIs(t, err, biz.ErrDocumentNotEditable)
+
+	// 修订版本复制字段值但不复制唯一字段，名称追加序号
+	amended, err := uc.AmendDocument(ctx, "Order", "SO-001", access)
+	assert.NoError(t, err)
+	assert.Equal(t, "SO-001-1", amended["name"])
+	assert.Equal(t, "SO-001", amended["amended_from"])
+	assert.Equal(t, biz.DocStatusDraft, amended["docstatus"])
+	assert.Equal(t, "ACME", amended["customer"])
+	assert.Equal(t, 100.0, amended["amount"])
+	assert.Nil(t, amended["po_number"])
+
+	// 再次修订原文档时跳过已占用的名称
+	again, err := uc.AmendDocument(ctx, "Order", "SO-001", access)
+	assert.NoError(t, err)
+	assert.Equal(t, "SO-001-2", again["name"])
+	assert.NoError(t, uc.DeleteDocument(ctx, "Order", "SO-001-2", access))
+
+	// 修订版本的修订版本递增序号而不是叠加后缀
+	_, err = uc.SubmitDocument(ctx, "Order", "SO-001-1", access)
+	assert.NoError(t, err)
+	_, err = uc.CancelDocument(ctx, "Order", "SO-001-1", "", access)
+	assert.NoError(t, err)
+	amended, err = uc.AmendDocument(ctx, "Order", "SO-001-1", access)
+	assert.NoError(t, err)
+	assert.Equal(t, "SO-001-2", amended["name"])
+	assert.Equal(t, "SO-001-1", amended["amended_from"])
+
+	// 提交前重新检查必填字段
+	_, err = uc.UpdateDocument(ctx, "Order", "SO-001-2", map[string]interface{}{"customer": ""}, access)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+
+	// 不支持提交的文档类型
+	_, err = uc.CreateDocument(ctx, "Note", map[string]interface{}{"name": "N1", "content": "x"}, access)
+	assert.NoError(t, err)
+	_, err = uc.SubmitDocument(ctx, "Note", "N1", access)
+	assert.ErrorIs(t, err, biz.ErrDocTypeNotSubmittable)
+
+	assert.Error(t, (&biz.DocField{DocType: "Order", FieldName: "amended_from", Label: "Amended From", FieldType: "Data"}).Validate())
+}
@@ -0,0 +1,397 @@
+package biz_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentTableName(t *testing.T) {
+	assert.Equal(t, "doc_purchase_order", biz.DocumentTableName("Purchase Order"))
+	assert.Equal(t, "doc_sales_invoice_item", biz.DocumentTableName("  Sales-Invoice  Item"))
+	assert.Regexp(t, `^doc_[0-9a-f]{8}$`, biz.DocumentTableName("采购订单"))
+	assert.Regexp(t, `^doc_order_[0-9a-f]{8}$`, biz.DocumentTableName("Order 订单"))
+	assert.NotEqual(t, biz.DocumentTableName("采购订单"), biz.DocumentTableName("销售订单"))
+	assert.LessOrEqual(t, len(biz.DocumentTableName(strings.Repeat("Long Name ", 10))), 63)
+
+	fields := []*biz.DocField{
+		{FieldName: "title", FieldType: "Data", IsUnique: true},
+		{FieldName: "amount", FieldType: "Currency"},
+	}
+	reordered := []*biz.DocField{fields[1], fields[0]}
+	assert.Equal(t, "amount:Currency,title:Data:unique", biz.DocumentTableSignature(fields))
+	assert.Equal(t, biz.DocumentTableSignature(fields), biz.DocumentTableSignature(reordered))
+}
+
+// stubDocumentColumn 与文档仓储一致：子表和未知字段无法翻译为SQL
+func stubDocumentColumn(meta *biz.DocumentMeta) biz.ConditionColumn {
+	return func(name string, hint biz.ConditionType) string {
+		if name == "docstatus" || name == "workflow_state" {
+			return "s." + name
+		}
+		for _, standard := range biz.StandardDocFields {
+			if name == standard {
+				return "t." + name
+			}
+		}
+		if field := meta.Field(name); field != nil && field.FieldType != "Table" {
+			return "t." + name
+		}
+		return "NULL"
+	}
+}
+
+// stubDocumentRepo 在内存中保存文档
+type stubDocumentRepo struct {
+	table  *biz.DocumentTable
+	syncs  int
+	docs   []map[string]interface{}
+	nextID int64
+	lists  []biz.DocumentQuery
+}
+
+func (r *stubDocumentRepo) GetDocumentTable(ctx context.Context, docType string) (*biz.DocumentTable, error) {
+	return r.table, nil
+}
+
+func (r *stubDocumentRepo) SyncDocumentTable(ctx context.Context, meta *biz.DocumentMeta, signature string) (*biz.DocumentTable, error) {
+	r.syncs++
+	r.table = &biz.DocumentTable{DocType: meta.DocType.Name, TableName: meta.Table.TableName, Signature: signature}
+	return r.table, nil
+}
+
+func (r *stubDocumentRepo) InsertDocument(ctx context.Context, meta *biz.DocumentMeta, doc map[string]interface{}, userID int64) (map[string]interface{}, error) {
+	for _, existing := range r.docs {
+		if existing["name"] == doc["name"] {
+			return nil, biz.ErrDocumentExists
+		}
+	}
+	r.nextID++
+	stored := map[string]interface{}{"id": r.nextID, "created_by": userID}
+	for k, v := range doc {
+		stored[k] = v
+	}
+	r.docs = append(r.docs, stored)
+	return r.copyOf(stored), nil
+}
+
+func (r *stubDocumentRepo) GetDocument(ctx context.Context, meta *biz.DocumentMeta, name string) (map[string]interface{}, error) {
+	for _, doc := range r.docs {
+		if doc["name"] == name {
+			return r.copyOf(doc), nil
+		}
+	}
+	return nil, biz.ErrDocumentNotFound
+}
+
+func (r *stubDocumentRepo) DocumentExists(ctx context.Context, meta *biz.DocumentMeta, name string) (bool, error) {
+	_, err := r.GetDocument(ctx, meta, name)
+	return err == nil, nil
+}
+
+func (r *stubDocumentRepo) UpdateDocument(ctx context.Context, meta *biz.DocumentMeta, name string, changes map[string]interface{}, userID int64) (map[string]interface{}, error) {
+	for _, doc := range r.docs {
+		if doc["name"] == name {
+			for k, v := range changes {
+				doc[k] = v
+			}
+			doc["updated_by"] = userID
+			return r.copyOf(doc), nil
+		}
+	}
+	return nil, biz.ErrDocumentNotFound
+}
+
+func (r *stubDocumentRepo) TransitionDocument(ctx context.Context, meta *biz.DocumentMeta, name string, transition *biz.DocumentTransition) (map[string]interface{}, error) {
+	for _, doc := range r.docs {
+		if doc["name"] == name {
+			if doc["docstatus"] != transition.From {
+				return nil, biz.ErrInvalidDocumentTransition
+			}
+			if transition.FromState != "" && doc["workflow_state"] != transition.FromState {
+				return nil, biz.ErrInvalidDocumentTransition
+			}
+			doc["docstatus"] = transition.To
+			doc["workflow_state"] = transition.WorkflowState
+			doc["updated_by"] = transition.UserID
+			if transition.Reason != "" {
+				doc["cancel_reason"] = transition.Reason
+			}
+			return r.copyOf(doc), nil
+		}
+	}
+	return nil, biz.ErrDocumentNotFound
+}
+
+func (r *stubDocumentRepo) DeleteDocument(ctx context.Context, meta *biz.DocumentMeta, name string) error {
+	for i, doc := range r.docs {
+		if doc["name"] == name {
+			r.docs = append(r.docs[:i], r.docs[i+1:]...)
+			return nil
+		}
+	}
+	return biz.ErrDocumentNotFound
+}
+
+func (r *stubDocumentRepo) ListDocuments(ctx context.Context, meta *biz.DocumentMeta, query *biz.DocumentQuery) ([]map[string]interface{}, int32, error) {
+	r.lists = append(r.lists, *query)
+	if query.Scope != nil {
+		if _, _, ok := query.Scope.SQL(stubDocumentColumn(meta), 1); !ok {
+			return nil, 0, biz.ErrScopeNotTranslatable
+		}
+	}
+	var matched []map[string]interface{}
+	for _, doc := range r.docs {
+		keep := query.Scope == nil || query.Scope.Match(doc)
+		for k, v := range query.Filters {
+			keep = keep && fmt.Sprint(doc[k]) == fmt.Sprint(v)
+		}
+		if keep {
+			matched = append(matched, r.copyOf(doc))
+		}
+	}
+	start := int((query.Page - 1) * query.Size)
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + int(query.Size)
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], int32(len(matched)), nil
+}
+
+func (r *stubDocumentRepo) copyOf(doc map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		copied[k] = v
+	}
+	return copied
+}
+
+func TestDocumentUsecase(t *testing.T) {
+	fieldRepo := &stubDocFieldRepo{fields: []*biz.DocField{
+		{DocType: "Order", FieldName: "customer", FieldType: "Data", IsMandatory: true},
+		{DocType: "Order", FieldName: "amount", FieldType: "Currency", PermissionLevel: 1},
+		{DocType: "Order", FieldName: "status", FieldType: "Select", Options: "Open\nClosed", DefaultValue: "Open"},
+		{DocType: "Order", FieldName: "urgent", FieldType: "Check"},
+		{DocType: "Order", FieldName: "order_date", FieldType: "Date"},
+		{DocType: "Order", FieldName: "pin", FieldType: "Password"},
+		{DocType: "Order", FieldName: "watchers", FieldType: "Table", Options: "Order Watcher"},
+		{DocType: "Order Watcher", FieldName: "user_id", FieldType: "Int", IsMandatory: true},
+	}}
+	permRepo := &stubDocTypePermissionRepo{docTypes: map[string]*biz.DocType{
+		"Order":         {Name: "Order", TitleField: "customer"},
+		"Order Watcher": {Name: "Order Watcher", IsChildTable: true},
+		"Empty":         {Name: "Empty"},
+	}}
+	repo := &stubDocumentRepo{}
+	uc := biz.NewDocumentUsecase(repo, fieldRepo, permRepo, &stubNamingSeriesRepo{}, &stubWorkflowRepo{}, &stubApprovalRepo{}, log.DefaultLogger)
+	ctx := context.Background()
+	open := &biz.DocumentAccess{UserID: 7}
+
+	_, err := uc.CreateDocument(ctx, "Empty", map[string]interface{}{}, open)
+	assert.ErrorIs(t, err, biz.ErrDocTypeNotStorable)
+	_, err = uc.CreateDocument(ctx, "Order Watcher", map[string]interface{}{}, open)
+	assert.ErrorIs(t, err, biz.ErrDocTypeNotStorable)
+
+	// 首次访问时创建数据表，字段未变化时不重复迁移
+	doc, err := uc.CreateDocument(ctx, "Order", map[string]interface{}{
+		"name": "SO-1", "customer": "Acme", "amount": "12.5", "urgent": float64(1), "pin": "1234",
+		"watchers": []interface{}{map[string]interface{}{"user_id": float64(3)}},
+	}, open)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.syncs)
+	assert.Equal(t, "doc_order", repo.table.TableName)
+	assert.Equal(t, "SO-1", doc["name"])
+	assert.Equal(t, 12.5, doc["amount"])
+	assert.Equal(t, "Open", doc["status"])
+	assert.Equal(t, true, doc["urgent"])
+	assert.Equal(t, biz.DocStatusDraft, doc["docstatus"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"user_id": int64(3), "idx": 1}}, doc["watchers"])
+	assert.NotContains(t, doc, "pin")
+
+	doc, err = uc.CreateDocument(ctx, "Order", map[string]interface{}{"customer": "Beta", "urgent": false}, open)
+	assert.NoError(t, err)
+	assert.Len(t, doc["name"], 10)
+	assert.Equal(t, 1, repo.syncs)
+
+	for _, values := range []map[string]interface{}{
+		{"amount": 1},
+		{"customer": "X", "status": "Pending"},
+		{"customer": "X", "order_date": "2024-13-01"},
+		{"customer": "X", "unknown": 1},
+		{"customer": "X", "watchers": []interface{}{map[string]interface{}{}}},
+		{"customer": "X", "name": "SO/1"},
+	} {
+		_, err = uc.CreateDocument(ctx, "Order", values, open)
+		assert.ErrorIs(t, err, biz.ErrInvalidDocument, "%v", values)
+	}
+	_, err = uc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-1", "customer": "X"}, open)
+	assert.ErrorIs(t, err, biz.ErrDocumentExists)
+
+	// 字段定义变化后自动迁移
+	fieldRepo.fields = append(fieldRepo.fields, &biz.DocField{DocType: "Order", FieldName: "remarks", FieldType: "Text"})
+	_, err = uc.GetDocument(ctx, "Order", "SO-1", open)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, repo.syncs)
+
+	// 无写权限的字段被丢弃，只有变化的字段参与检查
+	var guarded []string
+	guard := &biz.DocumentAccess{UserID: 8, GuardWrites: func(ctx context.Context, fields []string) ([]string, error) {
+		guarded = fields
+		return []string{"amount"}, nil
+	}}
+	doc, err = uc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"customer": "Acme", "amount": 99, "remarks": "rush"}, guard)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"amount", "remarks"}, guarded)
+	assert.Equal(t, 12.5, doc["amount"])
+	assert.Equal(t, "rush", doc["remarks"])
+	assert.Equal(t, int64(8), doc["updated_by"])
+
+	_, err = uc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"customer": ""}, open)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+	_, err = uc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"name": "SO-2"}, open)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+
+	// 数据范围：范围外的文档不可访问，更新后也必须仍在范围内
+	condRepo := &stubConditionPermissionRepo{rules: []*biz.PermissionRule{{ID: 1, CanRead: true, CanWrite: true, Condition: "doc.amount < 100"}}}
+	permissionUc := biz.NewPermissionUsecase(condRepo, log.DefaultLogger)
+	scope, err := permissionUc.GetPermissionScope(ctx, 7, "Order", "write", 0)
+	assert.NoError(t, err)
+	scoped := &biz.DocumentAccess{UserID: 7, Scope: scope}
+	_, err = uc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"amount": 500}, scoped)
+	assert.ErrorIs(t, err, biz.ErrDocumentForbidden)
+	_, err = uc.CreateDocument(ctx, "Order", map[string]interface{}{"customer": "Big", "amount": 1000}, scoped)
+	assert.ErrorIs(t, err, biz.ErrDocumentForbidden)
+	_, err = uc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-3", "customer": "Big", "amount": 1000}, open)
+	assert.NoError(t, err)
+	_, err = uc.GetDocument(ctx, "Order", "SO-3", scoped)
+	assert.ErrorIs(t, err, biz.ErrDocumentForbidden)
+
+	// 列表按数据范围过滤并按字段权限级别过滤字段
+	masked := &biz.DocumentAccess{UserID: 7, Scope: scope, MaskFields: func(ctx context.Context, docs []map[string]interface{}) ([]map[string]interface{}, error) {
+		for _, doc := range docs {
+			delete(doc, "amount")
+		}
+		return docs, nil
+	}}
+	list, err := uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{}, masked)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), list.Total)
+	assert.Equal(t, int32(20), list.Size)
+	for _, doc := range list.Documents {
+		assert.NotContains(t, doc, "amount")
+	}
+
+	// 无法转换为SQL的数据范围在内存中过滤
+	condRepo.rules = []*biz.PermissionRule{{ID: 2, CanRead: true, Condition: "user.id in doc.watchers"}}
+	scope, err = permissionUc.GetPermissionScope(ctx, 3, "Order", "read", 0)
+	assert.NoError(t, err)
+	list, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{Size: 1}, &biz.DocumentAccess{UserID: 3, Scope: scope})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), list.Total)
+
+	list, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{Filters: map[string]interface{}{"customer": "Beta"}}, open)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), list.Total)
+	_, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{Filters: map[string]interface{}{"watchers": "x"}}, open)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocumentListQuery)
+	_, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{OrderBy: "unknown"}, open)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocumentListQuery)
+
+	condRepo.rules = nil
+	scope, err = permissionUc.GetPermissionScope(ctx, 3, "Order", "read", 0)
+	assert.NoError(t, err)
+	list, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{}, &biz.DocumentAccess{UserID: 3, Scope: scope})
+	assert.NoError(t, err)
+	assert.Empty(t, list.Documents)
+
+	// 仅创建者可访问的规则可以创建文档，只能读取自己创建的文档
+	condRepo.rules = []*biz.PermissionRule{{ID: 3, CanRead: true, CanCreate: true, OnlyIfCreator: true}}
+	scope, err = permissionUc.GetPermissionScope(ctx, 3, "Order", "create", 0)
+	assert.NoError(t, err)
+	_, err = uc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-4", "customer": "Own", "amount": 1000}, &biz.DocumentAccess{UserID: 3, Scope: scope})
+	assert.NoError(t, err)
+	scope, err = permissionUc.GetPermissionScope(ctx, 3, "Order", "read", 0)
+	assert.NoError(t, err)
+	creator := &biz.DocumentAccess{UserID: 3, Scope: scope}
+	list, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{}, creator)
+	assert.NoError(t, err)
+	if assert.Len(t, list.Documents, 1) {
+		assert.Equal(t, "SO-4", list.Documents[0]["name"])
+	}
+	_, err = uc.GetDocument(ctx, "Order", "SO-1", creator)
+	assert.ErrorIs(t, err, biz.ErrDocumentForbidden)
+
+	// 已提交的文档不可修改和删除
+	repo.docs[0]["docstatus"] = biz.DocStatusSubmitted
+	_, err = uc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"remarks": "late"}, open)
+	assert.ErrorIs(t, err, biz.ErrDocumentNotEditable)
+	assert.ErrorIs(t, uc.DeleteDocument(ctx, "Order", "SO-1", open), biz.ErrDocumentNotEditable)
+	assert.NoError(t, uc.DeleteDocument(ctx, "Order", "SO-3", open))
+	_, err = uc.GetDocument(ctx, "Order", "SO-3", open)
+	assert.ErrorIs(t, err, biz.ErrDocumentNotFound)
+}
+
+func TestDocumentUsecase_ListAccess(t *testing.T) {
+	fieldRepo := &stubDocFieldRepo{fields: []*biz.DocField{
+		{DocType: "Order", FieldName: "customer", FieldType: "Data"},
+		{DocType: "Order", FieldName: "amount", FieldType: "Currency", PermissionLevel: 1},
+		{DocType: "Order", FieldName: "remarks", FieldType: "Data", PermissionLevel: 1},
+	}}
+	permRepo := &stubDocTypePermissionRepo{docTypes: map[string]*biz.DocType{
+		"Order": {Name: "Order", TitleField: "customer", SearchFields: []string{"remarks"}, SortField: "amount"},
+	}}
+	repo := &stubDocumentRepo{}
+	for i := 0; i < 1200; i++ {
+		repo.docs = append(repo.docs, map[string]interface{}{
+			"name": fmt.Sprintf("SO-%04d", i), "customer": "Acme", "amount": float64(i), "docstatus": biz.DocStatusDraft,
+		})
+	}
+	uc := biz.NewDocumentUsecase(repo, fieldRepo, permRepo, &stubNamingSeriesRepo{}, &stubWorkflowRepo{}, &stubApprovalRepo{}, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 级别1的字段不可读：不能过滤和排序，不参与搜索，默认排序退回更新时间
+	hidden := &biz.DocumentAccess{UserID: 7, UnreadableFields: func(ctx context.Context, fields []string) ([]string, error) {
+		var unreadable []string
+		for _, field := range fields {
+			if field == "amount" || field == "remarks" {
+				unreadable = append(unreadable, field)
+			}
+		}
+		return unreadable, nil
+	}}
+	_, err := uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{Filters: map[string]interface{}{"amount": 5}}, hidden)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocumentListQuery)
+	_, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{OrderBy: "amount"}, hidden)
+	assert.ErrorIs(t, err, biz.ErrInvalidDocumentListQuery)
+	_, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{Search: "rush"}, hidden)
+	assert.NoError(t, err)
+	last := repo.lists[len(repo.lists)-1]
+	assert.Equal(t, []string{"name", "customer"}, last.SearchFields)
+	assert.Equal(t, "updated_at", last.OrderBy)
+
+	_, err = uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{Search: "rush"}, &biz.DocumentAccess{UserID: 7})
+	assert.NoError(t, err)
+	last = repo.lists[len(repo.lists)-1]
+	assert.Equal(t, []string{"name", "customer", "remarks"}, last.SearchFields)
+	assert.Equal(t, "amount", last.OrderBy)
+
+	// 无法转换为SQL的数据范围分页扫描全部文档，不截断
+	condRepo := &stubConditionPermissionRepo{rules: []*biz.PermissionRule{{ID: 1, CanRead: true, Condition: "doc.amount >= 100 or user.id in doc.watchers"}}}
+	scope, err := biz.NewPermissionUsecase(condRepo, log.DefaultLogger).GetPermissionScope(ctx, 7, "Order", "read", 0)
+	assert.NoError(t, err)
+	repo.lists = nil
+	list, err := uc.ListDocuments(ctx, "Order", &biz.DocumentQuery{Page: 3, Size: 500}, &biz.DocumentAccess{UserID: 7, Scope: scope})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1100), list.Total)
+	assert.Len(t, list.Documents, 100)
+	assert.Len(t, repo.lists, 4)
+}
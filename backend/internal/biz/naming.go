@@ -0,0 +1,417 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 命名规则，DocType.NamingRule的格式为 规则[:选项]
+const (
+	NamingRuleAutoname   = "autoname"   // 按文档类型自增的整数
+	NamingRulePrompt     = "prompt"     // 创建时由用户指定
+	NamingRuleField      = "field"      // 取字段值，如 field:customer_code
+	NamingRuleSeries     = "series"     // 编号序列，如 series:PO-.YYYY.-.#####
+	NamingRuleRandom     = "random"     // 10位随机十六进制
+	NamingRuleExpression = "expression" // 表达式模板，如 expression:INV-{customer}-{YYYY}-{####}
+)
+
+// NamingRules 支持的命名规则
+var NamingRules = []string{
+	NamingRuleAutoname, NamingRulePrompt, NamingRuleField, NamingRuleSeries, NamingRuleRandom, NamingRuleExpression,
+}
+
+// 命名相关限制
+const (
+	// defaultSeriesDigits 编号序列未写计数位时追加的位数
+	defaultSeriesDigits = 5
+	// maxNamingAttempts 生成的名称与已有文档冲突时最多重试的次数
+	maxNamingAttempts = 10
+)
+
+// 错误定义
+var (
+	ErrInvalidNamingRule   = errors.New("invalid naming rule")
+	ErrNamingSeriesInvalid = errors.New("invalid naming series")
+)
+
+// namingPartKind 名称模板片段类型
+type namingPartKind int
+
+const (
+	namingPartLiteral namingPartKind = iota // 原样输出
+	namingPartDate                          // 日期：YYYY、YY、MM、DD
+	namingPartField                         // 文档字段值
+	namingPartCounter                       // 计数器，位数为#的个数
+)
+
+// namingPart 名称模板片段
+type namingPart struct {
+	kind   namingPartKind
+	value  string
+	digits int
+}
+
+// NamingRule 已解析的命名规则
+type NamingRule struct {
+	Kind   string `json:"kind"`
+	Option string `json:"option,omitempty"`
+
+	parts []namingPart
+}
+
+// ParseNamingRule 解析命名规则，空规则表示允许指定名称、未指定时随机生成
+func ParseNamingRule(rule string) (*NamingRule, error) {
+	kind, option, _ := strings.Cut(strings.TrimSpace(rule), ":")
+	r := &NamingRule{Kind: strings.TrimSpace(kind), Option: strings.TrimSpace(option)}
+
+	switch r.Kind {
+	case "":
+		return r, nil
+	case NamingRuleAutoname, NamingRulePrompt, NamingRuleRandom:
+		if r.Option != "" {
+			return nil, fmt.Errorf("%w: %s takes no option", ErrInvalidNamingRule, r.Kind)
+		}
+	case NamingRuleField:
+		if !docFieldNamePattern.MatchString(r.Option) {
+			return nil, fmt.Errorf("%w: field rule requires a field name, e.g. field:customer_code", ErrInvalidNamingRule)
+		}
+		r.parts = []namingPart{{kind: namingPartField, value: r.Option}}
+	case NamingRuleSeries:
+		parts, err := parseSeriesPattern(r.Option)
+		if err != nil {
+			return nil, err
+		}
+		r.parts = parts
+	case NamingRuleExpression:
+		parts, err := parseNamingExpression(r.Option)
+		if err != nil {
+			return nil, err
+		}
+		r.parts = parts
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidNamingRule, r.Kind)
+	}
+	return r, nil
+}
+
+// Fields 规则引用的文档字段
+func (r *NamingRule) Fields() []string {
+	var fields []string
+	for _, part := range r.parts {
+		if part.kind == namingPartField {
+			fields = append(fields, part.value)
+		}
+	}
+	return fields
+}
+
+// parseSeriesPattern 解析以"."分隔的编号序列，如 PO-.YYYY.-.#####；
+// 片段为日期占位符、{字段}、全部由#组成的计数器或原样文本，未写计数器时追加五位计数器
+func parseSeriesPattern(pattern string) ([]namingPart, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("%w: series rule requires a pattern, e.g. series:PO-.YYYY.-.#####", ErrInvalidNamingRule)
+	}
+
+	var parts []namingPart
+	hasCounter := false
+	for _, segment := range strings.Split(pattern, ".") {
+		if segment == "" {
+			continue
+		}
+		part, err := parseNamingSegment(segment, strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"))
+		if err != nil {
+			return nil, err
+		}
+		if part.kind == namingPartCounter {
+			if hasCounter {
+				return nil, fmt.Errorf("%w: series %s has more than one counter", ErrInvalidNamingRule, pattern)
+			}
+			hasCounter = true
+		}
+		parts = append(parts, part)
+	}
+	if !hasCounter {
+		parts = append(parts, namingPart{kind: namingPartCounter, digits: defaultSeriesDigits})
+	}
+	return parts, nil
+}
+
+// parseNamingExpression 解析表达式模板，{}中为日期占位符、字段名或计数器，其余原样输出；
+// 模板可以不含计数器，此时名称完全由字段和日期决定
+func parseNamingExpression(expression string) ([]namingPart, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("%w: expression rule requires a template, e.g. expression:INV-{customer}-{####}", ErrInvalidNamingRule)
+	}
+
+	var parts []namingPart
+	hasCounter := false
+	rest := expression
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		literal := rest
+		if open >= 0 {
+			literal = rest[:open]
+		}
+		if strings.Contains(literal, "}") {
+			return nil, fmt.Errorf("%w: unmatched } in %s", ErrInvalidNamingRule, expression)
+		}
+		if open < 0 {
+			parts = append(parts, namingPart{kind: namingPartLiteral, value: rest})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, namingPart{kind: namingPartLiteral, value: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed { in %s", ErrInvalidNamingRule, expression)
+		}
+		part, err := parseNamingSegment(rest[open:open+end+1], true)
+		if err != nil {
+			return nil, err
+		}
+		if part.kind == namingPartCounter {
+			if hasCounter {
+				return nil, fmt.Errorf("%w: expression %s has more than one counter", ErrInvalidNamingRule, expression)
+			}
+			hasCounter = true
+		}
+		parts = append(parts, part)
+		rest = rest[open+end+1:]
+	}
+	return parts, nil
+}
+
+// parseNamingSegment 解析单个片段，braced为true时片段形如{...}，其中不能是原样文本
+func parseNamingSegment(segment string, braced bool) (namingPart, error) {
+	inner := segment
+	if braced {
+		inner = strings.TrimSpace(segment[1 : len(segment)-1])
+	}
+
+	switch {
+	case inner != "" && strings.Trim(inner, "#") == "":
+		return namingPart{kind: namingPartCounter, digits: len(inner)}, nil
+	case inner == "YYYY" || inner == "YY" || inner == "MM" || inner == "DD":
+		return namingPart{kind: namingPartDate, value: inner}, nil
+	case !braced:
+		if strings.ContainsAny(segment, "{}/\\?#%") {
+			return namingPart{}, fmt.Errorf("%w: invalid characters in %s", ErrInvalidNamingRule, segment)
+		}
+		return namingPart{kind: namingPartLiteral, value: segment}, nil
+	case docFieldNamePattern.MatchString(inner):
+		return namingPart{kind: namingPartField, value: inner}, nil
+	}
+	return namingPart{}, fmt.Errorf("%w: unknown placeholder %s", ErrInvalidNamingRule, segment)
+}
+
+// render 按文档和当前时间生成名称，遇到计数器时以其前面已生成的文本为前缀取下一个值
+func (r *NamingRule) render(doc map[string]interface{}, now time.Time, next func(prefix string) (int64, error)) (string, error) {
+	var b strings.Builder
+	for _, part := range r.parts {
+		switch part.kind {
+		case namingPartLiteral:
+			b.WriteString(part.value)
+		case namingPartDate:
+			b.WriteString(formatNamingDate(part.value, now))
+		case namingPartField:
+			value, err := namingFieldValue(doc, part.value)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+		case namingPartCounter:
+			current, err := next(b.String())
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(fmt.Sprintf("%0*d", part.digits, current))
+		}
+	}
+	return b.String(), nil
+}
+
+// formatNamingDate 日期占位符的值
+func formatNamingDate(placeholder string, now time.Time) string {
+	switch placeholder {
+	case "YYYY":
+		return now.Format("2006")
+	case "YY":
+		return now.Format("06")
+	case "MM":
+		return now.Format("01")
+	}
+	return now.Format("02")
+}
+
+// namingFieldValue 字段值转换为名称文本，字段为空时无法命名
+func namingFieldValue(doc map[string]interface{}, field string) (string, error) {
+	switch v := doc[field].(type) {
+	case nil:
+		return "", fmt.Errorf("%w: field %s is required for naming", ErrInvalidDocument, field)
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "", fmt.Errorf("%w: field %s is required for naming", ErrInvalidDocument, field)
+		}
+		return strings.TrimSpace(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format("20060102150405"), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// NamingSeries 编号序列计数器，每个公司按前缀分别计数
+type NamingSeries struct {
+	Prefix    string    `json:"prefix"`
+	Current   int64     `json:"current"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NamingSeriesRepo 编号序列计数器仓储接口
+type NamingSeriesRepo interface {
+	// NextSeriesValue 原子地递增前缀的计数器并返回新值，前缀不存在时从1开始
+	NextSeriesValue(ctx context.Context, prefix string) (int64, error)
+	// ListNamingSeries 获取以prefix开头的计数器
+	ListNamingSeries(ctx context.Context, prefix string) ([]*NamingSeries, error)
+	// SetSeriesCurrent 设置计数器的当前值，下一个名称从current+1开始
+	SetSeriesCurrent(ctx context.Context, prefix string, current int64) (*NamingSeries, error)
+}
+
+// NamingSeriesUsecase 编号序列计数器用例
+type NamingSeriesUsecase struct {
+	repo NamingSeriesRepo
+	log  *log.Helper
+}
+
+// NewNamingSeriesUsecase 创建编号序列计数器用例
+func NewNamingSeriesUsecase(repo NamingSeriesRepo, logger log.Logger) *NamingSeriesUsecase {
+	return &NamingSeriesUsecase{
+		repo: repo,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// ListSeries 获取以prefix开头的计数器
+func (uc *NamingSeriesUsecase) ListSeries(ctx context.Context, prefix string) ([]*NamingSeries, error) {
+	return uc.repo.ListNamingSeries(ctx, prefix)
+}
+
+// SetSeriesCurrent 调整计数器的当前值，调小后生成的名称若与已有文档冲突会自动跳过
+func (uc *NamingSeriesUsecase) SetSeriesCurrent(ctx context.Context, prefix string, current int64) (*NamingSeries, error) {
+	if strings.TrimSpace(prefix) == "" || len(prefix) > maxDocumentNameLength {
+		return nil, fmt.Errorf("%w: prefix must be 1-%d characters", ErrNamingSeriesInvalid, maxDocumentNameLength)
+	}
+	if current < 0 {
+		return nil, fmt.Errorf("%w: current value cannot be negative", ErrNamingSeriesInvalid)
+	}
+	return uc.repo.SetSeriesCurrent(ctx, prefix, current)
+}
+
+// newDocumentName 按文档类型的命名规则生成文档名称并检查冲突；
+// 计数器生成的名称已被占用时继续取下一个值，字段和指定的名称冲突时返回ErrDocumentExists
+func (uc *DocumentUsecase) newDocumentName(ctx context.Context, meta *DocumentMeta, doc map[string]interface{}, requested string) (string, error) {
+	rule, err := ParseNamingRule(meta.DocType.NamingRule)
+	if err != nil {
+		return "", err
+	}
+
+	if requested != "" && rule.Kind != "" && rule.Kind != NamingRulePrompt {
+		return "", fmt.Errorf("%w: name is generated by naming rule %s", ErrInvalidDocument, rule.Kind)
+	}
+
+	switch rule.Kind {
+	case "", NamingRulePrompt:
+		if requested != "" {
+			return uc.claimName(ctx, meta, requested)
+		}
+		if rule.Kind == NamingRulePrompt {
+			return "", fmt.Errorf("%w: name is required", ErrInvalidDocument)
+		}
+		return uc.retryName(ctx, meta, func() (string, error) { return newDocumentHashName(), nil })
+	case NamingRuleRandom:
+		return uc.retryName(ctx, meta, func() (string, error) { return newDocumentHashName(), nil })
+	case NamingRuleAutoname:
+		return uc.retryName(ctx, meta, func() (string, error) {
+			current, err := uc.seriesRepo.NextSeriesValue(ctx, "autoname:"+meta.DocType.Name)
+			return strconv.FormatInt(current, 10), err
+		})
+	}
+
+	now := time.Now()
+	generate := func() (string, error) {
+		name, err := rule.render(doc, now, func(prefix string) (int64, error) {
+			return uc.seriesRepo.NextSeriesValue(ctx, prefix)
+		})
+		if err != nil {
+			return "", err
+		}
+		return checkDocumentName(name)
+	}
+	if !rule.hasCounter() {
+		name, err := generate()
+		if err != nil {
+			return "", err
+		}
+		return uc.claimName(ctx, meta, name)
+	}
+	return uc.retryName(ctx, meta, generate)
+}
+
+// hasCounter 规则是否含计数器，含计数器时冲突可通过取下一个值解决
+func (r *NamingRule) hasCounter() bool {
+	for _, part := range r.parts {
+		if part.kind == namingPartCounter {
+			return true
+		}
+	}
+	return false
+}
+
+// claimName 检查名称未被占用
+func (uc *DocumentUsecase) claimName(ctx context.Context, meta *DocumentMeta, name string) (string, error) {
+	exists, err := uc.repo.DocumentExists(ctx, meta, name)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("%w: %s", ErrDocumentExists, name)
+	}
+	return name, nil
+}
+
+// retryName 生成名称直到未被占用
+func (uc *DocumentUsecase) retryName(ctx context.Context, meta *DocumentMeta, generate func() (string, error)) (string, error) {
+	for attempt := 0; attempt < maxNamingAttempts; attempt++ {
+		name, err := generate()
+		if err != nil {
+			return "", err
+		}
+		exists, err := uc.repo.DocumentExists(ctx, meta, name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+		uc.log.Warnf("Generated document name %s of %s already exists, retrying", name, meta.DocType.Name)
+	}
+	return "", fmt.Errorf("%w: no free name after %d attempts", ErrDocumentExists, maxNamingAttempts)
+}
+
+// checkDocumentName 检查名称长度和字符
+func checkDocumentName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxDocumentNameLength || strings.ContainsAny(name, "/\\?#%") {
+		return "", fmt.Errorf("%w: name must be 1-%d characters without / \\ ? # %%", ErrInvalidDocument, maxDocumentNameLength)
+	}
+	return name, nil
+}
@@ -0,0 +1,160 @@
+package biz_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNamingRule(t *testing.T) {
+	valid := map[string][]string{
+		"":                                     nil,
+		"prompt":                               nil,
+		"field:customer_code":                  {"customer_code"},
+		"series:PO-.YYYY.-.#####":              nil,
+		"series:{branch}.-.MM.-":               {"branch"},
+		"expression:INV-{customer}-{YY}{####}": {"customer"},
+		"expression:{customer}/{order_date}":   {"customer", "order_date"},
+		"  series : SO-.YYYY.MM.DD.-.###  ":    nil,
+	}
+	for rule, fields := range valid {
+		parsed, err := biz.ParseNamingRule(rule)
+		if assert.NoError(t, err, rule) {
+			assert.Equal(t, fields, parsed.Fields(), rule)
+		}
+	}
+
+	for _, rule := range []string{
+		"hash", "random:10", "field:", "field:Customer", "series:", "series:PO-.#.##",
+		"series:PO/.####", "expression:", "expression:{####}-{##}", "expression:INV-{customer",
+		"expression:INV}-{####}", "expression:{Bad Field}",
+	} {
+		_, err := biz.ParseNamingRule(rule)
+		assert.ErrorIs(t, err, biz.ErrInvalidNamingRule, rule)
+	}
+
+	assert.Error(t, (&biz.DocType{Name: "Order", Label: "Order", Module: "sales", NamingRule: "series"}).Validate())
+	assert.NoError(t, (&biz.DocType{Name: "Order", Label: "Order", Module: "sales", NamingRule: "series:SO-.#####"}).Validate())
+}
+
+// stubNamingSeriesRepo 在内存中保存编号计数器
+type stubNamingSeriesRepo struct {
+	counters map[string]int64
+}
+
+func (r *stubNamingSeriesRepo) NextSeriesValue(ctx context.Context, prefix string) (int64, error) {
+	if r.counters == nil {
+		r.counters = make(map[string]int64)
+	}
+	r.counters[prefix]++
+	return r.counters[prefix], nil
+}
+
+func (r *stubNamingSeriesRepo) ListNamingSeries(ctx context.Context, prefix string) ([]*biz.NamingSeries, error) {
+	var series []*biz.NamingSeries
+	for p, current := range r.counters {
+		if strings.HasPrefix(p, prefix) {
+			series = append(series, &biz.NamingSeries{Prefix: p, Current: current})
+		}
+	}
+	return series, nil
+}
+
+func (r *stubNamingSeriesRepo) SetSeriesCurrent(ctx context.Context, prefix string, current int64) (*biz.NamingSeries, error) {
+	if r.counters == nil {
+		r.counters = make(map[string]int64)
+	}
+	r.counters[prefix] = current
+	return &biz.NamingSeries{Prefix: prefix, Current: current}, nil
+}
+
+func TestDocumentUsecase_Naming(t *testing.T) {
+	fieldRepo := &stubDocFieldRepo{}
+	docTypes := map[string]*biz.DocType{}
+	for _, name := range []string{"Order", "Item", "Invoice", "Ticket", "Note", "Lead"} {
+		docTypes[name] = &biz.DocType{Name: name}
+		fieldRepo.fields = append(fieldRepo.fields,
+			&biz.DocField{DocType: name, FieldName: "code", FieldType: "Data"},
+			&biz.DocField{DocType: name, FieldName: "customer", FieldType: "Data"})
+	}
+	docTypes["Order"].NamingRule = "series:PO-.YYYY.-.###"
+	docTypes["Item"].NamingRule = "field:code"
+	docTypes["Invoice"].NamingRule = "expression:INV-{customer}-{##}"
+	docTypes["Ticket"].NamingRule = "autoname"
+	docTypes["Note"].NamingRule = "prompt"
+	docTypes["Lead"].NamingRule = "random"
+
+	seriesRepo := &stubNamingSeriesRepo{}
+	uc := biz.NewDocumentUsecase(&stubDocumentRepo{}, fieldRepo, &stubDocTypePermissionRepo{docTypes: docTypes}, seriesRepo, &stubWorkflowRepo{}, &stubApprovalRepo{}, log.DefaultLogger)
+	namingUc := biz.NewNamingSeriesUsecase(seriesRepo, log.DefaultLogger)
+	ctx := context.Background()
+	access := &biz.DocumentAccess{UserID: 1}
+	year := time.Now().Format("2006")
+
+	create := func(docType string, values map[string]interface{}) (string, error) {
+		doc, err := uc.CreateDocument(ctx, docType, values, access)
+		if err != nil {
+			return "", err
+		}
+		return doc["name"].(string), nil
+	}
+
+	// 编号序列按前缀计数，调小计数器后跳过已占用的编号
+	name, err := create("Order", map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "PO-"+year+"-001", name)
+	name, _ = create("Order", map[string]interface{}{})
+	assert.Equal(t, "PO-"+year+"-002", name)
+	_, err = namingUc.SetSeriesCurrent(ctx, "PO-"+year+"-", 0)
+	assert.NoError(t, err)
+	name, _ = create("Order", map[string]interface{}{})
+	assert.Equal(t, "PO-"+year+"-003", name)
+	series, err := namingUc.ListSeries(ctx, "PO-")
+	assert.NoError(t, err)
+	assert.Equal(t, []*biz.NamingSeries{{Prefix: "PO-" + year + "-", Current: 3}}, series)
+	_, err = namingUc.SetSeriesCurrent(ctx, "PO-", -1)
+	assert.ErrorIs(t, err, biz.ErrNamingSeriesInvalid)
+
+	// 由规则生成名称时不接受指定名称
+	_, err = create("Order", map[string]interface{}{"name": "PO-X"})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+
+	// 字段命名冲突时报错，字段为空时无法命名
+	name, err = create("Item", map[string]interface{}{"code": " SKU-1 "})
+	assert.NoError(t, err)
+	assert.Equal(t, "SKU-1", name)
+	_, err = create("Item", map[string]interface{}{"code": "SKU-1"})
+	assert.ErrorIs(t, err, biz.ErrDocumentExists)
+	_, err = create("Item", map[string]interface{}{})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+	_, err = create("Item", map[string]interface{}{"code": "A/B"})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+
+	// 表达式中的计数器以字段值为前缀分别计数
+	name, _ = create("Invoice", map[string]interface{}{"customer": "ACME"})
+	assert.Equal(t, "INV-ACME-01", name)
+	name, _ = create("Invoice", map[string]interface{}{"customer": "BETA"})
+	assert.Equal(t, "INV-BETA-01", name)
+	name, _ = create("Invoice", map[string]interface{}{"customer": "ACME"})
+	assert.Equal(t, "INV-ACME-02", name)
+
+	name, _ = create("Ticket", map[string]interface{}{})
+	assert.Equal(t, "1", name)
+	assert.Equal(t, int64(1), seriesRepo.counters["autoname:Ticket"])
+
+	_, err = create("Note", map[string]interface{}{})
+	assert.ErrorIs(t, err, biz.ErrInvalidDocument)
+	name, _ = create("Note", map[string]interface{}{"name": "Meeting"})
+	assert.Equal(t, "Meeting", name)
+	_, err = create("Note", map[string]interface{}{"name": "Meeting"})
+	assert.ErrorIs(t, err, biz.ErrDocumentExists)
+
+	name, _ = create("Lead", map[string]interface{}{})
+	assert.Regexp(t, `^[0-9a-f]{10}$`, name)
+}
@@ -0,0 +1,125 @@
+package biz
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// 组织成员变动类型
+const (
+	MemberActionJoin   = "join"
+	MemberActionUpdate = "update"
+	MemberActionLeave  = "leave"
+)
+
+// 组织成员字段长度限制，与user_organizations、user_organization_history表一致
+const (
+	maxMemberPositionLength = 50
+	maxMemberReasonLength   = 500
+)
+
+// OrganizationMember 组织成员关系；用户最多一个有效的主组织，组织负责人必须是有效成员
+type OrganizationMember struct {
+	ID        int64      `json:"id"`
+	UserID    int32      `json:"user_id"`
+	OrgID     int32      `json:"org_id"`
+	Position  string     `json:"position"`
+	IsPrimary bool       `json:"is_primary"`
+	IsLeader  bool       `json:"is_leader"`
+	IsActive  bool       `json:"is_active"`
+	JoinedAt  time.Time  `json:"joined_at"`
+	LeftAt    *time.Time `json:"left_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	// 关联数据：按组织查询时返回用户，按用户查询时返回组织
+	User         *User         `json:"user,omitempty"`
+	Organization *Organization `json:"organization,omitempty"`
+}
+
+// OrganizationMemberUpdate 成员关系调整，为nil的字段保持不变
+type OrganizationMemberUpdate struct {
+	Position *string
+	// IsPrimary 只能设为true；主组织只能通过设置另一个组织为主组织来更换
+	IsPrimary *bool
+	IsLeader  *bool
+}
+
+// OrganizationMemberHistory 组织成员变动记录，保存变动后的成员关系快照
+type OrganizationMemberHistory struct {
+	ID         int64     `json:"id"`
+	UserID     int32     `json:"user_id"`
+	Username   string    `json:"username"`
+	OrgID      int32     `json:"org_id"`
+	OrgName    string    `json:"org_name"`
+	Action     string    `json:"action"`
+	Position   string    `json:"position"`
+	IsPrimary  bool      `json:"is_primary"`
+	IsLeader   bool      `json:"is_leader"`
+	Reason     string    `json:"reason,omitempty"`
+	OperatorID *int32    `json:"operator_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// MemberHistoryFilter 组织成员变动记录查询条件，UserID和OrgID为0时不过滤
+type MemberHistoryFilter struct {
+	UserID int32
+	OrgID  int32
+	Limit  int32
+}
+
+// AddMember 添加组织成员，用户曾是成员时恢复原成员关系；用户没有主组织时该组织自动成为主组织
+func (uc *OrganizationUsecase) AddMember(ctx context.Context, member *OrganizationMember, operatorID int32) (*OrganizationMember, error) {
+	member.Position = strings.TrimSpace(member.Position)
+	if len([]rune(member.Position)) > maxMemberPositionLength {
+		return nil, ErrInvalidOrganizationMember
+	}
+	if member.JoinedAt.IsZero() {
+		member.JoinedAt = time.Now()
+	}
+	return uc.repo.AddOrganizationMember(ctx, member, operatorID)
+}
+
+// UpdateMember 调整成员的职位、主组织或负责人身份
+func (uc *OrganizationUsecase) UpdateMember(ctx context.Context, orgID, userID int32, update *OrganizationMemberUpdate, operatorID int32) (*OrganizationMember, error) {
+	if update.Position != nil {
+		position := strings.TrimSpace(*update.Position)
+		if len([]rune(position)) > maxMemberPositionLength {
+			return nil, ErrInvalidOrganizationMember
+		}
+		update.Position = &position
+	}
+	return uc.repo.UpdateOrganizationMember(ctx, orgID, userID, update, operatorID)
+}
+
+// RemoveMember 移除组织成员并保留成员关系记录；移除主组织时由最早加入的其他组织接替，
+// 移除负责人时清空组织负责人
+func (uc *OrganizationUsecase) RemoveMember(ctx context.Context, orgID, userID int32, leftAt time.Time, reason string, operatorID int32) error {
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > maxMemberReasonLength {
+		return ErrInvalidOrganizationMember
+	}
+	if leftAt.IsZero() {
+		leftAt = time.Now()
+	}
+	return uc.repo.RemoveOrganizationMember(ctx, orgID, userID, leftAt, reason, operatorID)
+}
+
+// ListMembers 获取组织成员，includeInactive为true时包含已离开的成员
+func (uc *OrganizationUsecase) ListMembers(ctx context.Context, orgID int32, includeInactive bool) ([]*OrganizationMember, error) {
+	return uc.repo.ListOrganizationMembers(ctx, orgID, includeInactive)
+}
+
+// ListUserMemberships 获取用户所属的组织，includeInactive为true时包含已离开的组织
+func (uc *OrganizationUsecase) ListUserMemberships(ctx context.Context, userID int32, includeInactive bool) ([]*OrganizationMember, error) {
+	return uc.repo.ListUserMemberships(ctx, userID, includeInactive)
+}
+
+// ListMemberHistory 获取组织成员变动记录，按时间倒序
+func (uc *OrganizationUsecase) ListMemberHistory(ctx context.Context, filter *MemberHistoryFilter) ([]*OrganizationMemberHistory, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 500
+	}
+	return uc.repo.ListMemberHistory(ctx, filter)
+}
@@ -0,0 +1,40 @@
+package biz_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizationUsecase_Members(t *testing.T) {
+	repo := &stubOrganizationRepo{}
+	uc := biz.NewOrganizationUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	member, err := uc.AddMember(ctx, &biz.OrganizationMember{UserID: 1, OrgID: 2, Position: "  经理 "}, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, "经理", member.Position)
+	assert.False(t, member.JoinedAt.IsZero())
+
+	_, err = uc.AddMember(ctx, &biz.OrganizationMember{UserID: 1, OrgID: 2, Position: strings.Repeat("职", 51)}, 9)
+	assert.Equal(t, biz.ErrInvalidOrganizationMember, err)
+	assert.Len(t, repo.members, 1)
+
+	position := strings.Repeat("a", 51)
+	_, err = uc.UpdateMember(ctx, 2, 1, &biz.OrganizationMemberUpdate{Position: &position}, 9)
+	assert.Equal(t, biz.ErrInvalidOrganizationMember, err)
+
+	assert.Equal(t, biz.ErrInvalidOrganizationMember, uc.RemoveMember(ctx, 2, 1, time.Time{}, strings.Repeat("因", 501), 9))
+	assert.NoError(t, uc.RemoveMember(ctx, 2, 1, time.Time{}, "调岗", 9))
+	assert.False(t, repo.removedAt.IsZero())
+
+	_, err = uc.ListMemberHistory(ctx, &biz.MemberHistoryFilter{OrgID: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(500), repo.historyFilter.Limit)
+}
@@ -0,0 +1,443 @@
+package biz
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+)
+
+// OrganizationDocType 组织在权限系统中的文档类型名称，用户权限按此类型限制可访问的组织
+const OrganizationDocType = "Organization"
+
+// 组织搜索和选项的返回数量限制
+const (
+	defaultOrganizationSearchLimit = 20
+	maxOrganizationSearchLimit     = 100
+	// searchSnippetRadius 描述匹配时高亮片段在匹配内容前后保留的字符数
+	searchSnippetRadius = 20
+)
+
+// OrganizationScope 用户权限对组织的访问范围。用户没有组织类用户权限时OrgIDs为nil，可访问全部组织；
+// 否则只能访问授权组织及其下级组织（用户权限设置了hide_descendants时不含下级）
+type OrganizationScope struct {
+	OrgIDs map[int32]bool
+}
+
+// Allows 组织是否在访问范围内
+func (s *OrganizationScope) Allows(id int32) bool {
+	return s == nil || s.OrgIDs == nil || s.OrgIDs[id]
+}
+
+// IDs 访问范围内的组织ID，不限制时返回nil
+func (s *OrganizationScope) IDs() []int32 {
+	if s == nil || s.OrgIDs == nil {
+		return nil
+	}
+	ids := make([]int32, 0, len(s.OrgIDs))
+	for id := range s.OrgIDs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// PositionStat 职位人数统计，未设置职位的成员归入空职位
+type PositionStat struct {
+	Position string `json:"position"`
+	Count    int32  `json:"count"`
+}
+
+// TypeStat 组织类型统计：该类型的组织数量及其成员人数
+type TypeStat struct {
+	Type    string `json:"type"`
+	Count   int32  `json:"count"`
+	Members int32  `json:"members"`
+}
+
+// OrganizationStats 组织人数统计，同一用户在多个组织任职时只计一次
+type OrganizationStats struct {
+	OrgID            int32           `json:"org_id"`
+	TotalMembers     int32           `json:"total_members"`     // 统计范围内的总人数
+	DirectMembers    int32           `json:"direct_members"`    // 直属成员人数
+	SubOrganizations int32           `json:"sub_organizations"` // 下级组织数，不含子组织时为直属子组织数
+	TotalSubMembers  int32           `json:"total_sub_members"` // 下级组织的总人数
+	PositionStats    []*PositionStat `json:"position_stats"`
+	TypeStats        []*TypeStat     `json:"type_stats"`
+}
+
+// OrganizationSearchFilter 组织搜索条件
+type OrganizationSearchFilter struct {
+	Keyword     string
+	OrgType     string
+	OnlyEnabled bool
+	Limit       int32
+	// OrgIDs 限定搜索范围，nil表示不限制
+	OrgIDs []int32
+}
+
+// OrganizationSearchResult 组织搜索结果
+type OrganizationSearchResult struct {
+	Organization *Organization `json:"organization"`
+	// Path 从顶级组织到该组织的路径，只包含调用者可访问的组织
+	Path         []*OrganizationOption `json:"path"`
+	MatchedField string                `json:"matched_field"` // name、code或description
+	Highlight    string                `json:"highlight"`     // 匹配内容，关键字用<em>标记，其余部分已转义
+}
+
+// OrganizationOptionFilter 组织选项筛选条件
+type OrganizationOptionFilter struct {
+	OnlyEnabled bool
+	OrgType     string
+	// ExcludeID 排除该组织及其下级组织，用于选择上级组织
+	ExcludeID int32
+}
+
+// OrganizationOption 组织下拉选项，被筛除的组织的下级挂到最近的保留上级下
+type OrganizationOption struct {
+	ID       int32                 `json:"id"`
+	Name     string                `json:"name"`
+	Code     string                `json:"code"`
+	Type     string                `json:"type,omitempty"`
+	Level    int32                 `json:"level,omitempty"`
+	PathName string                `json:"path_name,omitempty"` // 完整路径名称，如 集团 / 销售部
+	Children []*OrganizationOption `json:"children,omitempty"`
+}
+
+// GetUserScope 获取用户对组织的访问范围
+func (uc *OrganizationUsecase) GetUserScope(ctx context.Context, userID int64) (*OrganizationScope, error) {
+	return uc.repo.GetUserOrganizationScope(ctx, userID)
+}
+
+// GetStats 统计组织人数，includeSubOrgs为true时包含全部下级组织；下级组织按访问范围过滤
+func (uc *OrganizationUsecase) GetStats(ctx context.Context, id int32, includeSubOrgs bool, scope *OrganizationScope) (*OrganizationStats, error) {
+	if !scope.Allows(id) {
+		return nil, ErrOrganizationForbidden
+	}
+
+	maxDepth := int32(1)
+	if includeSubOrgs {
+		maxDepth = MaxOrganizationDepth
+	}
+	descendants, err := uc.repo.GetOrganizationDescendants(ctx, id, maxDepth, false)
+	if err != nil {
+		return nil, err
+	}
+	org, err := uc.repo.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	counted := []*Organization{org}
+	for _, descendant := range descendants {
+		if scope.Allows(descendant.ID) {
+			counted = append(counted, descendant)
+		}
+	}
+	subOrganizations := int32(len(counted) - 1)
+	if !includeSubOrgs {
+		counted = counted[:1]
+	}
+
+	orgIDs := make([]int32, len(counted))
+	for i, o := range counted {
+		orgIDs[i] = o.ID
+	}
+	members, err := uc.repo.ListActiveMemberships(ctx, orgIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := aggregateOrganizationStats(counted, members)
+	stats.OrgID = id
+	stats.SubOrganizations = subOrganizations
+	return stats, nil
+}
+
+// Search 按名称、编码和描述搜索组织，返回每个结果的组织路径和匹配内容
+func (uc *OrganizationUsecase) Search(ctx context.Context, filter *OrganizationSearchFilter, scope *OrganizationScope) ([]*OrganizationSearchResult, error) {
+	filter.Keyword = strings.TrimSpace(filter.Keyword)
+	if filter.Keyword == "" {
+		return nil, ErrInvalidOrganizationSearch
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultOrganizationSearchLimit
+	}
+	if filter.Limit > maxOrganizationSearchLimit {
+		filter.Limit = maxOrganizationSearchLimit
+	}
+	filter.OrgIDs = scope.IDs()
+	if filter.OrgIDs != nil && len(filter.OrgIDs) == 0 {
+		return []*OrganizationSearchResult{}, nil
+	}
+
+	orgs, err := uc.repo.SearchOrganizations(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// 一次性加载所有结果路径上的组织
+	ids := make(map[int32]bool)
+	for _, org := range orgs {
+		for _, id := range pathIDs(org.Path) {
+			ids[id] = true
+		}
+	}
+	pathOrgs, err := uc.repo.GetOrganizationsByIDs(ctx, sortedIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int32]*Organization, len(pathOrgs))
+	for _, org := range pathOrgs {
+		byID[org.ID] = org
+	}
+
+	results := make([]*OrganizationSearchResult, 0, len(orgs))
+	for _, org := range orgs {
+		result := &OrganizationSearchResult{Organization: org, Path: make([]*OrganizationOption, 0)}
+		for _, id := range pathIDs(org.Path) {
+			if ancestor := byID[id]; ancestor != nil && scope.Allows(id) {
+				result.Path = append(result.Path, &OrganizationOption{ID: ancestor.ID, Name: ancestor.Name, Code: ancestor.Code})
+			}
+		}
+		result.MatchedField, result.Highlight = highlightOrganization(org, filter.Keyword)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetOptions 获取组织下拉选项树，只包含访问范围内的组织
+func (uc *OrganizationUsecase) GetOptions(ctx context.Context, filter *OrganizationOptionFilter, scope *OrganizationScope) ([]*OrganizationOption, error) {
+	orgs, err := uc.repo.ListOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildOrganizationOptions(orgs, filter, scope), nil
+}
+
+// aggregateOrganizationStats 按成员关系统计人数，第一个组织为统计对象本身
+func aggregateOrganizationStats(orgs []*Organization, members []*OrganizationMember) *OrganizationStats {
+	orgType := make(map[int32]string, len(orgs))
+	typeOrgs := make(map[string]int32)
+	for _, org := range orgs {
+		orgType[org.ID] = org.OrgType
+		typeOrgs[org.OrgType]++
+	}
+
+	selfID := orgs[0].ID
+	total := make(map[int32]bool)
+	direct := make(map[int32]bool)
+	sub := make(map[int32]bool)
+	positions := make(map[string]map[int32]bool)
+	typeMembers := make(map[string]map[int32]bool)
+	for _, member := range members {
+		total[member.UserID] = true
+		if member.OrgID == selfID {
+			direct[member.UserID] = true
+		} else {
+			sub[member.UserID] = true
+		}
+		addToSet(positions, member.Position, member.UserID)
+		addToSet(typeMembers, orgType[member.OrgID], member.UserID)
+	}
+
+	stats := &OrganizationStats{
+		TotalMembers:    int32(len(total)),
+		DirectMembers:   int32(len(direct)),
+		TotalSubMembers: int32(len(sub)),
+		PositionStats:   make([]*PositionStat, 0, len(positions)),
+		TypeStats:       make([]*TypeStat, 0, len(typeOrgs)),
+	}
+	for position, users := range positions {
+		stats.PositionStats = append(stats.PositionStats, &PositionStat{Position: position, Count: int32(len(users))})
+	}
+	sort.Slice(stats.PositionStats, func(i, j int) bool {
+		a, b := stats.PositionStats[i], stats.PositionStats[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Position < b.Position
+	})
+	for orgType, count := range typeOrgs {
+		stats.TypeStats = append(stats.TypeStats, &TypeStat{Type: orgType, Count: count, Members: int32(len(typeMembers[orgType]))})
+	}
+	sort.Slice(stats.TypeStats, func(i, j int) bool { return stats.TypeStats[i].Type < stats.TypeStats[j].Type })
+	return stats
+}
+
+// addToSet 将用户加入分组集合
+func addToSet(groups map[string]map[int32]bool, key string, userID int32) {
+	if groups[key] == nil {
+		groups[key] = make(map[int32]bool)
+	}
+	groups[key][userID] = true
+}
+
+// buildOrganizationOptions 构建选项树：排除组织及其下级、范围外和被筛除的组织不返回，
+// 其保留的下级挂到最近的保留上级下；路径名称只包含保留的组织
+func buildOrganizationOptions(orgs []*Organization, filter *OrganizationOptionFilter, scope *OrganizationScope) []*OrganizationOption {
+	byID := make(map[int32]*Organization, len(orgs))
+	for _, org := range orgs {
+		byID[org.ID] = org
+	}
+
+	sort.SliceStable(orgs, func(i, j int) bool {
+		if orgs[i].Level != orgs[j].Level {
+			return orgs[i].Level < orgs[j].Level
+		}
+		if orgs[i].SortOrder != orgs[j].SortOrder {
+			return orgs[i].SortOrder < orgs[j].SortOrder
+		}
+		return orgs[i].ID < orgs[j].ID
+	})
+
+	keep := func(org *Organization) bool {
+		return scope.Allows(org.ID) &&
+			(!filter.OnlyEnabled || org.IsEnabled) &&
+			(filter.OrgType == "" || org.OrgType == filter.OrgType)
+	}
+	excluded := func(org *Organization) bool {
+		if filter.ExcludeID == 0 {
+			return false
+		}
+		for _, id := range pathIDs(org.Path) {
+			if id == filter.ExcludeID {
+				return true
+			}
+		}
+		return org.ID == filter.ExcludeID
+	}
+
+	options := make(map[int32]*OrganizationOption)
+	roots := make([]*OrganizationOption, 0)
+	for _, org := range orgs {
+		if !keep(org) || excluded(org) {
+			continue
+		}
+		option := &OrganizationOption{ID: org.ID, Name: org.Name, Code: org.Code, Type: org.OrgType, Level: org.Level}
+		options[org.ID] = option
+
+		// 沿路径查找最近的保留上级，停用组织的下级在only_enabled时一并隐藏
+		var parent *OrganizationOption
+		names := make([]string, 0)
+		hidden := false
+		for _, id := range pathIDs(org.Path) {
+			if id == org.ID {
+				continue
+			}
+			ancestor := byID[id]
+			if ancestor != nil && filter.OnlyEnabled && !ancestor.IsEnabled {
+				hidden = true
+				break
+			}
+			if opt := options[id]; opt != nil {
+				parent = opt
+				names = append(names, opt.Name)
+			}
+		}
+		if hidden {
+			delete(options, org.ID)
+			continue
+		}
+		option.PathName = strings.Join(append(names, org.Name), " / ")
+
+		if parent != nil {
+			parent.Children = append(parent.Children, option)
+		} else {
+			roots = append(roots, option)
+		}
+	}
+	return roots
+}
+
+// highlightOrganization 返回首个匹配的字段及高亮内容；描述只返回匹配位置附近的片段
+func highlightOrganization(org *Organization, keyword string) (string, string) {
+	fields := []struct {
+		name  string
+		value string
+	}{{"name", org.Name}, {"code", org.Code}, {"description", org.Description}}
+
+	lowerKeyword := []rune(strings.ToLower(keyword))
+	for _, field := range fields {
+		value := []rune(field.value)
+		lower := []rune(strings.ToLower(field.value))
+		start := indexRunes(lower, lowerKeyword)
+		if start < 0 || len(lower) != len(value) {
+			continue
+		}
+		end := start + len(lowerKeyword)
+
+		from, to := 0, len(value)
+		if field.name == "description" {
+			if from = start - searchSnippetRadius; from < 0 {
+				from = 0
+			}
+			if to = end + searchSnippetRadius; to > len(value) {
+				to = len(value)
+			}
+		}
+
+		var b strings.Builder
+		if from > 0 {
+			b.WriteString("…")
+		}
+		b.WriteString(html.EscapeString(string(value[from:start])))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(string(value[start:end])))
+		b.WriteString("</em>")
+		b.WriteString(html.EscapeString(string(value[end:to])))
+		if to < len(value) {
+			b.WriteString("…")
+		}
+		return field.name, b.String()
+	}
+	return "", html.EscapeString(org.Name)
+}
+
+// indexRunes 返回sub在s中首次出现的位置，按字符计
+func indexRunes(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// pathIDs 解析组织路径（如 /1/2/3/）中的组织ID，从顶级组织开始
+func pathIDs(path string) []int32 {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	ids := make([]int32, 0, len(parts))
+	for _, part := range parts {
+		var id int32
+		valid := part != ""
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				valid = false
+				break
+			}
+			id = id*10 + int32(c-'0')
+		}
+		if valid {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// sortedIDs 将ID集合转换为有序切片
+func sortedIDs(set map[int32]bool) []int32 {
+	ids := make([]int32, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
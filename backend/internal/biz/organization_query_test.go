@@ -0,0 +1,232 @@
+package biz_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubOrganizationRepo 在内存中保存组织层级
+type stubOrganizationRepo struct {
+	biz.OrganizationRepo
+	orgs map[int32]*biz.Organization
+
+	moves     int
+	maxDepths []int32
+
+	members       []*biz.OrganizationMember
+	removedAt     time.Time
+	historyFilter *biz.MemberHistoryFilter
+	searchFilter  *biz.OrganizationSearchFilter
+}
+
+func (r *stubOrganizationRepo) MoveOrganization(ctx context.Context, id int32, parentID *int32) error {
+	r.moves++
+	r.orgs[id].ParentID = parentID
+	return nil
+}
+
+func (r *stubOrganizationRepo) AddOrganizationMember(ctx context.Context, member *biz.OrganizationMember, operatorID int32) (*biz.OrganizationMember, error) {
+	r.members = append(r.members, member)
+	return member, nil
+}
+
+func (r *stubOrganizationRepo) RemoveOrganizationMember(ctx context.Context, orgID, userID int32, leftAt time.Time, reason string, operatorID int32) error {
+	r.removedAt = leftAt
+	return nil
+}
+
+func (r *stubOrganizationRepo) ListMemberHistory(ctx context.Context, filter *biz.MemberHistoryFilter) ([]*biz.OrganizationMemberHistory, error) {
+	r.historyFilter = filter
+	return nil, nil
+}
+
+func (r *stubOrganizationRepo) GetOrganizationDescendants(ctx context.Context, id int32, maxDepth int32, onlyEnabled bool) ([]*biz.Organization, error) {
+	r.maxDepths = append(r.maxDepths, maxDepth)
+	if r.orgs[id] == nil {
+		return nil, biz.ErrOrganizationNotFound
+	}
+	descendants := []*biz.Organization{}
+	for _, org := range r.sortedOrgs() {
+		depth := int32(0)
+		for parent := org; parent.ParentID != nil; parent = r.orgs[*parent.ParentID] {
+			depth++
+			if *parent.ParentID == id {
+				if depth <= maxDepth {
+					descendants = append(descendants, org)
+				}
+				break
+			}
+		}
+	}
+	return descendants, nil
+}
+
+func (r *stubOrganizationRepo) sortedOrgs() []*biz.Organization {
+	orgs := make([]*biz.Organization, 0, len(r.orgs))
+	for _, org := range r.orgs {
+		orgs = append(orgs, org)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return orgs
+}
+
+func (r *stubOrganizationRepo) GetOrganization(ctx context.Context, id int32) (*biz.Organization, error) {
+	if r.orgs[id] == nil {
+		return nil, biz.ErrOrganizationNotFound
+	}
+	return r.orgs[id], nil
+}
+
+func (r *stubOrganizationRepo) ListActiveMemberships(ctx context.Context, orgIDs []int32) ([]*biz.OrganizationMember, error) {
+	var members []*biz.OrganizationMember
+	for _, member := range r.members {
+		for _, id := range orgIDs {
+			if member.OrgID == id {
+				members = append(members, member)
+			}
+		}
+	}
+	return members, nil
+}
+
+func (r *stubOrganizationRepo) SearchOrganizations(ctx context.Context, filter *biz.OrganizationSearchFilter) ([]*biz.Organization, error) {
+	r.searchFilter = filter
+	var orgs []*biz.Organization
+	for _, org := range r.sortedOrgs() {
+		if strings.Contains(org.Name, filter.Keyword) {
+			orgs = append(orgs, org)
+		}
+	}
+	return orgs, nil
+}
+
+func (r *stubOrganizationRepo) GetOrganizationsByIDs(ctx context.Context, ids []int32) ([]*biz.Organization, error) {
+	var orgs []*biz.Organization
+	for _, id := range ids {
+		if r.orgs[id] != nil {
+			orgs = append(orgs, r.orgs[id])
+		}
+	}
+	return orgs, nil
+}
+
+func (r *stubOrganizationRepo) ListOrganizations(ctx context.Context) ([]*biz.Organization, error) {
+	return r.sortedOrgs(), nil
+}
+
+func (r *stubOrganizationRepo) GetOrganizationAncestors(ctx context.Context, id int32) ([]*biz.Organization, error) {
+	var ancestors []*biz.Organization
+	for org := r.orgs[id]; org != nil; {
+		ancestors = append(ancestors, org)
+		if org.ParentID == nil {
+			break
+		}
+		org = r.orgs[*org.ParentID]
+	}
+	if len(ancestors) == 0 {
+		return nil, biz.ErrOrganizationNotFound
+	}
+	return ancestors, nil
+}
+
+func TestOrganizationUsecase_Query(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+	repo := &stubOrganizationRepo{
+		orgs: map[int32]*biz.Organization{
+			1: {ID: 1, Name: "集团", Code: "HQ", OrgType: "company", IsEnabled: true, Level: 1, Path: "/1/"},
+			2: {ID: 2, Name: "销售部", Code: "SALES", OrgType: "department", IsEnabled: true, ParentID: int32Ptr(1), Level: 2, Path: "/1/2/"},
+			3: {ID: 3, Name: "销售一组", Code: "SALES-1", OrgType: "team", IsEnabled: true, ParentID: int32Ptr(2), Level: 3, Path: "/1/2/3/"},
+			4: {ID: 4, Name: "研发部", Code: "RD", OrgType: "department", ParentID: int32Ptr(1), Level: 2, Path: "/1/4/"},
+		},
+		members: []*biz.OrganizationMember{
+			{UserID: 10, OrgID: 2, Position: "经理"},
+			{UserID: 10, OrgID: 3, Position: "经理"},
+			{UserID: 11, OrgID: 3, Position: "销售"},
+			{UserID: 12, OrgID: 4, Position: "工程师"},
+		},
+	}
+	uc := biz.NewOrganizationUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 同一用户在多个组织任职时只计一次
+	stats, err := uc.GetStats(ctx, 2, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), stats.TotalMembers)
+	assert.Equal(t, int32(1), stats.DirectMembers)
+	assert.Equal(t, int32(1), stats.SubOrganizations)
+	assert.Equal(t, int32(2), stats.TotalSubMembers)
+	assert.Equal(t, []*biz.PositionStat{{Position: "经理", Count: 1}, {Position: "销售", Count: 1}}, stats.PositionStats)
+	assert.Equal(t, []*biz.TypeStat{{Type: "department", Count: 1, Members: 1}, {Type: "team", Count: 1, Members: 2}}, stats.TypeStats)
+
+	stats, err = uc.GetStats(ctx, 1, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), stats.SubOrganizations)
+	assert.Equal(t, int32(0), stats.TotalMembers)
+
+	// 访问范围外的组织不可统计，范围外的下级组织不计入
+	scope := &biz.OrganizationScope{OrgIDs: map[int32]bool{1: true, 2: true}}
+	_, err = uc.GetStats(ctx, 3, false, scope)
+	assert.Equal(t, biz.ErrOrganizationForbidden, err)
+	stats, err = uc.GetStats(ctx, 1, true, scope)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), stats.SubOrganizations)
+	assert.Equal(t, int32(1), stats.TotalMembers)
+
+	_, err = uc.Search(ctx, &biz.OrganizationSearchFilter{Keyword: "  "}, nil)
+	assert.Equal(t, biz.ErrInvalidOrganizationSearch, err)
+
+	results, err := uc.Search(ctx, &biz.OrganizationSearchFilter{Keyword: "一组", Limit: 500}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(100), repo.searchFilter.Limit)
+	assert.Nil(t, repo.searchFilter.OrgIDs)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "name", results[0].MatchedField)
+		assert.Equal(t, "销售<em>一组</em>", results[0].Highlight)
+		assert.Len(t, results[0].Path, 3)
+	}
+
+	results, err = uc.Search(ctx, &biz.OrganizationSearchFilter{Keyword: "一组"}, &biz.OrganizationScope{OrgIDs: map[int32]bool{3: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{3}, repo.searchFilter.OrgIDs)
+	if assert.Len(t, results, 1) && assert.Len(t, results[0].Path, 1) {
+		assert.Equal(t, "销售一组", results[0].Path[0].Name)
+	}
+
+	options, err := uc.GetOptions(ctx, &biz.OrganizationOptionFilter{}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, options, 1) && assert.Len(t, options[0].Children, 2) {
+		assert.Equal(t, "集团 / 销售部 / 销售一组", options[0].Children[0].Children[0].PathName)
+	}
+
+	// 排除组织及其下级，只保留启用的组织
+	options, err = uc.GetOptions(ctx, &biz.OrganizationOptionFilter{ExcludeID: 2}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, options, 1) && assert.Len(t, options[0].Children, 1) {
+		assert.Equal(t, int32(4), options[0].Children[0].ID)
+	}
+	options, err = uc.GetOptions(ctx, &biz.OrganizationOptionFilter{OnlyEnabled: true}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, options, 1) {
+		assert.Len(t, options[0].Children, 1)
+	}
+
+	// 被筛除组织的下级挂到最近的保留上级下
+	options, err = uc.GetOptions(ctx, &biz.OrganizationOptionFilter{}, &biz.OrganizationScope{OrgIDs: map[int32]bool{1: true, 3: true}})
+	assert.NoError(t, err)
+	if assert.Len(t, options, 1) && assert.Len(t, options[0].Children, 1) {
+		assert.Equal(t, "集团 / 销售一组", options[0].Children[0].PathName)
+	}
+	options, err = uc.GetOptions(ctx, &biz.OrganizationOptionFilter{OrgType: "team"}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, options, 1) {
+		assert.Equal(t, int32(3), options[0].ID)
+	}
+}
@@ -58,18 +58,8 @@ func (d *DocType) Validate() error {
 	}
 
 	// 验证命名规则
-	if d.NamingRule != "" {
-		validNamingRules := []string{"autoname", "prompt", "field", "series", "random", "expression"}
-		isValid := false
-		for _, rule := range validNamingRules {
-			if d.NamingRule == rule {
-				isValid = true
-				break
-			}
-		}
-		if !isValid {
-			return fmt.Errorf("invalid naming rule: %s", d.NamingRule)
-		}
+	if _, err := ParseNamingRule(d.NamingRule); err != nil {
+		return err
 	}
 
 	// 验证排序方向
@@ -77,9 +67,41 @@ func (d *DocType) Validate() error {
 		return fmt.Errorf("sort order must be ASC or DESC")
 	}
 
+	// 验证字段写入屏蔽模式
+	if mode, ok := d.Permissions[FieldWriteModeKey]; ok {
+		if mode != FieldWriteModeReject && mode != FieldWriteModeDrop {
+			return fmt.Errorf("invalid field write mode: %v", mode)
+		}
+	}
+
 	return nil
 }
 
+// 字段写入屏蔽模式，配置在DocType.Permissions的field_write_mode键中
+const (
+	FieldWriteModeKey    = "field_write_mode"
+	FieldWriteModeReject = "reject" // 拒绝整个请求并返回越权字段列表
+	FieldWriteModeDrop   = "drop"   // 静默丢弃越权字段，其余字段照常写入
+)
+
+// FieldWriteMode 获取字段写入屏蔽模式，未配置时默认拒绝
+func (d *DocType) FieldWriteMode() string {
+	if mode, ok := d.Permissions[FieldWriteModeKey].(string); ok && mode == FieldWriteModeDrop {
+		return FieldWriteModeDrop
+	}
+	return FieldWriteModeReject
+}
+
+// FieldWriteForbiddenError 字段写入越权错误
+type FieldWriteForbiddenError struct {
+	DocType string
+	Fields  []string
+}
+
+func (e *FieldWriteForbiddenError) Error() string {
+	return fmt.Sprintf("no write permission on %s fields: %s", e.DocType, strings.Join(e.Fields, ", "))
+}
+
 // PermissionRule 权限规则
 type PermissionRule struct {
 	ID              int64  `json:"id"`
@@ -106,7 +128,8 @@ type PermissionRule struct {
 	CanSetUserPermissions bool `json:"can_set_user_permissions"` // 设置用户权限
 
 	// 条件权限
-	OnlyIfCreator bool `json:"only_if_creator"` // 仅创建者可访问
+	OnlyIfCreator bool   `json:"only_if_creator"`     // 仅创建者可访问
+	Condition     string `json:"condition,omitempty"` // 条件表达式，如 doc.amount < 10000，为空表示无条件
 
 	// 审计字段
 	CreatedAt time.Time `json:"created_at"`
@@ -144,14 +167,32 @@ func (r *PermissionRule) Validate() error {
 	if r.PermissionLevel > 0 {
 		// 字段级权限不应该有文档级的权限设置
 		if r.CanCreate || r.CanDelete || r.CanSubmit || r.CanCancel || r.CanAmend ||
-			r.CanPrint || r.CanEmail || r.CanImport || r.CanExport || r.CanShare || r.CanReport {
+			r.CanPrint || r.CanEmail || r.CanImport || r.CanExport || r.CanShare || r.CanReport ||
+			r.CanSetUserPermissions {
 			return fmt.Errorf("field level permissions (level > 0) should only use CanRead and CanWrite")
 		}
 	}
 
+	// 条件表达式语法检查，类型检查需要文档字段信息，在保存时进行
+	if strings.TrimSpace(r.Condition) != "" {
+		if _, err := ParsePermissionCondition(r.Condition); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// EffectivePermissionRule 角色的生效权限规则，包含沿继承链从上级角色获得的规则
+type EffectivePermissionRule struct {
+	*PermissionRule
+	SourceRoleID   int64  `json:"source_role_id"`   // 规则所属角色
+	SourceRoleCode string `json:"source_role_code"` // 规则所属角色编码
+	SourceRoleName string `json:"source_role_name"` // 规则所属角色名称
+	Inherited      bool   `json:"inherited"`        // 是否继承自上级角色
+	Depth          int    `json:"depth"`            // 继承层级，0表示角色自身的规则
+}
+
 // UserPermission 用户权限 - 数据范围权限
 type UserPermission struct {
 	ID              int64     `json:"id"`
@@ -227,27 +268,8 @@ func (f *FieldPermissionLevel) Validate() error {
 	}
 
 	// 验证字段类型
-	if strings.TrimSpace(f.FieldType) != "" {
-		validTypes := []string{
-			"Data", "Text", "Long Text", "HTML", "Markdown",
-			"Int", "Float", "Currency", "Percent",
-			"Date", "Datetime", "Time",
-			"Select", "Link", "Dynamic Link", "Table", "Check",
-			"Small Text", "Text Editor", "Code", "Password",
-			"Attach", "Attach Image", "Signature", "Color",
-			"Barcode", "Geolocation", "Duration", "Rating",
-		}
-
-		isValidType := false
-		for _, validType := range validTypes {
-			if f.FieldType == validType {
-				isValidType = true
-				break
-			}
-		}
-		if !isValidType {
-			return fmt.Errorf("invalid field type: %s", f.FieldType)
-		}
+	if strings.TrimSpace(f.FieldType) != "" && !IsValidFieldType(f.FieldType) {
+		return fmt.Errorf("invalid field type: %s", f.FieldType)
 	}
 
 	return nil
@@ -317,6 +339,77 @@ type AccessibleField struct {
 	CanAccess       bool   `json:"can_access"`
 }
 
+// LevelPermissions 用户在某文档类型上按权限级别授予的读写权限
+// 第n位对应permission_level=n，多个角色的授权按位合并
+type LevelPermissions struct {
+	ReadMask  uint16 `json:"read_mask"`
+	WriteMask uint16 `json:"write_mask"`
+}
+
+// Grant 授予指定级别的读写权限
+func (p *LevelPermissions) Grant(level int, canRead, canWrite bool) {
+	if level < 0 || level > 15 {
+		return
+	}
+	if canRead {
+		p.ReadMask |= 1 << uint(level)
+	}
+	if canWrite {
+		p.WriteMask |= 1 << uint(level)
+	}
+}
+
+// CanRead 是否拥有指定级别的读权限
+func (p *LevelPermissions) CanRead(level int) bool {
+	return level >= 0 && level <= 15 && p.ReadMask&(1<<uint(level)) != 0
+}
+
+// CanWrite 是否拥有指定级别的写权限
+func (p *LevelPermissions) CanWrite(level int) bool {
+	return level >= 0 && level <= 15 && p.WriteMask&(1<<uint(level)) != 0
+}
+
+// Allows 按操作类型判断指定级别是否可访问，write之外的操作均按读权限处理
+func (p *LevelPermissions) Allows(level int, permission string) bool {
+	if permission == "write" {
+		return p.CanWrite(level)
+	}
+	return p.CanRead(level)
+}
+
+// ReadLevels 返回拥有读权限的级别列表
+func (p *LevelPermissions) ReadLevels() []int {
+	return maskLevels(p.ReadMask)
+}
+
+// WriteLevels 返回拥有写权限的级别列表
+func (p *LevelPermissions) WriteLevels() []int {
+	return maskLevels(p.WriteMask)
+}
+
+// Bitmask 将读写掩码编码为单个整数，低16位为读、高16位为写，用于缓存
+func (p *LevelPermissions) Bitmask() int64 {
+	return int64(p.ReadMask) | int64(p.WriteMask)<<16
+}
+
+// LevelPermissionsFromBitmask 从缓存的整数还原读写掩码
+func LevelPermissionsFromBitmask(mask int64) *LevelPermissions {
+	return &LevelPermissions{
+		ReadMask:  uint16(mask & 0xFFFF),
+		WriteMask: uint16((mask >> 16) & 0xFFFF),
+	}
+}
+
+func maskLevels(mask uint16) []int {
+	levels := []int{}
+	for level := 0; level <= 15; level++ {
+		if mask&(1<<uint(level)) != 0 {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
 // ================================================================
 // 权限检查请求和响应
 // ================================================================
@@ -329,6 +422,9 @@ type PermissionCheckRequest struct {
 	PermissionLevel int    `json:"permission_level"`
 	DocID           *int64 `json:"doc_id,omitempty"`
 	DocCreatorID    *int64 `json:"doc_creator_id,omitempty"`
+
+	// Document 文档属性，提供时按权限规则的条件表达式逐条判断
+	Document map[string]interface{} `json:"document,omitempty"`
 }
 
 // Validate 验证PermissionCheckRequest数据的完整性和正确性
@@ -352,7 +448,7 @@ func (r *PermissionCheckRequest) Validate() error {
 	// 验证权限类型
 	validPermissions := []string{
 		"read", "write", "create", "delete", "submit", "cancel", "amend",
-		"print", "email", "import", "export", "share", "report",
+		"print", "email", "import", "export", "share", "report", "set_user_permissions",
 	}
 
 	isValidPermission := false
@@ -422,7 +518,8 @@ type CreatePermissionRuleRequest struct {
 	CanShare  bool `json:"can_share"`
 	CanReport bool `json:"can_report"`
 
-	OnlyIfCreator bool `json:"only_if_creator"`
+	OnlyIfCreator bool   `json:"only_if_creator"`
+	Condition     string `json:"condition,omitempty"`
 }
 
 // Validate 验证CreatePermissionRuleRequest数据的完整性和正确性
@@ -528,6 +625,10 @@ type PermissionRepo interface {
 	ListPermissionRules(ctx context.Context, roleID int64, docType string) ([]*PermissionRule, error)
 	DeletePermissionRule(ctx context.Context, id int64) error
 
+	// 角色继承
+	ListEffectivePermissionRules(ctx context.Context, roleID int64, docType string) ([]*EffectivePermissionRule, error)
+	GetDescendantRoleIDs(ctx context.Context, roleID int64) ([]int64, error)
+
 	// 用户权限管理
 	CreateUserPermission(ctx context.Context, userPermission *UserPermission) (*UserPermission, error)
 	UpdateUserPermission(ctx context.Context, userPermission *UserPermission) (*UserPermission, error)
@@ -556,14 +657,45 @@ type PermissionRepo interface {
 	CheckDocumentPermission(ctx context.Context, req *PermissionCheckRequest) (bool, error)
 	CheckPermission(ctx context.Context, userID int64, documentType, action string, permissionLevel int) (bool, error)
 	GetUserPermissionLevel(ctx context.Context, userID int64, documentType string) (int, error)
+	GetUserLevelPermissions(ctx context.Context, userID int64, documentType string) (*LevelPermissions, error)
 	GetUserEnhancedPermissions(ctx context.Context, userID int64, docType string) ([]*EnhancedUserPermission, error)
 	GetAccessibleFields(ctx context.Context, req *FieldPermissionRequest) ([]*AccessibleField, error)
 	FilterDocumentsByPermission(ctx context.Context, userID int64, documentType string, documents []map[string]interface{}) ([]map[string]interface{}, error)
 	GetUserRoles(ctx context.Context, userID int64) ([]string, error)
 
+	// GetNextRoleAssignmentChange 获取用户下一次角色分配生效或失效的时间，无待变更时返回nil
+	GetNextRoleAssignmentChange(ctx context.Context, userID int64, now time.Time) (*time.Time, error)
+
 	// 批量操作
 	BatchCreatePermissionRules(ctx context.Context, rules []*PermissionRule) error
 	BatchCreateUserPermissions(ctx context.Context, permissions []*UserPermission) error
+	// UpsertPermissionRules 批量写入权限规则，同一公司、角色、文档类型和级别已存在规则时覆盖其权限设置和条件
+	UpsertPermissionRules(ctx context.Context, rules []*PermissionRule) error
+
+	// 权限矩阵
+	ListRoleCodes(ctx context.Context) (map[int64]string, error)
+	// ReplacePermissionRules 在一个事务中写入upserts并删除removals
+	ReplacePermissionRules(ctx context.Context, upserts, removals []*PermissionRule) error
+
+	// 权限配置版本
+	// RestorePermissionSnapshot 在一个事务中用快照替换当前公司的权限规则和用户权限，字段权限级别在公司间共享，不随快照恢复
+	RestorePermissionSnapshot(ctx context.Context, snapshot *PermissionSnapshot) error
+
+	// 权限缓存
+	// ClearUserPermissionCache 用户的角色分配变化后清除其权限缓存，无缓存的实现直接返回
+	ClearUserPermissionCache(ctx context.Context, userIDs ...int64) error
+	// ClearRolePermissionCache 清除角色、其全部下级角色及持有这些角色的用户的权限缓存
+	ClearRolePermissionCache(ctx context.Context, roleID int64) error
+	// ListRoleHolderIDs 获取直接分配或受委托持有任一角色的用户，不论分配是否在有效期内
+	ListRoleHolderIDs(ctx context.Context, roleIDs []int64) ([]int64, error)
+
+	// 条件权限
+	// GetConditionFieldTypes 获取文档类型已登记字段的条件表达式类型
+	GetConditionFieldTypes(ctx context.Context, docType string) (map[string]ConditionType, error)
+	// ListUserPermissionRules 获取用户生效角色（含继承角色）在指定文档类型和级别上的权限规则
+	ListUserPermissionRules(ctx context.Context, userID int64, docType string, permissionLevel int) ([]*PermissionRule, error)
+	// GetUserConditionAttributes 获取条件表达式可引用的用户属性，键与ConditionUserAttributes一致
+	GetUserConditionAttributes(ctx context.Context, userID int64) (map[string]interface{}, error)
 }
 
 // ================================================================
@@ -587,6 +719,8 @@ type PermissionUsecaseInterface interface {
 	UpdatePermissionRule(ctx context.Context, rule *PermissionRule) (*PermissionRule, error)
 	DeletePermissionRule(ctx context.Context, id int64) error
 	BatchCreatePermissionRules(ctx context.Context, rules []*PermissionRule) error
+	ListEffectivePermissionRules(ctx context.Context, roleID int64, docType string) ([]*EffectivePermissionRule, error)
+	GetDescendantRoleIDs(ctx context.Context, roleID int64) ([]int64, error)
 
 	// 用户权限管理
 	CreateUserPermission(ctx context.Context, userPermission *UserPermission) (*UserPermission, error)
@@ -619,9 +753,11 @@ type PermissionUsecaseInterface interface {
 	CheckUserPermission(ctx context.Context, userID int64, permissionCode string) (bool, error)
 	CheckPermission(ctx context.Context, userID int64, documentType, action string, permissionLevel int) (bool, error)
 	GetUserPermissionLevel(ctx context.Context, userID int64, documentType string) (int, error)
+	GetUserLevelPermissions(ctx context.Context, userID int64, documentType string) (*LevelPermissions, error)
 	GetUserEnhancedPermissions(ctx context.Context, userID int64, docType string) ([]*EnhancedUserPermission, error)
 	GetAccessibleFields(ctx context.Context, req *FieldPermissionRequest) (*FieldPermissionResponse, error)
 	FilterDocumentsByPermission(ctx context.Context, userID int64, documentType string, documents []map[string]interface{}) ([]map[string]interface{}, error)
+	GuardFieldWrites(ctx context.Context, userID int64, documentType string, fields []string) ([]string, error)
 	GetUserRoles(ctx context.Context, userID int64) ([]string, error)
 }
 
@@ -661,6 +797,9 @@ func (uc *PermissionUsecase) ListDocTypes(ctx context.Context, module string) ([
 func (uc *PermissionUsecase) CreatePermissionRule(ctx context.Context, rule *PermissionRule) (*PermissionRule, error) {
 	rule.CreatedAt = time.Now()
 	rule.UpdatedAt = time.Now()
+	if err := uc.checkRuleCondition(ctx, rule); err != nil {
+		return nil, err
+	}
 	return uc.repo.CreatePermissionRule(ctx, rule)
 }
 
@@ -683,10 +822,14 @@ func (uc *PermissionUsecase) CreatePermissionRuleFromRequest(ctx context.Context
 		CanShare:        req.CanShare,
 		CanReport:       req.CanReport,
 		OnlyIfCreator:   req.OnlyIfCreator,
+		Condition:       req.Condition,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 
+	if err := uc.checkRuleCondition(ctx, rule); err != nil {
+		return nil, err
+	}
 	return uc.repo.CreatePermissionRule(ctx, rule)
 }
 
@@ -694,6 +837,16 @@ func (uc *PermissionUsecase) ListPermissionRules(ctx context.Context, roleID int
 	return uc.repo.ListPermissionRules(ctx, roleID, docType)
 }
 
+// ListEffectivePermissionRules 获取角色自身及继承自上级角色的全部权限规则
+func (uc *PermissionUsecase) ListEffectivePermissionRules(ctx context.Context, roleID int64, docType string) ([]*EffectivePermissionRule, error) {
+	return uc.repo.ListEffectivePermissionRules(ctx, roleID, docType)
+}
+
+// GetDescendantRoleIDs 获取继承该角色的全部下级角色
+func (uc *PermissionUsecase) GetDescendantRoleIDs(ctx context.Context, roleID int64) ([]int64, error) {
+	return uc.repo.GetDescendantRoleIDs(ctx, roleID)
+}
+
 // 用户权限管理
 func (uc *PermissionUsecase) CreateUserPermission(ctx context.Context, userPermission *UserPermission) (*UserPermission, error) {
 	userPermission.CreatedAt = time.Now()
@@ -719,7 +872,14 @@ func (uc *PermissionUsecase) CreateUserPermissionFromRequest(ctx context.Context
 
 // 权限检查
 func (uc *PermissionUsecase) CheckDocumentPermission(ctx context.Context, req *PermissionCheckRequest) (*PermissionCheckResponse, error) {
-	hasPermission, err := uc.repo.CheckDocumentPermission(ctx, req)
+	var hasPermission bool
+	var err error
+	if req.Document != nil {
+		// 提供文档属性时按规则条件判断
+		hasPermission, err = uc.checkDocumentConditions(ctx, req)
+	} else {
+		hasPermission, err = uc.repo.CheckDocumentPermission(ctx, req)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -753,7 +913,8 @@ func (uc *PermissionUsecase) CheckUserPermission(ctx context.Context, userID int
 	return uc.repo.CheckPermission(ctx, userID, "User", "read", 0)
 }
 
-// CheckPermission 检查ERP权限
+// CheckPermission 检查文档类型级的ERP权限，只有无条件且不限创建者的规则授予；
+// 对具体文档的访问使用GetPermissionScope
 func (uc *PermissionUsecase) CheckPermission(ctx context.Context, userID int64, documentType, action string, permissionLevel int) (bool, error) {
 	return uc.repo.CheckPermission(ctx, userID, documentType, action, permissionLevel)
 }
@@ -763,6 +924,11 @@ func (uc *PermissionUsecase) GetUserPermissionLevel(ctx context.Context, userID
 	return uc.repo.GetUserPermissionLevel(ctx, userID, documentType)
 }
 
+// GetUserLevelPermissions 获取用户在各权限级别上的读写授权
+func (uc *PermissionUsecase) GetUserLevelPermissions(ctx context.Context, userID int64, documentType string) (*LevelPermissions, error) {
+	return uc.repo.GetUserLevelPermissions(ctx, userID, documentType)
+}
+
 // ================================================================
 // 额外的业务逻辑方法实现
 // ================================================================
@@ -784,6 +950,9 @@ func (uc *PermissionUsecase) GetPermissionRule(ctx context.Context, id int64) (*
 
 func (uc *PermissionUsecase) UpdatePermissionRule(ctx context.Context, rule *PermissionRule) (*PermissionRule, error) {
 	rule.UpdatedAt = time.Now()
+	if err := uc.checkRuleCondition(ctx, rule); err != nil {
+		return nil, err
+	}
 	return uc.repo.UpdatePermissionRule(ctx, rule)
 }
 
@@ -884,3 +1053,109 @@ func (uc *PermissionUsecase) FilterDocumentsByPermission(ctx context.Context, us
 func (uc *PermissionUsecase) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
 	return uc.repo.GetUserRoles(ctx, userID)
 }
+
+// fieldPermissionLevelPageSize 分页读取全部字段权限级别时的每页数量
+const fieldPermissionLevelPageSize = 500
+
+// FieldPermissionLevelLister 可分页列出字段权限级别的仓储
+type FieldPermissionLevelLister interface {
+	ListFieldPermissionLevels(ctx context.Context, docType string, page, size int32) ([]*FieldPermissionLevel, error)
+}
+
+// ListAllFieldPermissionLevels 分页读取文档类型的全部字段权限级别，避免单页上限截断字段
+func ListAllFieldPermissionLevels(ctx context.Context, lister FieldPermissionLevelLister, docType string) ([]*FieldPermissionLevel, error) {
+	var all []*FieldPermissionLevel
+	for page := int32(1); ; page++ {
+		levels, err := lister.ListFieldPermissionLevels(ctx, docType, page, fieldPermissionLevelPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, levels...)
+		if len(levels) < fieldPermissionLevelPageSize {
+			return all, nil
+		}
+	}
+}
+
+// GuardFieldWrites 检查用户对即将写入字段的写权限
+// 字段级别取自field_permission_levels，未登记的字段按文档级(0)处理；
+// 该DocType未配置任何字段级别时不做限制。
+// reject模式下存在越权字段时返回FieldWriteForbiddenError，drop模式下返回需要丢弃的字段
+func (uc *PermissionUsecase) GuardFieldWrites(ctx context.Context, userID int64, documentType string, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	fieldLevels, err := ListAllFieldPermissionLevels(ctx, uc.repo, documentType)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldLevels) == 0 {
+		return nil, nil
+	}
+
+	levelOf := make(map[string]int, len(fieldLevels))
+	for _, fl := range fieldLevels {
+		levelOf[fl.FieldName] = fl.PermissionLevel
+	}
+
+	levelPerms, err := uc.repo.GetUserLevelPermissions(ctx, userID, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var forbidden []string
+	for _, field := range fields {
+		if !levelPerms.CanWrite(levelOf[field]) {
+			forbidden = append(forbidden, field)
+		}
+	}
+	if len(forbidden) == 0 {
+		return nil, nil
+	}
+
+	// 获取DocType的屏蔽模式，DocType不存在时按默认的拒绝模式处理
+	mode := FieldWriteModeReject
+	if docType, err := uc.repo.GetDocType(ctx, documentType); err == nil {
+		mode = docType.FieldWriteMode()
+	} else {
+		uc.logger.Warnf("failed to get doctype %s for field write guard: %v", documentType, err)
+	}
+
+	if mode == FieldWriteModeDrop {
+		uc.logger.Infof("dropped unwritable %s fields for user %d: %v", documentType, userID, forbidden)
+		return forbidden, nil
+	}
+
+	return nil, &FieldWriteForbiddenError{DocType: documentType, Fields: forbidden}
+}
+
+// UnreadableFields 返回给定字段中用户无读权限的字段
+// 与FilterDocumentsByPermission一致，未登记级别的字段按文档级(0)处理
+func (uc *PermissionUsecase) UnreadableFields(ctx context.Context, userID int64, documentType string, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	fieldLevels, err := ListAllFieldPermissionLevels(ctx, uc.repo, documentType)
+	if err != nil {
+		return nil, err
+	}
+	levelOf := make(map[string]int, len(fieldLevels))
+	for _, fl := range fieldLevels {
+		levelOf[fl.FieldName] = fl.PermissionLevel
+	}
+
+	levelPerms, err := uc.repo.GetUserLevelPermissions(ctx, userID, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var unreadable []string
+	for _, field := range fields {
+		if !levelPerms.CanRead(levelOf[field]) {
+			unreadable = append(unreadable, field)
+		}
+	}
+	return unreadable, nil
+}
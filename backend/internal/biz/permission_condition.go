@@ -0,0 +1,986 @@
+package biz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ConditionType 条件表达式的值类型
+type ConditionType string
+
+// 条件表达式的值类型，列表类型以"[]"为前缀
+const (
+	ConditionTypeAny    ConditionType = "any"
+	ConditionTypeBool   ConditionType = "bool"
+	ConditionTypeNumber ConditionType = "number"
+	ConditionTypeString ConditionType = "string"
+	ConditionTypeNull   ConditionType = "null"
+)
+
+// listOf 返回元素类型为t的列表类型
+func listOf(t ConditionType) ConditionType {
+	return "[]" + t
+}
+
+// IsList 是否为列表类型
+func (t ConditionType) IsList() bool {
+	return strings.HasPrefix(string(t), "[]")
+}
+
+// Elem 列表的元素类型
+func (t ConditionType) Elem() ConditionType {
+	return ConditionType(strings.TrimPrefix(string(t), "[]"))
+}
+
+// compatible 两个类型能否比较，any和null与任意标量类型兼容
+func (t ConditionType) compatible(other ConditionType) bool {
+	if t == ConditionTypeAny || other == ConditionTypeAny {
+		return true
+	}
+	if t.IsList() || other.IsList() {
+		return t == other
+	}
+	return t == other || t == ConditionTypeNull || other == ConditionTypeNull
+}
+
+// ConditionUserAttributes 条件表达式中可引用的用户属性及其类型
+var ConditionUserAttributes = map[string]ConditionType{
+	"id":         ConditionTypeNumber,
+	"username":   ConditionTypeString,
+	"email":      ConditionTypeString,
+	"roles":      listOf(ConditionTypeString), // 生效角色编码，含继承角色
+	"company_id": ConditionTypeNumber,         // 当前公司
+	"org_id":     ConditionTypeNumber,         // 当前公司的主组织
+	"org_ids":    listOf(ConditionTypeNumber), // 在当前公司所属的全部组织
+}
+
+// ConditionFieldType 将字段类型映射为条件表达式类型，未知类型不做检查
+func ConditionFieldType(fieldType string) ConditionType {
+	switch fieldType {
+	case "Int", "Float", "Currency", "Percent":
+		return ConditionTypeNumber
+	case "Check":
+		return ConditionTypeBool
+	case "Data", "Link", "Select", "Text", "Small Text", "Long Text", "Date", "Datetime", "Time":
+		return ConditionTypeString
+	}
+	return ConditionTypeAny
+}
+
+// ErrInvalidPermissionCondition 权限条件表达式语法或类型错误
+var ErrInvalidPermissionCondition = errors.New("invalid permission condition")
+
+// maxConditionLength 条件表达式的最大长度
+const maxConditionLength = 1000
+
+// PermissionCondition 已解析的权限条件表达式，例如 doc.amount < 10000 and doc.org_id in user.org_ids
+type PermissionCondition struct {
+	source string
+	root   *conditionNode
+}
+
+// 条件表达式语法树节点类型
+const (
+	nodeAnd     = "and"
+	nodeOr      = "or"
+	nodeNot     = "not"
+	nodeCompare = "compare"
+	nodeIn      = "in"
+	nodeLiteral = "literal"
+	nodeList    = "list"
+	nodeDoc     = "doc"
+	nodeUser    = "user"
+)
+
+// conditionNode 条件表达式语法树节点
+type conditionNode struct {
+	kind     string
+	op       string // 比较运算符，in节点为"in"或"not in"
+	name     string // doc或user节点的属性名
+	value    interface{}
+	children []*conditionNode
+}
+
+// ParsePermissionCondition 解析权限条件表达式
+func ParsePermissionCondition(source string) (*PermissionCondition, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("%w: empty expression", ErrInvalidPermissionCondition)
+	}
+	if len(source) > maxConditionLength {
+		return nil, fmt.Errorf("%w: expression longer than %d characters", ErrInvalidPermissionCondition, maxConditionLength)
+	}
+
+	tokens, err := tokenizeCondition(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPermissionCondition, err)
+	}
+
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPermissionCondition, err)
+	}
+
+	return &PermissionCondition{source: source, root: root}, nil
+}
+
+// String 返回表达式原文
+func (c *PermissionCondition) String() string {
+	return c.source
+}
+
+// DocFields 返回表达式引用的文档字段
+func (c *PermissionCondition) DocFields() []string {
+	seen := make(map[string]bool)
+	var fields []string
+	c.root.walk(func(n *conditionNode) {
+		if n.kind == nodeDoc && !seen[n.name] {
+			seen[n.name] = true
+			fields = append(fields, n.name)
+		}
+	})
+	return fields
+}
+
+// Check 对表达式做类型检查，fieldTypes为文档字段类型，未列出的字段视为any
+func (c *PermissionCondition) Check(fieldTypes map[string]ConditionType) error {
+	t, err := c.root.check(fieldTypes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPermissionCondition, err)
+	}
+	if t != ConditionTypeBool && t != ConditionTypeAny {
+		return fmt.Errorf("%w: expression must be boolean, got %s", ErrInvalidPermissionCondition, t)
+	}
+	return nil
+}
+
+// Evaluate 根据文档属性和用户属性求值，缺失的属性视为null
+func (c *PermissionCondition) Evaluate(doc, user map[string]interface{}) (bool, error) {
+	value, err := c.root.eval(doc, user)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value)
+}
+
+// ConditionColumn 条件表达式中文档字段对应的SQL表达式，hint为字段参与比较时的期望类型
+type ConditionColumn func(field string, hint ConditionType) string
+
+// QuotedConditionColumn 将文档字段映射为同名列
+func QuotedConditionColumn(field string, hint ConditionType) string {
+	return `"` + field + `"`
+}
+
+// ToSQL 将表达式转换为SQL条件，用户属性作为参数绑定，参数编号从nextArg开始；
+// 无法转换时ok为false，调用方需要在内存中用Evaluate过滤
+func (c *PermissionCondition) ToSQL(user map[string]interface{}, column ConditionColumn, nextArg int) (clause string, args []interface{}, ok bool) {
+	b := &conditionSQLBuilder{user: user, column: column, nextArg: nextArg}
+	clause, ok = b.build(c.root)
+	if !ok {
+		return "", nil, false
+	}
+	return clause, b.args, true
+}
+
+// ================================================================
+// 词法分析
+// ================================================================
+
+const (
+	tokenIdent  = "ident"
+	tokenNumber = "number"
+	tokenString = "string"
+	tokenSymbol = "symbol"
+)
+
+type conditionToken struct {
+	kind string
+	text string
+	pos  int
+}
+
+// tokenizeCondition 将表达式切分为标识符、数字、字符串和运算符
+func tokenizeCondition(source string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || runes[i] == '.' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, conditionToken{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) && precedesOperand(tokens)):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, conditionToken{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case r == '\'' || r == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, conditionToken{kind: tokenString, text: sb.String(), pos: start})
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, conditionToken{kind: tokenSymbol, text: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '<', '>', '!', '(', ')', '[', ']', ',', '=':
+				text := string(r)
+				if text == "=" {
+					text = "=="
+				}
+				tokens = append(tokens, conditionToken{kind: tokenSymbol, text: text, pos: i})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// precedesOperand 负号前是运算符或表达式开头时，负号属于数字字面量
+func precedesOperand(tokens []conditionToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind == tokenSymbol && last.text != ")" && last.text != "]" ||
+		last.kind == tokenIdent && isConditionKeyword(last.text)
+}
+
+func isConditionKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", "in":
+		return true
+	}
+	return false
+}
+
+// ================================================================
+// 语法分析
+// ================================================================
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *conditionParser) peek() conditionToken {
+	if p.done() {
+		return conditionToken{pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+// accept 当前记号为指定符号或关键字时消费它
+func (p *conditionParser) accept(texts ...string) (string, bool) {
+	if p.done() {
+		return "", false
+	}
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenSymbol && tok.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if tok.text == text || tok.kind == tokenIdent && strings.EqualFold(tok.text, text) {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *conditionParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		if p.done() {
+			return fmt.Errorf("expected %q at end of expression", text)
+		}
+		return fmt.Errorf("expected %q at position %d", text, p.peek().pos)
+	}
+	return nil
+}
+
+func (p *conditionParser) parseOr() (*conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &conditionNode{kind: nodeOr, children: []*conditionNode{left, right}}
+	}
+}
+
+func (p *conditionParser) parseAnd() (*conditionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &conditionNode{kind: nodeAnd, children: []*conditionNode{left, right}}
+	}
+}
+
+func (p *conditionParser) parseNot() (*conditionNode, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionNode{kind: nodeNot, children: []*conditionNode{operand}}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *conditionParser) parseComparison() (*conditionNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := p.accept("==", "!=", "<=", ">=", "<", ">"); ok {
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionNode{kind: nodeCompare, op: op, children: []*conditionNode{left, right}}, nil
+	}
+
+	op := "in"
+	if _, ok := p.accept("not"); ok {
+		if err := p.expect("in"); err != nil {
+			return nil, err
+		}
+		op = "not in"
+	} else if _, ok := p.accept("in"); !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionNode{kind: nodeIn, op: op, children: []*conditionNode{left, right}}, nil
+}
+
+func (p *conditionParser) parseOperand() (*conditionNode, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	if _, ok := p.accept("("); ok {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	}
+
+	if _, ok := p.accept("["); ok {
+		list := &conditionNode{kind: nodeList}
+		if _, ok := p.accept("]"); ok {
+			return list, nil
+		}
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			if item.kind != nodeLiteral {
+				return nil, fmt.Errorf("list items must be literals")
+			}
+			list.children = append(list.children, item)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		return list, p.expect("]")
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return &conditionNode{kind: nodeLiteral, value: value}, nil
+	case tokenString:
+		return &conditionNode{kind: nodeLiteral, value: tok.text}, nil
+	case tokenIdent:
+		return parseConditionIdent(tok)
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+// parseConditionIdent 解析字面量关键字以及doc.<字段>、user.<属性>引用
+func parseConditionIdent(tok conditionToken) (*conditionNode, error) {
+	switch strings.ToLower(tok.text) {
+	case "true":
+		return &conditionNode{kind: nodeLiteral, value: true}, nil
+	case "false":
+		return &conditionNode{kind: nodeLiteral, value: false}, nil
+	case "null":
+		return &conditionNode{kind: nodeLiteral, value: nil}, nil
+	}
+
+	scope, name, found := strings.Cut(tok.text, ".")
+	if !found || name == "" || strings.Contains(name, ".") || !isConditionIdentifier(name) {
+		return nil, fmt.Errorf("unknown identifier %q at position %d, expected doc.<field> or user.<attribute>", tok.text, tok.pos)
+	}
+	switch scope {
+	case nodeDoc:
+		return &conditionNode{kind: nodeDoc, name: name}, nil
+	case nodeUser:
+		if _, ok := ConditionUserAttributes[name]; !ok {
+			return nil, fmt.Errorf("unknown user attribute %q at position %d", name, tok.pos)
+		}
+		return &conditionNode{kind: nodeUser, name: name}, nil
+	}
+	return nil, fmt.Errorf("unknown identifier %q at position %d, expected doc.<field> or user.<attribute>", tok.text, tok.pos)
+}
+
+// isConditionIdentifier 字段名只允许ASCII字母、数字和下划线，以便安全地映射为列名
+func isConditionIdentifier(name string) bool {
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// walk 先序遍历语法树
+func (n *conditionNode) walk(fn func(*conditionNode)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+// ================================================================
+// 类型检查
+// ================================================================
+
+func (n *conditionNode) check(fieldTypes map[string]ConditionType) (ConditionType, error) {
+	switch n.kind {
+	case nodeLiteral:
+		return literalType(n.value), nil
+	case nodeDoc:
+		if t, ok := fieldTypes[n.name]; ok {
+			return t, nil
+		}
+		return ConditionTypeAny, nil
+	case nodeUser:
+		return ConditionUserAttributes[n.name], nil
+	case nodeList:
+		elem := ConditionTypeNull
+		for _, item := range n.children {
+			t := literalType(item.value)
+			if t == ConditionTypeNull {
+				continue
+			}
+			if elem != ConditionTypeNull && elem != t {
+				return "", fmt.Errorf("list mixes %s and %s items", elem, t)
+			}
+			elem = t
+		}
+		if elem == ConditionTypeNull {
+			elem = ConditionTypeAny
+		}
+		return listOf(elem), nil
+	case nodeAnd, nodeOr, nodeNot:
+		for _, child := range n.children {
+			t, err := child.check(fieldTypes)
+			if err != nil {
+				return "", err
+			}
+			if t != ConditionTypeBool && t != ConditionTypeAny {
+				return "", fmt.Errorf("operand of %s must be boolean, got %s", n.kind, t)
+			}
+		}
+		return ConditionTypeBool, nil
+	case nodeCompare:
+		left, right, err := n.checkOperands(fieldTypes)
+		if err != nil {
+			return "", err
+		}
+		if left.IsList() || right.IsList() {
+			return "", fmt.Errorf("cannot compare lists with %s", n.op)
+		}
+		if !left.compatible(right) {
+			return "", fmt.Errorf("cannot compare %s with %s", left, right)
+		}
+		if n.op != "==" && n.op != "!=" {
+			if left == ConditionTypeNull || right == ConditionTypeNull {
+				return "", fmt.Errorf("null can only be compared with == or !=")
+			}
+			if left == ConditionTypeBool || right == ConditionTypeBool {
+				return "", fmt.Errorf("booleans can only be compared with == or !=")
+			}
+		}
+		return ConditionTypeBool, nil
+	case nodeIn:
+		left, right, err := n.checkOperands(fieldTypes)
+		if err != nil {
+			return "", err
+		}
+		if right != ConditionTypeAny && !right.IsList() {
+			return "", fmt.Errorf("right side of %s must be a list, got %s", n.op, right)
+		}
+		if left.IsList() {
+			return "", fmt.Errorf("left side of %s must be a single value", n.op)
+		}
+		if right.IsList() && !left.compatible(right.Elem()) {
+			return "", fmt.Errorf("cannot look up %s in %s", left, right)
+		}
+		return ConditionTypeBool, nil
+	}
+	return "", fmt.Errorf("unknown expression")
+}
+
+func (n *conditionNode) checkOperands(fieldTypes map[string]ConditionType) (ConditionType, ConditionType, error) {
+	left, err := n.children[0].check(fieldTypes)
+	if err != nil {
+		return "", "", err
+	}
+	right, err := n.children[1].check(fieldTypes)
+	if err != nil {
+		return "", "", err
+	}
+	return left, right, nil
+}
+
+func literalType(value interface{}) ConditionType {
+	switch value.(type) {
+	case bool:
+		return ConditionTypeBool
+	case float64:
+		return ConditionTypeNumber
+	case string:
+		return ConditionTypeString
+	}
+	return ConditionTypeNull
+}
+
+// ================================================================
+// 求值
+// ================================================================
+
+func (n *conditionNode) eval(doc, user map[string]interface{}) (interface{}, error) {
+	switch n.kind {
+	case nodeLiteral:
+		return n.value, nil
+	case nodeDoc:
+		return normalizeConditionValue(doc[n.name]), nil
+	case nodeUser:
+		return normalizeConditionValue(user[n.name]), nil
+	case nodeList:
+		items := make([]interface{}, len(n.children))
+		for i, item := range n.children {
+			items[i] = item.value
+		}
+		return items, nil
+	case nodeNot:
+		value, err := n.children[0].eval(doc, user)
+		if err != nil {
+			return nil, err
+		}
+		b, err := truthy(value)
+		return !b, err
+	case nodeAnd, nodeOr:
+		// 短路求值
+		for _, child := range n.children {
+			value, err := child.eval(doc, user)
+			if err != nil {
+				return nil, err
+			}
+			b, err := truthy(value)
+			if err != nil {
+				return nil, err
+			}
+			if b == (n.kind == nodeOr) {
+				return b, nil
+			}
+		}
+		return n.kind == nodeAnd, nil
+	case nodeCompare:
+		left, err := n.children[0].eval(doc, user)
+		if err != nil {
+			return nil, err
+		}
+		right, err := n.children[1].eval(doc, user)
+		if err != nil {
+			return nil, err
+		}
+		return compareConditionValues(n.op, left, right)
+	case nodeIn:
+		left, err := n.children[0].eval(doc, user)
+		if err != nil {
+			return nil, err
+		}
+		right, err := n.children[1].eval(doc, user)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		if right != nil {
+			items, ok := right.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("right side of %s is not a list", n.op)
+			}
+			for _, item := range items {
+				if equalConditionValues(left, item) {
+					found = true
+					break
+				}
+			}
+		}
+		return found == (n.op == "in"), nil
+	}
+	return nil, fmt.Errorf("unknown expression")
+}
+
+// normalizeConditionValue 将文档和用户属性统一为float64、string、bool、nil或[]interface{}
+func normalizeConditionValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case *int64:
+		if v == nil {
+			return nil
+		}
+		return float64(*v)
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []int64:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = float64(item)
+		}
+		return items
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = normalizeConditionValue(item)
+		}
+		return items
+	}
+	return value
+}
+
+// truthy 逻辑运算的操作数必须是布尔值，null视为false
+func truthy(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("expected boolean, got %T", value)
+}
+
+func equalConditionValues(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	switch l := left.(type) {
+	case float64, string, bool:
+		return l == right
+	}
+	return false
+}
+
+// compareConditionValues 比较两个值，null与非null值的大小比较结果为false
+func compareConditionValues(op string, left, right interface{}) (bool, error) {
+	switch op {
+	case "==":
+		return equalConditionValues(left, right), nil
+	case "!=":
+		return !equalConditionValues(left, right), nil
+	}
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number with %T", right)
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %T", right)
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false, fmt.Errorf("cannot order %T values", left)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %s", op)
+}
+
+// ================================================================
+// SQL转换
+// ================================================================
+
+type conditionSQLBuilder struct {
+	user    map[string]interface{}
+	column  ConditionColumn
+	nextArg int
+	args    []interface{}
+}
+
+// bind 将值作为参数绑定并返回占位符
+func (b *conditionSQLBuilder) bind(value interface{}) string {
+	b.args = append(b.args, value)
+	placeholder := fmt.Sprintf("$%d", b.nextArg)
+	b.nextArg++
+	return placeholder
+}
+
+// build 转换节点，不引用文档字段的子表达式直接求值为常量
+func (b *conditionSQLBuilder) build(n *conditionNode) (string, bool) {
+	if !n.referencesDoc() {
+		value, err := n.eval(nil, b.user)
+		if err != nil {
+			return "", false
+		}
+		result, err := truthy(value)
+		if err != nil {
+			return "", false
+		}
+		return strings.ToUpper(strconv.FormatBool(result)), true
+	}
+
+	switch n.kind {
+	case nodeDoc:
+		// 单独出现的文档字段作为布尔值使用
+		return "COALESCE(" + b.column(n.name, ConditionTypeBool) + ", FALSE)", true
+	case nodeNot:
+		inner, ok := b.build(n.children[0])
+		return "NOT (" + inner + ")", ok
+	case nodeAnd, nodeOr:
+		left, ok := b.build(n.children[0])
+		if !ok {
+			return "", false
+		}
+		right, ok := b.build(n.children[1])
+		if !ok {
+			return "", false
+		}
+		return "(" + left + " " + strings.ToUpper(n.kind) + " " + right + ")", true
+	case nodeCompare:
+		return b.buildCompare(n)
+	case nodeIn:
+		return b.buildIn(n)
+	}
+	return "", false
+}
+
+// operand 转换比较运算的操作数，只支持文档字段和常量
+func (b *conditionSQLBuilder) operand(n *conditionNode, hint ConditionType) (string, interface{}, bool) {
+	if n.kind == nodeDoc {
+		return b.column(n.name, hint), nil, true
+	}
+	if n.referencesDoc() {
+		return "", nil, false
+	}
+	value, err := n.eval(nil, b.user)
+	if err != nil {
+		return "", nil, false
+	}
+	if value == nil {
+		return "NULL", nil, true
+	}
+	switch value.(type) {
+	case float64, string, bool:
+		return b.bind(value), value, true
+	}
+	return "", nil, false
+}
+
+func (b *conditionSQLBuilder) buildCompare(n *conditionNode) (string, bool) {
+	hint := b.hint(n.children[0], n.children[1])
+	left, _, ok := b.operand(n.children[0], hint)
+	if !ok {
+		return "", false
+	}
+	right, _, ok := b.operand(n.children[1], hint)
+	if !ok {
+		return "", false
+	}
+
+	// ==和!=按null安全语义比较，与Evaluate一致；大小比较遇到null时为false
+	switch n.op {
+	case "==":
+		return left + " IS NOT DISTINCT FROM " + right, true
+	case "!=":
+		return left + " IS DISTINCT FROM " + right, true
+	}
+	return "COALESCE(" + left + " " + n.op + " " + right + ", FALSE)", true
+}
+
+func (b *conditionSQLBuilder) buildIn(n *conditionNode) (string, bool) {
+	if n.children[1].referencesDoc() {
+		return "", false
+	}
+	value, err := n.children[1].eval(nil, b.user)
+	if err != nil {
+		return "", false
+	}
+	var items []interface{}
+	if value != nil {
+		list, ok := value.([]interface{})
+		if !ok || !scalarItems(list) {
+			return "", false
+		}
+		items = list
+	}
+
+	if len(items) == 0 {
+		return strings.ToUpper(strconv.FormatBool(n.op == "not in")), true
+	}
+
+	left, _, ok := b.operand(n.children[0], literalType(items[0]))
+	if !ok {
+		return "", false
+	}
+	placeholders := make([]string, len(items))
+	for i, item := range items {
+		placeholders[i] = b.bind(item)
+	}
+	clause := "COALESCE(" + left + " IN (" + strings.Join(placeholders, ", ") + "), FALSE)"
+	if n.op == "not in" {
+		clause = "NOT " + clause
+	}
+	return clause, true
+}
+
+// hint 比较中文档字段的期望类型取自另一侧的常量或用户属性
+func (b *conditionSQLBuilder) hint(left, right *conditionNode) ConditionType {
+	for _, n := range []*conditionNode{left, right} {
+		switch n.kind {
+		case nodeLiteral:
+			if t := literalType(n.value); t != ConditionTypeNull {
+				return t
+			}
+		case nodeUser:
+			return ConditionUserAttributes[n.name]
+		}
+	}
+	return ConditionTypeAny
+}
+
+func scalarItems(items []interface{}) bool {
+	for _, item := range items {
+		switch item.(type) {
+		case float64, string, bool:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// referencesDoc 子表达式是否引用文档字段
+func (n *conditionNode) referencesDoc() bool {
+	found := false
+	n.walk(func(child *conditionNode) {
+		if child.kind == nodeDoc {
+			found = true
+		}
+	})
+	return found
+}
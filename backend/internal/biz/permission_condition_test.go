@@ -0,0 +1,203 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionCondition(t *testing.T) {
+	fieldTypes := map[string]biz.ConditionType{
+		"amount": biz.ConditionFieldType("Currency"),
+		"org_id": biz.ConditionFieldType("Int"),
+		"status": biz.ConditionFieldType("Select"),
+	}
+
+	for _, source := range []string{
+		"",
+		"doc.amount <",
+		"doc.amount < 10)",
+		"amount < 10",
+		"doc.amount < 10000 and",
+		"doc.status == 'Draft",
+		"user.password == 'x'",
+		"doc.amount < 10 or or doc.amount > 5",
+	} {
+		_, err := biz.ParsePermissionCondition(source)
+		assert.ErrorIs(t, err, biz.ErrInvalidPermissionCondition, source)
+	}
+
+	// 类型检查依据字段类型和用户属性类型
+	for _, source := range []string{
+		"doc.amount < 'x'",
+		"doc.status > 3",
+		"doc.org_id in user.roles",
+		"doc.amount and doc.status == 'Draft'",
+	} {
+		condition, err := biz.ParsePermissionCondition(source)
+		assert.NoError(t, err, source)
+		assert.ErrorIs(t, condition.Check(fieldTypes), biz.ErrInvalidPermissionCondition, source)
+	}
+
+	condition, err := biz.ParsePermissionCondition("doc.amount < 10000 and (doc.org_id in user.org_ids or doc.status == 'Draft')")
+	assert.NoError(t, err)
+	assert.NoError(t, condition.Check(fieldTypes))
+	assert.Equal(t, []string{"amount", "org_id", "status"}, condition.DocFields())
+
+	user := map[string]interface{}{"id": int64(7), "username": "alice", "roles": []string{"SALES_USER"}, "org_ids": []int64{3, 4}, "org_id": int64(3)}
+	cases := []struct {
+		doc  map[string]interface{}
+		want bool
+	}{
+		{map[string]interface{}{"amount": 500, "org_id": int64(4), "status": "Submitted"}, true},
+		{map[string]interface{}{"amount": 500.5, "org_id": 9, "status": "Draft"}, true},
+		{map[string]interface{}{"amount": 20000, "org_id": 3, "status": "Draft"}, false},
+		{map[string]interface{}{"amount": 500, "org_id": 9, "status": "Submitted"}, false},
+		{map[string]interface{}{"org_id": 3}, false},
+	}
+	for _, c := range cases {
+		matched, err := condition.Evaluate(c.doc, user)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, matched, c.doc)
+	}
+
+	clause, args, ok := condition.ToSQL(user, biz.QuotedConditionColumn, 3)
+	assert.True(t, ok)
+	assert.Equal(t, `(COALESCE("amount" < $3, FALSE) AND (COALESCE("org_id" IN ($4, $5), FALSE) OR "status" IS NOT DISTINCT FROM $6))`, clause)
+	assert.Equal(t, []interface{}{float64(10000), float64(3), float64(4), "Draft"}, args)
+
+	// 仅引用用户属性的子表达式直接求值
+	condition, err = biz.ParsePermissionCondition("'ADMIN' in user.roles or doc.org_id == user.org_id")
+	assert.NoError(t, err)
+	clause, args, ok = condition.ToSQL(user, biz.QuotedConditionColumn, 1)
+	assert.True(t, ok)
+	assert.Equal(t, `(FALSE OR "org_id" IS NOT DISTINCT FROM $1)`, clause)
+	assert.Equal(t, []interface{}{float64(3)}, args)
+
+	// 文档字段之间的集合运算无法转换为SQL
+	condition, err = biz.ParsePermissionCondition("user.org_id in doc.org_ids")
+	assert.NoError(t, err)
+	_, _, ok = condition.ToSQL(user, biz.QuotedConditionColumn, 1)
+	assert.False(t, ok)
+}
+
+// stubConditionPermissionRepo 仅实现条件权限所需的方法
+type stubConditionPermissionRepo struct {
+	biz.PermissionRepo
+	rules []*biz.PermissionRule
+}
+
+func (r *stubConditionPermissionRepo) GetConditionFieldTypes(ctx context.Context, docType string) (map[string]biz.ConditionType, error) {
+	return map[string]biz.ConditionType{"amount": biz.ConditionTypeNumber}, nil
+}
+
+func (r *stubConditionPermissionRepo) ListUserPermissionRules(ctx context.Context, userID int64, docType string, permissionLevel int) ([]*biz.PermissionRule, error) {
+	return r.rules, nil
+}
+
+func (r *stubConditionPermissionRepo) GetUserConditionAttributes(ctx context.Context, userID int64) (map[string]interface{}, error) {
+	return map[string]interface{}{"id": userID, "org_ids": []int64{3}}, nil
+}
+
+func (r *stubConditionPermissionRepo) CreatePermissionRule(ctx context.Context, rule *biz.PermissionRule) (*biz.PermissionRule, error) {
+	return rule, nil
+}
+
+func TestPermissionUsecase_ConditionalRules(t *testing.T) {
+	repo := &stubConditionPermissionRepo{}
+	uc := biz.NewPermissionUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 保存时按字段类型检查
+	_, err := uc.CreatePermissionRule(ctx, &biz.PermissionRule{RoleID: 1, DocType: "Order", CanRead: true, Condition: "doc.amount < 'x'"})
+	assert.ErrorIs(t, err, biz.ErrInvalidPermissionCondition)
+	rule, err := uc.CreatePermissionRule(ctx, &biz.PermissionRule{RoleID: 1, DocType: "Order", CanRead: true, Condition: "  doc.amount < 10000 "})
+	assert.NoError(t, err)
+	assert.Equal(t, "doc.amount < 10000", rule.Condition)
+
+	repo.rules = []*biz.PermissionRule{
+		{ID: 1, CanRead: true, Condition: "doc.amount < 10000"},
+		{ID: 2, CanRead: true, Condition: "doc.org_id in user.org_ids"},
+		{ID: 3, CanWrite: true},
+	}
+	scope, err := uc.GetPermissionScope(ctx, 7, "Order", "read", 0)
+	assert.NoError(t, err)
+	assert.False(t, scope.Unrestricted)
+	assert.Len(t, scope.Conditions, 2)
+	assert.True(t, scope.Match(map[string]interface{}{"amount": 20000, "org_id": 3}))
+	assert.False(t, scope.Match(map[string]interface{}{"amount": 20000, "org_id": 4}))
+
+	clause, args, ok := scope.SQL(biz.QuotedConditionColumn, 1)
+	assert.True(t, ok)
+	assert.Equal(t, `(COALESCE("amount" < $1, FALSE)) OR (COALESCE("org_id" IN ($2), FALSE))`, clause)
+	assert.Len(t, args, 2)
+
+	// 无条件规则授予全部文档
+	scope, err = uc.GetPermissionScope(ctx, 7, "Order", "write", 0)
+	assert.NoError(t, err)
+	assert.True(t, scope.Unrestricted)
+	clause, _, _ = scope.SQL(biz.QuotedConditionColumn, 1)
+	assert.Equal(t, "TRUE", clause)
+
+	scope, err = uc.GetPermissionScope(ctx, 7, "Order", "delete", 0)
+	assert.NoError(t, err)
+	assert.True(t, scope.Denied())
+	clause, _, _ = scope.SQL(biz.QuotedConditionColumn, 1)
+	assert.Equal(t, "FALSE", clause)
+
+	_, err = uc.GetPermissionScope(ctx, 7, "Order", "approve", 0)
+	assert.ErrorIs(t, err, biz.ErrUnsupportedPermissionAction)
+
+	resp, err := uc.CheckDocumentPermission(ctx, &biz.PermissionCheckRequest{
+		UserID: 7, DocType: "Order", Permission: "read", Document: map[string]interface{}{"amount": 500},
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.HasPermission)
+	resp, err = uc.CheckDocumentPermission(ctx, &biz.PermissionCheckRequest{
+		UserID: 7, DocType: "Order", Permission: "read", Document: map[string]interface{}{"amount": 50000},
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.HasPermission)
+}
+
+func TestPermissionUsecase_OnlyIfCreatorRules(t *testing.T) {
+	repo := &stubConditionPermissionRepo{rules: []*biz.PermissionRule{
+		{ID: 1, CanRead: true, OnlyIfCreator: true},
+		{ID: 2, CanWrite: true, OnlyIfCreator: true, Condition: "doc.amount < 10000"},
+	}}
+	uc := biz.NewPermissionUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 没有条件的仅创建者规则不授予全部文档，其他用户创建的文档被排除
+	scope, err := uc.GetPermissionScope(ctx, 7, "Order", "read", 0)
+	assert.NoError(t, err)
+	assert.False(t, scope.Unrestricted)
+	assert.True(t, scope.Match(map[string]interface{}{"created_by": int64(7)}))
+	assert.False(t, scope.Match(map[string]interface{}{"created_by": int64(8)}))
+	clause, args, ok := scope.SQL(biz.QuotedConditionColumn, 1)
+	assert.True(t, ok)
+	assert.Equal(t, `("created_by" IS NOT DISTINCT FROM $1)`, clause)
+	assert.Len(t, args, 1)
+
+	// 条件与创建者限制同时成立
+	scope, err = uc.GetPermissionScope(ctx, 7, "Order", "write", 0)
+	assert.NoError(t, err)
+	assert.True(t, scope.Match(map[string]interface{}{"amount": 500, "created_by": int64(7)}))
+	assert.False(t, scope.Match(map[string]interface{}{"amount": 500, "created_by": int64(8)}))
+	assert.False(t, scope.Match(map[string]interface{}{"amount": 50000, "created_by": int64(7)}))
+
+	resp, err := uc.CheckDocumentPermission(ctx, &biz.PermissionCheckRequest{
+		UserID: 7, DocType: "Order", Permission: "read", Document: map[string]interface{}{"created_by": int64(8)},
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.HasPermission)
+	resp, err = uc.CheckDocumentPermission(ctx, &biz.PermissionCheckRequest{
+		UserID: 7, DocType: "Order", Permission: "read", Document: map[string]interface{}{"created_by": int64(7)},
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.HasPermission)
+}
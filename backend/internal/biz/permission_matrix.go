@@ -0,0 +1,424 @@
+package biz
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 权限矩阵CSV的固定列，操作列按PermissionRuleActions顺序位于permission_level与only_if_creator之间，条件表达式为最后一列
+const (
+	matrixColumnRole          = "role_code"
+	matrixColumnDocType       = "doc_type"
+	matrixColumnLevel         = "permission_level"
+	matrixColumnOnlyIfCreator = "only_if_creator"
+	matrixColumnCondition     = "condition"
+)
+
+// RuleChangeDelete 导入权限矩阵时移除的规则
+const RuleChangeDelete = "delete"
+
+// PermissionMatrixRow 权限矩阵中的一行：角色在文档类型某一权限级别上的操作集
+type PermissionMatrixRow struct {
+	RoleCode        string   `json:"role_code"`
+	DocType         string   `json:"doc_type"`
+	PermissionLevel int      `json:"permission_level"`
+	Actions         []string `json:"actions"`
+	OnlyIfCreator   bool     `json:"only_if_creator"`
+	Condition       string   `json:"condition,omitempty"` // 条件表达式，为空表示无条件
+}
+
+// key 返回行在矩阵中的唯一标识
+func (r *PermissionMatrixRow) key() string {
+	return fmt.Sprintf("%s/%s/%d", r.RoleCode, r.DocType, r.PermissionLevel)
+}
+
+// toRule 将矩阵行转换为指定角色的权限规则
+func (r *PermissionMatrixRow) toRule(roleID int64) (*PermissionRule, error) {
+	rule := &PermissionRule{
+		RoleID:          roleID,
+		DocType:         r.DocType,
+		PermissionLevel: r.PermissionLevel,
+		OnlyIfCreator:   r.OnlyIfCreator,
+		Condition:       strings.TrimSpace(r.Condition),
+	}
+	for _, action := range r.Actions {
+		flag := rule.actionFlag(action)
+		if flag == nil {
+			return nil, fmt.Errorf("invalid action: %s", action)
+		}
+		*flag = true
+	}
+	return rule, nil
+}
+
+// PermissionMatrixRowError 导入数据中某一行的错误，Row从1开始且不含CSV表头
+type PermissionMatrixRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// PermissionMatrixDiff 权限矩阵导入预览结果
+type PermissionMatrixDiff struct {
+	DocTypes []string                    `json:"doc_types"` // 导入覆盖的文档类型，其上未出现在导入数据中的规则将被移除
+	Changes  []*PermissionRuleChange     `json:"changes"`   // 新增、变更和移除的规则，不含未变化的规则
+	Summary  map[string]int              `json:"summary"`
+	Errors   []*PermissionMatrixRowError `json:"errors,omitempty"`
+	Applied  bool                        `json:"applied"`
+}
+
+// 错误定义
+var (
+	ErrPermissionMatrixEmpty   = errors.New("permission matrix is empty")
+	ErrPermissionMatrixInvalid = errors.New("permission matrix contains invalid rows")
+)
+
+// EncodePermissionMatrixCSV 将权限矩阵写为CSV，操作列以1/0表示
+func EncodePermissionMatrixCSV(w io.Writer, rows []*PermissionMatrixRow) error {
+	writer := csv.NewWriter(w)
+
+	header := append([]string{matrixColumnRole, matrixColumnDocType, matrixColumnLevel}, PermissionRuleActions...)
+	header = append(header, matrixColumnOnlyIfCreator, matrixColumnCondition)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		granted := make(map[string]bool, len(row.Actions))
+		for _, action := range row.Actions {
+			granted[action] = true
+		}
+
+		record := []string{row.RoleCode, row.DocType, strconv.Itoa(row.PermissionLevel)}
+		for _, action := range PermissionRuleActions {
+			record = append(record, matrixFlag(granted[action]))
+		}
+		record = append(record, matrixFlag(row.OnlyIfCreator), row.Condition)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// DecodePermissionMatrixCSV 解析CSV格式的权限矩阵，按表头识别列，列顺序不限
+// 操作列接受1/0、true/false、yes/no、y/n、x或空白
+func DecodePermissionMatrixCSV(r io.Reader) ([]*PermissionMatrixRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrPermissionMatrixEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name != matrixColumnRole && name != matrixColumnDocType && name != matrixColumnLevel &&
+			name != matrixColumnOnlyIfCreator && name != matrixColumnCondition && !isMatrixAction(name) {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{matrixColumnRole, matrixColumnDocType, matrixColumnLevel} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column: %s", required)
+		}
+	}
+
+	var rows []*PermissionMatrixRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := &PermissionMatrixRow{
+			RoleCode: strings.TrimSpace(record[columns[matrixColumnRole]]),
+			DocType:  strings.TrimSpace(record[columns[matrixColumnDocType]]),
+		}
+		row.PermissionLevel, err = strconv.Atoi(strings.TrimSpace(record[columns[matrixColumnLevel]]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid permission level: %s", line, record[columns[matrixColumnLevel]])
+		}
+
+		for _, action := range PermissionRuleActions {
+			index, ok := columns[action]
+			if !ok {
+				continue
+			}
+			granted, err := parseMatrixFlag(record[index])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: column %s: %w", line, action, err)
+			}
+			if granted {
+				row.Actions = append(row.Actions, action)
+			}
+		}
+
+		if index, ok := columns[matrixColumnOnlyIfCreator]; ok {
+			if row.OnlyIfCreator, err = parseMatrixFlag(record[index]); err != nil {
+				return nil, fmt.Errorf("line %d: column %s: %w", line, matrixColumnOnlyIfCreator, err)
+			}
+		}
+		if index, ok := columns[matrixColumnCondition]; ok {
+			row.Condition = strings.TrimSpace(record[index])
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrPermissionMatrixEmpty
+	}
+	return rows, nil
+}
+
+// isMatrixAction 判断列名是否为权限操作
+func isMatrixAction(name string) bool {
+	for _, action := range PermissionRuleActions {
+		if action == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matrixFlag 将权限标志转换为CSV单元格内容
+func matrixFlag(granted bool) string {
+	if granted {
+		return "1"
+	}
+	return "0"
+}
+
+// parseMatrixFlag 解析CSV单元格中的权限标志
+func parseMatrixFlag(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "0", "false", "no", "n":
+		return false, nil
+	case "1", "true", "yes", "y", "x":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid flag: %s", value)
+}
+
+// PermissionMatrixUsecase 权限矩阵导入导出用例
+type PermissionMatrixUsecase struct {
+	permRepo PermissionRepo
+	log      *log.Helper
+}
+
+// NewPermissionMatrixUsecase 创建权限矩阵用例
+func NewPermissionMatrixUsecase(permRepo PermissionRepo, logger log.Logger) *PermissionMatrixUsecase {
+	return &PermissionMatrixUsecase{
+		permRepo: permRepo,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// Export 导出全部权限规则构成的角色×文档类型×级别×操作矩阵
+func (uc *PermissionMatrixUsecase) Export(ctx context.Context) ([]*PermissionMatrixRow, error) {
+	roleCodes, err := uc.permRepo.ListRoleCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := uc.permRepo.ListPermissionRules(ctx, 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*PermissionMatrixRow, 0, len(rules))
+	for _, rule := range rules {
+		rows = append(rows, &PermissionMatrixRow{
+			RoleCode:        roleCodes[rule.RoleID],
+			DocType:         rule.DocType,
+			PermissionLevel: rule.PermissionLevel,
+			Actions:         rule.Actions(),
+			OnlyIfCreator:   rule.OnlyIfCreator,
+			Condition:       rule.Condition,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].RoleCode != rows[j].RoleCode {
+			return rows[i].RoleCode < rows[j].RoleCode
+		}
+		if rows[i].DocType != rows[j].DocType {
+			return rows[i].DocType < rows[j].DocType
+		}
+		return rows[i].PermissionLevel < rows[j].PermissionLevel
+	})
+	return rows, nil
+}
+
+// Import 导入权限矩阵，导入数据覆盖其中出现的文档类型：新增或更新导入的规则，并移除这些文档类型上未出现的规则
+// 任一行校验失败时返回带错误明细的差异和ErrPermissionMatrixInvalid；DryRun时只返回差异
+func (uc *PermissionMatrixUsecase) Import(ctx context.Context, rows []*PermissionMatrixRow, dryRun bool) (*PermissionMatrixDiff, error) {
+	if len(rows) == 0 {
+		return nil, ErrPermissionMatrixEmpty
+	}
+
+	roleCodes, err := uc.permRepo.ListRoleCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make(map[string]int64, len(roleCodes))
+	for id, code := range roleCodes {
+		roleIDs[code] = id
+	}
+
+	docTypes, err := uc.permRepo.ListDocTypes(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	knownDocTypes := make(map[string]bool, len(docTypes))
+	for _, docType := range docTypes {
+		knownDocTypes[docType.Name] = true
+	}
+
+	diff := &PermissionMatrixDiff{
+		Summary: map[string]int{RuleChangeCreate: 0, RuleChangeUpdate: 0, RuleChangeDelete: 0, RuleChangeUnchanged: 0},
+	}
+
+	// 校验并转换导入行
+	imported := make(map[string]*PermissionRule, len(rows))
+	covered := make(map[string]bool)
+	for i, row := range rows {
+		rule, err := uc.importRule(row, roleIDs, knownDocTypes, imported)
+		if err == nil {
+			// 条件表达式按文档字段类型检查，表达式无效计为行错误，读取字段失败则中止导入
+			if err = validateRuleCondition(ctx, uc.permRepo, rule); err != nil && !errors.Is(err, ErrInvalidPermissionCondition) {
+				return nil, err
+			}
+		}
+		if err != nil {
+			diff.Errors = append(diff.Errors, &PermissionMatrixRowError{Row: i + 1, Message: err.Error()})
+			continue
+		}
+		imported[row.key()] = rule
+		if !covered[row.DocType] {
+			covered[row.DocType] = true
+			diff.DocTypes = append(diff.DocTypes, row.DocType)
+		}
+	}
+	if len(diff.Errors) > 0 {
+		return diff, ErrPermissionMatrixInvalid
+	}
+	sort.Strings(diff.DocTypes)
+
+	// 与现有规则比较
+	var upserts, removals []*PermissionRule
+	for _, docType := range diff.DocTypes {
+		existing, err := uc.permRepo.ListPermissionRules(ctx, 0, docType)
+		if err != nil {
+			return nil, err
+		}
+
+		current := make(map[string]*PermissionRule, len(existing))
+		for _, rule := range existing {
+			key := (&PermissionMatrixRow{RoleCode: roleCodes[rule.RoleID], DocType: rule.DocType, PermissionLevel: rule.PermissionLevel}).key()
+			current[key] = rule
+			if _, ok := imported[key]; !ok {
+				uc.addChange(diff, &PermissionRuleChange{Change: RuleChangeDelete, Before: rule.Actions(), Removed: rule.Actions(), ConditionBefore: rule.Condition, Rule: rule}, roleCodes)
+				removals = append(removals, rule)
+			}
+		}
+
+		for _, row := range rows {
+			if row.DocType != docType {
+				continue
+			}
+			rule := imported[row.key()]
+			change := &PermissionRuleChange{After: rule.Actions(), ConditionAfter: rule.Condition, Rule: rule}
+			if old, ok := current[row.key()]; !ok {
+				change.Change = RuleChangeCreate
+				change.Added = change.After
+			} else {
+				change.Before = old.Actions()
+				change.ConditionBefore = old.Condition
+				change.Added, change.Removed = diffActions(change.Before, change.After)
+				if len(change.Added) == 0 && len(change.Removed) == 0 && old.OnlyIfCreator == rule.OnlyIfCreator &&
+					old.Condition == rule.Condition {
+					diff.Summary[RuleChangeUnchanged]++
+					continue
+				}
+				change.Change = RuleChangeUpdate
+			}
+			uc.addChange(diff, change, roleCodes)
+			upserts = append(upserts, rule)
+		}
+	}
+
+	if dryRun || len(diff.Changes) == 0 {
+		return diff, nil
+	}
+
+	now := time.Now()
+	for _, rule := range upserts {
+		rule.CreatedAt = now
+		rule.UpdatedAt = now
+	}
+	if err := uc.permRepo.ReplacePermissionRules(ctx, upserts, removals); err != nil {
+		return nil, err
+	}
+
+	diff.Applied = true
+	uc.log.Infof("Imported permission matrix for %d doc types: %d created, %d updated, %d removed",
+		len(diff.DocTypes), diff.Summary[RuleChangeCreate], diff.Summary[RuleChangeUpdate], diff.Summary[RuleChangeDelete])
+	return diff, nil
+}
+
+// importRule 校验导入行并转换为权限规则
+func (uc *PermissionMatrixUsecase) importRule(row *PermissionMatrixRow, roleIDs map[string]int64, docTypes map[string]bool, imported map[string]*PermissionRule) (*PermissionRule, error) {
+	roleID, ok := roleIDs[row.RoleCode]
+	if !ok {
+		return nil, fmt.Errorf("unknown role: %s", row.RoleCode)
+	}
+	if !docTypes[row.DocType] {
+		return nil, fmt.Errorf("unknown doc type: %s", row.DocType)
+	}
+	if _, ok := imported[row.key()]; ok {
+		return nil, fmt.Errorf("duplicate row for role %s, doc type %s, level %d", row.RoleCode, row.DocType, row.PermissionLevel)
+	}
+
+	rule, err := row.toRule(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// addChange 填充变更的规则标识并计入差异
+func (uc *PermissionMatrixUsecase) addChange(diff *PermissionMatrixDiff, change *PermissionRuleChange, roleCodes map[int64]string) {
+	change.RoleID = change.Rule.RoleID
+	change.RoleCode = roleCodes[change.Rule.RoleID]
+	change.DocType = change.Rule.DocType
+	change.PermissionLevel = change.Rule.PermissionLevel
+	diff.Summary[change.Change]++
+	diff.Changes = append(diff.Changes, change)
+}
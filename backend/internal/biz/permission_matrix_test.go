@@ -0,0 +1,156 @@
+package biz_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionMatrixCSV(t *testing.T) {
+	rows := []*biz.PermissionMatrixRow{
+		{RoleCode: "SALES_USER", DocType: "Order", PermissionLevel: 0, Actions: []string{"read", "create", "print", "set_user_permissions"}, OnlyIfCreator: true, Condition: "doc.amount < 10000"},
+		{RoleCode: "SALES_USER", DocType: "Order", PermissionLevel: 1, Actions: []string{"read"}},
+	}
+
+	var buf strings.Builder
+	assert.NoError(t, biz.EncodePermissionMatrixCSV(&buf, rows))
+
+	decoded, err := biz.DecodePermissionMatrixCSV(strings.NewReader(buf.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, rows[0], decoded[0])
+	assert.Equal(t, rows[1].Actions, decoded[1].Actions)
+
+	// 列顺序不限，操作列可省略，接受电子表格常用的标记
+	decoded, err = biz.DecodePermissionMatrixCSV(strings.NewReader("doc_type,Role_Code,permission_level,submit,read\nOrder,SALES_MANAGER,0,x,Y\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read", "submit"}, decoded[0].Actions)
+
+	_, err = biz.DecodePermissionMatrixCSV(strings.NewReader("role_code,doc_type,permission_level,approve\n"))
+	assert.Error(t, err)
+	_, err = biz.DecodePermissionMatrixCSV(strings.NewReader("role_code,doc_type,permission_level,read\nSALES_USER,Order,0,maybe\n"))
+	assert.Error(t, err)
+	_, err = biz.DecodePermissionMatrixCSV(strings.NewReader("role_code,doc_type,permission_level\n"))
+	assert.Equal(t, biz.ErrPermissionMatrixEmpty, err)
+}
+
+// stubMatrixPermissionRepo 仅实现权限矩阵导入所需的方法
+type stubMatrixPermissionRepo struct {
+	biz.PermissionRepo
+	rules    []*biz.PermissionRule
+	upserts  []*biz.PermissionRule
+	removals []*biz.PermissionRule
+}
+
+func (r *stubMatrixPermissionRepo) ListRoleCodes(ctx context.Context) (map[int64]string, error) {
+	return map[int64]string{1: "SALES_USER", 2: "SALES_MANAGER"}, nil
+}
+
+func (r *stubMatrixPermissionRepo) ListDocTypes(ctx context.Context, module string) ([]*biz.DocType, error) {
+	return []*biz.DocType{{Name: "Order"}, {Name: "Customer"}}, nil
+}
+
+func (r *stubMatrixPermissionRepo) ListPermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.PermissionRule, error) {
+	var rules []*biz.PermissionRule
+	for _, rule := range r.rules {
+		if docType == "" || rule.DocType == docType {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *stubMatrixPermissionRepo) GetConditionFieldTypes(ctx context.Context, docType string) (map[string]biz.ConditionType, error) {
+	return map[string]biz.ConditionType{"amount": biz.ConditionTypeNumber}, nil
+}
+
+func (r *stubMatrixPermissionRepo) ReplacePermissionRules(ctx context.Context, upserts, removals []*biz.PermissionRule) error {
+	r.upserts, r.removals = upserts, removals
+	return nil
+}
+
+func TestPermissionMatrixUsecase_Import(t *testing.T) {
+	repo := &stubMatrixPermissionRepo{rules: []*biz.PermissionRule{
+		{ID: 1, RoleID: 1, DocType: "Order", CanRead: true, CanCreate: true},
+		{ID: 2, RoleID: 2, DocType: "Order", CanRead: true, CanSubmit: true},
+		{ID: 3, RoleID: 1, DocType: "Order", PermissionLevel: 1, CanRead: true},
+		{ID: 4, RoleID: 1, DocType: "Customer", CanRead: true, Condition: "doc.amount < 100"},
+	}}
+	uc := biz.NewPermissionMatrixUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	exported, err := uc.Export(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, exported, 4)
+	assert.Equal(t, "SALES_MANAGER", exported[0].RoleCode)
+	assert.Equal(t, "doc.amount < 100", exported[1].Condition)
+
+	rows := []*biz.PermissionMatrixRow{
+		{RoleCode: "SALES_USER", DocType: "Order", Actions: []string{"read", "create"}},
+		{RoleCode: "SALES_MANAGER", DocType: "Order", Actions: []string{"read", "submit", "cancel"}},
+		{RoleCode: "SALES_MANAGER", DocType: "Order", PermissionLevel: 1, Actions: []string{"read", "write"}},
+	}
+
+	diff, err := uc.Import(ctx, rows, true)
+	assert.NoError(t, err)
+	assert.False(t, diff.Applied)
+	assert.Equal(t, []string{"Order"}, diff.DocTypes)
+	assert.Equal(t, map[string]int{
+		biz.RuleChangeCreate: 1, biz.RuleChangeUpdate: 1, biz.RuleChangeDelete: 1, biz.RuleChangeUnchanged: 1,
+	}, diff.Summary)
+	assert.Nil(t, repo.upserts)
+
+	diff, err = uc.Import(ctx, rows, false)
+	assert.NoError(t, err)
+	assert.True(t, diff.Applied)
+	assert.Len(t, repo.upserts, 2)
+	assert.Len(t, repo.removals, 1)
+	assert.Equal(t, int64(3), repo.removals[0].ID)
+
+	// 逐行校验，所有错误一并返回
+	diff, err = uc.Import(ctx, []*biz.PermissionMatrixRow{
+		{RoleCode: "UNKNOWN", DocType: "Order", Actions: []string{"read"}},
+		{RoleCode: "SALES_USER", DocType: "Invoice", Actions: []string{"read"}},
+		{RoleCode: "SALES_USER", DocType: "Order", PermissionLevel: 1, Actions: []string{"read", "submit"}},
+		{RoleCode: "SALES_USER", DocType: "Order", Actions: []string{"read"}},
+		{RoleCode: "SALES_USER", DocType: "Order", Actions: []string{"read"}},
+	}, false)
+	assert.Equal(t, biz.ErrPermissionMatrixInvalid, err)
+	assert.Len(t, diff.Errors, 4)
+	assert.Equal(t, 5, diff.Errors[3].Row)
+}
+
+func TestPermissionMatrixUsecase_ImportCondition(t *testing.T) {
+	repo := &stubMatrixPermissionRepo{rules: []*biz.PermissionRule{
+		{ID: 1, RoleID: 1, DocType: "Order", CanRead: true, CanCreate: true, Condition: "doc.amount < 100"},
+	}}
+	uc := biz.NewPermissionMatrixUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 仅条件变化的规则计为变更
+	diff, err := uc.Import(ctx, []*biz.PermissionMatrixRow{
+		{RoleCode: "SALES_USER", DocType: "Order", Actions: []string{"read", "create"}, Condition: " doc.amount < 500 "},
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, diff.Summary[biz.RuleChangeUpdate])
+	assert.Equal(t, "doc.amount < 100", diff.Changes[0].ConditionBefore)
+	assert.Equal(t, "doc.amount < 500", diff.Changes[0].ConditionAfter)
+	assert.Empty(t, diff.Changes[0].Added)
+	assert.Equal(t, "doc.amount < 500", repo.upserts[0].Condition)
+
+	// 条件表达式按文档字段类型校验
+	diff, err = uc.Import(ctx, []*biz.PermissionMatrixRow{
+		{RoleCode: "SALES_USER", DocType: "Order", Actions: []string{"read"}, Condition: "doc.amount <"},
+		{RoleCode: "SALES_USER", DocType: "Customer", Actions: []string{"read"}, Condition: "doc.amount = 'x'"},
+		{RoleCode: "SALES_MANAGER", DocType: "Order", Actions: []string{"read", "set_user_permissions"}},
+	}, true)
+	assert.Equal(t, biz.ErrPermissionMatrixInvalid, err)
+	if assert.Len(t, diff.Errors, 2) {
+		assert.Equal(t, 1, diff.Errors[0].Row)
+		assert.Equal(t, 2, diff.Errors[1].Row)
+	}
+}
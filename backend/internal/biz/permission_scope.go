@@ -0,0 +1,166 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedPermissionAction 不支持的权限操作
+var ErrUnsupportedPermissionAction = errors.New("unsupported permission action")
+
+// PermissionScope 用户对某文档类型某操作的数据范围，由生效权限规则的条件表达式按"或"组合
+type PermissionScope struct {
+	UserID       int64                  `json:"user_id"`
+	DocType      string                 `json:"doc_type"`
+	Action       string                 `json:"action"`
+	Unrestricted bool                   `json:"unrestricted"` // 存在无条件且不限创建者的规则，可访问全部文档
+	Conditions   []string               `json:"conditions,omitempty"`
+	User         map[string]interface{} `json:"-"`
+
+	conditions []*PermissionCondition
+}
+
+// Denied 没有任何规则授予该操作
+func (s *PermissionScope) Denied() bool {
+	return !s.Unrestricted && len(s.conditions) == 0
+}
+
+// SQL 将数据范围转换为SQL条件，参数编号从nextArg开始；
+// 任一条件无法转换时ok为false，调用方需在查询后用Match逐条过滤
+func (s *PermissionScope) SQL(column ConditionColumn, nextArg int) (clause string, args []interface{}, ok bool) {
+	if s.Unrestricted {
+		return "TRUE", nil, true
+	}
+	if len(s.conditions) == 0 {
+		return "FALSE", nil, true
+	}
+
+	clauses := make([]string, 0, len(s.conditions))
+	for _, condition := range s.conditions {
+		part, partArgs, ok := condition.ToSQL(s.User, column, nextArg+len(args))
+		if !ok {
+			return "", nil, false
+		}
+		clauses = append(clauses, "("+part+")")
+		args = append(args, partArgs...)
+	}
+	return strings.Join(clauses, " OR "), args, true
+}
+
+// Match 判断文档是否在数据范围内，条件求值出错视为不匹配
+func (s *PermissionScope) Match(doc map[string]interface{}) bool {
+	if s.Unrestricted {
+		return true
+	}
+	for _, condition := range s.conditions {
+		if matched, err := condition.Evaluate(doc, s.User); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRuleCondition 解析规则的条件表达式并按文档字段类型做类型检查
+func (uc *PermissionUsecase) checkRuleCondition(ctx context.Context, rule *PermissionRule) error {
+	return validateRuleCondition(ctx, uc.repo, rule)
+}
+
+// validateRuleCondition 解析规则的条件表达式并按文档字段类型做类型检查，表达式无效时返回ErrInvalidPermissionCondition
+func validateRuleCondition(ctx context.Context, repo PermissionRepo, rule *PermissionRule) error {
+	rule.Condition = strings.TrimSpace(rule.Condition)
+	if rule.Condition == "" {
+		return nil
+	}
+
+	condition, err := ParsePermissionCondition(rule.Condition)
+	if err != nil {
+		return err
+	}
+
+	fieldTypes, err := repo.GetConditionFieldTypes(ctx, rule.DocType)
+	if err != nil {
+		return err
+	}
+	return condition.Check(fieldTypes)
+}
+
+// creatorCondition 仅创建者可访问的规则附加的条件
+const creatorCondition = "doc.created_by == user.id"
+
+// scopeCondition 规则在数据范围中的条件表达式：仅创建者可访问的规则附加创建者条件，为空表示不限制
+func scopeCondition(rule *PermissionRule) string {
+	if !rule.OnlyIfCreator {
+		return rule.Condition
+	}
+	if rule.Condition == "" {
+		return creatorCondition
+	}
+	return "(" + rule.Condition + ") and " + creatorCondition
+}
+
+// GetPermissionScope 获取用户对文档类型在指定权限级别上某操作的数据范围，用于列表查询
+func (uc *PermissionUsecase) GetPermissionScope(ctx context.Context, userID int64, docType, action string, permissionLevel int) (*PermissionScope, error) {
+	rules, user, err := uc.loadConditionalRules(ctx, userID, docType, action, permissionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &PermissionScope{UserID: userID, DocType: docType, Action: action, User: user}
+	for _, rule := range rules {
+		source := scopeCondition(rule)
+		if source == "" {
+			scope.Unrestricted = true
+			scope.Conditions, scope.conditions = nil, nil
+			return scope, nil
+		}
+		condition, err := ParsePermissionCondition(source)
+		if err != nil {
+			// 保存时已校验，解析失败说明数据被直接修改，跳过该规则
+			uc.logger.Warnf("Skip permission rule %d with invalid condition: %v", rule.ID, err)
+			continue
+		}
+		scope.Conditions = append(scope.Conditions, condition.String())
+		scope.conditions = append(scope.conditions, condition)
+	}
+	return scope, nil
+}
+
+// checkDocumentConditions 无条件规则或条件对文档成立的规则授予权限，仅创建者可访问的规则只授予用户创建的文档
+func (uc *PermissionUsecase) checkDocumentConditions(ctx context.Context, req *PermissionCheckRequest) (bool, error) {
+	scope, err := uc.GetPermissionScope(ctx, req.UserID, req.DocType, req.Permission, req.PermissionLevel)
+	if err != nil {
+		return false, err
+	}
+	return scope.Match(req.Document), nil
+}
+
+// loadConditionalRules 获取授予该操作的生效规则以及用户属性
+func (uc *PermissionUsecase) loadConditionalRules(ctx context.Context, userID int64, docType, action string, permissionLevel int) ([]*PermissionRule, map[string]interface{}, error) {
+	probe := &PermissionRule{}
+	if probe.actionFlag(action) == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedPermissionAction, action)
+	}
+
+	rules, err := uc.repo.ListUserPermissionRules(ctx, userID, docType, permissionLevel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var granting []*PermissionRule
+	for _, rule := range rules {
+		if *rule.actionFlag(action) {
+			granting = append(granting, rule)
+		}
+	}
+	if len(granting) == 0 {
+		return nil, nil, nil
+	}
+
+	user, err := uc.repo.GetUserConditionAttributes(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return granting, user, nil
+}
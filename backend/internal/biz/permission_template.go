@@ -0,0 +1,500 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// PermissionRuleActions 权限规则支持的全部操作，顺序与PermissionRule字段一致
+var PermissionRuleActions = []string{
+	"read", "write", "create", "delete", "submit", "cancel", "amend",
+	"print", "email", "import", "export", "share", "report", "set_user_permissions",
+}
+
+// actionFlag 返回规则中对应操作的权限标志
+func (r *PermissionRule) actionFlag(action string) *bool {
+	switch action {
+	case "read":
+		return &r.CanRead
+	case "write":
+		return &r.CanWrite
+	case "create":
+		return &r.CanCreate
+	case "delete":
+		return &r.CanDelete
+	case "submit":
+		return &r.CanSubmit
+	case "cancel":
+		return &r.CanCancel
+	case "amend":
+		return &r.CanAmend
+	case "print":
+		return &r.CanPrint
+	case "email":
+		return &r.CanEmail
+	case "import":
+		return &r.CanImport
+	case "export":
+		return &r.CanExport
+	case "share":
+		return &r.CanShare
+	case "report":
+		return &r.CanReport
+	case "set_user_permissions":
+		return &r.CanSetUserPermissions
+	}
+	return nil
+}
+
+// Actions 返回规则授予的操作列表
+func (r *PermissionRule) Actions() []string {
+	var actions []string
+	for _, action := range PermissionRuleActions {
+		if *r.actionFlag(action) {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// PermissionTemplate 权限模板，按权限级别定义一组操作，可批量应用到角色
+type PermissionTemplate struct {
+	ID          int64                      `json:"id"`
+	Name        string                     `json:"name"`                  // 模板名称
+	Description string                     `json:"description,omitempty"` // 描述
+	Levels      []*PermissionTemplateLevel `json:"levels"`                // 各级别操作集
+	Version     int                        `json:"version"`               // 版本号，每次修改递增
+	CreatedAt   time.Time                  `json:"created_at"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+	CreatedBy   *int64                     `json:"created_by,omitempty"`
+	UpdatedBy   *int64                     `json:"updated_by,omitempty"`
+}
+
+// PermissionTemplateLevel 模板中某一权限级别的操作集
+type PermissionTemplateLevel struct {
+	PermissionLevel int      `json:"permission_level"`
+	Actions         []string `json:"actions"`
+	OnlyIfCreator   bool     `json:"only_if_creator"`
+}
+
+// Validate 验证PermissionTemplate数据的完整性和正确性
+func (t *PermissionTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("template name is required")
+	}
+
+	if len(t.Levels) == 0 {
+		return fmt.Errorf("template must define at least one permission level")
+	}
+
+	seen := make(map[int]bool)
+	for _, level := range t.Levels {
+		if seen[level.PermissionLevel] {
+			return fmt.Errorf("duplicate permission level: %d", level.PermissionLevel)
+		}
+		seen[level.PermissionLevel] = true
+
+		// 借助权限规则的校验保证模板生成的规则合法
+		rule, err := level.toRule(1, t.Name)
+		if err != nil {
+			return err
+		}
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("level %d: %w", level.PermissionLevel, err)
+		}
+	}
+
+	return nil
+}
+
+// toRule 将模板级别转换为指定角色和文档类型的权限规则
+func (l *PermissionTemplateLevel) toRule(roleID int64, docType string) (*PermissionRule, error) {
+	rule := &PermissionRule{
+		RoleID:          roleID,
+		DocType:         docType,
+		PermissionLevel: l.PermissionLevel,
+		OnlyIfCreator:   l.OnlyIfCreator,
+	}
+	for _, action := range l.Actions {
+		flag := rule.actionFlag(action)
+		if flag == nil {
+			return nil, fmt.Errorf("invalid action: %s", action)
+		}
+		*flag = true
+	}
+	return rule, nil
+}
+
+// BuildRules 为角色在指定文档类型上生成模板对应的权限规则
+func (t *PermissionTemplate) BuildRules(roleID int64, docType string) ([]*PermissionRule, error) {
+	rules := make([]*PermissionRule, 0, len(t.Levels))
+	for _, level := range t.Levels {
+		rule, err := level.toRule(roleID, docType)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// PermissionTemplateApplication 模板应用记录，用于模板变更后重新应用
+type PermissionTemplateApplication struct {
+	ID              int64     `json:"id"`
+	TemplateID      int64     `json:"template_id"`
+	RoleID          int64     `json:"role_id"`
+	DocType         string    `json:"doc_type"`
+	TemplateVersion int       `json:"template_version"` // 应用时的模板版本
+	AppliedAt       time.Time `json:"applied_at"`
+	AppliedBy       *int64    `json:"applied_by,omitempty"`
+}
+
+// 模板应用时规则的变更类型
+const (
+	RuleChangeCreate    = "create"
+	RuleChangeUpdate    = "update"
+	RuleChangeUnchanged = "unchanged"
+)
+
+// PermissionRuleChange 模板应用时单条规则的变更
+type PermissionRuleChange struct {
+	Change          string          `json:"change"` // create, update, unchanged, delete
+	RoleID          int64           `json:"role_id"`
+	RoleCode        string          `json:"role_code,omitempty"`
+	DocType         string          `json:"doc_type"`
+	PermissionLevel int             `json:"permission_level"`
+	Before          []string        `json:"before,omitempty"`           // 现有规则的操作
+	After           []string        `json:"after"`                      // 应用后的操作
+	Added           []string        `json:"added,omitempty"`            // 新增的操作
+	Removed         []string        `json:"removed,omitempty"`          // 移除的操作
+	ConditionBefore string          `json:"condition_before,omitempty"` // 现有规则的条件表达式
+	ConditionAfter  string          `json:"condition_after,omitempty"`  // 应用后的条件表达式
+	Rule            *PermissionRule `json:"-"`
+}
+
+// PermissionTemplateDiff 模板应用预览结果
+type PermissionTemplateDiff struct {
+	TemplateID      int64                   `json:"template_id"`
+	TemplateName    string                  `json:"template_name"`
+	TemplateVersion int                     `json:"template_version"`
+	RoleID          int64                   `json:"role_id"`
+	DocTypes        []string                `json:"doc_types"`
+	Changes         []*PermissionRuleChange `json:"changes"`
+	Summary         map[string]int          `json:"summary"`
+	Applied         bool                    `json:"applied"`
+}
+
+// ApplyPermissionTemplateRequest 应用权限模板请求
+type ApplyPermissionTemplateRequest struct {
+	TemplateID int64    `json:"template_id"`
+	RoleID     int64    `json:"role_id"`
+	DocTypes   []string `json:"doc_types,omitempty"` // 指定文档类型
+	Module     string   `json:"module,omitempty"`    // 或指定整个模块
+	DryRun     bool     `json:"dry_run"`             // 仅预览不应用
+}
+
+// 错误定义
+var (
+	ErrPermissionTemplateNotFound   = errors.New("permission template not found")
+	ErrPermissionTemplateNameExists = errors.New("permission template name already exists")
+	ErrTemplateTargetRequired       = errors.New("doc types or module is required")
+)
+
+// PermissionTemplateRepo 权限模板仓储接口
+type PermissionTemplateRepo interface {
+	CreateTemplate(ctx context.Context, template *PermissionTemplate) (*PermissionTemplate, error)
+	UpdateTemplate(ctx context.Context, template *PermissionTemplate) (*PermissionTemplate, error)
+	GetTemplate(ctx context.Context, id int64) (*PermissionTemplate, error)
+	ListTemplates(ctx context.Context) ([]*PermissionTemplate, error)
+	DeleteTemplate(ctx context.Context, id int64) error
+
+	// ListApplications 获取模板在当前公司的应用记录；模板定义在公司间共享，应用记录按公司隔离
+	ListApplications(ctx context.Context, templateID int64) ([]*PermissionTemplateApplication, error)
+	SaveApplications(ctx context.Context, applications []*PermissionTemplateApplication) error
+}
+
+// PermissionTemplateUsecase 权限模板用例
+type PermissionTemplateUsecase struct {
+	repo     PermissionTemplateRepo
+	permRepo PermissionRepo
+	tx       Transaction
+	log      *log.Helper
+}
+
+// NewPermissionTemplateUsecase 创建权限模板用例
+func NewPermissionTemplateUsecase(repo PermissionTemplateRepo, permRepo PermissionRepo, tx Transaction, logger log.Logger) *PermissionTemplateUsecase {
+	return &PermissionTemplateUsecase{
+		repo:     repo,
+		permRepo: permRepo,
+		tx:       tx,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// CreateTemplate 创建权限模板
+func (uc *PermissionTemplateUsecase) CreateTemplate(ctx context.Context, template *PermissionTemplate) (*PermissionTemplate, error) {
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	template.Version = 1
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+	return uc.repo.CreateTemplate(ctx, template)
+}
+
+// UpdateTemplate 更新权限模板，版本号递增以便重新应用
+func (uc *PermissionTemplateUsecase) UpdateTemplate(ctx context.Context, template *PermissionTemplate) (*PermissionTemplate, error) {
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	template.UpdatedAt = time.Now()
+	return uc.repo.UpdateTemplate(ctx, template)
+}
+
+// GetTemplate 获取权限模板
+func (uc *PermissionTemplateUsecase) GetTemplate(ctx context.Context, id int64) (*PermissionTemplate, error) {
+	return uc.repo.GetTemplate(ctx, id)
+}
+
+// ListTemplates 获取权限模板列表
+func (uc *PermissionTemplateUsecase) ListTemplates(ctx context.Context) ([]*PermissionTemplate, error) {
+	return uc.repo.ListTemplates(ctx)
+}
+
+// DeleteTemplate 删除权限模板，已生成的权限规则保留
+func (uc *PermissionTemplateUsecase) DeleteTemplate(ctx context.Context, id int64) error {
+	return uc.repo.DeleteTemplate(ctx, id)
+}
+
+// ApplyTemplate 将模板应用到角色的多个文档类型，DryRun时只返回差异
+// 模板中未定义的级别保持现有规则不变
+func (uc *PermissionTemplateUsecase) ApplyTemplate(ctx context.Context, req *ApplyPermissionTemplateRequest, operatorID int64) (*PermissionTemplateDiff, error) {
+	if req.RoleID <= 0 {
+		return nil, fmt.Errorf("RoleID must be positive")
+	}
+
+	template, err := uc.repo.GetTemplate(ctx, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+
+	docTypes, err := uc.resolveDocTypes(ctx, req.DocTypes, req.Module)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := uc.diffTemplate(ctx, template, req.RoleID, docTypes)
+	if err != nil {
+		return nil, err
+	}
+	if req.DryRun {
+		return diff, nil
+	}
+
+	if err := uc.applyDiffs(ctx, template, []*PermissionTemplateDiff{diff}, operatorID); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// ReapplyTemplate 模板变更后，将其重新应用到所有曾应用过的角色和文档类型
+func (uc *PermissionTemplateUsecase) ReapplyTemplate(ctx context.Context, templateID int64, dryRun bool, operatorID int64) ([]*PermissionTemplateDiff, error) {
+	template, err := uc.repo.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	applications, err := uc.repo.ListApplications(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按角色分组
+	roleDocTypes := make(map[int64][]string)
+	var roleIDs []int64
+	for _, app := range applications {
+		if _, ok := roleDocTypes[app.RoleID]; !ok {
+			roleIDs = append(roleIDs, app.RoleID)
+		}
+		roleDocTypes[app.RoleID] = append(roleDocTypes[app.RoleID], app.DocType)
+	}
+	sort.Slice(roleIDs, func(i, j int) bool { return roleIDs[i] < roleIDs[j] })
+
+	diffs := make([]*PermissionTemplateDiff, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		diff, err := uc.diffTemplate(ctx, template, roleID, roleDocTypes[roleID])
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+	}
+
+	if dryRun || len(diffs) == 0 {
+		return diffs, nil
+	}
+
+	if err := uc.applyDiffs(ctx, template, diffs, operatorID); err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// resolveDocTypes 解析应用目标，模块优先展开为其下全部文档类型
+func (uc *PermissionTemplateUsecase) resolveDocTypes(ctx context.Context, docTypes []string, module string) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	for _, dt := range docTypes {
+		dt = strings.TrimSpace(dt)
+		if dt != "" && !seen[dt] {
+			seen[dt] = true
+			result = append(result, dt)
+		}
+	}
+
+	if module != "" {
+		moduleDocTypes, err := uc.permRepo.ListDocTypes(ctx, module)
+		if err != nil {
+			return nil, err
+		}
+		for _, dt := range moduleDocTypes {
+			if !seen[dt.Name] {
+				seen[dt.Name] = true
+				result = append(result, dt.Name)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrTemplateTargetRequired
+	}
+	return result, nil
+}
+
+// diffTemplate 计算模板应用到角色时与现有规则的差异
+func (uc *PermissionTemplateUsecase) diffTemplate(ctx context.Context, template *PermissionTemplate, roleID int64, docTypes []string) (*PermissionTemplateDiff, error) {
+	diff := &PermissionTemplateDiff{
+		TemplateID:      template.ID,
+		TemplateName:    template.Name,
+		TemplateVersion: template.Version,
+		RoleID:          roleID,
+		DocTypes:        docTypes,
+		Summary:         map[string]int{RuleChangeCreate: 0, RuleChangeUpdate: 0, RuleChangeUnchanged: 0},
+	}
+
+	for _, docType := range docTypes {
+		existing, err := uc.permRepo.ListPermissionRules(ctx, roleID, docType)
+		if err != nil {
+			return nil, err
+		}
+		byLevel := make(map[int]*PermissionRule, len(existing))
+		for _, rule := range existing {
+			byLevel[rule.PermissionLevel] = rule
+		}
+
+		rules, err := template.BuildRules(roleID, docType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rule := range rules {
+			change := &PermissionRuleChange{
+				RoleID:          roleID,
+				DocType:         docType,
+				PermissionLevel: rule.PermissionLevel,
+				After:           rule.Actions(),
+				Rule:            rule,
+			}
+
+			if current, ok := byLevel[rule.PermissionLevel]; !ok {
+				change.Change = RuleChangeCreate
+				change.Added = change.After
+			} else {
+				// 模板只定义操作，保留现有规则的条件
+				rule.Condition = current.Condition
+				change.ConditionBefore, change.ConditionAfter = current.Condition, current.Condition
+				change.Before = current.Actions()
+				change.Added, change.Removed = diffActions(change.Before, change.After)
+				if len(change.Added) == 0 && len(change.Removed) == 0 && current.OnlyIfCreator == rule.OnlyIfCreator {
+					change.Change = RuleChangeUnchanged
+				} else {
+					change.Change = RuleChangeUpdate
+				}
+			}
+
+			diff.Summary[change.Change]++
+			diff.Changes = append(diff.Changes, change)
+		}
+	}
+
+	return diff, nil
+}
+
+// applyDiffs 在一个事务中写入所有新增和变更的规则，并记录应用历史
+func (uc *PermissionTemplateUsecase) applyDiffs(ctx context.Context, template *PermissionTemplate, diffs []*PermissionTemplateDiff, operatorID int64) error {
+	now := time.Now()
+	var rules []*PermissionRule
+	var applications []*PermissionTemplateApplication
+	for _, diff := range diffs {
+		for _, change := range diff.Changes {
+			if change.Change == RuleChangeUnchanged {
+				continue
+			}
+			change.Rule.CreatedAt = now
+			change.Rule.UpdatedAt = now
+			rules = append(rules, change.Rule)
+		}
+		for _, docType := range diff.DocTypes {
+			applications = append(applications, &PermissionTemplateApplication{
+				TemplateID:      template.ID,
+				RoleID:          diff.RoleID,
+				DocType:         docType,
+				TemplateVersion: template.Version,
+				AppliedAt:       now,
+				AppliedBy:       &operatorID,
+			})
+		}
+	}
+
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := uc.permRepo.UpsertPermissionRules(ctx, rules); err != nil {
+			return err
+		}
+		return uc.repo.SaveApplications(ctx, applications)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, diff := range diffs {
+		diff.Applied = true
+	}
+	uc.log.Infof("Applied permission template %s (v%d): %d rules written", template.Name, template.Version, len(rules))
+	return nil
+}
+
+// diffActions 比较两个操作列表，返回新增和移除的操作
+func diffActions(before, after []string) (added, removed []string) {
+	beforeSet := make(map[string]bool, len(before))
+	for _, a := range before {
+		beforeSet[a] = true
+	}
+	afterSet := make(map[string]bool, len(after))
+	for _, a := range after {
+		afterSet[a] = true
+		if !beforeSet[a] {
+			added = append(added, a)
+		}
+	}
+	for _, a := range before {
+		if !afterSet[a] {
+			removed = append(removed, a)
+		}
+	}
+	return added, removed
+}
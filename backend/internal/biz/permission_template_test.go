@@ -0,0 +1,194 @@
+package biz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template *biz.PermissionTemplate
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name: "valid template",
+			template: &biz.PermissionTemplate{
+				Name: "Clerk",
+				Levels: []*biz.PermissionTemplateLevel{
+					{PermissionLevel: 0, Actions: []string{"read", "write", "create"}},
+					{PermissionLevel: 1, Actions: []string{"read"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:     "empty name",
+			template: &biz.PermissionTemplate{Levels: []*biz.PermissionTemplateLevel{{Actions: []string{"read"}}}},
+			wantErr:  true,
+			errMsg:   "template name is required",
+		},
+		{
+			name:     "no levels",
+			template: &biz.PermissionTemplate{Name: "Empty"},
+			wantErr:  true,
+			errMsg:   "template must define at least one permission level",
+		},
+		{
+			name: "duplicate level",
+			template: &biz.PermissionTemplate{
+				Name: "Dup",
+				Levels: []*biz.PermissionTemplateLevel{
+					{PermissionLevel: 0, Actions: []string{"read"}},
+					{PermissionLevel: 0, Actions: []string{"write"}},
+				},
+			},
+			wantErr: true,
+			errMsg:  "duplicate permission level: 0",
+		},
+		{
+			name: "unknown action",
+			template: &biz.PermissionTemplate{
+				Name:   "Bad",
+				Levels: []*biz.PermissionTemplateLevel{{PermissionLevel: 0, Actions: []string{"fly"}}},
+			},
+			wantErr: true,
+			errMsg:  "invalid action: fly",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPermissionTemplate_BuildRules(t *testing.T) {
+	template := &biz.PermissionTemplate{
+		Name: "Approver",
+		Levels: []*biz.PermissionTemplateLevel{
+			{PermissionLevel: 0, Actions: []string{"read", "submit", "cancel"}},
+			{PermissionLevel: 1, Actions: []string{"read"}, OnlyIfCreator: true},
+		},
+	}
+
+	rules, err := template.BuildRules(3, "Order")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+
+	assert.Equal(t, int64(3), rules[0].RoleID)
+	assert.Equal(t, "Order", rules[0].DocType)
+	assert.Equal(t, []string{"read", "submit", "cancel"}, rules[0].Actions())
+	assert.False(t, rules[0].CanWrite)
+
+	assert.Equal(t, 1, rules[1].PermissionLevel)
+	assert.True(t, rules[1].OnlyIfCreator)
+	assert.Equal(t, []string{"read"}, rules[1].Actions())
+}
+
+type stubTxKey struct{}
+
+// stubTransaction 在ctx中标记事务，记录提交和回滚次数
+type stubTransaction struct {
+	commits   int
+	rollbacks int
+}
+
+func (t *stubTransaction) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(stubTxKey{}) != nil {
+		return fn(ctx)
+	}
+	if err := fn(context.WithValue(ctx, stubTxKey{}, t)); err != nil {
+		t.rollbacks++
+		return err
+	}
+	t.commits++
+	return nil
+}
+
+// inStubTx ctx是否处于stubTransaction事务中
+func inStubTx(ctx context.Context) bool {
+	return ctx.Value(stubTxKey{}) != nil
+}
+
+// stubTemplateRepo 权限模板仓储桩
+type stubTemplateRepo struct {
+	biz.PermissionTemplateRepo
+	template     *biz.PermissionTemplate
+	saveErr      error
+	applications []*biz.PermissionTemplateApplication
+	savedInTx    bool
+}
+
+func (r *stubTemplateRepo) GetTemplate(ctx context.Context, id int64) (*biz.PermissionTemplate, error) {
+	return r.template, nil
+}
+
+func (r *stubTemplateRepo) SaveApplications(ctx context.Context, applications []*biz.PermissionTemplateApplication) error {
+	r.savedInTx = inStubTx(ctx)
+	if r.saveErr != nil {
+		return r.saveErr
+	}
+	r.applications = append(r.applications, applications...)
+	return nil
+}
+
+// stubTemplatePermRepo 记录模板写入的权限规则
+type stubTemplatePermRepo struct {
+	biz.PermissionRepo
+	upserted     []*biz.PermissionRule
+	upsertedInTx bool
+}
+
+func (r *stubTemplatePermRepo) ListPermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.PermissionRule, error) {
+	return nil, nil
+}
+
+func (r *stubTemplatePermRepo) UpsertPermissionRules(ctx context.Context, rules []*biz.PermissionRule) error {
+	r.upsertedInTx = inStubTx(ctx)
+	r.upserted = append(r.upserted, rules...)
+	return nil
+}
+
+func TestPermissionTemplateUsecase_ApplyTemplateInOneTransaction(t *testing.T) {
+	template := &biz.PermissionTemplate{
+		ID: 1, Name: "Clerk", Version: 2,
+		Levels: []*biz.PermissionTemplateLevel{{PermissionLevel: 0, Actions: []string{"read", "write"}}},
+	}
+	repo := &stubTemplateRepo{template: template}
+	permRepo := &stubTemplatePermRepo{}
+	tx := &stubTransaction{}
+	uc := biz.NewPermissionTemplateUsecase(repo, permRepo, tx, log.DefaultLogger)
+
+	req := &biz.ApplyPermissionTemplateRequest{TemplateID: 1, RoleID: 3, DocTypes: []string{"Order"}}
+	diff, err := uc.ApplyTemplate(context.Background(), req, 9)
+	assert.NoError(t, err)
+	assert.True(t, diff.Applied)
+	assert.Len(t, permRepo.upserted, 1)
+	assert.Len(t, repo.applications, 1)
+	assert.True(t, permRepo.upsertedInTx)
+	assert.True(t, repo.savedInTx)
+	assert.Equal(t, 1, tx.commits)
+
+	// 应用记录写入失败时规则一并回滚
+	repo.saveErr = errors.New("save failed")
+	diff, err = uc.ApplyTemplate(context.Background(), req, 9)
+	assert.EqualError(t, err, "save failed")
+	assert.Nil(t, diff)
+	assert.Equal(t, 1, tx.rollbacks)
+	assert.Equal(t, 1, tx.commits)
+}
@@ -0,0 +1,112 @@
+package biz_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocType_FieldWriteMode(t *testing.T) {
+	assert.Equal(t, biz.FieldWriteModeReject, (&biz.DocType{}).FieldWriteMode())
+	assert.Equal(t, biz.FieldWriteModeReject, (&biz.DocType{
+		Permissions: map[string]interface{}{biz.FieldWriteModeKey: biz.FieldWriteModeReject},
+	}).FieldWriteMode())
+	assert.Equal(t, biz.FieldWriteModeDrop, (&biz.DocType{
+		Permissions: map[string]interface{}{biz.FieldWriteModeKey: biz.FieldWriteModeDrop},
+	}).FieldWriteMode())
+}
+
+func TestLevelPermissions(t *testing.T) {
+	// 角色授予级别0和3，但未授予级别2
+	perms := &biz.LevelPermissions{}
+	perms.Grant(0, true, true)
+	perms.Grant(3, true, false)
+
+	assert.True(t, perms.CanRead(0))
+	assert.True(t, perms.CanWrite(0))
+	assert.False(t, perms.CanRead(2))
+	assert.True(t, perms.CanRead(3))
+	assert.False(t, perms.CanWrite(3))
+	assert.Equal(t, []int{0, 3}, perms.ReadLevels())
+	assert.Equal(t, []int{0}, perms.WriteLevels())
+
+	restored := biz.LevelPermissionsFromBitmask(perms.Bitmask())
+	assert.Equal(t, perms, restored)
+}
+
+// stubFieldGuardRepo 提供字段级别、用户级别权限和DocType屏蔽模式
+type stubFieldGuardRepo struct {
+	biz.PermissionRepo
+	levels  []*biz.FieldPermissionLevel
+	perms   *biz.LevelPermissions
+	docType *biz.DocType
+}
+
+func (r *stubFieldGuardRepo) ListFieldPermissionLevels(ctx context.Context, docType string, page, size int32) ([]*biz.FieldPermissionLevel, error) {
+	start := int((page - 1) * size)
+	if start >= len(r.levels) {
+		return nil, nil
+	}
+	end := start + int(size)
+	if end > len(r.levels) {
+		end = len(r.levels)
+	}
+	return r.levels[start:end], nil
+}
+
+func (r *stubFieldGuardRepo) GetUserLevelPermissions(ctx context.Context, userID int64, docType string) (*biz.LevelPermissions, error) {
+	return r.perms, nil
+}
+
+func (r *stubFieldGuardRepo) GetDocType(ctx context.Context, name string) (*biz.DocType, error) {
+	if r.docType == nil {
+		return nil, fmt.Errorf("doctype not found")
+	}
+	return r.docType, nil
+}
+
+func TestPermissionUsecase_GuardFieldWrites(t *testing.T) {
+	ctx := context.Background()
+	perms := &biz.LevelPermissions{}
+	perms.Grant(0, true, true)
+	perms.Grant(2, true, false)
+
+	// 超过单页数量的字段级别全部参与检查
+	repo := &stubFieldGuardRepo{perms: perms}
+	for i := 0; i < 1200; i++ {
+		repo.levels = append(repo.levels, &biz.FieldPermissionLevel{DocType: "User", FieldName: fmt.Sprintf("field_%d", i)})
+	}
+	repo.levels = append(repo.levels, &biz.FieldPermissionLevel{DocType: "User", FieldName: "salary", PermissionLevel: 2})
+	uc := biz.NewPermissionUsecase(repo, log.DefaultLogger)
+
+	dropped, err := uc.GuardFieldWrites(ctx, 1, "User", []string{"field_1", "unregistered"})
+	assert.NoError(t, err)
+	assert.Empty(t, dropped)
+
+	// 默认（DocType不存在）和reject模式拒绝整个请求
+	_, err = uc.GuardFieldWrites(ctx, 1, "User", []string{"field_1", "salary"})
+	var forbiddenErr *biz.FieldWriteForbiddenError
+	assert.ErrorAs(t, err, &forbiddenErr)
+	assert.Equal(t, []string{"salary"}, forbiddenErr.Fields)
+
+	repo.docType = &biz.DocType{Name: "User", Permissions: map[string]interface{}{biz.FieldWriteModeKey: biz.FieldWriteModeReject}}
+	_, err = uc.GuardFieldWrites(ctx, 1, "User", []string{"salary"})
+	assert.ErrorAs(t, err, &forbiddenErr)
+
+	// drop模式返回需要丢弃的字段
+	repo.docType.Permissions[biz.FieldWriteModeKey] = biz.FieldWriteModeDrop
+	dropped, err = uc.GuardFieldWrites(ctx, 1, "User", []string{"field_1", "salary"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"salary"}, dropped)
+
+	// 未配置字段级别的文档类型不做限制
+	repo.levels = nil
+	dropped, err = uc.GuardFieldWrites(ctx, 1, "User", []string{"salary"})
+	assert.NoError(t, err)
+	assert.Empty(t, dropped)
+}
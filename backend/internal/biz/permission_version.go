@@ -0,0 +1,258 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 权限配置变更的对象类型
+const (
+	PermissionConfigRule           = "permission_rule"
+	PermissionConfigUserPermission = "user_permission"
+	PermissionConfigFieldLevel     = "field_level"
+)
+
+// 触发权限配置版本的操作
+const (
+	PermissionVersionBaseline = "baseline" // 首次变更前的初始配置
+	PermissionVersionRollback = "rollback"
+)
+
+// PermissionSnapshot 权限配置快照
+type PermissionSnapshot struct {
+	PermissionRules []*PermissionRule       `json:"permission_rules"`
+	UserPermissions []*UserPermission       `json:"user_permissions"`
+	FieldLevels     []*FieldPermissionLevel `json:"field_levels"`
+}
+
+// PermissionVersion 权限配置版本，每次变更后记录一个版本
+type PermissionVersion struct {
+	ID         int64               `json:"id"`        // 版本号
+	Operation  string              `json:"operation"` // 触发版本的操作
+	Comment    string              `json:"comment,omitempty"`
+	AuthorID   *int64              `json:"author_id,omitempty"`
+	AuthorName string              `json:"author_name,omitempty"`
+	RollbackOf *int64              `json:"rollback_of,omitempty"` // 回滚时的目标版本
+	CreatedAt  time.Time           `json:"created_at"`
+	Snapshot   *PermissionSnapshot `json:"snapshot,omitempty"`
+}
+
+// PermissionConfigChange 两个版本之间单个配置项的变更
+type PermissionConfigChange struct {
+	Kind   string      `json:"kind"`   // permission_rule, user_permission, field_level
+	Key    string      `json:"key"`    // 配置项的业务主键
+	Change string      `json:"change"` // create, update, delete
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// PermissionVersionDiff 两个版本之间的差异
+type PermissionVersionDiff struct {
+	From    int64                     `json:"from"`
+	To      int64                     `json:"to"`
+	Changes []*PermissionConfigChange `json:"changes"`
+	Summary map[string]int            `json:"summary"`
+}
+
+// PermissionChangeset 当前权限变更的作者和说明，由上下文传递给仓储记录版本
+type PermissionChangeset struct {
+	AuthorID   *int64
+	Comment    string
+	RollbackOf *int64
+}
+
+type permissionChangesetKey struct{}
+
+// WithPermissionChangeset 在上下文中设置权限变更的作者和说明
+func WithPermissionChangeset(ctx context.Context, changeset *PermissionChangeset) context.Context {
+	return context.WithValue(ctx, permissionChangesetKey{}, changeset)
+}
+
+// PermissionChangesetFromContext 获取上下文中的权限变更信息，未设置时返回空变更
+func PermissionChangesetFromContext(ctx context.Context) *PermissionChangeset {
+	if changeset, ok := ctx.Value(permissionChangesetKey{}).(*PermissionChangeset); ok && changeset != nil {
+		return changeset
+	}
+	return &PermissionChangeset{}
+}
+
+// 错误定义
+var (
+	ErrPermissionVersionNotFound = errors.New("permission version not found")
+)
+
+// PermissionVersionRepo 权限配置版本仓储接口
+type PermissionVersionRepo interface {
+	// RecordVersion 以当前权限配置为快照记录一个版本
+	RecordVersion(ctx context.Context, version *PermissionVersion) (*PermissionVersion, error)
+	GetVersion(ctx context.Context, id int64) (*PermissionVersion, error)
+	ListVersions(ctx context.Context, page, size int32) ([]*PermissionVersion, int32, error)
+	HasVersions(ctx context.Context) (bool, error)
+	// LockVersions 在ctx所在事务中锁定权限配置直到事务结束，串行化配置变更及其版本记录
+	LockVersions(ctx context.Context) error
+}
+
+// PermissionVersionUsecase 权限配置版本用例
+type PermissionVersionUsecase struct {
+	repo     PermissionVersionRepo
+	permRepo PermissionRepo
+	log      *log.Helper
+}
+
+// NewPermissionVersionUsecase 创建权限配置版本用例
+func NewPermissionVersionUsecase(repo PermissionVersionRepo, permRepo PermissionRepo, logger log.Logger) *PermissionVersionUsecase {
+	return &PermissionVersionUsecase{
+		repo:     repo,
+		permRepo: permRepo,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// ListVersions 分页获取权限配置版本，不含快照
+func (uc *PermissionVersionUsecase) ListVersions(ctx context.Context, page, size int32) ([]*PermissionVersion, int32, error) {
+	return uc.repo.ListVersions(ctx, page, size)
+}
+
+// GetVersion 获取权限配置版本及其快照
+func (uc *PermissionVersionUsecase) GetVersion(ctx context.Context, id int64) (*PermissionVersion, error) {
+	return uc.repo.GetVersion(ctx, id)
+}
+
+// DiffVersions 比较两个版本的权限配置
+func (uc *PermissionVersionUsecase) DiffVersions(ctx context.Context, from, to int64) (*PermissionVersionDiff, error) {
+	fromVersion, err := uc.repo.GetVersion(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := uc.repo.GetVersion(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &PermissionVersionDiff{
+		From:    from,
+		To:      to,
+		Changes: DiffPermissionSnapshots(fromVersion.Snapshot, toVersion.Snapshot),
+		Summary: map[string]int{RuleChangeCreate: 0, RuleChangeUpdate: 0, RuleChangeDelete: 0},
+	}
+	for _, change := range diff.Changes {
+		diff.Summary[change.Change]++
+	}
+	return diff, nil
+}
+
+// Rollback 将权限配置恢复为指定版本的快照，恢复本身记录为一个新版本
+func (uc *PermissionVersionUsecase) Rollback(ctx context.Context, id int64, changeset *PermissionChangeset) (*PermissionVersion, error) {
+	target, err := uc.repo.GetVersion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changeset.RollbackOf = &target.ID
+	if changeset.Comment == "" {
+		changeset.Comment = fmt.Sprintf("回滚到版本 %d", target.ID)
+	}
+
+	if err := uc.permRepo.RestorePermissionSnapshot(WithPermissionChangeset(ctx, changeset), target.Snapshot); err != nil {
+		return nil, err
+	}
+
+	uc.log.Infof("Permission configuration rolled back to version %d", target.ID)
+	return target, nil
+}
+
+// DiffPermissionSnapshots 比较两个快照，按业务主键对齐配置项，忽略ID和审计字段
+func DiffPermissionSnapshots(from, to *PermissionSnapshot) []*PermissionConfigChange {
+	if from == nil {
+		from = &PermissionSnapshot{}
+	}
+	if to == nil {
+		to = &PermissionSnapshot{}
+	}
+
+	var changes []*PermissionConfigChange
+	changes = append(changes, diffConfigItems(PermissionConfigRule, ruleItems(from.PermissionRules), ruleItems(to.PermissionRules))...)
+	changes = append(changes, diffConfigItems(PermissionConfigUserPermission, userPermissionItems(from.UserPermissions), userPermissionItems(to.UserPermissions))...)
+	changes = append(changes, diffConfigItems(PermissionConfigFieldLevel, fieldLevelItems(from.FieldLevels), fieldLevelItems(to.FieldLevels))...)
+	return changes
+}
+
+// configItem 快照中的配置项，value为去除ID和审计字段后用于比较的副本
+type configItem struct {
+	original interface{}
+	value    interface{}
+}
+
+func ruleItems(rules []*PermissionRule) map[string]configItem {
+	items := make(map[string]configItem, len(rules))
+	for _, rule := range rules {
+		normalized := *rule
+		normalized.ID, normalized.CreatedAt, normalized.UpdatedAt = 0, time.Time{}, time.Time{}
+		normalized.CreatedBy, normalized.UpdatedBy, normalized.Role = nil, nil, nil
+		key := fmt.Sprintf("%d/%s/%d", rule.RoleID, rule.DocType, rule.PermissionLevel)
+		items[key] = configItem{original: rule, value: normalized}
+	}
+	return items
+}
+
+func userPermissionItems(permissions []*UserPermission) map[string]configItem {
+	items := make(map[string]configItem, len(permissions))
+	for _, permission := range permissions {
+		normalized := *permission
+		normalized.ID, normalized.CreatedAt, normalized.UpdatedAt = 0, time.Time{}, time.Time{}
+		normalized.CreatedBy, normalized.UpdatedBy, normalized.User = nil, nil, nil
+		applicableFor := ""
+		if permission.ApplicableFor != nil {
+			applicableFor = *permission.ApplicableFor
+		}
+		key := fmt.Sprintf("%d/%s/%s/%s", permission.UserID, permission.DocType, permission.DocName, applicableFor)
+		items[key] = configItem{original: permission, value: normalized}
+	}
+	return items
+}
+
+func fieldLevelItems(levels []*FieldPermissionLevel) map[string]configItem {
+	items := make(map[string]configItem, len(levels))
+	for _, level := range levels {
+		normalized := *level
+		normalized.ID, normalized.CreatedAt, normalized.UpdatedAt = 0, time.Time{}, time.Time{}
+		normalized.CreatedBy, normalized.UpdatedBy = nil, nil
+		items[level.DocType+"/"+level.FieldName] = configItem{original: level, value: normalized}
+	}
+	return items
+}
+
+// diffConfigItems 比较同类配置项，结果按主键排序
+func diffConfigItems(kind string, from, to map[string]configItem) []*PermissionConfigChange {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []*PermissionConfigChange
+	for _, key := range keys {
+		before, inFrom := from[key]
+		after, inTo := to[key]
+		switch {
+		case !inFrom:
+			changes = append(changes, &PermissionConfigChange{Kind: kind, Key: key, Change: RuleChangeCreate, After: after.original})
+		case !inTo:
+			changes = append(changes, &PermissionConfigChange{Kind: kind, Key: key, Change: RuleChangeDelete, Before: before.original})
+		case !reflect.DeepEqual(before.value, after.value):
+			changes = append(changes, &PermissionConfigChange{Kind: kind, Key: key, Change: RuleChangeUpdate, Before: before.original, After: after.original})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,62 @@
+package biz_test
+
+import (
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPermissionSnapshots(t *testing.T) {
+	applicableFor := "Order"
+	createdBy := int64(9)
+	from := &biz.PermissionSnapshot{
+		PermissionRules: []*biz.PermissionRule{
+			{ID: 1, RoleID: 1, DocType: "Order", CanRead: true},
+			{ID: 2, RoleID: 2, DocType: "Order", CanRead: true, CanSubmit: true},
+		},
+		UserPermissions: []*biz.UserPermission{
+			{ID: 1, UserID: 5, DocType: "Customer", DocName: "C001", Value: "C001", ApplicableFor: &applicableFor},
+		},
+		FieldLevels: []*biz.FieldPermissionLevel{
+			{ID: 1, DocType: "Order", FieldName: "amount", PermissionLevel: 1},
+		},
+	}
+	to := &biz.PermissionSnapshot{
+		PermissionRules: []*biz.PermissionRule{
+			// 回滚后ID和审计字段变化不算作差异
+			{ID: 10, RoleID: 1, DocType: "Order", CanRead: true, CreatedBy: &createdBy, UpdatedAt: time.Now()},
+			{ID: 2, RoleID: 2, DocType: "Order", CanRead: true},
+			{ID: 3, RoleID: 2, DocType: "Customer", CanRead: true},
+		},
+		FieldLevels: []*biz.FieldPermissionLevel{
+			{ID: 1, DocType: "Order", FieldName: "amount", PermissionLevel: 2},
+		},
+	}
+
+	changes := biz.DiffPermissionSnapshots(from, to)
+	assert.Len(t, changes, 4)
+
+	assert.Equal(t, biz.PermissionConfigRule, changes[0].Kind)
+	assert.Equal(t, "2/Customer/0", changes[0].Key)
+	assert.Equal(t, biz.RuleChangeCreate, changes[0].Change)
+	assert.Nil(t, changes[0].Before)
+
+	assert.Equal(t, "2/Order/0", changes[1].Key)
+	assert.Equal(t, biz.RuleChangeUpdate, changes[1].Change)
+	assert.True(t, changes[1].Before.(*biz.PermissionRule).CanSubmit)
+	assert.False(t, changes[1].After.(*biz.PermissionRule).CanSubmit)
+
+	assert.Equal(t, biz.PermissionConfigUserPermission, changes[2].Kind)
+	assert.Equal(t, "5/Customer/C001/Order", changes[2].Key)
+	assert.Equal(t, biz.RuleChangeDelete, changes[2].Change)
+
+	assert.Equal(t, biz.PermissionConfigFieldLevel, changes[3].Kind)
+	assert.Equal(t, "Order/amount", changes[3].Key)
+	assert.Equal(t, biz.RuleChangeUpdate, changes[3].Change)
+
+	assert.Empty(t, biz.DiffPermissionSnapshots(from, from))
+	assert.Len(t, biz.DiffPermissionSnapshots(nil, from), 4)
+}
@@ -0,0 +1,450 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 个人资料字段，与users表的列名一致
+const (
+	ProfileFieldEmail     = "email"
+	ProfileFieldFirstName = "first_name"
+	ProfileFieldLastName  = "last_name"
+	ProfileFieldPhone     = "phone"
+	ProfileFieldGender    = "gender"
+	ProfileFieldBirthDate = "birth_date"
+	ProfileFieldAvatarURL = "avatar_url"
+)
+
+// ProfileFields 支持自助修改的个人资料字段，变更按此顺序记录
+var ProfileFields = []string{
+	ProfileFieldFirstName, ProfileFieldLastName, ProfileFieldPhone, ProfileFieldGender,
+	ProfileFieldBirthDate, ProfileFieldAvatarURL, ProfileFieldEmail,
+}
+
+// 未配置字段策略时的默认值：邮箱需要管理员审批，其余字段可直接修改
+var (
+	DefaultProfileEditableFields = []string{
+		ProfileFieldFirstName, ProfileFieldLastName, ProfileFieldPhone, ProfileFieldGender,
+		ProfileFieldBirthDate, ProfileFieldAvatarURL,
+	}
+	DefaultProfileApprovalFields = []string{ProfileFieldEmail}
+)
+
+// 个人资料变更申请状态
+const (
+	ProfileChangePending   = "pending"
+	ProfileChangeApproved  = "approved"
+	ProfileChangeRejected  = "rejected"
+	ProfileChangeCancelled = "cancelled"
+)
+
+// 个人资料审计日志的操作类型
+const (
+	ProfileActionUpdate  = "profile_update"
+	ProfileActionRequest = "profile_change_request"
+	ProfileActionApprove = "profile_change_approve"
+	ProfileActionReject  = "profile_change_reject"
+	ProfileActionCancel  = "profile_change_cancel"
+)
+
+// ProfilePolicy 个人资料字段策略，EditableFields可直接修改，ApprovalFields需要管理员审批，同时出现时以审批为准
+type ProfilePolicy struct {
+	EditableFields []string
+	ApprovalFields []string
+}
+
+// ProfileFieldChange 字段变更，生日为2006-01-02格式，空字符串表示未填写
+type ProfileFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ProfileChangeRequest 需要审批的个人资料变更申请
+type ProfileChangeRequest struct {
+	ID           int32                 `json:"id"`
+	UserID       int32                 `json:"user_id"`
+	Username     string                `json:"username,omitempty"`
+	Changes      []*ProfileFieldChange `json:"changes"`
+	Status       string                `json:"status"`
+	Comment      string                `json:"comment,omitempty"`
+	ReviewedBy   *int32                `json:"reviewed_by,omitempty"`
+	ReviewerName string                `json:"reviewer_name,omitempty"`
+	ReviewedAt   *time.Time            `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+// ProfileChangeRequestFilter 变更申请查询条件，UserID为0时查询全部用户
+type ProfileChangeRequestFilter struct {
+	UserID int32
+	Status string
+	Page   int32
+	Size   int32
+}
+
+// ProfileUpdateResult 个人资料修改结果，需要审批的字段进入PendingRequest
+type ProfileUpdateResult struct {
+	Applied        []*ProfileFieldChange `json:"applied"`
+	PendingRequest *ProfileChangeRequest `json:"pending_request,omitempty"`
+}
+
+// 错误定义
+var (
+	ErrProfileFieldUnknown            = errors.New("unknown profile field")
+	ErrProfileNoChanges               = errors.New("no profile changes")
+	ErrProfileChangePending           = errors.New("a profile change request is already pending")
+	ErrProfileChangeRequestNotFound   = errors.New("profile change request not found")
+	ErrProfileChangeRequestNotPending = errors.New("profile change request is not pending")
+	ErrProfileChangeStale             = errors.New("profile changed after the request was submitted")
+	ErrProfileEmailExists             = errors.New("email already in use")
+	ErrInvalidProfileChangeStatus     = errors.New("invalid profile change request status")
+	ErrProfileChangeSelfReview        = errors.New("cannot review own profile change request")
+)
+
+// ProfileFieldNotEditableError 用户不能自助修改的字段
+type ProfileFieldNotEditableError struct {
+	Fields []string
+}
+
+func (e *ProfileFieldNotEditableError) Error() string {
+	return fmt.Sprintf("profile fields not editable: %s", strings.Join(e.Fields, ", "))
+}
+
+// ProfileRepo 个人资料仓储接口
+type ProfileRepo interface {
+	// ApplyProfileChanges 修改用户字段，任一字段的当前值与Old不一致时返回ErrProfileChangeStale
+	ApplyProfileChanges(ctx context.Context, userID int32, changes []*ProfileFieldChange) error
+	// CreateProfileChangeRequest 创建变更申请，用户已有待审批的申请时返回ErrProfileChangePending
+	CreateProfileChangeRequest(ctx context.Context, req *ProfileChangeRequest) (*ProfileChangeRequest, error)
+	GetProfileChangeRequest(ctx context.Context, id int32) (*ProfileChangeRequest, error)
+	ListProfileChangeRequests(ctx context.Context, filter *ProfileChangeRequestFilter) ([]*ProfileChangeRequest, int32, error)
+	// ReviewProfileChangeRequest 在一个事务中结束待审批的申请，apply为true时同时修改用户字段
+	ReviewProfileChangeRequest(ctx context.Context, id int32, status string, reviewerID int32, comment string, apply bool) (*ProfileChangeRequest, error)
+}
+
+// ProfileUsecase 个人资料自助修改用例
+type ProfileUsecase struct {
+	repo      ProfileRepo
+	userRepo  UserRepo
+	auditRepo AuditRepo
+	modes     map[string]string
+	log       *log.Helper
+}
+
+// 字段的修改方式
+const (
+	profileModeDirect   = "direct"
+	profileModeApproval = "approval"
+)
+
+// NewProfileUsecase 创建个人资料用例，未配置字段策略时使用默认策略，忽略不支持的字段
+func NewProfileUsecase(repo ProfileRepo, userRepo UserRepo, auditRepo AuditRepo, policy *ProfilePolicy, logger log.Logger) *ProfileUsecase {
+	helper := log.NewHelper(logger)
+	if policy == nil || (len(policy.EditableFields) == 0 && len(policy.ApprovalFields) == 0) {
+		policy = &ProfilePolicy{EditableFields: DefaultProfileEditableFields, ApprovalFields: DefaultProfileApprovalFields}
+	}
+
+	modes := make(map[string]string, len(ProfileFields))
+	for mode, fields := range map[string][]string{profileModeDirect: policy.EditableFields, profileModeApproval: policy.ApprovalFields} {
+		for _, field := range fields {
+			if !isProfileField(field) {
+				helper.Warnf("ignoring unsupported profile field in policy: %s", field)
+				continue
+			}
+			if modes[field] != profileModeApproval {
+				modes[field] = mode
+			}
+		}
+	}
+
+	return &ProfileUsecase{
+		repo:      repo,
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+		modes:     modes,
+		log:       helper,
+	}
+}
+
+// DiffProfile 计算提交的字段值与用户当前资料的差异，只返回实际变化的字段，不允许自助修改的字段返回ProfileFieldNotEditableError
+func (uc *ProfileUsecase) DiffProfile(ctx context.Context, userID int32, values map[string]string) ([]*ProfileFieldChange, error) {
+	for field := range values {
+		if !isProfileField(field) {
+			return nil, fmt.Errorf("%w: %s", ErrProfileFieldUnknown, field)
+		}
+	}
+	user, err := uc.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	current := profileValues(user)
+	var changes []*ProfileFieldChange
+	var notEditable []string
+	for _, field := range ProfileFields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == current[field] {
+			continue
+		}
+		if uc.modes[field] == "" {
+			notEditable = append(notEditable, field)
+			continue
+		}
+		changes = append(changes, &ProfileFieldChange{Field: field, Old: current[field], New: value})
+	}
+	if len(notEditable) > 0 {
+		return nil, &ProfileFieldNotEditableError{Fields: notEditable}
+	}
+	return changes, nil
+}
+
+// CheckDirectChanges 检查字段是否都可以不经审批直接修改，供用户通过其他接口修改自己的资料时使用
+func (uc *ProfileUsecase) CheckDirectChanges(fields []string) error {
+	var blocked []string
+	for _, field := range fields {
+		if uc.modes[field] != profileModeDirect {
+			blocked = append(blocked, field)
+		}
+	}
+	if len(blocked) > 0 {
+		return &ProfileFieldNotEditableError{Fields: blocked}
+	}
+	return nil
+}
+
+// UpdateProfile 修改用户自己的资料：可直接修改的字段立即生效，需要审批的字段创建变更申请，每次变更都记录前后值
+func (uc *ProfileUsecase) UpdateProfile(ctx context.Context, userID int32, username string, changes []*ProfileFieldChange) (*ProfileUpdateResult, error) {
+	if len(changes) == 0 {
+		return nil, ErrProfileNoChanges
+	}
+
+	var direct, approval []*ProfileFieldChange
+	for _, change := range changes {
+		switch uc.modes[change.Field] {
+		case profileModeDirect:
+			direct = append(direct, change)
+		case profileModeApproval:
+			approval = append(approval, change)
+		default:
+			return nil, &ProfileFieldNotEditableError{Fields: []string{change.Field}}
+		}
+	}
+
+	if err := uc.checkEmail(ctx, userID, changes); err != nil {
+		return nil, err
+	}
+
+	// 已有待审批的申请时整个请求失败，避免只生效了一部分字段
+	if len(approval) > 0 {
+		pending, _, err := uc.repo.ListProfileChangeRequests(ctx, &ProfileChangeRequestFilter{
+			UserID: userID, Status: ProfileChangePending, Page: 1, Size: 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 {
+			return nil, ErrProfileChangePending
+		}
+	}
+
+	result := &ProfileUpdateResult{Applied: []*ProfileFieldChange{}}
+	if len(direct) > 0 {
+		if err := uc.repo.ApplyProfileChanges(ctx, userID, direct); err != nil {
+			return nil, err
+		}
+		result.Applied = direct
+		uc.writeLog(ctx, userID, username, ProfileActionUpdate, userID,
+			fmt.Sprintf("用户 %s 修改个人资料：%s", username, changedFields(direct)),
+			map[string]interface{}{"changes": direct})
+	}
+
+	if len(approval) > 0 {
+		req, err := uc.repo.CreateProfileChangeRequest(ctx, &ProfileChangeRequest{
+			UserID:  userID,
+			Changes: approval,
+			Status:  ProfileChangePending,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.PendingRequest = req
+		uc.writeLog(ctx, userID, username, ProfileActionRequest, userID,
+			fmt.Sprintf("用户 %s 申请修改个人资料：%s", username, changedFields(approval)),
+			map[string]interface{}{"request_id": req.ID, "changes": approval})
+	}
+
+	uc.log.Infof("Profile of user %d updated: %d applied, %d pending approval", userID, len(direct), len(approval))
+	return result, nil
+}
+
+// ListChangeRequests 分页获取个人资料变更申请
+func (uc *ProfileUsecase) ListChangeRequests(ctx context.Context, filter *ProfileChangeRequestFilter) ([]*ProfileChangeRequest, int32, error) {
+	if filter.Status != "" && !isProfileChangeStatus(filter.Status) {
+		return nil, 0, ErrInvalidProfileChangeStatus
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Size <= 0 {
+		filter.Size = 10
+	}
+	return uc.repo.ListProfileChangeRequests(ctx, filter)
+}
+
+// ApproveChangeRequest 审批通过变更申请并修改用户资料，申请提交后字段又被修改过时拒绝审批
+func (uc *ProfileUsecase) ApproveChangeRequest(ctx context.Context, id, reviewerID int32, reviewerName, comment string) (*ProfileChangeRequest, error) {
+	req, err := uc.pendingRequestForReview(ctx, id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkEmail(ctx, req.UserID, req.Changes); err != nil {
+		return nil, err
+	}
+
+	reviewed, err := uc.repo.ReviewProfileChangeRequest(ctx, id, ProfileChangeApproved, reviewerID, comment, true)
+	if err != nil {
+		return nil, err
+	}
+	uc.writeLog(ctx, reviewerID, reviewerName, ProfileActionApprove, reviewed.UserID,
+		fmt.Sprintf("%s 审批通过用户 %d 的资料变更申请：%s", reviewerName, reviewed.UserID, changedFields(reviewed.Changes)),
+		map[string]interface{}{"request_id": reviewed.ID, "changes": reviewed.Changes, "comment": comment})
+	return reviewed, nil
+}
+
+// RejectChangeRequest 驳回变更申请
+func (uc *ProfileUsecase) RejectChangeRequest(ctx context.Context, id, reviewerID int32, reviewerName, comment string) (*ProfileChangeRequest, error) {
+	if _, err := uc.pendingRequestForReview(ctx, id, reviewerID); err != nil {
+		return nil, err
+	}
+
+	reviewed, err := uc.repo.ReviewProfileChangeRequest(ctx, id, ProfileChangeRejected, reviewerID, comment, false)
+	if err != nil {
+		return nil, err
+	}
+	uc.writeLog(ctx, reviewerID, reviewerName, ProfileActionReject, reviewed.UserID,
+		fmt.Sprintf("%s 驳回用户 %d 的资料变更申请：%s", reviewerName, reviewed.UserID, changedFields(reviewed.Changes)),
+		map[string]interface{}{"request_id": reviewed.ID, "changes": reviewed.Changes, "comment": comment})
+	return reviewed, nil
+}
+
+// CancelChangeRequest 用户撤回自己待审批的变更申请
+func (uc *ProfileUsecase) CancelChangeRequest(ctx context.Context, id, userID int32, username string) (*ProfileChangeRequest, error) {
+	req, err := uc.repo.GetProfileChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserID != userID {
+		return nil, ErrProfileChangeRequestNotFound
+	}
+
+	cancelled, err := uc.repo.ReviewProfileChangeRequest(ctx, id, ProfileChangeCancelled, userID, "", false)
+	if err != nil {
+		return nil, err
+	}
+	uc.writeLog(ctx, userID, username, ProfileActionCancel, userID,
+		fmt.Sprintf("用户 %s 撤回资料变更申请：%s", username, changedFields(cancelled.Changes)),
+		map[string]interface{}{"request_id": cancelled.ID, "changes": cancelled.Changes})
+	return cancelled, nil
+}
+
+// pendingRequestForReview 获取待审批的申请，审批人不能审批自己的申请
+func (uc *ProfileUsecase) pendingRequestForReview(ctx context.Context, id, reviewerID int32) (*ProfileChangeRequest, error) {
+	req, err := uc.repo.GetProfileChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != ProfileChangePending {
+		return nil, ErrProfileChangeRequestNotPending
+	}
+	if req.UserID == reviewerID {
+		return nil, ErrProfileChangeSelfReview
+	}
+	return req, nil
+}
+
+// checkEmail 检查新邮箱未被其他用户使用
+func (uc *ProfileUsecase) checkEmail(ctx context.Context, userID int32, changes []*ProfileFieldChange) error {
+	for _, change := range changes {
+		if change.Field != ProfileFieldEmail {
+			continue
+		}
+		if existing, err := uc.userRepo.GetUserByEmail(ctx, change.New); err == nil && existing.ID != userID {
+			return ErrProfileEmailExists
+		}
+	}
+	return nil
+}
+
+// writeLog 写入个人资料变更的审计日志，detail中的changes记录每个字段的前后值，失败时仅记录错误
+func (uc *ProfileUsecase) writeLog(ctx context.Context, operatorID int32, operatorName, action string, userID int32, description string, detail map[string]interface{}) {
+	requestData, _ := json.Marshal(detail)
+	entry := &OperationLog{
+		UserID:      &operatorID,
+		Username:    operatorName,
+		Action:      action,
+		Resource:    "User",
+		ResourceID:  strconv.Itoa(int(userID)),
+		Description: description,
+		RequestData: string(requestData),
+		Status:      "success",
+		CreatedAt:   time.Now(),
+	}
+	if err := uc.auditRepo.CreateOperationLog(ctx, entry); err != nil {
+		uc.log.Errorf("failed to write audit log for profile of user %d: %v", userID, err)
+	}
+}
+
+// profileValues 用户当前的个人资料字段值
+func profileValues(user *User) map[string]string {
+	birthDate := ""
+	if !user.BirthDate.IsZero() {
+		birthDate = user.BirthDate.Format("2006-01-02")
+	}
+	return map[string]string{
+		ProfileFieldEmail:     user.Email,
+		ProfileFieldFirstName: user.FirstName,
+		ProfileFieldLastName:  user.LastName,
+		ProfileFieldPhone:     user.Phone,
+		ProfileFieldGender:    user.Gender,
+		ProfileFieldBirthDate: birthDate,
+		ProfileFieldAvatarURL: user.AvatarURL,
+	}
+}
+
+// changedFields 变更字段列表，用于日志描述
+func changedFields(changes []*ProfileFieldChange) string {
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	return strings.Join(fields, ", ")
+}
+
+func isProfileField(field string) bool {
+	for _, f := range ProfileFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isProfileChangeStatus(status string) bool {
+	switch status {
+	case ProfileChangePending, ProfileChangeApproved, ProfileChangeRejected, ProfileChangeCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package biz_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubProfileRepo 以字段值表保存用户资料
+type stubProfileRepo struct {
+	biz.UserRepo
+	profiles map[int32]map[string]string
+	requests []*biz.ProfileChangeRequest
+}
+
+func (r *stubProfileRepo) GetUser(ctx context.Context, id int32) (*biz.User, error) {
+	profile, ok := r.profiles[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	birthDate, _ := time.Parse("2006-01-02", profile[biz.ProfileFieldBirthDate])
+	return &biz.User{
+		ID: id, Email: profile[biz.ProfileFieldEmail], FirstName: profile[biz.ProfileFieldFirstName],
+		LastName: profile[biz.ProfileFieldLastName], Phone: profile[biz.ProfileFieldPhone], BirthDate: birthDate,
+	}, nil
+}
+
+func (r *stubProfileRepo) GetUserByEmail(ctx context.Context, email string) (*biz.User, error) {
+	for id, profile := range r.profiles {
+		if profile[biz.ProfileFieldEmail] == email {
+			return r.GetUser(ctx, id)
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (r *stubProfileRepo) ApplyProfileChanges(ctx context.Context, userID int32, changes []*biz.ProfileFieldChange) error {
+	profile := r.profiles[userID]
+	for _, change := range changes {
+		if profile[change.Field] != change.Old {
+			return biz.ErrProfileChangeStale
+		}
+	}
+	for _, change := range changes {
+		profile[change.Field] = change.New
+	}
+	return nil
+}
+
+func (r *stubProfileRepo) CreateProfileChangeRequest(ctx context.Context, req *biz.ProfileChangeRequest) (*biz.ProfileChangeRequest, error) {
+	req.ID = int32(len(r.requests) + 1)
+	r.requests = append(r.requests, req)
+	return req, nil
+}
+
+func (r *stubProfileRepo) GetProfileChangeRequest(ctx context.Context, id int32) (*biz.ProfileChangeRequest, error) {
+	if id < 1 || int(id) > len(r.requests) {
+		return nil, biz.ErrProfileChangeRequestNotFound
+	}
+	return r.requests[id-1], nil
+}
+
+func (r *stubProfileRepo) ListProfileChangeRequests(ctx context.Context, filter *biz.ProfileChangeRequestFilter) ([]*biz.ProfileChangeRequest, int32, error) {
+	var requests []*biz.ProfileChangeRequest
+	for _, req := range r.requests {
+		if (filter.UserID == 0 || req.UserID == filter.UserID) && (filter.Status == "" || req.Status == filter.Status) {
+			requests = append(requests, req)
+		}
+	}
+	return requests, int32(len(requests)), nil
+}
+
+func (r *stubProfileRepo) ReviewProfileChangeRequest(ctx context.Context, id int32, status string, reviewerID int32, comment string, apply bool) (*biz.ProfileChangeRequest, error) {
+	req, err := r.GetProfileChangeRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != biz.ProfileChangePending {
+		return nil, biz.ErrProfileChangeRequestNotPending
+	}
+	if apply {
+		if err := r.ApplyProfileChanges(ctx, req.UserID, req.Changes); err != nil {
+			return nil, err
+		}
+	}
+	req.Status, req.ReviewedBy, req.Comment = status, &reviewerID, comment
+	return req, nil
+}
+
+func TestProfileUsecase(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubProfileRepo{profiles: map[int32]map[string]string{
+		1: {biz.ProfileFieldEmail: "alice@example.com", biz.ProfileFieldFirstName: "Alice", biz.ProfileFieldLastName: "Liu"},
+		2: {biz.ProfileFieldEmail: "bob@example.com", biz.ProfileFieldFirstName: "Bob"},
+		9: {biz.ProfileFieldEmail: "admin@example.com"},
+	}}
+	auditRepo := &stubAuditRepo{}
+
+	// 未配置策略时使用默认策略：邮箱需要审批，其余字段可直接修改
+	uc := biz.NewProfileUsecase(repo, repo, auditRepo, nil, log.DefaultLogger)
+	assert.NoError(t, uc.CheckDirectChanges([]string{biz.ProfileFieldPhone, biz.ProfileFieldFirstName}))
+	var notEditable *biz.ProfileFieldNotEditableError
+	assert.ErrorAs(t, uc.CheckDirectChanges([]string{biz.ProfileFieldEmail, biz.ProfileFieldPhone}), &notEditable)
+	assert.Equal(t, []string{biz.ProfileFieldEmail}, notEditable.Fields)
+
+	// 只返回实际变化的字段，未知字段报错
+	_, err := uc.DiffProfile(ctx, 1, map[string]string{"username": "alice2"})
+	assert.ErrorIs(t, err, biz.ErrProfileFieldUnknown)
+	_, err = uc.DiffProfile(ctx, 404, map[string]string{biz.ProfileFieldPhone: "13800138000"})
+	assert.ErrorIs(t, err, biz.ErrUserNotFound)
+	changes, err := uc.DiffProfile(ctx, 1, map[string]string{
+		biz.ProfileFieldFirstName: "Alice", biz.ProfileFieldPhone: " 13800138000 ", biz.ProfileFieldEmail: "alice@corp.example.com",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*biz.ProfileFieldChange{
+		{Field: biz.ProfileFieldPhone, Old: "", New: "13800138000"},
+		{Field: biz.ProfileFieldEmail, Old: "alice@example.com", New: "alice@corp.example.com"},
+	}, changes)
+
+	// 可直接修改的字段立即生效，邮箱进入待审批申请，两类变更都记录前后值
+	result, err := uc.UpdateProfile(ctx, 1, "alice", changes)
+	assert.NoError(t, err)
+	assert.Equal(t, "13800138000", repo.profiles[1][biz.ProfileFieldPhone])
+	assert.Equal(t, "alice@example.com", repo.profiles[1][biz.ProfileFieldEmail])
+	assert.Len(t, result.Applied, 1)
+	if assert.NotNil(t, result.PendingRequest) {
+		assert.Equal(t, biz.ProfileChangePending, result.PendingRequest.Status)
+	}
+	if assert.Len(t, auditRepo.logs, 2) {
+		assert.Equal(t, biz.ProfileActionUpdate, auditRepo.logs[0].Action)
+		assert.Equal(t, "1", auditRepo.logs[0].ResourceID)
+		assert.Contains(t, auditRepo.logs[0].RequestData, `"old":"","new":"13800138000"`)
+		assert.Equal(t, biz.ProfileActionRequest, auditRepo.logs[1].Action)
+		assert.Contains(t, auditRepo.logs[1].RequestData, `"old":"alice@example.com","new":"alice@corp.example.com"`)
+	}
+
+	// 已有待审批申请时不能再提交需要审批的修改，也不会生效其余字段
+	_, err = uc.UpdateProfile(ctx, 1, "alice", []*biz.ProfileFieldChange{
+		{Field: biz.ProfileFieldLastName, Old: "Liu", New: "Li"},
+		{Field: biz.ProfileFieldEmail, Old: "alice@example.com", New: "alice@other.example.com"},
+	})
+	assert.ErrorIs(t, err, biz.ErrProfileChangePending)
+	assert.Equal(t, "Liu", repo.profiles[1][biz.ProfileFieldLastName])
+
+	// 邮箱已被其他用户使用
+	_, err = uc.UpdateProfile(ctx, 2, "bob", []*biz.ProfileFieldChange{{Field: biz.ProfileFieldEmail, Old: "bob@example.com", New: "alice@example.com"}})
+	assert.ErrorIs(t, err, biz.ErrProfileEmailExists)
+	_, err = uc.UpdateProfile(ctx, 2, "bob", nil)
+	assert.ErrorIs(t, err, biz.ErrProfileNoChanges)
+
+	// 不能审批自己的申请；审批通过后邮箱生效，已处理的申请不能再次审批
+	id := result.PendingRequest.ID
+	_, err = uc.ApproveChangeRequest(ctx, id, 1, "alice", "")
+	assert.ErrorIs(t, err, biz.ErrProfileChangeSelfReview)
+	approved, err := uc.ApproveChangeRequest(ctx, id, 9, "admin", "ok")
+	assert.NoError(t, err)
+	assert.Equal(t, biz.ProfileChangeApproved, approved.Status)
+	assert.Equal(t, "alice@corp.example.com", repo.profiles[1][biz.ProfileFieldEmail])
+	assert.Equal(t, biz.ProfileActionApprove, auditRepo.logs[len(auditRepo.logs)-1].Action)
+	_, err = uc.RejectChangeRequest(ctx, id, 9, "admin", "")
+	assert.ErrorIs(t, err, biz.ErrProfileChangeRequestNotPending)
+
+	// 申请提交后字段又被修改时不能审批通过
+	pending, err := uc.UpdateProfile(ctx, 2, "bob", []*biz.ProfileFieldChange{{Field: biz.ProfileFieldEmail, Old: "bob@example.com", New: "bob@corp.example.com"}})
+	assert.NoError(t, err)
+	repo.profiles[2][biz.ProfileFieldEmail] = "bob@admin-set.example.com"
+	_, err = uc.ApproveChangeRequest(ctx, pending.PendingRequest.ID, 9, "admin", "")
+	assert.ErrorIs(t, err, biz.ErrProfileChangeStale)
+
+	// 只能撤回自己的申请
+	_, err = uc.CancelChangeRequest(ctx, pending.PendingRequest.ID, 1, "alice")
+	assert.ErrorIs(t, err, biz.ErrProfileChangeRequestNotFound)
+	cancelled, err := uc.CancelChangeRequest(ctx, pending.PendingRequest.ID, 2, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, biz.ProfileChangeCancelled, cancelled.Status)
+
+	_, _, err = uc.ListChangeRequests(ctx, &biz.ProfileChangeRequestFilter{Status: "done"})
+	assert.ErrorIs(t, err, biz.ErrInvalidProfileChangeStatus)
+
+	// 自定义策略：未列出的字段不能自助修改，同时列在两处的字段需要审批
+	uc = biz.NewProfileUsecase(repo, repo, auditRepo, &biz.ProfilePolicy{
+		EditableFields: []string{biz.ProfileFieldFirstName, biz.ProfileFieldLastName, "nickname"},
+		ApprovalFields: []string{biz.ProfileFieldLastName},
+	}, log.DefaultLogger)
+	_, err = uc.DiffProfile(ctx, 1, map[string]string{biz.ProfileFieldPhone: "13900139000"})
+	assert.ErrorAs(t, err, &notEditable)
+	assert.Equal(t, []string{biz.ProfileFieldPhone}, notEditable.Fields)
+	assert.ErrorAs(t, uc.CheckDirectChanges([]string{biz.ProfileFieldLastName}), &notEditable)
+}
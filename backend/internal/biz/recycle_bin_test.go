@@ -0,0 +1,72 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubRecycleBinRepo struct {
+	biz.RecycleBinRepo
+	items      []*biz.DeletedItem
+	referenced map[int32]bool
+	before     time.Time
+	purged     []int32
+}
+
+func (r *stubRecycleBinRepo) ListDeletedItems(ctx context.Context, filter *biz.RecycleBinFilter) ([]*biz.DeletedItem, int64, error) {
+	return r.items, int64(len(r.items)), nil
+}
+
+func (r *stubRecycleBinRepo) ListExpiredItems(ctx context.Context, before time.Time) ([]*biz.DeletedItem, error) {
+	r.before = before
+	return r.items, nil
+}
+
+func (r *stubRecycleBinRepo) PurgeItem(ctx context.Context, itemType string, id int32) error {
+	if r.referenced[id] {
+		return biz.ErrRecycleBinItemReferenced
+	}
+	r.purged = append(r.purged, id)
+	return nil
+}
+
+func TestRecycleBinUsecase(t *testing.T) {
+	ctx := context.Background()
+	deletedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &stubRecycleBinRepo{
+		items: []*biz.DeletedItem{
+			{Type: biz.RecycleBinOrganization, ID: 1, DeletedAt: deletedAt},
+			{Type: biz.RecycleBinRole, ID: 2, DeletedAt: deletedAt},
+			{Type: biz.RecycleBinUser, ID: 3, DeletedAt: deletedAt},
+		},
+		referenced: map[int32]bool{2: true},
+	}
+
+	// 未配置保留期时使用默认保留期计算永久删除时间
+	uc := biz.NewRecycleBinUsecase(repo, 0, log.DefaultLogger)
+	items, total, err := uc.ListDeletedItems(ctx, &biz.RecycleBinFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, deletedAt.Add(biz.DefaultRecycleBinRetention), items[0].PurgeAt)
+
+	// 不支持的类型
+	_, _, err = uc.ListDeletedItems(ctx, &biz.RecycleBinFilter{Type: "permission"})
+	assert.ErrorIs(t, err, biz.ErrInvalidRecycleBinType)
+	assert.ErrorIs(t, uc.RestoreItem(ctx, "permission", 1), biz.ErrInvalidRecycleBinType)
+
+	// 按配置的保留期清理，仍被引用的条目跳过且不中断清理
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	uc = biz.NewRecycleBinUsecase(repo, biz.RecycleBinRetention(7*24*time.Hour), log.DefaultLogger)
+	result, err := uc.PurgeExpiredItems(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-7*24*time.Hour), repo.before)
+	assert.Equal(t, []int32{1, 3}, repo.purged)
+	assert.Equal(t, 2, result.Purged)
+	assert.Equal(t, 1, result.Skipped)
+}
@@ -0,0 +1,281 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// RoleAssignment 用户角色分配，可限定有效期或由其他用户委托而来
+type RoleAssignment struct {
+	ID          int64      `json:"id"`
+	UserID      int32      `json:"user_id"`
+	RoleID      int32      `json:"role_id"`
+	RoleCode    string     `json:"role_code,omitempty"`
+	RoleName    string     `json:"role_name,omitempty"`
+	ValidFrom   *time.Time `json:"valid_from,omitempty"`   // 为空表示立即生效
+	ValidUntil  *time.Time `json:"valid_until,omitempty"`  // 为空表示长期有效
+	AssignedBy  *int64     `json:"assigned_by,omitempty"`  // 分配人
+	DelegatedBy *int64     `json:"delegated_by,omitempty"` // 委托人，非空表示委托分配
+	Reason      string     `json:"reason,omitempty"`
+	IsActive    bool       `json:"is_active"`
+	AssignedAt  time.Time  `json:"assigned_at"`
+}
+
+// IsDelegated 是否为委托获得的分配
+func (a *RoleAssignment) IsDelegated() bool {
+	return a.DelegatedBy != nil
+}
+
+// IsEffective 判断分配在指定时间点是否生效
+func (a *RoleAssignment) IsEffective(now time.Time) bool {
+	if !a.IsActive {
+		return false
+	}
+	if a.ValidFrom != nil && now.Before(*a.ValidFrom) {
+		return false
+	}
+	if a.ValidUntil != nil && !now.Before(*a.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// IsExpired 判断分配在指定时间点是否已过期
+func (a *RoleAssignment) IsExpired(now time.Time) bool {
+	return a.ValidUntil != nil && !now.Before(*a.ValidUntil)
+}
+
+// ValidatePeriod 校验有效期：生效时间须早于失效时间，且失效时间不能早于当前时间
+func (a *RoleAssignment) ValidatePeriod(now time.Time) error {
+	if a.ValidFrom != nil && a.ValidUntil != nil && !a.ValidFrom.Before(*a.ValidUntil) {
+		return ErrInvalidAssignmentPeriod
+	}
+	if a.IsExpired(now) {
+		return ErrInvalidAssignmentPeriod
+	}
+	return nil
+}
+
+// 角色分配审计日志的资源与操作类型
+const (
+	RoleAssignmentAuditResource = "user_role"
+	RoleAssignmentActionExpire  = "role_assignment_expired"
+)
+
+// 错误定义
+var (
+	ErrRoleAssignmentNotFound   = errors.New("role assignment not found")
+	ErrInvalidAssignmentPeriod  = errors.New("valid_from must be before valid_until and valid_until must be in the future")
+	ErrDelegationPeriodRequired = errors.New("delegation requires valid_until")
+	ErrDelegationToSelf         = errors.New("cannot delegate a role to yourself")
+	ErrDelegatorLacksRole       = errors.New("delegator does not hold the role directly")
+	ErrDelegationExceedsGrant   = errors.New("delegation period exceeds the delegator's own assignment")
+)
+
+// RoleAssignmentRepo 角色分配仓储接口；用户和角色在公司间共享，角色分配同样不按公司隔离
+type RoleAssignmentRepo interface {
+	CreateRoleAssignment(ctx context.Context, assignment *RoleAssignment) (*RoleAssignment, error)
+	GetRoleAssignment(ctx context.Context, id int64) (*RoleAssignment, error)
+	ListUserRoleAssignments(ctx context.Context, userID int32, includeExpired bool) ([]*RoleAssignment, error)
+	ListDelegationsByUser(ctx context.Context, delegatorID int32) ([]*RoleAssignment, error)
+	// DeleteRoleAssignment 删除分配并级联撤销失去依据的委托，返回角色发生变化的用户（被分配人及被级联撤销的被委托人）
+	DeleteRoleAssignment(ctx context.Context, id int64) ([]int32, error)
+
+	// ListExpiredRoleAssignments 获取截至指定时间已过期的分配
+	ListExpiredRoleAssignments(ctx context.Context, now time.Time, limit int) ([]*RoleAssignment, error)
+}
+
+// RoleAssignmentUsecase 角色分配用例
+type RoleAssignmentUsecase struct {
+	repo      RoleAssignmentRepo
+	permRepo  PermissionRepo
+	auditRepo AuditRepo
+	log       *log.Helper
+}
+
+// NewRoleAssignmentUsecase 创建角色分配用例
+func NewRoleAssignmentUsecase(repo RoleAssignmentRepo, permRepo PermissionRepo, auditRepo AuditRepo, logger log.Logger) *RoleAssignmentUsecase {
+	return &RoleAssignmentUsecase{
+		repo:      repo,
+		permRepo:  permRepo,
+		auditRepo: auditRepo,
+		log:       log.NewHelper(logger),
+	}
+}
+
+// clearUserCache 角色分配变化后清除相关用户的权限缓存，清除失败仅记录日志，缓存仍会按TTL过期
+func (uc *RoleAssignmentUsecase) clearUserCache(ctx context.Context, userIDs ...int32) {
+	ids := make([]int64, 0, len(userIDs))
+	for _, id := range userIDs {
+		ids = append(ids, int64(id))
+	}
+	if err := uc.permRepo.ClearUserPermissionCache(ctx, ids...); err != nil {
+		uc.log.Warnf("Failed to clear permission cache for users %v: %v", userIDs, err)
+	}
+}
+
+// ListUserAssignments 获取用户的角色分配，includeExpired为false时仅返回未过期的分配
+func (uc *RoleAssignmentUsecase) ListUserAssignments(ctx context.Context, userID int32, includeExpired bool) ([]*RoleAssignment, error) {
+	return uc.repo.ListUserRoleAssignments(ctx, userID, includeExpired)
+}
+
+// ListDelegations 获取用户委托给他人的角色分配
+func (uc *RoleAssignmentUsecase) ListDelegations(ctx context.Context, delegatorID int32) ([]*RoleAssignment, error) {
+	return uc.repo.ListDelegationsByUser(ctx, delegatorID)
+}
+
+// GetAssignment 获取角色分配
+func (uc *RoleAssignmentUsecase) GetAssignment(ctx context.Context, id int64) (*RoleAssignment, error) {
+	return uc.repo.GetRoleAssignment(ctx, id)
+}
+
+// AssignRole 为用户新增一条（可限定有效期的）直接角色分配
+func (uc *RoleAssignmentUsecase) AssignRole(ctx context.Context, assignment *RoleAssignment, operatorID int64) (*RoleAssignment, error) {
+	if err := assignment.ValidatePeriod(time.Now()); err != nil {
+		return nil, err
+	}
+
+	assignment.AssignedBy = &operatorID
+	assignment.DelegatedBy = nil
+	assignment.IsActive = true
+	result, err := uc.repo.CreateRoleAssignment(ctx, assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.clearUserCache(ctx, result.UserID)
+	return result, nil
+}
+
+// DelegateRole 委托人将自身直接持有的角色在一段时间内委托给他人
+func (uc *RoleAssignmentUsecase) DelegateRole(ctx context.Context, delegatorID int32, assignment *RoleAssignment) (*RoleAssignment, error) {
+	now := time.Now()
+	if assignment.ValidUntil == nil {
+		return nil, ErrDelegationPeriodRequired
+	}
+	if err := assignment.ValidatePeriod(now); err != nil {
+		return nil, err
+	}
+	if assignment.UserID == delegatorID {
+		return nil, ErrDelegationToSelf
+	}
+
+	grants, err := uc.repo.ListUserRoleAssignments(ctx, delegatorID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDelegationGrant(grants, assignment, now); err != nil {
+		return nil, err
+	}
+
+	delegator := int64(delegatorID)
+	assignment.AssignedBy = &delegator
+	assignment.DelegatedBy = &delegator
+	assignment.IsActive = true
+	result, err := uc.repo.CreateRoleAssignment(ctx, assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.clearUserCache(ctx, result.UserID)
+	return result, nil
+}
+
+// checkDelegationGrant 校验委托人当前直接持有该角色，且委托期不超出其自身分配的有效期
+// 委托得来的角色不能再次委托
+func checkDelegationGrant(grants []*RoleAssignment, delegation *RoleAssignment, now time.Time) error {
+	held := false
+	var coveredUntil *time.Time
+	for _, grant := range grants {
+		if grant.RoleID != delegation.RoleID || grant.IsDelegated() || !grant.IsEffective(now) {
+			continue
+		}
+		if grant.ValidUntil == nil {
+			return nil
+		}
+		if !held || grant.ValidUntil.After(*coveredUntil) {
+			coveredUntil = grant.ValidUntil
+		}
+		held = true
+	}
+
+	if !held {
+		return ErrDelegatorLacksRole
+	}
+	if delegation.ValidUntil.After(*coveredUntil) {
+		return ErrDelegationExceedsGrant
+	}
+	return nil
+}
+
+// RevokeAssignment 撤销角色分配，撤销委托人的直接分配时一并撤销其转授的委托
+func (uc *RoleAssignmentUsecase) RevokeAssignment(ctx context.Context, id int64) (*RoleAssignment, error) {
+	assignment, err := uc.repo.GetRoleAssignment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := uc.repo.DeleteRoleAssignment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.clearUserCache(ctx, affected...)
+	return assignment, nil
+}
+
+// CleanupExpiredAssignments 清理已过期的角色分配，每条清理记录写入操作日志
+func (uc *RoleAssignmentUsecase) CleanupExpiredAssignments(ctx context.Context, now time.Time) (int, error) {
+	const batchSize = 500
+
+	expired, err := uc.repo.ListExpiredRoleAssignments(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, assignment := range expired {
+		affected, err := uc.repo.DeleteRoleAssignment(ctx, assignment.ID)
+		if err != nil {
+			if errors.Is(err, ErrRoleAssignmentNotFound) {
+				continue
+			}
+			return removed, err
+		}
+		removed++
+		uc.clearUserCache(ctx, affected...)
+
+		if err := uc.auditRepo.CreateOperationLog(ctx, expiredAssignmentLog(assignment, now)); err != nil {
+			uc.log.Errorf("failed to write audit log for expired role assignment %d: %v", assignment.ID, err)
+		}
+	}
+
+	return removed, nil
+}
+
+// expiredAssignmentLog 构造过期角色分配被清理的操作日志
+func expiredAssignmentLog(assignment *RoleAssignment, now time.Time) *OperationLog {
+	detail, _ := json.Marshal(assignment)
+
+	description := fmt.Sprintf("角色分配已过期并被清理：用户 %d 角色 %s", assignment.UserID, assignment.RoleCode)
+	if assignment.IsDelegated() {
+		description = fmt.Sprintf("角色委托已过期并被清理：用户 %d 角色 %s（委托人 %d）", assignment.UserID, assignment.RoleCode, *assignment.DelegatedBy)
+	}
+
+	return &OperationLog{
+		Username:    "system",
+		Action:      RoleAssignmentActionExpire,
+		Resource:    RoleAssignmentAuditResource,
+		ResourceID:  strconv.FormatInt(assignment.ID, 10),
+		Description: description,
+		RequestData: string(detail),
+		Status:      "success",
+		CreatedAt:   now,
+	}
+}
@@ -0,0 +1,140 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleAssignment_IsEffective(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *time.Time { ts := now.Add(d); return &ts }
+
+	assert.True(t, (&biz.RoleAssignment{IsActive: true}).IsEffective(now))
+	assert.False(t, (&biz.RoleAssignment{IsActive: false}).IsEffective(now))
+	assert.True(t, (&biz.RoleAssignment{IsActive: true, ValidFrom: at(-time.Hour), ValidUntil: at(time.Hour)}).IsEffective(now))
+	assert.False(t, (&biz.RoleAssignment{IsActive: true, ValidFrom: at(time.Hour)}).IsEffective(now))
+	assert.False(t, (&biz.RoleAssignment{IsActive: true, ValidUntil: at(0)}).IsEffective(now))
+
+	assert.Equal(t, biz.ErrInvalidAssignmentPeriod, (&biz.RoleAssignment{ValidFrom: at(2 * time.Hour), ValidUntil: at(time.Hour)}).ValidatePeriod(now))
+	assert.Equal(t, biz.ErrInvalidAssignmentPeriod, (&biz.RoleAssignment{ValidUntil: at(-time.Minute)}).ValidatePeriod(now))
+	assert.NoError(t, (&biz.RoleAssignment{ValidUntil: at(time.Hour)}).ValidatePeriod(now))
+}
+
+type stubRoleAssignmentRepo struct {
+	biz.RoleAssignmentRepo
+	assignments map[int32][]*biz.RoleAssignment
+	created     []*biz.RoleAssignment
+	expired     []*biz.RoleAssignment
+	cascade     map[int64][]int32 // 删除分配时级联撤销委托的被委托人
+}
+
+func (r *stubRoleAssignmentRepo) ListUserRoleAssignments(ctx context.Context, userID int32, includeExpired bool) ([]*biz.RoleAssignment, error) {
+	return r.assignments[userID], nil
+}
+
+func (r *stubRoleAssignmentRepo) CreateRoleAssignment(ctx context.Context, assignment *biz.RoleAssignment) (*biz.RoleAssignment, error) {
+	r.created = append(r.created, assignment)
+	return assignment, nil
+}
+
+func (r *stubRoleAssignmentRepo) GetRoleAssignment(ctx context.Context, id int64) (*biz.RoleAssignment, error) {
+	return &biz.RoleAssignment{ID: id, UserID: 1}, nil
+}
+
+func (r *stubRoleAssignmentRepo) DeleteRoleAssignment(ctx context.Context, id int64) ([]int32, error) {
+	return append([]int32{1}, r.cascade[id]...), nil
+}
+
+func (r *stubRoleAssignmentRepo) ListExpiredRoleAssignments(ctx context.Context, now time.Time, limit int) ([]*biz.RoleAssignment, error) {
+	return r.expired, nil
+}
+
+// stubCacheClearingPermRepo 记录被清除权限缓存的用户
+type stubCacheClearingPermRepo struct {
+	biz.PermissionRepo
+	cleared []int64
+}
+
+func (r *stubCacheClearingPermRepo) ClearUserPermissionCache(ctx context.Context, userIDs ...int64) error {
+	r.cleared = append(r.cleared, userIDs...)
+	return nil
+}
+
+func TestRoleAssignmentUsecase_DelegateRole(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *time.Time { ts := now.Add(d); return &ts }
+	delegatedBy := int64(3)
+
+	// 用户1长期持有角色10，限时持有角色20；用户2通过委托持有角色10
+	repo := &stubRoleAssignmentRepo{assignments: map[int32][]*biz.RoleAssignment{
+		1: {
+			{UserID: 1, RoleID: 10, IsActive: true},
+			{UserID: 1, RoleID: 20, IsActive: true, ValidUntil: at(24 * time.Hour)},
+		},
+		2: {
+			{UserID: 2, RoleID: 10, IsActive: true, ValidUntil: at(24 * time.Hour), DelegatedBy: &delegatedBy},
+		},
+	}}
+	permRepo := &stubCacheClearingPermRepo{}
+	uc := biz.NewRoleAssignmentUsecase(repo, permRepo, nil, log.DefaultLogger)
+	ctx := context.Background()
+
+	created, err := uc.DelegateRole(ctx, 1, &biz.RoleAssignment{UserID: 2, RoleID: 10, ValidUntil: at(7 * 24 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *created.DelegatedBy)
+	assert.True(t, created.IsActive)
+
+	_, err = uc.DelegateRole(ctx, 1, &biz.RoleAssignment{UserID: 2, RoleID: 20, ValidUntil: at(12 * time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = uc.DelegateRole(ctx, 1, &biz.RoleAssignment{UserID: 2, RoleID: 20, ValidUntil: at(48 * time.Hour)})
+	assert.Equal(t, biz.ErrDelegationExceedsGrant, err)
+
+	_, err = uc.DelegateRole(ctx, 1, &biz.RoleAssignment{UserID: 2, RoleID: 30, ValidUntil: at(time.Hour)})
+	assert.Equal(t, biz.ErrDelegatorLacksRole, err)
+
+	// 委托得来的角色不能再次委托
+	_, err = uc.DelegateRole(ctx, 2, &biz.RoleAssignment{UserID: 4, RoleID: 10, ValidUntil: at(time.Hour)})
+	assert.Equal(t, biz.ErrDelegatorLacksRole, err)
+
+	_, err = uc.DelegateRole(ctx, 1, &biz.RoleAssignment{UserID: 1, RoleID: 10, ValidUntil: at(time.Hour)})
+	assert.Equal(t, biz.ErrDelegationToSelf, err)
+
+	_, err = uc.DelegateRole(ctx, 1, &biz.RoleAssignment{UserID: 2, RoleID: 10})
+	assert.Equal(t, biz.ErrDelegationPeriodRequired, err)
+
+	assert.Len(t, repo.created, 2)
+	assert.Equal(t, []int64{2, 2}, permRepo.cleared)
+}
+
+func TestRoleAssignmentUsecase_ClearsUserCache(t *testing.T) {
+	now := time.Now()
+	repo := &stubRoleAssignmentRepo{cascade: map[int64][]int32{5: {2, 3}}}
+	permRepo := &stubCacheClearingPermRepo{}
+	uc := biz.NewRoleAssignmentUsecase(repo, permRepo, &stubAuditRepo{}, log.DefaultLogger)
+	ctx := context.Background()
+
+	_, err := uc.AssignRole(ctx, &biz.RoleAssignment{UserID: 4, RoleID: 10}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4}, permRepo.cleared)
+
+	// 撤销分配时清除被分配人及被级联撤销委托的被委托人
+	permRepo.cleared = nil
+	_, err = uc.RevokeAssignment(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, permRepo.cleared)
+
+	// 清理任务同样清除级联撤销的被委托人
+	permRepo.cleared = nil
+	repo.expired = []*biz.RoleAssignment{{ID: 5, UserID: 1, RoleID: 10}}
+	removed, err := uc.CleanupExpiredAssignments(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []int64{1, 2, 3}, permRepo.cleared)
+}
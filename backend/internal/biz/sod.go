@@ -0,0 +1,677 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 职责分离策略类型
+const (
+	SoDPolicyRolePair   = "role_pair"   // 角色互斥
+	SoDPolicyActionPair = "action_pair" // 同一文档类型上的操作互斥
+)
+
+// 职责分离策略的处理方式
+const (
+	SoDEnforcementBlock = "block" // 拒绝操作
+	SoDEnforcementWarn  = "warn"  // 允许操作但返回警告
+)
+
+// SoDPolicy 职责分离策略
+type SoDPolicy struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	PolicyType  string    `json:"policy_type"`         // role_pair, action_pair
+	RoleAID     *int64    `json:"role_a_id,omitempty"` // 角色互斥：角色A
+	RoleBID     *int64    `json:"role_b_id,omitempty"` // 角色互斥：角色B
+	DocType     string    `json:"doc_type,omitempty"`  // 操作互斥：文档类型
+	ActionA     string    `json:"action_a,omitempty"`  // 操作互斥：操作A
+	ActionB     string    `json:"action_b,omitempty"`  // 操作互斥：操作B
+	Enforcement string    `json:"enforcement"`         // block, warn
+	IsEnabled   bool      `json:"is_enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CreatedBy   *int64    `json:"created_by,omitempty"`
+	UpdatedBy   *int64    `json:"updated_by,omitempty"`
+}
+
+// Validate 验证SoDPolicy数据的完整性和正确性
+func (p *SoDPolicy) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("policy name is required")
+	}
+
+	switch p.Enforcement {
+	case SoDEnforcementBlock, SoDEnforcementWarn:
+	default:
+		return fmt.Errorf("invalid enforcement: %s", p.Enforcement)
+	}
+
+	switch p.PolicyType {
+	case SoDPolicyRolePair:
+		if p.RoleAID == nil || p.RoleBID == nil {
+			return fmt.Errorf("role_pair policy requires role_a_id and role_b_id")
+		}
+		if *p.RoleAID == *p.RoleBID {
+			return fmt.Errorf("role_pair policy requires two different roles")
+		}
+	case SoDPolicyActionPair:
+		if strings.TrimSpace(p.DocType) == "" {
+			return fmt.Errorf("action_pair policy requires doc_type")
+		}
+		rule := &PermissionRule{}
+		if rule.actionFlag(p.ActionA) == nil {
+			return fmt.Errorf("invalid action: %s", p.ActionA)
+		}
+		if rule.actionFlag(p.ActionB) == nil {
+			return fmt.Errorf("invalid action: %s", p.ActionB)
+		}
+		if p.ActionA == p.ActionB {
+			return fmt.Errorf("action_pair policy requires two different actions")
+		}
+	default:
+		return fmt.Errorf("invalid policy type: %s", p.PolicyType)
+	}
+
+	return nil
+}
+
+// SoDConflict 职责分离冲突，UserID为空时表示角色自身的权限规则即构成冲突
+type SoDConflict struct {
+	PolicyID    int64    `json:"policy_id"`
+	PolicyName  string   `json:"policy_name"`
+	PolicyType  string   `json:"policy_type"`
+	Enforcement string   `json:"enforcement"`
+	UserID      *int32   `json:"user_id,omitempty"`
+	Username    string   `json:"username,omitempty"`
+	RoleID      *int64   `json:"role_id,omitempty"`
+	DocType     string   `json:"doc_type,omitempty"`
+	Actions     []string `json:"actions,omitempty"`
+	RoleIDs     []int64  `json:"role_ids"` // 构成冲突的角色
+	Message     string   `json:"message"`
+}
+
+// IsBlocking 冲突是否阻止操作
+func (c *SoDConflict) IsBlocking() bool {
+	return c.Enforcement == SoDEnforcementBlock
+}
+
+// SplitSoDConflicts 将冲突拆分为阻止操作的冲突和仅警告的冲突
+func SplitSoDConflicts(conflicts []*SoDConflict) (blocking, warnings []*SoDConflict) {
+	for _, conflict := range conflicts {
+		if conflict.IsBlocking() {
+			blocking = append(blocking, conflict)
+		} else {
+			warnings = append(warnings, conflict)
+		}
+	}
+	return blocking, warnings
+}
+
+// SoDViolationError 变更引入了阻止操作的职责分离冲突
+type SoDViolationError struct {
+	Conflicts []*SoDConflict
+}
+
+func (e *SoDViolationError) Error() string {
+	return fmt.Sprintf("%v: %d conflicts", ErrSoDViolation, len(e.Conflicts))
+}
+
+func (e *SoDViolationError) Unwrap() error {
+	return ErrSoDViolation
+}
+
+// SoDSubject 职责分离检查对象：用户及其生效或已排期的角色
+type SoDSubject struct {
+	UserID   int32   `json:"user_id"`
+	Username string  `json:"username"`
+	RoleIDs  []int64 `json:"role_ids"`
+}
+
+// 错误定义
+var (
+	ErrSoDPolicyNotFound   = errors.New("sod policy not found")
+	ErrSoDPolicyNameExists = errors.New("sod policy name already exists")
+	ErrSoDViolation        = errors.New("sod policy violated")
+)
+
+// SoDRepo 职责分离仓储接口；策略约束的是在公司间共享的角色，因此策略也不按公司隔离
+type SoDRepo interface {
+	CreatePolicy(ctx context.Context, policy *SoDPolicy) (*SoDPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *SoDPolicy) (*SoDPolicy, error)
+	GetPolicy(ctx context.Context, id int64) (*SoDPolicy, error)
+	ListPolicies(ctx context.Context, enabledOnly bool) ([]*SoDPolicy, error)
+	DeletePolicy(ctx context.Context, id int64) error
+
+	// ListRoleParents 获取全部角色的上级角色映射
+	ListRoleParents(ctx context.Context) (map[int64]int64, error)
+	// ListRulesByDocTypes 获取指定文档类型上的全部权限规则
+	ListRulesByDocTypes(ctx context.Context, docTypes []string) ([]*PermissionRule, error)
+	// ListSubjects 获取持有角色的用户及其生效或已排期的角色；
+	// roleIDs和userIDs均为空时返回全部用户，否则仅返回持有roleIDs中任一角色或在userIDs中的用户
+	ListSubjects(ctx context.Context, roleIDs []int64, userIDs []int32) ([]*SoDSubject, error)
+	// ListTemporaryUserRoleIDs 获取用户当前生效的限时或委托角色
+	ListTemporaryUserRoleIDs(ctx context.Context, userID int32) ([]int64, error)
+	// LockSubjects 锁定变更涉及的角色和用户直至事务结束，涉及相同角色或用户的变更依次复核，
+	// 避免两个并发变更各自通过检查后合起来构成冲突
+	LockSubjects(ctx context.Context, roleIDs []int64, userIDs []int32) error
+}
+
+// SoDUsecase 职责分离用例
+type SoDUsecase struct {
+	repo SoDRepo
+	tx   Transaction
+	log  *log.Helper
+}
+
+// NewSoDUsecase 创建职责分离用例
+func NewSoDUsecase(repo SoDRepo, tx Transaction, logger log.Logger) *SoDUsecase {
+	return &SoDUsecase{
+		repo: repo,
+		tx:   tx,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// CreatePolicy 创建职责分离策略
+func (uc *SoDUsecase) CreatePolicy(ctx context.Context, policy *SoDPolicy) (*SoDPolicy, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return uc.repo.CreatePolicy(ctx, policy)
+}
+
+// UpdatePolicy 更新职责分离策略
+func (uc *SoDUsecase) UpdatePolicy(ctx context.Context, policy *SoDPolicy) (*SoDPolicy, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return uc.repo.UpdatePolicy(ctx, policy)
+}
+
+// GetPolicy 获取职责分离策略
+func (uc *SoDUsecase) GetPolicy(ctx context.Context, id int64) (*SoDPolicy, error) {
+	return uc.repo.GetPolicy(ctx, id)
+}
+
+// ListPolicies 获取职责分离策略列表
+func (uc *SoDUsecase) ListPolicies(ctx context.Context) ([]*SoDPolicy, error) {
+	return uc.repo.ListPolicies(ctx, false)
+}
+
+// DeletePolicy 删除职责分离策略
+func (uc *SoDUsecase) DeletePolicy(ctx context.Context, id int64) error {
+	return uc.repo.DeletePolicy(ctx, id)
+}
+
+// CheckUserRoles 检查将用户的长期角色替换为roleIDs后是否违反职责分离策略
+// 用户当前生效的限时或委托角色不受替换影响，一并参与检查
+func (uc *SoDUsecase) CheckUserRoles(ctx context.Context, userID int32, roleIDs []int32) ([]*SoDConflict, error) {
+	policies, err := uc.repo.ListPolicies(ctx, true)
+	if err != nil || len(policies) == 0 {
+		return nil, err
+	}
+
+	temporary, err := uc.repo.ListTemporaryUserRoleIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	graph, err := uc.loadGraph(ctx, policies)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := append([]int64(nil), temporary...)
+	for _, roleID := range roleIDs {
+		assigned = append(assigned, int64(roleID))
+	}
+
+	conflicts := graph.evaluate(policies, assigned, nil)
+	for _, conflict := range conflicts {
+		conflict.UserID = &userID
+	}
+	return conflicts, nil
+}
+
+// CheckPermissionRule 检查新增权限规则后是否违反职责分离的操作互斥策略
+// 同时检查规则所属角色本身，以及直接或通过继承持有该角色的用户
+func (uc *SoDUsecase) CheckPermissionRule(ctx context.Context, rule *PermissionRule) ([]*SoDConflict, error) {
+	policies, err := uc.repo.ListPolicies(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var relevant []*SoDPolicy
+	for _, policy := range policies {
+		if policy.PolicyType == SoDPolicyActionPair && policy.DocType == rule.DocType {
+			relevant = append(relevant, policy)
+		}
+	}
+	if len(relevant) == 0 {
+		return nil, nil
+	}
+
+	graph, err := uc.loadGraph(ctx, relevant)
+	if err != nil {
+		return nil, err
+	}
+
+	// 角色自身即构成冲突
+	conflicts := graph.evaluate(relevant, []int64{rule.RoleID}, rule)
+	flagged := make(map[int64]bool, len(conflicts))
+	for _, conflict := range conflicts {
+		roleID := rule.RoleID
+		conflict.RoleID = &roleID
+		flagged[conflict.PolicyID] = true
+	}
+
+	// 持有该角色或其下级角色的用户
+	subjects, err := uc.repo.ListSubjects(ctx, graph.descendants(rule.RoleID), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, subject := range subjects {
+		for _, conflict := range graph.evaluate(relevant, subject.RoleIDs, rule) {
+			if flagged[conflict.PolicyID] {
+				continue
+			}
+			userID := subject.UserID
+			conflict.UserID = &userID
+			conflict.Username = subject.Username
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// Enforce 在事务中执行改变用户角色、角色继承或角色权限规则的变更，并在同一事务内复核职责分离策略
+// userIDs为角色被调整的用户；继承关系或权限规则被调整的角色由变更前后的策略相关数据比较得出，
+// 只复核这些角色及其下级角色自身，以及userIDs中和持有这些角色的用户
+// 变更新引入阻止操作的冲突时回滚并返回SoDViolationError，变更前已存在的冲突不阻止操作
+// 返回变更新引入的仅警告的冲突
+func (uc *SoDUsecase) Enforce(ctx context.Context, userIDs []int32, fn func(ctx context.Context) error) ([]*SoDConflict, error) {
+	var warnings []*SoDConflict
+	err := uc.tx.InTx(ctx, func(ctx context.Context) error {
+		policies, err := uc.repo.ListPolicies(ctx, true)
+		if err != nil {
+			return err
+		}
+		if len(policies) == 0 {
+			return fn(ctx)
+		}
+
+		before, err := uc.loadGraph(ctx, policies)
+		if err != nil {
+			return err
+		}
+		// 变更前用户持有的角色，其他用户的角色不受变更影响
+		heldBefore := make(map[int32][]int64, len(userIDs))
+		if len(userIDs) > 0 {
+			subjects, err := uc.repo.ListSubjects(ctx, nil, userIDs)
+			if err != nil {
+				return err
+			}
+			for _, subject := range subjects {
+				heldBefore[subject.UserID] = subject.RoleIDs
+			}
+		}
+
+		if err := fn(ctx); err != nil {
+			return err
+		}
+
+		after, err := uc.loadGraph(ctx, policies)
+		if err != nil {
+			return err
+		}
+		changed := before.changedRoles(after)
+		roleIDs := mergeRoleIDs(before.descendantsOf(changed), after.descendantsOf(changed))
+		if len(roleIDs) == 0 && len(userIDs) == 0 {
+			return nil
+		}
+
+		// 锁定受影响的角色、被调整继承关系的角色的上级角色以及用户所持有的角色，再读取其他事务已提交的变更
+		locked := mergeRoleIDs(roleIDs, after.expand(changed))
+		if len(userIDs) > 0 {
+			subjects, err := uc.repo.ListSubjects(ctx, nil, userIDs)
+			if err != nil {
+				return err
+			}
+			for _, subject := range subjects {
+				locked = mergeRoleIDs(locked, subject.RoleIDs)
+			}
+		}
+		if err := uc.repo.LockSubjects(ctx, locked, userIDs); err != nil {
+			return err
+		}
+		if after, err = uc.loadGraph(ctx, policies); err != nil {
+			return err
+		}
+
+		var introduced []*SoDConflict
+		for _, roleID := range roleIDs {
+			existing := conflictKeys(before.evaluate(policies, []int64{roleID}, nil))
+			for _, conflict := range after.evaluate(policies, []int64{roleID}, nil) {
+				id := roleID
+				conflict.RoleID = &id
+				if !existing[conflict.PolicyID] {
+					introduced = append(introduced, conflict)
+				}
+			}
+		}
+
+		subjects, err := uc.repo.ListSubjects(ctx, roleIDs, userIDs)
+		if err != nil {
+			return err
+		}
+		for _, subject := range subjects {
+			held, ok := heldBefore[subject.UserID]
+			if !ok && !containsUserID(userIDs, subject.UserID) {
+				held = subject.RoleIDs
+			}
+			existing := conflictKeys(before.evaluate(policies, held, nil))
+			for _, conflict := range after.evaluate(policies, subject.RoleIDs, nil) {
+				userID := subject.UserID
+				conflict.UserID = &userID
+				conflict.Username = subject.Username
+				if !existing[conflict.PolicyID] {
+					introduced = append(introduced, conflict)
+				}
+			}
+		}
+
+		blocking, introducedWarnings := SplitSoDConflicts(introduced)
+		if len(blocking) > 0 {
+			return &SoDViolationError{Conflicts: blocking}
+		}
+		warnings = introducedWarnings
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// conflictKeys 返回同一主体已违反的策略
+func conflictKeys(conflicts []*SoDConflict) map[int64]bool {
+	keys := make(map[int64]bool, len(conflicts))
+	for _, conflict := range conflicts {
+		keys[conflict.PolicyID] = true
+	}
+	return keys
+}
+
+// containsUserID 判断用户是否在列表中
+func containsUserID(userIDs []int32, userID int32) bool {
+	for _, id := range userIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ListViolations 列出当前已存在的职责分离冲突，policyID非零时仅检查该策略
+func (uc *SoDUsecase) ListViolations(ctx context.Context, policyID int64) ([]*SoDConflict, error) {
+	policies, err := uc.repo.ListPolicies(ctx, policyID == 0)
+	if err != nil {
+		return nil, err
+	}
+	if policyID != 0 {
+		var selected []*SoDPolicy
+		for _, policy := range policies {
+			if policy.ID == policyID {
+				selected = append(selected, policy)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, ErrSoDPolicyNotFound
+		}
+		policies = selected
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	graph, err := uc.loadGraph(ctx, policies)
+	if err != nil {
+		return nil, err
+	}
+
+	subjects, err := uc.repo.ListSubjects(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []*SoDConflict
+	for _, subject := range subjects {
+		for _, conflict := range graph.evaluate(policies, subject.RoleIDs, nil) {
+			userID := subject.UserID
+			conflict.UserID = &userID
+			conflict.Username = subject.Username
+			violations = append(violations, conflict)
+		}
+	}
+
+	return violations, nil
+}
+
+// loadGraph 加载评估策略所需的角色继承关系和权限规则
+func (uc *SoDUsecase) loadGraph(ctx context.Context, policies []*SoDPolicy) (*sodGraph, error) {
+	parents, err := uc.repo.ListRoleParents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var docTypes []string
+	seen := make(map[string]bool)
+	for _, policy := range policies {
+		if policy.PolicyType == SoDPolicyActionPair && !seen[policy.DocType] {
+			seen[policy.DocType] = true
+			docTypes = append(docTypes, policy.DocType)
+		}
+	}
+
+	var rules []*PermissionRule
+	if len(docTypes) > 0 {
+		if rules, err = uc.repo.ListRulesByDocTypes(ctx, docTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	return newSoDGraph(parents, rules), nil
+}
+
+// sodGraph 职责分离评估所需的角色继承关系和按角色分组的权限规则
+type sodGraph struct {
+	parents     map[int64]int64
+	rulesByRole map[int64][]*PermissionRule
+}
+
+func newSoDGraph(parents map[int64]int64, rules []*PermissionRule) *sodGraph {
+	graph := &sodGraph{
+		parents:     parents,
+		rulesByRole: make(map[int64][]*PermissionRule),
+	}
+	for _, rule := range rules {
+		graph.rulesByRole[rule.RoleID] = append(graph.rulesByRole[rule.RoleID], rule)
+	}
+	return graph
+}
+
+// roles 返回拥有权限规则或上级角色的全部角色
+func (g *sodGraph) roles() []int64 {
+	seen := make(map[int64]bool)
+	var roles []int64
+	for roleID := range g.rulesByRole {
+		seen[roleID] = true
+		roles = append(roles, roleID)
+	}
+	for roleID := range g.parents {
+		if !seen[roleID] {
+			roles = append(roles, roleID)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// expand 返回角色及沿继承链获得的全部上级角色
+func (g *sodGraph) expand(roleIDs []int64) []int64 {
+	seen := make(map[int64]bool)
+	var expanded []int64
+	for _, roleID := range roleIDs {
+		current, ok := roleID, true
+		for depth := 0; ok && depth <= MaxRoleInheritanceDepth && !seen[current]; depth++ {
+			seen[current] = true
+			expanded = append(expanded, current)
+			current, ok = g.parents[current]
+		}
+	}
+	sort.Slice(expanded, func(i, j int) bool { return expanded[i] < expanded[j] })
+	return expanded
+}
+
+// descendants 返回角色自身及直接或间接继承它的全部下级角色
+func (g *sodGraph) descendants(roleID int64) []int64 {
+	result := []int64{roleID}
+	for child := range g.parents {
+		for _, ancestor := range g.expand([]int64{child}) {
+			if ancestor == roleID && child != roleID {
+				result = append(result, child)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// descendantsOf 返回各角色自身及其全部下级角色
+func (g *sodGraph) descendantsOf(roleIDs []int64) []int64 {
+	var result []int64
+	for _, roleID := range roleIDs {
+		result = mergeRoleIDs(result, g.descendants(roleID))
+	}
+	return result
+}
+
+// changedRoles 返回与other相比上级角色或策略相关权限规则不同的角色
+func (g *sodGraph) changedRoles(other *sodGraph) []int64 {
+	var changed []int64
+	for _, roleID := range mergeRoleIDs(g.roles(), other.roles()) {
+		parent, ok := g.parents[roleID]
+		otherParent, otherOK := other.parents[roleID]
+		if ok != otherOK || parent != otherParent ||
+			ruleSignature(g.rulesByRole[roleID]) != ruleSignature(other.rulesByRole[roleID]) {
+			changed = append(changed, roleID)
+		}
+	}
+	return changed
+}
+
+// ruleSignature 角色在策略文档类型上授予的操作，与规则ID和审计字段无关
+func ruleSignature(rules []*PermissionRule) string {
+	granted := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		for _, action := range PermissionRuleActions {
+			if *rule.actionFlag(action) {
+				granted = append(granted, rule.DocType+"/"+action)
+			}
+		}
+	}
+	sort.Strings(granted)
+	return strings.Join(granted, ",")
+}
+
+// evaluate 评估持有assigned角色（含继承）的主体是否违反策略，extra为待新增的权限规则
+func (g *sodGraph) evaluate(policies []*SoDPolicy, assigned []int64, extra *PermissionRule) []*SoDConflict {
+	roles := g.expand(assigned)
+	held := make(map[int64]bool, len(roles))
+	for _, roleID := range roles {
+		held[roleID] = true
+	}
+
+	var conflicts []*SoDConflict
+	for _, policy := range policies {
+		switch policy.PolicyType {
+		case SoDPolicyRolePair:
+			if held[*policy.RoleAID] && held[*policy.RoleBID] {
+				conflicts = append(conflicts, &SoDConflict{
+					PolicyID:    policy.ID,
+					PolicyName:  policy.Name,
+					PolicyType:  policy.PolicyType,
+					Enforcement: policy.Enforcement,
+					RoleIDs:     []int64{*policy.RoleAID, *policy.RoleBID},
+					Message:     fmt.Sprintf("角色 %d 与角色 %d 互斥", *policy.RoleAID, *policy.RoleBID),
+				})
+			}
+		case SoDPolicyActionPair:
+			grantedBy := g.grantingRoles(policy, roles, held, extra)
+			rolesA, rolesB := grantedBy[policy.ActionA], grantedBy[policy.ActionB]
+			if len(rolesA) > 0 && len(rolesB) > 0 {
+				conflicts = append(conflicts, &SoDConflict{
+					PolicyID:    policy.ID,
+					PolicyName:  policy.Name,
+					PolicyType:  policy.PolicyType,
+					Enforcement: policy.Enforcement,
+					DocType:     policy.DocType,
+					Actions:     []string{policy.ActionA, policy.ActionB},
+					RoleIDs:     mergeRoleIDs(rolesA, rolesB),
+					Message:     fmt.Sprintf("在 %s 上同时拥有 %s 与 %s 权限", policy.DocType, policy.ActionA, policy.ActionB),
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// grantingRoles 返回策略文档类型上授予互斥操作的角色
+func (g *sodGraph) grantingRoles(policy *SoDPolicy, roles []int64, held map[int64]bool, extra *PermissionRule) map[string][]int64 {
+	grantedBy := make(map[string][]int64)
+	collect := func(rule *PermissionRule) {
+		if rule.DocType != policy.DocType {
+			return
+		}
+		for _, action := range []string{policy.ActionA, policy.ActionB} {
+			if *rule.actionFlag(action) {
+				grantedBy[action] = append(grantedBy[action], rule.RoleID)
+			}
+		}
+	}
+
+	for _, roleID := range roles {
+		for _, rule := range g.rulesByRole[roleID] {
+			collect(rule)
+		}
+	}
+	if extra != nil && held[extra.RoleID] {
+		collect(extra)
+	}
+	return grantedBy
+}
+
+// mergeRoleIDs 合并去重角色ID并排序
+func mergeRoleIDs(groups ...[]int64) []int64 {
+	seen := make(map[int64]bool)
+	var merged []int64
+	for _, group := range groups {
+		for _, roleID := range group {
+			if !seen[roleID] {
+				seen[roleID] = true
+				merged = append(merged, roleID)
+			}
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+	return merged
+}
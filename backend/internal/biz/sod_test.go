@@ -0,0 +1,235 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoDPolicy_Validate(t *testing.T) {
+	roleA, roleB := int64(1), int64(2)
+
+	assert.NoError(t, (&biz.SoDPolicy{Name: "采购与付款分离", PolicyType: biz.SoDPolicyRolePair, RoleAID: &roleA, RoleBID: &roleB, Enforcement: biz.SoDEnforcementBlock}).Validate())
+	assert.NoError(t, (&biz.SoDPolicy{Name: "制单与提交分离", PolicyType: biz.SoDPolicyActionPair, DocType: "Order", ActionA: "create", ActionB: "submit", Enforcement: biz.SoDEnforcementWarn}).Validate())
+
+	assert.Error(t, (&biz.SoDPolicy{Name: "同一角色", PolicyType: biz.SoDPolicyRolePair, RoleAID: &roleA, RoleBID: &roleA, Enforcement: biz.SoDEnforcementBlock}).Validate())
+	assert.Error(t, (&biz.SoDPolicy{Name: "缺少角色", PolicyType: biz.SoDPolicyRolePair, RoleAID: &roleA, Enforcement: biz.SoDEnforcementBlock}).Validate())
+	assert.Error(t, (&biz.SoDPolicy{Name: "无效操作", PolicyType: biz.SoDPolicyActionPair, DocType: "Order", ActionA: "create", ActionB: "approve", Enforcement: biz.SoDEnforcementBlock}).Validate())
+	assert.Error(t, (&biz.SoDPolicy{Name: "相同操作", PolicyType: biz.SoDPolicyActionPair, DocType: "Order", ActionA: "create", ActionB: "create", Enforcement: biz.SoDEnforcementBlock}).Validate())
+	assert.Error(t, (&biz.SoDPolicy{Name: "无效处理方式", PolicyType: biz.SoDPolicyActionPair, DocType: "Order", ActionA: "create", ActionB: "submit", Enforcement: "ignore"}).Validate())
+	assert.Error(t, (&biz.SoDPolicy{Name: "无效类型", PolicyType: "user_pair", Enforcement: biz.SoDEnforcementBlock}).Validate())
+}
+
+type stubSoDRepo struct {
+	biz.SoDRepo
+	policies  []*biz.SoDPolicy
+	parents   map[int64]int64
+	rules     []*biz.PermissionRule
+	subjects  []*biz.SoDSubject
+	temporary map[int32][]int64
+
+	listed      []int // 每次ListSubjects的筛选条件数，0表示全部用户
+	lockedRoles []int64
+	lockedUsers []int32
+}
+
+func (r *stubSoDRepo) ListPolicies(ctx context.Context, enabledOnly bool) ([]*biz.SoDPolicy, error) {
+	return r.policies, nil
+}
+
+func (r *stubSoDRepo) ListRoleParents(ctx context.Context) (map[int64]int64, error) {
+	parents := make(map[int64]int64, len(r.parents))
+	for roleID, parentID := range r.parents {
+		parents[roleID] = parentID
+	}
+	return parents, nil
+}
+
+func (r *stubSoDRepo) ListRulesByDocTypes(ctx context.Context, docTypes []string) ([]*biz.PermissionRule, error) {
+	return r.rules, nil
+}
+
+func (r *stubSoDRepo) ListSubjects(ctx context.Context, roleIDs []int64, userIDs []int32) ([]*biz.SoDSubject, error) {
+	r.listed = append(r.listed, len(roleIDs)+len(userIDs))
+	wanted := make(map[int64]bool, len(roleIDs))
+	for _, roleID := range roleIDs {
+		wanted[roleID] = true
+	}
+	var subjects []*biz.SoDSubject
+	for _, subject := range r.subjects {
+		match := len(roleIDs) == 0 && len(userIDs) == 0
+		for _, userID := range userIDs {
+			match = match || subject.UserID == userID
+		}
+		for _, held := range subject.RoleIDs {
+			match = match || wanted[held]
+		}
+		if match {
+			copied := *subject
+			copied.RoleIDs = append([]int64(nil), subject.RoleIDs...)
+			subjects = append(subjects, &copied)
+		}
+	}
+	return subjects, nil
+}
+
+func (r *stubSoDRepo) ListTemporaryUserRoleIDs(ctx context.Context, userID int32) ([]int64, error) {
+	return r.temporary[userID], nil
+}
+
+func (r *stubSoDRepo) LockSubjects(ctx context.Context, roleIDs []int64, userIDs []int32) error {
+	r.lockedRoles, r.lockedUsers = roleIDs, userIDs
+	return nil
+}
+
+func TestSoDUsecase_Checks(t *testing.T) {
+	purchaser, payer, clerk, manager := int64(1), int64(2), int64(3), int64(4)
+
+	// 角色4继承角色3；角色3可创建订单，角色2可提交订单
+	repo := &stubSoDRepo{
+		policies: []*biz.SoDPolicy{
+			{ID: 1, Name: "采购与付款分离", PolicyType: biz.SoDPolicyRolePair, RoleAID: &purchaser, RoleBID: &payer, Enforcement: biz.SoDEnforcementBlock, IsEnabled: true},
+			{ID: 2, Name: "制单与提交分离", PolicyType: biz.SoDPolicyActionPair, DocType: "Order", ActionA: "create", ActionB: "submit", Enforcement: biz.SoDEnforcementWarn, IsEnabled: true},
+		},
+		parents: map[int64]int64{manager: clerk},
+		rules: []*biz.PermissionRule{
+			{RoleID: clerk, DocType: "Order", CanRead: true, CanCreate: true},
+			{RoleID: payer, DocType: "Order", CanRead: true, CanSubmit: true},
+		},
+		subjects: []*biz.SoDSubject{
+			{UserID: 10, Username: "alice", RoleIDs: []int64{manager, payer}},
+			{UserID: 11, Username: "bob", RoleIDs: []int64{manager}},
+		},
+		temporary: map[int32][]int64{12: {payer}},
+	}
+	uc := biz.NewSoDUsecase(repo, &stubTransaction{}, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 角色互斥：限时持有的角色同样参与检查
+	conflicts, err := uc.CheckUserRoles(ctx, 12, []int32{int32(purchaser)})
+	assert.NoError(t, err)
+	blocking, warnings := biz.SplitSoDConflicts(conflicts)
+	assert.Len(t, blocking, 1)
+	assert.Empty(t, warnings)
+	assert.Equal(t, int64(1), blocking[0].PolicyID)
+
+	// 操作互斥：通过继承获得的创建权限与提交权限冲突
+	conflicts, err = uc.CheckUserRoles(ctx, 13, []int32{int32(manager), int32(payer)})
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.False(t, conflicts[0].IsBlocking())
+	assert.Equal(t, []int64{payer, clerk}, conflicts[0].RoleIDs)
+
+	conflicts, err = uc.CheckUserRoles(ctx, 13, []int32{int32(manager)})
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	// 新增权限规则使角色自身构成冲突
+	conflicts, err = uc.CheckPermissionRule(ctx, &biz.PermissionRule{RoleID: clerk, DocType: "Order", CanSubmit: true})
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, clerk, *conflicts[0].RoleID)
+	assert.Nil(t, conflicts[0].UserID)
+
+	// 新增权限规则使持有下级角色的用户构成冲突
+	repo.subjects = append(repo.subjects, &biz.SoDSubject{UserID: 14, Username: "carol", RoleIDs: []int64{manager, purchaser}})
+	conflicts, err = uc.CheckPermissionRule(ctx, &biz.PermissionRule{RoleID: purchaser, DocType: "Order", CanSubmit: true})
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, int32(14), *conflicts[0].UserID)
+
+	conflicts, err = uc.CheckPermissionRule(ctx, &biz.PermissionRule{RoleID: purchaser, DocType: "Customer", CanSubmit: true})
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	// 违规报告
+	violations, err := uc.ListViolations(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "alice", violations[0].Username)
+
+	_, err = uc.ListViolations(ctx, 99)
+	assert.Equal(t, biz.ErrSoDPolicyNotFound, err)
+}
+
+func TestSoDUsecase_Enforce(t *testing.T) {
+	purchaser, payer, clerk, manager := int64(1), int64(2), int64(3), int64(4)
+
+	repo := &stubSoDRepo{
+		policies: []*biz.SoDPolicy{
+			{ID: 1, Name: "采购与付款分离", PolicyType: biz.SoDPolicyRolePair, RoleAID: &purchaser, RoleBID: &payer, Enforcement: biz.SoDEnforcementBlock, IsEnabled: true},
+			{ID: 2, Name: "制单与提交分离", PolicyType: biz.SoDPolicyActionPair, DocType: "Order", ActionA: "create", ActionB: "submit", Enforcement: biz.SoDEnforcementWarn, IsEnabled: true},
+		},
+		parents: map[int64]int64{},
+		rules: []*biz.PermissionRule{
+			{RoleID: clerk, DocType: "Order", CanRead: true, CanCreate: true},
+			{RoleID: payer, DocType: "Order", CanRead: true, CanSubmit: true},
+		},
+		subjects: []*biz.SoDSubject{
+			{UserID: 10, Username: "alice", RoleIDs: []int64{purchaser, payer}},
+			{UserID: 11, Username: "bob", RoleIDs: []int64{manager}},
+		},
+	}
+	tx := &stubTransaction{}
+	uc := biz.NewSoDUsecase(repo, tx, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 变更前已存在的冲突不阻止无关的变更，没有角色或用户被调整时无需复核
+	warnings, err := uc.Enforce(ctx, nil, func(ctx context.Context) error {
+		assert.True(t, inStubTx(ctx))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Empty(t, repo.listed)
+	assert.Nil(t, repo.lockedRoles)
+
+	// 限时分配或委托使用户同时持有互斥角色时回滚，只复核该用户
+	_, err = uc.Enforce(ctx, []int32{11}, func(ctx context.Context) error {
+		repo.subjects[1].RoleIDs = []int64{manager, purchaser, payer}
+		return nil
+	})
+	var violation *biz.SoDViolationError
+	assert.ErrorAs(t, err, &violation)
+	assert.ErrorIs(t, err, biz.ErrSoDViolation)
+	assert.Len(t, violation.Conflicts, 1)
+	assert.Equal(t, int32(11), *violation.Conflicts[0].UserID)
+	assert.Equal(t, 1, tx.rollbacks)
+	assert.Equal(t, []int32{11}, repo.lockedUsers)
+	assert.Equal(t, []int64{purchaser, payer, manager}, repo.lockedRoles)
+	repo.subjects[1].RoleIDs = []int64{manager}
+
+	// 调整继承关系使下级角色获得互斥操作，仅警告；只复核该角色和持有它的用户，并锁定新的上级角色
+	warnings, err = uc.Enforce(ctx, nil, func(ctx context.Context) error {
+		repo.parents[manager] = clerk
+		repo.rules = append(repo.rules, &biz.PermissionRule{RoleID: manager, DocType: "Order", CanSubmit: true})
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, warnings, 2) {
+		assert.Equal(t, manager, *warnings[0].RoleID)
+		assert.Equal(t, int32(11), *warnings[1].UserID)
+	}
+	assert.Equal(t, []int64{clerk, manager}, repo.lockedRoles)
+	assert.Empty(t, repo.lockedUsers)
+
+	// 修改权限规则使角色自身构成阻止级冲突
+	repo.policies[1].Enforcement = biz.SoDEnforcementBlock
+	repo.parents, repo.rules = map[int64]int64{}, repo.rules[:2]
+	_, err = uc.Enforce(ctx, nil, func(ctx context.Context) error {
+		repo.rules[0] = &biz.PermissionRule{RoleID: clerk, DocType: "Order", CanCreate: true, CanSubmit: true}
+		return nil
+	})
+	assert.ErrorAs(t, err, &violation)
+	assert.Equal(t, clerk, *violation.Conflicts[0].RoleID)
+	assert.Equal(t, []int64{clerk}, repo.lockedRoles)
+
+	// 复核从不遍历全部用户
+	for _, filters := range repo.listed {
+		assert.NotZero(t, filters)
+	}
+}
@@ -0,0 +1,131 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 系统配置值类型，与system_configs.config_type一致
+const (
+	SystemConfigTypeString = "string"
+	SystemConfigTypeInt    = "int"
+	SystemConfigTypeBool   = "bool"
+	SystemConfigTypeJSON   = "json"
+)
+
+// SystemConfig 系统配置；company_id为空的配置是全局默认值，公司配置覆盖全局默认值
+type SystemConfig struct {
+	Key         string    `json:"key"`
+	Value       string    `json:"value"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	IsPublic    bool      `json:"is_public"`
+	IsEncrypted bool      `json:"is_encrypted"`
+	CompanyID   *int32    `json:"company_id,omitempty"` // 为空时取的是全局默认值
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+var (
+	ErrSystemConfigNotFound = errors.New("system config not found")
+	ErrSystemConfigInvalid  = errors.New("invalid system config")
+)
+
+// SystemConfigRepo 系统配置仓储接口，读取时当前公司的配置优先于全局默认值
+type SystemConfigRepo interface {
+	// ListSystemConfigs 获取当前公司生效的全部配置
+	ListSystemConfigs(ctx context.Context) ([]*SystemConfig, error)
+	// GetSystemConfig 获取当前公司生效的配置，不存在时返回ErrSystemConfigNotFound
+	GetSystemConfig(ctx context.Context, key string) (*SystemConfig, error)
+	// SetCompanyConfig 保存当前公司的配置值，类型和说明沿用全局默认值；没有全局默认值时返回ErrSystemConfigNotFound
+	SetCompanyConfig(ctx context.Context, key, value string, userID int64) (*SystemConfig, error)
+	// DeleteCompanyConfig 删除当前公司的配置，恢复使用全局默认值；公司没有该配置时返回ErrSystemConfigNotFound
+	DeleteCompanyConfig(ctx context.Context, key string) error
+}
+
+// SystemConfigUsecase 系统配置用例
+type SystemConfigUsecase struct {
+	repo SystemConfigRepo
+	log  *log.Helper
+}
+
+// NewSystemConfigUsecase 创建系统配置用例
+func NewSystemConfigUsecase(repo SystemConfigRepo, logger log.Logger) *SystemConfigUsecase {
+	return &SystemConfigUsecase{
+		repo: repo,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// ListConfigs 获取当前公司生效的配置，加密配置不返回值
+func (uc *SystemConfigUsecase) ListConfigs(ctx context.Context) ([]*SystemConfig, error) {
+	configs, err := uc.repo.ListSystemConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		config.mask()
+	}
+	return configs, nil
+}
+
+// GetConfig 获取当前公司生效的配置，加密配置不返回值
+func (uc *SystemConfigUsecase) GetConfig(ctx context.Context, key string) (*SystemConfig, error) {
+	config, err := uc.repo.GetSystemConfig(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	config.mask()
+	return config, nil
+}
+
+// SetCompanyConfig 为当前公司设置配置值，值须符合全局默认值的类型；加密配置不能通过接口修改
+func (uc *SystemConfigUsecase) SetCompanyConfig(ctx context.Context, key, value string, userID int64) (*SystemConfig, error) {
+	current, err := uc.repo.GetSystemConfig(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if current.IsEncrypted {
+		return nil, fmt.Errorf("%w: encrypted config %s cannot be set", ErrSystemConfigInvalid, key)
+	}
+	if err := validateSystemConfigValue(current.Type, value); err != nil {
+		return nil, fmt.Errorf("%w: %s %v", ErrSystemConfigInvalid, key, err)
+	}
+	return uc.repo.SetCompanyConfig(ctx, key, value, userID)
+}
+
+// ResetCompanyConfig 删除当前公司的配置，恢复使用全局默认值
+func (uc *SystemConfigUsecase) ResetCompanyConfig(ctx context.Context, key string) error {
+	return uc.repo.DeleteCompanyConfig(ctx, key)
+}
+
+// mask 清除加密配置的值
+func (c *SystemConfig) mask() {
+	if c.IsEncrypted {
+		c.Value = ""
+	}
+}
+
+// validateSystemConfigValue 校验配置值是否符合配置类型，未知类型按字符串处理
+func validateSystemConfigValue(configType, value string) error {
+	switch configType {
+	case SystemConfigTypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("must be an integer")
+		}
+	case SystemConfigTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.New("must be true or false")
+		}
+	case SystemConfigTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return errors.New("must be valid JSON")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package biz_test
+
+import (
+	"context"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSystemConfigRepo 在内存中保存全局默认值和各公司的配置
+type stubSystemConfigRepo struct {
+	globals   map[string]*biz.SystemConfig
+	companies map[int32]map[string]string
+}
+
+func (r *stubSystemConfigRepo) effective(ctx context.Context, global *biz.SystemConfig) *biz.SystemConfig {
+	config := *global
+	companyID := biz.CompanyFromContext(ctx)
+	if value, ok := r.companies[companyID][global.Key]; ok {
+		config.Value = value
+		config.CompanyID = &companyID
+	}
+	return &config
+}
+
+func (r *stubSystemConfigRepo) ListSystemConfigs(ctx context.Context) ([]*biz.SystemConfig, error) {
+	configs := []*biz.SystemConfig{}
+	for _, global := range r.globals {
+		configs = append(configs, r.effective(ctx, global))
+	}
+	return configs, nil
+}
+
+func (r *stubSystemConfigRepo) GetSystemConfig(ctx context.Context, key string) (*biz.SystemConfig, error) {
+	global, ok := r.globals[key]
+	if !ok {
+		return nil, biz.ErrSystemConfigNotFound
+	}
+	return r.effective(ctx, global), nil
+}
+
+func (r *stubSystemConfigRepo) SetCompanyConfig(ctx context.Context, key, value string, userID int64) (*biz.SystemConfig, error) {
+	if _, ok := r.globals[key]; !ok {
+		return nil, biz.ErrSystemConfigNotFound
+	}
+	if r.companies == nil {
+		r.companies = make(map[int32]map[string]string)
+	}
+	companyID := biz.CompanyFromContext(ctx)
+	if r.companies[companyID] == nil {
+		r.companies[companyID] = make(map[string]string)
+	}
+	r.companies[companyID][key] = value
+	return r.GetSystemConfig(ctx, key)
+}
+
+func (r *stubSystemConfigRepo) DeleteCompanyConfig(ctx context.Context, key string) error {
+	companyID := biz.CompanyFromContext(ctx)
+	if _, ok := r.companies[companyID][key]; !ok {
+		return biz.ErrSystemConfigNotFound
+	}
+	delete(r.companies[companyID], key)
+	return nil
+}
+
+func TestSystemConfigUsecase_CompanyOverride(t *testing.T) {
+	repo := &stubSystemConfigRepo{globals: map[string]*biz.SystemConfig{
+		"password_min_length": {Key: "password_min_length", Value: "8", Type: biz.SystemConfigTypeInt},
+		"smtp_password":       {Key: "smtp_password", Value: "secret", Type: biz.SystemConfigTypeString, IsEncrypted: true},
+	}}
+	uc := biz.NewSystemConfigUsecase(repo, log.DefaultLogger)
+	companyA := biz.WithCompany(context.Background(), 1)
+	companyB := biz.WithCompany(context.Background(), 2)
+
+	// 值须符合配置类型，加密配置不能通过接口修改，也不返回值
+	_, err := uc.SetCompanyConfig(companyA, "password_min_length", "long", 1)
+	assert.ErrorIs(t, err, biz.ErrSystemConfigInvalid)
+	_, err = uc.SetCompanyConfig(companyA, "smtp_password", "changed", 1)
+	assert.ErrorIs(t, err, biz.ErrSystemConfigInvalid)
+	_, err = uc.SetCompanyConfig(companyA, "unknown", "1", 1)
+	assert.ErrorIs(t, err, biz.ErrSystemConfigNotFound)
+	config, err := uc.GetConfig(companyA, "smtp_password")
+	assert.NoError(t, err)
+	assert.Empty(t, config.Value)
+
+	// 公司配置只对本公司生效，其他公司仍使用全局默认值
+	config, err = uc.SetCompanyConfig(companyA, "password_min_length", "12", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "12", config.Value)
+	if assert.NotNil(t, config.CompanyID) {
+		assert.Equal(t, int32(1), *config.CompanyID)
+	}
+	config, err = uc.GetConfig(companyB, "password_min_length")
+	assert.NoError(t, err)
+	assert.Equal(t, "8", config.Value)
+	assert.Nil(t, config.CompanyID)
+
+	// 恢复后使用全局默认值
+	assert.NoError(t, uc.ResetCompanyConfig(companyA, "password_min_length"))
+	assert.ErrorIs(t, uc.ResetCompanyConfig(companyA, "password_min_length"), biz.ErrSystemConfigNotFound)
+	configs, err := uc.ListConfigs(companyA)
+	assert.NoError(t, err)
+	for _, config := range configs {
+		assert.Nil(t, config.CompanyID)
+		if config.Key == "password_min_length" {
+			assert.Equal(t, "8", config.Value)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package biz
+
+import "context"
+
+// Transaction 事务管理，fn中使用传入ctx执行的仓储操作在同一事务中完成；
+// ctx已处于事务中时加入外层事务，由外层统一提交或回滚
+type Transaction interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -43,12 +43,22 @@ type Role struct {
 	IsSystemRole bool      `json:"is_system_role"`
 	IsEnabled    bool      `json:"is_enabled"`
 	SortOrder    int32     `json:"sort_order"`
+	ParentRoleID *int32    `json:"parent_role_id,omitempty"` // 上级角色，继承其全部权限规则
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 
+	// ValidUntil 作为用户角色返回时，该用户持有此角色的截止时间，为空表示长期有效
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+
 	Permissions []*Permission `json:"permissions,omitempty"`
 }
 
+// MaxRoleInheritanceDepth 角色继承链的最大深度
+const MaxRoleInheritanceDepth = 10
+
+// MaxOrganizationDepth 组织树的最大深度
+const MaxOrganizationDepth = 100
+
 // Permission 权限实体
 type Permission struct {
 	ID          int32     `json:"id"`
@@ -75,8 +85,13 @@ type Organization struct {
 	Name        string    `json:"name"`
 	Code        string    `json:"code"`
 	Description string    `json:"description"`
+	OrgType     string    `json:"org_type,omitempty"`  // 组织类型: company, department, team
+	LeaderID    *int32    `json:"leader_id,omitempty"` // 负责人
 	IsEnabled   bool      `json:"is_enabled"`
 	SortOrder   int32     `json:"sort_order"`
+	Level       int32     `json:"level,omitempty"` // 层级，顶级组织为1
+	Path        string    `json:"path,omitempty"`  // 组织路径，如 /1/2/3/
+	Depth       int32     `json:"depth,omitempty"` // 相对查询起点的深度，仅下级组织列表返回
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
@@ -156,9 +171,11 @@ type UserRepo interface {
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	UpdateUser(ctx context.Context, user *User) (*User, error)
 	UpdatePassword(ctx context.Context, id int32, hashedPassword string) error
-	DeleteUser(ctx context.Context, id int32) error
+	DeleteUser(ctx context.Context, id int32, deletedBy int32) error
 	ListUsers(ctx context.Context, page, size int32, search string) ([]*User, int32, error)
 	ListUsersWithFilter(ctx context.Context, options interface{}) ([]*User, int32, error)
+	// StreamUsers 按过滤和排序条件逐个回调用户，用于导出等不分页的场景
+	StreamUsers(ctx context.Context, query *UserListQuery, fn func(*User) error) error
 
 	// 认证相关
 	ValidatePassword(hashedPassword, password string) bool
@@ -181,12 +198,14 @@ type RoleRepo interface {
 	CreateRole(ctx context.Context, role *Role) (*Role, error)
 	GetRole(ctx context.Context, id int32) (*Role, error)
 	UpdateRole(ctx context.Context, role *Role) (*Role, error)
-	DeleteRole(ctx context.Context, id int32) error
+	DeleteRole(ctx context.Context, id int32, deletedBy int32) error
 	ListRoles(ctx context.Context, page, size int32, search string, isEnabled *bool, sortField, sortOrder string) ([]*Role, int32, error)
 
 	GetRolePermissions(ctx context.Context, roleID int32) ([]*Permission, error)
 	AssignPermissions(ctx context.Context, roleID int32, permissionIDs []int32) error
 	GetEnabledRoles(ctx context.Context) ([]*Role, error)
+	// LockRoleHierarchy 在ctx所在事务中锁定角色继承关系直到事务结束，串行化上级角色的检查与变更
+	LockRoleHierarchy(ctx context.Context) error
 }
 
 // OrganizationRepo 组织仓储接口
@@ -194,11 +213,37 @@ type OrganizationRepo interface {
 	CreateOrganization(ctx context.Context, org *Organization) (*Organization, error)
 	GetOrganization(ctx context.Context, id int32) (*Organization, error)
 	UpdateOrganization(ctx context.Context, org *Organization) (*Organization, error)
-	DeleteOrganization(ctx context.Context, id int32) error
+	DeleteOrganization(ctx context.Context, id int32, deletedBy int32) error
 	GetOrganizationTree(ctx context.Context) ([]*Organization, error)
 	GetOrganizationUsers(ctx context.Context, orgID int32) ([]*User, error)
-	AssignUsers(ctx context.Context, orgID int32, userIDs []int32) error
+	// AssignUsers 将组织的有效成员同步为userIDs：新增缺少的成员，移除名单外的成员
+	AssignUsers(ctx context.Context, orgID int32, userIDs []int32, operatorID int32) error
 	GetEnabledOrganizations(ctx context.Context) ([]*Organization, error)
+	// GetOrganizationAncestors 获取组织及其全部上级组织，从组织本身开始直到顶级组织
+	GetOrganizationAncestors(ctx context.Context, id int32) ([]*Organization, error)
+	// SetOrganizationLeader 设置组织负责人，leaderID为nil时清除；负责人必须是组织的有效成员
+	SetOrganizationLeader(ctx context.Context, id int32, leaderID *int32, operatorID int32) error
+	// MoveOrganization 在事务内将组织连同其下级组织移动到新的上级组织下，parentID为nil时移为顶级组织
+	MoveOrganization(ctx context.Context, id int32, parentID *int32) error
+	// GetOrganizationDescendants 获取组织的下级组织及其相对深度，maxDepth限制返回的最大深度
+	GetOrganizationDescendants(ctx context.Context, id int32, maxDepth int32, onlyEnabled bool) ([]*Organization, error)
+	// AddOrganizationMember 添加组织成员，已离开的成员恢复原成员关系
+	AddOrganizationMember(ctx context.Context, member *OrganizationMember, operatorID int32) (*OrganizationMember, error)
+	// UpdateOrganizationMember 调整有效成员的职位、主组织或负责人身份
+	UpdateOrganizationMember(ctx context.Context, orgID, userID int32, update *OrganizationMemberUpdate, operatorID int32) (*OrganizationMember, error)
+	// RemoveOrganizationMember 将成员关系标记为已离开
+	RemoveOrganizationMember(ctx context.Context, orgID, userID int32, leftAt time.Time, reason string, operatorID int32) error
+	ListOrganizationMembers(ctx context.Context, orgID int32, includeInactive bool) ([]*OrganizationMember, error)
+	ListUserMemberships(ctx context.Context, userID int32, includeInactive bool) ([]*OrganizationMember, error)
+	ListMemberHistory(ctx context.Context, filter *MemberHistoryFilter) ([]*OrganizationMemberHistory, error)
+	// GetUserOrganizationScope 根据用户的组织类用户权限计算可访问的组织
+	GetUserOrganizationScope(ctx context.Context, userID int64) (*OrganizationScope, error)
+	// ListActiveMemberships 获取组织中启用用户的有效成员关系
+	ListActiveMemberships(ctx context.Context, orgIDs []int32) ([]*OrganizationMember, error)
+	SearchOrganizations(ctx context.Context, filter *OrganizationSearchFilter) ([]*Organization, error)
+	GetOrganizationsByIDs(ctx context.Context, ids []int32) ([]*Organization, error)
+	// ListOrganizations 获取全部未删除的组织，包含层级和路径
+	ListOrganizations(ctx context.Context) ([]*Organization, error)
 }
 
 // SessionRepo 会话仓储接口
@@ -244,8 +289,8 @@ func (uc *UserUsecase) UpdatePassword(ctx context.Context, id int32, hashedPassw
 	return uc.repo.UpdatePassword(ctx, id, hashedPassword)
 }
 
-func (uc *UserUsecase) DeleteUser(ctx context.Context, id int32) error {
-	return uc.repo.DeleteUser(ctx, id)
+func (uc *UserUsecase) DeleteUser(ctx context.Context, id int32, deletedBy int32) error {
+	return uc.repo.DeleteUser(ctx, id, deletedBy)
 }
 
 func (uc *UserUsecase) ListUsers(ctx context.Context, page, size int32, search string) ([]*User, int32, error) {
@@ -290,32 +335,103 @@ func (uc *UserUsecase) ValidateTwoFactor(ctx context.Context, userID int32, code
 
 // RoleUsecase 角色用例
 type RoleUsecase struct {
-	repo RoleRepo
-	log  *log.Helper
+	repo     RoleRepo
+	permRepo PermissionRepo
+	tx       Transaction
+	log      *log.Helper
 }
 
 // NewRoleUsecase 创建角色用例
-func NewRoleUsecase(repo RoleRepo, logger log.Logger) *RoleUsecase {
+func NewRoleUsecase(repo RoleRepo, permRepo PermissionRepo, tx Transaction, logger log.Logger) *RoleUsecase {
 	return &RoleUsecase{
-		repo: repo,
-		log:  log.NewHelper(logger),
+		repo:     repo,
+		permRepo: permRepo,
+		tx:       tx,
+		log:      log.NewHelper(logger),
 	}
 }
 
-func (uc *RoleUsecase) CreateRole(ctx context.Context, role *Role) (*Role, error) {
-	return uc.repo.CreateRole(ctx, role)
+// SameParentID 比较两个可空的上级ID
+func SameParentID(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func (uc *RoleUsecase) CreateRole(ctx context.Context, role *Role) (result *Role, err error) {
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := uc.repo.LockRoleHierarchy(ctx); err != nil {
+			return err
+		}
+		if err := uc.validateParentRole(ctx, role); err != nil {
+			return err
+		}
+		result, err = uc.repo.CreateRole(ctx, role)
+		return err
+	})
+	return result, err
 }
 
 func (uc *RoleUsecase) GetRole(ctx context.Context, id int32) (*Role, error) {
 	return uc.repo.GetRole(ctx, id)
 }
 
-func (uc *RoleUsecase) UpdateRole(ctx context.Context, role *Role) (*Role, error) {
-	return uc.repo.UpdateRole(ctx, role)
+// UpdateRole 更新角色；上级角色变化时，角色、其全部下级角色及持有这些角色的用户的生效权限随之变化，一并清除权限缓存
+func (uc *RoleUsecase) UpdateRole(ctx context.Context, role *Role) (result *Role, err error) {
+	err = uc.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := uc.repo.LockRoleHierarchy(ctx); err != nil {
+			return err
+		}
+		current, err := uc.repo.GetRole(ctx, role.ID)
+		if err != nil {
+			return err
+		}
+		if err := uc.validateParentRole(ctx, role); err != nil {
+			return err
+		}
+		if result, err = uc.repo.UpdateRole(ctx, role); err != nil {
+			return err
+		}
+
+		if !SameParentID(current.ParentRoleID, role.ParentRoleID) {
+			return uc.permRepo.ClearRolePermissionCache(ctx, int64(role.ID))
+		}
+		return nil
+	})
+	return result, err
+}
+
+// validateParentRole 沿上级角色链向上检查，拒绝形成环或超过最大深度的继承关系；调用方须持有角色继承关系锁
+func (uc *RoleUsecase) validateParentRole(ctx context.Context, role *Role) error {
+	if role.ParentRoleID == nil {
+		return nil
+	}
+
+	parentID := role.ParentRoleID
+	for depth := 1; parentID != nil; depth++ {
+		if role.ID != 0 && *parentID == role.ID {
+			return ErrRoleInheritanceCycle
+		}
+		if depth > MaxRoleInheritanceDepth {
+			return ErrRoleInheritanceTooDeep
+		}
+
+		parent, err := uc.repo.GetRole(ctx, *parentID)
+		if err != nil {
+			if depth == 1 {
+				return ErrParentRoleNotFound
+			}
+			return err
+		}
+		parentID = parent.ParentRoleID
+	}
+
+	return nil
 }
 
-func (uc *RoleUsecase) DeleteRole(ctx context.Context, id int32) error {
-	return uc.repo.DeleteRole(ctx, id)
+func (uc *RoleUsecase) DeleteRole(ctx context.Context, id int32, deletedBy int32) error {
+	return uc.repo.DeleteRole(ctx, id, deletedBy)
 }
 
 func (uc *RoleUsecase) ListRoles(ctx context.Context, page, size int32, search string, isEnabled *bool, sortField, sortOrder string) ([]*Role, int32, error) {
@@ -360,8 +476,8 @@ func (uc *OrganizationUsecase) UpdateOrganization(ctx context.Context, org *Orga
 	return uc.repo.UpdateOrganization(ctx, org)
 }
 
-func (uc *OrganizationUsecase) DeleteOrganization(ctx context.Context, id int32) error {
-	return uc.repo.DeleteOrganization(ctx, id)
+func (uc *OrganizationUsecase) DeleteOrganization(ctx context.Context, id int32, deletedBy int32) error {
+	return uc.repo.DeleteOrganization(ctx, id, deletedBy)
 }
 
 func (uc *OrganizationUsecase) GetOrganizationTree(ctx context.Context) ([]*Organization, error) {
@@ -372,14 +488,50 @@ func (uc *OrganizationUsecase) GetOrganizationUsers(ctx context.Context, orgID i
 	return uc.repo.GetOrganizationUsers(ctx, orgID)
 }
 
-func (uc *OrganizationUsecase) AssignUsers(ctx context.Context, orgID int32, userIDs []int32) error {
-	return uc.repo.AssignUsers(ctx, orgID, userIDs)
+func (uc *OrganizationUsecase) AssignUsers(ctx context.Context, orgID int32, userIDs []int32, operatorID int32) error {
+	return uc.repo.AssignUsers(ctx, orgID, userIDs, operatorID)
 }
 
 func (uc *OrganizationUsecase) GetEnabledOrganizations(ctx context.Context) ([]*Organization, error) {
 	return uc.repo.GetEnabledOrganizations(ctx)
 }
 
+// MoveOrganization 移动组织子树，targetParentID为nil时移为顶级组织
+func (uc *OrganizationUsecase) MoveOrganization(ctx context.Context, id int32, targetParentID *int32) error {
+	if targetParentID != nil && *targetParentID == id {
+		return ErrOrganizationCycle
+	}
+	return uc.repo.MoveOrganization(ctx, id, targetParentID)
+}
+
+// GetOrganizationPath 获取从顶级组织到该组织的路径
+func (uc *OrganizationUsecase) GetOrganizationPath(ctx context.Context, id int32) ([]*Organization, error) {
+	ancestors, err := uc.repo.GetOrganizationAncestors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	path := make([]*Organization, len(ancestors))
+	for i, org := range ancestors {
+		path[len(ancestors)-1-i] = org
+	}
+	return path, nil
+}
+
+// GetChildOrganizations 获取直属子组织，recursive为true时获取全部下级组织
+func (uc *OrganizationUsecase) GetChildOrganizations(ctx context.Context, id int32, recursive, onlyEnabled bool) ([]*Organization, error) {
+	maxDepth := int32(1)
+	if recursive {
+		maxDepth = MaxOrganizationDepth
+	}
+	return uc.repo.GetOrganizationDescendants(ctx, id, maxDepth, onlyEnabled)
+}
+
+// SetLeader 设置组织负责人，审批链按组织层级向上查找负责人
+func (uc *OrganizationUsecase) SetLeader(ctx context.Context, id int32, leaderID *int32, operatorID int32) error {
+	return uc.repo.SetOrganizationLeader(ctx, id, leaderID, operatorID)
+}
+
 // 操作日志列表请求
 type OperationLogListRequest struct {
 	Page      int32     `json:"page"`
@@ -554,6 +706,10 @@ var (
 	ErrRoleNameExists         = &BizError{Code: 400, Message: "Role name already exists"}
 	ErrCannotDeleteSystemRole = &BizError{Code: 400, Message: "Cannot delete system role"}
 	ErrRoleInUse              = &BizError{Code: 400, Message: "Role is in use"}
+	ErrRoleHasChildren        = &BizError{Code: 400, Message: "Role has child roles"}
+	ErrParentRoleNotFound     = &BizError{Code: 400, Message: "Parent role not found"}
+	ErrRoleInheritanceCycle   = &BizError{Code: 400, Message: "Role inheritance would create a cycle"}
+	ErrRoleInheritanceTooDeep = &BizError{Code: 400, Message: "Role inheritance chain is too deep"}
 
 	// 权限相关错误
 	ErrPermissionCodeExists  = &BizError{Code: 400, Message: "Permission code already exists"}
@@ -561,9 +717,23 @@ var (
 	ErrPermissionInUse       = &BizError{Code: 400, Message: "Permission is in use"}
 
 	// 组织相关错误
-	ErrOrganizationCodeExists  = &BizError{Code: 400, Message: "Organization code already exists"}
-	ErrOrganizationHasChildren = &BizError{Code: 400, Message: "Organization has child organizations"}
-	ErrOrganizationHasUsers    = &BizError{Code: 400, Message: "Organization has users"}
+	ErrOrganizationCodeExists    = &BizError{Code: 400, Message: "Organization code already exists"}
+	ErrOrganizationHasChildren   = &BizError{Code: 400, Message: "Organization has child organizations"}
+	ErrOrganizationHasUsers      = &BizError{Code: 400, Message: "Organization has users"}
+	ErrOrganizationNotFound      = &BizError{Code: 404, Message: "Organization not found"}
+	ErrOrganizationLeader        = &BizError{Code: 400, Message: "Organization leader must be an active member of the organization"}
+	ErrParentOrgNotFound         = &BizError{Code: 400, Message: "Parent organization not found"}
+	ErrOrganizationCycle         = &BizError{Code: 400, Message: "Organization cannot be moved under itself or its descendants"}
+	ErrOrganizationTooDeep       = &BizError{Code: 400, Message: "Organization tree is too deep"}
+	ErrOrganizationForbidden     = &BizError{Code: 403, Message: "Organization is outside the user's permitted organizations"}
+	ErrInvalidOrganizationSearch = &BizError{Code: 400, Message: "Search keyword is required"}
+
+	// 组织成员相关错误
+	ErrOrganizationMemberExists    = &BizError{Code: 400, Message: "User is already a member of the organization"}
+	ErrOrganizationMemberNotFound  = &BizError{Code: 404, Message: "Organization member not found"}
+	ErrOrganizationMemberUser      = &BizError{Code: 400, Message: "Organization member must be an active user"}
+	ErrInvalidOrganizationMember   = &BizError{Code: 400, Message: "Invalid organization member"}
+	ErrPrimaryOrganizationRequired = &BizError{Code: 400, Message: "Change the primary organization by setting another organization as primary"}
 )
 
 // BizError 业务错误
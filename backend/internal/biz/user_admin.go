@@ -0,0 +1,301 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// SuperAdminRoleCode 超级管理员角色编码，系统中至少保留一个启用的超级管理员
+const SuperAdminRoleCode = "SUPER_ADMIN"
+
+// MaxUserBatchSize 单次批量操作的最大用户数
+const MaxUserBatchSize = 500
+
+// 用户统计中按天统计登录次数的天数
+const (
+	DefaultUserStatsDays = 30
+	MaxUserStatsDays     = 365
+)
+
+// 批量操作单项失败的原因
+const (
+	UserBatchErrorNotFound       = "USER_NOT_FOUND"
+	UserBatchErrorDuplicate      = "DUPLICATE_USER_ID"
+	UserBatchErrorSelf           = "CANNOT_DELETE_SELF"
+	UserBatchErrorLastSuperAdmin = "LAST_SUPER_ADMIN"
+	UserBatchErrorRolledBack     = "ROLLED_BACK"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserBatchEmpty     = errors.New("user batch is empty")
+	ErrUserBatchTooLarge  = fmt.Errorf("user batch exceeds %d users", MaxUserBatchSize)
+	ErrCannotDisableSelf  = errors.New("cannot disable yourself")
+	ErrLastSuperAdmin     = errors.New("at least one enabled super admin must remain")
+	ErrUserRoleNotFound   = errors.New("user does not hold the role directly")
+	ErrSuperAdminRequired = errors.New("only super admins can change super admins")
+)
+
+// UserBatchItemResult 批量操作中单个用户的结果
+type UserBatchItemResult struct {
+	ID      int32  `json:"id"`
+	Success bool   `json:"success"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// UserBatchResult 批量操作结果，批量操作在一个事务中执行，任一项失败时所有项都不生效
+type UserBatchResult struct {
+	Total     int                    `json:"total"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Items     []*UserBatchItemResult `json:"items"`
+}
+
+// UserRoleStat 按角色统计的用户数
+type UserRoleStat struct {
+	RoleID    int32  `json:"role_id"`
+	RoleCode  string `json:"role_code"`
+	RoleName  string `json:"role_name"`
+	UserCount int32  `json:"user_count"`
+}
+
+// DailyLoginStat 某一天的登录次数和登录用户数
+type DailyLoginStat struct {
+	Date   string `json:"date"`
+	Logins int32  `json:"logins"`
+	Users  int32  `json:"users"`
+}
+
+// UserStats 用户统计
+type UserStats struct {
+	TotalUsers        int32             `json:"total_users"`
+	ActiveUsers       int32             `json:"active_users"`
+	DisabledUsers     int32             `json:"disabled_users"`
+	TwoFactorUsers    int32             `json:"two_factor_users"`
+	TwoFactorAdoption float64           `json:"two_factor_adoption"` // 启用用户中开启2FA的百分比
+	NewUsersToday     int32             `json:"new_users_today"`
+	NewUsersThisMonth int32             `json:"new_users_this_month"`
+	RoleStats         []*UserRoleStat   `json:"role_stats"`
+	LoginsPerDay      []*DailyLoginStat `json:"logins_per_day"`
+}
+
+// UserAdminRepo 用户管理仓储接口，涉及超级管理员的变更在同一把锁下检查并执行
+type UserAdminRepo interface {
+	// DeleteUsers 在一个事务中软删除用户，删除后没有启用的超级管理员时返回ErrLastSuperAdmin
+	DeleteUsers(ctx context.Context, ids []int32, deletedBy int32) error
+	// SetUserEnabled 启用或禁用用户，禁用最后一个启用的超级管理员时返回ErrLastSuperAdmin
+	SetUserEnabled(ctx context.Context, id int32, enabled bool) error
+	// RemoveUserRole 移除用户直接持有的角色并撤销其由此发出的委托
+	RemoveUserRole(ctx context.Context, userID, roleID int32) error
+	// GetUserStats 统计用户数量和since之后每天的登录情况，登录统计只返回有登录的日期
+	GetUserStats(ctx context.Context, since time.Time) (*UserStats, error)
+}
+
+// UserAdminUsecase 用户管理用例
+type UserAdminUsecase struct {
+	repo     UserAdminRepo
+	userRepo UserRepo
+	log      *log.Helper
+}
+
+// NewUserAdminUsecase 创建用户管理用例
+func NewUserAdminUsecase(repo UserAdminRepo, userRepo UserRepo, logger log.Logger) *UserAdminUsecase {
+	return &UserAdminUsecase{repo: repo, userRepo: userRepo, log: log.NewHelper(logger)}
+}
+
+// BatchDeleteUsers 批量删除用户，逐项校验后在一个事务中删除；任一项失败时不删除任何用户，结果中列出每项的原因
+func (uc *UserAdminUsecase) BatchDeleteUsers(ctx context.Context, ids []int32, operatorID int32) (*UserBatchResult, error) {
+	if len(ids) == 0 {
+		return nil, ErrUserBatchEmpty
+	}
+	if len(ids) > MaxUserBatchSize {
+		return nil, ErrUserBatchTooLarge
+	}
+
+	result := &UserBatchResult{Total: len(ids), Items: make([]*UserBatchItemResult, len(ids))}
+	seen := make(map[int32]bool, len(ids))
+	superAdmins := make(map[int32]bool)
+	for i, id := range ids {
+		item := &UserBatchItemResult{ID: id}
+		result.Items[i] = item
+
+		switch {
+		case seen[id]:
+			item.Code, item.Message = UserBatchErrorDuplicate, "user id is listed more than once"
+		case id == operatorID:
+			item.Code, item.Message = UserBatchErrorSelf, "cannot delete yourself"
+		default:
+			if _, err := uc.userRepo.GetUser(ctx, id); err != nil {
+				item.Code, item.Message = UserBatchErrorNotFound, "user not found"
+				break
+			}
+			isSuperAdmin, err := uc.hasRole(ctx, id, SuperAdminRoleCode)
+			if err != nil {
+				return nil, err
+			}
+			superAdmins[id] = isSuperAdmin
+		}
+		seen[id] = true
+	}
+
+	if result.hasFailures() {
+		return result.finish(), nil
+	}
+
+	if err := uc.repo.DeleteUsers(ctx, ids, operatorID); err != nil {
+		if !errors.Is(err, ErrLastSuperAdmin) {
+			return nil, err
+		}
+		uc.log.Warnf("Batch delete of %d users rejected: no enabled super admin would remain", len(ids))
+		for _, item := range result.Items {
+			if superAdmins[item.ID] {
+				item.Code, item.Message = UserBatchErrorLastSuperAdmin, ErrLastSuperAdmin.Error()
+			}
+		}
+		return result.finish(), nil
+	}
+
+	for _, item := range result.Items {
+		item.Success = true
+	}
+	return result.finish(), nil
+}
+
+// DeleteUser 删除单个用户，删除最后一个启用的超级管理员时返回ErrLastSuperAdmin
+func (uc *UserAdminUsecase) DeleteUser(ctx context.Context, id int32, operatorID int32) error {
+	return uc.repo.DeleteUsers(ctx, []int32{id}, operatorID)
+}
+
+// hasFailures 判断是否有校验失败的项
+func (r *UserBatchResult) hasFailures() bool {
+	for _, item := range r.Items {
+		if item.Code != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// finish 批量中有失败项时其余项标记为已回滚，并汇总成功和失败数
+func (r *UserBatchResult) finish() *UserBatchResult {
+	failed := r.hasFailures()
+	r.Succeeded, r.Failed = 0, 0
+	for _, item := range r.Items {
+		if failed && item.Code == "" {
+			item.Success = false
+			item.Code, item.Message = UserBatchErrorRolledBack, "not applied because other items in the batch failed"
+		}
+		if item.Success {
+			r.Succeeded++
+		} else {
+			r.Failed++
+		}
+	}
+	return r
+}
+
+// SetUserStatus 启用或禁用用户；不能禁用自己，只有超级管理员可以变更超级管理员的状态
+func (uc *UserAdminUsecase) SetUserStatus(ctx context.Context, id int32, enabled bool, operatorID int32, operatorIsSuperAdmin bool) error {
+	if !enabled && id == operatorID {
+		return ErrCannotDisableSelf
+	}
+	if _, err := uc.userRepo.GetUser(ctx, id); err != nil {
+		return ErrUserNotFound
+	}
+
+	if !operatorIsSuperAdmin {
+		isSuperAdmin, err := uc.hasRole(ctx, id, SuperAdminRoleCode)
+		if err != nil {
+			return err
+		}
+		if isSuperAdmin {
+			return ErrSuperAdminRequired
+		}
+	}
+
+	return uc.repo.SetUserEnabled(ctx, id, enabled)
+}
+
+// RemoveUserRole 移除用户直接持有的角色，只有超级管理员可以移除超级管理员角色
+func (uc *UserAdminUsecase) RemoveUserRole(ctx context.Context, userID, roleID int32, operatorIsSuperAdmin bool) error {
+	if _, err := uc.userRepo.GetUser(ctx, userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	if !operatorIsSuperAdmin {
+		roles, err := uc.userRepo.GetUserRoles(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if role.ID == roleID && role.Code == SuperAdminRoleCode {
+				return ErrSuperAdminRequired
+			}
+		}
+	}
+
+	return uc.repo.RemoveUserRole(ctx, userID, roleID)
+}
+
+// GetUserStats 获取用户统计，登录统计覆盖最近days天（含今天），没有登录的日期补0
+func (uc *UserAdminUsecase) GetUserStats(ctx context.Context, days int, now time.Time) (*UserStats, error) {
+	if days <= 0 {
+		days = DefaultUserStatsDays
+	}
+	if days > MaxUserStatsDays {
+		days = MaxUserStatsDays
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, 1-days)
+
+	stats, err := uc.repo.GetUserStats(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.ActiveUsers > 0 {
+		stats.TwoFactorAdoption = math.Round(float64(stats.TwoFactorUsers)/float64(stats.ActiveUsers)*10000) / 100
+	}
+	stats.LoginsPerDay = fillDailyLogins(stats.LoginsPerDay, since, days)
+	return stats, nil
+}
+
+// fillDailyLogins 按日期顺序返回从since开始days天的登录统计，缺少的日期补0
+func fillDailyLogins(logins []*DailyLoginStat, since time.Time, days int) []*DailyLoginStat {
+	byDate := make(map[string]*DailyLoginStat, len(logins))
+	for _, login := range logins {
+		byDate[login.Date] = login
+	}
+
+	filled := make([]*DailyLoginStat, days)
+	for i := range filled {
+		date := since.AddDate(0, 0, i).Format("2006-01-02")
+		if login, ok := byDate[date]; ok {
+			filled[i] = login
+		} else {
+			filled[i] = &DailyLoginStat{Date: date}
+		}
+	}
+	return filled
+}
+
+// hasRole 判断用户当前是否持有角色
+func (uc *UserAdminUsecase) hasRole(ctx context.Context, userID int32, code string) (bool, error) {
+	roles, err := uc.userRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if role.Code == code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
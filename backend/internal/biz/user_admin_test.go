@@ -0,0 +1,164 @@
+package biz_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubAdminUserRepo 仅实现按ID获取用户和用户角色
+type stubAdminUserRepo struct {
+	biz.UserRepo
+	users map[int32][]string // 用户ID -> 角色编码
+}
+
+func (r *stubAdminUserRepo) GetUser(ctx context.Context, id int32) (*biz.User, error) {
+	if _, ok := r.users[id]; !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &biz.User{ID: id}, nil
+}
+
+func (r *stubAdminUserRepo) GetUserRoles(ctx context.Context, userID int32) ([]*biz.Role, error) {
+	var roles []*biz.Role
+	for i, code := range r.users[userID] {
+		roles = append(roles, &biz.Role{ID: int32(i + 1), Code: code})
+	}
+	return roles, nil
+}
+
+// stubUserAdminRepo 记录删除和状态变更，仅剩的超级管理员被移走时返回ErrLastSuperAdmin
+type stubUserAdminRepo struct {
+	users       *stubAdminUserRepo
+	deleted     []int32
+	enabled     map[int32]bool
+	removed     [][2]int32
+	stats       *biz.UserStats
+	statsSince  time.Time
+	superAdmins int
+}
+
+func (r *stubUserAdminRepo) DeleteUsers(ctx context.Context, ids []int32, deletedBy int32) error {
+	remaining := r.superAdmins
+	for _, id := range ids {
+		if _, err := r.users.GetUser(ctx, id); err == nil && r.users.users[id][0] == biz.SuperAdminRoleCode {
+			remaining--
+		}
+	}
+	if remaining == 0 {
+		return biz.ErrLastSuperAdmin
+	}
+	r.deleted = append(r.deleted, ids...)
+	return nil
+}
+
+func (r *stubUserAdminRepo) SetUserEnabled(ctx context.Context, id int32, enabled bool) error {
+	if !enabled && r.superAdmins == 1 && r.users.users[id][0] == biz.SuperAdminRoleCode {
+		return biz.ErrLastSuperAdmin
+	}
+	r.enabled[id] = enabled
+	return nil
+}
+
+func (r *stubUserAdminRepo) RemoveUserRole(ctx context.Context, userID, roleID int32) error {
+	r.removed = append(r.removed, [2]int32{userID, roleID})
+	return nil
+}
+
+func (r *stubUserAdminRepo) GetUserStats(ctx context.Context, since time.Time) (*biz.UserStats, error) {
+	r.statsSince = since
+	return r.stats, nil
+}
+
+func TestUserAdminUsecase(t *testing.T) {
+	ctx := context.Background()
+	newUsecase := func() (*biz.UserAdminUsecase, *stubUserAdminRepo) {
+		users := &stubAdminUserRepo{users: map[int32][]string{
+			1: {biz.SuperAdminRoleCode},
+			2: {biz.SuperAdminRoleCode},
+			3: {"USER"},
+			4: {"USER"},
+		}}
+		repo := &stubUserAdminRepo{users: users, enabled: map[int32]bool{}, superAdmins: 2}
+		return biz.NewUserAdminUsecase(repo, users, log.DefaultLogger), repo
+	}
+
+	// 全部有效时在一个批次中删除
+	uc, repo := newUsecase()
+	result, err := uc.BatchDeleteUsers(ctx, []int32{2, 3}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{2, 3}, repo.deleted)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, 0, result.Failed)
+
+	// 任一项失败时不删除任何用户，其余项标记为已回滚
+	uc, repo = newUsecase()
+	result, err = uc.BatchDeleteUsers(ctx, []int32{3, 1, 99, 3, 4}, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, repo.deleted)
+	assert.Equal(t, 5, result.Total)
+	assert.Equal(t, 0, result.Succeeded)
+	assert.Equal(t, 5, result.Failed)
+	codes := make([]string, len(result.Items))
+	for i, item := range result.Items {
+		codes[i] = item.Code
+	}
+	assert.Equal(t, []string{
+		biz.UserBatchErrorRolledBack, biz.UserBatchErrorSelf, biz.UserBatchErrorNotFound,
+		biz.UserBatchErrorDuplicate, biz.UserBatchErrorRolledBack,
+	}, codes)
+
+	// 删除后没有超级管理员时回滚，超级管理员项标记原因
+	uc, repo = newUsecase()
+	repo.superAdmins = 1
+	result, err = uc.BatchDeleteUsers(ctx, []int32{2, 4}, 3)
+	assert.NoError(t, err)
+	assert.Empty(t, repo.deleted)
+	assert.Equal(t, biz.UserBatchErrorLastSuperAdmin, result.Items[0].Code)
+	assert.Equal(t, biz.UserBatchErrorRolledBack, result.Items[1].Code)
+
+	_, err = uc.BatchDeleteUsers(ctx, nil, 1)
+	assert.ErrorIs(t, err, biz.ErrUserBatchEmpty)
+	_, err = uc.BatchDeleteUsers(ctx, make([]int32, biz.MaxUserBatchSize+1), 1)
+	assert.ErrorIs(t, err, biz.ErrUserBatchTooLarge)
+
+	// 状态变更：不能禁用自己，非超级管理员不能变更超级管理员，不能禁用最后一个超级管理员
+	uc, repo = newUsecase()
+	assert.ErrorIs(t, uc.SetUserStatus(ctx, 3, false, 3, false), biz.ErrCannotDisableSelf)
+	assert.ErrorIs(t, uc.SetUserStatus(ctx, 99, false, 1, true), biz.ErrUserNotFound)
+	assert.ErrorIs(t, uc.SetUserStatus(ctx, 2, false, 3, false), biz.ErrSuperAdminRequired)
+	assert.NoError(t, uc.SetUserStatus(ctx, 4, false, 3, false))
+	assert.Equal(t, map[int32]bool{4: false}, repo.enabled)
+	repo.superAdmins = 1
+	assert.ErrorIs(t, uc.SetUserStatus(ctx, 2, false, 1, true), biz.ErrLastSuperAdmin)
+
+	// 移除角色：非超级管理员不能移除超级管理员角色
+	assert.ErrorIs(t, uc.RemoveUserRole(ctx, 2, 1, false), biz.ErrSuperAdminRequired)
+	assert.NoError(t, uc.RemoveUserRole(ctx, 2, 1, true))
+	assert.NoError(t, uc.RemoveUserRole(ctx, 3, 1, false))
+	assert.Equal(t, [][2]int32{{2, 1}, {3, 1}}, repo.removed)
+
+	// 统计：2FA采用率按启用用户计算，登录统计补齐没有登录的日期
+	repo.stats = &biz.UserStats{
+		TotalUsers: 4, ActiveUsers: 3, TwoFactorUsers: 1,
+		LoginsPerDay: []*biz.DailyLoginStat{{Date: "2024-03-09", Logins: 5, Users: 2}},
+	}
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	stats, err := uc.GetUserStats(ctx, 3, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), repo.statsSince)
+	assert.Equal(t, 33.33, stats.TwoFactorAdoption)
+	assert.Equal(t, []*biz.DailyLoginStat{
+		{Date: "2024-03-08"}, {Date: "2024-03-09", Logins: 5, Users: 2}, {Date: "2024-03-10"},
+	}, stats.LoginsPerDay)
+
+	stats, err = uc.GetUserStats(ctx, 0, now)
+	assert.NoError(t, err)
+	assert.Len(t, stats.LoginsPerDay, biz.DefaultUserStatsDays)
+}
@@ -0,0 +1,337 @@
+package biz
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// UserDocType 用户对应的文档类型，导出权限和字段级权限按该文档类型判断
+const UserDocType = "User"
+
+// UserExportColumns 可导出的用户字段，与users表的列和User文档类型的字段权限登记一致
+var UserExportColumns = []string{
+	"id", "username", "email", "first_name", "last_name", "phone", "gender", "birth_date", "avatar_url",
+	"is_enabled", "two_factor_enabled", "last_login_time", "last_login_ip", "login_count", "created_at", "updated_at",
+}
+
+// IsUserListField 判断字段是否可用于用户列表的过滤和排序
+func IsUserListField(field string) bool {
+	for _, column := range UserExportColumns {
+		if column == field {
+			return true
+		}
+	}
+	return false
+}
+
+// UserListQuery 用户列表的过滤和排序条件，格式与保存的UserFilter一致
+type UserListQuery struct {
+	Search           string
+	FilterConditions map[string]interface{}
+	SortConfig       map[string]interface{}
+}
+
+// ReferencedFields 返回过滤和排序条件引用的字段，用于校验调用方能否读取这些字段
+func (q *UserListQuery) ReferencedFields() []string {
+	var fields []string
+	if conditions, ok := q.FilterConditions["conditions"].([]interface{}); ok {
+		for _, condition := range conditions {
+			if condMap, ok := condition.(map[string]interface{}); ok {
+				if field, ok := condMap["field"].(string); ok {
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+	if field, ok := q.SortConfig["field"].(string); ok {
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// UserExportEncoder 逐个写出导出用户，Close写出文件结尾
+type UserExportEncoder interface {
+	Encode(user *User) error
+	Close() error
+}
+
+// ExportUsers 逐个读取符合条件的用户并写入导出，返回导出的用户数
+func (uc *UserUsecase) ExportUsers(ctx context.Context, query *UserListQuery, encoder UserExportEncoder) (int, error) {
+	count := 0
+	err := uc.repo.StreamUsers(ctx, query, func(user *User) error {
+		count++
+		return encoder.Encode(user)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, encoder.Close()
+}
+
+// userExportValue 返回用户字段的值，未设置的时间返回nil
+func userExportValue(user *User, column string) interface{} {
+	optionalTime := func(t time.Time) interface{} {
+		if t.IsZero() {
+			return nil
+		}
+		return t
+	}
+
+	switch column {
+	case "id":
+		return user.ID
+	case "username":
+		return user.Username
+	case "email":
+		return user.Email
+	case "first_name":
+		return user.FirstName
+	case "last_name":
+		return user.LastName
+	case "phone":
+		return user.Phone
+	case "gender":
+		return user.Gender
+	case "birth_date":
+		if user.BirthDate.IsZero() {
+			return nil
+		}
+		return user.BirthDate.Format("2006-01-02")
+	case "avatar_url":
+		return user.AvatarURL
+	case "is_enabled":
+		return user.IsActive
+	case "two_factor_enabled":
+		return user.TwoFactorEnabled
+	case "last_login_time":
+		return optionalTime(user.LastLoginAt)
+	case "last_login_ip":
+		return user.LastLoginIP
+	case "login_count":
+		return user.LoginCount
+	case "created_at":
+		return optionalTime(user.CreatedAt)
+	case "updated_at":
+		return optionalTime(user.UpdatedAt)
+	}
+	return nil
+}
+
+// userExportText 返回用户字段在CSV和XLSX中的文本
+func userExportText(user *User, column string) string {
+	switch value := userExportValue(user, column).(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case int32:
+		return strconv.FormatInt(int64(value), 10)
+	case time.Time:
+		return value.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// userCSVEncoder 以CSV格式导出用户
+type userCSVEncoder struct {
+	writer  *csv.Writer
+	columns []string
+	record  []string
+}
+
+// NewUserCSVEncoder 创建CSV导出，首行为列名
+func NewUserCSVEncoder(w io.Writer, columns []string) (UserExportEncoder, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return &userCSVEncoder{writer: writer, columns: columns, record: make([]string, len(columns))}, nil
+}
+
+// Encode 写出一个用户
+func (e *userCSVEncoder) Encode(user *User) error {
+	for i, column := range e.columns {
+		e.record[i] = userExportText(user, column)
+	}
+	return e.writer.Write(e.record)
+}
+
+// Close 刷新缓冲
+func (e *userCSVEncoder) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// userJSONEncoder 以JSON数组导出用户，每个元素只包含导出的列
+type userJSONEncoder struct {
+	w       *bufio.Writer
+	columns []string
+	count   int
+}
+
+// NewUserJSONEncoder 创建JSON导出
+func NewUserJSONEncoder(w io.Writer, columns []string) (UserExportEncoder, error) {
+	buffered := bufio.NewWriter(w)
+	if _, err := buffered.WriteString("["); err != nil {
+		return nil, err
+	}
+	return &userJSONEncoder{w: buffered, columns: columns}, nil
+}
+
+// Encode 写出一个用户
+func (e *userJSONEncoder) Encode(user *User) error {
+	item := make(map[string]interface{}, len(e.columns))
+	for _, column := range e.columns {
+		item[column] = userExportValue(user, column)
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	if e.count > 0 {
+		if err := e.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	e.count++
+	if _, err := e.w.WriteString("\n"); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+// Close 写出数组结尾并刷新缓冲
+func (e *userJSONEncoder) Close() error {
+	if _, err := e.w.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// xlsx的固定部件，工作表内容在导出时逐行写出
+const (
+	xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookXML = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd   = `</sheetData></worksheet>`
+)
+
+// userXLSXEncoder 以XLSX格式导出用户，单元格使用内联字符串，工作表边写边压缩，不在内存中保留全部行
+type userXLSXEncoder struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	columns []string
+	row     int
+}
+
+// NewUserXLSXEncoder 创建XLSX导出，首行为列名
+func NewUserXLSXEncoder(w io.Writer, columns []string) (UserExportEncoder, error) {
+	archive := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbookXML, "Users")},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		writer, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(writer, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &userXLSXEncoder{archive: archive, sheet: bufio.NewWriter(sheet), columns: columns}
+	if _, err := e.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	if err := e.writeRow(columns, nil); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Encode 写出一个用户，数值列写为数字单元格
+func (e *userXLSXEncoder) Encode(user *User) error {
+	values := make([]string, len(e.columns))
+	numeric := make([]bool, len(e.columns))
+	for i, column := range e.columns {
+		values[i] = userExportText(user, column)
+		_, numeric[i] = userExportValue(user, column).(int32)
+	}
+	return e.writeRow(values, numeric)
+}
+
+// writeRow 写出一行单元格
+func (e *userXLSXEncoder) writeRow(values []string, numeric []bool) error {
+	e.row++
+	fmt.Fprintf(e.sheet, `<row r="%d">`, e.row)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		ref := xlsxColumnName(i) + strconv.Itoa(e.row)
+		if numeric != nil && numeric[i] {
+			fmt.Fprintf(e.sheet, `<c r="%s"><v>%s</v></c>`, ref, value)
+			continue
+		}
+		fmt.Fprintf(e.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if err := xml.EscapeText(e.sheet, []byte(value)); err != nil {
+			return err
+		}
+		e.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := e.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Close 写出工作表结尾并完成压缩包
+func (e *userXLSXEncoder) Close() error {
+	if _, err := e.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.archive.Close()
+}
+
+// xlsxColumnName 将从0开始的列序号转换为列名（如0为A，27为AB）
+func xlsxColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
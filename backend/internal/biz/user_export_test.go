@@ -0,0 +1,80 @@
+package biz_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubStreamUserRepo 仅实现逐行读取用户
+type stubStreamUserRepo struct {
+	biz.UserRepo
+	users []*biz.User
+	query *biz.UserListQuery
+}
+
+func (r *stubStreamUserRepo) StreamUsers(ctx context.Context, query *biz.UserListQuery, fn func(*biz.User) error) error {
+	r.query = query
+	for _, user := range r.users {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestUserExport(t *testing.T) {
+	created := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	repo := &stubStreamUserRepo{users: []*biz.User{
+		{ID: 1, Username: "alice", Email: "alice@example.com", Phone: "13800138000", IsActive: true, CreatedAt: created},
+		{ID: 2, Username: "bob", Email: "bob@example.com", FirstName: "Bob <\"B\">"},
+	}}
+	uc := biz.NewUserUsecase(repo, log.DefaultLogger)
+	query := &biz.UserListQuery{
+		FilterConditions: map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"field": "phone", "operator": "contains", "value": "138"},
+		}},
+		SortConfig: map[string]interface{}{"field": "created_at", "direction": "desc"},
+	}
+	assert.Equal(t, []string{"phone", "created_at"}, query.ReferencedFields())
+	assert.True(t, biz.IsUserListField("last_login_ip"))
+	assert.False(t, biz.IsUserListField("password_hash"))
+
+	var buf bytes.Buffer
+	encoder, err := biz.NewUserCSVEncoder(&buf, []string{"id", "username", "is_enabled", "created_at", "last_login_time"})
+	assert.NoError(t, err)
+	count, err := uc.ExportUsers(context.Background(), query, encoder)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Same(t, query, repo.query)
+	assert.Equal(t, "id,username,is_enabled,created_at,last_login_time\n"+
+		"1,alice,true,2024-03-01T08:00:00Z,\n"+
+		"2,bob,false,,\n", buf.String())
+
+	buf.Reset()
+	encoder, err = biz.NewUserJSONEncoder(&buf, []string{"id", "first_name", "created_at"})
+	assert.NoError(t, err)
+	_, err = uc.ExportUsers(context.Background(), query, encoder)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1,"first_name":"","created_at":"2024-03-01T08:00:00Z"},{"id":2,"first_name":"Bob <\"B\">","created_at":null}]`, buf.String())
+
+	// 导出的xlsx可以被导入解析器读回
+	buf.Reset()
+	encoder, err = biz.NewUserXLSXEncoder(&buf, []string{"username", "email", "phone", "first_name"})
+	assert.NoError(t, err)
+	_, err = uc.ExportUsers(context.Background(), query, encoder)
+	assert.NoError(t, err)
+	rows, err := biz.DecodeUserImportXLSX(buf.Bytes())
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "alice", rows[0].Username)
+		assert.Equal(t, "13800138000", rows[0].Phone)
+		assert.Equal(t, "Bob <\"B\">", rows[1].FirstName)
+	}
+}
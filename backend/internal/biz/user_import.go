@@ -0,0 +1,693 @@
+package biz
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 用户导入文件的列，按表头识别，列顺序不限
+const (
+	importColumnUsername  = "username"
+	importColumnEmail     = "email"
+	importColumnPassword  = "password"
+	importColumnFirstName = "first_name"
+	importColumnLastName  = "last_name"
+	importColumnPhone     = "phone"
+	importColumnGender    = "gender"
+	importColumnRoles     = "roles"
+	importColumnOrgCodes  = "org_codes"
+	importColumnPosition  = "position"
+	importColumnIsActive  = "is_active"
+)
+
+// 用户导入限制
+const (
+	// MaxUserImportRows 单次导入的最大数据行数
+	MaxUserImportRows = 5000
+	// userImportBatchSize 每个事务提交的用户数，某一批失败不影响其他批次
+	userImportBatchSize = 100
+)
+
+// userImportColumns 用户导入文件支持的全部列
+var userImportColumns = []string{
+	importColumnUsername, importColumnEmail, importColumnPassword, importColumnFirstName, importColumnLastName,
+	importColumnPhone, importColumnGender, importColumnRoles, importColumnOrgCodes, importColumnPosition, importColumnIsActive,
+}
+
+// UserImportRow 导入文件中的一行用户数据
+type UserImportRow struct {
+	Row       int      `json:"row"` // 文件中的行号，表头为第1行
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Password  string   `json:"-"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Phone     string   `json:"phone"`
+	Gender    string   `json:"gender"`
+	Roles     []string `json:"roles"`     // 角色编码
+	OrgCodes  []string `json:"org_codes"` // 组织编码，第一个组织为主组织
+	Position  string   `json:"position"`  // 在所属组织中的职位
+	IsActive  bool     `json:"is_active"`
+
+	// PasswordGenerated 文件未提供密码，由系统生成欢迎密码
+	PasswordGenerated bool `json:"-"`
+}
+
+// UserImportRowError 导入行的校验或提交错误，Field为空表示整行错误
+type UserImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportedUser 导入成功的用户，Password仅在系统生成欢迎密码时返回
+type ImportedUser struct {
+	Row      int    `json:"row"`
+	ID       int32  `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// UserImportResult 用户导入报告
+type UserImportResult struct {
+	Total   int                   `json:"total"`
+	Valid   int                   `json:"valid"`   // 通过校验的行数
+	Created int                   `json:"created"` // 实际创建的用户数，DryRun时为0
+	DryRun  bool                  `json:"dry_run"`
+	Errors  []*UserImportRowError `json:"errors,omitempty"`
+	Users   []*ImportedUser       `json:"users,omitempty"`
+}
+
+// UserImportUser 待创建的导入用户，包含解析后的角色和组织
+type UserImportUser struct {
+	Row      int
+	User     *User
+	RoleIDs  []int32
+	OrgIDs   []int32 // 第一个组织为主组织
+	Position string
+}
+
+// 错误定义
+var (
+	ErrUserImportEmpty    = errors.New("user import file is empty")
+	ErrUserImportTooLarge = fmt.Errorf("user import file exceeds %d rows", MaxUserImportRows)
+)
+
+// UserImportRepo 用户导入仓储接口
+type UserImportRepo interface {
+	// FindExistingUsers 返回用户名或邮箱（不区分大小写）已被占用的用户，包括已删除的用户
+	FindExistingUsers(ctx context.Context, usernames, emails []string) ([]*User, error)
+	// CreateImportedUsers 在一个事务中创建一批用户，分配角色并加入当前公司和组织，成功后回填User.ID
+	CreateImportedUsers(ctx context.Context, users []*UserImportUser, operatorID int32) error
+}
+
+// DecodeUserImportCSV 解析CSV格式的用户导入文件
+func DecodeUserImportCSV(r io.Reader) ([]*UserImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return decodeUserImportRecords(records)
+}
+
+// DecodeUserImportXLSX 解析XLSX格式的用户导入文件，读取工作簿的第一个工作表
+func DecodeUserImportXLSX(content []byte) ([]*UserImportRow, error) {
+	records, err := readXLSXFirstSheet(content)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUserImportRecords(records)
+}
+
+// decodeUserImportRecords 按表头将记录转换为导入行，跳过空行
+// 多个角色或组织编码以逗号、分号或竖线分隔；is_active为空时默认启用
+func decodeUserImportRecords(records [][]string) ([]*UserImportRow, error) {
+	if len(records) == 0 {
+		return nil, ErrUserImportEmpty
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "" {
+			continue
+		}
+		if !isUserImportColumn(name) {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{importColumnUsername, importColumnEmail} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column: %s", required)
+		}
+	}
+
+	var rows []*UserImportRow
+	for i, record := range records[1:] {
+		line := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+		if len(rows) == MaxUserImportRows {
+			return nil, ErrUserImportTooLarge
+		}
+
+		cell := func(column string) string {
+			index, ok := columns[column]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		row := &UserImportRow{
+			Row:       line,
+			Username:  cell(importColumnUsername),
+			Email:     cell(importColumnEmail),
+			Password:  cell(importColumnPassword),
+			FirstName: cell(importColumnFirstName),
+			LastName:  cell(importColumnLastName),
+			Phone:     cell(importColumnPhone),
+			Gender:    strings.ToUpper(cell(importColumnGender)),
+			Roles:     splitImportList(cell(importColumnRoles)),
+			OrgCodes:  splitImportList(cell(importColumnOrgCodes)),
+			Position:  cell(importColumnPosition),
+			IsActive:  true,
+		}
+		if value := cell(importColumnIsActive); value != "" {
+			active, err := parseMatrixFlag(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: column %s: %w", line, importColumnIsActive, err)
+			}
+			row.IsActive = active
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrUserImportEmpty
+	}
+	return rows, nil
+}
+
+// isUserImportColumn 判断列名是否为用户导入支持的列
+func isUserImportColumn(name string) bool {
+	for _, column := range userImportColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlankRecord 判断记录的所有单元格是否为空
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// splitImportList 拆分以逗号、分号或竖线分隔的编码列表，去除空白和重复项
+func splitImportList(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	})
+
+	var items []string
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		seen[field] = true
+		items = append(items, field)
+	}
+	return items
+}
+
+// xlsx文件中用到的XML结构，只解析导入需要的部分
+type (
+	xlsxWorkbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	xlsxRelationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	xlsxSharedStrings struct {
+		Items []xlsxText `xml:"si"`
+	}
+	xlsxText struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	}
+	xlsxWorksheet struct {
+		Rows []struct {
+			Index int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+)
+
+// value 返回富文本或纯文本的完整内容
+func (t xlsxText) value() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// readXLSXFirstSheet 读取xlsx工作簿第一个工作表的单元格文本，按行号和列位置还原为记录
+func readXLSXFirstSheet(content []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := xlsxFirstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXLSXPart(file, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetFile, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("invalid xlsx file: missing %s", sheetPath)
+	}
+	var sheet xlsxWorksheet
+	if err := decodeXLSXPart(sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+	for i, row := range sheet.Rows {
+		// 省略的空行以空记录补齐，保持行号与表格一致
+		index := row.Index
+		if index == 0 {
+			index = i + 1
+		}
+		for len(records) < index-1 {
+			records = append(records, nil)
+		}
+
+		var record []string
+		for j, cell := range row.Cells {
+			column := j
+			if cell.Ref != "" {
+				if column, err = xlsxColumnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				n, err := strconv.Atoi(cell.Value)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("invalid xlsx file: bad shared string in cell %s", cell.Ref)
+				}
+				record[column] = shared.Items[n].value()
+			case "inlineStr":
+				record[column] = cell.Inline.value()
+			default:
+				record[column] = cell.Value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// xlsxFirstSheetPath 通过workbook.xml及其关系文件定位第一个工作表
+func xlsxFirstSheetPath(files map[string]*zip.File) (string, error) {
+	workbookFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("invalid xlsx file: missing workbook")
+	}
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(workbookFile, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("invalid xlsx file: no worksheet")
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	var rels xlsxRelationships
+	if err := decodeXLSXPart(relsFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", errors.New("invalid xlsx file: worksheet not found")
+}
+
+// decodeXLSXPart 解析xlsx压缩包中的XML文件
+func decodeXLSXPart(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("invalid xlsx file: %w", err)
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(reader).Decode(v); err != nil {
+		return fmt.Errorf("invalid xlsx file: %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// xlsxColumnIndex 将单元格引用（如AB12）转换为从0开始的列序号
+func xlsxColumnIndex(ref string) (int, error) {
+	column := 0
+	for _, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			column = column*26 + int(r-'A') + 1
+			continue
+		}
+		break
+	}
+	if column == 0 {
+		return 0, fmt.Errorf("invalid xlsx file: bad cell reference %s", ref)
+	}
+	return column - 1, nil
+}
+
+// UserImportUsecase 用户批量导入用例
+type UserImportUsecase struct {
+	repo     UserImportRepo
+	userRepo UserRepo
+	roleRepo RoleRepo
+	orgRepo  OrganizationRepo
+	sodUc    *SoDUsecase
+	log      *log.Helper
+}
+
+// NewUserImportUsecase 创建用户导入用例
+func NewUserImportUsecase(repo UserImportRepo, userRepo UserRepo, roleRepo RoleRepo, orgRepo OrganizationRepo, sodUc *SoDUsecase, logger log.Logger) *UserImportUsecase {
+	return &UserImportUsecase{
+		repo:     repo,
+		userRepo: userRepo,
+		roleRepo: roleRepo,
+		orgRepo:  orgRepo,
+		sodUc:    sodUc,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// Import 校验导入行并分批创建通过校验的用户；fieldErrors为调用方对字段格式和密码策略的校验结果，
+// 与此处的重复、角色、组织和职责分离校验合并到导入报告中。任一行有错误时只跳过该行，DryRun时只返回报告
+func (uc *UserImportUsecase) Import(ctx context.Context, rows []*UserImportRow, fieldErrors []*UserImportRowError, dryRun bool, operatorID int32) (*UserImportResult, error) {
+	if len(rows) == 0 {
+		return nil, ErrUserImportEmpty
+	}
+	if len(rows) > MaxUserImportRows {
+		return nil, ErrUserImportTooLarge
+	}
+
+	roleIDs, err := uc.roleIDsByCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := uc.orgsByCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existingUsernames, existingEmails, err := uc.existingUsers(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UserImportResult{Total: len(rows), DryRun: dryRun}
+	result.Errors = append(result.Errors, fieldErrors...)
+	invalid := make(map[int]bool, len(fieldErrors))
+	for _, rowErr := range fieldErrors {
+		invalid[rowErr.Row] = true
+	}
+	addError := func(row *UserImportRow, field, message string) {
+		result.Errors = append(result.Errors, &UserImportRowError{Row: row.Row, Field: field, Message: message})
+		invalid[row.Row] = true
+	}
+
+	var users []*UserImportUser
+	seenUsernames := make(map[string]int, len(rows))
+	seenEmails := make(map[string]int, len(rows))
+	for _, row := range rows {
+		email := strings.ToLower(row.Email)
+		if first, ok := seenUsernames[row.Username]; ok {
+			addError(row, importColumnUsername, fmt.Sprintf("duplicate username, first used in row %d", first))
+		} else if row.Username != "" {
+			seenUsernames[row.Username] = row.Row
+			if existingUsernames[row.Username] {
+				addError(row, importColumnUsername, "username already exists")
+			}
+		}
+		if first, ok := seenEmails[email]; ok {
+			addError(row, importColumnEmail, fmt.Sprintf("duplicate email, first used in row %d", first))
+		} else if email != "" {
+			seenEmails[email] = row.Row
+			if existingEmails[email] {
+				addError(row, importColumnEmail, "email already exists")
+			}
+		}
+
+		user := &UserImportUser{Row: row.Row, Position: row.Position}
+		for _, code := range row.Roles {
+			roleID, ok := roleIDs[code]
+			if !ok {
+				addError(row, importColumnRoles, fmt.Sprintf("unknown or disabled role: %s", code))
+				continue
+			}
+			user.RoleIDs = append(user.RoleIDs, roleID)
+		}
+		for _, code := range row.OrgCodes {
+			org, ok := orgs[code]
+			if !ok {
+				addError(row, importColumnOrgCodes, fmt.Sprintf("unknown organization: %s", code))
+				continue
+			}
+			if !org.IsEnabled {
+				addError(row, importColumnOrgCodes, fmt.Sprintf("organization is disabled: %s", code))
+				continue
+			}
+			user.OrgIDs = append(user.OrgIDs, org.ID)
+		}
+
+		if len(user.RoleIDs) > 0 {
+			if err := uc.checkSoD(ctx, user.RoleIDs); err != nil {
+				addError(row, importColumnRoles, err.Error())
+			}
+		}
+
+		if invalid[row.Row] {
+			continue
+		}
+		user.User = &User{
+			Username:  row.Username,
+			Email:     row.Email,
+			Password:  row.Password,
+			FirstName: row.FirstName,
+			LastName:  row.LastName,
+			Phone:     row.Phone,
+			Gender:    row.Gender,
+			IsActive:  row.IsActive,
+		}
+		users = append(users, user)
+	}
+	result.Valid = len(users)
+
+	if dryRun || len(users) == 0 {
+		sortUserImportErrors(result.Errors)
+		return result, nil
+	}
+
+	passwords := make(map[int]string, len(users))
+	for _, row := range rows {
+		if row.PasswordGenerated {
+			passwords[row.Row] = row.Password
+		}
+	}
+
+	for start := 0; start < len(users); start += userImportBatchSize {
+		end := start + userImportBatchSize
+		if end > len(users) {
+			end = len(users)
+		}
+		batch := users[start:end]
+
+		if err := uc.createBatch(ctx, batch, operatorID); err != nil {
+			uc.log.Errorf("failed to import users in rows %d-%d: %v", batch[0].Row, batch[len(batch)-1].Row, err)
+			for _, user := range batch {
+				result.Errors = append(result.Errors, &UserImportRowError{Row: user.Row, Message: "batch commit failed: " + err.Error()})
+			}
+			continue
+		}
+
+		for _, user := range batch {
+			result.Users = append(result.Users, &ImportedUser{
+				Row:      user.Row,
+				ID:       user.User.ID,
+				Username: user.User.Username,
+				Password: passwords[user.Row],
+			})
+		}
+		result.Created += len(batch)
+	}
+
+	sortUserImportErrors(result.Errors)
+	uc.log.Infof("Imported %d of %d users (%d rows with errors)", result.Created, result.Total, result.Total-result.Created)
+	return result, nil
+}
+
+// createBatch 加密一批用户的密码并在一个事务中创建
+func (uc *UserImportUsecase) createBatch(ctx context.Context, batch []*UserImportUser, operatorID int32) error {
+	now := time.Now()
+	for _, user := range batch {
+		hashed, err := uc.userRepo.HashPassword(user.User.Password)
+		if err != nil {
+			return err
+		}
+		user.User.Password = hashed
+		user.User.CreatedAt = now
+		user.User.UpdatedAt = now
+	}
+	return uc.repo.CreateImportedUsers(ctx, batch, operatorID)
+}
+
+// checkSoD 检查导入用户的角色组合是否违反阻止型职责分离策略
+func (uc *UserImportUsecase) checkSoD(ctx context.Context, roleIDs []int32) error {
+	conflicts, err := uc.sodUc.CheckUserRoles(ctx, 0, roleIDs)
+	if err != nil {
+		return err
+	}
+	blocking, _ := SplitSoDConflicts(conflicts)
+	if len(blocking) == 0 {
+		return nil
+	}
+	names := make([]string, len(blocking))
+	for i, conflict := range blocking {
+		names[i] = conflict.PolicyName
+	}
+	return fmt.Errorf("roles violate separation of duties policy: %s", strings.Join(names, ", "))
+}
+
+// roleIDsByCode 获取启用角色的编码到ID映射
+func (uc *UserImportUsecase) roleIDsByCode(ctx context.Context) (map[string]int32, error) {
+	roles, err := uc.roleRepo.GetEnabledRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make(map[string]int32, len(roles))
+	for _, role := range roles {
+		roleIDs[role.Code] = role.ID
+	}
+	return roleIDs, nil
+}
+
+// orgsByCode 获取当前公司组织的编码映射
+func (uc *UserImportUsecase) orgsByCode(ctx context.Context) (map[string]*Organization, error) {
+	list, err := uc.orgRepo.ListOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make(map[string]*Organization, len(list))
+	for _, org := range list {
+		orgs[org.Code] = org
+	}
+	return orgs, nil
+}
+
+// existingUsers 一次查询导入行中已被占用的用户名和邮箱，邮箱以小写返回
+func (uc *UserImportUsecase) existingUsers(ctx context.Context, rows []*UserImportRow) (map[string]bool, map[string]bool, error) {
+	usernames := make([]string, 0, len(rows))
+	emails := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.Username != "" {
+			usernames = append(usernames, row.Username)
+		}
+		if row.Email != "" {
+			emails = append(emails, strings.ToLower(row.Email))
+		}
+	}
+
+	users, err := uc.repo.FindExistingUsers(ctx, usernames, emails)
+	if err != nil {
+		return nil, nil, err
+	}
+	existingUsernames := make(map[string]bool, len(users))
+	existingEmails := make(map[string]bool, len(users))
+	for _, user := range users {
+		existingUsernames[user.Username] = true
+		existingEmails[strings.ToLower(user.Email)] = true
+	}
+	return existingUsernames, existingEmails, nil
+}
+
+// sortUserImportErrors 按行号排列导入错误，同一行保持原有顺序
+func sortUserImportErrors(rowErrors []*UserImportRowError) {
+	sort.SliceStable(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Row < rowErrors[j].Row
+	})
+}
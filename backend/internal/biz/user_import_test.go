@@ -0,0 +1,191 @@
+package biz_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeUserImport(t *testing.T) {
+	content := "\ufeffUsername,email,roles,org_codes,is_active\n" +
+		"alice,alice@example.com,\"ADMIN, USER\",HQ;SALES;HQ,\n" +
+		",,,,\n" +
+		"bob,bob@example.com,,,no\n"
+	rows, err := biz.DecodeUserImportCSV(strings.NewReader(content))
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, 2, rows[0].Row)
+		assert.Equal(t, []string{"ADMIN", "USER"}, rows[0].Roles)
+		assert.Equal(t, []string{"HQ", "SALES"}, rows[0].OrgCodes)
+		assert.True(t, rows[0].IsActive)
+		assert.Equal(t, 4, rows[1].Row)
+		assert.False(t, rows[1].IsActive)
+	}
+
+	_, err = biz.DecodeUserImportCSV(strings.NewReader("username,email,nickname\n"))
+	assert.EqualError(t, err, "unknown column: nickname")
+	_, err = biz.DecodeUserImportCSV(strings.NewReader("username\nalice\n"))
+	assert.EqualError(t, err, "missing column: email")
+	_, err = biz.DecodeUserImportCSV(strings.NewReader("username,email\n"))
+	assert.ErrorIs(t, err, biz.ErrUserImportEmpty)
+
+	// 最小的xlsx：共享字符串、内联字符串、数字单元格和被省略的空行
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Users" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="worksheet" Target="worksheets/users.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>username</t></si><si><t>email</t></si><si><t>phone</t></si><si><r><t>car</t></r><r><t>ol</t></r></si></sst>`,
+		"xl/worksheets/users.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c></row>` +
+			`<row r="3"><c r="A3" t="s"><v>3</v></c><c r="B3" t="inlineStr"><is><t>carol@example.com</t></is></c><c r="D3"><v>13800138000</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	for name, part := range parts {
+		writer, err := archive.Create(name)
+		assert.NoError(t, err)
+		writer.Write([]byte(part))
+	}
+	assert.NoError(t, archive.Close())
+
+	rows, err = biz.DecodeUserImportXLSX(buf.Bytes())
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, 3, rows[0].Row)
+		assert.Equal(t, "carol", rows[0].Username)
+		assert.Equal(t, "carol@example.com", rows[0].Email)
+		assert.Equal(t, "13800138000", rows[0].Phone)
+	}
+
+	_, err = biz.DecodeUserImportXLSX([]byte("not a zip"))
+	assert.Error(t, err)
+}
+
+// stubImportUserRepo 仅实现密码加密
+type stubImportUserRepo struct {
+	biz.UserRepo
+}
+
+func (r *stubImportUserRepo) HashPassword(password string) (string, error) {
+	return "hashed:" + password, nil
+}
+
+type stubUserImportRepo struct {
+	existing []*biz.User
+	created  []*biz.UserImportUser
+	batches  int
+	failOn   string // 批次中包含该用户名时提交失败
+}
+
+func (r *stubUserImportRepo) FindExistingUsers(ctx context.Context, usernames, emails []string) ([]*biz.User, error) {
+	return r.existing, nil
+}
+
+func (r *stubUserImportRepo) CreateImportedUsers(ctx context.Context, users []*biz.UserImportUser, operatorID int32) error {
+	r.batches++
+	for _, user := range users {
+		if user.User.Username == r.failOn {
+			return biz.ErrUsernameExists
+		}
+	}
+	for _, user := range users {
+		user.User.ID = int32(len(r.created) + 100)
+		r.created = append(r.created, user)
+	}
+	return nil
+}
+
+func TestUserImportUsecase(t *testing.T) {
+	roleRepo := &stubRoleRepo{roles: map[int32]*biz.Role{
+		1: {ID: 1, Code: "ADMIN", IsEnabled: true},
+		2: {ID: 2, Code: "LEGACY", IsEnabled: false},
+	}}
+	orgRepo := &stubOrganizationRepo{orgs: map[int32]*biz.Organization{
+		10: {ID: 10, Code: "HQ", IsEnabled: true},
+		11: {ID: 11, Code: "OLD", IsEnabled: false},
+	}}
+	newUsecase := func(repo *stubUserImportRepo) *biz.UserImportUsecase {
+		sodUc := biz.NewSoDUsecase(&stubSoDRepo{}, &stubTransaction{}, log.DefaultLogger)
+		return biz.NewUserImportUsecase(repo, &stubImportUserRepo{}, roleRepo, orgRepo, sodUc, log.DefaultLogger)
+	}
+	rows := func() []*biz.UserImportRow {
+		return []*biz.UserImportRow{
+			{Row: 2, Username: "alice", Email: "alice@example.com", Password: "Welcome1!", Roles: []string{"ADMIN"}, OrgCodes: []string{"HQ"}, IsActive: true},
+			{Row: 3, Username: "alice", Email: "ALICE@example.com", Password: "Welcome1!"},
+			{Row: 4, Username: "bob", Email: "bob@example.com", Password: "Generated1!", PasswordGenerated: true, Roles: []string{"LEGACY"}},
+			{Row: 5, Username: "carol", Email: "carol@example.com", Password: "Generated1!", PasswordGenerated: true, OrgCodes: []string{"OLD", "NOPE"}},
+			{Row: 6, Username: "dave", Email: "taken@example.com", Password: "Welcome1!"},
+			{Row: 7, Username: "erin", Email: "erin@example.com", Password: "Generated1!", PasswordGenerated: true},
+			{Row: 8, Username: "frank", Email: "frank@example.com", Password: "weak"},
+		}
+	}
+	fieldErrors := []*biz.UserImportRowError{{Row: 8, Field: "password", Message: "password too weak"}}
+	existing := []*biz.User{{ID: 1, Username: "someone", Email: "Taken@Example.com"}}
+
+	// 预览：报告全部错误但不提交
+	repo := &stubUserImportRepo{existing: existing}
+	result, err := newUsecase(repo).Import(context.Background(), rows(), fieldErrors, true, 1)
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 7, result.Total)
+	assert.Equal(t, 2, result.Valid)
+	assert.Equal(t, 0, result.Created)
+	assert.Zero(t, repo.batches)
+
+	messages := make(map[int][]string)
+	for _, rowErr := range result.Errors {
+		messages[rowErr.Row] = append(messages[rowErr.Row], rowErr.Field+": "+rowErr.Message)
+	}
+	assert.Equal(t, []string{
+		"username: duplicate username, first used in row 2",
+		"email: duplicate email, first used in row 2",
+	}, messages[3])
+	assert.Equal(t, []string{"roles: unknown or disabled role: LEGACY"}, messages[4])
+	assert.Equal(t, []string{"org_codes: organization is disabled: OLD", "org_codes: unknown organization: NOPE"}, messages[5])
+	assert.Equal(t, []string{"email: email already exists"}, messages[6])
+	assert.Equal(t, []string{"password: password too weak"}, messages[8])
+	for i := 1; i < len(result.Errors); i++ {
+		assert.LessOrEqual(t, result.Errors[i-1].Row, result.Errors[i].Row)
+	}
+
+	// 提交：只创建有效行，只返回生成的欢迎密码
+	repo = &stubUserImportRepo{existing: existing}
+	result, err = newUsecase(repo).Import(context.Background(), rows(), fieldErrors, false, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+	if assert.Len(t, repo.created, 2) {
+		alice := repo.created[0]
+		assert.Equal(t, "hashed:Welcome1!", alice.User.Password)
+		assert.Equal(t, []int32{1}, alice.RoleIDs)
+		assert.Equal(t, []int32{10}, alice.OrgIDs)
+	}
+	if assert.Len(t, result.Users, 2) {
+		assert.Equal(t, "alice", result.Users[0].Username)
+		assert.Empty(t, result.Users[0].Password)
+		assert.Equal(t, "erin", result.Users[1].Username)
+		assert.Equal(t, "Generated1!", result.Users[1].Password)
+	}
+
+	// 批次提交失败时该批的行计入错误
+	repo = &stubUserImportRepo{failOn: "alice"}
+	result, err = newUsecase(repo).Import(context.Background(), rows()[:1], nil, false, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, 2, result.Errors[0].Row)
+		assert.Contains(t, result.Errors[0].Message, "batch commit failed")
+	}
+
+	_, err = newUsecase(&stubUserImportRepo{}).Import(context.Background(), nil, nil, true, 1)
+	assert.ErrorIs(t, err, biz.ErrUserImportEmpty)
+}
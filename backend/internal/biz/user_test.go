@@ -0,0 +1,139 @@
+package biz_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubRoleRepo 仅实现角色查询，用于验证角色继承链和工作流角色检查
+type stubRoleRepo struct {
+	biz.RoleRepo
+	roles map[int32]*biz.Role
+	locks int
+}
+
+func (r *stubRoleRepo) LockRoleHierarchy(ctx context.Context) error {
+	r.locks++
+	return nil
+}
+
+func (r *stubRoleRepo) GetRole(ctx context.Context, id int32) (*biz.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, fmt.Errorf("role not found")
+	}
+	return role, nil
+}
+
+func (r *stubRoleRepo) GetEnabledRoles(ctx context.Context) ([]*biz.Role, error) {
+	var roles []*biz.Role
+	for _, role := range r.roles {
+		if role.IsEnabled {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (r *stubRoleRepo) UpdateRole(ctx context.Context, role *biz.Role) (*biz.Role, error) {
+	stored := *role
+	r.roles[role.ID] = &stored
+	return role, nil
+}
+
+// stubRoleCachePermRepo 记录被清除权限缓存的角色
+type stubRoleCachePermRepo struct {
+	biz.PermissionRepo
+	clearedRoles []int64
+}
+
+func (r *stubRoleCachePermRepo) ClearRolePermissionCache(ctx context.Context, roleID int64) error {
+	r.clearedRoles = append(r.clearedRoles, roleID)
+	return nil
+}
+
+func TestRoleUsecase_ParentRoleValidation(t *testing.T) {
+	parentOf := func(id int32) *int32 { return &id }
+
+	// 1 <- 2 <- 3
+	repo := &stubRoleRepo{roles: map[int32]*biz.Role{
+		1: {ID: 1, Code: "SALES_USER"},
+		2: {ID: 2, Code: "SALES_MANAGER", ParentRoleID: parentOf(1)},
+		3: {ID: 3, Code: "SALES_DIRECTOR", ParentRoleID: parentOf(2)},
+	}}
+	permRepo := &stubRoleCachePermRepo{}
+	uc := biz.NewRoleUsecase(repo, permRepo, &stubTransaction{}, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 上级角色变化时在继承关系锁内检查，并清除角色及其下级和持有用户的缓存
+	_, err := uc.UpdateRole(ctx, &biz.Role{ID: 3, ParentRoleID: parentOf(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.locks)
+	assert.Equal(t, []int64{3}, permRepo.clearedRoles)
+
+	// 上级角色不变时不清除缓存
+	_, err = uc.UpdateRole(ctx, &biz.Role{ID: 3, Name: "销售总监", ParentRoleID: parentOf(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3}, permRepo.clearedRoles)
+
+	_, err = uc.UpdateRole(ctx, &biz.Role{ID: 1, ParentRoleID: parentOf(3)})
+	assert.Equal(t, biz.ErrRoleInheritanceCycle, err)
+
+	_, err = uc.UpdateRole(ctx, &biz.Role{ID: 2, ParentRoleID: parentOf(2)})
+	assert.Equal(t, biz.ErrRoleInheritanceCycle, err)
+
+	_, err = uc.UpdateRole(ctx, &biz.Role{ID: 2, ParentRoleID: parentOf(99)})
+	assert.Equal(t, biz.ErrParentRoleNotFound, err)
+
+	_, err = uc.UpdateRole(ctx, &biz.Role{ID: 2})
+	assert.NoError(t, err)
+
+	// 继承链超过最大深度
+	deep := &stubRoleRepo{roles: map[int32]*biz.Role{1: {ID: 1}, 100: {ID: 100}}}
+	for id := int32(2); id <= biz.MaxRoleInheritanceDepth+1; id++ {
+		deep.roles[id] = &biz.Role{ID: id, ParentRoleID: parentOf(id - 1)}
+	}
+	_, err = biz.NewRoleUsecase(deep, permRepo, &stubTransaction{}, log.DefaultLogger).
+		UpdateRole(ctx, &biz.Role{ID: 100, ParentRoleID: parentOf(biz.MaxRoleInheritanceDepth + 1)})
+	assert.Equal(t, biz.ErrRoleInheritanceTooDeep, err)
+}
+
+func TestOrganizationUsecase_Tree(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+	repo := &stubOrganizationRepo{orgs: map[int32]*biz.Organization{
+		1: {ID: 1, Name: "集团"},
+		2: {ID: 2, Name: "销售部", ParentID: int32Ptr(1)},
+		3: {ID: 3, Name: "销售一组", ParentID: int32Ptr(2)},
+	}}
+	uc := biz.NewOrganizationUsecase(repo, log.DefaultLogger)
+	ctx := context.Background()
+
+	path, err := uc.GetOrganizationPath(ctx, 3)
+	assert.NoError(t, err)
+	if assert.Len(t, path, 3) {
+		assert.Equal(t, []int32{1, 2, 3}, []int32{path[0].ID, path[1].ID, path[2].ID})
+	}
+
+	_, err = uc.GetOrganizationPath(ctx, 99)
+	assert.Equal(t, biz.ErrOrganizationNotFound, err)
+
+	// 移到自身下直接拒绝，不进入仓储
+	assert.Equal(t, biz.ErrOrganizationCycle, uc.MoveOrganization(ctx, 2, int32Ptr(2)))
+	assert.Equal(t, 0, repo.moves)
+
+	assert.NoError(t, uc.MoveOrganization(ctx, 3, nil))
+	assert.Equal(t, 1, repo.moves)
+	assert.Nil(t, repo.orgs[3].ParentID)
+
+	_, err = uc.GetChildOrganizations(ctx, 1, false, false)
+	assert.NoError(t, err)
+	_, err = uc.GetChildOrganizations(ctx, 1, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{1, biz.MaxOrganizationDepth}, repo.maxDepths)
+}
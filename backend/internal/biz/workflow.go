@@ -0,0 +1,460 @@
+package biz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 工作流定义的长度限制
+const (
+	maxWorkflowStateLength  = 50 // 与document_workflow_states.workflow_state一致
+	maxWorkflowActionLength = 100
+)
+
+// WorkflowActionTransition 工作流转换操作日志的操作类型，资源为文档类型
+const WorkflowActionTransition = "workflow_transition"
+
+// 错误定义
+var (
+	ErrWorkflowNotFound             = errors.New("workflow not found")
+	ErrInvalidWorkflow              = errors.New("invalid workflow")
+	ErrWorkflowTransitionNotAllowed = errors.New("workflow transition not allowed")
+	ErrDocumentUnderWorkflow        = errors.New("document status is controlled by workflow")
+)
+
+// Workflow 文档类型的工作流定义，第一个状态为新建文档的初始状态
+type Workflow struct {
+	ID          int64                 `json:"id"`
+	DocType     string                `json:"doc_type"`              // 文档类型
+	Name        string                `json:"name"`                  // 工作流名称
+	Description string                `json:"description,omitempty"` // 描述
+	IsActive    bool                  `json:"is_active"`             // 是否启用
+	States      []*WorkflowState      `json:"states"`                // 状态定义
+	Transitions []*WorkflowTransition `json:"transitions"`           // 转换定义
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	CreatedBy   *int64                `json:"created_by,omitempty"`
+	UpdatedBy   *int64                `json:"updated_by,omitempty"`
+}
+
+// WorkflowState 工作流状态及其对应的文档状态
+type WorkflowState struct {
+	State     string `json:"state"`
+	DocStatus int    `json:"docstatus"` // 0=草稿, 1=已提交, 2=已取消
+}
+
+// WorkflowTransition 工作流转换：持有AllowedRole的用户可在State状态下执行Action进入NextState，
+// Condition不为空时还需对文档成立
+type WorkflowTransition struct {
+	State       string `json:"state"`
+	Action      string `json:"action"`
+	NextState   string `json:"next_state"`
+	AllowedRole string `json:"allowed_role"`
+	Condition   string `json:"condition,omitempty"`
+}
+
+// Validate 验证工作流定义本身，文档类型、角色和条件字段的检查在用例中进行
+func (w *Workflow) Validate() error {
+	w.DocType = strings.TrimSpace(w.DocType)
+	w.Name = strings.TrimSpace(w.Name)
+	if w.DocType == "" {
+		return fmt.Errorf("%w: doc type is required", ErrInvalidWorkflow)
+	}
+	if w.Name == "" || len(w.Name) > 100 {
+		return fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidWorkflow)
+	}
+
+	if len(w.States) == 0 {
+		return fmt.Errorf("%w: at least one state is required", ErrInvalidWorkflow)
+	}
+	seen := make(map[string]bool, len(w.States))
+	for _, state := range w.States {
+		state.State = strings.TrimSpace(state.State)
+		if state.State == "" || len(state.State) > maxWorkflowStateLength {
+			return fmt.Errorf("%w: state name must be 1-%d characters", ErrInvalidWorkflow, maxWorkflowStateLength)
+		}
+		if seen[state.State] {
+			return fmt.Errorf("%w: duplicate state %s", ErrInvalidWorkflow, state.State)
+		}
+		seen[state.State] = true
+		if state.DocStatus < DocStatusDraft || state.DocStatus > DocStatusCancelled {
+			return fmt.Errorf("%w: docstatus of state %s must be 0, 1 or 2", ErrInvalidWorkflow, state.State)
+		}
+	}
+	if w.States[0].DocStatus != DocStatusDraft {
+		return fmt.Errorf("%w: initial state %s must have docstatus 0", ErrInvalidWorkflow, w.States[0].State)
+	}
+
+	transitions := make(map[string]bool, len(w.Transitions))
+	for _, t := range w.Transitions {
+		t.State = strings.TrimSpace(t.State)
+		t.Action = strings.TrimSpace(t.Action)
+		t.NextState = strings.TrimSpace(t.NextState)
+		t.AllowedRole = strings.TrimSpace(t.AllowedRole)
+		t.Condition = strings.TrimSpace(t.Condition)
+
+		from, to := w.State(t.State), w.State(t.NextState)
+		if from == nil {
+			return fmt.Errorf("%w: transition from unknown state %s", ErrInvalidWorkflow, t.State)
+		}
+		if to == nil {
+			return fmt.Errorf("%w: transition to unknown state %s", ErrInvalidWorkflow, t.NextState)
+		}
+		if t.Action == "" || len(t.Action) > maxWorkflowActionLength {
+			return fmt.Errorf("%w: action must be 1-%d characters", ErrInvalidWorkflow, maxWorkflowActionLength)
+		}
+		if t.AllowedRole == "" {
+			return fmt.Errorf("%w: transition %s from %s requires an allowed role", ErrInvalidWorkflow, t.Action, t.State)
+		}
+		if !legalDocStatusChange(from.DocStatus, to.DocStatus) {
+			return fmt.Errorf("%w: transition %s cannot change docstatus from %d to %d",
+				ErrInvalidWorkflow, t.Action, from.DocStatus, to.DocStatus)
+		}
+		if t.Condition != "" {
+			if _, err := ParsePermissionCondition(t.Condition); err != nil {
+				return fmt.Errorf("%w: transition %s from %s: %v", ErrInvalidWorkflow, t.Action, t.State, err)
+			}
+		}
+
+		key := t.State + "\x00" + t.Action + "\x00" + t.AllowedRole
+		if transitions[key] {
+			return fmt.Errorf("%w: duplicate transition %s from %s for role %s", ErrInvalidWorkflow, t.Action, t.State, t.AllowedRole)
+		}
+		transitions[key] = true
+	}
+	return nil
+}
+
+// State 按名称查找状态
+func (w *Workflow) State(name string) *WorkflowState {
+	for _, state := range w.States {
+		if state.State == name {
+			return state
+		}
+	}
+	return nil
+}
+
+// currentState 文档所处的工作流状态；状态不在定义中时（如启用工作流前创建的文档）
+// 取第一个docstatus相同的状态
+func (w *Workflow) currentState(doc map[string]interface{}) *WorkflowState {
+	name, _ := doc["workflow_state"].(string)
+	if state := w.State(name); state != nil {
+		return state
+	}
+	status := docStatusOf(doc)
+	for _, state := range w.States {
+		if state.DocStatus == status {
+			return state
+		}
+	}
+	return nil
+}
+
+// legalDocStatusChange 工作流转换允许的文档状态变化：保持不变、草稿到已提交、已提交到已取消
+func legalDocStatusChange(from, to int) bool {
+	return from == to ||
+		(from == DocStatusDraft && to == DocStatusSubmitted) ||
+		(from == DocStatusSubmitted && to == DocStatusCancelled)
+}
+
+// WorkflowRepo 工作流仓储接口；工作流与文档类型一样描述文档结构，在公司间共享
+type WorkflowRepo interface {
+	// SaveWorkflow 按文档类型创建或替换工作流，并同步doc_types.has_workflow
+	SaveWorkflow(ctx context.Context, workflow *Workflow) (*Workflow, error)
+	GetWorkflow(ctx context.Context, docType string) (*Workflow, error)
+	ListWorkflows(ctx context.Context) ([]*Workflow, error)
+	DeleteWorkflow(ctx context.Context, docType string) error
+}
+
+// DocumentTransitions 文档当前的工作流状态和当前用户可执行的转换
+type DocumentTransitions struct {
+	DocType     string                `json:"doc_type"`
+	Name        string                `json:"name"`
+	State       string                `json:"state"`
+	DocStatus   int                   `json:"docstatus"`
+	Transitions []*WorkflowTransition `json:"transitions"`
+}
+
+// WorkflowUsecase 工作流用例，负责工作流定义的维护和文档状态转换
+type WorkflowUsecase struct {
+	repo       WorkflowRepo
+	documentUc *DocumentUsecase
+	permRepo   PermissionRepo
+	roleRepo   RoleRepo
+	auditRepo  AuditRepo
+	log        *log.Helper
+}
+
+// NewWorkflowUsecase 创建工作流用例
+func NewWorkflowUsecase(repo WorkflowRepo, documentUc *DocumentUsecase, permRepo PermissionRepo, roleRepo RoleRepo, auditRepo AuditRepo, logger log.Logger) *WorkflowUsecase {
+	return &WorkflowUsecase{
+		repo:       repo,
+		documentUc: documentUc,
+		permRepo:   permRepo,
+		roleRepo:   roleRepo,
+		auditRepo:  auditRepo,
+		log:        log.NewHelper(logger),
+	}
+}
+
+// SaveWorkflow 创建或替换文档类型的工作流：角色需为已启用角色，条件按文档字段类型做类型检查，
+// 含已提交或已取消状态时文档类型需支持提交
+func (uc *WorkflowUsecase) SaveWorkflow(ctx context.Context, workflow *Workflow) (*Workflow, error) {
+	if err := workflow.Validate(); err != nil {
+		return nil, err
+	}
+
+	docType, err := uc.permRepo.GetDocType(ctx, workflow.DocType)
+	if err != nil {
+		return nil, err
+	}
+	if docType.IsChildTable {
+		return nil, fmt.Errorf("%w: child table %s cannot have a workflow", ErrInvalidWorkflow, docType.Name)
+	}
+	for _, state := range workflow.States {
+		if state.DocStatus != DocStatusDraft && !docType.IsSubmittable {
+			return nil, fmt.Errorf("%w: state %s requires %s to be submittable", ErrInvalidWorkflow, state.State, docType.Name)
+		}
+	}
+	if workflow.IsActive {
+		chain, err := uc.documentUc.activeApprovalChain(ctx, workflow.DocType)
+		if err != nil {
+			return nil, err
+		}
+		if chain != nil {
+			return nil, fmt.Errorf("%w: %s is controlled by approval chain %s", ErrInvalidWorkflow, docType.Name, chain.Name)
+		}
+	}
+
+	roles, err := uc.roleRepo.GetEnabledRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		enabled[role.Code] = true
+	}
+
+	var fieldTypes map[string]ConditionType
+	for _, t := range workflow.Transitions {
+		if !enabled[t.AllowedRole] {
+			return nil, fmt.Errorf("%w: role %s does not exist or is disabled", ErrInvalidWorkflow, t.AllowedRole)
+		}
+		if t.Condition == "" {
+			continue
+		}
+		if fieldTypes == nil {
+			if fieldTypes, err = uc.permRepo.GetConditionFieldTypes(ctx, workflow.DocType); err != nil {
+				return nil, err
+			}
+		}
+		condition, err := ParsePermissionCondition(t.Condition)
+		if err == nil {
+			err = condition.Check(fieldTypes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: transition %s from %s: %v", ErrInvalidWorkflow, t.Action, t.State, err)
+		}
+	}
+
+	if workflow.Transitions == nil {
+		workflow.Transitions = []*WorkflowTransition{}
+	}
+	return uc.repo.SaveWorkflow(ctx, workflow)
+}
+
+// GetWorkflow 获取文档类型的工作流
+func (uc *WorkflowUsecase) GetWorkflow(ctx context.Context, docType string) (*Workflow, error) {
+	return uc.repo.GetWorkflow(ctx, docType)
+}
+
+// ListWorkflows 获取全部工作流
+func (uc *WorkflowUsecase) ListWorkflows(ctx context.Context) ([]*Workflow, error) {
+	return uc.repo.ListWorkflows(ctx)
+}
+
+// DeleteWorkflow 删除文档类型的工作流，文档保留当前状态
+func (uc *WorkflowUsecase) DeleteWorkflow(ctx context.Context, docType string) error {
+	return uc.repo.DeleteWorkflow(ctx, docType)
+}
+
+// ListTransitions 获取文档当前状态下当前用户可执行的转换
+func (uc *WorkflowUsecase) ListTransitions(ctx context.Context, docType, name string, access *DocumentAccess) (*DocumentTransitions, error) {
+	workflow, _, doc, err := uc.loadWorkflowDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+
+	current := workflow.currentState(doc)
+	result := &DocumentTransitions{
+		DocType:     docType,
+		Name:        name,
+		DocStatus:   docStatusOf(doc),
+		Transitions: []*WorkflowTransition{},
+	}
+	if current == nil {
+		result.State, _ = doc["workflow_state"].(string)
+		return result, nil
+	}
+	result.State = current.State
+
+	user, err := uc.permRepo.GetUserConditionAttributes(ctx, access.UserID)
+	if err != nil {
+		return nil, err
+	}
+	result.Transitions = uc.availableTransitions(workflow, current, doc, user)
+	return result, nil
+}
+
+// ApplyTransition 执行转换：更新文档的工作流状态和文档状态并记录操作日志；
+// 进入已提交状态前检查必填字段，进入已取消状态时comment作为取消原因
+func (uc *WorkflowUsecase) ApplyTransition(ctx context.Context, docType, name, action, comment string, access *DocumentAccess) (map[string]interface{}, error) {
+	action = strings.TrimSpace(action)
+	comment = strings.TrimSpace(comment)
+	if action == "" {
+		return nil, fmt.Errorf("%w: action is required", ErrWorkflowTransitionNotAllowed)
+	}
+	if len([]rune(comment)) > maxCancelReasonLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidDocument, maxCancelReasonLength)
+	}
+
+	workflow, meta, doc, err := uc.loadWorkflowDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, err
+	}
+	current := workflow.currentState(doc)
+	if current == nil {
+		return nil, fmt.Errorf("%w: document is in no state of workflow %s", ErrWorkflowTransitionNotAllowed, workflow.Name)
+	}
+
+	user, err := uc.permRepo.GetUserConditionAttributes(ctx, access.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var transition *WorkflowTransition
+	for _, t := range uc.availableTransitions(workflow, current, doc, user) {
+		if t.Action == action {
+			transition = t
+			break
+		}
+	}
+	if transition == nil {
+		return nil, fmt.Errorf("%w: %s is not available in state %s", ErrWorkflowTransitionNotAllowed, action, current.State)
+	}
+
+	next := workflow.State(transition.NextState)
+	from := docStatusOf(doc)
+	if from == DocStatusDraft && next.DocStatus == DocStatusSubmitted {
+		if err := checkMandatory(meta.Fields, doc); err != nil {
+			return nil, err
+		}
+	}
+	change := &DocumentTransition{
+		From:          from,
+		To:            next.DocStatus,
+		WorkflowState: next.State,
+		UserID:        access.UserID,
+	}
+	change.FromState, _ = doc["workflow_state"].(string)
+	if next.DocStatus == DocStatusCancelled && from != DocStatusCancelled {
+		change.Reason = comment
+	}
+
+	updated, err := uc.documentUc.repo.TransitionDocument(ctx, meta, name, change)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.auditRepo.CreateOperationLog(ctx, transitionLog(docType, doc, current, transition, next, comment, user)); err != nil {
+		uc.log.Errorf("failed to write audit log for workflow transition of %s %s: %v", docType, name, err)
+	}
+	uc.log.Infof("Workflow transition applied: %s %s %s -> %s by user %d", docType, name, current.State, next.State, access.UserID)
+	return uc.documentUc.present(ctx, meta, updated, access)
+}
+
+// loadWorkflowDocument 加载启用中的工作流和数据范围内的文档
+func (uc *WorkflowUsecase) loadWorkflowDocument(ctx context.Context, docType, name string, access *DocumentAccess) (*Workflow, *DocumentMeta, map[string]interface{}, error) {
+	workflow, err := uc.documentUc.activeWorkflow(ctx, docType)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if workflow == nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s has no active workflow", ErrWorkflowNotFound, docType)
+	}
+
+	meta, doc, err := uc.documentUc.loadDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return workflow, meta, doc, nil
+}
+
+// availableTransitions 当前状态下用户持有所需角色且条件成立的转换，条件求值出错视为不成立
+func (uc *WorkflowUsecase) availableTransitions(workflow *Workflow, current *WorkflowState, doc, user map[string]interface{}) []*WorkflowTransition {
+	roles := make(map[string]bool)
+	if codes, ok := user["roles"].([]string); ok {
+		for _, code := range codes {
+			roles[code] = true
+		}
+	}
+
+	available := []*WorkflowTransition{}
+	for _, t := range workflow.Transitions {
+		if t.State != current.State || !roles[t.AllowedRole] {
+			continue
+		}
+		if t.Condition != "" {
+			condition, err := ParsePermissionCondition(t.Condition)
+			if err != nil {
+				uc.log.Warnf("Skip workflow transition %s of %s with invalid condition: %v", t.Action, workflow.DocType, err)
+				continue
+			}
+			if ok, err := condition.Evaluate(doc, user); err != nil || !ok {
+				continue
+			}
+		}
+		available = append(available, t)
+	}
+	return available
+}
+
+// transitionLog 构造工作流转换的操作日志
+func transitionLog(docType string, doc map[string]interface{}, from *WorkflowState, transition *WorkflowTransition, to *WorkflowState, comment string, user map[string]interface{}) *OperationLog {
+	name, _ := doc["name"].(string)
+	detail, _ := json.Marshal(map[string]interface{}{
+		"doc_type":       docType,
+		"name":           name,
+		"action":         transition.Action,
+		"from_state":     from.State,
+		"to_state":       to.State,
+		"from_docstatus": docStatusOf(doc),
+		"to_docstatus":   to.DocStatus,
+		"comment":        comment,
+	})
+
+	entry := &OperationLog{
+		Action:      WorkflowActionTransition,
+		Resource:    docType,
+		Description: fmt.Sprintf("文档 %s 执行工作流操作 %s：%s -> %s", name, transition.Action, from.State, to.State),
+		RequestData: string(detail),
+		Status:      "success",
+		CreatedAt:   time.Now(),
+	}
+	if id, err := coerceInt(doc["id"]); err == nil {
+		entry.ResourceID = strconv.Itoa(id)
+	}
+	if userID, ok := user["id"].(int64); ok {
+		id := int32(userID)
+		entry.UserID = &id
+	}
+	entry.Username, _ = user["username"].(string)
+	return entry
+}
@@ -0,0 +1,258 @@
+package biz_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubWorkflowRepo 在内存中保存工作流
+type stubWorkflowRepo struct {
+	workflows map[string]*biz.Workflow
+}
+
+func (r *stubWorkflowRepo) SaveWorkflow(ctx context.Context, workflow *biz.Workflow) (*biz.Workflow, error) {
+	if r.workflows == nil {
+		r.workflows = make(map[string]*biz.Workflow)
+	}
+	r.workflows[workflow.DocType] = workflow
+	return workflow, nil
+}
+
+func (r *stubWorkflowRepo) GetWorkflow(ctx context.Context, docType string) (*biz.Workflow, error) {
+	if workflow, ok := r.workflows[docType]; ok {
+		return workflow, nil
+	}
+	return nil, biz.ErrWorkflowNotFound
+}
+
+func (r *stubWorkflowRepo) ListWorkflows(ctx context.Context) ([]*biz.Workflow, error) {
+	var workflows []*biz.Workflow
+	for _, workflow := range r.workflows {
+		workflows = append(workflows, workflow)
+	}
+	return workflows, nil
+}
+
+func (r *stubWorkflowRepo) DeleteWorkflow(ctx context.Context, docType string) error {
+	if _, ok := r.workflows[docType]; !ok {
+		return biz.ErrWorkflowNotFound
+	}
+	delete(r.workflows, docType)
+	return nil
+}
+
+// stubWorkflowPermissionRepo 提供文档类型、条件字段类型和用户角色
+type stubWorkflowPermissionRepo struct {
+	stubDocTypePermissionRepo
+	roles map[int64][]string
+}
+
+func (r *stubWorkflowPermissionRepo) GetConditionFieldTypes(ctx context.Context, docType string) (map[string]biz.ConditionType, error) {
+	return map[string]biz.ConditionType{"amount": biz.ConditionTypeNumber, "customer": biz.ConditionTypeString}, nil
+}
+
+func (r *stubWorkflowPermissionRepo) GetUserConditionAttributes(ctx context.Context, userID int64) (map[string]interface{}, error) {
+	return map[string]interface{}{"id": userID, "username": fmt.Sprintf("user%d", userID), "roles": r.roles[userID]}, nil
+}
+
+// stubAuditRepo 记录写入的操作日志
+type stubAuditRepo struct {
+	biz.AuditRepo
+	logs []*biz.OperationLog
+}
+
+func (r *stubAuditRepo) CreateOperationLog(ctx context.Context, log *biz.OperationLog) error {
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func TestWorkflow_Validate(t *testing.T) {
+	valid := func() *biz.Workflow {
+		return &biz.Workflow{
+			DocType: "Order",
+			Name:    "Order Approval",
+			States: []*biz.WorkflowState{
+				{State: "Pending", DocStatus: 0},
+				{State: "Approved", DocStatus: 1},
+				{State: "Rejected", DocStatus: 0},
+				{State: "Cancelled", DocStatus: 2},
+			},
+			Transitions: []*biz.WorkflowTransition{
+				{State: "Pending", Action: "Approve", NextState: "Approved", AllowedRole: "MANAGER", Condition: "doc.amount < 10000"},
+				{State: "Pending", Action: "Reject", NextState: "Rejected", AllowedRole: "MANAGER"},
+				{State: "Approved", Action: "Cancel", NextState: "Cancelled", AllowedRole: "MANAGER"},
+			},
+		}
+	}
+	assert.NoError(t, valid().Validate())
+
+	cases := map[string]func(w *biz.Workflow){
+		"no states":            func(w *biz.Workflow) { w.States = nil },
+		"duplicate state":      func(w *biz.Workflow) { w.States[2].State = " Pending " },
+		"initial not draft":    func(w *biz.Workflow) { w.States[0], w.States[1] = w.States[1], w.States[0] },
+		"bad docstatus":        func(w *biz.Workflow) { w.States[3].DocStatus = 3 },
+		"unknown next state":   func(w *biz.Workflow) { w.Transitions[0].NextState = "Done" },
+		"missing role":         func(w *biz.Workflow) { w.Transitions[1].AllowedRole = "" },
+		"draft to cancelled":   func(w *biz.Workflow) { w.Transitions[1].NextState = "Cancelled" },
+		"submitted to draft":   func(w *biz.Workflow) { w.Transitions[2].NextState = "Pending" },
+		"invalid condition":    func(w *biz.Workflow) { w.Transitions[0].Condition = "doc.amount <" },
+		"duplicate transition": func(w *biz.Workflow) { w.Transitions[1].Action = "Approve" },
+	}
+	for name, mutate := range cases {
+		w := valid()
+		mutate(w)
+		assert.ErrorIs(t, w.Validate(), biz.ErrInvalidWorkflow, name)
+	}
+}
+
+func TestWorkflowUsecase(t *testing.T) {
+	fieldRepo := &stubDocFieldRepo{fields: []*biz.DocField{
+		{DocType: "Order", FieldName: "customer", FieldType: "Data", IsMandatory: true},
+		{DocType: "Order", FieldName: "amount", FieldType: "Currency"},
+		{DocType: "Note", FieldName: "content", FieldType: "Text"},
+	}}
+	permRepo := &stubWorkflowPermissionRepo{
+		stubDocTypePermissionRepo: stubDocTypePermissionRepo{docTypes: map[string]*biz.DocType{
+			"Order": {Name: "Order", IsSubmittable: true},
+			"Note":  {Name: "Note"},
+		}},
+		roles: map[int64][]string{1: {"SALES_USER"}, 2: {"SALES_MANAGER"}, 3: {"SALES_MANAGER", "FINANCE"}},
+	}
+	roleRepo := &stubRoleRepo{roles: map[int32]*biz.Role{
+		1: {ID: 1, Code: "SALES_USER", IsEnabled: true},
+		2: {ID: 2, Code: "SALES_MANAGER", IsEnabled: true},
+		3: {ID: 3, Code: "FINANCE", IsEnabled: true},
+		4: {ID: 4, Code: "RETIRED", IsEnabled: false},
+	}}
+	workflowRepo := &stubWorkflowRepo{}
+	auditRepo := &stubAuditRepo{}
+	repo := &stubDocumentRepo{}
+	documentUc := biz.NewDocumentUsecase(repo, fieldRepo, permRepo, &stubNamingSeriesRepo{}, workflowRepo, &stubApprovalRepo{}, log.DefaultLogger)
+	uc := biz.NewWorkflowUsecase(workflowRepo, documentUc, permRepo, roleRepo, auditRepo, log.DefaultLogger)
+	ctx := context.Background()
+	clerk := &biz.DocumentAccess{UserID: 1}
+	manager := &biz.DocumentAccess{UserID: 2}
+	finance := &biz.DocumentAccess{UserID: 3}
+
+	workflow := &biz.Workflow{
+		DocType:  "Order",
+		Name:     "Order Approval",
+		IsActive: true,
+		States: []*biz.WorkflowState{
+			{State: "Pending", DocStatus: 0},
+			{State: "Reviewed", DocStatus: 0},
+			{State: "Approved", DocStatus: 1},
+			{State: "Cancelled", DocStatus: 2},
+		},
+		Transitions: []*biz.WorkflowTransition{
+			{State: "Pending", Action: "Review", NextState: "Reviewed", AllowedRole: "SALES_USER"},
+			{State: "Reviewed", Action: "Approve", NextState: "Approved", AllowedRole: "SALES_MANAGER", Condition: "doc.amount < 10000"},
+			{State: "Reviewed", Action: "Approve", NextState: "Approved", AllowedRole: "FINANCE"},
+			{State: "Approved", Action: "Cancel", NextState: "Cancelled", AllowedRole: "SALES_MANAGER"},
+		},
+	}
+
+	// 保存时检查角色、条件字段类型和文档类型
+	invalid := *workflow
+	invalid.Transitions = []*biz.WorkflowTransition{{State: "Pending", Action: "Review", NextState: "Reviewed", AllowedRole: "RETIRED"}}
+	_, err := uc.SaveWorkflow(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidWorkflow)
+	invalid.Transitions = []*biz.WorkflowTransition{{State: "Pending", Action: "Review", NextState: "Reviewed", AllowedRole: "SALES_USER", Condition: "doc.amount == 'x'"}}
+	_, err = uc.SaveWorkflow(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidWorkflow)
+	invalid = *workflow
+	invalid.DocType = "Note"
+	_, err = uc.SaveWorkflow(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrInvalidWorkflow)
+	invalid.DocType = "Invoice"
+	_, err = uc.SaveWorkflow(ctx, &invalid)
+	assert.ErrorIs(t, err, biz.ErrDocTypeNotFound)
+
+	_, err = uc.SaveWorkflow(ctx, workflow)
+	assert.NoError(t, err)
+
+	// 新建文档进入初始状态，不能绕过工作流直接提交
+	doc, err := documentUc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-1", "customer": "ACME", "amount": 20000.0}, clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", doc["workflow_state"])
+	_, err = documentUc.SubmitDocument(ctx, "Order", "SO-1", manager)
+	assert.ErrorIs(t, err, biz.ErrDocumentUnderWorkflow)
+
+	transitions, err := uc.ListTransitions(ctx, "Order", "SO-1", manager)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", transitions.State)
+	assert.Empty(t, transitions.Transitions)
+	_, err = uc.ApplyTransition(ctx, "Order", "SO-1", "Review", "", manager)
+	assert.ErrorIs(t, err, biz.ErrWorkflowTransitionNotAllowed)
+
+	doc, err = uc.ApplyTransition(ctx, "Order", "SO-1", "Review", "看过了", clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, "Reviewed", doc["workflow_state"])
+	assert.Equal(t, biz.DocStatusDraft, doc["docstatus"])
+
+	// 条件不成立的转换对经理不可用，财务不受金额限制
+	transitions, err = uc.ListTransitions(ctx, "Order", "SO-1", manager)
+	assert.NoError(t, err)
+	assert.Empty(t, transitions.Transitions)
+	transitions, err = uc.ListTransitions(ctx, "Order", "SO-1", finance)
+	assert.NoError(t, err)
+	if assert.Len(t, transitions.Transitions, 1) {
+		assert.Equal(t, "FINANCE", transitions.Transitions[0].AllowedRole)
+	}
+
+	_, err = documentUc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"amount": 5000.0}, clerk)
+	assert.NoError(t, err)
+	transitions, err = uc.ListTransitions(ctx, "Order", "SO-1", manager)
+	assert.NoError(t, err)
+	assert.Len(t, transitions.Transitions, 1)
+
+	doc, err = uc.ApplyTransition(ctx, "Order", "SO-1", "Approve", "", manager)
+	assert.NoError(t, err)
+	assert.Equal(t, "Approved", doc["workflow_state"])
+	assert.Equal(t, biz.DocStatusSubmitted, doc["docstatus"])
+	_, err = documentUc.UpdateDocument(ctx, "Order", "SO-1", map[string]interface{}{"amount": 1.0}, clerk)
+	assert.ErrorIs(t, err, biz.ErrDocumentNotEditable)
+
+	doc, err = uc.ApplyTransition(ctx, "Order", "SO-1", "Cancel", "客户撤单", manager)
+	assert.NoError(t, err)
+	assert.Equal(t, biz.DocStatusCancelled, doc["docstatus"])
+	assert.Equal(t, "客户撤单", doc["cancel_reason"])
+
+	// 每次转换记录操作日志
+	if assert.Len(t, auditRepo.logs, 3) {
+		entry := auditRepo.logs[1]
+		assert.Equal(t, biz.WorkflowActionTransition, entry.Action)
+		assert.Equal(t, "Order", entry.Resource)
+		assert.Equal(t, "user2", entry.Username)
+		assert.Equal(t, int32(2), *entry.UserID)
+		assert.Contains(t, entry.RequestData, `"from_state":"Reviewed"`)
+		assert.Contains(t, entry.RequestData, `"to_state":"Approved"`)
+	}
+
+	// 修订版本从初始状态开始
+	amended, err := documentUc.AmendDocument(ctx, "Order", "SO-1", manager)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", amended["workflow_state"])
+
+	// 启用工作流前创建的文档按docstatus对应到状态
+	_, err = documentUc.CreateDocument(ctx, "Order", map[string]interface{}{"name": "SO-2", "customer": "ACME"}, clerk)
+	assert.NoError(t, err)
+	repo.docs[len(repo.docs)-1]["workflow_state"] = "Draft"
+	transitions, err = uc.ListTransitions(ctx, "Order", "SO-2", clerk)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pending", transitions.State)
+	assert.Len(t, transitions.Transitions, 1)
+
+	// 停用后不能执行转换，可以直接提交
+	workflow.IsActive = false
+	_, err = uc.ListTransitions(ctx, "Order", "SO-2", clerk)
+	assert.ErrorIs(t, err, biz.ErrWorkflowNotFound)
+	_, err = documentUc.SubmitDocument(ctx, "Order", "SO-2", manager)
+	assert.NoError(t, err)
+}
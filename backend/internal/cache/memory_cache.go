@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -45,6 +46,10 @@ func (c *MemoryPermissionCache) userPermissionLevelKey(userID int64, docType str
 	return fmt.Sprintf("%suser_level:%d:%s", c.prefix, userID, docType)
 }
 
+func (c *MemoryPermissionCache) userLevelMaskKey(userID int64, docType string) string {
+	return fmt.Sprintf("%suser_level_mask:%d:%s", c.prefix, userID, docType)
+}
+
 func (c *MemoryPermissionCache) fieldPermissionLevelsKey(docType string) string {
 	return fmt.Sprintf("%sfield_levels:%s", c.prefix, docType)
 }
@@ -195,6 +200,34 @@ func (c *MemoryPermissionCache) DeleteUserPermissionLevel(ctx context.Context, u
 	return nil
 }
 
+// 用户分级读写权限缓存实现
+func (c *MemoryPermissionCache) SetUserLevelPermissions(ctx context.Context, userID int64, docType string, perms *biz.LevelPermissions, ttl time.Duration) error {
+	key := c.userLevelMaskKey(userID, docType)
+	c.cache.Store(key, perms.Bitmask())
+	return nil
+}
+
+func (c *MemoryPermissionCache) GetUserLevelPermissions(ctx context.Context, userID int64, docType string) (*biz.LevelPermissions, error) {
+	key := c.userLevelMaskKey(userID, docType)
+	value, ok := c.cache.Load(key)
+	if !ok {
+		return nil, nil // 缓存未命中
+	}
+
+	mask, ok := value.(int64)
+	if !ok {
+		return nil, fmt.Errorf("invalid cached data type for user level permissions")
+	}
+
+	return biz.LevelPermissionsFromBitmask(mask), nil
+}
+
+func (c *MemoryPermissionCache) DeleteUserLevelPermissions(ctx context.Context, userID int64, docType string) error {
+	key := c.userLevelMaskKey(userID, docType)
+	c.cache.Delete(key)
+	return nil
+}
+
 // 字段权限级别缓存实现
 func (c *MemoryPermissionCache) SetFieldPermissionLevels(ctx context.Context, docType string, levels map[string]int, ttl time.Duration) error {
 	key := c.fieldPermissionLevelsKey(docType)
@@ -303,10 +336,13 @@ func (c *MemoryPermissionCache) ClearRoleCache(ctx context.Context, roleID int64
 }
 
 func (c *MemoryPermissionCache) ClearDocTypeCache(ctx context.Context, docType string) error {
-	// 清除文档类型相关的所有缓存
+	// 清除文档类型相关的所有缓存，包括按用户缓存的分级权限
+	maskPrefix := c.prefix + "user_level_mask:"
+	docTypeSuffix := ":" + docType
 	c.cache.Range(func(key, value interface{}) bool {
 		if k, ok := key.(string); ok {
-			if k == c.docTypeKey(docType) || k == c.fieldPermissionLevelsKey(docType) {
+			if k == c.docTypeKey(docType) || k == c.fieldPermissionLevelsKey(docType) ||
+				(strings.HasPrefix(k, maskPrefix) && strings.HasSuffix(k, docTypeSuffix)) {
 				c.cache.Delete(key)
 			}
 		}
@@ -340,6 +376,7 @@ func (c *MemoryPermissionCache) GetCacheStats(ctx context.Context) (map[string]i
 		"user_roles":       0,
 		"permission_rules": 0,
 		"user_levels":      0,
+		"user_level_masks": 0,
 		"field_levels":     0,
 		"doctypes":         0,
 		"total":            0,
@@ -355,6 +392,8 @@ func (c *MemoryPermissionCache) GetCacheStats(ctx context.Context) (map[string]i
 				counts["user_roles"]++
 			case len(k) > len(c.prefix+"role_rules:") && k[:len(c.prefix+"role_rules:")] == c.prefix+"role_rules:":
 				counts["permission_rules"]++
+			case len(k) > len(c.prefix+"user_level_mask:") && k[:len(c.prefix+"user_level_mask:")] == c.prefix+"user_level_mask:":
+				counts["user_level_masks"]++
 			case len(k) > len(c.prefix+"user_level:") && k[:len(c.prefix+"user_level:")] == c.prefix+"user_level:":
 				counts["user_levels"]++
 			case len(k) > len(c.prefix+"field_levels:") && k[:len(c.prefix+"field_levels:")] == c.prefix+"field_levels:":
@@ -36,6 +36,11 @@ type PermissionCache interface {
 	GetUserPermissionLevel(ctx context.Context, userID int64, docType string) (int, error)
 	DeleteUserPermissionLevel(ctx context.Context, userID int64, docType string) error
 
+	// 用户分级读写权限缓存（位掩码）
+	SetUserLevelPermissions(ctx context.Context, userID int64, docType string, perms *biz.LevelPermissions, ttl time.Duration) error
+	GetUserLevelPermissions(ctx context.Context, userID int64, docType string) (*biz.LevelPermissions, error)
+	DeleteUserLevelPermissions(ctx context.Context, userID int64, docType string) error
+
 	// 字段权限级别缓存
 	SetFieldPermissionLevels(ctx context.Context, docType string, levels map[string]int, ttl time.Duration) error
 	GetFieldPermissionLevels(ctx context.Context, docType string) (map[string]int, error)
@@ -89,6 +94,10 @@ func (c *RedisPermissionCache) userPermissionLevelKey(userID int64, docType stri
 	return fmt.Sprintf("%suser_level:%d:%s", c.prefix, userID, docType)
 }
 
+func (c *RedisPermissionCache) userLevelMaskKey(userID int64, docType string) string {
+	return fmt.Sprintf("%suser_level_mask:%d:%s", c.prefix, userID, docType)
+}
+
 func (c *RedisPermissionCache) fieldPermissionLevelsKey(docType string) string {
 	return fmt.Sprintf("%sfield_levels:%s", c.prefix, docType)
 }
@@ -228,6 +237,35 @@ func (c *RedisPermissionCache) DeleteUserPermissionLevel(ctx context.Context, us
 	return c.client.Del(ctx, key).Err()
 }
 
+// 用户分级读写权限缓存实现
+func (c *RedisPermissionCache) SetUserLevelPermissions(ctx context.Context, userID int64, docType string, perms *biz.LevelPermissions, ttl time.Duration) error {
+	key := c.userLevelMaskKey(userID, docType)
+	return c.client.Set(ctx, key, perms.Bitmask(), ttl).Err()
+}
+
+func (c *RedisPermissionCache) GetUserLevelPermissions(ctx context.Context, userID int64, docType string) (*biz.LevelPermissions, error) {
+	key := c.userLevelMaskKey(userID, docType)
+	result, err := c.client.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // 缓存未命中
+		}
+		return nil, fmt.Errorf("failed to get user level permissions from cache: %w", err)
+	}
+
+	mask, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse user level permissions: %w", err)
+	}
+
+	return biz.LevelPermissionsFromBitmask(mask), nil
+}
+
+func (c *RedisPermissionCache) DeleteUserLevelPermissions(ctx context.Context, userID int64, docType string) error {
+	key := c.userLevelMaskKey(userID, docType)
+	return c.client.Del(ctx, key).Err()
+}
+
 // 字段权限级别缓存实现
 func (c *RedisPermissionCache) SetFieldPermissionLevels(ctx context.Context, docType string, levels map[string]int, ttl time.Duration) error {
 	key := c.fieldPermissionLevelsKey(docType)
@@ -390,6 +428,7 @@ func (c *RedisPermissionCache) GetCacheStats(ctx context.Context) (map[string]in
 		"user_roles":       fmt.Sprintf("%suser_roles:*", c.prefix),
 		"permission_rules": fmt.Sprintf("%srole_rules:*", c.prefix),
 		"user_levels":      fmt.Sprintf("%suser_level:*", c.prefix),
+		"user_level_masks": fmt.Sprintf("%suser_level_mask:*", c.prefix),
 		"field_levels":     fmt.Sprintf("%sfield_levels:*", c.prefix),
 		"doctypes":         fmt.Sprintf("%sdoctype:*", c.prefix),
 	}
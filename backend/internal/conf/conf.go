@@ -1,6 +1,10 @@
 package conf
 
-import "time"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Bootstrap 启动配置
 type Bootstrap struct {
@@ -9,6 +13,7 @@ type Bootstrap struct {
 	Auth     *Auth     `yaml:"auth"`
 	Log      *Log      `yaml:"log"`
 	Upload   *Upload   `yaml:"upload"`
+	Profile  *Profile  `yaml:"profile"`
 	Cors     *Cors     `yaml:"cors"`
 	Security *Security `yaml:"security"`
 }
@@ -59,9 +64,27 @@ func (g *GRPC) GetTimeout() time.Duration {
 
 // Data 数据配置
 type Data struct {
-	Database *Database `yaml:"database"`
-	Redis    *Redis    `yaml:"redis"`
-	Jwt      *JWT      `yaml:"jwt"`
+	Database  *Database  `yaml:"database"`
+	Redis     *Redis     `yaml:"redis"`
+	Jwt       *JWT       `yaml:"jwt"`
+	Retention *Retention `yaml:"retention"`
+}
+
+// Retention 数据保留配置
+type Retention struct {
+	RecycleBin string `yaml:"recycle_bin"`
+}
+
+// GetRecycleBin 返回回收站保留期，未配置或格式错误时返回0
+func (r *Retention) GetRecycleBin() time.Duration {
+	if r == nil || r.RecycleBin == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(r.RecycleBin)
+	if err != nil {
+		return 0
+	}
+	return duration
 }
 
 // JWT JWT配置
@@ -100,9 +123,63 @@ type Log struct {
 
 // Upload 文件上传配置
 type Upload struct {
-	MaxSize      string   `yaml:"max_size"`
-	Path         string   `yaml:"path"`
-	AllowedTypes []string `yaml:"allowed_types"`
+	MaxSize       string   `yaml:"max_size"`
+	Path          string   `yaml:"path"`
+	AllowedTypes  []string `yaml:"allowed_types"`
+	Storage       string   `yaml:"storage"` // local或s3，默认local
+	S3            *S3      `yaml:"s3"`
+	SignSecret    string   `yaml:"sign_secret"`
+	URLExpire     string   `yaml:"url_expire"`
+	ThumbnailSize int      `yaml:"thumbnail_size"`
+}
+
+// S3 S3兼容对象存储配置，使用路径风格访问存储桶
+type S3 struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
+// GetMaxSize 返回上传文件的最大字节数，支持KB、MB和GB后缀，默认10MB
+func (u *Upload) GetMaxSize() int64 {
+	const defaultMaxSize = 10 << 20
+	if u == nil || u.MaxSize == "" {
+		return defaultMaxSize
+	}
+
+	value := strings.ToUpper(strings.TrimSpace(u.MaxSize))
+	unit := int64(1)
+	for suffix, size := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(value, suffix) {
+			value, unit = strings.TrimSpace(strings.TrimSuffix(value, suffix)), size
+			break
+		}
+	}
+	size, err := strconv.ParseInt(strings.TrimSuffix(value, "B"), 10, 64)
+	if err != nil || size <= 0 {
+		return defaultMaxSize
+	}
+	return size * unit
+}
+
+// GetURLExpire 返回附件下载链接的有效期，默认15分钟
+func (u *Upload) GetURLExpire() time.Duration {
+	if u == nil || u.URLExpire == "" {
+		return 15 * time.Minute
+	}
+	duration, err := time.ParseDuration(u.URLExpire)
+	if err != nil || duration <= 0 {
+		return 15 * time.Minute
+	}
+	return duration
+}
+
+// Profile 个人资料自助修改配置，EditableFields可直接修改，ApprovalFields需要管理员审批
+type Profile struct {
+	EditableFields []string `yaml:"editable_fields"`
+	ApprovalFields []string `yaml:"approval_fields"`
 }
 
 // Cors 跨域配置
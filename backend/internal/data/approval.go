@@ -0,0 +1,643 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// approvalRepo 审批仓储实现
+type approvalRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewApprovalRepo 创建审批仓储
+func NewApprovalRepo(data *Data, logger log.Logger) biz.ApprovalRepo {
+	return &approvalRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// approvalChainColumns 审批链查询列，与scanApprovalChain的扫描顺序一致
+const approvalChainColumns = `id, doc_type, name, description, is_active, org_field, steps,
+		       created_at, updated_at, created_by, updated_by`
+
+// approvalRequestColumns 审批请求查询列，与scanApprovalRequest的扫描顺序一致
+const approvalRequestColumns = `id, company_id, doc_type, doc_name, chain_id, chain_name, steps, org_id, status,
+		       current_step, version, comment, requested_by, completed_at, created_at, updated_at`
+
+// approvalTaskColumns 审批任务查询列，与scanApprovalTask的扫描顺序一致；approval_tasks别名须为t，approval_requests别名须为r
+const approvalTaskColumns = `t.id, t.request_id, r.company_id, r.doc_type, r.doc_name, t.step, t.step_name, t.approver_id, t.org_id,
+		       t.status, t.delegated_from, t.escalated_from, t.due_at, t.comment, t.decided_at, t.created_at`
+
+// approvalDelegationColumns 审批委托查询列，与scanApprovalDelegation的扫描顺序一致
+const approvalDelegationColumns = `d.id, d.delegator_id, d.delegate_id, d.doc_type, d.starts_at, d.ends_at, d.reason,
+		       d.is_active, d.created_at`
+
+// SaveApprovalChain 按文档类型创建或替换审批链
+func (r *approvalRepo) SaveApprovalChain(ctx context.Context, chain *biz.ApprovalChain) (*biz.ApprovalChain, error) {
+	stepsJSON, err := json.Marshal(chain.Steps)
+	if err != nil {
+		r.log.Errorf("failed to marshal approval steps: %v", err)
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO approval_chains (doc_type, name, description, is_active, org_field, steps, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $7)
+		ON CONFLICT (doc_type) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, is_active = EXCLUDED.is_active,
+		    org_field = EXCLUDED.org_field, steps = EXCLUDED.steps, updated_by = EXCLUDED.updated_by
+		RETURNING ` + approvalChainColumns
+
+	userID := chain.UpdatedBy
+	if userID == nil {
+		userID = chain.CreatedBy
+	}
+	saved, err := r.scanApprovalChain(r.data.db.QueryRowContext(ctx, query,
+		chain.DocType, chain.Name, chain.Description, chain.IsActive, chain.OrgField, stepsJSON, userID,
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" && pqErr.Constraint == "fk_approval_chains_doc_type" {
+			return nil, biz.ErrDocTypeNotFound
+		}
+		r.log.Errorf("failed to save approval chain: %v", err)
+		return nil, err
+	}
+	return saved, nil
+}
+
+// GetApprovalChain 获取文档类型的审批链
+func (r *approvalRepo) GetApprovalChain(ctx context.Context, docType string) (*biz.ApprovalChain, error) {
+	chain, err := r.scanApprovalChain(r.data.db.QueryRowContext(ctx,
+		"SELECT "+approvalChainColumns+" FROM approval_chains WHERE doc_type = $1", docType))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrApprovalChainNotFound
+		}
+		r.log.Errorf("failed to get approval chain: %v", err)
+		return nil, err
+	}
+	return chain, nil
+}
+
+// ListApprovalChains 获取全部审批链
+func (r *approvalRepo) ListApprovalChains(ctx context.Context) ([]*biz.ApprovalChain, error) {
+	rows, err := r.data.db.QueryContext(ctx, "SELECT "+approvalChainColumns+" FROM approval_chains ORDER BY doc_type")
+	if err != nil {
+		r.log.Errorf("failed to list approval chains: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	chains := []*biz.ApprovalChain{}
+	for rows.Next() {
+		chain, err := r.scanApprovalChain(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan approval chain: %v", err)
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate approval chains: %v", err)
+		return nil, err
+	}
+
+	return chains, nil
+}
+
+// DeleteApprovalChain 删除文档类型的审批链
+func (r *approvalRepo) DeleteApprovalChain(ctx context.Context, docType string) error {
+	result, err := r.data.db.ExecContext(ctx, "DELETE FROM approval_chains WHERE doc_type = $1", docType)
+	if err != nil {
+		r.log.Errorf("failed to delete approval chain: %v", err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return biz.ErrApprovalChainNotFound
+	}
+	return nil
+}
+
+// CreateApprovalRequest 在事务中为当前公司创建审批请求及其全部任务，ctx已处于事务中时加入外层事务
+func (r *approvalRepo) CreateApprovalRequest(ctx context.Context, req *biz.ApprovalRequest) (*biz.ApprovalRequest, error) {
+	stepsJSON, err := json.Marshal(req.Steps)
+	if err != nil {
+		r.log.Errorf("failed to marshal approval steps: %v", err)
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO approval_requests (company_id, doc_type, doc_name, chain_id, chain_name, steps, org_id, status,
+		                               current_step, comment, requested_by, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12)
+		RETURNING id, version, created_at, updated_at`
+
+	req.CompanyID = biz.CompanyFromContext(ctx)
+
+	err = r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		err := tx.QueryRowContext(ctx, query,
+			req.CompanyID, req.DocType, req.DocName, req.ChainID, req.ChainName, stepsJSON, req.OrgID, req.Status,
+			req.CurrentStep, req.Comment, req.RequestedBy, req.CompletedAt,
+		).Scan(&req.ID, &req.Version, &req.CreatedAt, &req.UpdatedAt)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "uk_approval_requests_pending" {
+				return biz.ErrApprovalPending
+			}
+			r.log.Errorf("failed to create approval request: %v", err)
+			return err
+		}
+
+		for _, task := range req.Tasks {
+			task.RequestID = req.ID
+			if err := r.insertApprovalTask(ctx, tx, req, task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Tasks == nil {
+		req.Tasks = []*biz.ApprovalTask{}
+	}
+	return req, nil
+}
+
+// GetApprovalRequest 获取当前公司的审批请求及其任务
+func (r *approvalRepo) GetApprovalRequest(ctx context.Context, id int64) (*biz.ApprovalRequest, error) {
+	return r.getApprovalRequest(ctx, "SELECT "+approvalRequestColumns+" FROM approval_requests WHERE id = $1 AND company_id = $2",
+		id, biz.CompanyFromContext(ctx))
+}
+
+// GetPendingApprovalRequest 获取当前公司文档进行中的审批请求
+func (r *approvalRepo) GetPendingApprovalRequest(ctx context.Context, docType, docName string) (*biz.ApprovalRequest, error) {
+	return r.getApprovalRequest(ctx, "SELECT "+approvalRequestColumns+` FROM approval_requests
+		WHERE company_id = $1 AND doc_type = $2 AND doc_name = $3 AND status = 'pending'`,
+		biz.CompanyFromContext(ctx), docType, docName)
+}
+
+// ListApprovalRequests 获取当前公司文档的全部审批请求及其任务，最近的在前
+func (r *approvalRepo) ListApprovalRequests(ctx context.Context, docType, docName string) ([]*biz.ApprovalRequest, error) {
+	rows, err := r.data.db.QueryContext(ctx, "SELECT "+approvalRequestColumns+` FROM approval_requests
+		WHERE company_id = $1 AND doc_type = $2 AND doc_name = $3
+		ORDER BY created_at DESC, id DESC`, biz.CompanyFromContext(ctx), docType, docName)
+	if err != nil {
+		r.log.Errorf("failed to list approval requests: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []*biz.ApprovalRequest{}
+	byID := make(map[int64]*biz.ApprovalRequest)
+	var ids []int64
+	for rows.Next() {
+		req, err := r.scanApprovalRequest(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan approval request: %v", err)
+			return nil, err
+		}
+		requests = append(requests, req)
+		byID[req.ID] = req
+		ids = append(ids, req.ID)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate approval requests: %v", err)
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return requests, nil
+	}
+
+	tasks, err := r.listApprovalTasks(ctx, "WHERE t.request_id = ANY($1) ORDER BY t.request_id, t.step, t.id", pq.Int64Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		byID[task.RequestID].Tasks = append(byID[task.RequestID].Tasks, task)
+	}
+	return requests, nil
+}
+
+// UpdateApprovalRequest 在事务中按版本更新审批请求，并保存变化的任务
+func (r *approvalRepo) UpdateApprovalRequest(ctx context.Context, req *biz.ApprovalRequest, tasks []*biz.ApprovalTask) error {
+	query := `
+		UPDATE approval_requests
+		SET status = $3, current_step = $4, completed_at = $5, version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version, updated_at`
+
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		err := tx.QueryRowContext(ctx, query, req.ID, req.Version, req.Status, req.CurrentStep, req.CompletedAt).
+			Scan(&req.Version, &req.UpdatedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return biz.ErrApprovalRequestChanged
+			}
+			r.log.Errorf("failed to update approval request: %v", err)
+			return err
+		}
+
+		for _, task := range tasks {
+			if task.ID == 0 {
+				task.RequestID = req.ID
+				if err := r.insertApprovalTask(ctx, tx, req, task); err != nil {
+					return err
+				}
+				req.Tasks = append(req.Tasks, task)
+				continue
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE approval_tasks
+				SET approver_id = $3, status = $4, due_at = $5, comment = NULLIF($6, ''), decided_at = $7
+				WHERE id = $1 AND request_id = $2`,
+				task.ID, req.ID, task.ApproverID, task.Status, task.DueAt, task.Comment, task.DecidedAt)
+			if err != nil {
+				r.log.Errorf("failed to update approval task: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetApprovalTask 获取当前公司的审批任务
+func (r *approvalRepo) GetApprovalTask(ctx context.Context, id int64) (*biz.ApprovalTask, error) {
+	tasks, err := r.listApprovalTasks(ctx, "WHERE t.id = $1 AND r.company_id = $2", id, biz.CompanyFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, biz.ErrApprovalTaskNotFound
+	}
+	return tasks[0], nil
+}
+
+// ListApprovalTasks 获取审批人在当前公司的任务，待审批的按超时时间排在前面
+func (r *approvalRepo) ListApprovalTasks(ctx context.Context, approverID int64, status string) ([]*biz.ApprovalTask, error) {
+	return r.listApprovalTasks(ctx, `WHERE r.company_id = $1 AND t.approver_id = $2 AND ($3 = '' OR t.status = $3)
+		ORDER BY t.status = 'pending' DESC, t.due_at NULLS LAST, t.created_at DESC, t.id DESC`,
+		biz.CompanyFromContext(ctx), approverID, status)
+}
+
+// ListOverdueApprovalTasks 获取所有公司进行中请求里截至指定时间已超时的待审批任务
+func (r *approvalRepo) ListOverdueApprovalTasks(ctx context.Context, now time.Time, limit int) ([]*biz.ApprovalTask, error) {
+	return r.listApprovalTasks(ctx, `WHERE t.status = 'pending' AND t.due_at IS NOT NULL AND t.due_at <= $1
+		  AND r.status = 'pending'
+		ORDER BY t.due_at, t.id
+		LIMIT $2`, now, limit)
+}
+
+// CreateApprovalDelegation 创建审批委托
+func (r *approvalRepo) CreateApprovalDelegation(ctx context.Context, delegation *biz.ApprovalDelegation) (*biz.ApprovalDelegation, error) {
+	query := `
+		INSERT INTO approval_delegations (delegator_id, delegate_id, doc_type, starts_at, ends_at, reason, is_active)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7)
+		RETURNING id, created_at`
+
+	err := r.data.db.QueryRowContext(ctx, query,
+		delegation.DelegatorID, delegation.DelegateID, delegation.DocType,
+		delegation.StartsAt, delegation.EndsAt, delegation.Reason, delegation.IsActive,
+	).Scan(&delegation.ID, &delegation.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return nil, fmt.Errorf("%w: %s", biz.ErrInvalidApprovalDelegation, pqErr.Constraint)
+		}
+		r.log.Errorf("failed to create approval delegation: %v", err)
+		return nil, err
+	}
+	return delegation, nil
+}
+
+// GetApprovalDelegation 获取审批委托
+func (r *approvalRepo) GetApprovalDelegation(ctx context.Context, id int64) (*biz.ApprovalDelegation, error) {
+	delegation, err := r.scanApprovalDelegation(r.data.db.QueryRowContext(ctx,
+		"SELECT "+approvalDelegationColumns+" FROM approval_delegations d WHERE d.id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrApprovalDelegationNotFound
+		}
+		r.log.Errorf("failed to get approval delegation: %v", err)
+		return nil, err
+	}
+	return delegation, nil
+}
+
+// ListApprovalDelegations 获取委托人的审批委托，最近开始的在前
+func (r *approvalRepo) ListApprovalDelegations(ctx context.Context, delegatorID int64) ([]*biz.ApprovalDelegation, error) {
+	rows, err := r.data.db.QueryContext(ctx, "SELECT "+approvalDelegationColumns+` FROM approval_delegations d
+		WHERE d.delegator_id = $1
+		ORDER BY d.starts_at DESC, d.id DESC`, delegatorID)
+	if err != nil {
+		r.log.Errorf("failed to list approval delegations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	delegations := []*biz.ApprovalDelegation{}
+	for rows.Next() {
+		delegation, err := r.scanApprovalDelegation(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan approval delegation: %v", err)
+			return nil, err
+		}
+		delegations = append(delegations, delegation)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate approval delegations: %v", err)
+		return nil, err
+	}
+
+	return delegations, nil
+}
+
+// RevokeApprovalDelegation 撤销审批委托
+func (r *approvalRepo) RevokeApprovalDelegation(ctx context.Context, id int64) error {
+	result, err := r.data.db.ExecContext(ctx,
+		"UPDATE approval_delegations SET is_active = FALSE WHERE id = $1 AND is_active = TRUE", id)
+	if err != nil {
+		r.log.Errorf("failed to revoke approval delegation: %v", err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return biz.ErrApprovalDelegationNotFound
+	}
+	return nil
+}
+
+// GetActiveApprovalDelegation 获取委托人在指定时间生效的委托，受托人需为启用用户
+func (r *approvalRepo) GetActiveApprovalDelegation(ctx context.Context, delegatorID int64, docType string, at time.Time) (*biz.ApprovalDelegation, error) {
+	query := "SELECT " + approvalDelegationColumns + ` FROM approval_delegations d
+		INNER JOIN users u ON u.id = d.delegate_id AND u.is_enabled = TRUE AND u.deleted_at IS NULL
+		WHERE d.delegator_id = $1 AND d.is_active = TRUE AND d.starts_at <= $3 AND d.ends_at > $3
+		  AND (d.doc_type IS NULL OR d.doc_type = $2)
+		ORDER BY d.doc_type IS NULL, d.created_at DESC, d.id DESC
+		LIMIT 1`
+
+	delegation, err := r.scanApprovalDelegation(r.data.db.QueryRowContext(ctx, query, delegatorID, docType, at))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Errorf("failed to get active approval delegation: %v", err)
+		return nil, err
+	}
+	return delegation, nil
+}
+
+// ListRoleUserIDs 获取当前有效持有角色（含通过继承获得该角色）的启用用户
+func (r *approvalRepo) ListRoleUserIDs(ctx context.Context, roleCode string) ([]int64, error) {
+	query := fmt.Sprintf(`
+		WITH RECURSIVE role_tree AS (
+			SELECT id, 0 AS depth FROM roles WHERE code = $1 AND is_enabled = TRUE AND deleted_at IS NULL
+			UNION ALL
+			SELECT r.id, rt.depth + 1
+			FROM roles r
+			INNER JOIN role_tree rt ON r.parent_role_id = rt.id
+			WHERE r.is_enabled = TRUE AND rt.depth < %d
+		)
+		SELECT DISTINCT ur.user_id
+		FROM user_roles ur
+		INNER JOIN role_tree rt ON rt.id = ur.role_id
+		INNER JOIN users u ON u.id = ur.user_id AND u.is_enabled = TRUE AND u.deleted_at IS NULL
+		WHERE %s
+		ORDER BY ur.user_id`, biz.MaxRoleInheritanceDepth, effectiveUserRoleCondition)
+
+	return r.queryUserIDs(ctx, query, roleCode)
+}
+
+// ListActiveUserIDs 从给定用户中筛选出启用的用户
+func (r *approvalRepo) ListActiveUserIDs(ctx context.Context, userIDs []int64) ([]int64, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	return r.queryUserIDs(ctx, `
+		SELECT id FROM users
+		WHERE id = ANY($1) AND is_enabled = TRUE AND deleted_at IS NULL
+		ORDER BY id`, pq.Int64Array(userIDs))
+}
+
+// queryUserIDs 查询用户ID列表
+func (r *approvalRepo) queryUserIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := r.data.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Errorf("failed to query approver ids: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			r.log.Errorf("failed to scan approver id: %v", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate approver ids: %v", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// getApprovalRequest 查询单个审批请求并加载其任务
+func (r *approvalRepo) getApprovalRequest(ctx context.Context, query string, args ...interface{}) (*biz.ApprovalRequest, error) {
+	req, err := r.scanApprovalRequest(r.data.conn(ctx).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrApprovalRequestNotFound
+		}
+		r.log.Errorf("failed to get approval request: %v", err)
+		return nil, err
+	}
+
+	if req.Tasks, err = r.listApprovalTasks(ctx, "WHERE t.request_id = $1 ORDER BY t.step, t.id", req.ID); err != nil {
+		return nil, err
+	}
+	if req.Tasks == nil {
+		req.Tasks = []*biz.ApprovalTask{}
+	}
+	return req, nil
+}
+
+// insertApprovalTask 在事务中写入审批任务
+func (r *approvalRepo) insertApprovalTask(ctx context.Context, tx dbConn, req *biz.ApprovalRequest, task *biz.ApprovalTask) error {
+	query := `
+		INSERT INTO approval_tasks (request_id, step, step_name, approver_id, org_id, status,
+		                            delegated_from, escalated_from, due_at, comment, decided_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11)
+		RETURNING id, created_at`
+
+	err := tx.QueryRowContext(ctx, query,
+		task.RequestID, task.Step, task.StepName, task.ApproverID, task.OrgID, task.Status,
+		task.DelegatedFrom, task.EscalatedFrom, task.DueAt, task.Comment, task.DecidedAt,
+	).Scan(&task.ID, &task.CreatedAt)
+	if err != nil {
+		r.log.Errorf("failed to insert approval task: %v", err)
+		return err
+	}
+	task.CompanyID = req.CompanyID
+	task.DocType = req.DocType
+	task.DocName = req.DocName
+	return nil
+}
+
+// listApprovalTasks 按条件查询审批任务
+func (r *approvalRepo) listApprovalTasks(ctx context.Context, where string, args ...interface{}) ([]*biz.ApprovalTask, error) {
+	rows, err := r.data.conn(ctx).QueryContext(ctx, "SELECT "+approvalTaskColumns+`
+		FROM approval_tasks t
+		INNER JOIN approval_requests r ON r.id = t.request_id
+		`+where, args...)
+	if err != nil {
+		r.log.Errorf("failed to list approval tasks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []*biz.ApprovalTask{}
+	for rows.Next() {
+		task, err := r.scanApprovalTask(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan approval task: %v", err)
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate approval tasks: %v", err)
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// scanApprovalChain 扫描一行审批链
+func (r *approvalRepo) scanApprovalChain(row rowScanner) (*biz.ApprovalChain, error) {
+	var chain biz.ApprovalChain
+	var description, orgField sql.NullString
+	var stepsJSON []byte
+	var createdBy, updatedBy sql.NullInt64
+
+	err := row.Scan(&chain.ID, &chain.DocType, &chain.Name, &description, &chain.IsActive, &orgField,
+		&stepsJSON, &chain.CreatedAt, &chain.UpdatedAt, &createdBy, &updatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(stepsJSON, &chain.Steps); err != nil {
+		return nil, err
+	}
+	chain.Description = description.String
+	chain.OrgField = orgField.String
+	if createdBy.Valid {
+		chain.CreatedBy = &createdBy.Int64
+	}
+	if updatedBy.Valid {
+		chain.UpdatedBy = &updatedBy.Int64
+	}
+	return &chain, nil
+}
+
+// scanApprovalRequest 扫描一行审批请求，不含任务
+func (r *approvalRepo) scanApprovalRequest(row rowScanner) (*biz.ApprovalRequest, error) {
+	var req biz.ApprovalRequest
+	var chainID, orgID sql.NullInt64
+	var comment sql.NullString
+	var completedAt sql.NullTime
+	var stepsJSON []byte
+
+	err := row.Scan(&req.ID, &req.CompanyID, &req.DocType, &req.DocName, &chainID, &req.ChainName, &stepsJSON, &orgID,
+		&req.Status, &req.CurrentStep, &req.Version, &comment, &req.RequestedBy, &completedAt,
+		&req.CreatedAt, &req.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(stepsJSON, &req.Steps); err != nil {
+		return nil, err
+	}
+	if chainID.Valid {
+		req.ChainID = &chainID.Int64
+	}
+	if orgID.Valid {
+		req.OrgID = &orgID.Int64
+	}
+	if completedAt.Valid {
+		req.CompletedAt = &completedAt.Time
+	}
+	req.Comment = comment.String
+	req.Tasks = []*biz.ApprovalTask{}
+	return &req, nil
+}
+
+// scanApprovalTask 扫描一行审批任务
+func (r *approvalRepo) scanApprovalTask(row rowScanner) (*biz.ApprovalTask, error) {
+	var task biz.ApprovalTask
+	var orgID, delegatedFrom, escalatedFrom sql.NullInt64
+	var dueAt, decidedAt sql.NullTime
+	var comment sql.NullString
+
+	err := row.Scan(&task.ID, &task.RequestID, &task.CompanyID, &task.DocType, &task.DocName, &task.Step, &task.StepName,
+		&task.ApproverID, &orgID, &task.Status, &delegatedFrom, &escalatedFrom, &dueAt, &comment,
+		&decidedAt, &task.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if orgID.Valid {
+		task.OrgID = &orgID.Int64
+	}
+	if delegatedFrom.Valid {
+		task.DelegatedFrom = &delegatedFrom.Int64
+	}
+	if escalatedFrom.Valid {
+		task.EscalatedFrom = &escalatedFrom.Int64
+	}
+	if dueAt.Valid {
+		task.DueAt = &dueAt.Time
+	}
+	if decidedAt.Valid {
+		task.DecidedAt = &decidedAt.Time
+	}
+	task.Comment = comment.String
+	return &task, nil
+}
+
+// scanApprovalDelegation 扫描一行审批委托
+func (r *approvalRepo) scanApprovalDelegation(row rowScanner) (*biz.ApprovalDelegation, error) {
+	var delegation biz.ApprovalDelegation
+	var docType, reason sql.NullString
+
+	err := row.Scan(&delegation.ID, &delegation.DelegatorID, &delegation.DelegateID, &docType,
+		&delegation.StartsAt, &delegation.EndsAt, &reason, &delegation.IsActive, &delegation.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	delegation.DocType = docType.String
+	delegation.Reason = reason.String
+	return &delegation, nil
+}
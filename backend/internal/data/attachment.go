@@ -0,0 +1,213 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// attachmentColumns 附件查询列，与scanAttachment的扫描顺序一致
+const attachmentColumns = `id, doc_type, doc_name, file_name, content_type, file_size, storage_key,
+		       COALESCE(thumbnail_key, ''), COALESCE(uploaded_by, 0), created_at`
+
+// attachmentRepo 附件仓储实现
+type attachmentRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewAttachmentRepo 创建附件仓储
+func NewAttachmentRepo(data *Data, logger log.Logger) biz.AttachmentRepo {
+	return &attachmentRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// CreateAttachment 登记附件，在文档级别的事务锁下检查附件数上限，避免并发上传超出上限；
+// 文档附件归属当前公司
+func (r *attachmentRepo) CreateAttachment(ctx context.Context, attachment *biz.Attachment, maxCount int) (*biz.Attachment, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	companyID := attachmentCompany(ctx, attachment.DocType)
+	if maxCount > 0 {
+		lockKey := fmt.Sprintf("attachments:%v:%s:%s", companyID, attachment.DocType, attachment.DocName)
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", lockKey); err != nil {
+			r.log.Errorf("failed to lock document attachments: %v", err)
+			return nil, err
+		}
+
+		var count int
+		err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM attachments WHERE company_id IS NOT DISTINCT FROM $1 AND doc_type = $2 AND doc_name = $3",
+			companyID, attachment.DocType, attachment.DocName).Scan(&count)
+		if err != nil {
+			r.log.Errorf("failed to count document attachments: %v", err)
+			return nil, err
+		}
+		if count >= maxCount {
+			return nil, biz.ErrAttachmentLimitExceeded
+		}
+	}
+
+	if err := insertAttachment(ctx, tx, companyID, attachment); err != nil {
+		r.log.Errorf("failed to create attachment: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// GetAttachment 按ID获取附件，不区分公司：下载链接的签名已限定附件，打开链接时可能没有公司上下文
+func (r *attachmentRepo) GetAttachment(ctx context.Context, id int64) (*biz.Attachment, error) {
+	query := "SELECT " + attachmentColumns + " FROM attachments WHERE id = $1"
+
+	attachment, err := scanAttachment(r.data.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrAttachmentNotFound
+		}
+		r.log.Errorf("failed to get attachment: %v", err)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// ListAttachments 获取文档附件，按上传时间排列
+func (r *attachmentRepo) ListAttachments(ctx context.Context, docType, docName string) ([]*biz.Attachment, error) {
+	query := "SELECT " + attachmentColumns + ` FROM attachments
+		WHERE company_id IS NOT DISTINCT FROM $1 AND doc_type = $2 AND doc_name = $3 ORDER BY created_at, id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, attachmentCompany(ctx, docType), docType, docName)
+	if err != nil {
+		r.log.Errorf("failed to list attachments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*biz.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan attachment: %v", err)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// DeleteAttachment 删除当前公司的文档附件或不属于任何公司的头像登记
+func (r *attachmentRepo) DeleteAttachment(ctx context.Context, id int64) error {
+	result, err := r.data.db.ExecContext(ctx,
+		"DELETE FROM attachments WHERE id = $1 AND (company_id IS NULL OR company_id = $2)", id, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to delete attachment: %v", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return biz.ErrAttachmentNotFound
+	}
+	return nil
+}
+
+// SetUserAvatar 在一个事务中替换用户头像登记并更新用户头像地址
+func (r *attachmentRepo) SetUserAvatar(ctx context.Context, userID int64, attachment *biz.Attachment, avatarURL string) ([]*biz.Attachment, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE users SET avatar_url = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL",
+		avatarURL, time.Now(), userID)
+	if err != nil {
+		r.log.Errorf("failed to update user avatar: %v", err)
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, biz.ErrUserNotFound
+	}
+
+	query := "DELETE FROM attachments WHERE company_id IS NULL AND doc_type = $1 AND doc_name = $2 RETURNING " + attachmentColumns
+	rows, err := tx.QueryContext(ctx, query, attachment.DocType, attachment.DocName)
+	if err != nil {
+		r.log.Errorf("failed to delete previous avatars: %v", err)
+		return nil, err
+	}
+	replaced := []*biz.Attachment{}
+	for rows.Next() {
+		old, err := scanAttachment(rows)
+		if err != nil {
+			rows.Close()
+			r.log.Errorf("failed to scan previous avatar: %v", err)
+			return nil, err
+		}
+		replaced = append(replaced, old)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := insertAttachment(ctx, tx, nil, attachment); err != nil {
+		r.log.Errorf("failed to create avatar attachment: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// insertAttachment 插入附件并回填ID和创建时间，companyID为nil表示不属于任何公司
+func insertAttachment(ctx context.Context, tx *sql.Tx, companyID interface{}, attachment *biz.Attachment) error {
+	query := `
+		INSERT INTO attachments (company_id, doc_type, doc_name, file_name, content_type, file_size, storage_key, thumbnail_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, 0))
+		RETURNING id, created_at`
+
+	return tx.QueryRowContext(ctx, query,
+		companyID, attachment.DocType, attachment.DocName, attachment.FileName, attachment.ContentType,
+		attachment.FileSize, attachment.StorageKey, attachment.ThumbnailKey, attachment.UploadedBy,
+	).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+// attachmentCompany 附件所属公司：用户头像在公司间共享，返回nil；文档附件属于当前公司
+func attachmentCompany(ctx context.Context, docType string) interface{} {
+	if docType == biz.UserAvatarDocType {
+		return nil
+	}
+	return biz.CompanyFromContext(ctx)
+}
+
+// scanAttachment 扫描附件行
+func scanAttachment(row rowScanner) (*biz.Attachment, error) {
+	var attachment biz.Attachment
+	err := row.Scan(
+		&attachment.ID, &attachment.DocType, &attachment.DocName, &attachment.FileName, &attachment.ContentType,
+		&attachment.FileSize, &attachment.StorageKey, &attachment.ThumbnailKey, &attachment.UploadedBy, &attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
@@ -25,16 +25,16 @@ func NewAuditRepo(data *Data, logger log.Logger) biz.AuditRepo {
 	}
 }
 
-// CreateOperationLog 创建操作日志
+// CreateOperationLog 创建操作日志，记录到当前公司；没有公司上下文的操作（如定时任务）记为共享日志
 func (r *auditRepo) CreateOperationLog(ctx context.Context, log *biz.OperationLog) error {
 	query := `
-		INSERT INTO operation_logs (user_id, username, action, resource, resource_id, description,
+		INSERT INTO operation_logs (company_id, user_id, username, action, resource, resource_id, description,
 		                           ip_address, user_agent, request_data, response_data, 
 		                           status, error_message, execution_time, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
 
 	_, err := r.data.db.ExecContext(ctx, query,
-		log.UserID, log.Username, log.Action, log.Resource, log.ResourceID,
+		biz.CompanyFromContext(ctx), log.UserID, log.Username, log.Action, log.Resource, log.ResourceID,
 		log.Description, log.IPAddress, log.UserAgent, log.RequestData,
 		log.ResponseData, log.Status, log.ErrorMessage, log.ExecutionTime,
 		log.CreatedAt,
@@ -48,7 +48,7 @@ func (r *auditRepo) CreateOperationLog(ctx context.Context, log *biz.OperationLo
 	return nil
 }
 
-// GetOperationLog 获取操作日志
+// GetOperationLog 获取当前公司或共享的操作日志
 func (r *auditRepo) GetOperationLog(ctx context.Context, id int32) (*biz.OperationLog, error) {
 	var log biz.OperationLog
 	var userID sql.NullInt32
@@ -57,9 +57,9 @@ func (r *auditRepo) GetOperationLog(ctx context.Context, id int32) (*biz.Operati
 		SELECT id, user_id, username, action, resource, resource_id, description,
 		       ip_address, user_agent, request_data, response_data,
 		       status, error_message, execution_time, created_at
-		FROM operation_logs WHERE id = $1`
+		FROM operation_logs WHERE id = $1 AND ` + auditCompanyCondition("$2")
 
-	err := r.data.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.data.db.QueryRowContext(ctx, query, id, biz.CompanyFromContext(ctx)).Scan(
 		&log.ID, &userID, &log.Username, &log.Action, &log.Resource,
 		&log.ResourceID, &log.Description, &log.IPAddress, &log.UserAgent,
 		&log.RequestData, &log.ResponseData, &log.Status, &log.ErrorMessage,
@@ -81,16 +81,16 @@ func (r *auditRepo) GetOperationLog(ctx context.Context, id int32) (*biz.Operati
 	return &log, nil
 }
 
-// ListOperationLogs 操作日志列表
+// ListOperationLogs 当前公司和共享的操作日志列表
 func (r *auditRepo) ListOperationLogs(ctx context.Context, req *biz.OperationLogListRequest) ([]*biz.OperationLog, int32, error) {
 	offset := (req.Page - 1) * req.Size
 	var logs []*biz.OperationLog
 	var total int32
 
 	// 构建查询条件
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argIndex := 1
+	whereClause := "WHERE " + auditCompanyCondition("$1")
+	args := []interface{}{biz.CompanyFromContext(ctx)}
+	argIndex := 2
 
 	if req.UserID != nil {
 		whereClause += fmt.Sprintf(" AND user_id = $%d", argIndex)
@@ -185,11 +185,11 @@ func (r *auditRepo) ListOperationLogs(ctx context.Context, req *biz.OperationLog
 	return logs, total, nil
 }
 
-// DeleteOperationLogs 删除操作日志
+// DeleteOperationLogs 删除当前公司和共享的操作日志
 func (r *auditRepo) DeleteOperationLogs(ctx context.Context, beforeTime time.Time) (int64, error) {
-	query := "DELETE FROM operation_logs WHERE created_at < $1"
+	query := "DELETE FROM operation_logs WHERE created_at < $1 AND " + auditCompanyCondition("$2")
 
-	result, err := r.data.db.ExecContext(ctx, query, beforeTime)
+	result, err := r.data.db.ExecContext(ctx, query, beforeTime, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to delete operation logs: %v", err)
 		return 0, err
@@ -199,45 +199,47 @@ func (r *auditRepo) DeleteOperationLogs(ctx context.Context, beforeTime time.Tim
 	return affected, nil
 }
 
-// GetOperationStatistics 获取操作统计
+// GetOperationStatistics 获取当前公司和共享操作日志的统计
 func (r *auditRepo) GetOperationStatistics(ctx context.Context, startTime, endTime time.Time) (*biz.OperationStatistics, error) {
 	var stats biz.OperationStatistics
+	companyID := biz.CompanyFromContext(ctx)
+	inCompany := " AND " + auditCompanyCondition("$3")
 
 	// 总操作数
-	totalQuery := "SELECT COUNT(*) FROM operation_logs WHERE created_at BETWEEN $1 AND $2"
-	err := r.data.db.QueryRowContext(ctx, totalQuery, startTime, endTime).Scan(&stats.TotalOperations)
+	totalQuery := "SELECT COUNT(*) FROM operation_logs WHERE created_at BETWEEN $1 AND $2" + inCompany
+	err := r.data.db.QueryRowContext(ctx, totalQuery, startTime, endTime, companyID).Scan(&stats.TotalOperations)
 	if err != nil {
 		r.log.Errorf("failed to get total operations: %v", err)
 		return nil, err
 	}
 
 	// 成功操作数
-	successQuery := "SELECT COUNT(*) FROM operation_logs WHERE created_at BETWEEN $1 AND $2 AND status = 'success'"
-	err = r.data.db.QueryRowContext(ctx, successQuery, startTime, endTime).Scan(&stats.SuccessOperations)
+	successQuery := "SELECT COUNT(*) FROM operation_logs WHERE created_at BETWEEN $1 AND $2 AND status = 'success'" + inCompany
+	err = r.data.db.QueryRowContext(ctx, successQuery, startTime, endTime, companyID).Scan(&stats.SuccessOperations)
 	if err != nil {
 		r.log.Errorf("failed to get success operations: %v", err)
 		return nil, err
 	}
 
 	// 失败操作数
-	failedQuery := "SELECT COUNT(*) FROM operation_logs WHERE created_at BETWEEN $1 AND $2 AND status = 'failed'"
-	err = r.data.db.QueryRowContext(ctx, failedQuery, startTime, endTime).Scan(&stats.FailedOperations)
+	failedQuery := "SELECT COUNT(*) FROM operation_logs WHERE created_at BETWEEN $1 AND $2 AND status = 'failed'" + inCompany
+	err = r.data.db.QueryRowContext(ctx, failedQuery, startTime, endTime, companyID).Scan(&stats.FailedOperations)
 	if err != nil {
 		r.log.Errorf("failed to get failed operations: %v", err)
 		return nil, err
 	}
 
 	// 平均执行时间
-	avgTimeQuery := "SELECT COALESCE(AVG(execution_time), 0) FROM operation_logs WHERE created_at BETWEEN $1 AND $2"
-	err = r.data.db.QueryRowContext(ctx, avgTimeQuery, startTime, endTime).Scan(&stats.AverageExecutionTime)
+	avgTimeQuery := "SELECT COALESCE(AVG(execution_time), 0) FROM operation_logs WHERE created_at BETWEEN $1 AND $2" + inCompany
+	err = r.data.db.QueryRowContext(ctx, avgTimeQuery, startTime, endTime, companyID).Scan(&stats.AverageExecutionTime)
 	if err != nil {
 		r.log.Errorf("failed to get average execution time: %v", err)
 		return nil, err
 	}
 
 	// 活跃用户数
-	activeUsersQuery := "SELECT COUNT(DISTINCT user_id) FROM operation_logs WHERE created_at BETWEEN $1 AND $2 AND user_id IS NOT NULL"
-	err = r.data.db.QueryRowContext(ctx, activeUsersQuery, startTime, endTime).Scan(&stats.ActiveUsers)
+	activeUsersQuery := "SELECT COUNT(DISTINCT user_id) FROM operation_logs WHERE created_at BETWEEN $1 AND $2 AND user_id IS NOT NULL" + inCompany
+	err = r.data.db.QueryRowContext(ctx, activeUsersQuery, startTime, endTime, companyID).Scan(&stats.ActiveUsers)
 	if err != nil {
 		r.log.Errorf("failed to get active users: %v", err)
 		return nil, err
@@ -247,11 +249,11 @@ func (r *auditRepo) GetOperationStatistics(ctx context.Context, startTime, endTi
 	actionQuery := `
 		SELECT action, COUNT(*) as count 
 		FROM operation_logs 
-		WHERE created_at BETWEEN $1 AND $2 
+		WHERE created_at BETWEEN $1 AND $2` + inCompany + `
 		GROUP BY action 
 		ORDER BY count DESC`
 
-	rows, err := r.data.db.QueryContext(ctx, actionQuery, startTime, endTime)
+	rows, err := r.data.db.QueryContext(ctx, actionQuery, startTime, endTime, companyID)
 	if err != nil {
 		r.log.Errorf("failed to get action distribution: %v", err)
 		return nil, err
@@ -272,18 +274,18 @@ func (r *auditRepo) GetOperationStatistics(ctx context.Context, startTime, endTi
 	return &stats, nil
 }
 
-// GetTopActiveUsers 获取最活跃用户
+// GetTopActiveUsers 按当前公司和共享的操作日志获取最活跃用户
 func (r *auditRepo) GetTopActiveUsers(ctx context.Context, startTime, endTime time.Time, limit int32) ([]*biz.UserActivity, error) {
 	query := `
 		SELECT u.id, u.username, u.email, COUNT(ol.id) as operation_count
 		FROM users u
 		INNER JOIN operation_logs ol ON u.id = ol.user_id
-		WHERE ol.created_at BETWEEN $1 AND $2
+		WHERE ol.created_at BETWEEN $1 AND $2 AND ` + auditCompanyCondition("$4") + `
 		GROUP BY u.id, u.username, u.email
 		ORDER BY operation_count DESC
 		LIMIT $3`
 
-	rows, err := r.data.db.QueryContext(ctx, query, startTime, endTime, limit)
+	rows, err := r.data.db.QueryContext(ctx, query, startTime, endTime, limit, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to get top active users: %v", err)
 		return nil, err
@@ -303,3 +305,9 @@ func (r *auditRepo) GetTopActiveUsers(ctx context.Context, startTime, endTime ti
 
 	return activities, nil
 }
+
+// auditCompanyCondition 操作日志的公司条件：当前公司的日志和不属于任何公司的共享日志，
+// placeholder为当前公司ID的参数占位符
+func auditCompanyCondition(placeholder string) string {
+	return "(company_id = " + placeholder + " OR company_id IS NULL)"
+}
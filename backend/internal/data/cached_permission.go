@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"erp-system/internal/biz"
@@ -29,7 +30,7 @@ type CachedPermissionRepo struct {
 func NewCachedPermissionRepo(repo biz.PermissionRepo, cache cache.PermissionCache, logger log.Logger) biz.PermissionRepo {
 	return &CachedPermissionRepo{
 		repo:  repo,
-		cache: cache,
+		cache: newTxAwareCache(cache, logger),
 		log:   log.NewHelper(logger),
 
 		// 设置默认缓存TTL
@@ -42,6 +43,24 @@ func NewCachedPermissionRepo(repo biz.PermissionRepo, cache cache.PermissionCach
 	}
 }
 
+// NewPermissionCache 创建权限缓存，Redis不可用时退回进程内缓存
+func NewPermissionCache(data *Data, logger log.Logger) cache.PermissionCache {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := data.redis.Ping(ctx).Err(); err != nil {
+		log.NewHelper(logger).Warnf("Redis unavailable, using in-memory permission cache: %v", err)
+		return cache.NewMemoryPermissionCache(logger)
+	}
+	return cache.NewRedisPermissionCache(data.redis, logger)
+}
+
+// companyDocType 权限规则和用户分级权限按公司隔离，缓存键在文档类型前加上当前公司；
+// 以文档类型结尾的缓存键仍能按文档类型整体清除
+func companyDocType(ctx context.Context, docType string) string {
+	return fmt.Sprintf("%d:%s", biz.CompanyFromContext(ctx), docType)
+}
+
 // 文档类型管理 - 带缓存
 func (r *CachedPermissionRepo) CreateDocType(ctx context.Context, docType *biz.DocType) (*biz.DocType, error) {
 	result, err := r.repo.CreateDocType(ctx, docType)
@@ -131,18 +150,54 @@ func (r *CachedPermissionRepo) CreatePermissionRule(ctx context.Context, rule *b
 	}
 
 	// 清除相关角色的权限规则缓存
-	if err := r.cache.DeletePermissionRules(ctx, result.RoleID, result.DocType); err != nil {
+	if err := r.cache.DeletePermissionRules(ctx, result.RoleID, companyDocType(ctx, result.DocType)); err != nil {
 		r.log.Warnf("Failed to clear permission rule cache for role %d doctype %s: %v", result.RoleID, result.DocType, err)
 	}
 
 	// 清除角色相关缓存
-	if err := r.cache.ClearRoleCache(ctx, result.RoleID); err != nil {
-		r.log.Warnf("Failed to clear role cache for role %d: %v", result.RoleID, err)
+	r.clearRoleCache(ctx, result.RoleID)
+
+	// 清除该文档类型下用户的分级权限缓存
+	if err := r.cache.ClearDocTypeCache(ctx, companyDocType(ctx, result.DocType)); err != nil {
+		r.log.Warnf("Failed to clear doctype cache for %s: %v", result.DocType, err)
 	}
 
 	return result, nil
 }
 
+// clearRoleCache 清除角色及其全部下级角色的缓存，下级角色通过继承共享该角色的规则；
+// 持有这些角色的用户的角色列表和分级权限随之变化，其用户缓存一并清除
+func (r *CachedPermissionRepo) clearRoleCache(ctx context.Context, roleID int64) {
+	if err := r.ClearRolePermissionCache(ctx, roleID); err != nil {
+		r.log.Warnf("Failed to clear role cache for role %d: %v", roleID, err)
+	}
+}
+
+// ClearRolePermissionCache 清除角色、其全部下级角色及持有这些角色的用户的权限缓存
+func (r *CachedPermissionRepo) ClearRolePermissionCache(ctx context.Context, roleID int64) error {
+	descendants, err := r.repo.GetDescendantRoleIDs(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	roleIDs := append([]int64{roleID}, descendants...)
+
+	for _, id := range roleIDs {
+		if err := r.cache.ClearRoleCache(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	holders, err := r.repo.ListRoleHolderIDs(ctx, roleIDs)
+	if err != nil {
+		return err
+	}
+	return r.ClearUserPermissionCache(ctx, holders...)
+}
+
+func (r *CachedPermissionRepo) ListRoleHolderIDs(ctx context.Context, roleIDs []int64) ([]int64, error) {
+	return r.repo.ListRoleHolderIDs(ctx, roleIDs)
+}
+
 func (r *CachedPermissionRepo) GetPermissionRule(ctx context.Context, id int64) (*biz.PermissionRule, error) {
 	// 单个权限规则不缓存，因为通常通过角色+文档类型查询
 	return r.repo.GetPermissionRule(ctx, id)
@@ -150,7 +205,7 @@ func (r *CachedPermissionRepo) GetPermissionRule(ctx context.Context, id int64)
 
 func (r *CachedPermissionRepo) ListPermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.PermissionRule, error) {
 	// 先从缓存获取
-	cached, err := r.cache.GetPermissionRules(ctx, roleID, docType)
+	cached, err := r.cache.GetPermissionRules(ctx, roleID, companyDocType(ctx, docType))
 	if err != nil {
 		r.log.Warnf("Failed to get permission rules from cache for role %d doctype %s: %v", roleID, docType, err)
 	} else if cached != nil {
@@ -164,13 +219,22 @@ func (r *CachedPermissionRepo) ListPermissionRules(ctx context.Context, roleID i
 	}
 
 	// 缓存结果
-	if err := r.cache.SetPermissionRules(ctx, roleID, docType, rules, r.permissionRuleTTL); err != nil {
+	if err := r.cache.SetPermissionRules(ctx, roleID, companyDocType(ctx, docType), rules, r.permissionRuleTTL); err != nil {
 		r.log.Warnf("Failed to cache permission rules for role %d doctype %s: %v", roleID, docType, err)
 	}
 
 	return rules, nil
 }
 
+func (r *CachedPermissionRepo) ListEffectivePermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.EffectivePermissionRule, error) {
+	// 生效规则随继承链变化，不缓存
+	return r.repo.ListEffectivePermissionRules(ctx, roleID, docType)
+}
+
+func (r *CachedPermissionRepo) GetDescendantRoleIDs(ctx context.Context, roleID int64) ([]int64, error) {
+	return r.repo.GetDescendantRoleIDs(ctx, roleID)
+}
+
 func (r *CachedPermissionRepo) UpdatePermissionRule(ctx context.Context, rule *biz.PermissionRule) (*biz.PermissionRule, error) {
 	result, err := r.repo.UpdatePermissionRule(ctx, rule)
 	if err != nil {
@@ -178,13 +242,16 @@ func (r *CachedPermissionRepo) UpdatePermissionRule(ctx context.Context, rule *b
 	}
 
 	// 清除相关缓存
-	if err := r.cache.DeletePermissionRules(ctx, result.RoleID, result.DocType); err != nil {
+	if err := r.cache.DeletePermissionRules(ctx, result.RoleID, companyDocType(ctx, result.DocType)); err != nil {
 		r.log.Warnf("Failed to clear permission rule cache for role %d doctype %s: %v", result.RoleID, result.DocType, err)
 	}
 
 	// 清除角色相关缓存
-	if err := r.cache.ClearRoleCache(ctx, result.RoleID); err != nil {
-		r.log.Warnf("Failed to clear role cache for role %d: %v", result.RoleID, err)
+	r.clearRoleCache(ctx, result.RoleID)
+
+	// 清除该文档类型下用户的分级权限缓存
+	if err := r.cache.ClearDocTypeCache(ctx, companyDocType(ctx, result.DocType)); err != nil {
+		r.log.Warnf("Failed to clear doctype cache for %s: %v", result.DocType, err)
 	}
 
 	return result, nil
@@ -205,12 +272,14 @@ func (r *CachedPermissionRepo) DeletePermissionRule(ctx context.Context, id int6
 
 	// 清除相关缓存
 	if rule != nil {
-		if err := r.cache.DeletePermissionRules(ctx, rule.RoleID, rule.DocType); err != nil {
+		if err := r.cache.DeletePermissionRules(ctx, rule.RoleID, companyDocType(ctx, rule.DocType)); err != nil {
 			r.log.Warnf("Failed to clear permission rule cache for role %d doctype %s: %v", rule.RoleID, rule.DocType, err)
 		}
 
-		if err := r.cache.ClearRoleCache(ctx, rule.RoleID); err != nil {
-			r.log.Warnf("Failed to clear role cache for role %d: %v", rule.RoleID, err)
+		r.clearRoleCache(ctx, rule.RoleID)
+
+		if err := r.cache.ClearDocTypeCache(ctx, companyDocType(ctx, rule.DocType)); err != nil {
+			r.log.Warnf("Failed to clear doctype cache for %s: %v", rule.DocType, err)
 		}
 	}
 
@@ -385,7 +454,7 @@ func (r *CachedPermissionRepo) CheckPermission(ctx context.Context, userID int64
 
 func (r *CachedPermissionRepo) GetUserPermissionLevel(ctx context.Context, userID int64, documentType string) (int, error) {
 	// 先从缓存获取
-	level, err := r.cache.GetUserPermissionLevel(ctx, userID, documentType)
+	level, err := r.cache.GetUserPermissionLevel(ctx, userID, companyDocType(ctx, documentType))
 	if err != nil {
 		r.log.Warnf("Failed to get user permission level from cache for user %d doctype %s: %v", userID, documentType, err)
 	} else if level >= 0 {
@@ -399,26 +468,95 @@ func (r *CachedPermissionRepo) GetUserPermissionLevel(ctx context.Context, userI
 	}
 
 	// 缓存结果
-	if err := r.cache.SetUserPermissionLevel(ctx, userID, documentType, level, r.userPermissionLevelTTL); err != nil {
+	if err := r.cache.SetUserPermissionLevel(ctx, userID, companyDocType(ctx, documentType), level, r.userCacheTTL(ctx, userID, r.userPermissionLevelTTL)); err != nil {
 		r.log.Warnf("Failed to cache user permission level for user %d doctype %s: %v", userID, documentType, err)
 	}
 
 	return level, nil
 }
 
+func (r *CachedPermissionRepo) GetUserLevelPermissions(ctx context.Context, userID int64, documentType string) (*biz.LevelPermissions, error) {
+	// 先从缓存获取
+	cached, err := r.cache.GetUserLevelPermissions(ctx, userID, companyDocType(ctx, documentType))
+	if err != nil {
+		r.log.Warnf("Failed to get user level permissions from cache for user %d doctype %s: %v", userID, documentType, err)
+	} else if cached != nil {
+		return cached, nil
+	}
+
+	// 缓存未命中，从数据库获取
+	perms, err := r.repo.GetUserLevelPermissions(ctx, userID, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	// 缓存结果
+	if err := r.cache.SetUserLevelPermissions(ctx, userID, companyDocType(ctx, documentType), perms, r.userCacheTTL(ctx, userID, r.userPermissionLevelTTL)); err != nil {
+		r.log.Warnf("Failed to cache user level permissions for user %d doctype %s: %v", userID, documentType, err)
+	}
+
+	return perms, nil
+}
+
+// getFieldLevelMap 获取文档类型的字段权限级别映射 - 带缓存
+func (r *CachedPermissionRepo) getFieldLevelMap(ctx context.Context, documentType string) (map[string]int, error) {
+	cached, err := r.cache.GetFieldPermissionLevels(ctx, documentType)
+	if err != nil {
+		r.log.Warnf("Failed to get field permission levels from cache for doctype %s: %v", documentType, err)
+	} else if cached != nil {
+		return cached, nil
+	}
+
+	fieldPermissions, err := biz.ListAllFieldPermissionLevels(ctx, r.repo, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldLevels := make(map[string]int, len(fieldPermissions))
+	for _, fp := range fieldPermissions {
+		fieldLevels[fp.FieldName] = fp.PermissionLevel
+	}
+
+	if err := r.cache.SetFieldPermissionLevels(ctx, documentType, fieldLevels, r.fieldPermissionTTL); err != nil {
+		r.log.Warnf("Failed to cache field permission levels for doctype %s: %v", documentType, err)
+	}
+
+	return fieldLevels, nil
+}
+
 func (r *CachedPermissionRepo) GetUserEnhancedPermissions(ctx context.Context, userID int64, docType string) ([]*biz.EnhancedUserPermission, error) {
 	// 增强用户权限不缓存，因为结构复杂且使用频率不高
 	return r.repo.GetUserEnhancedPermissions(ctx, userID, docType)
 }
 
 func (r *CachedPermissionRepo) GetAccessibleFields(ctx context.Context, req *biz.FieldPermissionRequest) ([]*biz.AccessibleField, error) {
-	// 字段访问权限不缓存，因为参数复杂
-	return r.repo.GetAccessibleFields(ctx, req)
+	// 结果不缓存，但复用缓存的分级权限和字段级别
+	levelPerms, err := r.GetUserLevelPermissions(ctx, req.UserID, req.DocType)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldLevels, err := r.getFieldLevelMap(ctx, req.DocType)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildAccessibleFields(levelPerms, fieldLevels, req.Permission), nil
 }
 
 func (r *CachedPermissionRepo) FilterDocumentsByPermission(ctx context.Context, userID int64, documentType string, documents []map[string]interface{}) ([]map[string]interface{}, error) {
-	// 文档过滤不缓存，因为数据动态性强
-	return r.repo.FilterDocumentsByPermission(ctx, userID, documentType, documents)
+	// 文档过滤结果不缓存，因为数据动态性强，但复用缓存的分级权限和字段级别
+	levelPerms, err := r.GetUserLevelPermissions(ctx, userID, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldLevels, err := r.getFieldLevelMap(ctx, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterDocumentFields(levelPerms, fieldLevels, documents), nil
 }
 
 func (r *CachedPermissionRepo) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
@@ -437,13 +575,52 @@ func (r *CachedPermissionRepo) GetUserRoles(ctx context.Context, userID int64) (
 	}
 
 	// 缓存结果
-	if err := r.cache.SetUserRoles(ctx, userID, roles, r.userRoleTTL); err != nil {
+	if err := r.cache.SetUserRoles(ctx, userID, roles, r.userCacheTTL(ctx, userID, r.userRoleTTL)); err != nil {
 		r.log.Warnf("Failed to cache user roles for user %d: %v", userID, err)
 	}
 
 	return roles, nil
 }
 
+// ClearUserPermissionCache 清除用户的全部权限缓存
+func (r *CachedPermissionRepo) ClearUserPermissionCache(ctx context.Context, userIDs ...int64) error {
+	var firstErr error
+	for _, userID := range userIDs {
+		if err := r.cache.ClearUserCache(ctx, userID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *CachedPermissionRepo) GetNextRoleAssignmentChange(ctx context.Context, userID int64, now time.Time) (*time.Time, error) {
+	return r.repo.GetNextRoleAssignmentChange(ctx, userID, now)
+}
+
+// userCacheTTL 用户相关缓存的TTL不超过其下一次角色分配生效或失效的时间，确保到期的角色分配立即失去作用
+func (r *CachedPermissionRepo) userCacheTTL(ctx context.Context, userID int64, ttl time.Duration) time.Duration {
+	now := time.Now()
+	next, err := r.repo.GetNextRoleAssignmentChange(ctx, userID, now)
+	if err != nil {
+		r.log.Warnf("Failed to get next role assignment change for user %d: %v", userID, err)
+		return ttl
+	}
+
+	return capTTL(ttl, now, next)
+}
+
+// capTTL 将TTL截断到指定时间点之前
+func capTTL(ttl time.Duration, now time.Time, until *time.Time) time.Duration {
+	if until == nil {
+		return ttl
+	}
+
+	if remaining := until.Sub(now); remaining > 0 && remaining < ttl {
+		return remaining
+	}
+	return ttl
+}
+
 // 批量操作 - 清除相关缓存
 func (r *CachedPermissionRepo) BatchCreatePermissionRules(ctx context.Context, rules []*biz.PermissionRule) error {
 	err := r.repo.BatchCreatePermissionRules(ctx, rules)
@@ -451,7 +628,59 @@ func (r *CachedPermissionRepo) BatchCreatePermissionRules(ctx context.Context, r
 		return err
 	}
 
-	// 清除相关缓存
+	r.clearRuleCaches(ctx, rules)
+	return nil
+}
+
+func (r *CachedPermissionRepo) UpsertPermissionRules(ctx context.Context, rules []*biz.PermissionRule) error {
+	if err := r.repo.UpsertPermissionRules(ctx, rules); err != nil {
+		return err
+	}
+
+	r.clearRuleCaches(ctx, rules)
+	return nil
+}
+
+func (r *CachedPermissionRepo) ListRoleCodes(ctx context.Context) (map[int64]string, error) {
+	return r.repo.ListRoleCodes(ctx)
+}
+
+func (r *CachedPermissionRepo) ReplacePermissionRules(ctx context.Context, upserts, removals []*biz.PermissionRule) error {
+	if err := r.repo.ReplacePermissionRules(ctx, upserts, removals); err != nil {
+		return err
+	}
+
+	r.clearRuleCaches(ctx, append(append([]*biz.PermissionRule(nil), upserts...), removals...))
+	return nil
+}
+
+// RestorePermissionSnapshot 恢复快照后规则和用户权限整体变化，清除全部权限缓存
+func (r *CachedPermissionRepo) RestorePermissionSnapshot(ctx context.Context, snapshot *biz.PermissionSnapshot) error {
+	if err := r.repo.RestorePermissionSnapshot(ctx, snapshot); err != nil {
+		return err
+	}
+
+	if err := r.cache.ClearAllPermissionCache(ctx); err != nil {
+		r.log.Warnf("Failed to clear permission cache after restore: %v", err)
+	}
+	return nil
+}
+
+// 条件权限 - 规则条件和用户属性不缓存，结果依赖文档属性
+func (r *CachedPermissionRepo) GetConditionFieldTypes(ctx context.Context, docType string) (map[string]biz.ConditionType, error) {
+	return r.repo.GetConditionFieldTypes(ctx, docType)
+}
+
+func (r *CachedPermissionRepo) ListUserPermissionRules(ctx context.Context, userID int64, docType string, permissionLevel int) ([]*biz.PermissionRule, error) {
+	return r.repo.ListUserPermissionRules(ctx, userID, docType, permissionLevel)
+}
+
+func (r *CachedPermissionRepo) GetUserConditionAttributes(ctx context.Context, userID int64) (map[string]interface{}, error) {
+	return r.repo.GetUserConditionAttributes(ctx, userID)
+}
+
+// clearRuleCaches 清除规则涉及的角色及文档类型缓存
+func (r *CachedPermissionRepo) clearRuleCaches(ctx context.Context, rules []*biz.PermissionRule) {
 	roleDocTypeMap := make(map[int64]map[string]bool)
 	for _, rule := range rules {
 		if roleDocTypeMap[rule.RoleID] == nil {
@@ -462,19 +691,18 @@ func (r *CachedPermissionRepo) BatchCreatePermissionRules(ctx context.Context, r
 
 	for roleID, docTypes := range roleDocTypeMap {
 		// 清除角色缓存
-		if err := r.cache.ClearRoleCache(ctx, roleID); err != nil {
-			r.log.Warnf("Failed to clear role cache for role %d: %v", roleID, err)
-		}
+		r.clearRoleCache(ctx, roleID)
 
 		// 清除权限规则缓存
 		for docType := range docTypes {
-			if err := r.cache.DeletePermissionRules(ctx, roleID, docType); err != nil {
+			if err := r.cache.DeletePermissionRules(ctx, roleID, companyDocType(ctx, docType)); err != nil {
 				r.log.Warnf("Failed to clear permission rule cache for role %d doctype %s: %v", roleID, docType, err)
 			}
+			if err := r.cache.ClearDocTypeCache(ctx, companyDocType(ctx, docType)); err != nil {
+				r.log.Warnf("Failed to clear doctype cache for %s: %v", docType, err)
+			}
 		}
 	}
-
-	return nil
 }
 
 func (r *CachedPermissionRepo) BatchCreateUserPermissions(ctx context.Context, permissions []*biz.UserPermission) error {
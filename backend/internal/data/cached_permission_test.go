@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/cache"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapTTL(t *testing.T) {
+	now := time.Now()
+	soon := now.Add(5 * time.Minute)
+	later := now.Add(2 * time.Hour)
+
+	assert.Equal(t, 30*time.Minute, capTTL(30*time.Minute, now, nil))
+	assert.Equal(t, 5*time.Minute, capTTL(30*time.Minute, now, &soon))
+	assert.Equal(t, 30*time.Minute, capTTL(30*time.Minute, now, &later))
+}
+
+// stubCachedPermissionRepo 记录查询次数，用于判断是否命中缓存
+type stubCachedPermissionRepo struct {
+	biz.PermissionRepo
+	rules       []*biz.PermissionRule
+	descendants map[int64][]int64
+	holders     map[int64][]int64
+	ruleReads   int
+	roleReads   int
+}
+
+func (r *stubCachedPermissionRepo) ListPermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.PermissionRule, error) {
+	r.ruleReads++
+	var result []*biz.PermissionRule
+	for _, rule := range r.rules {
+		if rule.RoleID == roleID && rule.DocType == docType {
+			result = append(result, rule)
+		}
+	}
+	return result, nil
+}
+
+func (r *stubCachedPermissionRepo) CreatePermissionRule(ctx context.Context, rule *biz.PermissionRule) (*biz.PermissionRule, error) {
+	rule.ID = int64(len(r.rules) + 1)
+	r.rules = append(r.rules, rule)
+	return rule, nil
+}
+
+func (r *stubCachedPermissionRepo) GetDescendantRoleIDs(ctx context.Context, roleID int64) ([]int64, error) {
+	return r.descendants[roleID], nil
+}
+
+func (r *stubCachedPermissionRepo) ListRoleHolderIDs(ctx context.Context, roleIDs []int64) ([]int64, error) {
+	var userIDs []int64
+	for _, roleID := range roleIDs {
+		userIDs = append(userIDs, r.holders[roleID]...)
+	}
+	return userIDs, nil
+}
+
+func (r *stubCachedPermissionRepo) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	r.roleReads++
+	return []string{"SALES"}, nil
+}
+
+func (r *stubCachedPermissionRepo) GetNextRoleAssignmentChange(ctx context.Context, userID int64, now time.Time) (*time.Time, error) {
+	return nil, nil
+}
+
+func TestPermissionRepoChain(t *testing.T) {
+	base := &stubCachedPermissionRepo{rules: []*biz.PermissionRule{{ID: 1, RoleID: 1, DocType: "Order", CanRead: true}}}
+	versions := &stubPermissionVersionRepo{}
+	repo := newPermissionRepoChain(base, cache.NewMemoryPermissionCache(log.DefaultLogger), versions, &stubTransaction{}, log.DefaultLogger)
+	ctx := context.Background()
+
+	// 重复查询命中缓存
+	rules, err := repo.ListPermissionRules(ctx, 1, "Order")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+	_, err = repo.ListPermissionRules(ctx, 1, "Order")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, base.ruleReads)
+
+	roles, err := repo.GetUserRoles(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SALES"}, roles)
+	_, err = repo.GetUserRoles(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, base.roleReads)
+
+	// 规则变更经版本记录层后清除缓存，并记录版本
+	_, err = repo.CreatePermissionRule(ctx, &biz.PermissionRule{RoleID: 1, DocType: "Order", CanWrite: true})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, versions.versions)
+	assert.Equal(t, "create_permission_rule", versions.versions[len(versions.versions)-1].Operation)
+
+	rules, err = repo.ListPermissionRules(ctx, 1, "Order")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, 2, base.ruleReads)
+}
+
+func TestCachedPermissionRepo_ClearRolePermissionCache(t *testing.T) {
+	// 角色2继承角色1，用户9持有角色2
+	base := &stubCachedPermissionRepo{
+		descendants: map[int64][]int64{1: {2}},
+		holders:     map[int64][]int64{2: {9}},
+	}
+	repo := NewCachedPermissionRepo(base, cache.NewMemoryPermissionCache(log.DefaultLogger), log.DefaultLogger)
+	ctx := context.Background()
+
+	_, err := repo.GetUserRoles(ctx, 9)
+	assert.NoError(t, err)
+	_, err = repo.GetUserRoles(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, base.roleReads)
+
+	// 上级角色的缓存清除级联到下级角色的持有用户
+	assert.NoError(t, repo.ClearRolePermissionCache(ctx, 1))
+	_, err = repo.GetUserRoles(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, base.roleReads)
+}
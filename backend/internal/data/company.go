@@ -0,0 +1,389 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// companyColumns 公司查询列，与scanCompany的扫描顺序一致
+const companyColumns = `c.id, c.name, c.code, COALESCE(c.description, ''), c.is_enabled, c.created_at, c.updated_at`
+
+// copyPermissionRulesQuery 将模板公司的权限规则复制到新公司
+const copyPermissionRulesQuery = `
+	INSERT INTO permission_rules (company_id, role_id, doc_type, permission_level, can_read, can_write, can_create,
+	                              can_delete, can_submit, can_cancel, can_amend, can_print, can_email, can_import,
+	                              can_export, can_share, can_report, can_set_user_permissions, only_if_creator, condition, created_by, updated_by)
+	SELECT $1, role_id, doc_type, permission_level, can_read, can_write, can_create,
+	       can_delete, can_submit, can_cancel, can_amend, can_print, can_email, can_import,
+	       can_export, can_share, can_report, can_set_user_permissions, only_if_creator, condition, $3, $3
+	FROM permission_rules WHERE company_id = $2`
+
+// companyRepo 公司仓储实现
+type companyRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewCompanyRepo 创建公司仓储
+func NewCompanyRepo(data *Data, logger log.Logger) biz.CompanyRepo {
+	return &companyRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// scanCompany 扫描companyColumns
+func scanCompany(row interface{ Scan(...interface{}) error }) (*biz.Company, error) {
+	var company biz.Company
+	err := row.Scan(&company.ID, &company.Name, &company.Code, &company.Description, &company.IsEnabled,
+		&company.CreatedAt, &company.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// CreateCompany 创建公司并将创建人加入公司，templateID不为0时在同一事务中复制模板公司的权限规则
+func (r *companyRepo) CreateCompany(ctx context.Context, company *biz.Company, templateID, operatorID int32) (*biz.Company, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO companies (name, code, description, is_enabled, created_by, updated_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $5)
+		RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		company.Name, company.Code, company.Description, company.IsEnabled, operatorID,
+	).Scan(&company.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "uk_companies_code" {
+			return nil, biz.ErrCompanyCodeExists
+		}
+		r.log.Errorf("failed to create company: %v", err)
+		return nil, err
+	}
+
+	if templateID != 0 {
+		if _, err = tx.ExecContext(ctx, copyPermissionRulesQuery, company.ID, templateID, operatorID); err != nil {
+			r.log.Errorf("failed to copy permission rules to company: %v", err)
+			return nil, err
+		}
+	}
+
+	if operatorID != 0 {
+		if err = r.addUserCompanyTx(ctx, tx, operatorID, company.ID, operatorID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return nil, err
+	}
+
+	return r.GetCompany(ctx, company.ID)
+}
+
+// UpdateCompany 更新公司
+func (r *companyRepo) UpdateCompany(ctx context.Context, company *biz.Company) (*biz.Company, error) {
+	query := `
+		UPDATE companies SET name = $1, code = $2, description = NULLIF($3, ''), is_enabled = $4
+		WHERE id = $5`
+
+	result, err := r.data.db.ExecContext(ctx, query,
+		company.Name, company.Code, company.Description, company.IsEnabled, company.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "uk_companies_code" {
+			return nil, biz.ErrCompanyCodeExists
+		}
+		r.log.Errorf("failed to update company: %v", err)
+		return nil, err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, biz.ErrCompanyNotFound
+	}
+	return r.GetCompany(ctx, company.ID)
+}
+
+// GetCompany 获取公司
+func (r *companyRepo) GetCompany(ctx context.Context, id int32) (*biz.Company, error) {
+	company, err := scanCompany(r.data.db.QueryRowContext(ctx,
+		"SELECT "+companyColumns+" FROM companies c WHERE c.id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrCompanyNotFound
+		}
+		r.log.Errorf("failed to get company: %v", err)
+		return nil, err
+	}
+	return company, nil
+}
+
+// ListCompanies 获取全部公司
+func (r *companyRepo) ListCompanies(ctx context.Context) ([]*biz.Company, error) {
+	rows, err := r.data.db.QueryContext(ctx, "SELECT "+companyColumns+" FROM companies c ORDER BY c.id")
+	if err != nil {
+		r.log.Errorf("failed to list companies: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	companies := []*biz.Company{}
+	for rows.Next() {
+		company, err := scanCompany(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan company: %v", err)
+			return nil, err
+		}
+		companies = append(companies, company)
+	}
+	return companies, rows.Err()
+}
+
+// AddUserCompany 将用户加入公司，用户没有默认公司时该公司成为默认公司
+func (r *companyRepo) AddUserCompany(ctx context.Context, userID, companyID, operatorID int32) (*biz.UserCompany, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var active bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT is_enabled AND deleted_at IS NULL FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&active)
+	if err != nil && err != sql.ErrNoRows {
+		r.log.Errorf("failed to lock user: %v", err)
+		return nil, err
+	}
+	if !active {
+		return nil, biz.ErrCompanyMemberUser
+	}
+
+	if err = r.addUserCompanyTx(ctx, tx, userID, companyID, operatorID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return nil, err
+	}
+
+	return r.GetUserCompany(ctx, userID, companyID)
+}
+
+// addUserCompanyTx 在事务中将用户加入公司，已是成员时不做修改
+func (r *companyRepo) addUserCompanyTx(ctx context.Context, tx *sql.Tx, userID, companyID, operatorID int32) error {
+	query := `
+		INSERT INTO user_companies (user_id, company_id, is_default, created_by)
+		VALUES ($1, $2, NOT EXISTS (SELECT 1 FROM user_companies WHERE user_id = $1 AND is_default = TRUE), NULLIF($3, 0))
+		ON CONFLICT (user_id, company_id) DO NOTHING`
+
+	if _, err := tx.ExecContext(ctx, query, userID, companyID, operatorID); err != nil {
+		r.log.Errorf("failed to add user to company: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RemoveUserCompany 将用户移出公司，移出默认公司时由最早加入的其他公司接替；用户至少保留一个公司
+func (r *companyRepo) RemoveUserCompany(ctx context.Context, userID, companyID int32) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// 锁定用户的全部公司关系，避免并发移出导致用户没有公司或没有默认公司
+	rows, err := tx.QueryContext(ctx, `
+		SELECT company_id, is_default FROM user_companies
+		WHERE user_id = $1
+		ORDER BY created_at, id
+		FOR UPDATE`, userID)
+	if err != nil {
+		r.log.Errorf("failed to lock user companies: %v", err)
+		return err
+	}
+	var found, wasDefault bool
+	var next int32
+	for rows.Next() {
+		var id int32
+		var isDefault bool
+		if err := rows.Scan(&id, &isDefault); err != nil {
+			rows.Close()
+			r.log.Errorf("failed to scan user company: %v", err)
+			return err
+		}
+		if id == companyID {
+			found, wasDefault = true, isDefault
+		} else if next == 0 {
+			next = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !found {
+		return biz.ErrCompanyMemberNotFound
+	}
+	if next == 0 {
+		return biz.ErrLastUserCompany
+	}
+
+	if _, err = tx.ExecContext(ctx,
+		"DELETE FROM user_companies WHERE user_id = $1 AND company_id = $2", userID, companyID); err != nil {
+		r.log.Errorf("failed to remove user from company: %v", err)
+		return err
+	}
+
+	if wasDefault {
+		if _, err = tx.ExecContext(ctx,
+			"UPDATE user_companies SET is_default = TRUE WHERE user_id = $1 AND company_id = $2", userID, next); err != nil {
+			r.log.Errorf("failed to set default company: %v", err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+// SetDefaultCompany 设置用户的默认公司
+func (r *companyRepo) SetDefaultCompany(ctx context.Context, userID, companyID int32) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var isDefault bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT is_default FROM user_companies WHERE user_id = $1 AND company_id = $2 FOR UPDATE",
+		userID, companyID).Scan(&isDefault)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return biz.ErrCompanyMemberNotFound
+		}
+		r.log.Errorf("failed to check user company: %v", err)
+		return err
+	}
+	if isDefault {
+		return nil
+	}
+
+	// 先清除原默认公司，满足每个用户最多一个默认公司的唯一索引
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE user_companies SET is_default = FALSE
+		WHERE user_id = $1 AND is_default = TRUE AND company_id <> $2`, userID, companyID); err != nil {
+		r.log.Errorf("failed to clear default company: %v", err)
+		return err
+	}
+	if _, err = tx.ExecContext(ctx,
+		"UPDATE user_companies SET is_default = TRUE WHERE user_id = $1 AND company_id = $2", userID, companyID); err != nil {
+		r.log.Errorf("failed to set default company: %v", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetUserCompany 获取用户在公司中的成员关系
+func (r *companyRepo) GetUserCompany(ctx context.Context, userID, companyID int32) (*biz.UserCompany, error) {
+	memberships, err := r.listUserCompanies(ctx, "uc.user_id = $1 AND uc.company_id = $2", userID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memberships) == 0 {
+		return nil, biz.ErrCompanyMemberNotFound
+	}
+	return memberships[0], nil
+}
+
+// ListUserCompanies 获取用户所属的公司，默认公司在前
+func (r *companyRepo) ListUserCompanies(ctx context.Context, userID int32) ([]*biz.UserCompany, error) {
+	return r.listUserCompanies(ctx, "uc.user_id = $1", userID)
+}
+
+func (r *companyRepo) listUserCompanies(ctx context.Context, where string, args ...interface{}) ([]*biz.UserCompany, error) {
+	query := `
+		SELECT uc.user_id, uc.company_id, uc.is_default, uc.created_at, ` + companyColumns + `
+		FROM user_companies uc
+		INNER JOIN companies c ON c.id = uc.company_id
+		WHERE ` + where + `
+		ORDER BY uc.is_default DESC, c.id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Errorf("failed to list user companies: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []*biz.UserCompany{}
+	for rows.Next() {
+		var membership biz.UserCompany
+		var company biz.Company
+		err := rows.Scan(&membership.UserID, &membership.CompanyID, &membership.IsDefault, &membership.CreatedAt,
+			&company.ID, &company.Name, &company.Code, &company.Description, &company.IsEnabled,
+			&company.CreatedAt, &company.UpdatedAt)
+		if err != nil {
+			r.log.Errorf("failed to scan user company: %v", err)
+			return nil, err
+		}
+		membership.Company = &company
+		memberships = append(memberships, &membership)
+	}
+	return memberships, rows.Err()
+}
+
+// ListCompanyUsers 获取公司的有效用户
+func (r *companyRepo) ListCompanyUsers(ctx context.Context, companyID int32) ([]*biz.User, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.first_name, u.last_name, u.is_enabled, u.created_at, u.updated_at
+		FROM users u
+		INNER JOIN user_companies uc ON uc.user_id = u.id
+		WHERE uc.company_id = $1 AND u.deleted_at IS NULL
+		ORDER BY u.id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, companyID)
+	if err != nil {
+		r.log.Errorf("failed to list company users: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*biz.User{}
+	for rows.Next() {
+		var user biz.User
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.FirstName, &user.LastName,
+			&user.IsActive, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			r.log.Errorf("failed to scan company user: %v", err)
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
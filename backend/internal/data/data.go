@@ -16,7 +16,7 @@ import (
 )
 
 // ProviderSet is data providers.
-var ProviderSet = wire.NewSet(NewData, NewUserRepo, NewRoleRepo, NewPermissionRepo, NewOrganizationRepo, NewSessionRepo, NewAuditRepo)
+var ProviderSet = wire.NewSet(NewData, NewTransaction, NewUserRepo, NewRoleRepo, NewPermissionCache, ProvidePermissionRepo, NewPermissionVersionRepo, NewOrganizationRepo, NewSessionRepo, NewAuditRepo, NewPermissionTemplateRepo, NewRoleAssignmentRepo, NewSoDRepo, NewDocFieldRepo, NewDocumentRepo, NewNamingSeriesRepo, NewWorkflowRepo, NewApprovalRepo, NewCompanyRepo, NewUserImportRepo, NewUserFilterRepo, NewUserAdminRepo, NewRecycleBinRepo, NewAttachmentRepo, NewFileStorage, NewProfileRepo, NewSystemConfigRepo)
 
 // getProjectRoot 获取项目根目录路径
 func getProjectRoot() string {
@@ -0,0 +1,269 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// docFieldColumns 字段定义查询列，与scanDocField的扫描顺序一致
+const docFieldColumns = `id, doc_type, field_name, label, field_type, COALESCE(options, ''), is_mandatory, is_unique,
+		       COALESCE(default_value, ''), COALESCE(depends_on, ''), permission_level, idx,
+		       created_at, updated_at, created_by, updated_by`
+
+// docFieldRepo 字段定义仓储实现
+type docFieldRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewDocFieldRepo 创建字段定义仓储
+func NewDocFieldRepo(data *Data, logger log.Logger) biz.DocFieldRepo {
+	return &docFieldRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// ListDocFields 获取文档类型的字段定义
+func (r *docFieldRepo) ListDocFields(ctx context.Context, docType string) ([]*biz.DocField, error) {
+	rows, err := r.data.db.QueryContext(ctx,
+		"SELECT "+docFieldColumns+" FROM doc_fields WHERE doc_type = $1 ORDER BY idx, id", docType)
+	if err != nil {
+		r.log.Errorf("failed to list doc fields: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []*biz.DocField
+	for rows.Next() {
+		field, err := scanDocField(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan doc field: %v", err)
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate doc fields: %v", err)
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// GetDocField 获取字段定义
+func (r *docFieldRepo) GetDocField(ctx context.Context, docType, fieldName string) (*biz.DocField, error) {
+	row := r.data.db.QueryRowContext(ctx,
+		"SELECT "+docFieldColumns+" FROM doc_fields WHERE doc_type = $1 AND field_name = $2", docType, fieldName)
+	field, err := scanDocField(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrDocFieldNotFound
+		}
+		r.log.Errorf("failed to get doc field: %v", err)
+		return nil, err
+	}
+	return field, nil
+}
+
+// CreateDocField 创建字段定义并同步字段权限级别
+func (r *docFieldRepo) CreateDocField(ctx context.Context, field *biz.DocField) (*biz.DocField, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO doc_fields (doc_type, field_name, label, field_type, options, is_mandatory, is_unique,
+		                        default_value, depends_on, permission_level, idx, created_at, updated_at,
+		                        created_by, updated_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11, $12, $13, $14, $14)
+		RETURNING id`
+
+	err = tx.QueryRowContext(ctx, query,
+		field.DocType, field.FieldName, field.Label, field.FieldType, field.Options, field.IsMandatory,
+		field.IsUnique, field.DefaultValue, field.DependsOn, field.PermissionLevel, field.Idx,
+		field.CreatedAt, field.UpdatedAt, field.CreatedBy,
+	).Scan(&field.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, biz.ErrDocFieldExists
+		}
+		r.log.Errorf("failed to create doc field: %v", err)
+		return nil, err
+	}
+	field.UpdatedBy = field.CreatedBy
+
+	if err := upsertFieldPermissionLevel(ctx, tx, field); err != nil {
+		r.log.Errorf("failed to sync field permission level: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return nil, err
+	}
+
+	return field, nil
+}
+
+// UpdateDocField 更新字段定义并同步字段权限级别
+func (r *docFieldRepo) UpdateDocField(ctx context.Context, field *biz.DocField) (*biz.DocField, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE doc_fields
+		SET label = $1, field_type = $2, options = NULLIF($3, ''), is_mandatory = $4, is_unique = $5,
+		    default_value = NULLIF($6, ''), depends_on = NULLIF($7, ''), permission_level = $8, idx = $9,
+		    updated_at = $10, updated_by = $11
+		WHERE doc_type = $12 AND field_name = $13`
+
+	result, err := tx.ExecContext(ctx, query,
+		field.Label, field.FieldType, field.Options, field.IsMandatory, field.IsUnique,
+		field.DefaultValue, field.DependsOn, field.PermissionLevel, field.Idx,
+		field.UpdatedAt, field.UpdatedBy, field.DocType, field.FieldName,
+	)
+	if err != nil {
+		r.log.Errorf("failed to update doc field: %v", err)
+		return nil, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, biz.ErrDocFieldNotFound
+	}
+
+	if err := upsertFieldPermissionLevel(ctx, tx, field); err != nil {
+		r.log.Errorf("failed to sync field permission level: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return nil, err
+	}
+
+	return field, nil
+}
+
+// DeleteDocField 删除字段定义及其字段权限级别
+func (r *docFieldRepo) DeleteDocField(ctx context.Context, docType, fieldName string) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM doc_fields WHERE doc_type = $1 AND field_name = $2", docType, fieldName)
+	if err != nil {
+		r.log.Errorf("failed to delete doc field: %v", err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return biz.ErrDocFieldNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM field_permission_levels WHERE doc_type = $1 AND field_name = $2", docType, fieldName)
+	if err != nil {
+		r.log.Errorf("failed to delete field permission level: %v", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// SyncFieldPermissionLevels 按字段定义重写字段权限级别，并删除没有字段定义的级别；
+// 尚未登记字段的文档类型保留现有级别
+func (r *docFieldRepo) SyncFieldPermissionLevels(ctx context.Context, docType string) (int, error) {
+	fields, err := r.ListDocFields(ctx, docType)
+	if err != nil || len(fields) == 0 {
+		return 0, err
+	}
+
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if err := upsertFieldPermissionLevel(ctx, tx, field); err != nil {
+			r.log.Errorf("failed to sync field permission level: %v", err)
+			return 0, err
+		}
+		names = append(names, field.FieldName)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM field_permission_levels WHERE doc_type = $1 AND NOT (field_name = ANY($2))",
+		docType, pq.Array(names))
+	if err != nil {
+		r.log.Errorf("failed to delete stale field permission levels: %v", err)
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return 0, err
+	}
+
+	return len(fields), nil
+}
+
+// upsertFieldPermissionLevel 将字段定义写入字段权限级别
+func upsertFieldPermissionLevel(ctx context.Context, tx *sql.Tx, field *biz.DocField) error {
+	level := field.FieldPermissionLevel()
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO field_permission_levels (doc_type, field_name, field_label, permission_level, field_type,
+		                                     is_mandatory, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (doc_type, field_name) DO UPDATE
+		SET field_label = EXCLUDED.field_label, permission_level = EXCLUDED.permission_level,
+		    field_type = EXCLUDED.field_type, is_mandatory = EXCLUDED.is_mandatory,
+		    updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP`,
+		level.DocType, level.FieldName, level.FieldLabel, level.PermissionLevel, level.FieldType,
+		level.IsMandatory, field.UpdatedBy,
+	)
+	return err
+}
+
+// scanDocField 扫描一行字段定义
+func scanDocField(row rowScanner) (*biz.DocField, error) {
+	var field biz.DocField
+	var createdBy, updatedBy sql.NullInt64
+	err := row.Scan(
+		&field.ID, &field.DocType, &field.FieldName, &field.Label, &field.FieldType, &field.Options,
+		&field.IsMandatory, &field.IsUnique, &field.DefaultValue, &field.DependsOn,
+		&field.PermissionLevel, &field.Idx, &field.CreatedAt, &field.UpdatedAt, &createdBy, &updatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		field.CreatedBy = &createdBy.Int64
+	}
+	if updatedBy.Valid {
+		field.UpdatedBy = &updatedBy.Int64
+	}
+	return &field, nil
+}
@@ -0,0 +1,715 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// documentStandardColumns 文档数据表的标准列，与scanDocument的扫描顺序一致
+const documentStandardColumns = `t.id, t.name, t.created_at, t.updated_at, t.created_by, t.updated_by,
+		       COALESCE(s.docstatus, 0), COALESCE(s.workflow_state, 'Draft'), a.doc_name`
+
+// documentColumnSpec 字段类型对应的列定义和information_schema中的data_type
+type documentColumnSpec struct {
+	ddl      string
+	dataType string
+}
+
+// documentColumnType 字段类型映射为列类型，子表和地理位置以JSONB保存，其余文本类字段为TEXT
+func documentColumnType(fieldType string) documentColumnSpec {
+	switch fieldType {
+	case "Int", "Rating":
+		return documentColumnSpec{"BIGINT", "bigint"}
+	case "Float", "Percent", "Duration":
+		return documentColumnSpec{"DOUBLE PRECISION", "double precision"}
+	case "Currency":
+		return documentColumnSpec{"NUMERIC(18,6)", "numeric"}
+	case "Check":
+		return documentColumnSpec{"BOOLEAN", "boolean"}
+	case "Date":
+		return documentColumnSpec{"DATE", "date"}
+	case "Datetime":
+		return documentColumnSpec{"TIMESTAMP WITH TIME ZONE", "timestamp with time zone"}
+	case "Time":
+		return documentColumnSpec{"TIME", "time without time zone"}
+	case "Table", "Geolocation":
+		return documentColumnSpec{"JSONB", "jsonb"}
+	}
+	return documentColumnSpec{"TEXT", "text"}
+}
+
+// documentRepo 通用文档仓储实现
+type documentRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewDocumentRepo 创建通用文档仓储
+func NewDocumentRepo(data *Data, logger log.Logger) biz.DocumentRepo {
+	return &documentRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// GetDocumentTable 获取文档类型的数据表登记
+func (r *documentRepo) GetDocumentTable(ctx context.Context, docType string) (*biz.DocumentTable, error) {
+	var table biz.DocumentTable
+	err := r.data.db.QueryRowContext(ctx,
+		"SELECT doc_type, table_name, signature, synced_at FROM document_tables WHERE doc_type = $1", docType,
+	).Scan(&table.DocType, &table.TableName, &table.Signature, &table.SyncedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.log.Errorf("failed to get document table: %v", err)
+		return nil, err
+	}
+	return &table, nil
+}
+
+// SyncDocumentTable 在事务中创建或迁移数据表，同一文档类型的迁移通过咨询锁串行执行
+func (r *documentRepo) SyncDocumentTable(ctx context.Context, meta *biz.DocumentMeta, signature string) (*biz.DocumentTable, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	docType := meta.DocType.Name
+	if _, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "document_table:"+docType); err != nil {
+		r.log.Errorf("failed to lock document table: %v", err)
+		return nil, err
+	}
+
+	// 已登记的数据表沿用原名称，文档类型改名后数据表不变
+	var tableName string
+	err = tx.QueryRowContext(ctx, "SELECT table_name FROM document_tables WHERE doc_type = $1", docType).Scan(&tableName)
+	switch {
+	case err == sql.ErrNoRows:
+		tableName = meta.Table.TableName
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO document_tables (doc_type, table_name, signature) VALUES ($1, $2, '')", docType, tableName)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return nil, fmt.Errorf("%w: table %s is registered to another doctype", biz.ErrDocumentTableConflict, tableName)
+			}
+			r.log.Errorf("failed to register document table: %v", err)
+			return nil, err
+		}
+	case err != nil:
+		r.log.Errorf("failed to get document table: %v", err)
+		return nil, err
+	}
+
+	if err := r.migrateDocumentTable(ctx, tx, tableName, meta.Fields); err != nil {
+		return nil, err
+	}
+
+	table := &biz.DocumentTable{DocType: docType, TableName: tableName, Signature: signature}
+	err = tx.QueryRowContext(ctx,
+		"UPDATE document_tables SET signature = $1, synced_at = CURRENT_TIMESTAMP WHERE doc_type = $2 RETURNING synced_at",
+		signature, docType,
+	).Scan(&table.SyncedAt)
+	if err != nil {
+		r.log.Errorf("failed to update document table signature: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// migrateDocumentTable 创建数据表，补充缺少的列、调整类型不一致的列并维护唯一索引；
+// 文档按公司隔离，名称和唯一字段在公司内唯一；已删除字段的列保留，避免丢失数据
+func (r *documentRepo) migrateDocumentTable(ctx context.Context, tx *sql.Tx, tableName string, fields []*biz.DocField) error {
+	quotedTable := pq.QuoteIdentifier(tableName)
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+quotedTable+` (
+		    id BIGSERIAL PRIMARY KEY,
+		    company_id BIGINT NOT NULL REFERENCES companies(id),
+		    name VARCHAR(100) NOT NULL,
+		    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+		    created_by BIGINT REFERENCES users(id),
+		    updated_by BIGINT REFERENCES users(id),
+		    UNIQUE (company_id, name)
+		)`)
+	if err != nil {
+		r.log.Errorf("failed to create document table %s: %v", tableName, err)
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, tableName)
+	if err != nil {
+		r.log.Errorf("failed to get document table columns: %v", err)
+		return err
+	}
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			rows.Close()
+			r.log.Errorf("failed to scan document table column: %v", err)
+			return err
+		}
+		columns[name] = dataType
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate document table columns: %v", err)
+		return err
+	}
+
+	for _, field := range fields {
+		spec := documentColumnType(field.FieldType)
+		column := pq.QuoteIdentifier(field.FieldName)
+
+		dataType, exists := columns[field.FieldName]
+		switch {
+		case !exists:
+			_, err = tx.ExecContext(ctx, "ALTER TABLE "+quotedTable+" ADD COLUMN "+column+" "+spec.ddl)
+		case dataType != spec.dataType:
+			_, err = tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s",
+				quotedTable, column, spec.ddl, column, spec.ddl))
+			if pqErr, ok := err.(*pq.Error); ok {
+				return fmt.Errorf("%w: cannot convert column %s from %s to %s: %s",
+					biz.ErrDocumentTableMigration, field.FieldName, dataType, spec.dataType, pqErr.Message)
+			}
+		}
+		if err != nil {
+			r.log.Errorf("failed to migrate column %s.%s: %v", tableName, field.FieldName, err)
+			return err
+		}
+
+		index := pq.QuoteIdentifier(documentIndexName(tableName, field.FieldName))
+		if field.IsUnique {
+			_, err = tx.ExecContext(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS "+index+" ON "+quotedTable+" (company_id, "+column+")")
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return fmt.Errorf("%w: existing documents have duplicate values in %s",
+					biz.ErrDocumentTableMigration, field.FieldName)
+			}
+		} else {
+			_, err = tx.ExecContext(ctx, "DROP INDEX IF EXISTS "+index)
+		}
+		if err != nil {
+			r.log.Errorf("failed to migrate unique index of %s.%s: %v", tableName, field.FieldName, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertDocument 在当前公司写入文档行和草稿状态记录，修订版本的状态记录关联同一公司内来源文档的状态记录
+func (r *documentRepo) InsertDocument(ctx context.Context, meta *biz.DocumentMeta, doc map[string]interface{}, userID int64) (map[string]interface{}, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	name, _ := doc["name"].(string)
+	companyID := biz.CompanyFromContext(ctx)
+	columns := []string{"company_id", "name", "created_by", "updated_by"}
+	placeholders := []string{"$1", "$2", "$3", "$3"}
+	args := []interface{}{companyID, name, nullableUserID(userID)}
+	for _, field := range meta.Fields {
+		value, ok := doc[field.FieldName]
+		if !ok {
+			continue
+		}
+		encoded, err := encodeDocValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, encoded)
+		columns = append(columns, pq.QuoteIdentifier(field.FieldName))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id",
+		pq.QuoteIdentifier(meta.Table.TableName), strings.Join(columns, ", "), strings.Join(placeholders, ", ")),
+		args...,
+	).Scan(&id)
+	if err != nil {
+		if mapped := r.uniqueViolation(meta, err); mapped != nil {
+			return nil, mapped
+		}
+		r.log.Errorf("failed to insert document: %v", err)
+		return nil, err
+	}
+
+	amendedFrom, _ := doc["amended_from"].(string)
+	workflowState, _ := doc["workflow_state"].(string)
+	if workflowState == "" {
+		workflowState = biz.WorkflowStateDraft
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO document_workflow_states (doc_type, doc_id, doc_name, workflow_state, docstatus, amended_from, is_amended)
+		SELECT $1, $2::BIGINT, $3, $4, $5::INTEGER, a.id, a.id IS NOT NULL
+		FROM (SELECT 1) one
+		LEFT JOIN `+pq.QuoteIdentifier(meta.Table.TableName)+` af ON $6 <> '' AND af.company_id = $7 AND af.name = $6
+		LEFT JOIN document_workflow_states a ON a.doc_type = $1 AND a.doc_id = af.id`,
+		meta.DocType.Name, id, name, workflowState, biz.DocStatusDraft, amendedFrom, companyID)
+	if err != nil {
+		r.log.Errorf("failed to create document workflow state: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return nil, err
+	}
+
+	return r.GetDocument(ctx, meta, name)
+}
+
+// GetDocument 按名称获取当前公司的文档，附带文档状态
+func (r *documentRepo) GetDocument(ctx context.Context, meta *biz.DocumentMeta, name string) (map[string]interface{}, error) {
+	query := "SELECT " + documentSelectColumns(meta) + documentFromClause(meta) + " WHERE t.company_id = $2 AND t.name = $3"
+	doc, err := scanDocument(r.data.conn(ctx).QueryRowContext(ctx, query,
+		meta.DocType.Name, biz.CompanyFromContext(ctx), name), meta)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrDocumentNotFound
+		}
+		r.log.Errorf("failed to get document: %v", err)
+		return nil, err
+	}
+	return doc, nil
+}
+
+// DocumentExists 判断名称在当前公司是否已被占用
+func (r *documentRepo) DocumentExists(ctx context.Context, meta *biz.DocumentMeta, name string) (bool, error) {
+	var exists bool
+	err := r.data.conn(ctx).QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM "+pq.QuoteIdentifier(meta.Table.TableName)+" WHERE company_id = $1 AND name = $2)",
+		biz.CompanyFromContext(ctx), name,
+	).Scan(&exists)
+	if err != nil {
+		r.log.Errorf("failed to check document name: %v", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// UpdateDocument 更新草稿文档的字段并记录修改人
+func (r *documentRepo) UpdateDocument(ctx context.Context, meta *biz.DocumentMeta, name string, changes map[string]interface{}, userID int64) (map[string]interface{}, error) {
+	args := []interface{}{nullableUserID(userID)}
+	assignments := []string{"updated_at = CURRENT_TIMESTAMP", "updated_by = $1"}
+	for _, field := range meta.Fields {
+		value, ok := changes[field.FieldName]
+		if !ok {
+			continue
+		}
+		encoded, err := encodeDocValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, encoded)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(field.FieldName), len(args)))
+	}
+	args = append(args, biz.CompanyFromContext(ctx), name, meta.DocType.Name)
+
+	// 只更新仍为草稿的文档，避免与并发的提交或取消交错
+	result, err := r.data.conn(ctx).ExecContext(ctx, fmt.Sprintf(`UPDATE %s t SET %s
+		FROM document_workflow_states s
+		WHERE t.company_id = $%d AND t.name = $%d AND s.doc_type = $%d AND s.doc_id = t.id AND s.docstatus = %d`,
+		pq.QuoteIdentifier(meta.Table.TableName), strings.Join(assignments, ", "),
+		len(args)-2, len(args)-1, len(args), biz.DocStatusDraft), args...)
+	if err != nil {
+		if mapped := r.uniqueViolation(meta, err); mapped != nil {
+			return nil, mapped
+		}
+		r.log.Errorf("failed to update document: %v", err)
+		return nil, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		exists, err := r.DocumentExists(ctx, meta, name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, biz.ErrDocumentNotFound
+		}
+		return nil, fmt.Errorf("%w: only draft documents can be modified", biz.ErrDocumentNotEditable)
+	}
+
+	return r.GetDocument(ctx, meta, name)
+}
+
+// TransitionDocument 在事务中按原状态条件更新状态记录，并记录文档修改人；
+// 文档状态发生变化时记录提交或取消信息
+func (r *documentRepo) TransitionDocument(ctx context.Context, meta *biz.DocumentMeta, name string, transition *biz.DocumentTransition) (map[string]interface{}, error) {
+	assignments := "docstatus = $3, workflow_state = $4"
+	if transition.From != transition.To {
+		switch transition.To {
+		case biz.DocStatusSubmitted:
+			assignments += ", submitted_at = CURRENT_TIMESTAMP, submitted_by = $6"
+		case biz.DocStatusCancelled:
+			assignments += ", cancelled_at = CURRENT_TIMESTAMP, cancelled_by = $6, cancel_reason = NULLIF($7, '')"
+		}
+	}
+
+	var doc map[string]interface{}
+	err := r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		var id int64
+		err := tx.QueryRowContext(ctx, "UPDATE "+pq.QuoteIdentifier(meta.Table.TableName)+
+			" SET updated_at = CURRENT_TIMESTAMP, updated_by = $1 WHERE company_id = $2 AND name = $3 RETURNING id",
+			nullableUserID(transition.UserID), biz.CompanyFromContext(ctx), name).Scan(&id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return biz.ErrDocumentNotFound
+			}
+			r.log.Errorf("failed to update document: %v", err)
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			UPDATE document_workflow_states SET `+assignments+`
+			WHERE doc_type = $1 AND doc_id = $2 AND docstatus = $5
+			  AND ($8 = '' OR COALESCE(workflow_state, 'Draft') = $8)`,
+			meta.DocType.Name, id, transition.To, transition.WorkflowState, transition.From,
+			nullableUserID(transition.UserID), transition.Reason, transition.FromState)
+		if err != nil {
+			r.log.Errorf("failed to update document workflow state: %v", err)
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return fmt.Errorf("%w: status of %s has been changed by another request", biz.ErrInvalidDocumentTransition, name)
+		}
+
+		doc, err = r.GetDocument(ctx, meta, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// DeleteDocument 删除当前公司的文档行及其状态记录
+func (r *documentRepo) DeleteDocument(ctx context.Context, meta *biz.DocumentMeta, name string) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "DELETE FROM "+pq.QuoteIdentifier(meta.Table.TableName)+
+		" WHERE company_id = $1 AND name = $2 RETURNING id", biz.CompanyFromContext(ctx), name).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return biz.ErrDocumentNotFound
+		}
+		r.log.Errorf("failed to delete document: %v", err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM document_workflow_states WHERE doc_type = $1 AND doc_id = $2", meta.DocType.Name, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return fmt.Errorf("%w: document has been amended, delete the amended documents first", biz.ErrDocumentNotEditable)
+		}
+		r.log.Errorf("failed to delete document workflow state: %v", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// ListDocuments 分页查询当前公司的文档，数据范围无法转换为SQL时返回ErrScopeNotTranslatable
+func (r *documentRepo) ListDocuments(ctx context.Context, meta *biz.DocumentMeta, query *biz.DocumentQuery) ([]map[string]interface{}, int32, error) {
+	column := documentColumn(meta)
+	args := []interface{}{meta.DocType.Name, biz.CompanyFromContext(ctx)}
+	conditions := []string{"t.company_id = $2"}
+
+	filterNames := make([]string, 0, len(query.Filters))
+	for name := range query.Filters {
+		filterNames = append(filterNames, name)
+	}
+	sort.Strings(filterNames)
+	for _, name := range filterNames {
+		value := query.Filters[name]
+		if value == nil {
+			conditions = append(conditions, column(name, biz.ConditionTypeAny)+" IS NULL")
+			continue
+		}
+		field := meta.Field(name)
+		if field != nil {
+			encoded, err := encodeDocValue(field, value)
+			if err != nil {
+				return nil, 0, err
+			}
+			value = encoded
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column(name, biz.ConditionTypeAny), len(args)))
+	}
+
+	if query.Search != "" && len(query.SearchFields) == 0 {
+		// 可搜索的字段均无读权限时不匹配任何文档
+		conditions = append(conditions, "FALSE")
+	} else if query.Search != "" {
+		args = append(args, "%"+escapeLikePattern(query.Search)+"%")
+		matches := make([]string, 0, len(query.SearchFields))
+		for _, name := range query.SearchFields {
+			matches = append(matches, fmt.Sprintf("%s::text ILIKE $%d", column(name, biz.ConditionTypeString), len(args)))
+		}
+		conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+	}
+
+	if query.Scope != nil {
+		clause, scopeArgs, ok := query.Scope.SQL(column, len(args)+1)
+		if !ok {
+			return nil, 0, biz.ErrScopeNotTranslatable
+		}
+		conditions = append(conditions, "("+clause+")")
+		args = append(args, scopeArgs...)
+	}
+
+	whereClause := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int32
+	err := r.data.db.QueryRowContext(ctx, "SELECT COUNT(*)"+documentFromClause(meta)+whereClause, args...).Scan(&total)
+	if err != nil {
+		r.log.Errorf("failed to count documents: %v", err)
+		return nil, 0, err
+	}
+
+	orderClause := fmt.Sprintf(" ORDER BY %s %s NULLS LAST, t.id DESC",
+		column(query.OrderBy, biz.ConditionTypeAny), strings.ToUpper(query.Order))
+	args = append(args, query.Size, (query.Page-1)*query.Size)
+	listQuery := "SELECT " + documentSelectColumns(meta) + documentFromClause(meta) + whereClause + orderClause +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.data.db.QueryContext(ctx, listQuery, args...)
+	if err != nil {
+		r.log.Errorf("failed to list documents: %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	docs := []map[string]interface{}{}
+	for rows.Next() {
+		doc, err := scanDocument(rows, meta)
+		if err != nil {
+			r.log.Errorf("failed to scan document: %v", err)
+			return nil, 0, err
+		}
+		docs = append(docs, doc)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate documents: %v", err)
+		return nil, 0, err
+	}
+
+	return docs, total, nil
+}
+
+// uniqueViolation 将唯一约束冲突转换为业务错误：名称冲突为文档已存在，其余为唯一字段重复
+func (r *documentRepo) uniqueViolation(meta *biz.DocumentMeta, err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok || pqErr.Code != "23505" {
+		return nil
+	}
+	for _, field := range meta.Fields {
+		if pqErr.Constraint == documentIndexName(meta.Table.TableName, field.FieldName) {
+			return fmt.Errorf("%w: value of %s must be unique", biz.ErrInvalidDocument, field.FieldName)
+		}
+	}
+	return biz.ErrDocumentExists
+}
+
+// documentFromClause 数据表关联文档状态记录及修订来源的状态记录，$1为文档类型
+func documentFromClause(meta *biz.DocumentMeta) string {
+	return " FROM " + pq.QuoteIdentifier(meta.Table.TableName) + ` t
+		LEFT JOIN document_workflow_states s ON s.doc_type = $1 AND s.doc_id = t.id
+		LEFT JOIN document_workflow_states a ON a.id = s.amended_from`
+}
+
+// documentSelectColumns 标准列、状态列和字段列
+func documentSelectColumns(meta *biz.DocumentMeta) string {
+	columns := []string{documentStandardColumns}
+	for _, field := range meta.Fields {
+		columns = append(columns, "t."+pq.QuoteIdentifier(field.FieldName))
+	}
+	return strings.Join(columns, ", ")
+}
+
+// documentColumn 将文档字段映射为查询列，未定义的字段按NULL处理
+func documentColumn(meta *biz.DocumentMeta) biz.ConditionColumn {
+	return func(name string, hint biz.ConditionType) string {
+		switch name {
+		case "docstatus":
+			return "COALESCE(s.docstatus, 0)"
+		case "workflow_state":
+			return "COALESCE(s.workflow_state, 'Draft')"
+		case "amended_from":
+			return "a.doc_name"
+		}
+		for _, standard := range biz.StandardDocFields {
+			if name == standard {
+				return "t." + name
+			}
+		}
+		if field := meta.Field(name); field != nil && field.FieldType != "Table" {
+			return "t." + pq.QuoteIdentifier(name)
+		}
+		return "NULL"
+	}
+}
+
+// scanDocument 扫描一行文档
+func scanDocument(row rowScanner, meta *biz.DocumentMeta) (map[string]interface{}, error) {
+	var id int64
+	var name, workflowState string
+	var createdAt, updatedAt time.Time
+	var createdBy, updatedBy sql.NullInt64
+	var amendedFrom sql.NullString
+	var docStatus int
+
+	values := make([]interface{}, len(meta.Fields))
+	dest := []interface{}{&id, &name, &createdAt, &updatedAt, &createdBy, &updatedBy, &docStatus, &workflowState, &amendedFrom}
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{
+		"id":             id,
+		"name":           name,
+		"created_at":     createdAt,
+		"updated_at":     updatedAt,
+		"created_by":     nil,
+		"updated_by":     nil,
+		"docstatus":      docStatus,
+		"workflow_state": workflowState,
+		"amended_from":   nil,
+	}
+	if createdBy.Valid {
+		doc["created_by"] = createdBy.Int64
+	}
+	if updatedBy.Valid {
+		doc["updated_by"] = updatedBy.Int64
+	}
+	if amendedFrom.Valid {
+		doc["amended_from"] = amendedFrom.String
+	}
+
+	for i, field := range meta.Fields {
+		value, err := decodeDocValue(field, values[i])
+		if err != nil {
+			return nil, err
+		}
+		doc[field.FieldName] = value
+	}
+	return doc, nil
+}
+
+// encodeDocValue 将字段值转换为写入参数，JSONB列序列化为JSON
+func encodeDocValue(field *biz.DocField, value interface{}) (interface{}, error) {
+	if documentColumnType(field.FieldType).dataType != "jsonb" {
+		return value, nil
+	}
+	if value == nil {
+		if field.FieldType == "Table" {
+			return "[]", nil
+		}
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// decodeDocValue 将列值转换为字段值：NUMERIC转为浮点数，JSONB反序列化，日期和时间格式化为字符串
+func decodeDocValue(field *biz.DocField, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		if field.FieldType == "Table" {
+			return []interface{}{}, nil
+		}
+		return nil, nil
+	case []byte:
+		switch documentColumnType(field.FieldType).dataType {
+		case "jsonb":
+			var decoded interface{}
+			if err := json.Unmarshal(v, &decoded); err != nil {
+				return nil, err
+			}
+			return decoded, nil
+		case "numeric":
+			return strconv.ParseFloat(string(v), 64)
+		}
+		return string(v), nil
+	case time.Time:
+		switch field.FieldType {
+		case "Date":
+			return v.Format("2006-01-02"), nil
+		case "Time":
+			return v.Format("15:04:05"), nil
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// documentIndexName 唯一索引名称，超过标识符长度时以哈希缩短
+func documentIndexName(tableName, fieldName string) string {
+	name := "uk_" + tableName + "_" + fieldName
+	if len(name) <= 63 {
+		return name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%s_%08x", name[:54], h.Sum32())
+}
+
+// escapeLikePattern 转义LIKE模式中的通配符
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// nullableUserID 未登录用户的ID按NULL写入
+func nullableUserID(userID int64) interface{} {
+	if userID <= 0 {
+		return nil
+	}
+	return userID
+}
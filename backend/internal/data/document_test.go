@@ -0,0 +1,36 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentColumns(t *testing.T) {
+	assert.Equal(t, "uk_doc_order_title", documentIndexName("doc_order", "title"))
+	assert.Len(t, documentIndexName("doc_"+strings.Repeat("x", 50), strings.Repeat("y", 40)), 63)
+
+	meta := &biz.DocumentMeta{Fields: []*biz.DocField{
+		{FieldName: "amount", FieldType: "Currency"},
+		{FieldName: "items", FieldType: "Table"},
+	}}
+	column := documentColumn(meta)
+	assert.Equal(t, `t."amount"`, column("amount", biz.ConditionTypeNumber))
+	assert.Equal(t, "t.created_by", column("created_by", biz.ConditionTypeNumber))
+	assert.Equal(t, "COALESCE(s.docstatus, 0)", column("docstatus", biz.ConditionTypeNumber))
+	assert.Equal(t, "NULL", column("items", biz.ConditionTypeAny))
+	assert.Equal(t, "NULL", column("unknown", biz.ConditionTypeAny))
+
+	value, err := decodeDocValue(meta.Fields[0], []byte("12.500000"))
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, value)
+	value, err = decodeDocValue(meta.Fields[1], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{}, value)
+	encoded, err := encodeDocValue(meta.Fields[1], []interface{}{map[string]interface{}{"sku": "A"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"sku":"A"}]`, encoded)
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// namingSeriesRepo 编号序列计数器仓储实现
+type namingSeriesRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewNamingSeriesRepo 创建编号序列计数器仓储
+func NewNamingSeriesRepo(data *Data, logger log.Logger) biz.NamingSeriesRepo {
+	return &namingSeriesRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// NextSeriesValue 以单条语句递增当前公司的计数器，行锁保证并发请求取得不同的值
+func (r *namingSeriesRepo) NextSeriesValue(ctx context.Context, prefix string) (int64, error) {
+	var current int64
+	err := r.data.db.QueryRowContext(ctx, `
+		INSERT INTO naming_series (company_id, prefix, current) VALUES ($1, $2, 1)
+		ON CONFLICT (company_id, prefix) DO UPDATE SET current = naming_series.current + 1
+		RETURNING current`, biz.CompanyFromContext(ctx), prefix,
+	).Scan(&current)
+	if err != nil {
+		r.log.Errorf("failed to increment naming series %s: %v", prefix, err)
+		return 0, err
+	}
+	return current, nil
+}
+
+// ListNamingSeries 获取当前公司以prefix开头的计数器
+func (r *namingSeriesRepo) ListNamingSeries(ctx context.Context, prefix string) ([]*biz.NamingSeries, error) {
+	rows, err := r.data.db.QueryContext(ctx, `
+		SELECT prefix, current, updated_at FROM naming_series
+		WHERE company_id = $1 AND left(prefix, length($2)) = $2
+		ORDER BY prefix`, biz.CompanyFromContext(ctx), prefix)
+	if err != nil {
+		r.log.Errorf("failed to list naming series: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []*biz.NamingSeries{}
+	for rows.Next() {
+		var s biz.NamingSeries
+		if err := rows.Scan(&s.Prefix, &s.Current, &s.UpdatedAt); err != nil {
+			r.log.Errorf("failed to scan naming series: %v", err)
+			return nil, err
+		}
+		series = append(series, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate naming series: %v", err)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// SetSeriesCurrent 设置当前公司计数器的当前值，前缀不存在时创建
+func (r *namingSeriesRepo) SetSeriesCurrent(ctx context.Context, prefix string, current int64) (*biz.NamingSeries, error) {
+	series := &biz.NamingSeries{Prefix: prefix, Current: current}
+	err := r.data.db.QueryRowContext(ctx, `
+		INSERT INTO naming_series (company_id, prefix, current) VALUES ($1, $2, $3)
+		ON CONFLICT (company_id, prefix) DO UPDATE SET current = EXCLUDED.current
+		RETURNING updated_at`, biz.CompanyFromContext(ctx), prefix, current,
+	).Scan(&series.UpdatedAt)
+	if err != nil {
+		r.log.Errorf("failed to set naming series %s: %v", prefix, err)
+		return nil, err
+	}
+	return series, nil
+}
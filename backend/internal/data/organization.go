@@ -30,19 +30,19 @@ func NewOrganizationRepo(data *Data, logger log.Logger) biz.OrganizationRepo {
 func (r *organizationRepo) CreateOrganization(ctx context.Context, org *biz.Organization) (*biz.Organization, error) {
 	var id int32
 	query := `
-		INSERT INTO organizations (parent_id, name, code, description, is_enabled, sort_order, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO organizations (parent_id, name, code, description, is_enabled, sort_order, created_at, updated_at, company_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id`
 
 	err := r.data.db.QueryRowContext(ctx, query,
 		org.ParentID, org.Name, org.Code, org.Description,
-		org.IsEnabled, org.SortOrder, org.CreatedAt, org.UpdatedAt,
+		org.IsEnabled, org.SortOrder, org.CreatedAt, org.UpdatedAt, biz.CompanyFromContext(ctx),
 	).Scan(&id)
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			if pqErr.Code == "23505" { // unique constraint violation
-				if pqErr.Constraint == "organizations_code_key" {
+				if pqErr.Constraint == "uk_organizations_company_code" {
 					return nil, biz.ErrOrganizationCodeExists
 				}
 			}
@@ -61,12 +61,14 @@ func (r *organizationRepo) GetOrganization(ctx context.Context, id int32) (*biz.
 	var parentID sql.NullInt32
 
 	query := `
-		SELECT id, parent_id, name, code, description, is_enabled, sort_order, created_at, updated_at
-		FROM organizations WHERE id = $1`
-
-	err := r.data.db.QueryRowContext(ctx, query, id).Scan(
-		&org.ID, &parentID, &org.Name, &org.Code, &org.Description,
-		&org.IsEnabled, &org.SortOrder, &org.CreatedAt, &org.UpdatedAt,
+		SELECT id, parent_id, name, code, description, COALESCE(org_type, ''), leader_id, is_enabled, sort_order,
+		       COALESCE(level, 1), COALESCE(path, ''), created_at, updated_at
+		FROM organizations WHERE id = $1 AND company_id = $2 AND deleted_at IS NULL`
+
+	var leaderID sql.NullInt32
+	err := r.data.db.QueryRowContext(ctx, query, id, biz.CompanyFromContext(ctx)).Scan(
+		&org.ID, &parentID, &org.Name, &org.Code, &org.Description, &org.OrgType, &leaderID,
+		&org.IsEnabled, &org.SortOrder, &org.Level, &org.Path, &org.CreatedAt, &org.UpdatedAt,
 	)
 
 	if err != nil {
@@ -80,21 +82,35 @@ func (r *organizationRepo) GetOrganization(ctx context.Context, id int32) (*biz.
 	if parentID.Valid {
 		org.ParentID = &parentID.Int32
 	}
+	if leaderID.Valid {
+		org.LeaderID = &leaderID.Int32
+	}
 
 	return &org, nil
 }
 
-// UpdateOrganization 更新组织
+// UpdateOrganization 更新组织，上级组织变化时与MoveOrganization做相同的环检查并刷新子树路径
 func (r *organizationRepo) UpdateOrganization(ctx context.Context, org *biz.Organization) (*biz.Organization, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	moved, err := r.checkOrganizationParent(ctx, tx, org.ID, org.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		UPDATE organizations 
 		SET parent_id = $2, name = $3, description = $4, is_enabled = $5, sort_order = $6, updated_at = $7
-		WHERE id = $1`
+		WHERE id = $1 AND company_id = $8 AND deleted_at IS NULL`
 
 	org.UpdatedAt = time.Now()
-	_, err := r.data.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		org.ID, org.ParentID, org.Name, org.Description,
-		org.IsEnabled, org.SortOrder, org.UpdatedAt,
+		org.IsEnabled, org.SortOrder, org.UpdatedAt, biz.CompanyFromContext(ctx),
 	)
 
 	if err != nil {
@@ -102,15 +118,28 @@ func (r *organizationRepo) UpdateOrganization(ctx context.Context, org *biz.Orga
 		return nil, err
 	}
 
+	if moved {
+		if err := r.refreshSubtreePaths(ctx, tx, org.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit organization update: %v", err)
+		return nil, err
+	}
+
 	return org, nil
 }
 
-// DeleteOrganization 删除组织
-func (r *organizationRepo) DeleteOrganization(ctx context.Context, id int32) error {
+// DeleteOrganization 软删除组织，成员历史和权限引用保留到永久删除
+func (r *organizationRepo) DeleteOrganization(ctx context.Context, id int32, deletedBy int32) error {
+	companyID := biz.CompanyFromContext(ctx)
+
 	// 检查是否有子组织
 	var childCount int32
-	childQuery := "SELECT COUNT(*) FROM organizations WHERE parent_id = $1"
-	if err := r.data.db.QueryRowContext(ctx, childQuery, id).Scan(&childCount); err != nil {
+	childQuery := "SELECT COUNT(*) FROM organizations WHERE parent_id = $1 AND company_id = $2 AND deleted_at IS NULL"
+	if err := r.data.db.QueryRowContext(ctx, childQuery, id, companyID).Scan(&childCount); err != nil {
 		r.log.Errorf("failed to check child organizations: %v", err)
 		return err
 	}
@@ -121,8 +150,8 @@ func (r *organizationRepo) DeleteOrganization(ctx context.Context, id int32) err
 
 	// 检查是否有用户
 	var userCount int32
-	userQuery := "SELECT COUNT(*) FROM user_organizations WHERE organization_id = $1"
-	if err := r.data.db.QueryRowContext(ctx, userQuery, id).Scan(&userCount); err != nil {
+	userQuery := "SELECT COUNT(*) FROM user_organizations WHERE org_id = $1 AND company_id = $2 AND is_active = TRUE"
+	if err := r.data.db.QueryRowContext(ctx, userQuery, id, companyID).Scan(&userCount); err != nil {
 		r.log.Errorf("failed to check organization users: %v", err)
 		return err
 	}
@@ -132,8 +161,8 @@ func (r *organizationRepo) DeleteOrganization(ctx context.Context, id int32) err
 	}
 
 	// 删除组织
-	query := "DELETE FROM organizations WHERE id = $1"
-	_, err := r.data.db.ExecContext(ctx, query, id)
+	query := "UPDATE organizations SET deleted_at = $1, deleted_by = NULLIF($2, 0) WHERE id = $3 AND company_id = $4 AND deleted_at IS NULL"
+	_, err := r.data.db.ExecContext(ctx, query, time.Now(), deletedBy, id, companyID)
 	if err != nil {
 		r.log.Errorf("failed to delete organization: %v", err)
 		return err
@@ -181,9 +210,10 @@ func (r *organizationRepo) ListAllOrganizations(ctx context.Context) ([]*biz.Org
 	query := `
 		SELECT id, parent_id, name, code, description, is_enabled, sort_order, created_at, updated_at
 		FROM organizations 
+		WHERE company_id = $1 AND deleted_at IS NULL
 		ORDER BY sort_order, created_at`
 
-	rows, err := r.data.db.QueryContext(ctx, query)
+	rows, err := r.data.db.QueryContext(ctx, query, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to list all organizations: %v", err)
 		return nil, err
@@ -221,9 +251,9 @@ func (r *organizationRepo) GetOrganizationByCode(ctx context.Context, code strin
 
 	query := `
 		SELECT id, parent_id, name, code, description, is_enabled, sort_order, created_at, updated_at
-		FROM organizations WHERE code = $1`
+		FROM organizations WHERE code = $1 AND company_id = $2 AND deleted_at IS NULL`
 
-	err := r.data.db.QueryRowContext(ctx, query, code).Scan(
+	err := r.data.db.QueryRowContext(ctx, query, code, biz.CompanyFromContext(ctx)).Scan(
 		&org.ID, &parentID, &org.Name, &org.Code, &org.Description,
 		&org.IsEnabled, &org.SortOrder, &org.CreatedAt, &org.UpdatedAt,
 	)
@@ -248,10 +278,10 @@ func (r *organizationRepo) GetChildOrganizations(ctx context.Context, parentID i
 	query := `
 		SELECT id, parent_id, name, code, description, is_enabled, sort_order, created_at, updated_at
 		FROM organizations 
-		WHERE parent_id = $1
+		WHERE parent_id = $1 AND company_id = $2 AND deleted_at IS NULL
 		ORDER BY sort_order, created_at`
 
-	rows, err := r.data.db.QueryContext(ctx, query, parentID)
+	rows, err := r.data.db.QueryContext(ctx, query, parentID, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to get child organizations: %v", err)
 		return nil, err
@@ -287,10 +317,10 @@ func (r *organizationRepo) GetEnabledOrganizations(ctx context.Context) ([]*biz.
 	query := `
 		SELECT id, parent_id, name, code, description, is_enabled, sort_order, created_at, updated_at
 		FROM organizations 
-		WHERE is_enabled = true
+		WHERE is_enabled = true AND company_id = $1 AND deleted_at IS NULL
 		ORDER BY sort_order, created_at`
 
-	rows, err := r.data.db.QueryContext(ctx, query)
+	rows, err := r.data.db.QueryContext(ctx, query, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to get enabled organizations: %v", err)
 		return nil, err
@@ -321,18 +351,18 @@ func (r *organizationRepo) GetEnabledOrganizations(ctx context.Context) ([]*biz.
 	return organizations, nil
 }
 
-// GetOrganizationUsers 获取组织用户
+// GetOrganizationUsers 获取组织的有效成员用户
 func (r *organizationRepo) GetOrganizationUsers(ctx context.Context, orgID int32) ([]*biz.User, error) {
 	query := `
 		SELECT u.id, u.username, u.email, u.first_name, u.last_name, u.phone, u.gender,
-		       u.avatar_url, u.is_active, u.two_factor_enabled,
-		       u.last_login_at, u.last_login_ip, u.login_count, u.created_at, u.updated_at
+		       u.avatar_url, u.is_enabled, u.two_factor_enabled,
+		       u.last_login_time, u.last_login_ip, u.login_count, u.created_at, u.updated_at
 		FROM users u
 		INNER JOIN user_organizations uo ON u.id = uo.user_id
-		WHERE uo.organization_id = $1 AND u.is_active = true
+		WHERE uo.org_id = $1 AND uo.company_id = $2 AND uo.is_active = TRUE AND u.is_enabled = TRUE AND u.deleted_at IS NULL
 		ORDER BY u.created_at DESC`
 
-	rows, err := r.data.db.QueryContext(ctx, query, orgID)
+	rows, err := r.data.db.QueryContext(ctx, query, orgID, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to get organization users: %v", err)
 		return nil, err
@@ -342,12 +372,13 @@ func (r *organizationRepo) GetOrganizationUsers(ctx context.Context, orgID int32
 	var users []*biz.User
 	for rows.Next() {
 		var user biz.User
+		var phone, gender, avatarURL, lastLoginIP sql.NullString
 		var lastLoginAt sql.NullTime
 
 		err := rows.Scan(
 			&user.ID, &user.Username, &user.Email, &user.FirstName, &user.LastName,
-			&user.Phone, &user.Gender, &user.AvatarURL, &user.IsActive,
-			&user.TwoFactorEnabled, &lastLoginAt, &user.LastLoginIP, &user.LoginCount,
+			&phone, &gender, &avatarURL, &user.IsActive,
+			&user.TwoFactorEnabled, &lastLoginAt, &lastLoginIP, &user.LoginCount,
 			&user.CreatedAt, &user.UpdatedAt,
 		)
 		if err != nil {
@@ -355,6 +386,10 @@ func (r *organizationRepo) GetOrganizationUsers(ctx context.Context, orgID int32
 			return nil, err
 		}
 
+		user.Phone = phone.String
+		user.Gender = gender.String
+		user.AvatarURL = avatarURL.String
+		user.LastLoginIP = lastLoginIP.String
 		if lastLoginAt.Valid {
 			user.LastLoginAt = lastLoginAt.Time
 		}
@@ -365,32 +400,311 @@ func (r *organizationRepo) GetOrganizationUsers(ctx context.Context, orgID int32
 	return users, nil
 }
 
-// AssignUsers 分配用户到组织
-func (r *organizationRepo) AssignUsers(ctx context.Context, orgID int32, userIDs []int32) error {
-	// 开启事务
+// GetOrganizationAncestors 获取组织及其全部上级组织，从组织本身开始直到顶级组织
+func (r *organizationRepo) GetOrganizationAncestors(ctx context.Context, id int32) ([]*biz.Organization, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM organizations WHERE id = $1 AND company_id = $3 AND deleted_at IS NULL
+			UNION ALL
+			SELECT o.id, o.parent_id, a.depth + 1
+			FROM organizations o
+			INNER JOIN ancestors a ON o.id = a.parent_id
+			WHERE a.depth < $2
+		)
+		SELECT o.id, o.parent_id, o.name, o.code, COALESCE(o.description, ''), COALESCE(o.org_type, ''),
+		       o.leader_id, o.is_enabled, o.sort_order, o.created_at, o.updated_at
+		FROM ancestors a
+		INNER JOIN organizations o ON o.id = a.id
+		ORDER BY a.depth`
+
+	rows, err := r.data.db.QueryContext(ctx, query, id, biz.MaxOrganizationDepth, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to get organization ancestors: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var organizations []*biz.Organization
+	for rows.Next() {
+		var org biz.Organization
+		var parentID, leaderID sql.NullInt32
+
+		err := rows.Scan(
+			&org.ID, &parentID, &org.Name, &org.Code, &org.Description, &org.OrgType,
+			&leaderID, &org.IsEnabled, &org.SortOrder, &org.CreatedAt, &org.UpdatedAt,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan organization ancestor: %v", err)
+			return nil, err
+		}
+
+		if parentID.Valid {
+			org.ParentID = &parentID.Int32
+		}
+		if leaderID.Valid {
+			org.LeaderID = &leaderID.Int32
+		}
+
+		organizations = append(organizations, &org)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate organization ancestors: %v", err)
+		return nil, err
+	}
+
+	if len(organizations) == 0 {
+		return nil, biz.ErrOrganizationNotFound
+	}
+
+	return organizations, nil
+}
+
+// SetOrganizationLeader 设置组织负责人，负责人需为组织的有效成员且用户已启用
+func (r *organizationRepo) SetOrganizationLeader(ctx context.Context, id int32, leaderID *int32, operatorID int32) error {
 	tx, err := r.data.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// 删除现有关联
-	_, err = tx.ExecContext(ctx, "DELETE FROM user_organizations WHERE organization_id = $1", orgID)
+	current, err := r.lockMemberOrganization(ctx, tx, id)
 	if err != nil {
-		r.log.Errorf("failed to delete organization users: %v", err)
 		return err
 	}
+	if leaderID != nil && current.Valid && current.Int32 == *leaderID {
+		return nil
+	}
+	if leaderID == nil && !current.Valid {
+		return nil
+	}
 
-	// 添加新关联
-	for _, userID := range userIDs {
-		_, err = tx.ExecContext(ctx,
-			"INSERT INTO user_organizations (user_id, organization_id, created_at) VALUES ($1, $2, $3)",
-			userID, orgID, time.Now())
-		if err != nil {
-			r.log.Errorf("failed to assign user to organization: %v", err)
+	if leaderID != nil {
+		var eligible bool
+		query := `
+			SELECT EXISTS (
+				SELECT 1 FROM user_organizations uo
+				INNER JOIN users u ON u.id = uo.user_id
+				WHERE uo.org_id = $1 AND uo.user_id = $2 AND uo.is_active = TRUE
+				  AND u.is_enabled = TRUE AND u.deleted_at IS NULL
+			)`
+		if err := tx.QueryRowContext(ctx, query, id, *leaderID).Scan(&eligible); err != nil {
+			r.log.Errorf("failed to check organization leader: %v", err)
 			return err
 		}
+		if !eligible {
+			return biz.ErrOrganizationLeader
+		}
+	}
+
+	if err := r.setLeaderTx(ctx, tx, id, current, leaderID, operatorID); err != nil {
+		return err
+	}
+	if leaderID != nil {
+		if err := r.recordMemberHistory(ctx, tx, id, *leaderID, biz.MemberActionUpdate, "", operatorID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit organization leader: %v", err)
+		return err
 	}
 
-	return tx.Commit()
+	return nil
+}
+
+// MoveOrganization 在事务内将组织连同其下级组织移动到新的上级组织下，parentID为nil时移为顶级组织
+func (r *organizationRepo) MoveOrganization(ctx context.Context, id int32, parentID *int32) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	moved, err := r.checkOrganizationParent(ctx, tx, id, parentID)
+	if err != nil || !moved {
+		return err
+	}
+
+	query := "UPDATE organizations SET parent_id = $2, updated_at = $3 WHERE id = $1 AND company_id = $4"
+	if _, err := tx.ExecContext(ctx, query, id, parentID, time.Now(), biz.CompanyFromContext(ctx)); err != nil {
+		r.log.Errorf("failed to move organization: %v", err)
+		return err
+	}
+
+	if err := r.refreshSubtreePaths(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit organization move: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetOrganizationDescendants 获取组织的下级组织及其相对深度，按深度和排序返回；
+// onlyEnabled为true时不进入停用组织的子树
+func (r *organizationRepo) GetOrganizationDescendants(ctx context.Context, id int32, maxDepth int32, onlyEnabled bool) ([]*biz.Organization, error) {
+	query := `
+		WITH RECURSIVE descendants AS (
+			SELECT id, 0 AS depth FROM organizations WHERE id = $1 AND company_id = $4
+			UNION ALL
+			SELECT o.id, d.depth + 1
+			FROM organizations o
+			INNER JOIN descendants d ON o.parent_id = d.id
+			WHERE d.depth < $2 AND o.deleted_at IS NULL AND (NOT $3 OR o.is_enabled = TRUE)
+		)
+		SELECT o.id, o.parent_id, o.name, o.code, COALESCE(o.description, ''), COALESCE(o.org_type, ''),
+		       o.leader_id, o.is_enabled, o.sort_order, COALESCE(o.level, 1), COALESCE(o.path, ''),
+		       d.depth, o.created_at, o.updated_at
+		FROM descendants d
+		INNER JOIN organizations o ON o.id = d.id
+		ORDER BY d.depth, o.sort_order, o.id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, id, maxDepth, onlyEnabled, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to get organization descendants: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := false
+	organizations := make([]*biz.Organization, 0)
+	for rows.Next() {
+		var org biz.Organization
+		var parentID, leaderID sql.NullInt32
+
+		err := rows.Scan(
+			&org.ID, &parentID, &org.Name, &org.Code, &org.Description, &org.OrgType,
+			&leaderID, &org.IsEnabled, &org.SortOrder, &org.Level, &org.Path,
+			&org.Depth, &org.CreatedAt, &org.UpdatedAt,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan organization descendant: %v", err)
+			return nil, err
+		}
+
+		if org.Depth == 0 {
+			found = true
+			continue
+		}
+		if parentID.Valid {
+			org.ParentID = &parentID.Int32
+		}
+		if leaderID.Valid {
+			org.LeaderID = &leaderID.Int32
+		}
+
+		organizations = append(organizations, &org)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate organization descendants: %v", err)
+		return nil, err
+	}
+
+	if !found {
+		return nil, biz.ErrOrganizationNotFound
+	}
+
+	return organizations, nil
+}
+
+// checkOrganizationParent 在事务内检查组织的新上级组织，返回上级组织是否发生变化。
+// 组织树变更通过事务级咨询锁串行执行，避免两个并发移动互相成为对方的下级而形成环；
+// 上级组织必须属于同一公司
+func (r *organizationRepo) checkOrganizationParent(ctx context.Context, tx *sql.Tx, id int32, parentID *int32) (bool, error) {
+	companyID := biz.CompanyFromContext(ctx)
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", fmt.Sprintf("organization_tree:%d", companyID)); err != nil {
+		r.log.Errorf("failed to lock organization tree: %v", err)
+		return false, err
+	}
+
+	var current sql.NullInt32
+	err := tx.QueryRowContext(ctx, "SELECT parent_id FROM organizations WHERE id = $1 AND company_id = $2 AND deleted_at IS NULL FOR UPDATE", id, companyID).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, biz.ErrOrganizationNotFound
+		}
+		r.log.Errorf("failed to lock organization: %v", err)
+		return false, err
+	}
+
+	if parentID == nil {
+		return current.Valid, nil
+	}
+	if current.Valid && current.Int32 == *parentID {
+		return false, nil
+	}
+	if *parentID == id {
+		return false, biz.ErrOrganizationCycle
+	}
+
+	// 自目标上级组织向上查找，路径中出现组织本身说明目标是其下级组织
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM organizations WHERE id = $1 AND company_id = $4 AND deleted_at IS NULL
+			UNION ALL
+			SELECT o.id, o.parent_id, a.depth + 1
+			FROM organizations o
+			INNER JOIN ancestors a ON o.id = a.parent_id
+			WHERE a.depth < $3
+		)
+		SELECT COUNT(*), COALESCE(BOOL_OR(id = $2), FALSE) FROM ancestors`
+
+	var depth int32
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, query, *parentID, id, biz.MaxOrganizationDepth, companyID).Scan(&depth, &cycle); err != nil {
+		r.log.Errorf("failed to check organization parent: %v", err)
+		return false, err
+	}
+
+	switch {
+	case depth == 0:
+		return false, biz.ErrParentOrgNotFound
+	case cycle:
+		return false, biz.ErrOrganizationCycle
+	}
+	return true, nil
+}
+
+// refreshSubtreePaths 逐层刷新组织下级的路径和层级。路径触发器只根据上级组织计算当前行，
+// 因此按层级依次更新，保证每层更新时上一层的路径已经是新值
+func (r *organizationRepo) refreshSubtreePaths(ctx context.Context, tx *sql.Tx, id int32) error {
+	parents := []int64{int64(id)}
+	for level := 0; len(parents) > 0; level++ {
+		if level >= biz.MaxOrganizationDepth {
+			return biz.ErrOrganizationTooDeep
+		}
+
+		rows, err := tx.QueryContext(ctx,
+			"UPDATE organizations SET parent_id = parent_id WHERE parent_id = ANY($1) RETURNING id",
+			pq.Array(parents))
+		if err != nil {
+			r.log.Errorf("failed to refresh organization paths: %v", err)
+			return err
+		}
+
+		var children []int64
+		for rows.Next() {
+			var child int64
+			if err := rows.Scan(&child); err != nil {
+				rows.Close()
+				r.log.Errorf("failed to scan organization child: %v", err)
+				return err
+			}
+			children = append(children, child)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			r.log.Errorf("failed to iterate organization children: %v", err)
+			return err
+		}
+
+		parents = children
+	}
+	return nil
 }
@@ -0,0 +1,669 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"erp-system/internal/biz"
+)
+
+// memberColumns 成员关系查询列，需与memberDest的扫描顺序一致
+const memberColumns = `uo.id, uo.user_id, uo.org_id, COALESCE(uo.position, ''), uo.is_primary,
+		       COALESCE(o.leader_id = uo.user_id, FALSE), uo.is_active,
+		       COALESCE(uo.joined_at, uo.created_at), uo.left_at, uo.created_at, uo.updated_at`
+
+// AddOrganizationMember 添加组织成员，已离开的成员恢复原成员关系并重新记录加入时间
+func (r *organizationRepo) AddOrganizationMember(ctx context.Context, member *biz.OrganizationMember, operatorID int32) (*biz.OrganizationMember, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	leaderID, err := r.lockMemberOrganization(ctx, tx, member.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.lockMemberUser(ctx, tx, member.UserID, true); err != nil {
+		return nil, err
+	}
+
+	if err := r.addMemberTx(ctx, tx, member, leaderID, operatorID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit organization member: %v", err)
+		return nil, err
+	}
+
+	return r.getOrganizationMember(ctx, member.OrgID, member.UserID)
+}
+
+// UpdateOrganizationMember 调整有效成员的职位、主组织或负责人身份
+func (r *organizationRepo) UpdateOrganizationMember(ctx context.Context, orgID, userID int32, update *biz.OrganizationMemberUpdate, operatorID int32) (*biz.OrganizationMember, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	leaderID, err := r.lockMemberOrganization(ctx, tx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.lockMemberUser(ctx, tx, userID, false); err != nil {
+		return nil, err
+	}
+
+	member, err := r.getMemberTx(ctx, tx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !member.IsActive {
+		return nil, biz.ErrOrganizationMemberNotFound
+	}
+
+	if update.Position != nil && *update.Position != member.Position {
+		query := "UPDATE user_organizations SET position = $3, updated_at = $4 WHERE user_id = $1 AND org_id = $2"
+		if _, err := tx.ExecContext(ctx, query, userID, orgID, *update.Position, time.Now()); err != nil {
+			r.log.Errorf("failed to update member position: %v", err)
+			return nil, err
+		}
+	}
+
+	if update.IsPrimary != nil && *update.IsPrimary != member.IsPrimary {
+		if !*update.IsPrimary {
+			return nil, biz.ErrPrimaryOrganizationRequired
+		}
+		if err := r.setPrimaryTx(ctx, tx, userID, orgID, operatorID); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.IsLeader != nil && *update.IsLeader != member.IsLeader {
+		var newLeader *int32
+		if *update.IsLeader {
+			if err := r.lockMemberUser(ctx, tx, userID, true); err != nil {
+				if err == biz.ErrOrganizationMemberUser {
+					return nil, biz.ErrOrganizationLeader
+				}
+				return nil, err
+			}
+			newLeader = &userID
+		}
+		if err := r.setLeaderTx(ctx, tx, orgID, leaderID, newLeader, operatorID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.recordMemberHistory(ctx, tx, orgID, userID, biz.MemberActionUpdate, "", operatorID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit organization member update: %v", err)
+		return nil, err
+	}
+
+	return r.getOrganizationMember(ctx, orgID, userID)
+}
+
+// RemoveOrganizationMember 将成员关系标记为已离开，保留记录用于历史查询
+func (r *organizationRepo) RemoveOrganizationMember(ctx context.Context, orgID, userID int32, leftAt time.Time, reason string, operatorID int32) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	leaderID, err := r.lockMemberOrganization(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+	if err := r.lockMemberUser(ctx, tx, userID, false); err != nil {
+		return err
+	}
+
+	if err := r.removeMemberTx(ctx, tx, orgID, userID, leaderID, leftAt, reason, operatorID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit organization member removal: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// AssignUsers 将组织的有效成员同步为userIDs：新增缺少的成员，移除名单外的成员，保留其余成员的职位等信息
+func (r *organizationRepo) AssignUsers(ctx context.Context, orgID int32, userIDs []int32, operatorID int32) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	leaderID, err := r.lockMemberOrganization(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+
+	// 组织行锁已阻止其他事务增减该组织成员，这里无需锁定成员关系
+	rows, err := tx.QueryContext(ctx, "SELECT user_id FROM user_organizations WHERE org_id = $1 AND is_active = TRUE", orgID)
+	if err != nil {
+		r.log.Errorf("failed to get organization members: %v", err)
+		return err
+	}
+	current := make(map[int32]bool)
+	for rows.Next() {
+		var userID int32
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			r.log.Errorf("failed to scan organization member: %v", err)
+			return err
+		}
+		current[userID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate organization members: %v", err)
+		return err
+	}
+
+	wanted := make(map[int32]bool, len(userIDs))
+	for _, userID := range userIDs {
+		wanted[userID] = true
+	}
+
+	// 按用户ID顺序加锁，避免与其他成员变更事务死锁
+	var changed []int32
+	for userID := range wanted {
+		if !current[userID] {
+			changed = append(changed, userID)
+		}
+	}
+	for userID := range current {
+		if !wanted[userID] {
+			changed = append(changed, userID)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i] < changed[j] })
+
+	now := time.Now()
+	for _, userID := range changed {
+		if wanted[userID] {
+			if err := r.lockMemberUser(ctx, tx, userID, true); err != nil {
+				return err
+			}
+			member := &biz.OrganizationMember{UserID: userID, OrgID: orgID, JoinedAt: now}
+			if err := r.addMemberTx(ctx, tx, member, leaderID, operatorID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := r.lockMemberUser(ctx, tx, userID, false); err != nil {
+			return err
+		}
+		if err := r.removeMemberTx(ctx, tx, orgID, userID, leaderID, now, "", operatorID); err != nil {
+			return err
+		}
+		if leaderID.Valid && leaderID.Int32 == userID {
+			leaderID = sql.NullInt32{}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListOrganizationMembers 获取组织成员及用户信息，负责人在前
+func (r *organizationRepo) ListOrganizationMembers(ctx context.Context, orgID int32, includeInactive bool) ([]*biz.OrganizationMember, error) {
+	query := `
+		SELECT ` + memberColumns + `,
+		       u.username, u.email, u.first_name, u.last_name, COALESCE(u.phone, ''), COALESCE(u.avatar_url, ''), u.is_enabled
+		FROM user_organizations uo
+		INNER JOIN organizations o ON o.id = uo.org_id
+		INNER JOIN users u ON u.id = uo.user_id
+		WHERE uo.org_id = $1 AND o.company_id = $3 AND ($2 OR uo.is_active = TRUE) AND u.deleted_at IS NULL
+		ORDER BY uo.is_active DESC, COALESCE(o.leader_id = uo.user_id, FALSE) DESC, uo.joined_at, uo.id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, orgID, includeInactive, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to list organization members: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*biz.OrganizationMember, 0)
+	for rows.Next() {
+		var member biz.OrganizationMember
+		var user biz.User
+		var leftAt sql.NullTime
+		dest := append(memberDest(&member, &leftAt),
+			&user.Username, &user.Email, &user.FirstName, &user.LastName, &user.Phone, &user.AvatarURL, &user.IsActive)
+		if err := rows.Scan(dest...); err != nil {
+			r.log.Errorf("failed to scan organization member: %v", err)
+			return nil, err
+		}
+		applyLeftAt(&member, leftAt)
+		user.ID = member.UserID
+		member.User = &user
+		members = append(members, &member)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate organization members: %v", err)
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		if err := r.checkOrganizationExists(ctx, orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	return members, nil
+}
+
+// ListUserMemberships 获取用户在当前公司所属的组织，主组织在前
+func (r *organizationRepo) ListUserMemberships(ctx context.Context, userID int32, includeInactive bool) ([]*biz.OrganizationMember, error) {
+	query := `
+		SELECT ` + memberColumns + `,
+		       o.parent_id, o.name, o.code, COALESCE(o.org_type, ''), o.is_enabled, COALESCE(o.level, 1), COALESCE(o.path, '')
+		FROM user_organizations uo
+		INNER JOIN organizations o ON o.id = uo.org_id
+		WHERE uo.user_id = $1 AND uo.company_id = $3 AND ($2 OR uo.is_active = TRUE) AND o.deleted_at IS NULL
+		ORDER BY uo.is_active DESC, uo.is_primary DESC, uo.joined_at, uo.id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, userID, includeInactive, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to list user memberships: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*biz.OrganizationMember, 0)
+	for rows.Next() {
+		var member biz.OrganizationMember
+		var org biz.Organization
+		var parentID sql.NullInt32
+		var leftAt sql.NullTime
+		dest := append(memberDest(&member, &leftAt),
+			&parentID, &org.Name, &org.Code, &org.OrgType, &org.IsEnabled, &org.Level, &org.Path)
+		if err := rows.Scan(dest...); err != nil {
+			r.log.Errorf("failed to scan user membership: %v", err)
+			return nil, err
+		}
+		applyLeftAt(&member, leftAt)
+		org.ID = member.OrgID
+		if parentID.Valid {
+			org.ParentID = &parentID.Int32
+		}
+		member.Organization = &org
+		members = append(members, &member)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate user memberships: %v", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// ListMemberHistory 获取当前公司的组织成员变动记录，按时间倒序
+func (r *organizationRepo) ListMemberHistory(ctx context.Context, filter *biz.MemberHistoryFilter) ([]*biz.OrganizationMemberHistory, error) {
+	conditions := []string{"o.company_id = $1"}
+	args := []interface{}{biz.CompanyFromContext(ctx)}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("h.user_id = $%d", len(args)))
+	}
+	if filter.OrgID != 0 {
+		args = append(args, filter.OrgID)
+		conditions = append(conditions, fmt.Sprintf("h.org_id = $%d", len(args)))
+	}
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT h.id, h.user_id, u.username, h.org_id, o.name, h.action, COALESCE(h.position, ''),
+		       h.is_primary, h.is_leader, COALESCE(h.reason, ''), h.operator_id, h.created_at
+		FROM user_organization_history h
+		INNER JOIN users u ON u.id = h.user_id
+		INNER JOIN organizations o ON o.id = h.org_id
+		WHERE %s
+		ORDER BY h.created_at DESC, h.id DESC
+		LIMIT $%d`, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.data.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Errorf("failed to list organization member history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]*biz.OrganizationMemberHistory, 0)
+	for rows.Next() {
+		var record biz.OrganizationMemberHistory
+		var operatorID sql.NullInt32
+		err := rows.Scan(
+			&record.ID, &record.UserID, &record.Username, &record.OrgID, &record.OrgName, &record.Action,
+			&record.Position, &record.IsPrimary, &record.IsLeader, &record.Reason, &operatorID, &record.CreatedAt,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan organization member history: %v", err)
+			return nil, err
+		}
+		if operatorID.Valid {
+			record.OperatorID = &operatorID.Int32
+		}
+		history = append(history, &record)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate organization member history: %v", err)
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// addMemberTx 在事务内添加或恢复成员关系，调用方需已锁定组织和用户
+func (r *organizationRepo) addMemberTx(ctx context.Context, tx *sql.Tx, member *biz.OrganizationMember, leaderID sql.NullInt32, operatorID int32) error {
+	var isActive bool
+	err := tx.QueryRowContext(ctx,
+		"SELECT is_active FROM user_organizations WHERE user_id = $1 AND org_id = $2 FOR UPDATE",
+		member.UserID, member.OrgID).Scan(&isActive)
+	switch {
+	case err == sql.ErrNoRows:
+		query := `
+			INSERT INTO user_organizations (user_id, org_id, position, is_primary, joined_at, is_active, company_id)
+			VALUES ($1, $2, $3, FALSE, $4, TRUE, $5)`
+		if _, err := tx.ExecContext(ctx, query, member.UserID, member.OrgID, member.Position, member.JoinedAt, biz.CompanyFromContext(ctx)); err != nil {
+			r.log.Errorf("failed to add organization member: %v", err)
+			return err
+		}
+	case err != nil:
+		r.log.Errorf("failed to get organization member: %v", err)
+		return err
+	case isActive:
+		return biz.ErrOrganizationMemberExists
+	default:
+		query := `
+			UPDATE user_organizations
+			SET position = $3, is_primary = FALSE, joined_at = $4, left_at = NULL, is_active = TRUE, updated_at = $5
+			WHERE user_id = $1 AND org_id = $2`
+		if _, err := tx.ExecContext(ctx, query, member.UserID, member.OrgID, member.Position, member.JoinedAt, time.Now()); err != nil {
+			r.log.Errorf("failed to restore organization member: %v", err)
+			return err
+		}
+	}
+
+	// 用户在当前公司没有主组织时，新加入的组织成为主组织
+	primary := member.IsPrimary
+	if !primary {
+		var hasPrimary bool
+		query := "SELECT EXISTS (SELECT 1 FROM user_organizations WHERE user_id = $1 AND company_id = $2 AND is_primary = TRUE AND is_active = TRUE)"
+		if err := tx.QueryRowContext(ctx, query, member.UserID, biz.CompanyFromContext(ctx)).Scan(&hasPrimary); err != nil {
+			r.log.Errorf("failed to check primary organization: %v", err)
+			return err
+		}
+		primary = !hasPrimary
+	}
+	if primary {
+		if err := r.setPrimaryTx(ctx, tx, member.UserID, member.OrgID, operatorID); err != nil {
+			return err
+		}
+	}
+
+	if member.IsLeader && !(leaderID.Valid && leaderID.Int32 == member.UserID) {
+		if err := r.setLeaderTx(ctx, tx, member.OrgID, leaderID, &member.UserID, operatorID); err != nil {
+			return err
+		}
+	}
+
+	return r.recordMemberHistory(ctx, tx, member.OrgID, member.UserID, biz.MemberActionJoin, "", operatorID)
+}
+
+// removeMemberTx 在事务内将成员关系标记为已离开，调用方需已锁定组织和用户
+func (r *organizationRepo) removeMemberTx(ctx context.Context, tx *sql.Tx, orgID, userID int32, leaderID sql.NullInt32, leftAt time.Time, reason string, operatorID int32) error {
+	member, err := r.getMemberTx(ctx, tx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if !member.IsActive {
+		return biz.ErrOrganizationMemberNotFound
+	}
+	if leftAt.Before(member.JoinedAt) {
+		return biz.ErrInvalidOrganizationMember
+	}
+
+	query := `
+		UPDATE user_organizations SET is_active = FALSE, is_primary = FALSE, left_at = $3, updated_at = $4
+		WHERE user_id = $1 AND org_id = $2`
+	if _, err := tx.ExecContext(ctx, query, userID, orgID, leftAt, time.Now()); err != nil {
+		r.log.Errorf("failed to remove organization member: %v", err)
+		return err
+	}
+
+	if member.IsLeader {
+		if err := r.setLeaderTx(ctx, tx, orgID, leaderID, nil, operatorID); err != nil {
+			return err
+		}
+	}
+
+	if err := r.recordMemberHistory(ctx, tx, orgID, userID, biz.MemberActionLeave, reason, operatorID); err != nil {
+		return err
+	}
+
+	// 移除主组织后由同一公司内最早加入的其他组织接替
+	if member.IsPrimary {
+		var nextOrgID int32
+		query := `
+			SELECT org_id FROM user_organizations
+			WHERE user_id = $1 AND company_id = $2 AND is_active = TRUE
+			ORDER BY COALESCE(joined_at, created_at), id
+			LIMIT 1`
+		err := tx.QueryRowContext(ctx, query, userID, biz.CompanyFromContext(ctx)).Scan(&nextOrgID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			r.log.Errorf("failed to find next primary organization: %v", err)
+			return err
+		}
+		if err := r.setPrimaryTx(ctx, tx, userID, nextOrgID, operatorID); err != nil {
+			return err
+		}
+		return r.recordMemberHistory(ctx, tx, nextOrgID, userID, biz.MemberActionUpdate, "", operatorID)
+	}
+
+	return nil
+}
+
+// setPrimaryTx 将组织设为用户在当前公司的主组织，原主组织取消并记录变动
+func (r *organizationRepo) setPrimaryTx(ctx context.Context, tx *sql.Tx, userID, orgID int32, operatorID int32) error {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE user_organizations SET is_primary = FALSE, updated_at = $3
+		WHERE user_id = $1 AND org_id <> $2 AND company_id = $4 AND is_primary = TRUE
+		RETURNING org_id`, userID, orgID, time.Now(), biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to clear primary organization: %v", err)
+		return err
+	}
+	var previous []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			r.log.Errorf("failed to scan primary organization: %v", err)
+			return err
+		}
+		previous = append(previous, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate primary organizations: %v", err)
+		return err
+	}
+
+	query := "UPDATE user_organizations SET is_primary = TRUE, updated_at = $3 WHERE user_id = $1 AND org_id = $2"
+	if _, err := tx.ExecContext(ctx, query, userID, orgID, time.Now()); err != nil {
+		r.log.Errorf("failed to set primary organization: %v", err)
+		return err
+	}
+
+	for _, id := range previous {
+		if err := r.recordMemberHistory(ctx, tx, id, userID, biz.MemberActionUpdate, "", operatorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setLeaderTx 更换组织负责人，原负责人仍是成员时记录其变动；新负责人的变动由调用方记录
+func (r *organizationRepo) setLeaderTx(ctx context.Context, tx *sql.Tx, orgID int32, current sql.NullInt32, leaderID *int32, operatorID int32) error {
+	query := "UPDATE organizations SET leader_id = $2, updated_at = $3 WHERE id = $1"
+	if _, err := tx.ExecContext(ctx, query, orgID, leaderID, time.Now()); err != nil {
+		r.log.Errorf("failed to set organization leader: %v", err)
+		return err
+	}
+
+	if current.Valid && (leaderID == nil || *leaderID != current.Int32) {
+		return r.recordMemberHistory(ctx, tx, orgID, current.Int32, biz.MemberActionUpdate, "", operatorID)
+	}
+	return nil
+}
+
+// recordMemberHistory 记录成员关系当前状态的快照
+func (r *organizationRepo) recordMemberHistory(ctx context.Context, tx *sql.Tx, orgID, userID int32, action, reason string, operatorID int32) error {
+	query := `
+		INSERT INTO user_organization_history (user_id, org_id, action, position, is_primary, is_leader, reason, operator_id)
+		SELECT uo.user_id, uo.org_id, $3, uo.position, uo.is_primary, COALESCE(o.leader_id = uo.user_id, FALSE),
+		       NULLIF($4, ''), NULLIF($5, 0)
+		FROM user_organizations uo
+		INNER JOIN organizations o ON o.id = uo.org_id
+		WHERE uo.user_id = $1 AND uo.org_id = $2`
+
+	if _, err := tx.ExecContext(ctx, query, userID, orgID, action, reason, operatorID); err != nil {
+		r.log.Errorf("failed to record organization member history: %v", err)
+		return err
+	}
+	return nil
+}
+
+// lockMemberOrganization 锁定当前公司的组织行并返回当前负责人，同一组织的成员变更串行执行
+func (r *organizationRepo) lockMemberOrganization(ctx context.Context, tx *sql.Tx, orgID int32) (sql.NullInt32, error) {
+	var leaderID sql.NullInt32
+	query := "SELECT leader_id FROM organizations WHERE id = $1 AND company_id = $2 AND deleted_at IS NULL FOR UPDATE"
+	err := tx.QueryRowContext(ctx, query, orgID, biz.CompanyFromContext(ctx)).Scan(&leaderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return leaderID, biz.ErrOrganizationNotFound
+		}
+		r.log.Errorf("failed to lock organization: %v", err)
+		return leaderID, err
+	}
+	return leaderID, nil
+}
+
+// lockMemberUser 锁定用户行，同一用户的主组织变更串行执行；requireActive为true时要求用户已启用、
+// 未删除且属于当前公司
+func (r *organizationRepo) lockMemberUser(ctx context.Context, tx *sql.Tx, userID int32, requireActive bool) error {
+	var active bool
+	query := `
+		SELECT u.is_enabled AND u.deleted_at IS NULL
+		       AND EXISTS (SELECT 1 FROM user_companies uc WHERE uc.user_id = u.id AND uc.company_id = $2)
+		FROM users u WHERE u.id = $1
+		FOR UPDATE OF u`
+	err := tx.QueryRowContext(ctx, query, userID, biz.CompanyFromContext(ctx)).Scan(&active)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return biz.ErrOrganizationMemberUser
+		}
+		r.log.Errorf("failed to lock user: %v", err)
+		return err
+	}
+	if requireActive && !active {
+		return biz.ErrOrganizationMemberUser
+	}
+	return nil
+}
+
+// getMemberTx 在事务内获取并锁定成员关系
+func (r *organizationRepo) getMemberTx(ctx context.Context, tx *sql.Tx, orgID, userID int32) (*biz.OrganizationMember, error) {
+	query := `
+		SELECT ` + memberColumns + `
+		FROM user_organizations uo
+		INNER JOIN organizations o ON o.id = uo.org_id
+		WHERE uo.user_id = $1 AND uo.org_id = $2
+		FOR UPDATE OF uo`
+
+	var member biz.OrganizationMember
+	var leftAt sql.NullTime
+	if err := tx.QueryRowContext(ctx, query, userID, orgID).Scan(memberDest(&member, &leftAt)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrOrganizationMemberNotFound
+		}
+		r.log.Errorf("failed to get organization member: %v", err)
+		return nil, err
+	}
+	applyLeftAt(&member, leftAt)
+	return &member, nil
+}
+
+// getOrganizationMember 获取成员关系
+func (r *organizationRepo) getOrganizationMember(ctx context.Context, orgID, userID int32) (*biz.OrganizationMember, error) {
+	query := `
+		SELECT ` + memberColumns + `
+		FROM user_organizations uo
+		INNER JOIN organizations o ON o.id = uo.org_id
+		WHERE uo.user_id = $1 AND uo.org_id = $2`
+
+	var member biz.OrganizationMember
+	var leftAt sql.NullTime
+	if err := r.data.db.QueryRowContext(ctx, query, userID, orgID).Scan(memberDest(&member, &leftAt)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrOrganizationMemberNotFound
+		}
+		r.log.Errorf("failed to get organization member: %v", err)
+		return nil, err
+	}
+	applyLeftAt(&member, leftAt)
+	return &member, nil
+}
+
+// checkOrganizationExists 组织不存在或不属于当前公司时返回ErrOrganizationNotFound
+func (r *organizationRepo) checkOrganizationExists(ctx context.Context, orgID int32) error {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM organizations WHERE id = $1 AND company_id = $2 AND deleted_at IS NULL)"
+	if err := r.data.db.QueryRowContext(ctx, query, orgID, biz.CompanyFromContext(ctx)).Scan(&exists); err != nil {
+		r.log.Errorf("failed to check organization: %v", err)
+		return err
+	}
+	if !exists {
+		return biz.ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// memberDest 成员关系列的扫描目标，顺序与memberColumns一致；离开时间可空，扫描后由applyLeftAt填充
+func memberDest(member *biz.OrganizationMember, leftAt *sql.NullTime) []interface{} {
+	return []interface{}{
+		&member.ID, &member.UserID, &member.OrgID, &member.Position, &member.IsPrimary,
+		&member.IsLeader, &member.IsActive, &member.JoinedAt, leftAt, &member.CreatedAt, &member.UpdatedAt,
+	}
+}
+
+// applyLeftAt 填充成员关系的离开时间
+func applyLeftAt(member *biz.OrganizationMember, leftAt sql.NullTime) {
+	if leftAt.Valid {
+		member.LeftAt = &leftAt.Time
+	}
+}
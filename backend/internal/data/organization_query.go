@@ -0,0 +1,212 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"erp-system/internal/biz"
+
+	"github.com/lib/pq"
+)
+
+// organizationColumns 组织查询列，与scanOrganization的扫描顺序一致
+const organizationColumns = `id, parent_id, name, code, COALESCE(description, ''), COALESCE(org_type, ''),
+		leader_id, is_enabled, sort_order, COALESCE(level, 1), COALESCE(path, ''), created_at, updated_at`
+
+// GetUserOrganizationScope 根据用户权限计算用户可访问的组织。用户权限的value或doc_name
+// 为组织ID或编码，授权组织的下级组织一并可访问，除非设置了hide_descendants。只计算当前公司的用户权限和组织
+func (r *organizationRepo) GetUserOrganizationScope(ctx context.Context, userID int64) (*biz.OrganizationScope, error) {
+	companyID := biz.CompanyFromContext(ctx)
+
+	var restrictions int
+	err := r.data.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM user_permissions
+		WHERE user_id = $1 AND doc_type = $2 AND company_id = $3
+		  AND (applicable_for IS NULL OR applicable_for = '' OR applicable_for = $2)`,
+		userID, biz.OrganizationDocType, companyID).Scan(&restrictions)
+	if err != nil {
+		r.log.Errorf("failed to count organization user permissions: %v", err)
+		return nil, err
+	}
+	if restrictions == 0 {
+		return &biz.OrganizationScope{}, nil
+	}
+
+	query := `
+		SELECT DISTINCT o.id
+		FROM user_permissions up
+		INNER JOIN organizations g ON g.deleted_at IS NULL AND g.company_id = up.company_id
+			AND (g.id::text IN (up.value, up.doc_name) OR g.code IN (up.value, up.doc_name))
+		INNER JOIN organizations o ON o.deleted_at IS NULL AND o.company_id = g.company_id
+			AND (o.id = g.id OR (NOT COALESCE(up.hide_descendants, FALSE) AND o.path LIKE g.path || '%'))
+		WHERE up.user_id = $1 AND up.doc_type = $2 AND up.company_id = $3
+		  AND (up.applicable_for IS NULL OR up.applicable_for = '' OR up.applicable_for = $2)`
+
+	rows, err := r.data.db.QueryContext(ctx, query, userID, biz.OrganizationDocType, companyID)
+	if err != nil {
+		r.log.Errorf("failed to get user organization scope: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	scope := &biz.OrganizationScope{OrgIDs: make(map[int32]bool)}
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			r.log.Errorf("failed to scan user organization scope: %v", err)
+			return nil, err
+		}
+		scope.OrgIDs[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate user organization scope: %v", err)
+		return nil, err
+	}
+
+	return scope, nil
+}
+
+// ListActiveMemberships 获取组织中启用用户的有效成员关系，只返回统计所需的用户、组织和职位
+func (r *organizationRepo) ListActiveMemberships(ctx context.Context, orgIDs []int32) ([]*biz.OrganizationMember, error) {
+	members := make([]*biz.OrganizationMember, 0)
+	if len(orgIDs) == 0 {
+		return members, nil
+	}
+
+	query := `
+		SELECT uo.user_id, uo.org_id, COALESCE(uo.position, '')
+		FROM user_organizations uo
+		INNER JOIN users u ON u.id = uo.user_id
+		WHERE uo.org_id = ANY($1) AND uo.is_active = TRUE
+		  AND u.deleted_at IS NULL AND u.is_enabled = TRUE`
+
+	rows, err := r.data.db.QueryContext(ctx, query, pq.Array(orgIDs))
+	if err != nil {
+		r.log.Errorf("failed to list active memberships: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		member := &biz.OrganizationMember{IsActive: true}
+		if err := rows.Scan(&member.UserID, &member.OrgID, &member.Position); err != nil {
+			r.log.Errorf("failed to scan active membership: %v", err)
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate active memberships: %v", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// SearchOrganizations 按名称、编码和描述模糊搜索组织；编码或名称完全匹配的排在最前，其次是名称前缀匹配
+func (r *organizationRepo) SearchOrganizations(ctx context.Context, filter *biz.OrganizationSearchFilter) ([]*biz.Organization, error) {
+	keyword := escapeLikePattern(filter.Keyword)
+	query := `
+		SELECT ` + organizationColumns + `
+		FROM organizations
+		WHERE deleted_at IS NULL AND company_id = $9
+		  AND (name ILIKE $1 OR code ILIKE $1 OR COALESCE(description, '') ILIKE $1)
+		  AND ($2 = '' OR org_type = $2)
+		  AND (NOT $3 OR is_enabled = TRUE)
+		  AND (NOT $4 OR id = ANY($5))
+		ORDER BY CASE
+				WHEN LOWER(code) = LOWER($6) OR LOWER(name) = LOWER($6) THEN 0
+				WHEN name ILIKE $7 THEN 1
+				WHEN name ILIKE $1 OR code ILIKE $1 THEN 2
+				ELSE 3
+			END, level, sort_order, id
+		LIMIT $8`
+
+	rows, err := r.data.db.QueryContext(ctx, query,
+		"%"+keyword+"%", filter.OrgType, filter.OnlyEnabled,
+		filter.OrgIDs != nil, pq.Array(filter.OrgIDs),
+		filter.Keyword, keyword+"%", filter.Limit, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to search organizations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanOrganizations(rows)
+}
+
+// GetOrganizationsByIDs 批量获取组织，不存在、已删除或不属于当前公司的组织被忽略
+func (r *organizationRepo) GetOrganizationsByIDs(ctx context.Context, ids []int32) ([]*biz.Organization, error) {
+	if len(ids) == 0 {
+		return make([]*biz.Organization, 0), nil
+	}
+
+	query := `
+		SELECT ` + organizationColumns + `
+		FROM organizations
+		WHERE id = ANY($1) AND company_id = $2 AND deleted_at IS NULL`
+
+	rows, err := r.data.db.QueryContext(ctx, query, pq.Array(ids), biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to get organizations by ids: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanOrganizations(rows)
+}
+
+// ListOrganizations 获取当前公司全部未删除的组织，按层级和排序返回
+func (r *organizationRepo) ListOrganizations(ctx context.Context) ([]*biz.Organization, error) {
+	query := `
+		SELECT ` + organizationColumns + `
+		FROM organizations
+		WHERE deleted_at IS NULL AND company_id = $1
+		ORDER BY level, sort_order, id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to list organizations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanOrganizations(rows)
+}
+
+// scanOrganizations 扫描按organizationColumns查询的组织
+func (r *organizationRepo) scanOrganizations(rows *sql.Rows) ([]*biz.Organization, error) {
+	organizations := make([]*biz.Organization, 0)
+	for rows.Next() {
+		var org biz.Organization
+		var parentID, leaderID sql.NullInt32
+
+		err := rows.Scan(
+			&org.ID, &parentID, &org.Name, &org.Code, &org.Description, &org.OrgType,
+			&leaderID, &org.IsEnabled, &org.SortOrder, &org.Level, &org.Path,
+			&org.CreatedAt, &org.UpdatedAt,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan organization: %v", err)
+			return nil, err
+		}
+
+		if parentID.Valid {
+			org.ParentID = &parentID.Int32
+		}
+		if leaderID.Valid {
+			org.LeaderID = &leaderID.Int32
+		}
+
+		organizations = append(organizations, &org)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate organizations: %v", err)
+		return nil, err
+	}
+
+	return organizations, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"erp-system/internal/biz"
@@ -38,7 +39,7 @@ func (r *permissionRepo) CreateDocType(ctx context.Context, docType *biz.DocType
 	permissionsJSON, _ := json.Marshal(docType.Permissions)
 	searchFieldsJSON, _ := json.Marshal(docType.SearchFields)
 
-	err := r.data.db.QueryRowContext(ctx, query,
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
 		docType.Name, docType.Label, docType.Module, docType.IsSubmittable, docType.IsChildTable,
 		permissionsJSON, docType.NamingRule, docType.TitleField, searchFieldsJSON,
 		docType.SortField, docType.SortOrder, docType.Description,
@@ -70,7 +71,7 @@ func (r *permissionRepo) GetDocType(ctx context.Context, name string) (*biz.DocT
 		       created_at, updated_at, version
 		FROM doc_types WHERE name = $1`
 
-	err := r.data.db.QueryRowContext(ctx, query, name).Scan(
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, name).Scan(
 		&docType.ID, &docType.Name, &docType.Module, &docType.IsSubmittable,
 		&docType.IsChildTable, &permissionsJSON, &docType.NamingRule,
 		&titleField, &searchFieldsJSON, &sortField, &docType.SortOrder,
@@ -79,7 +80,7 @@ func (r *permissionRepo) GetDocType(ctx context.Context, name string) (*biz.DocT
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("doctype not found")
+			return nil, biz.ErrDocTypeNotFound
 		}
 		r.log.Errorf("failed to get doctype: %v", err)
 		return nil, err
@@ -114,7 +115,7 @@ func (r *permissionRepo) UpdateDocType(ctx context.Context, docType *biz.DocType
 	searchFieldsJSON, _ := json.Marshal(docType.SearchFields)
 	docType.UpdatedAt = time.Now()
 
-	_, err := r.data.db.ExecContext(ctx, query,
+	_, err := r.data.conn(ctx).ExecContext(ctx, query,
 		docType.Module, docType.IsSubmittable, docType.IsChildTable,
 		permissionsJSON, docType.NamingRule, docType.TitleField,
 		searchFieldsJSON, docType.SortField, docType.SortOrder,
@@ -132,7 +133,7 @@ func (r *permissionRepo) UpdateDocType(ctx context.Context, docType *biz.DocType
 func (r *permissionRepo) DeleteDocType(ctx context.Context, name string) error {
 	// 检查是否有相关权限规则
 	var count int
-	err := r.data.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM permission_rules WHERE document_type = $1", name).Scan(&count)
+	err := r.data.conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM permission_rules WHERE document_type = $1", name).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -141,7 +142,7 @@ func (r *permissionRepo) DeleteDocType(ctx context.Context, name string) error {
 	}
 
 	// 删除DocType
-	_, err = r.data.db.ExecContext(ctx, "DELETE FROM doc_types WHERE name = $1", name)
+	_, err = r.data.conn(ctx).ExecContext(ctx, "DELETE FROM doc_types WHERE name = $1", name)
 	if err != nil {
 		r.log.Errorf("failed to delete doctype: %v", err)
 		return err
@@ -159,7 +160,7 @@ func (r *permissionRepo) ListDocTypes(ctx context.Context, module string) ([]*bi
 		WHERE ($1 = '' OR module = $1)
 		ORDER BY module, name`
 
-	rows, err := r.data.db.QueryContext(ctx, query, module)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, module)
 	if err != nil {
 		r.log.Errorf("failed to list doctypes: %v", err)
 		return nil, err
@@ -214,15 +215,17 @@ func (r *permissionRepo) CreatePermissionRule(ctx context.Context, rule *biz.Per
 	query := `
 		INSERT INTO permission_rules (role_id, doc_type, permission_level, can_read, can_write, can_create,
 		                            can_delete, can_submit, can_cancel, can_amend, can_report, can_export, 
-		                            can_import, can_share, can_print, can_email, only_if_creator, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		                            can_import, can_share, can_print, can_email, only_if_creator, condition, created_at, updated_at,
+		                            company_id, can_set_user_permissions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NULLIF($18, ''), $19, $20, $21, $22)
 		RETURNING id`
 
-	err := r.data.db.QueryRowContext(ctx, query,
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
 		rule.RoleID, rule.DocType, rule.PermissionLevel, rule.CanRead, rule.CanWrite,
 		rule.CanCreate, rule.CanDelete, rule.CanSubmit, rule.CanCancel, rule.CanAmend,
 		rule.CanReport, rule.CanExport, rule.CanImport, rule.CanShare, rule.CanPrint,
-		rule.CanEmail, rule.OnlyIfCreator, rule.CreatedAt, rule.UpdatedAt,
+		rule.CanEmail, rule.OnlyIfCreator, rule.Condition, rule.CreatedAt, rule.UpdatedAt,
+		biz.CompanyFromContext(ctx), rule.CanSetUserPermissions,
 	).Scan(&id)
 
 	if err != nil {
@@ -240,15 +243,15 @@ func (r *permissionRepo) GetPermissionRule(ctx context.Context, id int64) (*biz.
 	query := `
 		SELECT id, role_id, doc_type, permission_level, can_read, can_write, can_create,
 		       can_delete, can_submit, can_cancel, can_amend, can_report, can_export, can_import,
-		       can_share, can_print, can_email, only_if_creator, created_at, updated_at
-		FROM permission_rules WHERE id = $1`
+		       can_share, can_print, can_email, can_set_user_permissions, only_if_creator, COALESCE(condition, ''), created_at, updated_at
+		FROM permission_rules WHERE id = $1 AND company_id = $2`
 
-	err := r.data.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, id, biz.CompanyFromContext(ctx)).Scan(
 		&rule.ID, &rule.RoleID, &rule.DocType, &rule.PermissionLevel,
 		&rule.CanRead, &rule.CanWrite, &rule.CanCreate, &rule.CanDelete,
 		&rule.CanSubmit, &rule.CanCancel, &rule.CanAmend, &rule.CanReport,
 		&rule.CanExport, &rule.CanImport, &rule.CanShare, &rule.CanPrint,
-		&rule.CanEmail, &rule.OnlyIfCreator, &rule.CreatedAt, &rule.UpdatedAt,
+		&rule.CanEmail, &rule.CanSetUserPermissions, &rule.OnlyIfCreator, &rule.Condition, &rule.CreatedAt, &rule.UpdatedAt,
 	)
 
 	if err != nil {
@@ -271,15 +274,17 @@ func (r *permissionRepo) UpdatePermissionRule(ctx context.Context, rule *biz.Per
 		SET role_id = $1, doc_type = $2, permission_level = $3, can_read = $4, can_write = $5,
 		    can_create = $6, can_delete = $7, can_submit = $8, can_cancel = $9, can_amend = $10,
 		    can_report = $11, can_export = $12, can_import = $13, can_share = $14,
-		    can_print = $15, can_email = $16, only_if_creator = $17, updated_at = $18
-		WHERE id = $19`
+		    can_print = $15, can_email = $16, only_if_creator = $17, condition = NULLIF($18, ''), updated_at = $19,
+		    can_set_user_permissions = $22
+		WHERE id = $20 AND company_id = $21`
 
 	rule.UpdatedAt = time.Now()
-	_, err := r.data.db.ExecContext(ctx, query,
+	_, err := r.data.conn(ctx).ExecContext(ctx, query,
 		rule.RoleID, rule.DocType, rule.PermissionLevel, rule.CanRead, rule.CanWrite,
 		rule.CanCreate, rule.CanDelete, rule.CanSubmit, rule.CanCancel, rule.CanAmend,
 		rule.CanReport, rule.CanExport, rule.CanImport, rule.CanShare,
-		rule.CanPrint, rule.CanEmail, rule.OnlyIfCreator, rule.UpdatedAt, rule.ID,
+		rule.CanPrint, rule.CanEmail, rule.OnlyIfCreator, rule.Condition, rule.UpdatedAt, rule.ID,
+		biz.CompanyFromContext(ctx), rule.CanSetUserPermissions,
 	)
 
 	if err != nil {
@@ -291,7 +296,7 @@ func (r *permissionRepo) UpdatePermissionRule(ctx context.Context, rule *biz.Per
 }
 
 func (r *permissionRepo) DeletePermissionRule(ctx context.Context, id int64) error {
-	_, err := r.data.db.ExecContext(ctx, "DELETE FROM permission_rules WHERE id = $1", id)
+	_, err := r.data.conn(ctx).ExecContext(ctx, "DELETE FROM permission_rules WHERE id = $1 AND company_id = $2", id, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to delete permission rule: %v", err)
 		return err
@@ -303,12 +308,12 @@ func (r *permissionRepo) ListPermissionRules(ctx context.Context, roleID int64,
 	query := `
 		SELECT id, role_id, doc_type, permission_level, can_read, can_write, can_create,
 		       can_delete, can_submit, can_cancel, can_amend, can_report, can_export, can_import,
-		       can_share, can_print, can_email, only_if_creator, created_at, updated_at
+		       can_share, can_print, can_email, can_set_user_permissions, only_if_creator, COALESCE(condition, ''), created_at, updated_at
 		FROM permission_rules
-		WHERE ($1 = 0 OR role_id = $1) AND ($2 = '' OR doc_type = $2)
+		WHERE ($1 = 0 OR role_id = $1) AND ($2 = '' OR doc_type = $2) AND company_id = $3
 		ORDER BY doc_type, permission_level, role_id`
 
-	rows, err := r.data.db.QueryContext(ctx, query, roleID, docType)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, roleID, docType, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to list permission rules: %v", err)
 		return nil, err
@@ -323,7 +328,7 @@ func (r *permissionRepo) ListPermissionRules(ctx context.Context, roleID int64,
 			&rule.CanRead, &rule.CanWrite, &rule.CanCreate, &rule.CanDelete,
 			&rule.CanSubmit, &rule.CanCancel, &rule.CanAmend, &rule.CanReport,
 			&rule.CanExport, &rule.CanImport, &rule.CanShare, &rule.CanPrint,
-			&rule.CanEmail, &rule.OnlyIfCreator, &rule.CreatedAt, &rule.UpdatedAt,
+			&rule.CanEmail, &rule.CanSetUserPermissions, &rule.OnlyIfCreator, &rule.Condition, &rule.CreatedAt, &rule.UpdatedAt,
 		)
 		if err != nil {
 			r.log.Errorf("failed to scan permission rule: %v", err)
@@ -346,39 +351,47 @@ func (r *permissionRepo) BatchCreatePermissionRules(ctx context.Context, rules [
 		return nil
 	}
 
-	// 使用事务处理批量插入
-	tx, err := r.data.db.BeginTx(ctx, nil)
-	if err != nil {
-		r.log.Errorf("failed to begin transaction: %v", err)
-		return err
-	}
-	defer tx.Rollback()
-
 	query := `
 		INSERT INTO permission_rules (role_id, doc_type, permission_level, can_read, can_write, can_create,
 		                            can_delete, can_submit, can_cancel, can_amend, can_report, can_export, 
-		                            can_import, can_share, can_print, can_email, only_if_creator, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`
-
-	for _, rule := range rules {
-		_, err = tx.ExecContext(ctx, query,
-			rule.RoleID, rule.DocType, rule.PermissionLevel, rule.CanRead, rule.CanWrite,
-			rule.CanCreate, rule.CanDelete, rule.CanSubmit, rule.CanCancel, rule.CanAmend,
-			rule.CanReport, rule.CanExport, rule.CanImport, rule.CanShare, rule.CanPrint,
-			rule.CanEmail, rule.OnlyIfCreator, rule.CreatedAt, rule.UpdatedAt,
-		)
-		if err != nil {
-			r.log.Errorf("failed to batch create permission rule: %v", err)
-			return err
+		                            can_import, can_share, can_print, can_email, only_if_creator, condition, created_at, updated_at,
+		                            company_id, can_set_user_permissions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NULLIF($18, ''), $19, $20, $21, $22)`
+
+	// 使用事务处理批量插入
+	companyID := biz.CompanyFromContext(ctx)
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		for _, rule := range rules {
+			_, err := tx.ExecContext(ctx, query,
+				rule.RoleID, rule.DocType, rule.PermissionLevel, rule.CanRead, rule.CanWrite,
+				rule.CanCreate, rule.CanDelete, rule.CanSubmit, rule.CanCancel, rule.CanAmend,
+				rule.CanReport, rule.CanExport, rule.CanImport, rule.CanShare, rule.CanPrint,
+				rule.CanEmail, rule.OnlyIfCreator, rule.Condition, rule.CreatedAt, rule.UpdatedAt, companyID,
+				rule.CanSetUserPermissions,
+			)
+			if err != nil {
+				r.log.Errorf("failed to batch create permission rule: %v", err)
+				return err
+			}
 		}
-	}
+		return nil
+	})
+}
 
-	if err = tx.Commit(); err != nil {
-		r.log.Errorf("failed to commit transaction: %v", err)
-		return err
+// UpsertPermissionRules 在一个事务中批量写入权限规则，已存在的规则覆盖其权限设置和条件
+func (r *permissionRepo) UpsertPermissionRules(ctx context.Context, rules []*biz.PermissionRule) error {
+	if len(rules) == 0 {
+		return nil
 	}
 
-	return nil
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		if err := upsertPermissionRules(ctx, r.data.conn(ctx), rules); err != nil {
+			r.log.Errorf("failed to upsert permission rule: %v", err)
+			return err
+		}
+		return nil
+	})
 }
 
 func (r *permissionRepo) BatchCreateUserPermissions(ctx context.Context, userPerms []*biz.UserPermission) error {
@@ -386,37 +399,28 @@ func (r *permissionRepo) BatchCreateUserPermissions(ctx context.Context, userPer
 		return nil
 	}
 
-	// 使用事务处理批量插入
-	tx, err := r.data.db.BeginTx(ctx, nil)
-	if err != nil {
-		r.log.Errorf("failed to begin transaction: %v", err)
-		return err
-	}
-	defer tx.Rollback()
-
 	query := `
 		INSERT INTO user_permissions (user_id, permission_value, doc_name, doc_type, is_default,
-		                            created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
-
-	for _, userPerm := range userPerms {
-		_, err = tx.ExecContext(ctx, query,
-			userPerm.UserID, userPerm.Value, userPerm.DocName,
-			userPerm.DocType, userPerm.IsDefault,
-			userPerm.CreatedAt, userPerm.UpdatedAt,
-		)
-		if err != nil {
-			r.log.Errorf("failed to batch create user permission: %v", err)
-			return err
-		}
-	}
+		                            created_at, updated_at, company_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
-	if err = tx.Commit(); err != nil {
-		r.log.Errorf("failed to commit transaction: %v", err)
-		return err
-	}
-
-	return nil
+	// 使用事务处理批量插入
+	companyID := biz.CompanyFromContext(ctx)
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		for _, userPerm := range userPerms {
+			_, err := tx.ExecContext(ctx, query,
+				userPerm.UserID, userPerm.Value, userPerm.DocName,
+				userPerm.DocType, userPerm.IsDefault,
+				userPerm.CreatedAt, userPerm.UpdatedAt, companyID,
+			)
+			if err != nil {
+				r.log.Errorf("failed to batch create user permission: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // UserPermission Operations
@@ -424,14 +428,14 @@ func (r *permissionRepo) CreateUserPermission(ctx context.Context, userPerm *biz
 	var id int64
 	query := `
 		INSERT INTO user_permissions (user_id, permission_value, doc_name, doc_type, is_default,
-		                            created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		                            created_at, updated_at, company_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id`
 
-	err := r.data.db.QueryRowContext(ctx, query,
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
 		userPerm.UserID, userPerm.Value, userPerm.DocName,
 		userPerm.DocType, userPerm.IsDefault,
-		userPerm.CreatedAt, userPerm.UpdatedAt,
+		userPerm.CreatedAt, userPerm.UpdatedAt, biz.CompanyFromContext(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -449,9 +453,9 @@ func (r *permissionRepo) GetUserPermission(ctx context.Context, id int64) (*biz.
 	query := `
 		SELECT id, user_id, permission_value, doc_name, doc_type, is_default,
 		       created_at, updated_at
-		FROM user_permissions WHERE id = $1`
+		FROM user_permissions WHERE id = $1 AND company_id = $2`
 
-	err := r.data.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, id, biz.CompanyFromContext(ctx)).Scan(
 		&userPerm.ID, &userPerm.UserID, &userPerm.Value, &userPerm.DocName,
 		&userPerm.DocType, &userPerm.IsDefault,
 		&userPerm.CreatedAt, &userPerm.UpdatedAt,
@@ -473,13 +477,13 @@ func (r *permissionRepo) UpdateUserPermission(ctx context.Context, userPerm *biz
 		UPDATE user_permissions 
 		SET user_id = $1, permission_value = $2, doc_name = $3, doc_type = $4,
 		    is_default = $5, updated_at = $6
-		WHERE id = $7`
+		WHERE id = $7 AND company_id = $8`
 
 	userPerm.UpdatedAt = time.Now()
-	_, err := r.data.db.ExecContext(ctx, query,
+	_, err := r.data.conn(ctx).ExecContext(ctx, query,
 		userPerm.UserID, userPerm.Value, userPerm.DocName,
 		userPerm.DocType, userPerm.IsDefault,
-		userPerm.UpdatedAt, userPerm.ID,
+		userPerm.UpdatedAt, userPerm.ID, biz.CompanyFromContext(ctx),
 	)
 
 	if err != nil {
@@ -491,7 +495,7 @@ func (r *permissionRepo) UpdateUserPermission(ctx context.Context, userPerm *biz
 }
 
 func (r *permissionRepo) DeleteUserPermission(ctx context.Context, id int64) error {
-	_, err := r.data.db.ExecContext(ctx, "DELETE FROM user_permissions WHERE id = $1", id)
+	_, err := r.data.conn(ctx).ExecContext(ctx, "DELETE FROM user_permissions WHERE id = $1 AND company_id = $2", id, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to delete user permission: %v", err)
 		return err
@@ -504,12 +508,12 @@ func (r *permissionRepo) ListUserPermissions(ctx context.Context, userID int64,
 		SELECT id, user_id, permission_value, doc_name, doc_type, is_default,
 		       created_at, updated_at
 		FROM user_permissions
-		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR doc_type = $2)
+		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR doc_type = $2) AND company_id = $5
 		ORDER BY doc_type, user_id, permission_value
 		LIMIT $3 OFFSET $4`
 
 	offset := (page - 1) * size
-	rows, err := r.data.db.QueryContext(ctx, query, userID, docType, size, offset)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, userID, docType, size, offset, biz.CompanyFromContext(ctx))
 	if err != nil {
 		r.log.Errorf("failed to list user permissions: %v", err)
 		return nil, err
@@ -545,10 +549,10 @@ func (r *permissionRepo) GetUserPermissionsCount(ctx context.Context, userID int
 	query := `
 		SELECT COUNT(*)
 		FROM user_permissions
-		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR doc_type = $2)`
+		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR doc_type = $2) AND company_id = $3`
 
 	var count int32
-	err := r.data.db.QueryRowContext(ctx, query, userID, docType).Scan(&count)
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, userID, docType, biz.CompanyFromContext(ctx)).Scan(&count)
 	if err != nil {
 		r.log.Errorf("failed to count user permissions: %v", err)
 		return 0, err
@@ -566,7 +570,7 @@ func (r *permissionRepo) CreateFieldPermissionLevel(ctx context.Context, fieldPe
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id`
 
-	err := r.data.db.QueryRowContext(ctx, query,
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
 		fieldPerm.DocType, fieldPerm.FieldName, fieldPerm.PermissionLevel,
 		"", "", false, // 默认值
 		fieldPerm.CreatedAt, fieldPerm.UpdatedAt,
@@ -591,7 +595,7 @@ func (r *permissionRepo) GetFieldPermissionLevel(ctx context.Context, id int64)
 
 	var fieldLabel, fieldType string
 	var isMandatory bool
-	err := r.data.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&fieldPerm.ID, &fieldPerm.DocType, &fieldPerm.FieldName,
 		&fieldPerm.PermissionLevel, &fieldLabel, &fieldType,
 		&isMandatory, &fieldPerm.CreatedAt, &fieldPerm.UpdatedAt,
@@ -618,7 +622,7 @@ func (r *permissionRepo) ListFieldPermissionLevels(ctx context.Context, docType
 		LIMIT $2 OFFSET $3`
 
 	offset := (page - 1) * size
-	rows, err := r.data.db.QueryContext(ctx, query, docType, size, offset)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, docType, size, offset)
 	if err != nil {
 		r.log.Errorf("failed to list field permission levels: %v", err)
 		return nil, err
@@ -653,7 +657,7 @@ func (r *permissionRepo) ListFieldPermissionLevels(ctx context.Context, docType
 }
 
 func (r *permissionRepo) DeleteFieldPermissionLevel(ctx context.Context, id int64) error {
-	_, err := r.data.db.ExecContext(ctx, "DELETE FROM field_permission_levels WHERE id = $1", id)
+	_, err := r.data.conn(ctx).ExecContext(ctx, "DELETE FROM field_permission_levels WHERE id = $1", id)
 	if err != nil {
 		r.log.Errorf("failed to delete field permission level: %v", err)
 		return err
@@ -669,7 +673,7 @@ func (r *permissionRepo) UpdateFieldPermissionLevel(ctx context.Context, fieldPe
 		WHERE id = $8`
 
 	fieldPerm.UpdatedAt = time.Now()
-	_, err := r.data.db.ExecContext(ctx, query,
+	_, err := r.data.conn(ctx).ExecContext(ctx, query,
 		fieldPerm.DocType, fieldPerm.FieldName, fieldPerm.PermissionLevel,
 		"", "", false, fieldPerm.UpdatedAt, fieldPerm.ID,
 	)
@@ -698,7 +702,7 @@ func (r *permissionRepo) CreateDocumentWorkflowState(ctx context.Context, state
 		docName = fmt.Sprintf("%d", state.DocID)
 	}
 
-	err := r.data.db.QueryRowContext(ctx, query,
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
 		state.DocType, docName, state.WorkflowState,
 		"", state.SubmittedBy, state.CreatedAt, // 使用空字符串作为workflow_action，使用SubmittedBy作为created_by
 	).Scan(&id)
@@ -724,7 +728,7 @@ func (r *permissionRepo) GetDocumentWorkflowState(ctx context.Context, stateID i
 
 	var docName string
 	var createdBy sql.NullInt64
-	err := r.data.db.QueryRowContext(ctx, query, stateID).Scan(
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, stateID).Scan(
 		&state.ID, &state.DocType, &docName,
 		&state.WorkflowState, &workflowAction,
 		&createdBy, &state.CreatedAt,
@@ -752,7 +756,7 @@ func (r *permissionRepo) GetDocumentWorkflowState(ctx context.Context, stateID i
 }
 
 func (r *permissionRepo) DeleteDocumentWorkflowState(ctx context.Context, stateID int64) error {
-	_, err := r.data.db.ExecContext(ctx,
+	_, err := r.data.conn(ctx).ExecContext(ctx,
 		"DELETE FROM document_workflow_states WHERE id = $1",
 		stateID)
 	if err != nil {
@@ -780,7 +784,7 @@ func (r *permissionRepo) ListDocumentWorkflowStates(ctx context.Context, docType
 		LIMIT $5 OFFSET $6`
 
 	offset := (page - 1) * size
-	rows, err := r.data.db.QueryContext(ctx, query, docType, documentName, state, userID, size, offset)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, docType, documentName, state, userID, size, offset)
 	if err != nil {
 		r.log.Errorf("failed to list document workflow states: %v", err)
 		return nil, err
@@ -822,51 +826,29 @@ func (r *permissionRepo) ListDocumentWorkflowStates(ctx context.Context, docType
 }
 
 // Permission Checking Operations
+
+// CheckPermission 检查用户在其生效角色（含继承角色）上是否拥有文档类型级的指定操作权限；
+// 带条件或仅创建者可访问的规则只授予部分文档，由数据范围判断，不满足文档类型级检查
 func (r *permissionRepo) CheckPermission(ctx context.Context, userID int64, documentType, action string, permissionLevel int) (bool, error) {
-	// Construct dynamic query based on the action
-	var columnCheck string
-	switch action {
-	case "read":
-		columnCheck = "pr.read"
-	case "write":
-		columnCheck = "pr.write"
-	case "create":
-		columnCheck = "pr.\"create\""
-	case "delete":
-		columnCheck = "pr.\"delete\""
-	case "submit":
-		columnCheck = "pr.submit"
-	case "cancel":
-		columnCheck = "pr.cancel"
-	case "amend":
-		columnCheck = "pr.amend"
-	case "print":
-		columnCheck = "pr.print"
-	case "email":
-		columnCheck = "pr.email"
-	case "import":
-		columnCheck = "pr.import"
-	case "export":
-		columnCheck = "pr.export"
-	case "share":
-		columnCheck = "pr.share"
-	case "report":
-		columnCheck = "pr.report"
-	default:
+	if !isPermissionAction(action) {
 		return false, fmt.Errorf("unsupported permission action: %s", action)
 	}
 
-	query := fmt.Sprintf(`
-		SELECT CASE WHEN COUNT(*) > 0 THEN 1 ELSE 0 END
-		FROM user_roles ur
-		INNER JOIN permission_rules pr ON ur.role_id = pr.role
-		WHERE ur.user_id = $1 
-		  AND pr.document_type = $2
-		  AND pr.permission_level = $3
-		  AND %s`, columnCheck)
+	query := userEffectiveRolesCTE + fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM effective_roles er
+			INNER JOIN permission_rules pr ON pr.role_id = er.role_id
+			WHERE pr.doc_type = $2
+			  AND pr.permission_level = $3
+			  AND pr.company_id = $4
+			  AND pr.can_%s
+			  AND COALESCE(pr.condition, '') = ''
+			  AND NOT COALESCE(pr.only_if_creator, FALSE)
+		)`, action)
 
 	var hasPermission bool
-	err := r.data.db.QueryRowContext(ctx, query, userID, documentType, permissionLevel).Scan(&hasPermission)
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, userID, documentType, permissionLevel, biz.CompanyFromContext(ctx)).Scan(&hasPermission)
 	if err != nil {
 		r.log.Errorf("failed to check permission: %v", err)
 		return false, err
@@ -875,6 +857,16 @@ func (r *permissionRepo) CheckPermission(ctx context.Context, userID int64, docu
 	return hasPermission, nil
 }
 
+// isPermissionAction 判断是否为权限规则支持的操作，操作名与can_*列一一对应
+func isPermissionAction(action string) bool {
+	for _, a := range biz.PermissionRuleActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *permissionRepo) GetDocumentWorkflowStatesCount(ctx context.Context, docType, documentName, state string, userID int64) (int32, error) {
 	query := `
 		SELECT COUNT(*) 
@@ -885,7 +877,7 @@ func (r *permissionRepo) GetDocumentWorkflowStatesCount(ctx context.Context, doc
 		AND ($4 = 0 OR dws.created_by = $4)`
 
 	var count int32
-	err := r.data.db.QueryRowContext(ctx, query, docType, documentName, state, userID).Scan(&count)
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, docType, documentName, state, userID).Scan(&count)
 	if err != nil {
 		r.log.Errorf("failed to count document workflow states: %v", err)
 		return 0, err
@@ -901,7 +893,7 @@ func (r *permissionRepo) GetFieldPermissionLevelsCount(ctx context.Context, docT
 		WHERE document_type = $1`
 
 	var count int32
-	err := r.data.db.QueryRowContext(ctx, query, docType).Scan(&count)
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, docType).Scan(&count)
 	if err != nil {
 		r.log.Errorf("failed to count field permission levels: %v", err)
 		return 0, err
@@ -911,16 +903,16 @@ func (r *permissionRepo) GetFieldPermissionLevelsCount(ctx context.Context, docT
 }
 
 func (r *permissionRepo) GetUserPermissionLevel(ctx context.Context, userID int64, documentType string) (int, error) {
-	query := `
+	query := userEffectiveRolesCTE + `
 		SELECT COALESCE(MIN(pr.permission_level), 0)
-		FROM user_roles ur
-		INNER JOIN permission_rules pr ON ur.role_id = pr.role
-		WHERE ur.user_id = $1 
-		  AND pr.document_type = $2
-		  AND (pr.read = 1 OR pr.write = 1)`
+		FROM effective_roles er
+		INNER JOIN permission_rules pr ON pr.role_id = er.role_id
+		WHERE pr.doc_type = $2
+		  AND pr.company_id = $3
+		  AND (pr.can_read OR pr.can_write)`
 
 	var level int
-	err := r.data.db.QueryRowContext(ctx, query, userID, documentType).Scan(&level)
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, userID, documentType, biz.CompanyFromContext(ctx)).Scan(&level)
 	if err != nil {
 		r.log.Errorf("failed to get user permission level: %v", err)
 		return 0, err
@@ -929,52 +921,117 @@ func (r *permissionRepo) GetUserPermissionLevel(ctx context.Context, userID int6
 	return level, nil
 }
 
+// GetUserLevelPermissions 按权限级别汇总用户所有生效角色（含继承角色）的读写授权
+func (r *permissionRepo) GetUserLevelPermissions(ctx context.Context, userID int64, documentType string) (*biz.LevelPermissions, error) {
+	query := userEffectiveRolesCTE + `
+		SELECT pr.permission_level, BOOL_OR(pr.can_read), BOOL_OR(pr.can_write)
+		FROM effective_roles er
+		INNER JOIN permission_rules pr ON pr.role_id = er.role_id
+		WHERE pr.doc_type = $2 AND pr.company_id = $3
+		GROUP BY pr.permission_level`
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, userID, documentType, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to get user level permissions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := &biz.LevelPermissions{}
+	for rows.Next() {
+		var level int
+		var canRead, canWrite bool
+		if err := rows.Scan(&level, &canRead, &canWrite); err != nil {
+			r.log.Errorf("failed to scan user level permission: %v", err)
+			return nil, err
+		}
+		perms.Grant(level, canRead, canWrite)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate user level permissions: %v", err)
+		return nil, err
+	}
+
+	return perms, nil
+}
+
 func (r *permissionRepo) FilterDocumentsByPermission(ctx context.Context, userID int64, documentType string, documents []map[string]interface{}) ([]map[string]interface{}, error) {
-	// 获取用户权限级别
-	permissionLevel, err := r.GetUserPermissionLevel(ctx, userID, documentType)
+	// 获取用户各级别的读写授权
+	levelPerms, err := r.GetUserLevelPermissions(ctx, userID, documentType)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldLevels, err := r.getFieldLevelMap(ctx, documentType)
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取字段权限配置 (使用较大的limit来获取所有字段权限)
-	fieldPermissions, err := r.ListFieldPermissionLevels(ctx, documentType, 1, 1000)
+	return filterDocumentFields(levelPerms, fieldLevels, documents), nil
+}
+
+// getFieldLevelMap 获取文档类型的字段权限级别映射
+func (r *permissionRepo) getFieldLevelMap(ctx context.Context, documentType string) (map[string]int, error) {
+	fieldPermissions, err := biz.ListAllFieldPermissionLevels(ctx, r, documentType)
 	if err != nil {
 		return nil, err
 	}
 
-	// 过滤文档字段
+	fieldLevels := make(map[string]int, len(fieldPermissions))
+	for _, fp := range fieldPermissions {
+		fieldLevels[fp.FieldName] = fp.PermissionLevel
+	}
+
+	return fieldLevels, nil
+}
+
+// filterDocumentFields 按各级别读权限过滤文档字段，未登记级别的字段按文档级(0)处理
+func filterDocumentFields(levelPerms *biz.LevelPermissions, fieldLevels map[string]int, documents []map[string]interface{}) []map[string]interface{} {
 	var filteredDocuments []map[string]interface{}
 	for _, doc := range documents {
 		filteredDoc := make(map[string]interface{})
 		for field, value := range doc {
-			canRead := true
-
-			// 检查字段权限
-			for _, fp := range fieldPermissions {
-				if fp.FieldName == field && fp.PermissionLevel > permissionLevel {
-					canRead = false // 当前结构体没有Read字段，默认为false
-					break
-				}
-			}
-
-			if canRead {
+			if levelPerms.CanRead(fieldLevels[field]) {
 				filteredDoc[field] = value
 			}
 		}
 		filteredDocuments = append(filteredDocuments, filteredDoc)
 	}
 
-	return filteredDocuments, nil
+	return filteredDocuments
 }
 
+// buildAccessibleFields 按各级别读写权限计算字段的可访问性
+func buildAccessibleFields(levelPerms *biz.LevelPermissions, fieldLevels map[string]int, permission string) []*biz.AccessibleField {
+	fieldNames := make([]string, 0, len(fieldLevels))
+	for name := range fieldLevels {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+
+	accessibleFields := make([]*biz.AccessibleField, 0, len(fieldNames))
+	for _, name := range fieldNames {
+		level := fieldLevels[name]
+		accessibleFields = append(accessibleFields, &biz.AccessibleField{
+			FieldName:       name,
+			CanAccess:       levelPerms.Allows(level, permission),
+			PermissionLevel: level,
+		})
+	}
+
+	return accessibleFields
+}
+
+// GetUserRoles 获取用户的生效角色编码，包括沿继承链获得的上级角色
 func (r *permissionRepo) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
-	query := `
-		SELECT role_name 
-		FROM user_roles ur 
-		JOIN roles r ON ur.role_id = r.id 
-		WHERE ur.user_id = $1`
+	query := userEffectiveRolesCTE + `
+		SELECT DISTINCT r.code
+		FROM effective_roles er
+		INNER JOIN roles r ON r.id = er.role_id
+		ORDER BY r.code`
 
-	rows, err := r.data.db.QueryContext(ctx, query, userID)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, userID)
 	if err != nil {
 		r.log.Errorf("failed to get user roles: %v", err)
 		return nil, err
@@ -999,6 +1056,16 @@ func (r *permissionRepo) GetUserRoles(ctx context.Context, userID int64) ([]stri
 	return roles, nil
 }
 
+// ClearUserPermissionCache 数据库仓储不缓存，缓存由CachedPermissionRepo清除
+func (r *permissionRepo) ClearUserPermissionCache(ctx context.Context, userIDs ...int64) error {
+	return nil
+}
+
+// ClearRolePermissionCache 数据库仓储不缓存，缓存由CachedPermissionRepo清除
+func (r *permissionRepo) ClearRolePermissionCache(ctx context.Context, roleID int64) error {
+	return nil
+}
+
 // CheckDocumentPermission 检查文档权限
 func (r *permissionRepo) CheckDocumentPermission(ctx context.Context, req *biz.PermissionCheckRequest) (bool, error) {
 	// 根据请求检查权限
@@ -1007,66 +1074,62 @@ func (r *permissionRepo) CheckDocumentPermission(ctx context.Context, req *biz.P
 
 // GetAccessibleFields 获取可访问字段
 func (r *permissionRepo) GetAccessibleFields(ctx context.Context, req *biz.FieldPermissionRequest) ([]*biz.AccessibleField, error) {
-	// 获取用户权限级别
-	userLevel, err := r.GetUserPermissionLevel(ctx, req.UserID, req.DocType)
+	// 获取用户各级别的读写授权
+	levelPerms, err := r.GetUserLevelPermissions(ctx, req.UserID, req.DocType)
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取字段权限配置 (使用较大的limit来获取所有字段权限)
-	fieldPermissions, err := r.ListFieldPermissionLevels(ctx, req.DocType, 1, 1000)
+	fieldLevels, err := r.getFieldLevelMap(ctx, req.DocType)
 	if err != nil {
 		return nil, err
 	}
 
-	var accessibleFields []*biz.AccessibleField
-	for _, fp := range fieldPermissions {
-		accessible := &biz.AccessibleField{
-			FieldName:       fp.FieldName,
-			CanAccess:       userLevel >= fp.PermissionLevel,
-			PermissionLevel: fp.PermissionLevel,
-		}
-		accessibleFields = append(accessibleFields, accessible)
-	}
-
-	return accessibleFields, nil
+	return buildAccessibleFields(levelPerms, fieldLevels, req.Permission), nil
 }
 
 // GetUserEnhancedPermissions 获取用户增强权限
+// 汇总用户生效角色（含继承角色）上的权限规则，RoleCode为规则实际所属的角色
 func (r *permissionRepo) GetUserEnhancedPermissions(ctx context.Context, userID int64, docType string) ([]*biz.EnhancedUserPermission, error) {
-	// 获取用户权限 (使用较大的limit来获取所有用户权限)
-	userPerms, err := r.ListUserPermissions(ctx, userID, docType, 1, 1000)
+	query := userEffectiveRolesCTE + `
+		SELECT DISTINCT pr.doc_type, pr.permission_level, ro.code, ro.name,
+		       pr.can_read, pr.can_write, pr.can_create, pr.can_delete, pr.can_submit, pr.can_cancel,
+		       pr.can_amend, pr.can_print, pr.can_email, pr.can_import, pr.can_export, pr.can_share,
+		       pr.can_report, pr.only_if_creator
+		FROM effective_roles er
+		INNER JOIN permission_rules pr ON pr.role_id = er.role_id
+		INNER JOIN roles ro ON ro.id = er.role_id
+		WHERE ($2 = '' OR pr.doc_type = $2) AND pr.company_id = $3
+		ORDER BY pr.doc_type, pr.permission_level, ro.code`
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, userID, docType, biz.CompanyFromContext(ctx))
 	if err != nil {
+		r.log.Errorf("failed to get user enhanced permissions: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
 	var enhancedPerms []*biz.EnhancedUserPermission
-	for _, perm := range userPerms {
-		enhanced := &biz.EnhancedUserPermission{
-			UserID:          perm.UserID,
-			DocType:         perm.DocType,
-			PermissionLevel: 0,  // 默认权限级别
-			RoleCode:        "", // 需要从用户角色中获取
-			RoleName:        "", // 需要从用户角色中获取
-
-			// 权限列表，这里设置为默认值，实际应该从权限规则中计算
-			CanRead:       true,
-			CanWrite:      false,
-			CanCreate:     false,
-			CanDelete:     false,
-			CanSubmit:     false,
-			CanCancel:     false,
-			CanAmend:      false,
-			CanPrint:      false,
-			CanEmail:      false,
-			CanImport:     false,
-			CanExport:     false,
-			CanShare:      false,
-			CanReport:     false,
-			OnlyIfCreator: false,
+	for rows.Next() {
+		enhanced := &biz.EnhancedUserPermission{UserID: userID}
+		err := rows.Scan(
+			&enhanced.DocType, &enhanced.PermissionLevel, &enhanced.RoleCode, &enhanced.RoleName,
+			&enhanced.CanRead, &enhanced.CanWrite, &enhanced.CanCreate, &enhanced.CanDelete,
+			&enhanced.CanSubmit, &enhanced.CanCancel, &enhanced.CanAmend, &enhanced.CanPrint,
+			&enhanced.CanEmail, &enhanced.CanImport, &enhanced.CanExport, &enhanced.CanShare,
+			&enhanced.CanReport, &enhanced.OnlyIfCreator,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan user enhanced permission: %v", err)
+			return nil, err
 		}
 		enhancedPerms = append(enhancedPerms, enhanced)
 	}
 
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate user enhanced permissions: %v", err)
+		return nil, err
+	}
+
 	return enhancedPerms, nil
 }
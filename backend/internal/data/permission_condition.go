@@ -0,0 +1,128 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"erp-system/internal/biz"
+
+	"github.com/lib/pq"
+)
+
+// GetConditionFieldTypes 按字段权限级别中登记的字段类型获取条件表达式类型
+func (r *permissionRepo) GetConditionFieldTypes(ctx context.Context, docType string) (map[string]biz.ConditionType, error) {
+	rows, err := r.data.db.QueryContext(ctx,
+		"SELECT field_name, COALESCE(field_type, '') FROM field_permission_levels WHERE doc_type = $1", docType)
+	if err != nil {
+		r.log.Errorf("failed to get condition field types: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	fieldTypes := make(map[string]biz.ConditionType)
+	for rows.Next() {
+		var name, fieldType string
+		if err := rows.Scan(&name, &fieldType); err != nil {
+			r.log.Errorf("failed to scan condition field type: %v", err)
+			return nil, err
+		}
+		fieldTypes[name] = biz.ConditionFieldType(fieldType)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate condition field types: %v", err)
+		return nil, err
+	}
+
+	return fieldTypes, nil
+}
+
+// ListUserPermissionRules 获取用户生效角色（含继承角色）在指定文档类型和级别上的权限规则
+func (r *permissionRepo) ListUserPermissionRules(ctx context.Context, userID int64, docType string, permissionLevel int) ([]*biz.PermissionRule, error) {
+	query := userEffectiveRolesCTE + `
+		SELECT DISTINCT pr.id, pr.role_id, pr.doc_type, pr.permission_level, pr.can_read, pr.can_write, pr.can_create,
+		       pr.can_delete, pr.can_submit, pr.can_cancel, pr.can_amend, pr.can_report, pr.can_export, pr.can_import,
+		       pr.can_share, pr.can_print, pr.can_email, pr.can_set_user_permissions, pr.only_if_creator, COALESCE(pr.condition, '')
+		FROM effective_roles er
+		INNER JOIN permission_rules pr ON pr.role_id = er.role_id
+		WHERE pr.doc_type = $2 AND pr.permission_level = $3 AND pr.company_id = $4
+		ORDER BY pr.id`
+
+	rows, err := r.data.db.QueryContext(ctx, query, userID, docType, permissionLevel, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to list user permission rules: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*biz.PermissionRule
+	for rows.Next() {
+		var rule biz.PermissionRule
+		err := rows.Scan(
+			&rule.ID, &rule.RoleID, &rule.DocType, &rule.PermissionLevel,
+			&rule.CanRead, &rule.CanWrite, &rule.CanCreate, &rule.CanDelete,
+			&rule.CanSubmit, &rule.CanCancel, &rule.CanAmend, &rule.CanReport,
+			&rule.CanExport, &rule.CanImport, &rule.CanShare, &rule.CanPrint,
+			&rule.CanEmail, &rule.CanSetUserPermissions, &rule.OnlyIfCreator, &rule.Condition,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan user permission rule: %v", err)
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate user permission rules: %v", err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// GetUserConditionAttributes 获取条件表达式可引用的用户属性：基本信息、生效角色编码、当前公司和在当前公司所属的组织
+func (r *permissionRepo) GetUserConditionAttributes(ctx context.Context, userID int64) (map[string]interface{}, error) {
+	companyID := biz.CompanyFromContext(ctx)
+	query := `
+		SELECT u.username, u.email,
+		       (SELECT uo.org_id FROM user_organizations uo
+		        WHERE uo.user_id = u.id AND uo.company_id = $2 AND uo.is_active = TRUE AND uo.is_primary = TRUE
+		        LIMIT 1),
+		       ARRAY(SELECT uo.org_id FROM user_organizations uo
+		             WHERE uo.user_id = u.id AND uo.company_id = $2 AND uo.is_active = TRUE
+		             ORDER BY uo.org_id)
+		FROM users u
+		WHERE u.id = $1`
+
+	var username, email string
+	var primaryOrgID sql.NullInt64
+	var orgIDs pq.Int64Array
+	err := r.data.db.QueryRowContext(ctx, query, userID, companyID).Scan(&username, &email, &primaryOrgID, &orgIDs)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		r.log.Errorf("failed to get user condition attributes: %v", err)
+		return nil, err
+	}
+
+	roles, err := r.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := map[string]interface{}{
+		"id":         userID,
+		"username":   username,
+		"email":      email,
+		"roles":      roles,
+		"company_id": int64(companyID),
+		"org_ids":    []int64(orgIDs),
+		"org_id":     nil,
+	}
+	if primaryOrgID.Valid {
+		attributes["org_id"] = primaryOrgID.Int64
+	}
+	return attributes, nil
+}
@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+
+	"erp-system/internal/biz"
+)
+
+// upsertPermissionRuleQuery 写入权限规则，同一公司、角色、文档类型和级别已存在规则时覆盖其权限设置和条件，便于模板重复应用
+const upsertPermissionRuleQuery = `
+	INSERT INTO permission_rules (role_id, doc_type, permission_level, can_read, can_write, can_create,
+	                            can_delete, can_submit, can_cancel, can_amend, can_report, can_export,
+	                            can_import, can_share, can_print, can_email, only_if_creator, created_at, updated_at,
+	                            company_id, can_set_user_permissions, condition)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, NULLIF($22, ''))
+	ON CONFLICT (company_id, role_id, doc_type, permission_level) DO UPDATE
+	SET can_read = EXCLUDED.can_read, can_write = EXCLUDED.can_write, can_create = EXCLUDED.can_create,
+	    can_delete = EXCLUDED.can_delete, can_submit = EXCLUDED.can_submit, can_cancel = EXCLUDED.can_cancel,
+	    can_amend = EXCLUDED.can_amend, can_report = EXCLUDED.can_report, can_export = EXCLUDED.can_export,
+	    can_import = EXCLUDED.can_import, can_share = EXCLUDED.can_share, can_print = EXCLUDED.can_print,
+	    can_email = EXCLUDED.can_email, can_set_user_permissions = EXCLUDED.can_set_user_permissions,
+	    only_if_creator = EXCLUDED.only_if_creator, condition = EXCLUDED.condition,
+	    updated_at = EXCLUDED.updated_at`
+
+// upsertPermissionRules 在事务中批量写入当前公司的权限规则
+func upsertPermissionRules(ctx context.Context, tx dbConn, rules []*biz.PermissionRule) error {
+	companyID := biz.CompanyFromContext(ctx)
+	for _, rule := range rules {
+		_, err := tx.ExecContext(ctx, upsertPermissionRuleQuery,
+			rule.RoleID, rule.DocType, rule.PermissionLevel, rule.CanRead, rule.CanWrite,
+			rule.CanCreate, rule.CanDelete, rule.CanSubmit, rule.CanCancel, rule.CanAmend,
+			rule.CanReport, rule.CanExport, rule.CanImport, rule.CanShare, rule.CanPrint,
+			rule.CanEmail, rule.OnlyIfCreator, rule.CreatedAt, rule.UpdatedAt, companyID,
+			rule.CanSetUserPermissions, rule.Condition,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRoleCodes 获取全部角色的ID与编码映射
+func (r *permissionRepo) ListRoleCodes(ctx context.Context) (map[int64]string, error) {
+	rows, err := r.data.conn(ctx).QueryContext(ctx, "SELECT id, code FROM roles")
+	if err != nil {
+		r.log.Errorf("failed to list role codes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			r.log.Errorf("failed to scan role code: %v", err)
+			return nil, err
+		}
+		codes[id] = code
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate role codes: %v", err)
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// ReplacePermissionRules 在一个事务中写入upserts并删除removals
+func (r *permissionRepo) ReplacePermissionRules(ctx context.Context, upserts, removals []*biz.PermissionRule) error {
+	companyID := biz.CompanyFromContext(ctx)
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		for _, rule := range removals {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM permission_rules WHERE id = $1 AND company_id = $2", rule.ID, companyID); err != nil {
+				r.log.Errorf("failed to delete permission rule: %v", err)
+				return err
+			}
+		}
+
+		if err := upsertPermissionRules(ctx, tx, upserts); err != nil {
+			r.log.Errorf("failed to upsert permission rule: %v", err)
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,250 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// permissionTemplateRepo 权限模板仓储实现
+type permissionTemplateRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewPermissionTemplateRepo 创建权限模板仓储
+func NewPermissionTemplateRepo(data *Data, logger log.Logger) biz.PermissionTemplateRepo {
+	return &permissionTemplateRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// CreateTemplate 创建权限模板
+func (r *permissionTemplateRepo) CreateTemplate(ctx context.Context, template *biz.PermissionTemplate) (*biz.PermissionTemplate, error) {
+	levelsJSON, err := json.Marshal(template.Levels)
+	if err != nil {
+		r.log.Errorf("failed to marshal template levels: %v", err)
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO permission_templates (name, description, levels, version, created_at, updated_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+
+	err = r.data.db.QueryRowContext(ctx, query,
+		template.Name, template.Description, levelsJSON, template.Version,
+		template.CreatedAt, template.UpdatedAt, template.CreatedBy,
+	).Scan(&template.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, biz.ErrPermissionTemplateNameExists
+		}
+		r.log.Errorf("failed to create permission template: %v", err)
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// UpdateTemplate 更新权限模板，版本号递增
+func (r *permissionTemplateRepo) UpdateTemplate(ctx context.Context, template *biz.PermissionTemplate) (*biz.PermissionTemplate, error) {
+	levelsJSON, err := json.Marshal(template.Levels)
+	if err != nil {
+		r.log.Errorf("failed to marshal template levels: %v", err)
+		return nil, err
+	}
+
+	query := `
+		UPDATE permission_templates
+		SET name = $1, description = $2, levels = $3, version = version + 1,
+		    updated_at = $4, updated_by = $5
+		WHERE id = $6
+		RETURNING version`
+
+	err = r.data.db.QueryRowContext(ctx, query,
+		template.Name, template.Description, levelsJSON,
+		template.UpdatedAt, template.UpdatedBy, template.ID,
+	).Scan(&template.Version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrPermissionTemplateNotFound
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, biz.ErrPermissionTemplateNameExists
+		}
+		r.log.Errorf("failed to update permission template: %v", err)
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// GetTemplate 获取权限模板
+func (r *permissionTemplateRepo) GetTemplate(ctx context.Context, id int64) (*biz.PermissionTemplate, error) {
+	query := `
+		SELECT id, name, description, levels, version, created_at, updated_at, created_by, updated_by
+		FROM permission_templates WHERE id = $1`
+
+	template, err := r.scanTemplate(r.data.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrPermissionTemplateNotFound
+		}
+		r.log.Errorf("failed to get permission template: %v", err)
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// ListTemplates 获取全部权限模板
+func (r *permissionTemplateRepo) ListTemplates(ctx context.Context) ([]*biz.PermissionTemplate, error) {
+	query := `
+		SELECT id, name, description, levels, version, created_at, updated_at, created_by, updated_by
+		FROM permission_templates
+		ORDER BY name`
+
+	rows, err := r.data.db.QueryContext(ctx, query)
+	if err != nil {
+		r.log.Errorf("failed to list permission templates: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*biz.PermissionTemplate
+	for rows.Next() {
+		template, err := r.scanTemplate(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan permission template: %v", err)
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate permission templates: %v", err)
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// DeleteTemplate 删除权限模板，应用记录级联删除
+func (r *permissionTemplateRepo) DeleteTemplate(ctx context.Context, id int64) error {
+	result, err := r.data.db.ExecContext(ctx, "DELETE FROM permission_templates WHERE id = $1", id)
+	if err != nil {
+		r.log.Errorf("failed to delete permission template: %v", err)
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return biz.ErrPermissionTemplateNotFound
+	}
+	return nil
+}
+
+// ListApplications 获取模板在当前公司的应用记录
+func (r *permissionTemplateRepo) ListApplications(ctx context.Context, templateID int64) ([]*biz.PermissionTemplateApplication, error) {
+	query := `
+		SELECT id, template_id, role_id, doc_type, template_version, applied_at, applied_by
+		FROM permission_template_applications
+		WHERE company_id = $1 AND template_id = $2
+		ORDER BY role_id, doc_type`
+
+	rows, err := r.data.db.QueryContext(ctx, query, biz.CompanyFromContext(ctx), templateID)
+	if err != nil {
+		r.log.Errorf("failed to list template applications: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applications []*biz.PermissionTemplateApplication
+	for rows.Next() {
+		var app biz.PermissionTemplateApplication
+		var appliedBy sql.NullInt64
+		if err := rows.Scan(&app.ID, &app.TemplateID, &app.RoleID, &app.DocType,
+			&app.TemplateVersion, &app.AppliedAt, &appliedBy); err != nil {
+			r.log.Errorf("failed to scan template application: %v", err)
+			return nil, err
+		}
+		if appliedBy.Valid {
+			app.AppliedBy = &appliedBy.Int64
+		}
+		applications = append(applications, &app)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate template applications: %v", err)
+		return nil, err
+	}
+
+	return applications, nil
+}
+
+// SaveApplications 记录模板在当前公司的应用，同一公司、模板、角色和文档类型只保留最近一次
+func (r *permissionTemplateRepo) SaveApplications(ctx context.Context, applications []*biz.PermissionTemplateApplication) error {
+	if len(applications) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO permission_template_applications (company_id, template_id, role_id, doc_type, template_version, applied_at, applied_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (company_id, template_id, role_id, doc_type) DO UPDATE
+		SET template_version = EXCLUDED.template_version, applied_at = EXCLUDED.applied_at,
+		    applied_by = EXCLUDED.applied_by`
+
+	companyID := biz.CompanyFromContext(ctx)
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		for _, app := range applications {
+			if _, err := tx.ExecContext(ctx, query,
+				companyID, app.TemplateID, app.RoleID, app.DocType, app.TemplateVersion, app.AppliedAt, app.AppliedBy,
+			); err != nil {
+				r.log.Errorf("failed to save template application: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r *permissionTemplateRepo) scanTemplate(row rowScanner) (*biz.PermissionTemplate, error) {
+	var template biz.PermissionTemplate
+	var description sql.NullString
+	var levelsJSON []byte
+	var createdBy, updatedBy sql.NullInt64
+
+	err := row.Scan(&template.ID, &template.Name, &description, &levelsJSON, &template.Version,
+		&template.CreatedAt, &template.UpdatedAt, &createdBy, &updatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(levelsJSON, &template.Levels); err != nil {
+		return nil, err
+	}
+	if description.Valid {
+		template.Description = description.String
+	}
+	if createdBy.Valid {
+		template.CreatedBy = &createdBy.Int64
+	}
+	if updatedBy.Valid {
+		template.UpdatedBy = &updatedBy.Int64
+	}
+
+	return &template, nil
+}
@@ -68,6 +68,27 @@ func TestDocType_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "DocType module is required",
 		},
+		{
+			name: "drop field write mode",
+			docType: &biz.DocType{
+				Name:        "TestDoc",
+				Label:       "Test Document",
+				Module:      "TestModule",
+				Permissions: map[string]interface{}{biz.FieldWriteModeKey: biz.FieldWriteModeDrop},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid field write mode should fail",
+			docType: &biz.DocType{
+				Name:        "TestDoc",
+				Label:       "Test Document",
+				Module:      "TestModule",
+				Permissions: map[string]interface{}{biz.FieldWriteModeKey: "ignore"},
+			},
+			wantErr: true,
+			errMsg:  "invalid field write mode",
+		},
 	}
 
 	for _, tt := range tests {
@@ -237,3 +258,38 @@ func TestFieldPermissionLevel_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterDocumentFields(t *testing.T) {
+	perms := &biz.LevelPermissions{}
+	perms.Grant(0, true, false)
+	perms.Grant(3, true, false)
+
+	fieldLevels := map[string]int{"username": 0, "salary": 2, "notes": 3}
+	documents := []map[string]interface{}{
+		{"username": "alice", "salary": 100, "notes": "n", "unregistered": "x"},
+	}
+
+	filtered := filterDocumentFields(perms, fieldLevels, documents)
+	assert.Equal(t, []map[string]interface{}{
+		{"username": "alice", "notes": "n", "unregistered": "x"},
+	}, filtered)
+
+	fields := buildAccessibleFields(perms, fieldLevels, "read")
+	access := make(map[string]bool)
+	for _, f := range fields {
+		access[f.FieldName] = f.CanAccess
+	}
+	assert.Equal(t, map[string]bool{"username": true, "salary": false, "notes": true}, access)
+
+	for _, f := range buildAccessibleFields(perms, fieldLevels, "write") {
+		assert.False(t, f.CanAccess)
+	}
+}
+
+func TestIsPermissionAction(t *testing.T) {
+	for _, action := range biz.PermissionRuleActions {
+		assert.True(t, isPermissionAction(action), action)
+	}
+	assert.False(t, isPermissionAction("read; DROP TABLE roles"))
+	assert.False(t, isPermissionAction(""))
+}
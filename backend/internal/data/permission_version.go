@@ -0,0 +1,382 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// permissionVersionRepo 权限配置版本仓储实现
+type permissionVersionRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewPermissionVersionRepo 创建权限配置版本仓储
+func NewPermissionVersionRepo(data *Data, logger log.Logger) biz.PermissionVersionRepo {
+	return &permissionVersionRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// permissionConfigLockKey 权限配置变更的事务级咨询锁
+const permissionConfigLockKey = 73010033
+
+// LockVersions 在当前事务中获取权限配置锁，直到事务结束，使配置变更与其版本快照按顺序一一对应
+func (r *permissionVersionRepo) LockVersions(ctx context.Context) error {
+	if _, err := r.data.conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", permissionConfigLockKey); err != nil {
+		r.log.Errorf("failed to lock permission config: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RecordVersion 读取当前公司的权限配置并保存为该公司的新版本；ctx处于事务中时快照包含该事务内尚未提交的变更
+func (r *permissionVersionRepo) RecordVersion(ctx context.Context, version *biz.PermissionVersion) (*biz.PermissionVersion, error) {
+	err := r.data.InTx(ctx, func(ctx context.Context) error {
+		tx := r.data.conn(ctx)
+		snapshot, err := capturePermissionSnapshot(ctx, tx)
+		if err != nil {
+			r.log.Errorf("failed to capture permission snapshot: %v", err)
+			return err
+		}
+
+		snapshotJSON, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO permission_config_versions (operation, comment, author_id, rollback_of, snapshot, company_id)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)
+			RETURNING id, created_at`
+
+		err = tx.QueryRowContext(ctx, query,
+			version.Operation, version.Comment, version.AuthorID, version.RollbackOf, snapshotJSON,
+			biz.CompanyFromContext(ctx),
+		).Scan(&version.ID, &version.CreatedAt)
+		if err != nil {
+			r.log.Errorf("failed to record permission version: %v", err)
+			return err
+		}
+
+		version.Snapshot = snapshot
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// GetVersion 获取当前公司的权限配置版本及其快照
+func (r *permissionVersionRepo) GetVersion(ctx context.Context, id int64) (*biz.PermissionVersion, error) {
+	query := `
+		SELECT v.id, v.operation, COALESCE(v.comment, ''), v.author_id, COALESCE(u.username, ''),
+		       v.rollback_of, v.created_at, v.snapshot
+		FROM permission_config_versions v
+		LEFT JOIN users u ON u.id = v.author_id
+		WHERE v.id = $1 AND v.company_id = $2`
+
+	var version biz.PermissionVersion
+	var snapshotJSON []byte
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, id, biz.CompanyFromContext(ctx)).Scan(
+		&version.ID, &version.Operation, &version.Comment, &version.AuthorID, &version.AuthorName,
+		&version.RollbackOf, &version.CreatedAt, &snapshotJSON,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrPermissionVersionNotFound
+		}
+		r.log.Errorf("failed to get permission version: %v", err)
+		return nil, err
+	}
+
+	version.Snapshot = &biz.PermissionSnapshot{}
+	if err := json.Unmarshal(snapshotJSON, version.Snapshot); err != nil {
+		r.log.Errorf("failed to decode permission snapshot %d: %v", id, err)
+		return nil, err
+	}
+
+	return &version, nil
+}
+
+// ListVersions 分页获取当前公司的权限配置版本，按版本号倒序，不含快照
+func (r *permissionVersionRepo) ListVersions(ctx context.Context, page, size int32) ([]*biz.PermissionVersion, int32, error) {
+	companyID := biz.CompanyFromContext(ctx)
+
+	var total int32
+	err := r.data.conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM permission_config_versions WHERE company_id = $1", companyID).Scan(&total)
+	if err != nil {
+		r.log.Errorf("failed to count permission versions: %v", err)
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT v.id, v.operation, COALESCE(v.comment, ''), v.author_id, COALESCE(u.username, ''),
+		       v.rollback_of, v.created_at
+		FROM permission_config_versions v
+		LEFT JOIN users u ON u.id = v.author_id
+		WHERE v.company_id = $3
+		ORDER BY v.id DESC
+		LIMIT $1 OFFSET $2`
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, size, (page-1)*size, companyID)
+	if err != nil {
+		r.log.Errorf("failed to list permission versions: %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var versions []*biz.PermissionVersion
+	for rows.Next() {
+		var version biz.PermissionVersion
+		err := rows.Scan(
+			&version.ID, &version.Operation, &version.Comment, &version.AuthorID, &version.AuthorName,
+			&version.RollbackOf, &version.CreatedAt,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan permission version: %v", err)
+			return nil, 0, err
+		}
+		versions = append(versions, &version)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate permission versions: %v", err)
+		return nil, 0, err
+	}
+
+	return versions, total, nil
+}
+
+// HasVersions 当前公司是否已记录过权限配置版本
+func (r *permissionVersionRepo) HasVersions(ctx context.Context) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM permission_config_versions WHERE company_id = $1)"
+	err := r.data.conn(ctx).QueryRowContext(ctx, query, biz.CompanyFromContext(ctx)).Scan(&exists)
+	if err != nil {
+		r.log.Errorf("failed to check permission versions: %v", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// capturePermissionSnapshot 读取当前公司的权限规则、用户权限，以及在公司间共享的字段权限级别
+func capturePermissionSnapshot(ctx context.Context, tx dbConn) (*biz.PermissionSnapshot, error) {
+	companyID := biz.CompanyFromContext(ctx)
+	snapshot := &biz.PermissionSnapshot{
+		PermissionRules: []*biz.PermissionRule{},
+		UserPermissions: []*biz.UserPermission{},
+		FieldLevels:     []*biz.FieldPermissionLevel{},
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, role_id, doc_type, permission_level, can_read, can_write, can_create,
+		       can_delete, can_submit, can_cancel, can_amend, can_report, can_export, can_import,
+		       can_share, can_print, can_email, can_set_user_permissions, only_if_creator, COALESCE(condition, ''),
+		       created_at, updated_at, created_by, updated_by
+		FROM permission_rules
+		WHERE company_id = $1
+		ORDER BY id`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var rule biz.PermissionRule
+		if err := rows.Scan(
+			&rule.ID, &rule.RoleID, &rule.DocType, &rule.PermissionLevel,
+			&rule.CanRead, &rule.CanWrite, &rule.CanCreate, &rule.CanDelete,
+			&rule.CanSubmit, &rule.CanCancel, &rule.CanAmend, &rule.CanReport,
+			&rule.CanExport, &rule.CanImport, &rule.CanShare, &rule.CanPrint,
+			&rule.CanEmail, &rule.CanSetUserPermissions, &rule.OnlyIfCreator, &rule.Condition, &rule.CreatedAt, &rule.UpdatedAt,
+			&rule.CreatedBy, &rule.UpdatedBy,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		snapshot.PermissionRules = append(snapshot.PermissionRules, &rule)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT id, user_id, doc_type, doc_name, value, applicable_for, hide_descendants, is_default,
+		       created_at, updated_at, created_by, updated_by
+		FROM user_permissions
+		WHERE company_id = $1
+		ORDER BY id`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var permission biz.UserPermission
+		if err := rows.Scan(
+			&permission.ID, &permission.UserID, &permission.DocType, &permission.DocName, &permission.Value,
+			&permission.ApplicableFor, &permission.HideDescendants, &permission.IsDefault,
+			&permission.CreatedAt, &permission.UpdatedAt, &permission.CreatedBy, &permission.UpdatedBy,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		snapshot.UserPermissions = append(snapshot.UserPermissions, &permission)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT id, doc_type, field_name, COALESCE(field_label, ''), permission_level,
+		       COALESCE(field_type, ''), is_mandatory, created_at, updated_at, created_by, updated_by
+		FROM field_permission_levels
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var level biz.FieldPermissionLevel
+		if err := rows.Scan(
+			&level.ID, &level.DocType, &level.FieldName, &level.FieldLabel, &level.PermissionLevel,
+			&level.FieldType, &level.IsMandatory, &level.CreatedAt, &level.UpdatedAt,
+			&level.CreatedBy, &level.UpdatedBy,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		snapshot.FieldLevels = append(snapshot.FieldLevels, &level)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// closeRows 关闭结果集并返回迭代过程中的错误
+func closeRows(rows *sql.Rows) error {
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	return rows.Close()
+}
+
+// RestorePermissionSnapshot 在一个事务中用快照替换当前公司的权限规则和用户权限，
+// 已删除的角色或用户对应的配置会被跳过。字段权限级别在公司间共享，回滚一个公司的配置不改变字段权限级别
+func (r *permissionRepo) RestorePermissionSnapshot(ctx context.Context, snapshot *biz.PermissionSnapshot) error {
+	return r.data.InTx(ctx, func(ctx context.Context) error {
+		return r.restorePermissionSnapshot(ctx, r.data.conn(ctx), snapshot)
+	})
+}
+
+// restorePermissionSnapshot 在tx中执行快照恢复
+func (r *permissionRepo) restorePermissionSnapshot(ctx context.Context, tx dbConn, snapshot *biz.PermissionSnapshot) error {
+	companyID := biz.CompanyFromContext(ctx)
+
+	roleIDs, err := queryIDSet(ctx, tx, "SELECT id FROM roles")
+	if err != nil {
+		r.log.Errorf("failed to list roles: %v", err)
+		return err
+	}
+	userIDs, err := queryIDSet(ctx, tx, "SELECT id FROM users")
+	if err != nil {
+		r.log.Errorf("failed to list users: %v", err)
+		return err
+	}
+
+	for _, table := range []string{"permission_rules", "user_permissions"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE company_id = $1", companyID); err != nil {
+			r.log.Errorf("failed to clear %s: %v", table, err)
+			return err
+		}
+	}
+
+	for _, rule := range snapshot.PermissionRules {
+		if !roleIDs[rule.RoleID] {
+			r.log.Warnf("skip permission rule %d: role %d no longer exists", rule.ID, rule.RoleID)
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO permission_rules (id, role_id, doc_type, permission_level, can_read, can_write, can_create,
+			                            can_delete, can_submit, can_cancel, can_amend, can_report, can_export,
+			                            can_import, can_share, can_print, can_email, only_if_creator, condition,
+			                            created_at, updated_at, created_by, updated_by, company_id, can_set_user_permissions)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, NULLIF($19, ''),
+			        $20, $21, $22, $23, $24, $25)`,
+			rule.ID, rule.RoleID, rule.DocType, rule.PermissionLevel, rule.CanRead, rule.CanWrite,
+			rule.CanCreate, rule.CanDelete, rule.CanSubmit, rule.CanCancel, rule.CanAmend,
+			rule.CanReport, rule.CanExport, rule.CanImport, rule.CanShare, rule.CanPrint,
+			rule.CanEmail, rule.OnlyIfCreator, rule.Condition, rule.CreatedAt, rule.UpdatedAt,
+			existingUserID(userIDs, rule.CreatedBy), existingUserID(userIDs, rule.UpdatedBy), companyID,
+			rule.CanSetUserPermissions,
+		)
+		if err != nil {
+			r.log.Errorf("failed to restore permission rule: %v", err)
+			return err
+		}
+	}
+
+	for _, permission := range snapshot.UserPermissions {
+		if !userIDs[permission.UserID] {
+			r.log.Warnf("skip user permission %d: user %d no longer exists", permission.ID, permission.UserID)
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO user_permissions (id, user_id, doc_type, doc_name, value, applicable_for, hide_descendants,
+			                            is_default, created_at, updated_at, created_by, updated_by, company_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			permission.ID, permission.UserID, permission.DocType, permission.DocName, permission.Value,
+			permission.ApplicableFor, permission.HideDescendants, permission.IsDefault,
+			permission.CreatedAt, permission.UpdatedAt,
+			existingUserID(userIDs, permission.CreatedBy), existingUserID(userIDs, permission.UpdatedBy), companyID,
+		)
+		if err != nil {
+			r.log.Errorf("failed to restore user permission: %v", err)
+			return err
+		}
+	}
+
+	// 恢复原始ID后同步序列，避免后续新增时主键冲突
+	for _, table := range []string{"permission_rules", "user_permissions"} {
+		query := "SELECT setval(pg_get_serial_sequence('" + table + "', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM " + table
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			r.log.Errorf("failed to reset %s sequence: %v", table, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryIDSet 查询ID集合
+func queryIDSet(ctx context.Context, tx dbConn, query string) (map[int64]bool, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// existingUserID 审计字段引用的用户已删除时返回nil
+func existingUserID(userIDs map[int64]bool, id *int64) *int64 {
+	if id == nil || !userIDs[*id] {
+		return nil
+	}
+	return id
+}
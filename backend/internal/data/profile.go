@@ -0,0 +1,264 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// profileColumn 个人资料字段对应的赋值表达式和当前值表达式，当前值统一转换为与biz层一致的字符串
+type profileColumn struct {
+	set     string
+	current string
+}
+
+// profileColumns 支持自助修改的用户列，空字符串写入为NULL（姓名和邮箱不允许为空，由业务层校验）
+var profileColumns = map[string]profileColumn{
+	biz.ProfileFieldEmail:     {set: "email = $%d", current: "email"},
+	biz.ProfileFieldFirstName: {set: "first_name = $%d", current: "first_name"},
+	biz.ProfileFieldLastName:  {set: "last_name = $%d", current: "last_name"},
+	biz.ProfileFieldPhone:     {set: "phone = NULLIF($%d, '')", current: "COALESCE(phone, '')"},
+	biz.ProfileFieldGender:    {set: "gender = NULLIF($%d, '')::smallint", current: "COALESCE(gender::text, '')"},
+	biz.ProfileFieldBirthDate: {set: "birth_date = NULLIF($%d, '')::date", current: "COALESCE(to_char(birth_date, 'YYYY-MM-DD'), '')"},
+	biz.ProfileFieldAvatarURL: {set: "avatar_url = NULLIF($%d, '')", current: "COALESCE(avatar_url, '')"},
+}
+
+// profileChangeRequestColumns 变更申请查询列，与scanProfileChangeRequest的扫描顺序一致
+const profileChangeRequestColumns = `p.id, p.user_id, COALESCE(u.username, ''), p.changes, p.status, COALESCE(p.comment, ''),
+		       p.reviewed_by, COALESCE(ru.username, ''), p.reviewed_at, p.created_at`
+
+// profileChangeRequestFrom 变更申请及申请人、审批人
+const profileChangeRequestFrom = `
+		FROM profile_change_requests p
+		LEFT JOIN users u ON u.id = p.user_id
+		LEFT JOIN users ru ON ru.id = p.reviewed_by`
+
+// profileRepo 个人资料仓储实现
+type profileRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewProfileRepo 创建个人资料仓储
+func NewProfileRepo(data *Data, logger log.Logger) biz.ProfileRepo {
+	return &profileRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// ApplyProfileChanges 修改用户字段
+func (r *profileRepo) ApplyProfileChanges(ctx context.Context, userID int32, changes []*biz.ProfileFieldChange) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.applyProfileChanges(ctx, tx, userID, changes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applyProfileChanges 在事务中修改用户字段，仅当每个字段的当前值仍等于修改前的值时才修改，避免覆盖并发修改
+func (r *profileRepo) applyProfileChanges(ctx context.Context, tx *sql.Tx, userID int32, changes []*biz.ProfileFieldChange) error {
+	sets := make([]string, 0, len(changes)+1)
+	conditions := []string{"id = $1", "deleted_at IS NULL"}
+	args := []interface{}{userID}
+	for _, change := range changes {
+		column, ok := profileColumns[change.Field]
+		if !ok {
+			return fmt.Errorf("%w: %s", biz.ErrProfileFieldUnknown, change.Field)
+		}
+		args = append(args, change.New, change.Old)
+		sets = append(sets, fmt.Sprintf(column.set, len(args)-1))
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", column.current, len(args)))
+	}
+	sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+
+	query := "UPDATE users SET " + strings.Join(sets, ", ") + " WHERE " + strings.Join(conditions, " AND ")
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return biz.ErrProfileEmailExists
+		}
+		r.log.Errorf("failed to apply profile changes: %v", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return biz.ErrUserNotFound
+	}
+	return biz.ErrProfileChangeStale
+}
+
+// CreateProfileChangeRequest 创建变更申请，依赖待审批申请的部分唯一索引防止并发重复提交
+func (r *profileRepo) CreateProfileChangeRequest(ctx context.Context, req *biz.ProfileChangeRequest) (*biz.ProfileChangeRequest, error) {
+	changes, err := json.Marshal(req.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO profile_change_requests (user_id, changes, status)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err = r.data.db.QueryRowContext(ctx, query, req.UserID, string(changes), req.Status).Scan(&req.ID, &req.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return nil, biz.ErrProfileChangePending
+			case "23503":
+				return nil, biz.ErrUserNotFound
+			}
+		}
+		r.log.Errorf("failed to create profile change request: %v", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+// GetProfileChangeRequest 获取变更申请
+func (r *profileRepo) GetProfileChangeRequest(ctx context.Context, id int32) (*biz.ProfileChangeRequest, error) {
+	query := "SELECT " + profileChangeRequestColumns + profileChangeRequestFrom + " WHERE p.id = $1"
+
+	req, err := scanProfileChangeRequest(r.data.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrProfileChangeRequestNotFound
+		}
+		r.log.Errorf("failed to get profile change request: %v", err)
+		return nil, err
+	}
+	return req, nil
+}
+
+// ListProfileChangeRequests 分页获取变更申请，按提交时间倒序
+func (r *profileRepo) ListProfileChangeRequests(ctx context.Context, filter *biz.ProfileChangeRequestFilter) ([]*biz.ProfileChangeRequest, int32, error) {
+	where := " WHERE ($1 = 0 OR p.user_id = $1) AND ($2 = '' OR p.status = $2)"
+	args := []interface{}{filter.UserID, filter.Status}
+
+	var total int32
+	countQuery := "SELECT COUNT(*) FROM profile_change_requests p" + where
+	if err := r.data.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		r.log.Errorf("failed to count profile change requests: %v", err)
+		return nil, 0, err
+	}
+
+	query := "SELECT " + profileChangeRequestColumns + profileChangeRequestFrom + where +
+		" ORDER BY p.created_at DESC, p.id DESC LIMIT $3 OFFSET $4"
+	args = append(args, filter.Size, (filter.Page-1)*filter.Size)
+
+	rows, err := r.data.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Errorf("failed to list profile change requests: %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	requests := []*biz.ProfileChangeRequest{}
+	for rows.Next() {
+		req, err := scanProfileChangeRequest(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan profile change request: %v", err)
+			return nil, 0, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, total, rows.Err()
+}
+
+// ReviewProfileChangeRequest 锁定待审批的申请后结束申请，审批通过时在同一事务中修改用户字段
+func (r *profileRepo) ReviewProfileChangeRequest(ctx context.Context, id int32, status string, reviewerID int32, comment string, apply bool) (*biz.ProfileChangeRequest, error) {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int32
+	var currentStatus string
+	var changes []byte
+	err = tx.QueryRowContext(ctx,
+		"SELECT user_id, status, changes FROM profile_change_requests WHERE id = $1 FOR UPDATE", id,
+	).Scan(&userID, &currentStatus, &changes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrProfileChangeRequestNotFound
+		}
+		r.log.Errorf("failed to lock profile change request: %v", err)
+		return nil, err
+	}
+	if currentStatus != biz.ProfileChangePending {
+		return nil, biz.ErrProfileChangeRequestNotPending
+	}
+
+	if apply {
+		var fieldChanges []*biz.ProfileFieldChange
+		if err := json.Unmarshal(changes, &fieldChanges); err != nil {
+			return nil, err
+		}
+		if err := r.applyProfileChanges(ctx, tx, userID, fieldChanges); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE profile_change_requests SET status = $1, reviewed_by = $2, reviewed_at = $3, comment = NULLIF($4, '') WHERE id = $5",
+		status, reviewerID, time.Now(), comment, id)
+	if err != nil {
+		r.log.Errorf("failed to review profile change request: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetProfileChangeRequest(ctx, id)
+}
+
+// scanProfileChangeRequest 扫描变更申请行
+func scanProfileChangeRequest(row rowScanner) (*biz.ProfileChangeRequest, error) {
+	var req biz.ProfileChangeRequest
+	var changes []byte
+	var reviewedBy sql.NullInt32
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&req.ID, &req.UserID, &req.Username, &changes, &req.Status, &req.Comment,
+		&reviewedBy, &req.ReviewerName, &reviewedAt, &req.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &req.Changes); err != nil {
+		return nil, err
+	}
+	if reviewedBy.Valid {
+		req.ReviewedBy = &reviewedBy.Int32
+	}
+	if reviewedAt.Valid {
+		req.ReviewedAt = &reviewedAt.Time
+	}
+	return &req, nil
+}
@@ -0,0 +1,252 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// deletedItemsQuery 回收站条目，组织只包含$1公司的组织，$1为NULL时包含全部公司；清理顺序为组织、角色、用户，同类型按删除时间先后，
+// 使子组织和子角色先于上级、被组织和角色引用的用户最后被永久删除
+const deletedItemsQuery = `
+	SELECT 1 AS purge_order, 'organization' AS type, o.id, o.name, o.code, o.deleted_at, o.deleted_by
+	FROM organizations o WHERE o.deleted_at IS NOT NULL AND ($1::BIGINT IS NULL OR o.company_id = $1)
+	UNION ALL
+	SELECT 2, 'role', r.id, r.name, r.code, r.deleted_at, r.deleted_by
+	FROM roles r WHERE r.deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 3, 'user', u.id, u.username, u.email, u.deleted_at, u.deleted_by
+	FROM users u WHERE u.deleted_at IS NOT NULL`
+
+// recycleBinTables 回收站条目类型对应的表
+var recycleBinTables = map[string]string{
+	biz.RecycleBinUser:         "users",
+	biz.RecycleBinRole:         "roles",
+	biz.RecycleBinOrganization: "organizations",
+}
+
+// recycleBinRepo 回收站仓储实现
+type recycleBinRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewRecycleBinRepo 创建回收站仓储
+func NewRecycleBinRepo(data *Data, logger log.Logger) biz.RecycleBinRepo {
+	return &recycleBinRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// ListDeletedItems 分页获取回收站条目，按删除时间倒序
+func (r *recycleBinRepo) ListDeletedItems(ctx context.Context, filter *biz.RecycleBinFilter) ([]*biz.DeletedItem, int64, error) {
+	keyword := ""
+	if filter.Keyword != "" {
+		keyword = "%" + filter.Keyword + "%"
+	}
+	where := "WHERE ($2 = '' OR d.type = $2) AND ($3 = '' OR d.name ILIKE $3 OR d.code ILIKE $3)"
+	args := []interface{}{biz.CompanyFromContext(ctx), filter.Type, keyword}
+
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM (" + deletedItemsQuery + ") d " + where
+	if err := r.data.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		r.log.Errorf("failed to count deleted items: %v", err)
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT d.type, d.id, d.name, d.code, d.deleted_at, d.deleted_by, COALESCE(du.username, '')
+		FROM (` + deletedItemsQuery + `) d
+		LEFT JOIN users du ON du.id = d.deleted_by
+		` + where + `
+		ORDER BY d.deleted_at DESC, d.type, d.id DESC
+		LIMIT $4 OFFSET $5`
+	args = append(args, filter.Size, (filter.Page-1)*filter.Size)
+
+	items, err := r.queryDeletedItems(ctx, query, args...)
+	if err != nil {
+		r.log.Errorf("failed to list deleted items: %v", err)
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// ListExpiredItems 获取全部公司中删除时间早于before的条目，按永久删除顺序排列
+func (r *recycleBinRepo) ListExpiredItems(ctx context.Context, before time.Time) ([]*biz.DeletedItem, error) {
+	query := `
+		SELECT d.type, d.id, d.name, d.code, d.deleted_at, d.deleted_by, ''
+		FROM (` + deletedItemsQuery + `) d
+		WHERE d.deleted_at < $2
+		ORDER BY d.purge_order, d.deleted_at, d.id`
+
+	items, err := r.queryDeletedItems(ctx, query, nil, before)
+	if err != nil {
+		r.log.Errorf("failed to list expired deleted items: %v", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *recycleBinRepo) queryDeletedItems(ctx context.Context, query string, args ...interface{}) ([]*biz.DeletedItem, error) {
+	rows, err := r.data.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*biz.DeletedItem{}
+	for rows.Next() {
+		var item biz.DeletedItem
+		var deletedBy sql.NullInt32
+		if err := rows.Scan(&item.Type, &item.ID, &item.Name, &item.Code, &item.DeletedAt, &deletedBy, &item.DeletedByName); err != nil {
+			return nil, err
+		}
+		if deletedBy.Valid {
+			item.DeletedBy = &deletedBy.Int32
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// RestoreItem 恢复回收站条目，组织只能恢复当前公司的组织
+func (r *recycleBinRepo) RestoreItem(ctx context.Context, itemType string, id int32) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	switch itemType {
+	case biz.RecycleBinUser:
+		err = r.restoreUser(ctx, tx, id)
+	case biz.RecycleBinRole:
+		err = r.restoreRole(ctx, tx, id)
+	case biz.RecycleBinOrganization:
+		err = r.restoreOrganization(ctx, tx, id)
+	default:
+		return biz.ErrInvalidRecycleBinType
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// restoreUser 恢复用户，用户名和邮箱在永久删除前一直被占用，恢复时不会冲突
+func (r *recycleBinRepo) restoreUser(ctx context.Context, tx *sql.Tx, id int32) error {
+	result, err := tx.ExecContext(ctx,
+		"UPDATE users SET deleted_at = NULL, deleted_by = NULL, updated_at = $1 WHERE id = $2 AND deleted_at IS NOT NULL",
+		time.Now(), id)
+	if err != nil {
+		r.log.Errorf("failed to restore user: %v", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return biz.ErrRecycleBinItemNotFound
+	}
+	return nil
+}
+
+// restoreRole 恢复角色，上级角色仍在回收站中时不能恢复
+func (r *recycleBinRepo) restoreRole(ctx context.Context, tx *sql.Tx, id int32) error {
+	var parentDeleted bool
+	query := `
+		SELECT COALESCE(p.deleted_at IS NOT NULL, FALSE)
+		FROM roles r
+		LEFT JOIN roles p ON p.id = r.parent_role_id
+		WHERE r.id = $1 AND r.deleted_at IS NOT NULL
+		FOR UPDATE OF r`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&parentDeleted); err != nil {
+		if err == sql.ErrNoRows {
+			return biz.ErrRecycleBinItemNotFound
+		}
+		r.log.Errorf("failed to lock deleted role: %v", err)
+		return err
+	}
+	if parentDeleted {
+		return biz.ErrRecycleBinParentDeleted
+	}
+
+	_, err := tx.ExecContext(ctx,
+		"UPDATE roles SET deleted_at = NULL, deleted_by = NULL, updated_at = $1 WHERE id = $2",
+		time.Now(), id)
+	if err != nil {
+		r.log.Errorf("failed to restore role: %v", err)
+		return err
+	}
+	return nil
+}
+
+// restoreOrganization 恢复组织，上级组织仍在回收站中或编码已被其他组织使用时不能恢复
+func (r *recycleBinRepo) restoreOrganization(ctx context.Context, tx *sql.Tx, id int32) error {
+	companyID := biz.CompanyFromContext(ctx)
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", fmt.Sprintf("organization_tree:%d", companyID)); err != nil {
+		r.log.Errorf("failed to lock organization tree: %v", err)
+		return err
+	}
+
+	var parentDeleted bool
+	query := `
+		SELECT COALESCE(p.deleted_at IS NOT NULL, FALSE)
+		FROM organizations o
+		LEFT JOIN organizations p ON p.id = o.parent_id
+		WHERE o.id = $1 AND o.company_id = $2 AND o.deleted_at IS NOT NULL
+		FOR UPDATE OF o`
+	if err := tx.QueryRowContext(ctx, query, id, companyID).Scan(&parentDeleted); err != nil {
+		if err == sql.ErrNoRows {
+			return biz.ErrRecycleBinItemNotFound
+		}
+		r.log.Errorf("failed to lock deleted organization: %v", err)
+		return err
+	}
+	if parentDeleted {
+		return biz.ErrRecycleBinParentDeleted
+	}
+
+	_, err := tx.ExecContext(ctx,
+		"UPDATE organizations SET deleted_at = NULL, deleted_by = NULL, updated_at = $1 WHERE id = $2",
+		time.Now(), id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return biz.ErrOrganizationCodeExists
+		}
+		r.log.Errorf("failed to restore organization: %v", err)
+		return err
+	}
+	return nil
+}
+
+// PurgeItem 永久删除回收站条目，关联的成员关系、角色分配和权限按外键级联删除；
+// 仍被其他记录引用时返回ErrRecycleBinItemReferenced
+func (r *recycleBinRepo) PurgeItem(ctx context.Context, itemType string, id int32) error {
+	table, ok := recycleBinTables[itemType]
+	if !ok {
+		return biz.ErrInvalidRecycleBinType
+	}
+
+	result, err := r.data.db.ExecContext(ctx, "DELETE FROM "+table+" WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return biz.ErrRecycleBinItemReferenced
+		}
+		r.log.Errorf("failed to purge %s %d: %v", itemType, id, err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return biz.ErrRecycleBinItemNotFound
+	}
+	return nil
+}
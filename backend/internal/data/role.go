@@ -31,13 +31,13 @@ func NewRoleRepo(data *Data, logger log.Logger) biz.RoleRepo {
 func (r *roleRepo) CreateRole(ctx context.Context, role *biz.Role) (*biz.Role, error) {
 	var id int32
 	query := `
-		INSERT INTO roles (name, code, description, is_system_role, is_enabled, sort_order, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO roles (name, code, description, is_system_role, is_enabled, sort_order, parent_role_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id`
 
-	err := r.data.db.QueryRowContext(ctx, query,
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
 		role.Name, role.Code, role.Description, role.IsSystemRole,
-		role.IsEnabled, role.SortOrder, role.CreatedAt, role.UpdatedAt,
+		role.IsEnabled, role.SortOrder, role.ParentRoleID, role.CreatedAt, role.UpdatedAt,
 	).Scan(&id)
 
 	if err != nil {
@@ -61,17 +61,11 @@ func (r *roleRepo) CreateRole(ctx context.Context, role *biz.Role) (*biz.Role, e
 
 // GetRole 获取角色
 func (r *roleRepo) GetRole(ctx context.Context, id int32) (*biz.Role, error) {
-	var role biz.Role
 	query := `
-		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, created_at, updated_at
-		FROM roles WHERE id = $1`
-
-	err := r.data.db.QueryRowContext(ctx, query, id).Scan(
-		&role.ID, &role.Name, &role.Code, &role.Description,
-		&role.IsSystemRole, &role.IsEnabled, &role.SortOrder,
-		&role.CreatedAt, &role.UpdatedAt,
-	)
+		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, parent_role_id, created_at, updated_at
+		FROM roles WHERE id = $1 AND deleted_at IS NULL`
 
+	role, err := scanRole(r.data.conn(ctx).QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("role not found")
@@ -80,21 +74,25 @@ func (r *roleRepo) GetRole(ctx context.Context, id int32) (*biz.Role, error) {
 		return nil, err
 	}
 
-	return &role, nil
+	return role, nil
 }
 
 // UpdateRole 更新角色
 func (r *roleRepo) UpdateRole(ctx context.Context, role *biz.Role) (*biz.Role, error) {
 	query := `
 		UPDATE roles 
-		SET name = $2, description = $3, is_enabled = $4, sort_order = $5, updated_at = $6
-		WHERE id = $1`
+		SET name = $2, description = $3, is_enabled = $4, sort_order = $5, parent_role_id = $6, updated_at = $7
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	role.UpdatedAt = time.Now()
-	_, err := r.data.db.ExecContext(ctx, query,
-		role.ID, role.Name, role.Description, role.IsEnabled,
-		role.SortOrder, role.UpdatedAt,
-	)
+	// 禁用超级管理员角色会移走所有超级管理员，需在超级管理员锁下执行
+	err := withSuperAdminGuard(ctx, r.data, func(ctx context.Context, tx dbConn) error {
+		_, err := tx.ExecContext(ctx, query,
+			role.ID, role.Name, role.Description, role.IsEnabled,
+			role.SortOrder, role.ParentRoleID, role.UpdatedAt,
+		)
+		return err
+	})
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
@@ -111,8 +109,17 @@ func (r *roleRepo) UpdateRole(ctx context.Context, role *biz.Role) (*biz.Role, e
 	return role, nil
 }
 
-// DeleteRole 删除角色
-func (r *roleRepo) DeleteRole(ctx context.Context, id int32) error {
+// LockRoleHierarchy 通过事务级咨询锁串行化角色继承关系的变更，避免两个并发修改互相成为对方的下级而形成环
+func (r *roleRepo) LockRoleHierarchy(ctx context.Context) error {
+	if _, err := r.data.conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "role_hierarchy"); err != nil {
+		r.log.Errorf("failed to lock role hierarchy: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteRole 软删除角色，保留角色权限以便从回收站恢复
+func (r *roleRepo) DeleteRole(ctx context.Context, id int32, deletedBy int32) error {
 	// 检查是否为系统角色
 	role, err := r.GetRole(ctx, id)
 	if err != nil {
@@ -126,7 +133,7 @@ func (r *roleRepo) DeleteRole(ctx context.Context, id int32) error {
 	// 检查是否有用户使用此角色
 	var userCount int32
 	countQuery := "SELECT COUNT(*) FROM user_roles WHERE role_id = $1"
-	if err := r.data.db.QueryRowContext(ctx, countQuery, id).Scan(&userCount); err != nil {
+	if err := r.data.conn(ctx).QueryRowContext(ctx, countQuery, id).Scan(&userCount); err != nil {
 		r.log.Errorf("failed to check role usage: %v", err)
 		return err
 	}
@@ -135,28 +142,26 @@ func (r *roleRepo) DeleteRole(ctx context.Context, id int32) error {
 		return biz.ErrRoleInUse
 	}
 
-	// 开启事务
-	tx, err := r.data.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// 删除角色权限关联
-	_, err = tx.ExecContext(ctx, "DELETE FROM role_permissions WHERE role_id = $1", id)
-	if err != nil {
-		r.log.Errorf("failed to delete role permissions: %v", err)
+	// 检查是否有下级角色继承此角色
+	var childCount int32
+	childQuery := "SELECT COUNT(*) FROM roles WHERE parent_role_id = $1 AND deleted_at IS NULL"
+	if err := r.data.conn(ctx).QueryRowContext(ctx, childQuery, id).Scan(&childCount); err != nil {
+		r.log.Errorf("failed to check child roles: %v", err)
 		return err
 	}
 
-	// 删除角色
-	_, err = tx.ExecContext(ctx, "DELETE FROM roles WHERE id = $1", id)
-	if err != nil {
-		r.log.Errorf("failed to delete role: %v", err)
-		return err
+	if childCount > 0 {
+		return biz.ErrRoleHasChildren
 	}
 
-	return tx.Commit()
+	query := "UPDATE roles SET deleted_at = $1, deleted_by = NULLIF($2, 0) WHERE id = $3 AND deleted_at IS NULL"
+	return withSuperAdminGuard(ctx, r.data, func(ctx context.Context, tx dbConn) error {
+		if _, err := tx.ExecContext(ctx, query, time.Now(), deletedBy, id); err != nil {
+			r.log.Errorf("failed to delete role: %v", err)
+			return err
+		}
+		return nil
+	})
 }
 
 // ListRoles 角色列表
@@ -165,30 +170,26 @@ func (r *roleRepo) ListRoles(ctx context.Context, page, size int32, search strin
 	var roles []*biz.Role
 	var total int32
 
-	// 构建WHERE条件
-	whereClause := ""
+	// 构建WHERE条件，已删除的角色只在回收站中列出
+	whereClause := "WHERE deleted_at IS NULL"
 	var whereArgs []interface{}
 	argIndex := 0
 
 	if search != "" {
-		whereClause = "WHERE name ILIKE $" + fmt.Sprintf("%d", argIndex+1) + " OR code ILIKE $" + fmt.Sprintf("%d", argIndex+2)
+		whereClause += " AND (name ILIKE $" + fmt.Sprintf("%d", argIndex+1) + " OR code ILIKE $" + fmt.Sprintf("%d", argIndex+2) + ")"
 		whereArgs = append(whereArgs, "%"+search+"%", "%"+search+"%")
 		argIndex += 2
 	}
 
 	if isEnabled != nil {
-		if whereClause == "" {
-			whereClause = "WHERE is_enabled = $" + fmt.Sprintf("%d", argIndex+1)
-		} else {
-			whereClause += " AND is_enabled = $" + fmt.Sprintf("%d", argIndex+1)
-		}
+		whereClause += " AND is_enabled = $" + fmt.Sprintf("%d", argIndex+1)
 		whereArgs = append(whereArgs, *isEnabled)
 		argIndex++
 	}
 
 	// 查询总数
 	countQuery := "SELECT COUNT(*) FROM roles " + whereClause
-	err := r.data.db.QueryRowContext(ctx, countQuery, whereArgs...).Scan(&total)
+	err := r.data.conn(ctx).QueryRowContext(ctx, countQuery, whereArgs...).Scan(&total)
 	if err != nil {
 		r.log.Errorf("failed to count roles: %v", err)
 		return nil, 0, err
@@ -228,7 +229,7 @@ func (r *roleRepo) ListRoles(ctx context.Context, page, size int32, search strin
 
 	// 查询数据
 	query := fmt.Sprintf(`
-		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, created_at, updated_at
+		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, parent_role_id, created_at, updated_at
 		FROM roles 
 		%s
 		%s 
@@ -237,7 +238,7 @@ func (r *roleRepo) ListRoles(ctx context.Context, page, size int32, search strin
 	// 添加分页参数
 	whereArgs = append(whereArgs, size, offset)
 
-	rows, err := r.data.db.QueryContext(ctx, query, whereArgs...)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, whereArgs...)
 	if err != nil {
 		r.log.Errorf("failed to list roles: %v", err)
 		return nil, 0, err
@@ -245,18 +246,13 @@ func (r *roleRepo) ListRoles(ctx context.Context, page, size int32, search strin
 	defer rows.Close()
 
 	for rows.Next() {
-		var role biz.Role
-		err := rows.Scan(
-			&role.ID, &role.Name, &role.Code, &role.Description,
-			&role.IsSystemRole, &role.IsEnabled, &role.SortOrder,
-			&role.CreatedAt, &role.UpdatedAt,
-		)
+		role, err := scanRole(rows)
 		if err != nil {
 			r.log.Errorf("failed to scan role: %v", err)
 			return nil, 0, err
 		}
 
-		roles = append(roles, &role)
+		roles = append(roles, role)
 	}
 
 	return roles, total, nil
@@ -302,17 +298,11 @@ func (r *roleRepo) AssignPermissions(ctx context.Context, roleID int32, permissi
 
 // GetRoleByCode 根据编码获取角色
 func (r *roleRepo) GetRoleByCode(ctx context.Context, code string) (*biz.Role, error) {
-	var role biz.Role
 	query := `
-		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, created_at, updated_at
-		FROM roles WHERE code = $1`
-
-	err := r.data.db.QueryRowContext(ctx, query, code).Scan(
-		&role.ID, &role.Name, &role.Code, &role.Description,
-		&role.IsSystemRole, &role.IsEnabled, &role.SortOrder,
-		&role.CreatedAt, &role.UpdatedAt,
-	)
+		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, parent_role_id, created_at, updated_at
+		FROM roles WHERE code = $1 AND deleted_at IS NULL`
 
+	role, err := scanRole(r.data.conn(ctx).QueryRowContext(ctx, query, code))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("role not found")
@@ -321,18 +311,18 @@ func (r *roleRepo) GetRoleByCode(ctx context.Context, code string) (*biz.Role, e
 		return nil, err
 	}
 
-	return &role, nil
+	return role, nil
 }
 
 // GetEnabledRoles 获取启用的角色
 func (r *roleRepo) GetEnabledRoles(ctx context.Context) ([]*biz.Role, error) {
 	query := `
-		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, created_at, updated_at
+		SELECT id, name, code, description, is_system_role, is_enabled, sort_order, parent_role_id, created_at, updated_at
 		FROM roles 
-		WHERE is_enabled = true
+		WHERE is_enabled = true AND deleted_at IS NULL
 		ORDER BY sort_order, created_at`
 
-	rows, err := r.data.db.QueryContext(ctx, query)
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query)
 	if err != nil {
 		r.log.Errorf("failed to get enabled roles: %v", err)
 		return nil, err
@@ -341,19 +331,36 @@ func (r *roleRepo) GetEnabledRoles(ctx context.Context) ([]*biz.Role, error) {
 
 	var roles []*biz.Role
 	for rows.Next() {
-		var role biz.Role
-		err := rows.Scan(
-			&role.ID, &role.Name, &role.Code, &role.Description,
-			&role.IsSystemRole, &role.IsEnabled, &role.SortOrder,
-			&role.CreatedAt, &role.UpdatedAt,
-		)
+		role, err := scanRole(rows)
 		if err != nil {
 			r.log.Errorf("failed to scan role: %v", err)
 			return nil, err
 		}
 
-		roles = append(roles, &role)
+		roles = append(roles, role)
 	}
 
 	return roles, nil
 }
+
+// scanRole 扫描角色行，列顺序与查询中的SELECT保持一致，extra为角色列之后的附加列
+func scanRole(row rowScanner, extra ...interface{}) (*biz.Role, error) {
+	var role biz.Role
+	var parentRoleID sql.NullInt32
+
+	dest := []interface{}{
+		&role.ID, &role.Name, &role.Code, &role.Description,
+		&role.IsSystemRole, &role.IsEnabled, &role.SortOrder,
+		&parentRoleID, &role.CreatedAt, &role.UpdatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentRoleID.Valid {
+		role.ParentRoleID = &parentRoleID.Int32
+	}
+
+	return &role, nil
+}
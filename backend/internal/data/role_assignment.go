@@ -0,0 +1,250 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// effectiveUserRoleCondition 用户角色分配当前处于有效期内且角色未被删除的条件，user_roles别名须为ur
+const effectiveUserRoleCondition = `ur.is_active = TRUE
+			AND (ur.valid_from IS NULL OR ur.valid_from <= CURRENT_TIMESTAMP)
+			AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)
+			AND NOT EXISTS (SELECT 1 FROM roles dr WHERE dr.id = ur.role_id AND dr.deleted_at IS NOT NULL)`
+
+// roleAssignmentColumns 角色分配查询列，与scanRoleAssignment的扫描顺序一致
+const roleAssignmentColumns = `ur.id, ur.user_id, ur.role_id, r.code, r.name, ur.valid_from, ur.valid_until,
+		       ur.assigned_by, ur.delegated_by, COALESCE(ur.reason, ''), COALESCE(ur.is_active, TRUE), COALESCE(ur.assigned_at, ur.created_at)`
+
+// roleAssignmentRepo 角色分配仓储实现
+type roleAssignmentRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewRoleAssignmentRepo 创建角色分配仓储
+func NewRoleAssignmentRepo(data *Data, logger log.Logger) biz.RoleAssignmentRepo {
+	return &roleAssignmentRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// CreateRoleAssignment 创建角色分配
+func (r *roleAssignmentRepo) CreateRoleAssignment(ctx context.Context, assignment *biz.RoleAssignment) (*biz.RoleAssignment, error) {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, valid_from, valid_until, assigned_by, delegated_by, reason, is_active, assigned_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	assignment.AssignedAt = time.Now()
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
+		assignment.UserID, assignment.RoleID, assignment.ValidFrom, assignment.ValidUntil,
+		assignment.AssignedBy, assignment.DelegatedBy, assignment.Reason, assignment.IsActive, assignment.AssignedAt,
+	).Scan(&assignment.ID)
+	if err != nil {
+		r.log.Errorf("failed to create role assignment: %v", err)
+		return nil, err
+	}
+
+	return r.GetRoleAssignment(ctx, assignment.ID)
+}
+
+// GetRoleAssignment 获取角色分配
+func (r *roleAssignmentRepo) GetRoleAssignment(ctx context.Context, id int64) (*biz.RoleAssignment, error) {
+	query := `
+		SELECT ` + roleAssignmentColumns + `
+		FROM user_roles ur
+		INNER JOIN roles r ON r.id = ur.role_id
+		WHERE ur.id = $1`
+
+	assignment, err := scanRoleAssignment(r.data.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrRoleAssignmentNotFound
+		}
+		r.log.Errorf("failed to get role assignment: %v", err)
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// ListUserRoleAssignments 获取用户的角色分配
+func (r *roleAssignmentRepo) ListUserRoleAssignments(ctx context.Context, userID int32, includeExpired bool) ([]*biz.RoleAssignment, error) {
+	query := `
+		SELECT ` + roleAssignmentColumns + `
+		FROM user_roles ur
+		INNER JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1 AND ($2 OR ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)
+		ORDER BY r.sort_order, ur.valid_from NULLS FIRST, ur.id`
+
+	return r.listRoleAssignments(ctx, query, userID, includeExpired)
+}
+
+// ListDelegationsByUser 获取用户委托给他人的角色分配
+func (r *roleAssignmentRepo) ListDelegationsByUser(ctx context.Context, delegatorID int32) ([]*biz.RoleAssignment, error) {
+	query := `
+		SELECT ` + roleAssignmentColumns + `
+		FROM user_roles ur
+		INNER JOIN roles r ON r.id = ur.role_id
+		WHERE ur.delegated_by = $1
+		ORDER BY ur.valid_until, ur.id`
+
+	return r.listRoleAssignments(ctx, query, delegatorID)
+}
+
+// ListExpiredRoleAssignments 获取截至指定时间已过期的分配
+func (r *roleAssignmentRepo) ListExpiredRoleAssignments(ctx context.Context, now time.Time, limit int) ([]*biz.RoleAssignment, error) {
+	query := `
+		SELECT ` + roleAssignmentColumns + `
+		FROM user_roles ur
+		INNER JOIN roles r ON r.id = ur.role_id
+		WHERE ur.valid_until IS NOT NULL AND ur.valid_until <= $1
+		ORDER BY ur.valid_until, ur.id
+		LIMIT $2`
+
+	return r.listRoleAssignments(ctx, query, now, limit)
+}
+
+func (r *roleAssignmentRepo) listRoleAssignments(ctx context.Context, query string, args ...interface{}) ([]*biz.RoleAssignment, error) {
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Errorf("failed to list role assignments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []*biz.RoleAssignment
+	for rows.Next() {
+		assignment, err := scanRoleAssignment(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan role assignment: %v", err)
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate role assignments: %v", err)
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// DeleteRoleAssignment 删除角色分配，并撤销被分配人因此不再直接持有的角色上的委托
+func (r *roleAssignmentRepo) DeleteRoleAssignment(ctx context.Context, id int64) ([]int32, error) {
+	var affected []int32
+	err := withSuperAdminGuard(ctx, r.data, func(ctx context.Context, tx dbConn) error {
+		var userID int32
+		err := tx.QueryRowContext(ctx, "DELETE FROM user_roles WHERE id = $1 RETURNING user_id", id).Scan(&userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return biz.ErrRoleAssignmentNotFound
+			}
+			r.log.Errorf("failed to delete role assignment: %v", err)
+			return err
+		}
+
+		delegateIDs, err := deleteOrphanedDelegations(ctx, tx, userID)
+		if err != nil {
+			r.log.Errorf("failed to delete orphaned delegations: %v", err)
+			return err
+		}
+		affected = append([]int32{userID}, delegateIDs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return affected, nil
+}
+
+// deleteOrphanedDelegations 删除委托人已不再直接持有对应角色的委托，返回被撤销委托的被委托人
+func deleteOrphanedDelegations(ctx context.Context, tx dbConn, delegatorID int32) ([]int32, error) {
+	query := `
+		DELETE FROM user_roles d
+		WHERE d.delegated_by = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM user_roles ur
+				WHERE ur.user_id = $1 AND ur.role_id = d.role_id AND ur.delegated_by IS NULL
+					AND ur.is_active = TRUE
+					AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)
+			)
+		RETURNING d.user_id`
+
+	rows, err := tx.QueryContext(ctx, query, delegatorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegateIDs []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		delegateIDs = append(delegateIDs, id)
+	}
+	return delegateIDs, rows.Err()
+}
+
+// GetNextRoleAssignmentChange 获取用户下一次角色分配生效或失效的时间
+func (r *permissionRepo) GetNextRoleAssignmentChange(ctx context.Context, userID int64, now time.Time) (*time.Time, error) {
+	query := `
+		SELECT MIN(t) FROM (
+			SELECT valid_from AS t FROM user_roles
+			WHERE user_id = $1 AND is_active = TRUE AND valid_from > $2
+			UNION ALL
+			SELECT valid_until AS t FROM user_roles
+			WHERE user_id = $1 AND is_active = TRUE AND valid_until > $2
+		) boundaries`
+
+	var next sql.NullTime
+	if err := r.data.conn(ctx).QueryRowContext(ctx, query, userID, now).Scan(&next); err != nil {
+		r.log.Errorf("failed to get next role assignment change: %v", err)
+		return nil, err
+	}
+
+	if !next.Valid {
+		return nil, nil
+	}
+	return &next.Time, nil
+}
+
+// scanRoleAssignment 扫描角色分配行，列顺序与roleAssignmentColumns保持一致
+func scanRoleAssignment(row rowScanner) (*biz.RoleAssignment, error) {
+	var assignment biz.RoleAssignment
+	var validFrom, validUntil sql.NullTime
+	var assignedBy, delegatedBy sql.NullInt64
+
+	err := row.Scan(
+		&assignment.ID, &assignment.UserID, &assignment.RoleID, &assignment.RoleCode, &assignment.RoleName,
+		&validFrom, &validUntil, &assignedBy, &delegatedBy,
+		&assignment.Reason, &assignment.IsActive, &assignment.AssignedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if validFrom.Valid {
+		assignment.ValidFrom = &validFrom.Time
+	}
+	if validUntil.Valid {
+		assignment.ValidUntil = &validUntil.Time
+	}
+	if assignedBy.Valid {
+		assignment.AssignedBy = &assignedBy.Int64
+	}
+	if delegatedBy.Valid {
+		assignment.DelegatedBy = &delegatedBy.Int64
+	}
+
+	return &assignment, nil
+}
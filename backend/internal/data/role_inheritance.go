@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"fmt"
+
+	"erp-system/internal/biz"
+
+	"github.com/lib/pq"
+)
+
+// userEffectiveRolesCTE 用户的生效角色：有效期内直接分配或委托的角色及沿parent_role_id向上继承的角色
+// 以$1为用户ID，查询中通过effective_roles(role_id, assigned_role_id, depth)引用
+var userEffectiveRolesCTE = fmt.Sprintf(`
+	WITH RECURSIVE effective_roles AS (
+		SELECT ur.role_id, ur.role_id AS assigned_role_id, 0 AS depth
+		FROM user_roles ur
+		WHERE ur.user_id = $1 AND %s
+		UNION ALL
+		SELECT r.parent_role_id, er.assigned_role_id, er.depth + 1
+		FROM effective_roles er
+		INNER JOIN roles r ON r.id = er.role_id
+		WHERE r.parent_role_id IS NOT NULL AND er.depth < %d
+	)`, effectiveUserRoleCondition, biz.MaxRoleInheritanceDepth)
+
+// ListEffectivePermissionRules 获取角色自身及沿继承链获得的权限规则，按层级由近及远排列
+func (r *permissionRepo) ListEffectivePermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.EffectivePermissionRule, error) {
+	query := fmt.Sprintf(`
+		WITH RECURSIVE role_chain AS (
+			SELECT id AS role_id, parent_role_id, 0 AS depth
+			FROM roles WHERE id = $1
+			UNION ALL
+			SELECT r.id, r.parent_role_id, rc.depth + 1
+			FROM role_chain rc
+			INNER JOIN roles r ON r.id = rc.parent_role_id
+			WHERE rc.depth < %d
+		)
+		SELECT pr.id, pr.role_id, pr.doc_type, pr.permission_level, pr.can_read, pr.can_write, pr.can_create,
+		       pr.can_delete, pr.can_submit, pr.can_cancel, pr.can_amend, pr.can_report, pr.can_export, pr.can_import,
+		       pr.can_share, pr.can_print, pr.can_email, pr.can_set_user_permissions, pr.only_if_creator, COALESCE(pr.condition, ''),
+		       pr.created_at, pr.updated_at, ro.code, ro.name, rc.depth
+		FROM role_chain rc
+		INNER JOIN permission_rules pr ON pr.role_id = rc.role_id
+		INNER JOIN roles ro ON ro.id = rc.role_id
+		WHERE ($2 = '' OR pr.doc_type = $2) AND pr.company_id = $3
+		ORDER BY pr.doc_type, pr.permission_level, rc.depth`, biz.MaxRoleInheritanceDepth)
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, roleID, docType, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to list effective permission rules: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*biz.EffectivePermissionRule
+	for rows.Next() {
+		var rule biz.PermissionRule
+		effective := &biz.EffectivePermissionRule{PermissionRule: &rule}
+		err := rows.Scan(
+			&rule.ID, &rule.RoleID, &rule.DocType, &rule.PermissionLevel,
+			&rule.CanRead, &rule.CanWrite, &rule.CanCreate, &rule.CanDelete,
+			&rule.CanSubmit, &rule.CanCancel, &rule.CanAmend, &rule.CanReport,
+			&rule.CanExport, &rule.CanImport, &rule.CanShare, &rule.CanPrint,
+			&rule.CanEmail, &rule.CanSetUserPermissions, &rule.OnlyIfCreator, &rule.Condition, &rule.CreatedAt, &rule.UpdatedAt,
+			&effective.SourceRoleCode, &effective.SourceRoleName, &effective.Depth,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan effective permission rule: %v", err)
+			return nil, err
+		}
+
+		effective.SourceRoleID = rule.RoleID
+		effective.Inherited = effective.Depth > 0
+		rules = append(rules, effective)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate effective permission rules: %v", err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// GetDescendantRoleIDs 获取直接或间接继承该角色的全部下级角色ID
+func (r *permissionRepo) GetDescendantRoleIDs(ctx context.Context, roleID int64) ([]int64, error) {
+	query := fmt.Sprintf(`
+		WITH RECURSIVE descendants AS (
+			SELECT id, 1 AS depth
+			FROM roles WHERE parent_role_id = $1
+			UNION ALL
+			SELECT r.id, d.depth + 1
+			FROM descendants d
+			INNER JOIN roles r ON r.parent_role_id = d.id
+			WHERE d.depth < %d
+		)
+		SELECT DISTINCT id FROM descendants ORDER BY id`, biz.MaxRoleInheritanceDepth)
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, roleID)
+	if err != nil {
+		r.log.Errorf("failed to get descendant roles: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roleIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			r.log.Errorf("failed to scan descendant role: %v", err)
+			return nil, err
+		}
+		roleIDs = append(roleIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate descendant roles: %v", err)
+		return nil, err
+	}
+
+	return roleIDs, nil
+}
+
+// ListRoleHolderIDs 获取直接分配或受委托持有任一角色的用户
+func (r *permissionRepo) ListRoleHolderIDs(ctx context.Context, roleIDs []int64) ([]int64, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx,
+		"SELECT DISTINCT user_id FROM user_roles WHERE role_id = ANY($1) ORDER BY user_id", pq.Array(roleIDs))
+	if err != nil {
+		r.log.Errorf("failed to list role holders: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			r.log.Errorf("failed to scan role holder: %v", err)
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate role holders: %v", err)
+		return nil, err
+	}
+
+	return userIDs, nil
+}
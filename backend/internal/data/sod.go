@@ -0,0 +1,355 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// sodPolicyColumns 职责分离策略查询列，与scanSoDPolicy的扫描顺序一致
+const sodPolicyColumns = `id, name, COALESCE(description, ''), policy_type, role_a_id, role_b_id,
+		       COALESCE(doc_type, ''), COALESCE(action_a, ''), COALESCE(action_b, ''), enforcement, is_enabled,
+		       created_at, updated_at, created_by, updated_by`
+
+// sodUserRoleCondition 职责分离检查计入的用户角色：除生效中的分配外，已排期尚未生效的分配也计入，避免分配生效时才出现冲突
+const sodUserRoleCondition = `ur.is_active = TRUE
+			AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)
+			AND NOT EXISTS (SELECT 1 FROM roles dr WHERE dr.id = ur.role_id AND dr.deleted_at IS NOT NULL)`
+
+// 复核职责分离策略的变更按角色和用户加的事务锁
+const (
+	sodRoleLockKey = "sod_role:%d"
+	sodUserLockKey = "sod_user:%d"
+)
+
+// sodRepo 职责分离仓储实现
+type sodRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewSoDRepo 创建职责分离仓储
+func NewSoDRepo(data *Data, logger log.Logger) biz.SoDRepo {
+	return &sodRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// CreatePolicy 创建职责分离策略
+func (r *sodRepo) CreatePolicy(ctx context.Context, policy *biz.SoDPolicy) (*biz.SoDPolicy, error) {
+	query := `
+		INSERT INTO sod_policies (name, description, policy_type, role_a_id, role_b_id, doc_type, action_a, action_b,
+		                          enforcement, is_enabled, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $11)
+		RETURNING id`
+
+	err := r.data.conn(ctx).QueryRowContext(ctx, query,
+		policy.Name, policy.Description, policy.PolicyType, policy.RoleAID, policy.RoleBID,
+		policy.DocType, policy.ActionA, policy.ActionB, policy.Enforcement, policy.IsEnabled, policy.CreatedBy,
+	).Scan(&policy.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, biz.ErrSoDPolicyNameExists
+		}
+		r.log.Errorf("failed to create sod policy: %v", err)
+		return nil, err
+	}
+
+	return r.GetPolicy(ctx, policy.ID)
+}
+
+// UpdatePolicy 更新职责分离策略
+func (r *sodRepo) UpdatePolicy(ctx context.Context, policy *biz.SoDPolicy) (*biz.SoDPolicy, error) {
+	query := `
+		UPDATE sod_policies
+		SET name = $1, description = $2, policy_type = $3, role_a_id = $4, role_b_id = $5,
+		    doc_type = NULLIF($6, ''), action_a = NULLIF($7, ''), action_b = NULLIF($8, ''),
+		    enforcement = $9, is_enabled = $10, updated_by = $11
+		WHERE id = $12`
+
+	result, err := r.data.conn(ctx).ExecContext(ctx, query,
+		policy.Name, policy.Description, policy.PolicyType, policy.RoleAID, policy.RoleBID,
+		policy.DocType, policy.ActionA, policy.ActionB, policy.Enforcement, policy.IsEnabled,
+		policy.UpdatedBy, policy.ID,
+	)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, biz.ErrSoDPolicyNameExists
+		}
+		r.log.Errorf("failed to update sod policy: %v", err)
+		return nil, err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, biz.ErrSoDPolicyNotFound
+	}
+
+	return r.GetPolicy(ctx, policy.ID)
+}
+
+// GetPolicy 获取职责分离策略
+func (r *sodRepo) GetPolicy(ctx context.Context, id int64) (*biz.SoDPolicy, error) {
+	query := `SELECT ` + sodPolicyColumns + ` FROM sod_policies WHERE id = $1`
+
+	policy, err := scanSoDPolicy(r.data.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrSoDPolicyNotFound
+		}
+		r.log.Errorf("failed to get sod policy: %v", err)
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// ListPolicies 获取职责分离策略列表
+func (r *sodRepo) ListPolicies(ctx context.Context, enabledOnly bool) ([]*biz.SoDPolicy, error) {
+	query := `
+		SELECT ` + sodPolicyColumns + `
+		FROM sod_policies
+		WHERE (NOT $1 OR is_enabled = TRUE)
+		ORDER BY id`
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, enabledOnly)
+	if err != nil {
+		r.log.Errorf("failed to list sod policies: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []*biz.SoDPolicy
+	for rows.Next() {
+		policy, err := scanSoDPolicy(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan sod policy: %v", err)
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate sod policies: %v", err)
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// DeletePolicy 删除职责分离策略
+func (r *sodRepo) DeletePolicy(ctx context.Context, id int64) error {
+	result, err := r.data.conn(ctx).ExecContext(ctx, "DELETE FROM sod_policies WHERE id = $1", id)
+	if err != nil {
+		r.log.Errorf("failed to delete sod policy: %v", err)
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return biz.ErrSoDPolicyNotFound
+	}
+
+	return nil
+}
+
+// ListRoleParents 获取全部角色的上级角色映射
+func (r *sodRepo) ListRoleParents(ctx context.Context) (map[int64]int64, error) {
+	rows, err := r.data.conn(ctx).QueryContext(ctx, "SELECT id, parent_role_id FROM roles WHERE parent_role_id IS NOT NULL")
+	if err != nil {
+		r.log.Errorf("failed to list role parents: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := make(map[int64]int64)
+	for rows.Next() {
+		var id, parentID int64
+		if err := rows.Scan(&id, &parentID); err != nil {
+			r.log.Errorf("failed to scan role parent: %v", err)
+			return nil, err
+		}
+		parents[id] = parentID
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate role parents: %v", err)
+		return nil, err
+	}
+
+	return parents, nil
+}
+
+// ListRulesByDocTypes 获取当前公司在指定文档类型上的全部权限规则
+func (r *sodRepo) ListRulesByDocTypes(ctx context.Context, docTypes []string) ([]*biz.PermissionRule, error) {
+	query := `
+		SELECT id, role_id, doc_type, permission_level, can_read, can_write, can_create,
+		       can_delete, can_submit, can_cancel, can_amend, can_report, can_export, can_import,
+		       can_share, can_print, can_email, can_set_user_permissions, only_if_creator, created_at, updated_at
+		FROM permission_rules
+		WHERE doc_type = ANY($1) AND company_id = $2
+		ORDER BY role_id, doc_type, permission_level`
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, pq.Array(docTypes), biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to list permission rules by doc types: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*biz.PermissionRule
+	for rows.Next() {
+		var rule biz.PermissionRule
+		err := rows.Scan(
+			&rule.ID, &rule.RoleID, &rule.DocType, &rule.PermissionLevel,
+			&rule.CanRead, &rule.CanWrite, &rule.CanCreate, &rule.CanDelete,
+			&rule.CanSubmit, &rule.CanCancel, &rule.CanAmend, &rule.CanReport,
+			&rule.CanExport, &rule.CanImport, &rule.CanShare, &rule.CanPrint,
+			&rule.CanEmail, &rule.CanSetUserPermissions, &rule.OnlyIfCreator, &rule.CreatedAt, &rule.UpdatedAt,
+		)
+		if err != nil {
+			r.log.Errorf("failed to scan permission rule: %v", err)
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate permission rules: %v", err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// ListSubjects 获取持有角色的用户及其生效或已排期的角色；
+// roleIDs和userIDs均为空时返回全部用户，否则仅返回持有roleIDs中任一角色或在userIDs中的用户
+func (r *sodRepo) ListSubjects(ctx context.Context, roleIDs []int64, userIDs []int32) ([]*biz.SoDSubject, error) {
+	query := `
+		SELECT u.id, u.username, ARRAY_AGG(DISTINCT ur.role_id)
+		FROM user_roles ur
+		INNER JOIN users u ON u.id = ur.user_id AND u.deleted_at IS NULL
+		WHERE ` + sodUserRoleCondition + `
+			AND (CARDINALITY($1::BIGINT[]) = 0 AND CARDINALITY($2::INTEGER[]) = 0
+				OR ur.user_id = ANY($2)
+				OR ur.user_id IN (SELECT held.user_id FROM user_roles held WHERE held.role_id = ANY($1)))
+		GROUP BY u.id, u.username
+		HAVING CARDINALITY($1::BIGINT[]) = 0 OR BOOL_OR(ur.role_id = ANY($1)) OR u.id = ANY($2)
+		ORDER BY u.id`
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, pq.Array(roleIDs), pq.Array(userIDs))
+	if err != nil {
+		r.log.Errorf("failed to list sod subjects: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subjects []*biz.SoDSubject
+	for rows.Next() {
+		var subject biz.SoDSubject
+		if err := rows.Scan(&subject.UserID, &subject.Username, pq.Array(&subject.RoleIDs)); err != nil {
+			r.log.Errorf("failed to scan sod subject: %v", err)
+			return nil, err
+		}
+		subjects = append(subjects, &subject)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate sod subjects: %v", err)
+		return nil, err
+	}
+
+	return subjects, nil
+}
+
+// ListTemporaryUserRoleIDs 获取用户当前生效的限时或委托角色
+func (r *sodRepo) ListTemporaryUserRoleIDs(ctx context.Context, userID int32) ([]int64, error) {
+	query := `
+		SELECT DISTINCT ur.role_id
+		FROM user_roles ur
+		WHERE ur.user_id = $1 AND ` + effectiveUserRoleCondition + `
+			AND (ur.valid_until IS NOT NULL OR ur.delegated_by IS NOT NULL)`
+
+	rows, err := r.data.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		r.log.Errorf("failed to list temporary user roles: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roleIDs []int64
+	for rows.Next() {
+		var roleID int64
+		if err := rows.Scan(&roleID); err != nil {
+			r.log.Errorf("failed to scan temporary user role: %v", err)
+			return nil, err
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate temporary user roles: %v", err)
+		return nil, err
+	}
+
+	return roleIDs, nil
+}
+
+// LockSubjects 按角色和用户获取事务锁，需在事务中调用；按固定顺序加锁，避免并发变更相互等待
+func (r *sodRepo) LockSubjects(ctx context.Context, roleIDs []int64, userIDs []int32) error {
+	keys := make([]string, 0, len(roleIDs)+len(userIDs))
+	for _, roleID := range roleIDs {
+		keys = append(keys, fmt.Sprintf(sodRoleLockKey, roleID))
+	}
+	for _, userID := range userIDs {
+		keys = append(keys, fmt.Sprintf(sodUserLockKey, userID))
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, err := r.data.conn(ctx).ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", key); err != nil {
+			r.log.Errorf("failed to lock sod subjects: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// scanSoDPolicy 扫描职责分离策略行，列顺序与sodPolicyColumns保持一致
+func scanSoDPolicy(row rowScanner) (*biz.SoDPolicy, error) {
+	var policy biz.SoDPolicy
+	var roleAID, roleBID, createdBy, updatedBy sql.NullInt64
+	var createdAt, updatedAt time.Time
+
+	err := row.Scan(
+		&policy.ID, &policy.Name, &policy.Description, &policy.PolicyType, &roleAID, &roleBID,
+		&policy.DocType, &policy.ActionA, &policy.ActionB, &policy.Enforcement, &policy.IsEnabled,
+		&createdAt, &updatedAt, &createdBy, &updatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	policy.CreatedAt = createdAt
+	policy.UpdatedAt = updatedAt
+	if roleAID.Valid {
+		policy.RoleAID = &roleAID.Int64
+	}
+	if roleBID.Valid {
+		policy.RoleBID = &roleBID.Int64
+	}
+	if createdBy.Valid {
+		policy.CreatedBy = &createdBy.Int64
+	}
+	if updatedBy.Valid {
+		policy.UpdatedBy = &updatedBy.Int64
+	}
+
+	return &policy, nil
+}
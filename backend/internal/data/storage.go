@@ -0,0 +1,113 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// NewFileStorage 按上传配置创建文件存储，storage为s3时使用S3兼容对象存储，否则使用本地文件系统
+func NewFileStorage(c *conf.Upload, logger log.Logger) (biz.FileStorage, error) {
+	if c != nil && strings.EqualFold(c.Storage, "s3") {
+		if c.S3 == nil || c.S3.Endpoint == "" || c.S3.Bucket == "" {
+			return nil, fmt.Errorf("s3 storage requires endpoint and bucket")
+		}
+		return NewS3Storage(c.S3, logger)
+	}
+
+	root := "./uploads"
+	if c != nil && c.Path != "" {
+		root = c.Path
+	}
+	return NewLocalStorage(root, logger), nil
+}
+
+// localStorage 本地文件系统存储
+type localStorage struct {
+	root string
+	log  *log.Helper
+}
+
+// NewLocalStorage 创建以root为根目录的本地文件存储
+func NewLocalStorage(root string, logger log.Logger) biz.FileStorage {
+	return &localStorage{
+		root: root,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// Put 写入文件，先写入临时文件再重命名，避免读取到写了一半的文件
+func (s *localStorage) Put(ctx context.Context, key, contentType string, data []byte) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		s.log.Errorf("failed to create storage directory: %v", err)
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		s.log.Errorf("failed to create temp file: %v", err)
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+		tmp.Close()
+		s.log.Errorf("failed to write file %s: %v", key, err)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// Open 打开文件
+func (s *localStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, biz.ErrStorageObjectNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Delete 删除文件，文件不存在时视为成功
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path 将存储键转换为根目录下的文件路径，拒绝跳出根目录的键
+func (s *localStorage) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || cleaned != "/"+key {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned[1:])), nil
+}
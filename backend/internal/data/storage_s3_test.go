@@ -0,0 +1,78 @@
+package data
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Storage(t *testing.T) {
+	ctx := context.Background()
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20240301/us-east-1/s3/aws4_request") ||
+			r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	storage, err := NewFileStorage(&conf.Upload{Storage: "s3", S3: &conf.S3{
+		Endpoint: server.URL, Bucket: "erp", AccessKey: "AKID", SecretKey: "secret",
+	}}, log.DefaultLogger)
+	assert.NoError(t, err)
+	storage.(*s3Storage).now = func() time.Time { return time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC) }
+
+	// 对象键按路径风格放在存储桶下，特殊字符经过编码
+	assert.NoError(t, storage.Put(ctx, "attachments/Sales Order/a.pdf", "application/pdf", []byte("%PDF-1.4")))
+	assert.Equal(t, []byte("%PDF-1.4"), objects["/erp/attachments/Sales Order/a.pdf"])
+
+	file, err := storage.Open(ctx, "attachments/Sales Order/a.pdf")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "%PDF-1.4", string(content))
+
+	assert.NoError(t, storage.Delete(ctx, "attachments/Sales Order/a.pdf"))
+	_, err = storage.Open(ctx, "attachments/Sales Order/a.pdf")
+	assert.ErrorIs(t, err, biz.ErrStorageObjectNotFound)
+
+	// 签名错误时返回错误
+	storage.(*s3Storage).accessKey = "OTHER"
+	assert.Error(t, storage.Put(ctx, "attachments/b.txt", "text/plain", []byte("x")))
+
+	// 缺少存储桶配置
+	_, err = NewFileStorage(&conf.Upload{Storage: "s3", S3: &conf.S3{Endpoint: server.URL}}, log.DefaultLogger)
+	assert.Error(t, err)
+
+	// AWS文档中的签名密钥派生示例
+	key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
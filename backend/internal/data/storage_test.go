@@ -0,0 +1,35 @@
+package data
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewLocalStorage(t.TempDir(), log.DefaultLogger)
+
+	assert.NoError(t, storage.Put(ctx, "attachments/Order/a.txt", "text/plain", []byte("hello")))
+	file, err := storage.Open(ctx, "attachments/Order/a.txt")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "hello", string(content))
+
+	// 删除后不存在，重复删除视为成功
+	assert.NoError(t, storage.Delete(ctx, "attachments/Order/a.txt"))
+	assert.NoError(t, storage.Delete(ctx, "attachments/Order/a.txt"))
+	_, err = storage.Open(ctx, "attachments/Order/a.txt")
+	assert.ErrorIs(t, err, biz.ErrStorageObjectNotFound)
+
+	// 拒绝跳出根目录的键
+	for _, key := range []string{"", "../escape.txt", "attachments/../../escape.txt", "/absolute.txt"} {
+		assert.Error(t, storage.Put(ctx, key, "text/plain", []byte("x")), key)
+	}
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// systemConfigColumns 系统配置查询列，与scanSystemConfig的扫描顺序一致
+const systemConfigColumns = `config_key, COALESCE(config_value, ''), COALESCE(config_type, 'string'), COALESCE(description, ''),
+		       COALESCE(is_public, FALSE), COALESCE(is_encrypted, FALSE), company_id, updated_at`
+
+// effectiveSystemConfigs 当前公司生效的配置：同一配置键公司配置排在全局默认值之前，$1为公司ID
+const effectiveSystemConfigs = `
+		SELECT DISTINCT ON (config_key) ` + systemConfigColumns + `
+		FROM system_configs
+		WHERE (company_id IS NULL OR company_id = $1)`
+
+// systemConfigRepo 系统配置仓储实现
+type systemConfigRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewSystemConfigRepo 创建系统配置仓储
+func NewSystemConfigRepo(data *Data, logger log.Logger) biz.SystemConfigRepo {
+	return &systemConfigRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// ListSystemConfigs 获取当前公司生效的全部配置，按配置键排列
+func (r *systemConfigRepo) ListSystemConfigs(ctx context.Context) ([]*biz.SystemConfig, error) {
+	rows, err := r.data.db.QueryContext(ctx, effectiveSystemConfigs+`
+		ORDER BY config_key, company_id NULLS LAST`, biz.CompanyFromContext(ctx))
+	if err != nil {
+		r.log.Errorf("failed to list system configs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := []*biz.SystemConfig{}
+	for rows.Next() {
+		config, err := scanSystemConfig(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan system config: %v", err)
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate system configs: %v", err)
+		return nil, err
+	}
+
+	return configs, nil
+}
+
+// GetSystemConfig 获取当前公司生效的配置
+func (r *systemConfigRepo) GetSystemConfig(ctx context.Context, key string) (*biz.SystemConfig, error) {
+	config, err := scanSystemConfig(r.data.db.QueryRowContext(ctx, effectiveSystemConfigs+` AND config_key = $2
+		ORDER BY config_key, company_id NULLS LAST`, biz.CompanyFromContext(ctx), key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrSystemConfigNotFound
+		}
+		r.log.Errorf("failed to get system config: %v", err)
+		return nil, err
+	}
+	return config, nil
+}
+
+// SetCompanyConfig 以全局默认值为模板写入或更新当前公司的配置
+func (r *systemConfigRepo) SetCompanyConfig(ctx context.Context, key, value string, userID int64) (*biz.SystemConfig, error) {
+	query := `
+		INSERT INTO system_configs (company_id, config_key, config_value, config_type, description, is_public, is_encrypted, updated_by)
+		SELECT $1, config_key, $3, config_type, description, is_public, is_encrypted, $4
+		FROM system_configs
+		WHERE company_id IS NULL AND config_key = $2
+		ON CONFLICT (company_id, config_key) WHERE company_id IS NOT NULL DO UPDATE
+		SET config_value = EXCLUDED.config_value, updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + systemConfigColumns
+
+	config, err := scanSystemConfig(r.data.db.QueryRowContext(ctx, query,
+		biz.CompanyFromContext(ctx), key, value, nullableUserID(userID)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrSystemConfigNotFound
+		}
+		r.log.Errorf("failed to set company config: %v", err)
+		return nil, err
+	}
+	return config, nil
+}
+
+// DeleteCompanyConfig 删除当前公司的配置
+func (r *systemConfigRepo) DeleteCompanyConfig(ctx context.Context, key string) error {
+	result, err := r.data.db.ExecContext(ctx,
+		"DELETE FROM system_configs WHERE company_id = $1 AND config_key = $2", biz.CompanyFromContext(ctx), key)
+	if err != nil {
+		r.log.Errorf("failed to delete company config: %v", err)
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return biz.ErrSystemConfigNotFound
+	}
+	return nil
+}
+
+// scanSystemConfig 扫描一行系统配置
+func scanSystemConfig(row rowScanner) (*biz.SystemConfig, error) {
+	var config biz.SystemConfig
+	var companyID sql.NullInt32
+	var updatedAt sql.NullTime
+
+	err := row.Scan(&config.Key, &config.Value, &config.Type, &config.Description,
+		&config.IsPublic, &config.IsEncrypted, &companyID, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if companyID.Valid {
+		config.CompanyID = &companyID.Int32
+	}
+	config.UpdatedAt = updatedAt.Time
+	return &config, nil
+}
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"erp-system/internal/biz"
+)
+
+// dbConn 连接池或事务，仓储通过它执行语句以便加入调用方的事务
+type dbConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txContextKey struct{}
+
+// txScope 上下文中的事务及其提交后回调
+type txScope struct {
+	tx          *sql.Tx
+	afterCommit []func()
+}
+
+// NewTransaction 创建事务管理
+func NewTransaction(data *Data) biz.Transaction {
+	return data
+}
+
+// InTx 在事务中执行fn，ctx已处于事务中时加入外层事务
+func (d *Data) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*txScope); ok {
+		return fn(ctx)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scope := &txScope{tx: tx}
+	if err := fn(context.WithValue(ctx, txContextKey{}, scope)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, callback := range scope.afterCommit {
+		callback()
+	}
+	return nil
+}
+
+// conn 返回ctx所在的事务，不在事务中时返回连接池
+func (d *Data) conn(ctx context.Context) dbConn {
+	if scope, ok := ctx.Value(txContextKey{}).(*txScope); ok {
+		return scope.tx
+	}
+	return d.db
+}
+
+// afterCommit 注册事务提交后执行的回调，ctx不在事务中时立即执行；事务回滚时回调不执行
+func afterCommit(ctx context.Context, fn func()) {
+	if scope, ok := ctx.Value(txContextKey{}).(*txScope); ok {
+		scope.afterCommit = append(scope.afterCommit, fn)
+		return
+	}
+	fn()
+}
@@ -0,0 +1,206 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/cache"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// txAwareCache 事务感知的权限缓存：事务中的读取绕过缓存以看到本事务的变更，读到的数据可能回滚，不写入缓存；
+// 事务中的失效操作推迟到提交之后执行，避免提交前被并发读取重新缓存旧数据
+type txAwareCache struct {
+	cache.PermissionCache
+	log *log.Helper
+}
+
+// newTxAwareCache 包装权限缓存
+func newTxAwareCache(permissionCache cache.PermissionCache, logger log.Logger) cache.PermissionCache {
+	return &txAwareCache{PermissionCache: permissionCache, log: log.NewHelper(logger)}
+}
+
+// inTx ctx是否处于事务中
+func inTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txContextKey{}).(*txScope)
+	return ok
+}
+
+// invalidate 提交后执行失效操作，ctx不在事务中时立即执行
+func (c *txAwareCache) invalidate(ctx context.Context, name string, fn func() error) error {
+	if !inTx(ctx) {
+		return fn()
+	}
+	afterCommit(ctx, func() {
+		if err := fn(); err != nil {
+			c.log.Warnf("Failed to %s after commit: %v", name, err)
+		}
+	})
+	return nil
+}
+
+func (c *txAwareCache) GetUserPermissions(ctx context.Context, userID int64) ([]string, error) {
+	if inTx(ctx) {
+		return nil, nil
+	}
+	return c.PermissionCache.GetUserPermissions(ctx, userID)
+}
+
+func (c *txAwareCache) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	if inTx(ctx) {
+		return nil, nil
+	}
+	return c.PermissionCache.GetUserRoles(ctx, userID)
+}
+
+func (c *txAwareCache) GetPermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.PermissionRule, error) {
+	if inTx(ctx) {
+		return nil, nil
+	}
+	return c.PermissionCache.GetPermissionRules(ctx, roleID, docType)
+}
+
+func (c *txAwareCache) GetUserPermissionLevel(ctx context.Context, userID int64, docType string) (int, error) {
+	if inTx(ctx) {
+		return -1, nil
+	}
+	return c.PermissionCache.GetUserPermissionLevel(ctx, userID, docType)
+}
+
+func (c *txAwareCache) GetUserLevelPermissions(ctx context.Context, userID int64, docType string) (*biz.LevelPermissions, error) {
+	if inTx(ctx) {
+		return nil, nil
+	}
+	return c.PermissionCache.GetUserLevelPermissions(ctx, userID, docType)
+}
+
+func (c *txAwareCache) GetFieldPermissionLevels(ctx context.Context, docType string) (map[string]int, error) {
+	if inTx(ctx) {
+		return nil, nil
+	}
+	return c.PermissionCache.GetFieldPermissionLevels(ctx, docType)
+}
+
+func (c *txAwareCache) GetDocType(ctx context.Context, name string) (*biz.DocType, error) {
+	if inTx(ctx) {
+		return nil, nil
+	}
+	return c.PermissionCache.GetDocType(ctx, name)
+}
+
+func (c *txAwareCache) SetUserPermissions(ctx context.Context, userID int64, permissions []string, ttl time.Duration) error {
+	if inTx(ctx) {
+		return nil
+	}
+	return c.PermissionCache.SetUserPermissions(ctx, userID, permissions, ttl)
+}
+
+func (c *txAwareCache) SetUserRoles(ctx context.Context, userID int64, roles []string, ttl time.Duration) error {
+	if inTx(ctx) {
+		return nil
+	}
+	return c.PermissionCache.SetUserRoles(ctx, userID, roles, ttl)
+}
+
+func (c *txAwareCache) SetPermissionRules(ctx context.Context, roleID int64, docType string, rules []*biz.PermissionRule, ttl time.Duration) error {
+	if inTx(ctx) {
+		return nil
+	}
+	return c.PermissionCache.SetPermissionRules(ctx, roleID, docType, rules, ttl)
+}
+
+func (c *txAwareCache) SetUserPermissionLevel(ctx context.Context, userID int64, docType string, level int, ttl time.Duration) error {
+	if inTx(ctx) {
+		return nil
+	}
+	return c.PermissionCache.SetUserPermissionLevel(ctx, userID, docType, level, ttl)
+}
+
+func (c *txAwareCache) SetUserLevelPermissions(ctx context.Context, userID int64, docType string, perms *biz.LevelPermissions, ttl time.Duration) error {
+	if inTx(ctx) {
+		return nil
+	}
+	return c.PermissionCache.SetUserLevelPermissions(ctx, userID, docType, perms, ttl)
+}
+
+func (c *txAwareCache) SetFieldPermissionLevels(ctx context.Context, docType string, levels map[string]int, ttl time.Duration) error {
+	if inTx(ctx) {
+		return nil
+	}
+	return c.PermissionCache.SetFieldPermissionLevels(ctx, docType, levels, ttl)
+}
+
+func (c *txAwareCache) SetDocType(ctx context.Context, name string, docType *biz.DocType, ttl time.Duration) error {
+	if inTx(ctx) {
+		return nil
+	}
+	return c.PermissionCache.SetDocType(ctx, name, docType, ttl)
+}
+
+func (c *txAwareCache) DeleteUserPermissions(ctx context.Context, userID int64) error {
+	return c.invalidate(ctx, "delete user permissions cache", func() error {
+		return c.PermissionCache.DeleteUserPermissions(ctx, userID)
+	})
+}
+
+func (c *txAwareCache) DeleteUserRoles(ctx context.Context, userID int64) error {
+	return c.invalidate(ctx, "delete user roles cache", func() error {
+		return c.PermissionCache.DeleteUserRoles(ctx, userID)
+	})
+}
+
+func (c *txAwareCache) DeletePermissionRules(ctx context.Context, roleID int64, docType string) error {
+	return c.invalidate(ctx, "delete permission rules cache", func() error {
+		return c.PermissionCache.DeletePermissionRules(ctx, roleID, docType)
+	})
+}
+
+func (c *txAwareCache) DeleteUserPermissionLevel(ctx context.Context, userID int64, docType string) error {
+	return c.invalidate(ctx, "delete user permission level cache", func() error {
+		return c.PermissionCache.DeleteUserPermissionLevel(ctx, userID, docType)
+	})
+}
+
+func (c *txAwareCache) DeleteUserLevelPermissions(ctx context.Context, userID int64, docType string) error {
+	return c.invalidate(ctx, "delete user level permissions cache", func() error {
+		return c.PermissionCache.DeleteUserLevelPermissions(ctx, userID, docType)
+	})
+}
+
+func (c *txAwareCache) DeleteFieldPermissionLevels(ctx context.Context, docType string) error {
+	return c.invalidate(ctx, "delete field permission levels cache", func() error {
+		return c.PermissionCache.DeleteFieldPermissionLevels(ctx, docType)
+	})
+}
+
+func (c *txAwareCache) DeleteDocType(ctx context.Context, name string) error {
+	return c.invalidate(ctx, "delete doctype cache", func() error {
+		return c.PermissionCache.DeleteDocType(ctx, name)
+	})
+}
+
+func (c *txAwareCache) ClearUserCache(ctx context.Context, userID int64) error {
+	return c.invalidate(ctx, "clear user cache", func() error {
+		return c.PermissionCache.ClearUserCache(ctx, userID)
+	})
+}
+
+func (c *txAwareCache) ClearRoleCache(ctx context.Context, roleID int64) error {
+	return c.invalidate(ctx, "clear role cache", func() error {
+		return c.PermissionCache.ClearRoleCache(ctx, roleID)
+	})
+}
+
+func (c *txAwareCache) ClearDocTypeCache(ctx context.Context, docType string) error {
+	return c.invalidate(ctx, "clear doctype cache", func() error {
+		return c.PermissionCache.ClearDocTypeCache(ctx, docType)
+	})
+}
+
+func (c *txAwareCache) ClearAllPermissionCache(ctx context.Context) error {
+	return c.invalidate(ctx, "clear permission cache", func() error {
+		return c.PermissionCache.ClearAllPermissionCache(ctx)
+	})
+}
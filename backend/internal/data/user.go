@@ -30,15 +30,21 @@ func NewUserRepo(data *Data, logger log.Logger) biz.UserRepo {
 	}
 }
 
-// CreateUser 创建用户
-func (r *userRepo) CreateUser(ctx context.Context, user *biz.User) (*biz.User, error) {
-	var id int32
-	query := `
+// createUserQuery 创建用户，新用户加入当前公司并以其为默认公司，自助注册的用户加入默认公司
+const createUserQuery = `
+	WITH created AS (
 		INSERT INTO users (username, email, password_hash, salt, first_name, last_name, phone, gender, birth_date, 
 		                  avatar_url, is_enabled, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-		RETURNING id`
+		RETURNING id
+	), membership AS (
+		INSERT INTO user_companies (user_id, company_id, is_default)
+		SELECT id, $14, TRUE FROM created
+	)
+	SELECT id FROM created`
 
+// createUserArgs 返回createUserQuery的参数
+func createUserArgs(ctx context.Context, user *biz.User) []interface{} {
 	// 处理可选的gender字段
 	var gender interface{} = nil
 	if user.Gender != "" {
@@ -51,23 +57,35 @@ func (r *userRepo) CreateUser(ctx context.Context, user *biz.User) (*biz.User, e
 		phone = user.Phone
 	}
 
-	err := r.data.db.QueryRowContext(ctx, query,
+	return []interface{}{
 		user.Username, user.Email, user.Password, "", user.FirstName, user.LastName,
 		phone, gender, user.BirthDate, user.AvatarURL, user.IsActive,
-		user.CreatedAt, user.UpdatedAt,
-	).Scan(&id)
+		user.CreatedAt, user.UpdatedAt, biz.CompanyFromContext(ctx),
+	}
+}
 
-	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			if pqErr.Code == "23505" { // unique constraint violation
-				if strings.Contains(pqErr.Detail, "username") {
-					return nil, biz.ErrUsernameExists
-				}
-				if strings.Contains(pqErr.Detail, "email") {
-					return nil, biz.ErrEmailExists
-				}
+// convertCreateUserError 将用户名或邮箱的唯一约束冲突转换为业务错误
+func convertCreateUserError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		if pqErr.Code == "23505" { // unique constraint violation
+			if strings.Contains(pqErr.Detail, "username") {
+				return biz.ErrUsernameExists
+			}
+			if strings.Contains(pqErr.Detail, "email") {
+				return biz.ErrEmailExists
 			}
 		}
+	}
+	return err
+}
+
+// CreateUser 创建用户
+func (r *userRepo) CreateUser(ctx context.Context, user *biz.User) (*biz.User, error) {
+	var id int32
+	if err := r.data.db.QueryRowContext(ctx, createUserQuery, createUserArgs(ctx, user)...).Scan(&id); err != nil {
+		if converted := convertCreateUserError(err); converted != err {
+			return nil, converted
+		}
 		r.log.Errorf("failed to create user: %v", err)
 		return nil, err
 	}
@@ -86,7 +104,7 @@ func (r *userRepo) GetUser(ctx context.Context, id int32) (*biz.User, error) {
 		SELECT id, username, email, password_hash, first_name, last_name, phone, gender, birth_date,
 		       avatar_url, is_enabled, two_factor_enabled, two_factor_secret,
 		       last_login_time, last_login_ip, login_count, created_at, updated_at
-		FROM users WHERE id = $1`
+		FROM users WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.data.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName,
@@ -139,7 +157,7 @@ func (r *userRepo) GetUserByUsername(ctx context.Context, username string) (*biz
 		SELECT id, username, email, password_hash, first_name, last_name, phone, gender, birth_date,
 		       avatar_url, is_enabled, two_factor_enabled, two_factor_secret,
 		       last_login_time, last_login_ip, login_count, created_at, updated_at
-		FROM users WHERE username = $1`
+		FROM users WHERE username = $1 AND deleted_at IS NULL`
 
 	err := r.data.db.QueryRowContext(ctx, query, username).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName,
@@ -192,7 +210,7 @@ func (r *userRepo) GetUserByEmail(ctx context.Context, email string) (*biz.User,
 		SELECT id, username, email, password_hash, first_name, last_name, phone, gender, birth_date,
 		       avatar_url, is_enabled, two_factor_enabled, two_factor_secret,
 		       last_login_time, last_login_ip, login_count, created_at, updated_at
-		FROM users WHERE email = $1`
+		FROM users WHERE email = $1 AND deleted_at IS NULL`
 
 	err := r.data.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName,
@@ -241,7 +259,7 @@ func (r *userRepo) UpdateUser(ctx context.Context, user *biz.User) (*biz.User, e
 		UPDATE users 
 		SET email = $1, first_name = $2, last_name = $3, phone = $4, gender = $5, 
 		    birth_date = $6, avatar_url = $7, is_enabled = $8, updated_at = $9
-		WHERE id = $10`
+		WHERE id = $10 AND deleted_at IS NULL`
 
 	user.UpdatedAt = time.Now()
 	_, err := r.data.db.ExecContext(ctx, query,
@@ -260,7 +278,7 @@ func (r *userRepo) UpdateUser(ctx context.Context, user *biz.User) (*biz.User, e
 
 // UpdatePassword 更新用户密码
 func (r *userRepo) UpdatePassword(ctx context.Context, id int32, hashedPassword string) error {
-	query := "UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+	query := "UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL"
 	_, err := r.data.db.ExecContext(ctx, query, hashedPassword, id)
 	if err != nil {
 		r.log.Errorf("failed to update password: %v", err)
@@ -269,10 +287,10 @@ func (r *userRepo) UpdatePassword(ctx context.Context, id int32, hashedPassword
 	return nil
 }
 
-// DeleteUser 删除用户
-func (r *userRepo) DeleteUser(ctx context.Context, id int32) error {
-	query := "DELETE FROM users WHERE id = $1"
-	_, err := r.data.db.ExecContext(ctx, query, id)
+// DeleteUser 软删除用户，保留成员关系和角色分配以便从回收站恢复
+func (r *userRepo) DeleteUser(ctx context.Context, id int32, deletedBy int32) error {
+	query := "UPDATE users SET deleted_at = $1, deleted_by = NULLIF($2, 0) WHERE id = $3 AND deleted_at IS NULL"
+	_, err := r.data.db.ExecContext(ctx, query, time.Now(), deletedBy, id)
 	if err != nil {
 		r.log.Errorf("failed to delete user: %v", err)
 		return err
@@ -311,9 +329,7 @@ func (r *userRepo) ListUsersWithOptions(ctx context.Context, options *UserListOp
 
 	// 查询数据
 	query := `
-		SELECT id, username, email, first_name, last_name, phone, gender, birth_date,
-		       avatar_url, is_enabled, two_factor_enabled,
-		       last_login_time, last_login_ip, login_count, created_at, updated_at
+		SELECT ` + listUserColumns + `
 		FROM users ` + whereClause + ` ` + orderClause + ` 
 		LIMIT $` + fmt.Sprintf("%d", len(args)+1) + ` OFFSET $` + fmt.Sprintf("%d", len(args)+2)
 
@@ -327,49 +343,94 @@ func (r *userRepo) ListUsersWithOptions(ctx context.Context, options *UserListOp
 	defer rows.Close()
 
 	for rows.Next() {
-		var user biz.User
-		var phone, gender, avatarURL, lastLoginIP sql.NullString
-		var birthDate, lastLoginAt sql.NullTime
-
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Email, &user.FirstName, &user.LastName,
-			&phone, &gender, &birthDate, &avatarURL, &user.IsActive,
-			&user.TwoFactorEnabled, &lastLoginAt, &lastLoginIP,
-			&user.LoginCount, &user.CreatedAt, &user.UpdatedAt,
-		)
+		user, err := scanListUser(rows)
 		if err != nil {
 			r.log.Errorf("failed to scan user: %v", err)
 			return nil, 0, err
 		}
+		users = append(users, user)
+	}
 
-		if phone.Valid {
-			user.Phone = phone.String
-		}
-		if gender.Valid {
-			user.Gender = gender.String
-		}
-		if avatarURL.Valid {
-			user.AvatarURL = avatarURL.String
-		}
-		if lastLoginIP.Valid {
-			user.LastLoginIP = lastLoginIP.String
-		}
-		if birthDate.Valid {
-			user.BirthDate = birthDate.Time
-		}
-		if lastLoginAt.Valid {
-			user.LastLoginAt = lastLoginAt.Time
-		}
+	return users, total, nil
+}
+
+// listUserColumns 用户列表查询列，与scanListUser的扫描顺序一致
+const listUserColumns = `id, username, email, first_name, last_name, phone, gender, birth_date,
+		       avatar_url, is_enabled, two_factor_enabled,
+		       last_login_time, last_login_ip, login_count, created_at, updated_at`
 
-		users = append(users, &user)
+// scanListUser 扫描listUserColumns
+func scanListUser(rows *sql.Rows) (*biz.User, error) {
+	var user biz.User
+	var phone, gender, avatarURL, lastLoginIP sql.NullString
+	var birthDate, lastLoginAt sql.NullTime
+
+	err := rows.Scan(
+		&user.ID, &user.Username, &user.Email, &user.FirstName, &user.LastName,
+		&phone, &gender, &birthDate, &avatarURL, &user.IsActive,
+		&user.TwoFactorEnabled, &lastLoginAt, &lastLoginIP,
+		&user.LoginCount, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	return users, total, nil
+	if phone.Valid {
+		user.Phone = phone.String
+	}
+	if gender.Valid {
+		user.Gender = gender.String
+	}
+	if avatarURL.Valid {
+		user.AvatarURL = avatarURL.String
+	}
+	if lastLoginIP.Valid {
+		user.LastLoginIP = lastLoginIP.String
+	}
+	if birthDate.Valid {
+		user.BirthDate = birthDate.Time
+	}
+	if lastLoginAt.Valid {
+		user.LastLoginAt = lastLoginAt.Time
+	}
+	return &user, nil
+}
+
+// StreamUsers 按过滤和排序条件逐行读取用户，不分页，不在内存中保留全部结果
+func (r *userRepo) StreamUsers(ctx context.Context, query *biz.UserListQuery, fn func(*biz.User) error) error {
+	options := &UserListOptions{
+		Search:           query.Search,
+		FilterConditions: query.FilterConditions,
+		SortConfig:       query.SortConfig,
+	}
+	whereClause, args := r.buildUserFilterQuery(options)
+	// 追加id保证排序稳定
+	orderClause := r.buildUserSortQuery(options.SortConfig) + ", id"
+
+	rows, err := r.data.db.QueryContext(ctx, "SELECT "+listUserColumns+" FROM users "+whereClause+" "+orderClause, args...)
+	if err != nil {
+		r.log.Errorf("failed to stream users: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanListUser(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan user: %v", err)
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // buildUserFilterQuery 构建用户过滤查询条件
 func (r *userRepo) buildUserFilterQuery(options *UserListOptions) (string, []interface{}) {
-	whereClause := "WHERE 1=1"
+	// 已删除的用户只在回收站中列出
+	whereClause := "WHERE deleted_at IS NULL"
 	args := []interface{}{}
 
 	// 兼容旧版搜索
@@ -385,10 +446,15 @@ func (r *userRepo) buildUserFilterQuery(options *UserListOptions) (string, []int
 		if conditions, ok := options.FilterConditions["conditions"].([]interface{}); ok {
 			for _, condition := range conditions {
 				if condMap, ok := condition.(map[string]interface{}); ok {
-					field := condMap["field"].(string)
-					operator := condMap["operator"].(string)
+					field, _ := condMap["field"].(string)
+					operator, _ := condMap["operator"].(string)
 					value := condMap["value"]
 
+					// 字段名直接拼入SQL，只允许用户表的已知列
+					if !biz.IsUserListField(field) {
+						continue
+					}
+
 					switch operator {
 					case "equals":
 						whereClause += fmt.Sprintf(" AND %s = $%d", field, len(args)+1)
@@ -495,29 +561,45 @@ func (r *userRepo) HashPassword(password string) (string, error) {
 	return r.pm.HashPassword(password)
 }
 
-// UpdateLoginInfo 更新登录信息
+// UpdateLoginInfo 更新登录信息并记录一次登录，用于按天统计登录情况
 func (r *userRepo) UpdateLoginInfo(ctx context.Context, userID int32, ip string) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
 	query := `
-		UPDATE users 
+		UPDATE users
 		SET last_login_time = $1, last_login_ip = $2, login_count = login_count + 1
 		WHERE id = $3`
 
-	_, err := r.data.db.ExecContext(ctx, query, time.Now(), ip, userID)
-	if err != nil {
+	if _, err := tx.ExecContext(ctx, query, now, ip, userID); err != nil {
 		r.log.Errorf("failed to update login info: %v", err)
 		return err
 	}
-	return nil
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO user_login_logs (user_id, ip_address, login_at) VALUES ($1, NULLIF($2, ''), $3)",
+		userID, ip, now); err != nil {
+		r.log.Errorf("failed to record login: %v", err)
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// GetUserRoles 获取用户角色
+// GetUserRoles 获取用户当前生效的角色，ValidUntil为该用户持有此角色的最晚截止时间
 func (r *userRepo) GetUserRoles(ctx context.Context, userID int32) ([]*biz.Role, error) {
 	query := `
-		SELECT r.id, r.name, r.code, r.description, r.is_system_role, r.is_enabled, 
-		       r.sort_order, r.created_at, r.updated_at
+		SELECT r.id, r.name, r.code, r.description, r.is_system_role, r.is_enabled,
+		       r.sort_order, r.parent_role_id, r.created_at, r.updated_at,
+		       CASE WHEN BOOL_OR(ur.valid_until IS NULL) THEN NULL ELSE MAX(ur.valid_until) END
 		FROM roles r
 		INNER JOIN user_roles ur ON r.id = ur.role_id
-		WHERE ur.user_id = $1 AND r.is_enabled = true
+		WHERE ur.user_id = $1 AND r.is_enabled = true AND ` + effectiveUserRoleCondition + `
+		GROUP BY r.id
 		ORDER BY r.sort_order`
 
 	rows, err := r.data.db.QueryContext(ctx, query, userID)
@@ -529,16 +611,16 @@ func (r *userRepo) GetUserRoles(ctx context.Context, userID int32) ([]*biz.Role,
 
 	var roles []*biz.Role
 	for rows.Next() {
-		var role biz.Role
-		err := rows.Scan(
-			&role.ID, &role.Name, &role.Code, &role.Description, &role.IsSystemRole,
-			&role.IsEnabled, &role.SortOrder, &role.CreatedAt, &role.UpdatedAt,
-		)
+		var validUntil sql.NullTime
+		role, err := scanRole(rows, &validUntil)
 		if err != nil {
 			r.log.Errorf("failed to scan role: %v", err)
 			return nil, err
 		}
-		roles = append(roles, &role)
+		if validUntil.Valid {
+			role.ValidUntil = &validUntil.Time
+		}
+		roles = append(roles, role)
 	}
 
 	return roles, nil
@@ -551,7 +633,7 @@ func (r *userRepo) GetUserPermissions(ctx context.Context, userID int32) ([]stri
 		FROM permissions p
 		INNER JOIN role_permissions rp ON p.id = rp.permission_id
 		INNER JOIN user_roles ur ON rp.role_id = ur.role_id
-		WHERE ur.user_id = $1`
+		WHERE ur.user_id = $1 AND ` + effectiveUserRoleCondition
 
 	rows, err := r.data.db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -574,39 +656,43 @@ func (r *userRepo) GetUserPermissions(ctx context.Context, userID int32) ([]stri
 	return permissions, nil
 }
 
-// AssignRoles 分配角色
+// AssignRoles 分配角色，仅替换用户长期有效的直接分配，限时分配和委托分配保持不变
+// 用户不再持有的角色上由其发出的委托一并撤销
 func (r *userRepo) AssignRoles(ctx context.Context, userID int32, roleIDs []int32) error {
 	r.log.Infof("AssignRoles called with userID: %d, roleIDs: %v", userID, roleIDs)
-	tx, err := r.data.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// 删除现有角色
-	_, err = tx.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = $1", userID)
-	if err != nil {
-		r.log.Errorf("failed to delete user roles: %v", err)
-		return err
-	}
-
-	// 添加新角色
-	for _, roleID := range roleIDs {
-		_, err = tx.ExecContext(ctx,
-			"INSERT INTO user_roles (user_id, role_id, granted_at) VALUES ($1, $2, $3)",
-			userID, roleID, time.Now())
+	return withSuperAdminGuard(ctx, r.data, func(ctx context.Context, tx dbConn) error {
+		// 删除现有的长期直接分配
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM user_roles
+			WHERE user_id = $1 AND delegated_by IS NULL AND valid_from IS NULL AND valid_until IS NULL`, userID)
 		if err != nil {
-			r.log.Errorf("failed to assign role: %v", err)
+			r.log.Errorf("failed to delete user roles: %v", err)
 			return err
 		}
-	}
 
-	return tx.Commit()
+		// 添加新角色
+		for _, roleID := range roleIDs {
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO user_roles (user_id, role_id, assigned_at) VALUES ($1, $2, $3)",
+				userID, roleID, time.Now())
+			if err != nil {
+				r.log.Errorf("failed to assign role: %v", err)
+				return err
+			}
+		}
+
+		// 撤销失去角色后遗留的委托
+		if _, err := deleteOrphanedDelegations(ctx, tx, userID); err != nil {
+			r.log.Errorf("failed to delete orphaned delegations: %v", err)
+			return err
+		}
+		return nil
+	})
 }
 
 // EnableTwoFactor 启用2FA
 func (r *userRepo) EnableTwoFactor(ctx context.Context, userID int32, secret string) error {
-	query := `UPDATE users SET two_factor_enabled = true, two_factor_secret = $1 WHERE id = $2`
+	query := `UPDATE users SET two_factor_enabled = true, two_factor_secret = $1 WHERE id = $2 AND deleted_at IS NULL`
 	_, err := r.data.db.ExecContext(ctx, query, secret, userID)
 	if err != nil {
 		r.log.Errorf("failed to enable two factor: %v", err)
@@ -617,7 +703,7 @@ func (r *userRepo) EnableTwoFactor(ctx context.Context, userID int32, secret str
 
 // DisableTwoFactor 禁用2FA
 func (r *userRepo) DisableTwoFactor(ctx context.Context, userID int32) error {
-	query := `UPDATE users SET two_factor_enabled = false, two_factor_secret = '' WHERE id = $1`
+	query := `UPDATE users SET two_factor_enabled = false, two_factor_secret = '' WHERE id = $1 AND deleted_at IS NULL`
 	_, err := r.data.db.ExecContext(ctx, query, userID)
 	if err != nil {
 		r.log.Errorf("failed to disable two factor: %v", err)
@@ -0,0 +1,196 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// superAdminLockKey 变更超级管理员的事务锁，保证并发的删除、禁用、移除角色以及禁用或删除超级管理员角色不会同时移走最后一个超级管理员
+const superAdminLockKey = "super_admin"
+
+// userAdminRepo 用户管理仓储实现
+type userAdminRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewUserAdminRepo 创建用户管理仓储
+func NewUserAdminRepo(data *Data, logger log.Logger) biz.UserAdminRepo {
+	return &userAdminRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// DeleteUsers 在一个事务中软删除用户
+func (r *userAdminRepo) DeleteUsers(ctx context.Context, ids []int32, deletedBy int32) error {
+	return withSuperAdminGuard(ctx, r.data, func(ctx context.Context, tx dbConn) error {
+		query := "UPDATE users SET deleted_at = $1, deleted_by = NULLIF($2, 0) WHERE id = ANY($3) AND deleted_at IS NULL"
+		if _, err := tx.ExecContext(ctx, query, time.Now(), deletedBy, pq.Array(ids)); err != nil {
+			r.log.Errorf("failed to delete users: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// SetUserEnabled 启用或禁用用户
+func (r *userAdminRepo) SetUserEnabled(ctx context.Context, id int32, enabled bool) error {
+	return withSuperAdminGuard(ctx, r.data, func(ctx context.Context, tx dbConn) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE users SET is_enabled = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL", enabled, time.Now(), id)
+		if err != nil {
+			r.log.Errorf("failed to set user status: %v", err)
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return biz.ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+// RemoveUserRole 移除用户直接持有的角色（含限时分配），用户收到的委托保持不变
+func (r *userAdminRepo) RemoveUserRole(ctx context.Context, userID, roleID int32) error {
+	return withSuperAdminGuard(ctx, r.data, func(ctx context.Context, tx dbConn) error {
+		result, err := tx.ExecContext(ctx,
+			"DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2 AND delegated_by IS NULL", userID, roleID)
+		if err != nil {
+			r.log.Errorf("failed to remove user role: %v", err)
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return biz.ErrUserRoleNotFound
+		}
+
+		// 撤销失去角色后遗留的委托
+		if _, err := deleteOrphanedDelegations(ctx, tx, userID); err != nil {
+			r.log.Errorf("failed to delete orphaned delegations: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// withSuperAdminGuard 在超级管理员锁下执行变更，变更前有启用的超级管理员而变更后没有时回滚并返回ErrLastSuperAdmin
+// ctx中已有事务时加入该事务，由外层事务提交
+func withSuperAdminGuard(ctx context.Context, data *Data, fn func(ctx context.Context, tx dbConn) error) error {
+	return data.InTx(ctx, func(ctx context.Context) error {
+		tx := data.conn(ctx)
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", superAdminLockKey); err != nil {
+			return err
+		}
+
+		before, err := countSuperAdmins(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+		after, err := countSuperAdmins(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if before > 0 && after == 0 {
+			return biz.ErrLastSuperAdmin
+		}
+		return nil
+	})
+}
+
+// countSuperAdmins 统计当前持有启用的超级管理员角色的启用用户数
+func countSuperAdmins(ctx context.Context, tx dbConn) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT u.id)
+		FROM users u
+		INNER JOIN user_roles ur ON ur.user_id = u.id
+		INNER JOIN roles r ON r.id = ur.role_id
+		WHERE r.code = $1 AND r.is_enabled = TRUE AND r.deleted_at IS NULL
+			AND u.is_enabled = TRUE AND u.deleted_at IS NULL AND ` + effectiveUserRoleCondition
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, biz.SuperAdminRoleCode).Scan(&count)
+	return count, err
+}
+
+// GetUserStats 统计用户数量、各角色的用户数和since之后每天的登录情况
+func (r *userAdminRepo) GetUserStats(ctx context.Context, since time.Time) (*biz.UserStats, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	stats := &biz.UserStats{RoleStats: []*biz.UserRoleStat{}}
+	countQuery := `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE is_enabled),
+		       COUNT(*) FILTER (WHERE is_enabled AND two_factor_enabled),
+		       COUNT(*) FILTER (WHERE created_at >= $1),
+		       COUNT(*) FILTER (WHERE created_at >= $2)
+		FROM users WHERE deleted_at IS NULL`
+	err := r.data.db.QueryRowContext(ctx, countQuery, today, monthStart).Scan(
+		&stats.TotalUsers, &stats.ActiveUsers, &stats.TwoFactorUsers, &stats.NewUsersToday, &stats.NewUsersThisMonth,
+	)
+	if err != nil {
+		r.log.Errorf("failed to count users: %v", err)
+		return nil, err
+	}
+	stats.DisabledUsers = stats.TotalUsers - stats.ActiveUsers
+
+	roleQuery := `
+		SELECT r.id, r.code, r.name, COUNT(DISTINCT ur.user_id)
+		FROM roles r
+		LEFT JOIN user_roles ur ON ur.role_id = r.id AND ` + effectiveUserRoleCondition + `
+			AND EXISTS (SELECT 1 FROM users u WHERE u.id = ur.user_id AND u.deleted_at IS NULL)
+		WHERE r.is_enabled = TRUE AND r.deleted_at IS NULL
+		GROUP BY r.id, r.code, r.name, r.sort_order
+		ORDER BY r.sort_order, r.id`
+	rows, err := r.data.db.QueryContext(ctx, roleQuery)
+	if err != nil {
+		r.log.Errorf("failed to count role users: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var stat biz.UserRoleStat
+		if err := rows.Scan(&stat.RoleID, &stat.RoleCode, &stat.RoleName, &stat.UserCount); err != nil {
+			r.log.Errorf("failed to scan role user count: %v", err)
+			return nil, err
+		}
+		stats.RoleStats = append(stats.RoleStats, &stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	loginQuery := `
+		SELECT TO_CHAR(login_at, 'YYYY-MM-DD') AS day, COUNT(*), COUNT(DISTINCT user_id)
+		FROM user_login_logs
+		WHERE login_at >= $1
+		GROUP BY day
+		ORDER BY day`
+	loginRows, err := r.data.db.QueryContext(ctx, loginQuery, since)
+	if err != nil {
+		r.log.Errorf("failed to count logins: %v", err)
+		return nil, err
+	}
+	defer loginRows.Close()
+	for loginRows.Next() {
+		var stat biz.DailyLoginStat
+		if err := loginRows.Scan(&stat.Date, &stat.Logins, &stat.Users); err != nil {
+			r.log.Errorf("failed to scan daily logins: %v", err)
+			return nil, err
+		}
+		stats.LoginsPerDay = append(stats.LoginsPerDay, &stat)
+	}
+	return stats, loginRows.Err()
+}
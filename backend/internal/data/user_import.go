@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// userImportRepo 用户导入仓储实现
+type userImportRepo struct {
+	data *Data
+	log  *log.Helper
+	orgs *organizationRepo // 复用组织成员的加入逻辑
+}
+
+// NewUserImportRepo 创建用户导入仓储
+func NewUserImportRepo(data *Data, logger log.Logger) biz.UserImportRepo {
+	helper := log.NewHelper(logger)
+	return &userImportRepo{
+		data: data,
+		log:  helper,
+		orgs: &organizationRepo{data: data, log: helper},
+	}
+}
+
+// FindExistingUsers 返回用户名或邮箱（不区分大小写）已被占用的用户，包括已删除的用户
+func (r *userImportRepo) FindExistingUsers(ctx context.Context, usernames, emails []string) ([]*biz.User, error) {
+	if len(usernames) == 0 && len(emails) == 0 {
+		return nil, nil
+	}
+
+	query := "SELECT id, username, email FROM users WHERE username = ANY($1) OR LOWER(email) = ANY($2)"
+	rows, err := r.data.db.QueryContext(ctx, query, pq.Array(usernames), pq.Array(emails))
+	if err != nil {
+		r.log.Errorf("failed to find existing users: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*biz.User
+	for rows.Next() {
+		var user biz.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email); err != nil {
+			r.log.Errorf("failed to scan existing user: %v", err)
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// CreateImportedUsers 在一个事务中创建一批用户，分配角色并加入当前公司和组织，成功后回填User.ID
+func (r *userImportRepo) CreateImportedUsers(ctx context.Context, users []*biz.UserImportUser, operatorID int32) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, imported := range users {
+		user := imported.User
+		if err := tx.QueryRowContext(ctx, createUserQuery, createUserArgs(ctx, user)...).Scan(&user.ID); err != nil {
+			if converted := convertCreateUserError(err); converted != err {
+				return converted
+			}
+			r.log.Errorf("failed to create imported user %s: %v", user.Username, err)
+			return err
+		}
+
+		for _, roleID := range imported.RoleIDs {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO user_roles (user_id, role_id, assigned_at) VALUES ($1, $2, $3)",
+				user.ID, roleID, now)
+			if err != nil {
+				r.log.Errorf("failed to assign role to imported user %s: %v", user.Username, err)
+				return err
+			}
+		}
+
+		for i, orgID := range imported.OrgIDs {
+			leaderID, err := r.orgs.lockMemberOrganization(ctx, tx, orgID)
+			if err != nil {
+				return err
+			}
+			member := &biz.OrganizationMember{
+				UserID:    user.ID,
+				OrgID:     orgID,
+				Position:  imported.Position,
+				IsPrimary: i == 0,
+				JoinedAt:  now,
+			}
+			if err := r.orgs.addMemberTx(ctx, tx, member, leaderID, operatorID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit imported users: %v", err)
+		return err
+	}
+	return nil
+}
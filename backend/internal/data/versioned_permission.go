@@ -0,0 +1,214 @@
+package data
+
+import (
+	"context"
+	"sync"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/cache"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 权限配置版本的操作名称
+const (
+	versionOpCreatePermissionRule   = "create_permission_rule"
+	versionOpUpdatePermissionRule   = "update_permission_rule"
+	versionOpDeletePermissionRule   = "delete_permission_rule"
+	versionOpBatchPermissionRules   = "batch_create_permission_rules"
+	versionOpUpsertPermissionRules  = "upsert_permission_rules"
+	versionOpReplacePermissionRules = "replace_permission_rules"
+	versionOpCreateUserPermission   = "create_user_permission"
+	versionOpUpdateUserPermission   = "update_user_permission"
+	versionOpDeleteUserPermission   = "delete_user_permission"
+	versionOpBatchUserPermissions   = "batch_create_user_permissions"
+	versionOpCreateFieldLevel       = "create_field_level"
+	versionOpUpdateFieldLevel       = "update_field_level"
+	versionOpDeleteFieldLevel       = "delete_field_level"
+)
+
+// VersionedPermissionRepo 权限配置版本装饰器，权限规则、用户权限和字段权限级别的变更与其版本记录在同一事务中完成
+type VersionedPermissionRepo struct {
+	biz.PermissionRepo
+	versions  biz.PermissionVersionRepo
+	tx        biz.Transaction
+	log       *log.Helper
+	baselined sync.Map // 已确认存在版本的公司
+}
+
+// NewVersionedPermissionRepo 创建带版本记录的权限仓储
+func NewVersionedPermissionRepo(repo biz.PermissionRepo, versions biz.PermissionVersionRepo, tx biz.Transaction, logger log.Logger) biz.PermissionRepo {
+	return &VersionedPermissionRepo{
+		PermissionRepo: repo,
+		versions:       versions,
+		tx:             tx,
+		log:            log.NewHelper(logger),
+	}
+}
+
+// ProvidePermissionRepo 提供带缓存并记录配置版本的权限仓储
+func ProvidePermissionRepo(data *Data, permissionCache cache.PermissionCache, versions biz.PermissionVersionRepo, logger log.Logger) biz.PermissionRepo {
+	return newPermissionRepoChain(NewPermissionRepo(data, logger), permissionCache, versions, data, logger)
+}
+
+// newPermissionRepoChain 组装权限仓储：版本记录包裹缓存，缓存包裹数据库仓储，变更先经缓存失效再记录版本
+func newPermissionRepoChain(repo biz.PermissionRepo, permissionCache cache.PermissionCache, versions biz.PermissionVersionRepo, tx biz.Transaction, logger log.Logger) biz.PermissionRepo {
+	return NewVersionedPermissionRepo(NewCachedPermissionRepo(repo, permissionCache, logger), versions, tx, logger)
+}
+
+// track 在一个事务中执行变更并记录版本：先锁定权限配置，首次变更前记录基线版本，
+// 变更后记录新版本；版本记录失败时变更一并回滚
+func (r *VersionedPermissionRepo) track(ctx context.Context, operation string, mutate func(ctx context.Context) error) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := r.versions.LockVersions(ctx); err != nil {
+			return err
+		}
+
+		if err := r.ensureBaseline(ctx); err != nil {
+			return err
+		}
+
+		if err := mutate(ctx); err != nil {
+			return err
+		}
+
+		changeset := biz.PermissionChangesetFromContext(ctx)
+		_, err := r.versions.RecordVersion(ctx, &biz.PermissionVersion{
+			Operation:  operation,
+			Comment:    changeset.Comment,
+			AuthorID:   changeset.AuthorID,
+			RollbackOf: changeset.RollbackOf,
+		})
+		if err != nil {
+			r.log.Errorf("Failed to record permission version for %s: %v", operation, err)
+			return err
+		}
+		return nil
+	})
+}
+
+// ensureBaseline 当前公司尚无任何版本时，将变更前的配置记录为基线版本，使首次变更也可回滚
+func (r *VersionedPermissionRepo) ensureBaseline(ctx context.Context) error {
+	companyID := biz.CompanyFromContext(ctx)
+	if _, ok := r.baselined.Load(companyID); ok {
+		return nil
+	}
+
+	exists, err := r.versions.HasVersions(ctx)
+	if err != nil {
+		r.log.Errorf("Failed to check permission versions: %v", err)
+		return err
+	}
+	if !exists {
+		if _, err := r.versions.RecordVersion(ctx, &biz.PermissionVersion{Operation: biz.PermissionVersionBaseline}); err != nil {
+			r.log.Errorf("Failed to record baseline permission version: %v", err)
+			return err
+		}
+	}
+
+	// 基线随事务提交后才可视为已存在
+	afterCommit(ctx, func() {
+		r.baselined.Store(companyID, true)
+	})
+	return nil
+}
+
+// 权限规则管理 - 记录版本
+func (r *VersionedPermissionRepo) CreatePermissionRule(ctx context.Context, rule *biz.PermissionRule) (result *biz.PermissionRule, err error) {
+	err = r.track(ctx, versionOpCreatePermissionRule, func(ctx context.Context) error {
+		result, err = r.PermissionRepo.CreatePermissionRule(ctx, rule)
+		return err
+	})
+	return result, err
+}
+
+func (r *VersionedPermissionRepo) UpdatePermissionRule(ctx context.Context, rule *biz.PermissionRule) (result *biz.PermissionRule, err error) {
+	err = r.track(ctx, versionOpUpdatePermissionRule, func(ctx context.Context) error {
+		result, err = r.PermissionRepo.UpdatePermissionRule(ctx, rule)
+		return err
+	})
+	return result, err
+}
+
+func (r *VersionedPermissionRepo) DeletePermissionRule(ctx context.Context, id int64) error {
+	return r.track(ctx, versionOpDeletePermissionRule, func(ctx context.Context) error {
+		return r.PermissionRepo.DeletePermissionRule(ctx, id)
+	})
+}
+
+func (r *VersionedPermissionRepo) BatchCreatePermissionRules(ctx context.Context, rules []*biz.PermissionRule) error {
+	return r.track(ctx, versionOpBatchPermissionRules, func(ctx context.Context) error {
+		return r.PermissionRepo.BatchCreatePermissionRules(ctx, rules)
+	})
+}
+
+func (r *VersionedPermissionRepo) UpsertPermissionRules(ctx context.Context, rules []*biz.PermissionRule) error {
+	return r.track(ctx, versionOpUpsertPermissionRules, func(ctx context.Context) error {
+		return r.PermissionRepo.UpsertPermissionRules(ctx, rules)
+	})
+}
+
+func (r *VersionedPermissionRepo) ReplacePermissionRules(ctx context.Context, upserts, removals []*biz.PermissionRule) error {
+	return r.track(ctx, versionOpReplacePermissionRules, func(ctx context.Context) error {
+		return r.PermissionRepo.ReplacePermissionRules(ctx, upserts, removals)
+	})
+}
+
+// 用户权限管理 - 记录版本
+func (r *VersionedPermissionRepo) CreateUserPermission(ctx context.Context, userPermission *biz.UserPermission) (result *biz.UserPermission, err error) {
+	err = r.track(ctx, versionOpCreateUserPermission, func(ctx context.Context) error {
+		result, err = r.PermissionRepo.CreateUserPermission(ctx, userPermission)
+		return err
+	})
+	return result, err
+}
+
+func (r *VersionedPermissionRepo) UpdateUserPermission(ctx context.Context, userPermission *biz.UserPermission) (result *biz.UserPermission, err error) {
+	err = r.track(ctx, versionOpUpdateUserPermission, func(ctx context.Context) error {
+		result, err = r.PermissionRepo.UpdateUserPermission(ctx, userPermission)
+		return err
+	})
+	return result, err
+}
+
+func (r *VersionedPermissionRepo) DeleteUserPermission(ctx context.Context, id int64) error {
+	return r.track(ctx, versionOpDeleteUserPermission, func(ctx context.Context) error {
+		return r.PermissionRepo.DeleteUserPermission(ctx, id)
+	})
+}
+
+func (r *VersionedPermissionRepo) BatchCreateUserPermissions(ctx context.Context, permissions []*biz.UserPermission) error {
+	return r.track(ctx, versionOpBatchUserPermissions, func(ctx context.Context) error {
+		return r.PermissionRepo.BatchCreateUserPermissions(ctx, permissions)
+	})
+}
+
+// 字段权限级别管理 - 记录版本
+func (r *VersionedPermissionRepo) CreateFieldPermissionLevel(ctx context.Context, field *biz.FieldPermissionLevel) (result *biz.FieldPermissionLevel, err error) {
+	err = r.track(ctx, versionOpCreateFieldLevel, func(ctx context.Context) error {
+		result, err = r.PermissionRepo.CreateFieldPermissionLevel(ctx, field)
+		return err
+	})
+	return result, err
+}
+
+func (r *VersionedPermissionRepo) UpdateFieldPermissionLevel(ctx context.Context, field *biz.FieldPermissionLevel) (result *biz.FieldPermissionLevel, err error) {
+	err = r.track(ctx, versionOpUpdateFieldLevel, func(ctx context.Context) error {
+		result, err = r.PermissionRepo.UpdateFieldPermissionLevel(ctx, field)
+		return err
+	})
+	return result, err
+}
+
+func (r *VersionedPermissionRepo) DeleteFieldPermissionLevel(ctx context.Context, id int64) error {
+	return r.track(ctx, versionOpDeleteFieldLevel, func(ctx context.Context) error {
+		return r.PermissionRepo.DeleteFieldPermissionLevel(ctx, id)
+	})
+}
+
+// RestorePermissionSnapshot 恢复快照并记录回滚版本
+func (r *VersionedPermissionRepo) RestorePermissionSnapshot(ctx context.Context, snapshot *biz.PermissionSnapshot) error {
+	return r.track(ctx, biz.PermissionVersionRollback, func(ctx context.Context) error {
+		return r.PermissionRepo.RestorePermissionSnapshot(ctx, snapshot)
+	})
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubPermissionVersionRepo 在内存中记录权限配置版本
+type stubPermissionVersionRepo struct {
+	versions  []*biz.PermissionVersion
+	companies map[int32]bool // 已有版本的公司
+	locks     int
+	recordErr error
+}
+
+func (r *stubPermissionVersionRepo) RecordVersion(ctx context.Context, version *biz.PermissionVersion) (*biz.PermissionVersion, error) {
+	if r.recordErr != nil {
+		return nil, r.recordErr
+	}
+	version.ID = int64(len(r.versions) + 1)
+	r.versions = append(r.versions, version)
+	if r.companies == nil {
+		r.companies = make(map[int32]bool)
+	}
+	r.companies[biz.CompanyFromContext(ctx)] = true
+	return version, nil
+}
+
+func (r *stubPermissionVersionRepo) GetVersion(ctx context.Context, id int64) (*biz.PermissionVersion, error) {
+	if id <= 0 || int(id) > len(r.versions) {
+		return nil, biz.ErrPermissionVersionNotFound
+	}
+	return r.versions[id-1], nil
+}
+
+func (r *stubPermissionVersionRepo) ListVersions(ctx context.Context, page, size int32) ([]*biz.PermissionVersion, int32, error) {
+	return r.versions, int32(len(r.versions)), nil
+}
+
+func (r *stubPermissionVersionRepo) HasVersions(ctx context.Context) (bool, error) {
+	return r.companies[biz.CompanyFromContext(ctx)], nil
+}
+
+func (r *stubPermissionVersionRepo) LockVersions(ctx context.Context) error {
+	r.locks++
+	return nil
+}
+
+// stubTransaction 直接执行fn，记录事务次数及失败回滚次数
+type stubTransaction struct {
+	calls     int
+	rollbacks int
+}
+
+func (t *stubTransaction) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.calls++
+	if err := fn(ctx); err != nil {
+		t.rollbacks++
+		return err
+	}
+	return nil
+}
+
+// stubVersionedPermissionRepo 仅实现版本记录测试所需的变更方法
+type stubVersionedPermissionRepo struct {
+	biz.PermissionRepo
+	restored *biz.PermissionSnapshot
+}
+
+func (r *stubVersionedPermissionRepo) CreatePermissionRule(ctx context.Context, rule *biz.PermissionRule) (*biz.PermissionRule, error) {
+	if rule.RoleID == 0 {
+		return nil, fmt.Errorf("invalid role")
+	}
+	rule.ID = 1
+	return rule, nil
+}
+
+func (r *stubVersionedPermissionRepo) DeleteUserPermission(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (r *stubVersionedPermissionRepo) RestorePermissionSnapshot(ctx context.Context, snapshot *biz.PermissionSnapshot) error {
+	r.restored = snapshot
+	return nil
+}
+
+func TestVersionedPermissionRepo(t *testing.T) {
+	versions := &stubPermissionVersionRepo{}
+	base := &stubVersionedPermissionRepo{}
+	tx := &stubTransaction{}
+	repo := NewVersionedPermissionRepo(base, versions, tx, log.DefaultLogger)
+
+	authorID := int64(7)
+	ctx := biz.WithPermissionChangeset(context.Background(), &biz.PermissionChangeset{AuthorID: &authorID, Comment: "开放订单读取"})
+	assert.Equal(t, "开放订单读取", biz.PermissionChangesetFromContext(ctx).Comment)
+	assert.NotNil(t, biz.PermissionChangesetFromContext(context.Background()))
+
+	// 首次变更前记录基线版本
+	rule, err := repo.CreatePermissionRule(ctx, &biz.PermissionRule{RoleID: 1, DocType: "Order", CanRead: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rule.ID)
+	assert.Len(t, versions.versions, 2)
+	assert.Equal(t, biz.PermissionVersionBaseline, versions.versions[0].Operation)
+	assert.Equal(t, "create_permission_rule", versions.versions[1].Operation)
+	assert.Equal(t, &authorID, versions.versions[1].AuthorID)
+	assert.Equal(t, "开放订单读取", versions.versions[1].Comment)
+	assert.Equal(t, 1, tx.calls)
+	assert.Equal(t, 1, versions.locks)
+
+	// 变更失败不记录版本
+	_, err = repo.CreatePermissionRule(ctx, &biz.PermissionRule{DocType: "Order"})
+	assert.Error(t, err)
+	assert.Len(t, versions.versions, 2)
+
+	assert.NoError(t, repo.DeleteUserPermission(context.Background(), 3))
+	assert.Len(t, versions.versions, 3)
+	assert.Equal(t, "delete_user_permission", versions.versions[2].Operation)
+	assert.Nil(t, versions.versions[2].AuthorID)
+
+	// 回滚恢复目标版本快照，并记录为指向目标版本的新版本
+	versions.versions[0].Snapshot = &biz.PermissionSnapshot{}
+	uc := biz.NewPermissionVersionUsecase(versions, repo, log.DefaultLogger)
+	target, err := uc.Rollback(ctx, 1, &biz.PermissionChangeset{AuthorID: &authorID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), target.ID)
+	assert.Same(t, versions.versions[0].Snapshot, base.restored)
+	assert.Len(t, versions.versions, 4)
+	assert.Equal(t, biz.PermissionVersionRollback, versions.versions[3].Operation)
+	assert.Equal(t, int64(1), *versions.versions[3].RollbackOf)
+	assert.Equal(t, "回滚到版本 1", versions.versions[3].Comment)
+
+	_, err = uc.Rollback(ctx, 99, &biz.PermissionChangeset{})
+	assert.ErrorIs(t, err, biz.ErrPermissionVersionNotFound)
+
+	// 每个公司在首次变更前各自记录基线版本
+	companyCtx := biz.WithCompany(context.Background(), 2)
+	assert.NoError(t, repo.DeleteUserPermission(companyCtx, 3))
+	assert.Len(t, versions.versions, 6)
+	assert.Equal(t, biz.PermissionVersionBaseline, versions.versions[4].Operation)
+	assert.NoError(t, repo.DeleteUserPermission(companyCtx, 4))
+	assert.Len(t, versions.versions, 7)
+
+	// 版本记录失败时返回错误，变更随事务回滚
+	versions.recordErr = fmt.Errorf("snapshot failed")
+	rollbacks := tx.rollbacks
+	err = repo.DeleteUserPermission(companyCtx, 5)
+	assert.EqualError(t, err, "snapshot failed")
+	assert.Equal(t, rollbacks+1, tx.rollbacks)
+	assert.Len(t, versions.versions, 7)
+}
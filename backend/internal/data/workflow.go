@@ -0,0 +1,189 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"erp-system/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/lib/pq"
+)
+
+// workflowRepo 工作流仓储实现
+type workflowRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+// NewWorkflowRepo 创建工作流仓储
+func NewWorkflowRepo(data *Data, logger log.Logger) biz.WorkflowRepo {
+	return &workflowRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+// workflowColumns 工作流查询列，与scanWorkflow的扫描顺序一致
+const workflowColumns = `id, doc_type, name, description, is_active, states, transitions,
+		       created_at, updated_at, created_by, updated_by`
+
+// SaveWorkflow 在事务中按文档类型创建或替换工作流，并同步文档类型的has_workflow标志
+func (r *workflowRepo) SaveWorkflow(ctx context.Context, workflow *biz.Workflow) (*biz.Workflow, error) {
+	statesJSON, err := json.Marshal(workflow.States)
+	if err != nil {
+		r.log.Errorf("failed to marshal workflow states: %v", err)
+		return nil, err
+	}
+	transitionsJSON, err := json.Marshal(workflow.Transitions)
+	if err != nil {
+		r.log.Errorf("failed to marshal workflow transitions: %v", err)
+		return nil, err
+	}
+
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO workflows (doc_type, name, description, is_active, states, transitions, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (doc_type) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, is_active = EXCLUDED.is_active,
+		    states = EXCLUDED.states, transitions = EXCLUDED.transitions, updated_by = EXCLUDED.updated_by
+		RETURNING ` + workflowColumns
+
+	userID := workflow.UpdatedBy
+	if userID == nil {
+		userID = workflow.CreatedBy
+	}
+	saved, err := r.scanWorkflow(tx.QueryRowContext(ctx, query,
+		workflow.DocType, workflow.Name, workflow.Description, workflow.IsActive,
+		statesJSON, transitionsJSON, userID,
+	))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" && pqErr.Constraint == "fk_workflows_doc_type" {
+			return nil, biz.ErrDocTypeNotFound
+		}
+		r.log.Errorf("failed to save workflow: %v", err)
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE doc_types SET has_workflow = $2 WHERE name = $1", workflow.DocType, workflow.IsActive)
+	if err != nil {
+		r.log.Errorf("failed to update doctype workflow flag: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// GetWorkflow 获取文档类型的工作流
+func (r *workflowRepo) GetWorkflow(ctx context.Context, docType string) (*biz.Workflow, error) {
+	workflow, err := r.scanWorkflow(r.data.db.QueryRowContext(ctx,
+		"SELECT "+workflowColumns+" FROM workflows WHERE doc_type = $1", docType))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, biz.ErrWorkflowNotFound
+		}
+		r.log.Errorf("failed to get workflow: %v", err)
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// ListWorkflows 获取全部工作流
+func (r *workflowRepo) ListWorkflows(ctx context.Context) ([]*biz.Workflow, error) {
+	rows, err := r.data.db.QueryContext(ctx, "SELECT "+workflowColumns+" FROM workflows ORDER BY doc_type")
+	if err != nil {
+		r.log.Errorf("failed to list workflows: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	workflows := []*biz.Workflow{}
+	for rows.Next() {
+		workflow, err := r.scanWorkflow(rows)
+		if err != nil {
+			r.log.Errorf("failed to scan workflow: %v", err)
+			return nil, err
+		}
+		workflows = append(workflows, workflow)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Errorf("failed to iterate workflows: %v", err)
+		return nil, err
+	}
+
+	return workflows, nil
+}
+
+// DeleteWorkflow 删除文档类型的工作流并清除has_workflow标志
+func (r *workflowRepo) DeleteWorkflow(ctx context.Context, docType string) error {
+	tx, err := r.data.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Errorf("failed to begin transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM workflows WHERE doc_type = $1", docType)
+	if err != nil {
+		r.log.Errorf("failed to delete workflow: %v", err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return biz.ErrWorkflowNotFound
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE doc_types SET has_workflow = FALSE WHERE name = $1", docType); err != nil {
+		r.log.Errorf("failed to update doctype workflow flag: %v", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Errorf("failed to commit transaction: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// scanWorkflow 扫描一行工作流
+func (r *workflowRepo) scanWorkflow(row rowScanner) (*biz.Workflow, error) {
+	var workflow biz.Workflow
+	var description sql.NullString
+	var statesJSON, transitionsJSON []byte
+	var createdBy, updatedBy sql.NullInt64
+
+	err := row.Scan(&workflow.ID, &workflow.DocType, &workflow.Name, &description, &workflow.IsActive,
+		&statesJSON, &transitionsJSON, &workflow.CreatedAt, &workflow.UpdatedAt, &createdBy, &updatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(statesJSON, &workflow.States); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(transitionsJSON, &workflow.Transitions); err != nil {
+		return nil, err
+	}
+	workflow.Description = description.String
+	if createdBy.Valid {
+		workflow.CreatedBy = &createdBy.Int64
+	}
+	if updatedBy.Valid {
+		workflow.UpdatedBy = &updatedBy.Int64
+	}
+	return &workflow, nil
+}
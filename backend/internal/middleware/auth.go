@@ -25,6 +25,10 @@ type JWTClaims struct {
 	Roles     []string `json:"roles"`
 	SessionID string   `json:"session_id"`
 	TokenType string   `json:"token_type"` // access, refresh
+	CompanyID int32    `json:"company_id,omitempty"`
+
+	// RoleExpiry 限时角色的截止时间（Unix秒）
+	RoleExpiry map[string]int64 `json:"role_expiry,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -105,6 +109,11 @@ func (m *AuthMiddleware) JWT() middleware.Middleware {
 				return nil, errors.Unauthorized("UNAUTHORIZED", "invalid token type")
 			}
 
+			// 未携带公司的令牌（迁移前签发）需刷新后使用
+			if claims.CompanyID <= 0 {
+				return nil, errors.Unauthorized("UNAUTHORIZED", "token has no company")
+			}
+
 			// 检查会话是否有效
 			if err := m.validateSession(ctx, claims.UserID, claims.SessionID); err != nil {
 				m.logger.Warnf("Invalid session: %v", err)
@@ -275,11 +284,28 @@ func (m *AuthMiddleware) setUserContext(ctx context.Context, claims *JWTClaims)
 	ctx = SetUserIDToContext(ctx, claims.UserID)
 	ctx = SetUsernameToContext(ctx, claims.Username)
 	ctx = SetUserEmailToContext(ctx, claims.Email)
-	ctx = SetUserRolesToContext(ctx, claims.Roles)
+	ctx = SetUserRolesToContext(ctx, activeRoles(claims, time.Now()))
 	ctx = SetSessionIDToContext(ctx, claims.SessionID)
+	ctx = biz.WithCompany(ctx, claims.CompanyID)
 	return ctx
 }
 
+// activeRoles 过滤令牌签发后已到期的限时角色
+func activeRoles(claims *JWTClaims, now time.Time) []string {
+	if len(claims.RoleExpiry) == 0 {
+		return claims.Roles
+	}
+
+	roles := make([]string, 0, len(claims.Roles))
+	for _, role := range claims.Roles {
+		if expiry, ok := claims.RoleExpiry[role]; ok && now.Unix() >= expiry {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
 // generatePermissionCode 生成权限编码
 func (m *AuthMiddleware) generatePermissionCode(tr transport.Transporter) string {
 	var method, path string
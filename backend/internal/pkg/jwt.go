@@ -22,9 +22,31 @@ type CustomClaims struct {
 	Permissions []string `json:"permissions"`
 	SessionID   string   `json:"session_id"`
 	TokenType   string   `json:"token_type"` // access, refresh
+
+	// CompanyID 当前公司，迁移前签发的令牌没有该字段，访问令牌需刷新后使用，刷新令牌进入用户的默认公司
+	CompanyID int32 `json:"company_id,omitempty"`
+
+	// RoleExpiry 限时角色的截止时间（Unix秒），令牌有效期内到期的角色不再生效
+	RoleExpiry map[string]int64 `json:"role_expiry,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActiveRoles 返回在指定时间点仍然有效的角色
+func (c *CustomClaims) ActiveRoles(now time.Time) []string {
+	if len(c.RoleExpiry) == 0 {
+		return c.Roles
+	}
+
+	roles := make([]string, 0, len(c.Roles))
+	for _, role := range c.Roles {
+		if expiry, ok := c.RoleExpiry[role]; ok && now.Unix() >= expiry {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
 // NewJWTManager 创建JWT管理器
 func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	return &JWTManager{
@@ -34,7 +56,8 @@ func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 }
 
 // Generate 生成JWT令牌
-func (manager *JWTManager) Generate(userID int64, username, email string, roles, permissions []string, sessionID, tokenType string) (string, error) {
+// roleExpiry为限时角色的截止时间（Unix秒），长期有效的角色无需包含
+func (manager *JWTManager) Generate(userID int64, username, email string, roles []string, roleExpiry map[string]int64, permissions []string, sessionID, tokenType string, companyID int32) (string, error) {
 	claims := CustomClaims{
 		UserID:      userID,
 		Username:    username,
@@ -43,6 +66,8 @@ func (manager *JWTManager) Generate(userID int64, username, email string, roles,
 		Permissions: permissions,
 		SessionID:   sessionID,
 		TokenType:   tokenType,
+		CompanyID:   companyID,
+		RoleExpiry:  roleExpiry,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(manager.tokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -84,12 +109,13 @@ func (manager *JWTManager) Verify(tokenString string) (*CustomClaims, error) {
 }
 
 // GenerateRefreshToken 生成刷新令牌
-func (manager *JWTManager) GenerateRefreshToken(userID int64, username, sessionID string) (string, error) {
+func (manager *JWTManager) GenerateRefreshToken(userID int64, username, sessionID string, companyID int32) (string, error) {
 	refreshClaims := CustomClaims{
 		UserID:    userID,
 		Username:  username,
 		SessionID: sessionID,
 		TokenType: "refresh",
+		CompanyID: companyID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 30)), // 30 days
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"erp-system/internal/biz"
 	"erp-system/internal/conf"
 	"erp-system/internal/middleware"
 	"erp-system/internal/pkg"
@@ -29,6 +30,23 @@ type HTTPServer struct {
 	permissionService *service.PermissionService
 	organizationService *service.OrganizationService
 	systemService       *service.SystemService
+	permissionTemplateService *service.PermissionTemplateService
+	roleAssignmentService     *service.RoleAssignmentService
+	sodService                *service.SoDService
+	permissionMatrixService   *service.PermissionMatrixService
+	permissionVersionService  *service.PermissionVersionService
+	docFieldService           *service.DocFieldService
+	documentService           *service.DocumentService
+	namingSeriesService       *service.NamingSeriesService
+	workflowService           *service.WorkflowService
+	approvalService           *service.ApprovalService
+	companyService            *service.CompanyService
+	userImportService         *service.UserImportService
+	userExportService         *service.UserExportService
+	recycleBinService         *service.RecycleBinService
+	attachmentService         *service.AttachmentService
+	profileService            *service.ProfileService
+	systemConfigService       *service.SystemConfigService
 	jwtSecret           string
 	log                 *log.Helper
 }
@@ -43,6 +61,23 @@ func NewHTTPServer(
 	permissionService *service.PermissionService,
 	organizationService *service.OrganizationService,
 	systemService *service.SystemService,
+	permissionTemplateService *service.PermissionTemplateService,
+	roleAssignmentService *service.RoleAssignmentService,
+	sodService *service.SoDService,
+	permissionMatrixService *service.PermissionMatrixService,
+	permissionVersionService *service.PermissionVersionService,
+	docFieldService *service.DocFieldService,
+	documentService *service.DocumentService,
+	namingSeriesService *service.NamingSeriesService,
+	workflowService *service.WorkflowService,
+	approvalService *service.ApprovalService,
+	companyService *service.CompanyService,
+	userImportService *service.UserImportService,
+	userExportService *service.UserExportService,
+	recycleBinService *service.RecycleBinService,
+	attachmentService *service.AttachmentService,
+	profileService *service.ProfileService,
+	systemConfigService *service.SystemConfigService,
 	logger log.Logger,
 ) *HTTPServer {
 	var opts = []khttp.ServerOption{}
@@ -74,6 +109,23 @@ func NewHTTPServer(
 		permissionService:   permissionService,
 		organizationService: organizationService,
 		systemService:       systemService,
+		permissionTemplateService: permissionTemplateService,
+		roleAssignmentService:     roleAssignmentService,
+		sodService:                sodService,
+		permissionMatrixService:   permissionMatrixService,
+		permissionVersionService:  permissionVersionService,
+		docFieldService:           docFieldService,
+		documentService:           documentService,
+		namingSeriesService:       namingSeriesService,
+		workflowService:           workflowService,
+		approvalService:           approvalService,
+		companyService:            companyService,
+		userImportService:         userImportService,
+		userExportService:         userExportService,
+		recycleBinService:         recycleBinService,
+		attachmentService:         attachmentService,
+		profileService:            profileService,
+		systemConfigService:       systemConfigService,
 		jwtSecret:           jwtSecret,
 		log:                 log.NewHelper(logger),
 	}
@@ -98,26 +150,51 @@ func (s *HTTPServer) registerRoutes() {
 	auth.HandleFunc("/register", s.handleRegister).Methods("POST", "OPTIONS")
 	auth.HandleFunc("/refresh", s.handleRefreshToken).Methods("POST", "OPTIONS")
 
+	// 附件下载（通过签名链接鉴权，无需登录）
+	v1.HandleFunc("/attachments/{id:[0-9]+}/download", s.handleDownloadAttachment).Methods("GET", "OPTIONS")
+
 	// 需要认证的路由
 	authenticated := v1.NewRoute().Subrouter()
 	// 这里应该使用标准HTTP中间件，而不是Kratos中间件
 	authenticated.Use(s.jwtMiddleware)
+	authenticated.Use(s.permissionChangesetMiddleware)
 
 	// 认证相关路由
 	authenticated.HandleFunc("/auth/logout", s.handleLogout).Methods("POST", "OPTIONS")
 	authenticated.HandleFunc("/auth/profile", s.handleGetProfile).Methods("GET", "OPTIONS")
+	authenticated.HandleFunc("/auth/profile", s.handleUpdateProfile).Methods("PUT", "OPTIONS")
+	authenticated.HandleFunc("/auth/profile/change-requests", s.handleListMyProfileChangeRequests).Methods("GET", "OPTIONS")
+	authenticated.HandleFunc("/auth/profile/change-requests/{id:[0-9]+}/cancel", s.handleCancelProfileChangeRequest).Methods("POST", "OPTIONS")
 	authenticated.HandleFunc("/auth/change-password", s.handleChangePassword).Methods("POST", "OPTIONS")
+	authenticated.HandleFunc("/auth/switch-company", s.handleSwitchCompany).Methods("POST", "OPTIONS")
 
 	// 用户管理路由
 	users := authenticated.PathPrefix("/users").Subrouter()
 	users.HandleFunc("", s.handleListUsers).Methods("GET", "OPTIONS")
 	users.HandleFunc("", s.handleCreateUser).Methods("POST", "OPTIONS")
+	users.HandleFunc("/import", s.handleImportUsers).Methods("POST", "OPTIONS")
+	users.HandleFunc("/export", s.handleExportUsers).Methods("GET", "POST", "OPTIONS")
+	users.HandleFunc("/batch-delete", s.handleBatchDeleteUsers).Methods("POST", "OPTIONS")
+	users.HandleFunc("/stats", s.handleGetUserStats).Methods("GET", "OPTIONS")
 	users.HandleFunc("/{id:[0-9]+}", s.handleGetUser).Methods("GET", "OPTIONS")
 	users.HandleFunc("/{id:[0-9]+}", s.handleUpdateUser).Methods("PUT", "OPTIONS")
 	users.HandleFunc("/{id:[0-9]+}", s.handleDeleteUser).Methods("DELETE", "OPTIONS")
 	users.HandleFunc("/{id:[0-9]+}/roles", s.handleAssignUserRoles).Methods("POST", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/roles/{roleId:[0-9]+}", s.handleRemoveUserRole).Methods("DELETE", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/permissions", s.handleGetUserPermissions).Methods("GET", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/toggle-status", s.handleToggleUserStatus).Methods("POST", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/role-assignments", s.handleListUserRoleAssignments).Methods("GET", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/role-assignments", s.handleCreateUserRoleAssignment).Methods("POST", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/role-assignments/{assignmentId:[0-9]+}", s.handleRevokeUserRoleAssignment).Methods("DELETE", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/organizations", s.handleListUserOrganizations).Methods("GET", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/organizations", s.handleAssignUserOrganization).Methods("POST", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/organization-history", s.handleListUserOrganizationHistory).Methods("GET", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/companies", s.handleListUserCompanies).Methods("GET", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/companies/{companyId:[0-9]+}/default", s.handleSetUserDefaultCompany).Methods("PUT", "OPTIONS")
 	users.HandleFunc("/{id:[0-9]+}/reset-password", s.handleResetUserPassword).Methods("POST", "OPTIONS")
 	users.HandleFunc("/{id:[0-9]+}/toggle-2fa", s.handleToggleUser2FA).Methods("POST", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/avatar", s.handleGetUserAvatar).Methods("GET", "OPTIONS")
+	users.HandleFunc("/{id:[0-9]+}/avatar", s.handleUploadUserAvatar).Methods("POST", "OPTIONS")
 
 	// 角色管理路由
 	roles := authenticated.PathPrefix("/roles").Subrouter()
@@ -142,6 +219,22 @@ func (s *HTTPServer) registerRoutes() {
 	// permissions.HandleFunc("/menus", s.handleGetUserMenus).Methods("GET", "OPTIONS")
 	// permissions.HandleFunc("/check", s.handleCheckUserPermission).Methods("POST", "OPTIONS")
 
+	// 角色委托路由
+	delegations := authenticated.PathPrefix("/role-delegations").Subrouter()
+	delegations.HandleFunc("", s.handleListMyRoleDelegations).Methods("GET", "OPTIONS")
+	delegations.HandleFunc("", s.handleDelegateRole).Methods("POST", "OPTIONS")
+	delegations.HandleFunc("/{assignmentId:[0-9]+}", s.handleRevokeRoleDelegation).Methods("DELETE", "OPTIONS")
+
+	// 公司管理路由
+	companies := authenticated.PathPrefix("/companies").Subrouter()
+	companies.HandleFunc("", s.handleListCompanies).Methods("GET", "OPTIONS")
+	companies.HandleFunc("", s.handleCreateCompany).Methods("POST", "OPTIONS")
+	companies.HandleFunc("/{id:[0-9]+}", s.handleGetCompany).Methods("GET", "OPTIONS")
+	companies.HandleFunc("/{id:[0-9]+}", s.handleUpdateCompany).Methods("PUT", "OPTIONS")
+	companies.HandleFunc("/{id:[0-9]+}/users", s.handleListCompanyUsers).Methods("GET", "OPTIONS")
+	companies.HandleFunc("/{id:[0-9]+}/users", s.handleAddCompanyUser).Methods("POST", "OPTIONS")
+	companies.HandleFunc("/{id:[0-9]+}/users/{userId:[0-9]+}", s.handleRemoveCompanyUser).Methods("DELETE", "OPTIONS")
+
 	// 组织管理路由
 	orgs := authenticated.PathPrefix("/organizations").Subrouter()
 	orgs.HandleFunc("", s.handleCreateOrganization).Methods("POST", "OPTIONS")
@@ -150,7 +243,30 @@ func (s *HTTPServer) registerRoutes() {
 	orgs.HandleFunc("/{id:[0-9]+}", s.handleDeleteOrganization).Methods("DELETE", "OPTIONS")
 	orgs.HandleFunc("/tree", s.handleGetOrganizationTree).Methods("GET", "OPTIONS")
 	orgs.HandleFunc("/enabled", s.handleGetEnabledOrganizations).Methods("GET", "OPTIONS")
+	orgs.HandleFunc("/search", s.handleSearchOrganizations).Methods("GET", "OPTIONS")
+	orgs.HandleFunc("/options", s.handleGetOrganizationOptions).Methods("GET", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/stats", s.handleGetOrganizationStats).Methods("GET", "OPTIONS")
 	orgs.HandleFunc("/{id:[0-9]+}/users", s.handleAssignOrganizationUsers).Methods("POST", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/leader", s.handleSetOrganizationLeader).Methods("POST", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/move", s.handleMoveOrganization).Methods("POST", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/path", s.handleGetOrganizationPath).Methods("GET", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/children", s.handleGetChildOrganizations).Methods("GET", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/members", s.handleListOrganizationMembers).Methods("GET", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/members", s.handleAddOrganizationMember).Methods("POST", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/members/history", s.handleListOrganizationMemberHistory).Methods("GET", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/members/{userId:[0-9]+}", s.handleUpdateOrganizationMember).Methods("PUT", "OPTIONS")
+	orgs.HandleFunc("/{id:[0-9]+}/members/{userId:[0-9]+}", s.handleRemoveOrganizationMember).Methods("DELETE", "OPTIONS")
+
+	// 个人资料变更审批路由
+	profileChanges := authenticated.PathPrefix("/profile-change-requests").Subrouter()
+	profileChanges.HandleFunc("", s.handleListProfileChangeRequests).Methods("GET", "OPTIONS")
+	profileChanges.HandleFunc("/{id:[0-9]+}/approve", s.handleApproveProfileChangeRequest).Methods("POST", "OPTIONS")
+	profileChanges.HandleFunc("/{id:[0-9]+}/reject", s.handleRejectProfileChangeRequest).Methods("POST", "OPTIONS")
+
+	// 回收站路由
+	recycleBin := authenticated.PathPrefix("/recycle-bin").Subrouter()
+	recycleBin.HandleFunc("", s.handleListDeletedItems).Methods("GET", "OPTIONS")
+	recycleBin.HandleFunc("/{type}/{id:[0-9]+}/restore", s.handleRestoreDeletedItem).Methods("POST", "OPTIONS")
 
 	// 系统管理路由
 	system := authenticated.PathPrefix("/system").Subrouter()
@@ -161,6 +277,10 @@ func (s *HTTPServer) registerRoutes() {
 	system.HandleFunc("/cleanup-logs", s.handleCleanupLogs).Methods("POST", "OPTIONS")
 	system.HandleFunc("/info", s.handleGetSystemInfo).Methods("GET", "OPTIONS")
 	system.HandleFunc("/dashboard", s.handleGetDashboardData).Methods("GET", "OPTIONS")
+	system.HandleFunc("/configs", s.handleListSystemConfigs).Methods("GET", "OPTIONS")
+	system.HandleFunc("/configs/{key}", s.handleGetSystemConfig).Methods("GET", "OPTIONS")
+	system.HandleFunc("/configs/{key}", s.handleSetSystemConfig).Methods("PUT", "OPTIONS")
+	system.HandleFunc("/configs/{key}", s.handleResetSystemConfig).Methods("DELETE", "OPTIONS")
 
 	// DocType管理路由（标准系统）
 	docTypes := authenticated.PathPrefix("/doctypes").Subrouter()
@@ -170,6 +290,57 @@ func (s *HTTPServer) registerRoutes() {
 	docTypes.HandleFunc("/{id:[0-9]+}", s.handleGetDocTypeManagement).Methods("GET", "OPTIONS")
 	docTypes.HandleFunc("/{id:[0-9]+}", s.handleUpdateDocTypeManagement).Methods("PUT", "OPTIONS")
 	docTypes.HandleFunc("/{id:[0-9]+}", s.handleDeleteDocTypeManagement).Methods("DELETE", "OPTIONS")
+	docTypes.HandleFunc("/{name}/fields", s.handleListDocFields).Methods("GET", "OPTIONS")
+	docTypes.HandleFunc("/{name}/fields", s.handleCreateDocField).Methods("POST", "OPTIONS")
+	docTypes.HandleFunc("/{name}/fields/{field}", s.handleGetDocField).Methods("GET", "OPTIONS")
+	docTypes.HandleFunc("/{name}/fields/{field}", s.handleUpdateDocField).Methods("PUT", "OPTIONS")
+	docTypes.HandleFunc("/{name}/fields/{field}", s.handleDeleteDocField).Methods("DELETE", "OPTIONS")
+	docTypes.HandleFunc("/{name}/sync-field-levels", s.handleSyncFieldPermissionLevels).Methods("POST", "OPTIONS")
+	docTypes.HandleFunc("/{name}/sync-table", s.handleSyncDocumentTable).Methods("POST", "OPTIONS")
+	docTypes.HandleFunc("/{name}/workflow", s.handleGetWorkflow).Methods("GET", "OPTIONS")
+	docTypes.HandleFunc("/{name}/workflow", s.handleSaveWorkflow).Methods("PUT", "OPTIONS")
+	docTypes.HandleFunc("/{name}/workflow", s.handleDeleteWorkflow).Methods("DELETE", "OPTIONS")
+	docTypes.HandleFunc("/{name}/approval-chain", s.handleGetApprovalChain).Methods("GET", "OPTIONS")
+	docTypes.HandleFunc("/{name}/approval-chain", s.handleSaveApprovalChain).Methods("PUT", "OPTIONS")
+	docTypes.HandleFunc("/{name}/approval-chain", s.handleDeleteApprovalChain).Methods("DELETE", "OPTIONS")
+
+	// 工作流路由
+	authenticated.HandleFunc("/workflows", s.handleListWorkflows).Methods("GET", "OPTIONS")
+
+	// 审批路由
+	authenticated.HandleFunc("/approval-chains", s.handleListApprovalChains).Methods("GET", "OPTIONS")
+	authenticated.HandleFunc("/approval-tasks", s.handleListApprovalTasks).Methods("GET", "OPTIONS")
+	authenticated.HandleFunc("/approval-tasks/{id:[0-9]+}/approve", s.handleApproveApprovalTask).Methods("POST", "OPTIONS")
+	authenticated.HandleFunc("/approval-tasks/{id:[0-9]+}/reject", s.handleRejectApprovalTask).Methods("POST", "OPTIONS")
+	authenticated.HandleFunc("/approval-tasks/{id:[0-9]+}/delegate", s.handleDelegateApprovalTask).Methods("POST", "OPTIONS")
+	authenticated.HandleFunc("/approval-requests/{id:[0-9]+}", s.handleGetApprovalRequest).Methods("GET", "OPTIONS")
+	authenticated.HandleFunc("/approval-delegations", s.handleListApprovalDelegations).Methods("GET", "OPTIONS")
+	authenticated.HandleFunc("/approval-delegations", s.handleCreateApprovalDelegation).Methods("POST", "OPTIONS")
+	authenticated.HandleFunc("/approval-delegations/{id:[0-9]+}", s.handleRevokeApprovalDelegation).Methods("DELETE", "OPTIONS")
+
+	// 通用文档路由
+	resources := authenticated.PathPrefix("/resource").Subrouter()
+	resources.HandleFunc("/{doctype}", s.handleListDocuments).Methods("GET", "OPTIONS")
+	resources.HandleFunc("/{doctype}", s.handleCreateDocument).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}", s.handleGetDocument).Methods("GET", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}", s.handleUpdateDocument).Methods("PUT", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}", s.handleDeleteDocument).Methods("DELETE", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/submit", s.handleSubmitDocument).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/cancel", s.handleCancelDocument).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/amend", s.handleAmendDocument).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/transitions", s.handleListTransitions).Methods("GET", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/transitions", s.handleApplyTransition).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/approvals", s.handleListApprovals).Methods("GET", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/approvals", s.handleRequestApproval).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/approvals/cancel", s.handleCancelApproval).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/attachments", s.handleListAttachments).Methods("GET", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/attachments", s.handleUploadAttachment).Methods("POST", "OPTIONS")
+	resources.HandleFunc("/{doctype}/{name}/attachments/{attachmentId:[0-9]+}", s.handleDeleteAttachment).Methods("DELETE", "OPTIONS")
+
+	// 编号序列路由
+	namingSeries := authenticated.PathPrefix("/naming-series").Subrouter()
+	namingSeries.HandleFunc("", s.handleListNamingSeries).Methods("GET", "OPTIONS")
+	namingSeries.HandleFunc("", s.handleSetNamingSeries).Methods("PUT", "OPTIONS")
 
 	// ERP文档权限系统路由
 	erpPermissions := authenticated.PathPrefix("/erp-permissions").Subrouter()
@@ -183,6 +354,28 @@ func (s *HTTPServer) registerRoutes() {
 	erpPermissions.HandleFunc("/permission-rules/{id:[0-9]+}", s.handleGetPermissionRule).Methods("GET", "OPTIONS")
 	erpPermissions.HandleFunc("/permission-rules/{id:[0-9]+}", s.handleUpdatePermissionRule).Methods("PUT", "OPTIONS")
 	erpPermissions.HandleFunc("/permission-rules/{id:[0-9]+}", s.handleDeletePermissionRule).Methods("DELETE", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates", s.handleListPermissionTemplates).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates", s.handleCreatePermissionTemplate).Methods("POST", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates/{id:[0-9]+}", s.handleGetPermissionTemplate).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates/{id:[0-9]+}", s.handleUpdatePermissionTemplate).Methods("PUT", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates/{id:[0-9]+}", s.handleDeletePermissionTemplate).Methods("DELETE", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates/{id:[0-9]+}/preview", s.handlePreviewPermissionTemplate).Methods("POST", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates/{id:[0-9]+}/apply", s.handleApplyPermissionTemplate).Methods("POST", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-templates/{id:[0-9]+}/reapply", s.handleReapplyPermissionTemplate).Methods("POST", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-matrix/export", s.handleExportPermissionMatrix).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-matrix/import", s.handleImportPermissionMatrix).Methods("POST", "OPTIONS")
+	erpPermissions.HandleFunc("/sod-policies", s.handleListSoDPolicies).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/sod-policies", s.handleCreateSoDPolicy).Methods("POST", "OPTIONS")
+	erpPermissions.HandleFunc("/sod-policies/{id:[0-9]+}", s.handleGetSoDPolicy).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/sod-policies/{id:[0-9]+}", s.handleUpdateSoDPolicy).Methods("PUT", "OPTIONS")
+	erpPermissions.HandleFunc("/sod-policies/{id:[0-9]+}", s.handleDeleteSoDPolicy).Methods("DELETE", "OPTIONS")
+	erpPermissions.HandleFunc("/sod-violations", s.handleListSoDViolations).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-versions", s.handleListPermissionVersions).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-versions/diff", s.handleDiffPermissionVersions).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-versions/{id:[0-9]+}", s.handleGetPermissionVersion).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-versions/{id:[0-9]+}/rollback", s.handleRollbackPermissionVersion).Methods("POST", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-scope", s.handleGetPermissionScope).Methods("GET", "OPTIONS")
+	erpPermissions.HandleFunc("/permission-check/document", s.handleCheckDocumentPermission).Methods("POST", "OPTIONS")
 
 	// 健康检查
 	router.HandleFunc("/health", s.handleHealth).Methods("GET")
@@ -485,11 +678,13 @@ func (s *HTTPServer) sendError(w http.ResponseWriter, err error) {
 	var status int
 	var code string
 	var message string
+	var details string
 
 	if e := errors.FromError(err); e != nil {
 		status = int(e.Code)
 		code = e.Reason
 		message = e.Message
+		details = e.Metadata["details"]
 	} else {
 		status = http.StatusInternalServerError
 		code = "INTERNAL_ERROR"
@@ -515,6 +710,7 @@ func (s *HTTPServer) sendError(w http.ResponseWriter, err error) {
 		Error: &ErrorInfo{
 			Code:    code,
 			Message: message,
+			Details: details,
 		},
 	}
 
@@ -599,6 +795,12 @@ func (s *HTTPServer) jwtMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// 未携带公司的令牌（迁移前签发）需刷新后使用
+		if claims.CompanyID <= 0 {
+			s.sendError(w, errors.Unauthorized("UNAUTHORIZED", "token has no company"))
+			return
+		}
+
 		// 检查token是否过期
 		if time.Now().After(claims.ExpiresAt.Time) {
 			s.sendError(w, errors.Unauthorized("UNAUTHORIZED", "token expired"))
@@ -610,8 +812,11 @@ func (s *HTTPServer) jwtMiddleware(next http.Handler) http.Handler {
 		ctx = middleware.SetUserIDToContext(ctx, claims.UserID)
 		ctx = middleware.SetUsernameToContext(ctx, claims.Username)
 		ctx = middleware.SetUserEmailToContext(ctx, claims.Email)
-		ctx = middleware.SetUserRolesToContext(ctx, claims.Roles)
+		// 令牌签发后到期的限时角色不再生效
+		ctx = middleware.SetUserRolesToContext(ctx, claims.ActiveRoles(time.Now()))
 		ctx = middleware.SetSessionIDToContext(ctx, claims.SessionID)
+		// 仓储按令牌中的当前公司隔离数据
+		ctx = biz.WithCompany(ctx, claims.CompanyID)
 
 		// 继续执行
 		next.ServeHTTP(w, r.WithContext(ctx))
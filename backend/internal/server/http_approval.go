@@ -0,0 +1,216 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// ========== 审批处理器 ==========
+
+// handleListApprovalChains 获取全部审批链
+func (s *HTTPServer) handleListApprovalChains(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.approvalService.ListApprovalChains(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetApprovalChain 获取文档类型的审批链
+func (s *HTTPServer) handleGetApprovalChain(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.approvalService.GetApprovalChain(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleSaveApprovalChain 创建或替换文档类型的审批链
+func (s *HTTPServer) handleSaveApprovalChain(w http.ResponseWriter, r *http.Request) {
+	var req service.SaveApprovalChainRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.approvalService.SaveApprovalChain(r.Context(), mux.Vars(r)["name"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDeleteApprovalChain 删除文档类型的审批链
+func (s *HTTPServer) handleDeleteApprovalChain(w http.ResponseWriter, r *http.Request) {
+	if err := s.approvalService.DeleteApprovalChain(r.Context(), mux.Vars(r)["name"]); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "审批链删除成功"})
+}
+
+// handleListApprovalTasks 获取当前用户的审批任务，可按status过滤
+func (s *HTTPServer) handleListApprovalTasks(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.approvalService.ListMyTasks(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleApproveApprovalTask 同意审批任务，请求体可省略
+func (s *HTTPServer) handleApproveApprovalTask(w http.ResponseWriter, r *http.Request) {
+	id, req, err := s.parseApprovalDecision(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.approvalService.ApproveTask(r.Context(), id, req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleRejectApprovalTask 驳回审批任务，请求体可省略
+func (s *HTTPServer) handleRejectApprovalTask(w http.ResponseWriter, r *http.Request) {
+	id, req, err := s.parseApprovalDecision(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.approvalService.RejectTask(r.Context(), id, req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDelegateApprovalTask 将审批任务转交给其他用户
+func (s *HTTPServer) handleDelegateApprovalTask(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseApprovalID(r, "审批任务ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.DelegateApprovalTaskRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.approvalService.DelegateTask(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetApprovalRequest 获取审批请求及其任务
+func (s *HTTPServer) handleGetApprovalRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseApprovalID(r, "审批请求ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.approvalService.GetApprovalRequest(r.Context(), id)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleListApprovalDelegations 获取当前用户的审批委托
+func (s *HTTPServer) handleListApprovalDelegations(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.approvalService.ListMyDelegations(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCreateApprovalDelegation 创建审批委托
+func (s *HTTPServer) handleCreateApprovalDelegation(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateApprovalDelegationRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.approvalService.CreateDelegation(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleRevokeApprovalDelegation 撤销审批委托
+func (s *HTTPServer) handleRevokeApprovalDelegation(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseApprovalID(r, "审批委托ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.approvalService.RevokeDelegation(r.Context(), id); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "审批委托撤销成功"})
+}
+
+// parseApprovalDecision 从请求中获取审批任务ID和可省略的审批意见
+func (s *HTTPServer) parseApprovalDecision(r *http.Request) (int64, *service.DecideApprovalTaskRequest, error) {
+	id, err := s.parseApprovalID(r, "审批任务ID无效")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var req service.DecideApprovalTaskRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			return 0, nil, err
+		}
+	}
+	return id, &req, nil
+}
+
+// parseApprovalID 从URL路径中获取审批任务、请求或委托的ID
+func (s *HTTPServer) parseApprovalID(r *http.Request, message string) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, errors.BadRequest("INVALID_PARAMETER", message)
+	}
+	return id, nil
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// attachmentFormField 上传文件使用的multipart字段名
+const attachmentFormField = "file"
+
+// ========== 附件处理器 ==========
+
+// handleUploadAttachment 上传文档附件，请求体为multipart/form-data，文件字段名为file
+func (s *HTTPServer) handleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	part, err := s.openUploadPart(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+	defer part.Close()
+
+	vars := mux.Vars(r)
+	resp, err := s.documentService.UploadAttachment(r.Context(), vars["doctype"], vars["name"], part.FileName(), part)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleListAttachments 获取文档附件，返回的下载链接在有效期内无需登录即可访问
+func (s *HTTPServer) handleListAttachments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resp, err := s.documentService.ListAttachments(r.Context(), vars["doctype"], vars["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDeleteAttachment 删除文档附件
+func (s *HTTPServer) handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAttachmentID(r, "attachmentId")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := s.documentService.DeleteAttachment(r.Context(), vars["doctype"], vars["name"], id); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{
+		"message": "删除成功",
+	})
+}
+
+// handleUploadUserAvatar 上传用户头像，请求体为multipart/form-data，文件字段名为file
+func (s *HTTPServer) handleUploadUserAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	part, err := s.openUploadPart(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+	defer part.Close()
+
+	resp, err := s.attachmentService.UploadAvatar(r.Context(), userID, part.FileName(), part)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetUserAvatar 重定向到用户头像的签名下载链接，size=thumbnail时返回缩略图
+func (s *HTTPServer) handleGetUserAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	url, err := s.attachmentService.GetAvatarURL(r.Context(), userID, r.URL.Query().Get("size"))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// handleDownloadAttachment 通过签名链接下载附件，链接由有权限的用户获取，下载本身无需登录
+func (s *HTTPServer) handleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAttachmentID(r, "id")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		s.sendError(w, errors.Forbidden("INVALID_SIGNATURE", "下载链接无效"))
+		return
+	}
+
+	attachment, file, err := s.attachmentService.OpenDownload(r.Context(), id, query.Get("variant"), expires, query.Get("signature"))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+	defer file.Close()
+
+	// 只有图片内联展示，其余类型一律作为下载，避免上传的HTML等内容在站点域下执行
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		s.log.Warnf("failed to send attachment %d: %v", id, err)
+	}
+}
+
+// openUploadPart 从multipart请求中找到文件字段，文件内容按流读取，大小上限由业务层检查
+func (s *HTTPServer) openUploadPart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.BadRequest("INVALID_REQUEST", "请求必须为multipart/form-data格式")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.BadRequest("INVALID_REQUEST", "缺少上传文件")
+		}
+		if err != nil {
+			return nil, errors.BadRequest("INVALID_REQUEST", "读取上传文件失败")
+		}
+		if part.FormName() == attachmentFormField {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// parseAttachmentID 解析路径中的附件ID
+func parseAttachmentID(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+	if err != nil {
+		return 0, errors.BadRequest("INVALID_PARAMETER", "附件ID无效")
+	}
+	return id, nil
+}
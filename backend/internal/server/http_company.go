@@ -0,0 +1,193 @@
+package server
+
+import (
+	"net/http"
+
+	"erp-system/internal/service"
+)
+
+// ========== 公司处理器 ==========
+
+// handleListCompanies 获取全部公司
+func (s *HTTPServer) handleListCompanies(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.companyService.ListCompanies(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCreateCompany 创建公司
+func (s *HTTPServer) handleCreateCompany(w http.ResponseWriter, r *http.Request) {
+	var req service.CompanyRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.companyService.CreateCompany(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleGetCompany 获取公司详情
+func (s *HTTPServer) handleGetCompany(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseMemberPathID(r, "id", "公司ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.companyService.GetCompany(r.Context(), id)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleUpdateCompany 更新公司
+func (s *HTTPServer) handleUpdateCompany(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseMemberPathID(r, "id", "公司ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.CompanyRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.companyService.UpdateCompany(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleListCompanyUsers 获取公司的用户
+func (s *HTTPServer) handleListCompanyUsers(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseMemberPathID(r, "id", "公司ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.companyService.ListCompanyUsers(r.Context(), id)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleAddCompanyUser 将用户加入公司
+func (s *HTTPServer) handleAddCompanyUser(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseMemberPathID(r, "id", "公司ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.AddCompanyUserRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.companyService.AddCompanyUser(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleRemoveCompanyUser 将用户移出公司
+func (s *HTTPServer) handleRemoveCompanyUser(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseMemberPathID(r, "id", "公司ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+	userID, err := s.parseMemberPathID(r, "userId", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.companyService.RemoveCompanyUser(r.Context(), id, userID); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "用户已移出公司"})
+}
+
+// handleListUserCompanies 获取用户所属的公司
+func (s *HTTPServer) handleListUserCompanies(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.companyService.ListUserCompanies(r.Context(), userID)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleSetUserDefaultCompany 设置用户登录时进入的默认公司
+func (s *HTTPServer) handleSetUserDefaultCompany(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+	companyID, err := s.parseMemberPathID(r, "companyId", "公司ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.companyService.SetDefaultCompany(r.Context(), userID, companyID); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "默认公司设置成功"})
+}
+
+// handleSwitchCompany 切换当前公司，返回绑定新公司的令牌
+func (s *HTTPServer) handleSwitchCompany(w http.ResponseWriter, r *http.Request) {
+	var req service.SwitchCompanyRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.authService.SwitchCompany(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+
+	"erp-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ========== 字段定义处理器 ==========
+
+// handleListDocFields 获取文档类型的字段定义
+func (s *HTTPServer) handleListDocFields(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.docFieldService.ListFields(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCreateDocField 创建字段定义
+func (s *HTTPServer) handleCreateDocField(w http.ResponseWriter, r *http.Request) {
+	var req service.DocFieldRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.docFieldService.CreateField(r.Context(), mux.Vars(r)["name"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleGetDocField 获取字段定义
+func (s *HTTPServer) handleGetDocField(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resp, err := s.docFieldService.GetField(r.Context(), vars["name"], vars["field"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleUpdateDocField 更新字段定义
+func (s *HTTPServer) handleUpdateDocField(w http.ResponseWriter, r *http.Request) {
+	var req service.DocFieldRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	resp, err := s.docFieldService.UpdateField(r.Context(), vars["name"], vars["field"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDeleteDocField 删除字段定义
+func (s *HTTPServer) handleDeleteDocField(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := s.docFieldService.DeleteField(r.Context(), vars["name"], vars["field"]); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "字段定义删除成功"})
+}
+
+// handleSyncFieldPermissionLevels 按字段定义重新同步字段权限级别
+func (s *HTTPServer) handleSyncFieldPermissionLevels(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.docFieldService.SyncFieldPermissionLevels(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// ========== 通用文档处理器 ==========
+
+// handleListDocuments 分页获取文档，filters为字段等值过滤的JSON对象
+func (s *HTTPServer) handleListDocuments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &service.ListDocumentsRequest{
+		Search:  query.Get("search"),
+		OrderBy: query.Get("order_by"),
+		Order:   query.Get("order"),
+	}
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 0 {
+		req.Page = int32(page)
+	}
+	if size, err := strconv.Atoi(query.Get("size")); err == nil && size > 0 {
+		req.Size = int32(size)
+	}
+	if filters := query.Get("filters"); filters != "" {
+		if err := json.Unmarshal([]byte(filters), &req.Filters); err != nil {
+			s.sendError(w, errors.BadRequest("INVALID_PARAMETER", "filters必须是JSON对象"))
+			return
+		}
+	}
+
+	resp, err := s.documentService.ListDocuments(r.Context(), mux.Vars(r)["doctype"], req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCreateDocument 创建文档
+func (s *HTTPServer) handleCreateDocument(w http.ResponseWriter, r *http.Request) {
+	var values map[string]interface{}
+	if err := s.parseJSON(r, &values); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.documentService.CreateDocument(r.Context(), mux.Vars(r)["doctype"], values)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleGetDocument 获取文档
+func (s *HTTPServer) handleGetDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resp, err := s.documentService.GetDocument(r.Context(), vars["doctype"], vars["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleUpdateDocument 更新文档
+func (s *HTTPServer) handleUpdateDocument(w http.ResponseWriter, r *http.Request) {
+	var values map[string]interface{}
+	if err := s.parseJSON(r, &values); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	resp, err := s.documentService.UpdateDocument(r.Context(), vars["doctype"], vars["name"], values)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDeleteDocument 删除文档
+func (s *HTTPServer) handleDeleteDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := s.documentService.DeleteDocument(r.Context(), vars["doctype"], vars["name"]); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "文档删除成功"})
+}
+
+// handleSubmitDocument 提交文档
+func (s *HTTPServer) handleSubmitDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resp, err := s.documentService.SubmitDocument(r.Context(), vars["doctype"], vars["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCancelDocument 取消文档，请求体可省略
+func (s *HTTPServer) handleCancelDocument(w http.ResponseWriter, r *http.Request) {
+	var req service.CancelDocumentRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+
+	vars := mux.Vars(r)
+	resp, err := s.documentService.CancelDocument(r.Context(), vars["doctype"], vars["name"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleAmendDocument 修订已取消的文档
+func (s *HTTPServer) handleAmendDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resp, err := s.documentService.AmendDocument(r.Context(), vars["doctype"], vars["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleListTransitions 获取文档可执行的工作流转换
+func (s *HTTPServer) handleListTransitions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resp, err := s.documentService.ListTransitions(r.Context(), vars["doctype"], vars["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleApplyTransition 执行工作流转换
+func (s *HTTPServer) handleApplyTransition(w http.ResponseWriter, r *http.Request) {
+	var req service.ApplyTransitionRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	resp, err := s.documentService.ApplyTransition(r.Context(), vars["doctype"], vars["name"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleSyncDocumentTable 按字段定义迁移文档类型的数据表
+func (s *HTTPServer) handleSyncDocumentTable(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.documentService.SyncDocumentTable(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleListApprovals 获取文档的审批历史
+func (s *HTTPServer) handleListApprovals(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	resp, err := s.documentService.ListApprovals(r.Context(), vars["doctype"], vars["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleRequestApproval 为文档发起审批，请求体可省略
+func (s *HTTPServer) handleRequestApproval(w http.ResponseWriter, r *http.Request) {
+	var req service.ApprovalCommentRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+
+	vars := mux.Vars(r)
+	resp, err := s.documentService.RequestApproval(r.Context(), vars["doctype"], vars["name"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleCancelApproval 撤回文档进行中的审批，请求体可省略
+func (s *HTTPServer) handleCancelApproval(w http.ResponseWriter, r *http.Request) {
+	var req service.ApprovalCommentRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+
+	vars := mux.Vars(r)
+	resp, err := s.documentService.CancelApproval(r.Context(), vars["doctype"], vars["name"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
@@ -140,14 +140,13 @@ func (s *HTTPServer) handleAssignUserRoles(w http.ResponseWriter, r *http.Reques
 	}
 	req.UserID = int32(id)
 
-	if err := s.userService.AssignRoles(r.Context(), &req); err != nil {
+	resp, err := s.userService.AssignRoles(r.Context(), &req)
+	if err != nil {
 		s.sendError(w, err)
 		return
 	}
 
-	s.sendResponse(w, http.StatusOK, map[string]string{
-		"message": "角色分配成功",
-	})
+	s.sendResponse(w, http.StatusOK, resp)
 }
 
 // handleResetUserPassword 重置用户密码
@@ -668,6 +667,94 @@ func (s *HTTPServer) handleGetEnabledOrganizations(w http.ResponseWriter, r *htt
 	s.sendResponse(w, http.StatusOK, resp)
 }
 
+// handleSetOrganizationLeader 设置组织负责人
+func (s *HTTPServer) handleSetOrganizationLeader(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 32)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.SetOrganizationLeaderRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.organizationService.SetOrganizationLeader(r.Context(), int32(id), &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{
+		"message": "组织负责人设置成功",
+	})
+}
+
+// handleMoveOrganization 移动组织到新的上级组织下
+func (s *HTTPServer) handleMoveOrganization(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 32)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.MoveOrganizationRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.organizationService.MoveOrganization(r.Context(), int32(id), &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{
+		"message": "组织移动成功",
+	})
+}
+
+// handleGetOrganizationPath 获取从顶级组织到该组织的路径
+func (s *HTTPServer) handleGetOrganizationPath(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 32)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.organizationService.GetOrganizationPath(r.Context(), int32(id))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetChildOrganizations 获取子组织，recursive=true时返回全部下级组织
+func (s *HTTPServer) handleGetChildOrganizations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 32)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	recursive, _ := strconv.ParseBool(r.URL.Query().Get("recursive"))
+	onlyEnabled, _ := strconv.ParseBool(r.URL.Query().Get("only_enabled"))
+	resp, err := s.organizationService.GetChildOrganizations(r.Context(), int32(id), recursive, onlyEnabled)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
 // handleAssignOrganizationUsers 分配组织用户
 func (s *HTTPServer) handleAssignOrganizationUsers(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
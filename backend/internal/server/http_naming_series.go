@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"erp-system/internal/service"
+)
+
+// ========== 编号序列处理器 ==========
+
+// handleListNamingSeries 获取编号序列计数器，可按前缀过滤
+func (s *HTTPServer) handleListNamingSeries(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.namingSeriesService.ListSeries(r.Context(), r.URL.Query().Get("prefix"))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleSetNamingSeries 设置编号序列计数器的当前值
+func (s *HTTPServer) handleSetNamingSeries(w http.ResponseWriter, r *http.Request) {
+	var req service.SetNamingSeriesRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.namingSeriesService.SetSeriesCurrent(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// ========== 组织成员处理器 ==========
+
+// handleListOrganizationMembers 获取组织成员，include_inactive=true时包含已离开的成员
+func (s *HTTPServer) handleListOrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	orgID, err := s.parseMemberPathID(r, "id", "组织ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	includeInactive, _ := strconv.ParseBool(r.URL.Query().Get("include_inactive"))
+	resp, err := s.organizationService.ListOrganizationMembers(r.Context(), orgID, includeInactive)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleAddOrganizationMember 添加组织成员
+func (s *HTTPServer) handleAddOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	orgID, err := s.parseMemberPathID(r, "id", "组织ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.AddOrganizationMemberRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.organizationService.AddOrganizationMember(r.Context(), orgID, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleUpdateOrganizationMember 调整组织成员的职位、主组织或负责人身份
+func (s *HTTPServer) handleUpdateOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	orgID, userID, err := s.parseMemberIDs(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.UpdateOrganizationMemberRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.organizationService.UpdateOrganizationMember(r.Context(), orgID, userID, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleRemoveOrganizationMember 移除组织成员，请求体可省略
+func (s *HTTPServer) handleRemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	orgID, userID, err := s.parseMemberIDs(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.RemoveOrganizationMemberRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+
+	if err := s.organizationService.RemoveOrganizationMember(r.Context(), orgID, userID, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "组织成员移除成功"})
+}
+
+// handleListOrganizationMemberHistory 获取组织的成员变动记录
+func (s *HTTPServer) handleListOrganizationMemberHistory(w http.ResponseWriter, r *http.Request) {
+	orgID, err := s.parseMemberPathID(r, "id", "组织ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.organizationService.ListOrganizationMemberHistory(r.Context(), orgID, parseHistoryLimit(r))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleListUserOrganizations 获取用户所属的组织，include_inactive=true时包含已离开的组织
+func (s *HTTPServer) handleListUserOrganizations(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	includeInactive, _ := strconv.ParseBool(r.URL.Query().Get("include_inactive"))
+	resp, err := s.organizationService.ListUserOrganizations(r.Context(), userID, includeInactive)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleListUserOrganizationHistory 获取用户的组织变动记录
+func (s *HTTPServer) handleListUserOrganizationHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.organizationService.ListUserOrganizationHistory(r.Context(), userID, parseHistoryLimit(r))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// parseMemberIDs 从URL路径中获取组织ID和成员用户ID
+func (s *HTTPServer) parseMemberIDs(r *http.Request) (int32, int32, error) {
+	orgID, err := s.parseMemberPathID(r, "id", "组织ID无效")
+	if err != nil {
+		return 0, 0, err
+	}
+	userID, err := s.parseMemberPathID(r, "userId", "用户ID无效")
+	if err != nil {
+		return 0, 0, err
+	}
+	return orgID, userID, nil
+}
+
+// parseMemberPathID 从URL路径中获取组织或用户ID
+func (s *HTTPServer) parseMemberPathID(r *http.Request, name, message string) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)[name], 10, 32)
+	if err != nil {
+		return 0, errors.BadRequest("INVALID_PARAMETER", message)
+	}
+	return int32(id), nil
+}
+
+// parseHistoryLimit 获取变动记录的返回条数，未指定或无效时由业务层使用默认值
+func parseHistoryLimit(r *http.Request) int32 {
+	limit, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(limit)
+}
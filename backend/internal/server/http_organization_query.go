@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ========== 组织查询处理器 ==========
+
+// handleGetOrganizationStats 获取组织人数统计，include_sub_orgs=true时包含全部下级组织
+func (s *HTTPServer) handleGetOrganizationStats(w http.ResponseWriter, r *http.Request) {
+	orgID, err := s.parseMemberPathID(r, "id", "组织ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	includeSubOrgs, _ := strconv.ParseBool(r.URL.Query().Get("include_sub_orgs"))
+	resp, err := s.organizationService.GetOrganizationStats(r.Context(), orgID, includeSubOrgs)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleSearchOrganizations 搜索组织
+func (s *HTTPServer) handleSearchOrganizations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &service.SearchOrganizationsRequest{
+		Keyword: query.Get("keyword"),
+		Type:    query.Get("type"),
+	}
+	req.OnlyEnabled, _ = strconv.ParseBool(query.Get("only_enabled"))
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil {
+			s.sendError(w, errors.BadRequest("INVALID_PARAMETER", "返回数量无效"))
+			return
+		}
+		req.Limit = int32(value)
+	}
+
+	resp, err := s.organizationService.SearchOrganizations(r.Context(), req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetOrganizationOptions 获取组织下拉选项树
+func (s *HTTPServer) handleGetOrganizationOptions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &service.GetOrganizationOptionsRequest{Type: query.Get("type")}
+	req.OnlyEnabled, _ = strconv.ParseBool(query.Get("only_enabled"))
+	if excludeID := query.Get("exclude_id"); excludeID != "" {
+		value, err := strconv.ParseInt(excludeID, 10, 32)
+		if err != nil {
+			s.sendError(w, errors.BadRequest("INVALID_PARAMETER", "排除的组织ID无效"))
+			return
+		}
+		req.ExcludeID = int32(value)
+	}
+
+	resp, err := s.organizationService.GetOrganizationOptions(r.Context(), req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
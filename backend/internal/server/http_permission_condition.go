@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/middleware"
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ========== 条件权限处理器 ==========
+
+// handleGetPermissionScope 获取用户对文档类型某操作的数据范围
+func (s *HTTPServer) handleGetPermissionScope(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &service.GetPermissionScopeRequest{
+		DocType: query.Get("doc_type"),
+		Action:  query.Get("action"),
+	}
+
+	if value := query.Get("user_id"); value != "" {
+		userID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || userID <= 0 {
+			s.sendError(w, errors.BadRequest("INVALID_PARAMETER", "用户ID无效"))
+			return
+		}
+		req.UserID = userID
+	}
+	if value := query.Get("permission_level"); value != "" {
+		level, err := strconv.Atoi(value)
+		if err != nil {
+			s.sendError(w, errors.BadRequest("INVALID_PARAMETER", "权限级别无效"))
+			return
+		}
+		req.PermissionLevel = level
+	}
+
+	resp, err := s.permissionService.GetPermissionScope(r.Context(), req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCheckDocumentPermission 检查用户对文档的权限，请求中带文档属性时按条件表达式判断
+func (s *HTTPServer) handleCheckDocumentPermission(w http.ResponseWriter, r *http.Request) {
+	var req service.CheckDocumentPermissionRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	currentUser := middleware.GetCurrentUser(r.Context())
+	if req.UserID == 0 {
+		req.UserID = currentUser.ID
+	}
+	if req.UserID != currentUser.ID && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		s.sendError(w, errors.Forbidden("PERMISSION_DENIED", "无权限检查其他用户的权限"))
+		return
+	}
+
+	resp, err := s.permissionService.CheckDocumentPermission(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// maxPermissionMatrixSize 导入权限矩阵的请求体上限
+const maxPermissionMatrixSize = 10 << 20
+
+// ========== 权限矩阵导入导出处理器 ==========
+
+// handleExportPermissionMatrix 导出权限矩阵，format=csv|json，默认csv
+func (s *HTTPServer) handleExportPermissionMatrix(w http.ResponseWriter, r *http.Request) {
+	file, err := s.permissionMatrixService.ExportMatrix(r.Context(), permissionMatrixFormat(r))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file.Filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Content)
+}
+
+// handleImportPermissionMatrix 导入权限矩阵，dry_run=true时只返回差异
+func (s *HTTPServer) handleImportPermissionMatrix(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPermissionMatrixSize))
+	if err != nil {
+		s.sendError(w, errors.BadRequest("INVALID_REQUEST", "读取导入数据失败"))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	resp, err := s.permissionMatrixService.ImportMatrix(r.Context(), permissionMatrixFormat(r), content, dryRun)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// permissionMatrixFormat 解析权限矩阵格式，优先使用format参数，其次根据Content-Type判断
+func permissionMatrixFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return service.PermissionMatrixFormatJSON
+	}
+	return service.PermissionMatrixFormatCSV
+}
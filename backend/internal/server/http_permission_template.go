@@ -0,0 +1,171 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// ========== 权限模板处理器 ==========
+
+// handleListPermissionTemplates 获取权限模板列表
+func (s *HTTPServer) handleListPermissionTemplates(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.permissionTemplateService.ListTemplates(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCreatePermissionTemplate 创建权限模板
+func (s *HTTPServer) handleCreatePermissionTemplate(w http.ResponseWriter, r *http.Request) {
+	var req service.PermissionTemplateRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.permissionTemplateService.CreateTemplate(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleGetPermissionTemplate 获取权限模板详情
+func (s *HTTPServer) handleGetPermissionTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseTemplateID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.permissionTemplateService.GetTemplate(r.Context(), id)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleUpdatePermissionTemplate 更新权限模板
+func (s *HTTPServer) handleUpdatePermissionTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseTemplateID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.PermissionTemplateRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.permissionTemplateService.UpdateTemplate(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDeletePermissionTemplate 删除权限模板
+func (s *HTTPServer) handleDeletePermissionTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseTemplateID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.permissionTemplateService.DeleteTemplate(r.Context(), id); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "权限模板删除成功"})
+}
+
+// handlePreviewPermissionTemplate 预览权限模板应用结果
+func (s *HTTPServer) handlePreviewPermissionTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseTemplateID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.ApplyPermissionTemplateRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.permissionTemplateService.PreviewTemplate(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleApplyPermissionTemplate 应用权限模板
+func (s *HTTPServer) handleApplyPermissionTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseTemplateID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.ApplyPermissionTemplateRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.permissionTemplateService.ApplyTemplate(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleReapplyPermissionTemplate 重新应用权限模板，dry_run=true时仅预览
+func (s *HTTPServer) handleReapplyPermissionTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseTemplateID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	resp, err := s.permissionTemplateService.ReapplyTemplate(r.Context(), id, dryRun)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// parseTemplateID 从URL路径中获取权限模板ID
+func (s *HTTPServer) parseTemplateID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, errors.BadRequest("INVALID_PARAMETER", "权限模板ID无效")
+	}
+	return id, nil
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// changeCommentHeader 权限配置变更说明请求头，值需URL编码
+const changeCommentHeader = "X-Change-Comment"
+
+// ========== 权限配置版本处理器 ==========
+
+// permissionChangesetMiddleware 将当前用户和变更说明放入上下文，权限配置变更时记录到版本中
+func (s *HTTPServer) permissionChangesetMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := middleware.GetCurrentUser(r.Context()).ID
+		comment := r.Header.Get(changeCommentHeader)
+		if unescaped, err := url.PathUnescape(comment); err == nil {
+			comment = unescaped
+		}
+
+		ctx := biz.WithPermissionChangeset(r.Context(), &biz.PermissionChangeset{AuthorID: &userID, Comment: comment})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// handleListPermissionVersions 获取权限配置版本列表
+func (s *HTTPServer) handleListPermissionVersions(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.ParseInt(r.URL.Query().Get("page"), 10, 32)
+	size, _ := strconv.ParseInt(r.URL.Query().Get("size"), 10, 32)
+
+	resp, err := s.permissionVersionService.ListVersions(r.Context(), &service.ListPermissionVersionsRequest{
+		Page: int32(page),
+		Size: int32(size),
+	})
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetPermissionVersion 获取权限配置版本及其快照
+func (s *HTTPServer) handleGetPermissionVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parsePermissionVersionID(mux.Vars(r)["id"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.permissionVersionService.GetVersion(r.Context(), id)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDiffPermissionVersions 比较两个权限配置版本，from和to为版本号
+func (s *HTTPServer) handleDiffPermissionVersions(w http.ResponseWriter, r *http.Request) {
+	from, err := s.parsePermissionVersionID(r.URL.Query().Get("from"))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+	to, err := s.parsePermissionVersionID(r.URL.Query().Get("to"))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.permissionVersionService.DiffVersions(r.Context(), from, to)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleRollbackPermissionVersion 将权限配置回滚到指定版本
+func (s *HTTPServer) handleRollbackPermissionVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parsePermissionVersionID(mux.Vars(r)["id"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.RollbackPermissionVersionRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+
+	resp, err := s.permissionVersionService.Rollback(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// parsePermissionVersionID 解析权限配置版本号
+func (s *HTTPServer) parsePermissionVersionID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.BadRequest("INVALID_PARAMETER", "权限配置版本号无效")
+	}
+	return id, nil
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/service"
+)
+
+// ========== 个人资料处理器 ==========
+
+// handleUpdateProfile 修改当前用户的个人资料，请求体为字段名到新值的JSON对象
+func (s *HTTPServer) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var req service.UpdateProfileRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.profileService.UpdateProfile(r.Context(), req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	// 有字段等待审批时返回202
+	status := http.StatusOK
+	if resp.PendingRequest != nil {
+		status = http.StatusAccepted
+	}
+	s.sendResponse(w, status, resp)
+}
+
+// handleListMyProfileChangeRequests 获取当前用户的资料变更申请
+func (s *HTTPServer) handleListMyProfileChangeRequests(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.profileService.ListMyChangeRequests(r.Context(), parseProfileChangeRequestsQuery(r))
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCancelProfileChangeRequest 撤回当前用户待审批的资料变更申请
+func (s *HTTPServer) handleCancelProfileChangeRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseMemberPathID(r, "id", "变更申请ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.profileService.CancelChangeRequest(r.Context(), id)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleListProfileChangeRequests 获取全部用户的资料变更申请，可按status和user_id过滤
+func (s *HTTPServer) handleListProfileChangeRequests(w http.ResponseWriter, r *http.Request) {
+	req := parseProfileChangeRequestsQuery(r)
+	if userID, err := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 32); err == nil {
+		req.UserID = int32(userID)
+	}
+
+	resp, err := s.profileService.ListChangeRequests(r.Context(), req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleApproveProfileChangeRequest 审批通过资料变更申请
+func (s *HTTPServer) handleApproveProfileChangeRequest(w http.ResponseWriter, r *http.Request) {
+	s.handleReviewProfileChangeRequest(w, r, s.profileService.ApproveChangeRequest)
+}
+
+// handleRejectProfileChangeRequest 驳回资料变更申请
+func (s *HTTPServer) handleRejectProfileChangeRequest(w http.ResponseWriter, r *http.Request) {
+	s.handleReviewProfileChangeRequest(w, r, s.profileService.RejectChangeRequest)
+}
+
+func (s *HTTPServer) handleReviewProfileChangeRequest(w http.ResponseWriter, r *http.Request,
+	review func(ctx context.Context, id int32, req *service.ReviewProfileChangeRequest) (*biz.ProfileChangeRequest, error)) {
+	id, err := s.parseMemberPathID(r, "id", "变更申请ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.ReviewProfileChangeRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+
+	resp, err := review(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// parseProfileChangeRequestsQuery 解析变更申请列表的分页和状态参数
+func parseProfileChangeRequestsQuery(r *http.Request) *service.ListProfileChangeRequestsRequest {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	size, _ := strconv.Atoi(query.Get("size"))
+	return &service.ListProfileChangeRequestsRequest{
+		Status: query.Get("status"),
+		Page:   int32(page),
+		Size:   int32(size),
+	}
+}
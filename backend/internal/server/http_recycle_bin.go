@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ========== 回收站处理器 ==========
+
+// handleListDeletedItems 获取回收站条目，type可为user、role或organization
+func (s *HTTPServer) handleListDeletedItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	size, _ := strconv.Atoi(query.Get("size"))
+
+	req := &service.ListDeletedItemsRequest{
+		Type:    query.Get("type"),
+		Keyword: query.Get("keyword"),
+		Page:    int32(page),
+		Size:    int32(size),
+	}
+
+	resp, err := s.recycleBinService.ListDeletedItems(r.Context(), req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleRestoreDeletedItem 从回收站恢复用户、角色或组织
+func (s *HTTPServer) handleRestoreDeletedItem(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseMemberPathID(r, "id", "ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.recycleBinService.RestoreItem(r.Context(), mux.Vars(r)["type"], id); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{
+		"message": "恢复成功",
+	})
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// ========== 角色分配与委托处理器 ==========
+
+// handleListUserRoleAssignments 获取用户的角色分配，include_expired=true时包含已过期的分配
+func (s *HTTPServer) handleListUserRoleAssignments(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseUserID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	includeExpired, _ := strconv.ParseBool(r.URL.Query().Get("include_expired"))
+
+	resp, err := s.roleAssignmentService.ListUserRoleAssignments(r.Context(), userID, includeExpired)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCreateUserRoleAssignment 为用户新增限时角色分配
+func (s *HTTPServer) handleCreateUserRoleAssignment(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseUserID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.RoleAssignmentRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.roleAssignmentService.CreateRoleAssignment(r.Context(), userID, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleRevokeUserRoleAssignment 撤销用户的角色分配
+func (s *HTTPServer) handleRevokeUserRoleAssignment(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseUserID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	id, err := s.parseAssignmentID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.roleAssignmentService.RevokeRoleAssignment(r.Context(), userID, id); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "角色分配已撤销"})
+}
+
+// handleListMyRoleDelegations 获取当前用户发出的角色委托
+func (s *HTTPServer) handleListMyRoleDelegations(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.roleAssignmentService.ListMyDelegations(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDelegateRole 将当前用户的角色委托给同事
+func (s *HTTPServer) handleDelegateRole(w http.ResponseWriter, r *http.Request) {
+	var req service.DelegateRoleRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.roleAssignmentService.DelegateRole(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleRevokeRoleDelegation 撤销角色委托
+func (s *HTTPServer) handleRevokeRoleDelegation(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseAssignmentID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.roleAssignmentService.RevokeDelegation(r.Context(), id); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "角色委托已撤销"})
+}
+
+// parseUserID 从URL路径中获取用户ID
+func (s *HTTPServer) parseUserID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		return 0, errors.BadRequest("INVALID_PARAMETER", "用户ID无效")
+	}
+	return int32(id), nil
+}
+
+// parseAssignmentID 从URL路径中获取角色分配ID
+func (s *HTTPServer) parseAssignmentID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["assignmentId"], 10, 64)
+	if err != nil {
+		return 0, errors.BadRequest("INVALID_PARAMETER", "角色分配ID无效")
+	}
+	return id, nil
+}
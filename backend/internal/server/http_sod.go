@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/gorilla/mux"
+)
+
+// ========== 职责分离策略处理器 ==========
+
+// handleListSoDPolicies 获取职责分离策略列表
+func (s *HTTPServer) handleListSoDPolicies(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.sodService.ListPolicies(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleCreateSoDPolicy 创建职责分离策略
+func (s *HTTPServer) handleCreateSoDPolicy(w http.ResponseWriter, r *http.Request) {
+	var req service.SoDPolicyRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.sodService.CreatePolicy(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleGetSoDPolicy 获取职责分离策略详情
+func (s *HTTPServer) handleGetSoDPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseSoDPolicyID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.sodService.GetPolicy(r.Context(), id)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleUpdateSoDPolicy 更新职责分离策略
+func (s *HTTPServer) handleUpdateSoDPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseSoDPolicyID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.SoDPolicyRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.sodService.UpdatePolicy(r.Context(), id, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDeleteSoDPolicy 删除职责分离策略
+func (s *HTTPServer) handleDeleteSoDPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := s.parseSoDPolicyID(r)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.sodService.DeletePolicy(r.Context(), id); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "职责分离策略删除成功"})
+}
+
+// handleListSoDViolations 获取现有职责分离违规报告，可通过policy_id筛选策略
+func (s *HTTPServer) handleListSoDViolations(w http.ResponseWriter, r *http.Request) {
+	var policyID int64
+	if value := r.URL.Query().Get("policy_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			s.sendError(w, errors.BadRequest("INVALID_PARAMETER", "职责分离策略ID无效"))
+			return
+		}
+		policyID = id
+	}
+
+	resp, err := s.sodService.ListViolations(r.Context(), policyID)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// parseSoDPolicyID 从URL路径中获取职责分离策略ID
+func (s *HTTPServer) parseSoDPolicyID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, errors.BadRequest("INVALID_PARAMETER", "职责分离策略ID无效")
+	}
+	return id, nil
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+
+	"erp-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ========== 系统配置处理器 ==========
+
+// handleListSystemConfigs 获取当前公司生效的系统配置
+func (s *HTTPServer) handleListSystemConfigs(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.systemConfigService.ListConfigs(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetSystemConfig 获取当前公司生效的系统配置
+func (s *HTTPServer) handleGetSystemConfig(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.systemConfigService.GetConfig(r.Context(), mux.Vars(r)["key"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleSetSystemConfig 设置当前公司的配置值
+func (s *HTTPServer) handleSetSystemConfig(w http.ResponseWriter, r *http.Request) {
+	var req service.SetSystemConfigRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.systemConfigService.SetCompanyConfig(r.Context(), mux.Vars(r)["key"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleResetSystemConfig 删除当前公司的配置值，恢复使用全局默认值
+func (s *HTTPServer) handleResetSystemConfig(w http.ResponseWriter, r *http.Request) {
+	if err := s.systemConfigService.ResetCompanyConfig(r.Context(), mux.Vars(r)["key"]); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "已恢复全局默认配置"})
+}
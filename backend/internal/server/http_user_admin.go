@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-system/internal/service"
+)
+
+// ========== 用户管理处理器 ==========
+
+// handleBatchDeleteUsers 批量删除用户，返回每个用户的结果
+func (s *HTTPServer) handleBatchDeleteUsers(w http.ResponseWriter, r *http.Request) {
+	var req service.BatchDeleteUsersRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.userService.BatchDeleteUsers(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleToggleUserStatus 启用或禁用用户，请求体可省略，省略时在启用和禁用之间切换
+func (s *HTTPServer) handleToggleUserStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.ToggleUserStatusRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+	req.UserID = userID
+
+	resp, err := s.userService.ToggleUserStatus(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleRemoveUserRole 移除用户直接持有的角色
+func (s *HTTPServer) handleRemoveUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+	roleID, err := s.parseMemberPathID(r, "roleId", "角色ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	if err := s.userService.RemoveUserRole(r.Context(), userID, roleID); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{
+		"message": "角色移除成功",
+	})
+}
+
+// handleAssignUserOrganization 将用户加入组织
+func (s *HTTPServer) handleAssignUserOrganization(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	var req service.AssignUserOrganizationRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.organizationService.AssignUserOrganization(r.Context(), userID, &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusCreated, resp)
+}
+
+// handleGetUserPermissions 获取用户当前生效的角色和权限
+func (s *HTTPServer) handleGetUserPermissions(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.parseMemberPathID(r, "id", "用户ID无效")
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.userService.GetUserPermissions(r.Context(), userID)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetUserStats 获取用户统计，days指定按天统计登录情况的天数，默认30天
+func (s *HTTPServer) handleGetUserStats(w http.ResponseWriter, r *http.Request) {
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+
+	resp, err := s.userService.GetUserStats(r.Context(), days)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
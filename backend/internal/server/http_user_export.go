@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// ========== 用户导出处理器 ==========
+
+// handleExportUsers 导出用户，format=csv|xlsx|json，默认csv
+// GET通过查询参数指定search、filter_id和fields（逗号分隔）；POST的请求体可携带与用户列表相同的过滤和排序条件
+func (s *HTTPServer) handleExportUsers(w http.ResponseWriter, r *http.Request) {
+	var req service.UserExportRequest
+	if r.Method == http.MethodPost {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.sendError(w, err)
+			return
+		}
+	}
+
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" {
+		req.Format = strings.ToLower(format)
+	}
+	if search := query.Get("search"); search != "" {
+		req.Search = search
+	}
+	if filterID := query.Get("filter_id"); filterID != "" {
+		id, err := strconv.ParseInt(filterID, 10, 32)
+		if err != nil {
+			s.sendError(w, errors.BadRequest("INVALID_FILTER_ID", "过滤器ID无效"))
+			return
+		}
+		req.FilterID = int32(id)
+	}
+	if fields := query.Get("fields"); fields != "" {
+		req.Fields = strings.Split(fields, ",")
+	}
+
+	export, err := s.userExportService.PrepareExport(r.Context(), &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+export.Filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	s.userExportService.WriteExport(r.Context(), export, w)
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-system/internal/service"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// maxUserImportSize 导入用户的请求体上限
+const maxUserImportSize = 20 << 20
+
+// ========== 用户导入处理器 ==========
+
+// handleImportUsers 批量导入用户，请求体为CSV或XLSX文件，dry_run=true时只返回校验报告
+func (s *HTTPServer) handleImportUsers(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUserImportSize))
+	if err != nil {
+		s.sendError(w, errors.BadRequest("INVALID_REQUEST", "读取导入数据失败"))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	resp, err := s.userImportService.ImportUsers(r.Context(), userImportFormat(r), content, dryRun)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// userImportFormat 解析导入文件格式，优先使用format参数，其次根据Content-Type判断
+func userImportFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet") {
+		return service.UserImportFormatXLSX
+	}
+	return service.UserImportFormatCSV
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+
+	"erp-system/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ========== 工作流处理器 ==========
+
+// handleListWorkflows 获取全部工作流
+func (s *HTTPServer) handleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.workflowService.ListWorkflows(r.Context())
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleGetWorkflow 获取文档类型的工作流
+func (s *HTTPServer) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.workflowService.GetWorkflow(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleSaveWorkflow 创建或替换文档类型的工作流
+func (s *HTTPServer) handleSaveWorkflow(w http.ResponseWriter, r *http.Request) {
+	var req service.SaveWorkflowRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	resp, err := s.workflowService.SaveWorkflow(r.Context(), mux.Vars(r)["name"], &req)
+	if err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, resp)
+}
+
+// handleDeleteWorkflow 删除文档类型的工作流
+func (s *HTTPServer) handleDeleteWorkflow(w http.ResponseWriter, r *http.Request) {
+	if err := s.workflowService.DeleteWorkflow(r.Context(), mux.Vars(r)["name"]); err != nil {
+		s.sendError(w, err)
+		return
+	}
+
+	s.sendResponse(w, http.StatusOK, map[string]string{"message": "工作流删除成功"})
+}
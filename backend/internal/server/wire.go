@@ -4,6 +4,7 @@
 package server
 
 import (
+	"context"
 	"erp-system/internal/biz"
 	"erp-system/internal/conf"
 	"erp-system/internal/data"
@@ -27,6 +28,24 @@ var ProviderSet = wire.NewSet(
 	biz.NewPermissionUsecase,
 	biz.NewOrganizationUsecase,
 	biz.NewAuditUsecase,
+	biz.NewPermissionTemplateUsecase,
+	biz.NewRoleAssignmentUsecase,
+	biz.NewSoDUsecase,
+	biz.NewPermissionMatrixUsecase,
+	biz.NewPermissionVersionUsecase,
+	biz.NewDocFieldUsecase,
+	biz.NewDocumentUsecase,
+	biz.NewNamingSeriesUsecase,
+	biz.NewWorkflowUsecase,
+	biz.NewApprovalUsecase,
+	biz.NewCompanyUsecase,
+	biz.NewUserImportUsecase,
+	biz.NewUserFilterUsecase,
+	biz.NewUserAdminUsecase,
+	biz.NewRecycleBinUsecase,
+	biz.NewAttachmentUsecase,
+	biz.NewProfileUsecase,
+	biz.NewSystemConfigUsecase,
 
 	// Service layer
 	service.NewAuthService,
@@ -35,10 +54,30 @@ var ProviderSet = wire.NewSet(
 	service.NewPermissionService,
 	service.NewOrganizationService,
 	service.NewSystemService,
+	service.NewPermissionTemplateService,
+	service.NewRoleAssignmentService,
+	service.NewSoDService,
+	service.NewPermissionMatrixService,
+	service.NewPermissionVersionService,
+	service.NewDocFieldService,
+	service.NewDocumentService,
+	service.NewNamingSeriesService,
+	service.NewWorkflowService,
+	service.NewApprovalService,
+	service.NewCompanyService,
+	service.NewUserImportService,
+	service.NewUserExportService,
+	service.NewRecycleBinService,
+	service.NewAttachmentService,
+	service.NewProfileService,
+	service.NewSystemConfigService,
 
 	// Infrastructure
 	pkg.NewPasswordManager,
 	NewJWTManager,
+	NewRecycleBinRetention,
+	NewAttachmentConfig,
+	NewProfilePolicy,
 
 	// Servers
 	NewHTTPServer,
@@ -62,13 +101,50 @@ func NewJWTManager(c *conf.Data) *pkg.JWTManager {
 	return pkg.NewJWTManager(secretKey, tokenDuration)
 }
 
+// NewRecycleBinRetention 从配置中获取回收站保留期
+func NewRecycleBinRetention(c *conf.Data) biz.RecycleBinRetention {
+	return biz.RecycleBinRetention(c.Retention.GetRecycleBin())
+}
+
+// NewAttachmentConfig 从上传配置中获取附件限制，下载链接签名密钥未配置时使用JWT密钥
+func NewAttachmentConfig(u *conf.Upload, d *conf.Data) *biz.AttachmentConfig {
+	signSecret := "dev-jwt-secret-key-for-testing-only"
+	if d.Jwt != nil && d.Jwt.SecretKey != "" {
+		signSecret = d.Jwt.SecretKey
+	}
+	config := &biz.AttachmentConfig{
+		MaxSize:       u.GetMaxSize(),
+		URLExpire:     u.GetURLExpire(),
+		ThumbnailSize: biz.DefaultThumbnailSize,
+	}
+	if u != nil {
+		if u.SignSecret != "" {
+			signSecret = u.SignSecret
+		}
+		config.AllowedTypes = u.AllowedTypes
+		if u.ThumbnailSize > 0 {
+			config.ThumbnailSize = u.ThumbnailSize
+		}
+	}
+	config.SignKey = []byte(signSecret)
+	return config
+}
+
+// NewProfilePolicy 从配置中获取个人资料字段策略
+func NewProfilePolicy(p *conf.Profile) *biz.ProfilePolicy {
+	if p == nil {
+		return nil
+	}
+	return &biz.ProfilePolicy{EditableFields: p.EditableFields, ApprovalFields: p.ApprovalFields}
+}
+
 // InitializeApp 初始化应用
-func InitializeApp(*conf.Server, *conf.Data, log.Logger) (*kratos.App, func(), error) {
+func InitializeApp(*conf.Server, *conf.Data, *conf.Upload, *conf.Profile, log.Logger) (*kratos.App, func(), error) {
 	panic(wire.Build(ProviderSet, newApp))
 }
 
 // newApp 创建Kratos应用实例
-func newApp(logger log.Logger, hs *HTTPServer, gs *GRPCServer) *kratos.App {
+func newApp(logger log.Logger, hs *HTTPServer, gs *GRPCServer, ras *service.RoleAssignmentService, as *service.ApprovalService, rbs *service.RecycleBinService) *kratos.App {
 	return kratos.New(
 		kratos.Name("erp-system"),
 		kratos.Version("v1.0.0"),
@@ -77,5 +153,12 @@ func newApp(logger log.Logger, hs *HTTPServer, gs *GRPCServer) *kratos.App {
 			hs.Server,
 			gs.Server,
 		),
+		// 启动过期角色分配清理、审批超时升级和回收站清理任务，应用停止时随上下文取消
+		kratos.AfterStart(func(ctx context.Context) error {
+			go ras.StartRoleAssignmentCleanupTask(ctx)
+			go as.StartApprovalEscalationTask(ctx)
+			go rbs.StartRecycleBinPurgeTask(ctx)
+			return nil
+		}),
 	)
 }
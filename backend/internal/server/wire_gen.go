@@ -7,6 +7,7 @@
 package server
 
 import (
+	"context"
 	"erp-system/internal/biz"
 	"erp-system/internal/conf"
 	"erp-system/internal/data"
@@ -21,7 +22,7 @@ import (
 // Injectors from wire.go:
 
 // InitializeApp 初始化应用
-func InitializeApp(server *conf.Server, confData *conf.Data, logger log.Logger) (*kratos.App, func(), error) {
+func InitializeApp(server *conf.Server, confData *conf.Data, upload *conf.Upload, profile *conf.Profile, logger log.Logger) (*kratos.App, func(), error) {
 	dataData, cleanup, err := data.NewData(confData, logger)
 	if err != nil {
 		return nil, nil, err
@@ -30,23 +31,85 @@ func InitializeApp(server *conf.Server, confData *conf.Data, logger log.Logger)
 	userUsecase := biz.NewUserUsecase(userRepo, logger)
 	jwtManager := NewJWTManager(confData)
 	passwordManager := pkg.NewPasswordManager()
-	authService := service.NewAuthService(userUsecase, jwtManager, passwordManager, logger)
-	userService := service.NewUserService(userUsecase, passwordManager, logger)
-	roleRepo := data.NewRoleRepo(dataData, logger)
-	roleUsecase := biz.NewRoleUsecase(roleRepo, logger)
-	roleService := service.NewRoleService(roleUsecase, logger)
-	permissionRepo := data.NewPermissionRepo(dataData, logger)
+	companyRepo := data.NewCompanyRepo(dataData, logger)
+	companyUsecase := biz.NewCompanyUsecase(companyRepo, logger)
+	authService := service.NewAuthService(userUsecase, companyUsecase, jwtManager, passwordManager, logger)
+	permissionVersionRepo := data.NewPermissionVersionRepo(dataData, logger)
+	permissionCache := data.NewPermissionCache(dataData, logger)
+	permissionRepo := data.ProvidePermissionRepo(dataData, permissionCache, permissionVersionRepo, logger)
 	permissionUsecase := biz.NewPermissionUsecase(permissionRepo, logger)
-	permissionService := service.NewPermissionService(permissionUsecase, logger)
+	sodRepo := data.NewSoDRepo(dataData, logger)
+	transaction := data.NewTransaction(dataData)
+	soDUsecase := biz.NewSoDUsecase(sodRepo, transaction, logger)
+	userAdminRepo := data.NewUserAdminRepo(dataData, logger)
+	userAdminUsecase := biz.NewUserAdminUsecase(userAdminRepo, userRepo, logger)
+	profileRepo := data.NewProfileRepo(dataData, logger)
+	auditRepo := data.NewAuditRepo(dataData, logger)
+	profilePolicy := NewProfilePolicy(profile)
+	profileUsecase := biz.NewProfileUsecase(profileRepo, userRepo, auditRepo, profilePolicy, logger)
+	userService := service.NewUserService(userUsecase, userAdminUsecase, profileUsecase, permissionUsecase, soDUsecase, passwordManager, logger)
+	roleRepo := data.NewRoleRepo(dataData, logger)
+	roleUsecase := biz.NewRoleUsecase(roleRepo, permissionRepo, transaction, logger)
+	roleService := service.NewRoleService(roleUsecase, permissionUsecase, soDUsecase, logger)
+	docFieldRepo := data.NewDocFieldRepo(dataData, logger)
+	docFieldUsecase := biz.NewDocFieldUsecase(docFieldRepo, permissionRepo, logger)
+	permissionService := service.NewPermissionService(permissionUsecase, soDUsecase, docFieldUsecase, logger)
 	organizationRepo := data.NewOrganizationRepo(dataData, logger)
 	organizationUsecase := biz.NewOrganizationUsecase(organizationRepo, logger)
-	organizationService := service.NewOrganizationService(organizationUsecase, logger)
-	auditRepo := data.NewAuditRepo(dataData, logger)
+	organizationService := service.NewOrganizationService(organizationUsecase, permissionUsecase, logger)
 	auditUsecase := biz.NewAuditUsecase(auditRepo, logger)
 	systemService := service.NewSystemService(auditUsecase, logger)
-	httpServer := NewHTTPServer(server, confData, authService, userService, roleService, permissionService, organizationService, systemService, logger)
+	permissionTemplateRepo := data.NewPermissionTemplateRepo(dataData, logger)
+	permissionTemplateUsecase := biz.NewPermissionTemplateUsecase(permissionTemplateRepo, permissionRepo, transaction, logger)
+	permissionTemplateService := service.NewPermissionTemplateService(permissionTemplateUsecase, permissionUsecase, soDUsecase, logger)
+	roleAssignmentRepo := data.NewRoleAssignmentRepo(dataData, logger)
+	roleAssignmentUsecase := biz.NewRoleAssignmentUsecase(roleAssignmentRepo, permissionRepo, auditRepo, logger)
+	roleAssignmentService := service.NewRoleAssignmentService(roleAssignmentUsecase, userUsecase, roleUsecase, soDUsecase, logger)
+	soDService := service.NewSoDService(soDUsecase, permissionUsecase, logger)
+	permissionMatrixUsecase := biz.NewPermissionMatrixUsecase(permissionRepo, logger)
+	permissionMatrixService := service.NewPermissionMatrixService(permissionMatrixUsecase, soDUsecase, logger)
+	permissionVersionUsecase := biz.NewPermissionVersionUsecase(permissionVersionRepo, permissionRepo, logger)
+	permissionVersionService := service.NewPermissionVersionService(permissionVersionUsecase, soDUsecase, logger)
+	docFieldService := service.NewDocFieldService(docFieldUsecase, permissionUsecase, logger)
+	documentRepo := data.NewDocumentRepo(dataData, logger)
+	namingSeriesRepo := data.NewNamingSeriesRepo(dataData, logger)
+	workflowRepo := data.NewWorkflowRepo(dataData, logger)
+	approvalRepo := data.NewApprovalRepo(dataData, logger)
+	documentUsecase := biz.NewDocumentUsecase(documentRepo, docFieldRepo, permissionRepo, namingSeriesRepo, workflowRepo, approvalRepo, logger)
+	workflowUsecase := biz.NewWorkflowUsecase(workflowRepo, documentUsecase, permissionRepo, roleRepo, auditRepo, logger)
+	approvalUsecase := biz.NewApprovalUsecase(approvalRepo, documentUsecase, organizationRepo, permissionRepo, roleRepo, auditRepo, transaction, logger)
+	attachmentRepo := data.NewAttachmentRepo(dataData, logger)
+	fileStorage, err := data.NewFileStorage(upload, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	attachmentConfig := NewAttachmentConfig(upload, confData)
+	attachmentUsecase := biz.NewAttachmentUsecase(attachmentRepo, fileStorage, permissionRepo, attachmentConfig, logger)
+	documentService := service.NewDocumentService(documentUsecase, workflowUsecase, approvalUsecase, attachmentUsecase, permissionUsecase, logger)
+	namingSeriesUsecase := biz.NewNamingSeriesUsecase(namingSeriesRepo, logger)
+	namingSeriesService := service.NewNamingSeriesService(namingSeriesUsecase, logger)
+	workflowService := service.NewWorkflowService(workflowUsecase, logger)
+	approvalService := service.NewApprovalService(approvalUsecase, logger)
+	companyService := service.NewCompanyService(companyUsecase, permissionUsecase, logger)
+	userImportRepo := data.NewUserImportRepo(dataData, logger)
+	userImportUsecase := biz.NewUserImportUsecase(userImportRepo, userRepo, roleRepo, organizationRepo, soDUsecase, logger)
+	userImportService := service.NewUserImportService(userImportUsecase, passwordManager, logger)
+	userFilterRepo := data.NewUserFilterRepo(dataData, logger)
+	userFilterUsecase := biz.NewUserFilterUsecase(userFilterRepo, logger)
+	userExportService := service.NewUserExportService(userUsecase, permissionUsecase, userFilterUsecase, logger)
+	recycleBinRepo := data.NewRecycleBinRepo(dataData, logger)
+	recycleBinRetention := NewRecycleBinRetention(confData)
+	recycleBinUsecase := biz.NewRecycleBinUsecase(recycleBinRepo, recycleBinRetention, logger)
+	recycleBinService := service.NewRecycleBinService(recycleBinUsecase, logger)
+	attachmentService := service.NewAttachmentService(attachmentUsecase, logger)
+	profileService := service.NewProfileService(profileUsecase, permissionUsecase, logger)
+	systemConfigRepo := data.NewSystemConfigRepo(dataData, logger)
+	systemConfigUsecase := biz.NewSystemConfigUsecase(systemConfigRepo, logger)
+	systemConfigService := service.NewSystemConfigService(systemConfigUsecase, logger)
+	httpServer := NewHTTPServer(server, confData, authService, userService, roleService, permissionService, organizationService, systemService, permissionTemplateService, roleAssignmentService, soDService, permissionMatrixService, permissionVersionService, docFieldService, documentService, namingSeriesService, workflowService, approvalService, companyService, userImportService, userExportService, recycleBinService, attachmentService, profileService, systemConfigService, logger)
 	grpcServer := NewGRPCServer(server, logger)
-	app := newApp(logger, httpServer, grpcServer)
+	app := newApp(logger, httpServer, grpcServer, roleAssignmentService, approvalService, recycleBinService)
 	return app, func() {
 		cleanup()
 	}, nil
@@ -55,7 +118,7 @@ func InitializeApp(server *conf.Server, confData *conf.Data, logger log.Logger)
 // wire.go:
 
 // ProviderSet 是所有提供者的集合
-var ProviderSet = wire.NewSet(data.ProviderSet, biz.NewUserUsecase, biz.NewRoleUsecase, biz.NewPermissionUsecase, biz.NewOrganizationUsecase, biz.NewAuditUsecase, service.NewAuthService, service.NewUserService, service.NewRoleService, service.NewPermissionService, service.NewOrganizationService, service.NewSystemService, pkg.NewPasswordManager, NewJWTManager,
+var ProviderSet = wire.NewSet(data.ProviderSet, biz.NewUserUsecase, biz.NewRoleUsecase, biz.NewPermissionUsecase, biz.NewOrganizationUsecase, biz.NewAuditUsecase, biz.NewPermissionTemplateUsecase, biz.NewRoleAssignmentUsecase, biz.NewSoDUsecase, biz.NewPermissionMatrixUsecase, biz.NewPermissionVersionUsecase, biz.NewDocFieldUsecase, biz.NewDocumentUsecase, biz.NewNamingSeriesUsecase, biz.NewWorkflowUsecase, biz.NewApprovalUsecase, biz.NewCompanyUsecase, biz.NewUserImportUsecase, biz.NewUserFilterUsecase, biz.NewUserAdminUsecase, biz.NewRecycleBinUsecase, biz.NewAttachmentUsecase, biz.NewProfileUsecase, biz.NewSystemConfigUsecase, service.NewAuthService, service.NewUserService, service.NewRoleService, service.NewPermissionService, service.NewOrganizationService, service.NewSystemService, service.NewPermissionTemplateService, service.NewRoleAssignmentService, service.NewSoDService, service.NewPermissionMatrixService, service.NewPermissionVersionService, service.NewDocFieldService, service.NewDocumentService, service.NewNamingSeriesService, service.NewWorkflowService, service.NewApprovalService, service.NewCompanyService, service.NewUserImportService, service.NewUserExportService, service.NewRecycleBinService, service.NewAttachmentService, service.NewProfileService, service.NewSystemConfigService, pkg.NewPasswordManager, NewJWTManager, NewRecycleBinRetention, NewAttachmentConfig, NewProfilePolicy,
 
 	NewHTTPServer,
 	NewGRPCServer,
@@ -77,11 +140,53 @@ func NewJWTManager(c *conf.Data) *pkg.JWTManager {
 	return pkg.NewJWTManager(secretKey, tokenDuration)
 }
 
+// NewRecycleBinRetention 从配置中获取回收站保留期
+func NewRecycleBinRetention(c *conf.Data) biz.RecycleBinRetention {
+	return biz.RecycleBinRetention(c.Retention.GetRecycleBin())
+}
+
+// NewAttachmentConfig 从上传配置中获取附件限制，下载链接签名密钥未配置时使用JWT密钥
+func NewAttachmentConfig(u *conf.Upload, d *conf.Data) *biz.AttachmentConfig {
+	signSecret := "dev-jwt-secret-key-for-testing-only"
+	if d.Jwt != nil && d.Jwt.SecretKey != "" {
+		signSecret = d.Jwt.SecretKey
+	}
+	config := &biz.AttachmentConfig{
+		MaxSize:       u.GetMaxSize(),
+		URLExpire:     u.GetURLExpire(),
+		ThumbnailSize: biz.DefaultThumbnailSize,
+	}
+	if u != nil {
+		if u.SignSecret != "" {
+			signSecret = u.SignSecret
+		}
+		config.AllowedTypes = u.AllowedTypes
+		if u.ThumbnailSize > 0 {
+			config.ThumbnailSize = u.ThumbnailSize
+		}
+	}
+	config.SignKey = []byte(signSecret)
+	return config
+}
+
+// NewProfilePolicy 从配置中获取个人资料字段策略
+func NewProfilePolicy(p *conf.Profile) *biz.ProfilePolicy {
+	if p == nil {
+		return nil
+	}
+	return &biz.ProfilePolicy{EditableFields: p.EditableFields, ApprovalFields: p.ApprovalFields}
+}
+
 // newApp 创建Kratos应用实例
-func newApp(logger log.Logger, hs *HTTPServer, gs *GRPCServer) *kratos.App {
+func newApp(logger log.Logger, hs *HTTPServer, gs *GRPCServer, ras *service.RoleAssignmentService, as *service.ApprovalService, rbs *service.RecycleBinService) *kratos.App {
 	return kratos.New(kratos.Name("erp-system"), kratos.Version("v1.0.0"), kratos.Logger(logger), kratos.Server(
 		hs.Server,
 		gs.Server,
-	),
+	), kratos.AfterStart(func(ctx context.Context) error {
+		go ras.StartRoleAssignmentCleanupTask(ctx)
+		go as.StartApprovalEscalationTask(ctx)
+		go rbs.StartRecycleBinPurgeTask(ctx)
+		return nil
+	}),
 	)
 }
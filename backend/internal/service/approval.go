@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// approvalEscalationInterval 审批超时升级任务的执行间隔
+const approvalEscalationInterval = 5 * time.Minute
+
+// ApprovalService 审批服务：审批链定义、审批待办、审批委托，文档发起和撤回审批由DocumentService提供
+type ApprovalService struct {
+	approvalUc *biz.ApprovalUsecase
+	log        *log.Helper
+}
+
+// NewApprovalService 创建审批服务
+func NewApprovalService(approvalUc *biz.ApprovalUsecase, logger log.Logger) *ApprovalService {
+	return &ApprovalService{
+		approvalUc: approvalUc,
+		log:        log.NewHelper(logger),
+	}
+}
+
+// SaveApprovalChainRequest 创建或替换审批链请求
+type SaveApprovalChainRequest struct {
+	Name        string              `json:"name" validate:"required,max=100"`
+	Description string              `json:"description"`
+	IsActive    *bool               `json:"is_active"` // 省略时启用
+	OrgField    string              `json:"org_field"`
+	Steps       []*biz.ApprovalStep `json:"steps" validate:"required"`
+}
+
+// ListApprovalChainsResponse 审批链列表响应
+type ListApprovalChainsResponse struct {
+	Chains []*biz.ApprovalChain `json:"chains"`
+	Total  int32                `json:"total"`
+}
+
+// ListApprovalTasksResponse 审批任务列表响应
+type ListApprovalTasksResponse struct {
+	Tasks []*biz.ApprovalTask `json:"tasks"`
+	Total int32               `json:"total"`
+}
+
+// DecideApprovalTaskRequest 同意或驳回审批任务请求
+type DecideApprovalTaskRequest struct {
+	Comment string `json:"comment"`
+}
+
+// DelegateApprovalTaskRequest 转交审批任务请求
+type DelegateApprovalTaskRequest struct {
+	UserID  int64  `json:"user_id" validate:"required"`
+	Comment string `json:"comment"`
+}
+
+// CreateApprovalDelegationRequest 创建审批委托请求，DocType为空时委托全部文档类型
+type CreateApprovalDelegationRequest struct {
+	DelegateID int64     `json:"delegate_id" validate:"required"`
+	DocType    string    `json:"doc_type"`
+	StartsAt   time.Time `json:"starts_at"` // 省略时立即生效
+	EndsAt     time.Time `json:"ends_at" validate:"required"`
+	Reason     string    `json:"reason"`
+}
+
+// ListApprovalDelegationsResponse 审批委托列表响应
+type ListApprovalDelegationsResponse struct {
+	Delegations []*biz.ApprovalDelegation `json:"delegations"`
+	Total       int32                     `json:"total"`
+}
+
+// ListApprovalChains 获取全部审批链
+func (s *ApprovalService) ListApprovalChains(ctx context.Context) (*ListApprovalChainsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看审批链")
+	}
+
+	chains, err := s.approvalUc.ListApprovalChains(ctx)
+	if err != nil {
+		return nil, s.convertError(err, "获取审批链列表失败")
+	}
+	return &ListApprovalChainsResponse{Chains: chains, Total: int32(len(chains))}, nil
+}
+
+// GetApprovalChain 获取文档类型的审批链
+func (s *ApprovalService) GetApprovalChain(ctx context.Context, docType string) (*biz.ApprovalChain, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看审批链")
+	}
+
+	chain, err := s.approvalUc.GetApprovalChain(ctx, docType)
+	if err != nil {
+		return nil, s.convertError(err, "获取审批链失败")
+	}
+	return chain, nil
+}
+
+// SaveApprovalChain 创建或替换文档类型的审批链
+func (s *ApprovalService) SaveApprovalChain(ctx context.Context, docType string, req *SaveApprovalChainRequest) (*biz.ApprovalChain, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改审批链")
+	}
+
+	chain := &biz.ApprovalChain{
+		DocType:     docType,
+		Name:        req.Name,
+		Description: req.Description,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		OrgField:    req.OrgField,
+		Steps:       req.Steps,
+		CreatedBy:   &currentUser.ID,
+		UpdatedBy:   &currentUser.ID,
+	}
+	saved, err := s.approvalUc.SaveApprovalChain(ctx, chain)
+	if err != nil {
+		return nil, s.convertError(err, "审批链保存失败")
+	}
+
+	s.log.Infof("Approval chain saved successfully: %s (%s)", saved.Name, saved.DocType)
+	return saved, nil
+}
+
+// DeleteApprovalChain 删除文档类型的审批链
+func (s *ApprovalService) DeleteApprovalChain(ctx context.Context, docType string) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限删除审批链")
+	}
+
+	if err := s.approvalUc.DeleteApprovalChain(ctx, docType); err != nil {
+		return s.convertError(err, "审批链删除失败")
+	}
+
+	s.log.Infof("Approval chain deleted successfully: %s", docType)
+	return nil
+}
+
+// ListMyTasks 获取当前用户的审批任务，status为空时返回全部状态
+func (s *ApprovalService) ListMyTasks(ctx context.Context, status string) (*ListApprovalTasksResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+
+	tasks, err := s.approvalUc.ListTasks(ctx, currentUser.ID, status)
+	if err != nil {
+		return nil, s.convertError(err, "获取审批任务失败")
+	}
+	return &ListApprovalTasksResponse{Tasks: tasks, Total: int32(len(tasks))}, nil
+}
+
+// ApproveTask 同意审批任务
+func (s *ApprovalService) ApproveTask(ctx context.Context, taskID int64, req *DecideApprovalTaskRequest) (*biz.ApprovalRequest, error) {
+	return s.decideTask(ctx, taskID, true, req)
+}
+
+// RejectTask 驳回审批任务
+func (s *ApprovalService) RejectTask(ctx context.Context, taskID int64, req *DecideApprovalTaskRequest) (*biz.ApprovalRequest, error) {
+	return s.decideTask(ctx, taskID, false, req)
+}
+
+// DelegateTask 将审批任务转交给其他用户
+func (s *ApprovalService) DelegateTask(ctx context.Context, taskID int64, req *DelegateApprovalTaskRequest) (*biz.ApprovalRequest, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+
+	approval, err := s.approvalUc.DelegateTask(ctx, taskID, req.UserID, req.Comment, currentUser.ID)
+	if err != nil {
+		return nil, s.convertError(err, "审批任务转交失败")
+	}
+
+	s.log.Infof("Approval task %d delegated to user %d by %s", taskID, req.UserID, currentUser.Username)
+	return approval, nil
+}
+
+// GetApprovalRequest 获取审批请求，非管理员只能查看自己发起或参与审批的请求
+func (s *ApprovalService) GetApprovalRequest(ctx context.Context, id int64) (*biz.ApprovalRequest, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	override := currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN")
+
+	approval, err := s.approvalUc.GetApprovalRequest(ctx, id, currentUser.ID, override)
+	if err != nil {
+		return nil, s.convertError(err, "获取审批请求失败")
+	}
+	return approval, nil
+}
+
+// ListMyDelegations 获取当前用户的审批委托
+func (s *ApprovalService) ListMyDelegations(ctx context.Context) (*ListApprovalDelegationsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+
+	delegations, err := s.approvalUc.ListDelegations(ctx, currentUser.ID)
+	if err != nil {
+		return nil, s.convertError(err, "获取审批委托失败")
+	}
+	return &ListApprovalDelegationsResponse{Delegations: delegations, Total: int32(len(delegations))}, nil
+}
+
+// CreateDelegation 将当前用户在委托期内的审批任务委托给其他用户
+func (s *ApprovalService) CreateDelegation(ctx context.Context, req *CreateApprovalDelegationRequest) (*biz.ApprovalDelegation, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+
+	startsAt := req.StartsAt
+	if startsAt.IsZero() {
+		startsAt = time.Now()
+	}
+	delegation, err := s.approvalUc.CreateDelegation(ctx, &biz.ApprovalDelegation{
+		DelegatorID: currentUser.ID,
+		DelegateID:  req.DelegateID,
+		DocType:     req.DocType,
+		StartsAt:    startsAt,
+		EndsAt:      req.EndsAt,
+		Reason:      req.Reason,
+	})
+	if err != nil {
+		return nil, s.convertError(err, "审批委托创建失败")
+	}
+
+	s.log.Infof("Approval delegation created: user %d -> %d by %s", currentUser.ID, req.DelegateID, currentUser.Username)
+	return delegation, nil
+}
+
+// RevokeDelegation 撤销审批委托，管理员可撤销他人的委托
+func (s *ApprovalService) RevokeDelegation(ctx context.Context, id int64) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	override := currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN")
+
+	if err := s.approvalUc.RevokeDelegation(ctx, id, currentUser.ID, override); err != nil {
+		return s.convertError(err, "审批委托撤销失败")
+	}
+
+	s.log.Infof("Approval delegation %d revoked by %s", id, currentUser.Username)
+	return nil
+}
+
+// StartApprovalEscalationTask 启动审批超时升级任务，启动时立即执行一次
+func (s *ApprovalService) StartApprovalEscalationTask(ctx context.Context) {
+	ticker := time.NewTicker(approvalEscalationInterval)
+	defer ticker.Stop()
+
+	for {
+		escalated, err := s.approvalUc.EscalateOverdueTasks(ctx, time.Now())
+		if err != nil {
+			s.log.Errorf("Approval escalation error: %v", err)
+		} else if escalated > 0 {
+			s.log.Infof("Escalated %d overdue approval tasks", escalated)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("Approval escalation task stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// decideTask 同意或驳回当前用户的审批任务
+func (s *ApprovalService) decideTask(ctx context.Context, taskID int64, approve bool, req *DecideApprovalTaskRequest) (*biz.ApprovalRequest, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+
+	approval, err := s.approvalUc.DecideTask(ctx, taskID, approve, req.Comment, currentUser.ID)
+	if err != nil {
+		return nil, s.convertError(err, "审批失败")
+	}
+
+	s.log.Infof("Approval task %d decided by %s: approve=%v", taskID, currentUser.Username, approve)
+	return approval, nil
+}
+
+// convertError 将审批业务错误转换为API错误
+func (s *ApprovalService) convertError(err error, message string) error {
+	if apiErr := convertApprovalError(err); apiErr != nil {
+		return apiErr
+	}
+	switch {
+	case stderrors.Is(err, biz.ErrDocTypeNotFound):
+		return errors.NotFound("DOCTYPE_NOT_FOUND", "文档类型不存在")
+	case stderrors.Is(err, biz.ErrDocumentNotFound):
+		return errors.NotFound("DOCUMENT_NOT_FOUND", "文档不存在")
+	case stderrors.Is(err, biz.ErrInvalidDocumentTransition):
+		return errors.Conflict("INVALID_DOCUMENT_TRANSITION", err.Error())
+	case stderrors.Is(err, biz.ErrInvalidDocument):
+		return errors.BadRequest("INVALID_DOCUMENT", err.Error())
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
+
+// convertApprovalError 转换审批相关的业务错误，DocumentService与ApprovalService共用；非审批错误返回nil
+func convertApprovalError(err error) error {
+	switch {
+	case stderrors.Is(err, biz.ErrApprovalChainNotFound):
+		return errors.NotFound("APPROVAL_CHAIN_NOT_FOUND", err.Error())
+	case stderrors.Is(err, biz.ErrInvalidApprovalChain):
+		return errors.BadRequest("INVALID_APPROVAL_CHAIN", err.Error())
+	case stderrors.Is(err, biz.ErrApprovalRequestNotFound):
+		return errors.NotFound("APPROVAL_REQUEST_NOT_FOUND", err.Error())
+	case stderrors.Is(err, biz.ErrApprovalPending):
+		return errors.Conflict("APPROVAL_PENDING", err.Error())
+	case stderrors.Is(err, biz.ErrApprovalRequestChanged):
+		return errors.Conflict("APPROVAL_REQUEST_CHANGED", err.Error())
+	case stderrors.Is(err, biz.ErrApproverNotFound):
+		return errors.BadRequest("APPROVER_NOT_FOUND", err.Error())
+	case stderrors.Is(err, biz.ErrApprovalTaskNotFound):
+		return errors.NotFound("APPROVAL_TASK_NOT_FOUND", err.Error())
+	case stderrors.Is(err, biz.ErrApprovalTaskNotPending):
+		return errors.Conflict("APPROVAL_TASK_NOT_PENDING", err.Error())
+	case stderrors.Is(err, biz.ErrApprovalNotAllowed):
+		return errors.Forbidden("APPROVAL_NOT_ALLOWED", err.Error())
+	case stderrors.Is(err, biz.ErrDocumentUnderApproval):
+		return errors.Conflict("DOCUMENT_UNDER_APPROVAL", err.Error())
+	case stderrors.Is(err, biz.ErrApprovalDelegationNotFound):
+		return errors.NotFound("APPROVAL_DELEGATION_NOT_FOUND", err.Error())
+	case stderrors.Is(err, biz.ErrInvalidApprovalDelegation):
+		return errors.BadRequest("INVALID_APPROVAL_DELEGATION", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// AttachmentService 用户头像和附件下载服务
+type AttachmentService struct {
+	attachmentUc *biz.AttachmentUsecase
+	log          *log.Helper
+}
+
+// NewAttachmentService 创建附件服务
+func NewAttachmentService(attachmentUc *biz.AttachmentUsecase, logger log.Logger) *AttachmentService {
+	return &AttachmentService{
+		attachmentUc: attachmentUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// UploadAvatarResponse 上传头像响应
+type UploadAvatarResponse struct {
+	AvatarURL  string          `json:"avatar_url"`
+	Attachment *biz.Attachment `json:"attachment"`
+}
+
+// UploadAvatar 上传用户头像，用户本人或用户管理员可上传
+func (s *AttachmentService) UploadAvatar(ctx context.Context, userID int32, fileName string, file io.Reader) (*UploadAvatarResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if currentUser.ID != int64(userID) && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改用户头像")
+	}
+
+	attachment, err := s.attachmentUc.UploadAvatar(ctx, int64(userID), fileName, file, currentUser.ID)
+	if err != nil {
+		return nil, s.convertError(err, "头像上传失败")
+	}
+
+	s.log.Infof("Avatar of user %d uploaded by %s", userID, currentUser.Username)
+	return &UploadAvatarResponse{AvatarURL: biz.UserAvatarURL(int64(userID)), Attachment: attachment}, nil
+}
+
+// GetAvatarURL 获取用户头像的签名下载链接，variant为thumbnail时返回缩略图链接
+func (s *AttachmentService) GetAvatarURL(ctx context.Context, userID int32, variant string) (string, error) {
+	attachment, err := s.attachmentUc.GetAvatar(ctx, int64(userID))
+	if err != nil {
+		return "", s.convertError(err, "头像获取失败")
+	}
+	if variant == biz.AttachmentVariantThumbnail && attachment.ThumbnailURL != "" {
+		return attachment.ThumbnailURL, nil
+	}
+	return attachment.URL, nil
+}
+
+// OpenDownload 校验签名下载链接并打开附件文件，调用方负责关闭返回的文件
+func (s *AttachmentService) OpenDownload(ctx context.Context, id int64, variant string, expires int64, signature string) (*biz.Attachment, io.ReadCloser, error) {
+	attachment, file, err := s.attachmentUc.OpenAttachment(ctx, id, variant, expires, signature, time.Now())
+	if err != nil {
+		return nil, nil, s.convertError(err, "附件下载失败")
+	}
+	return attachment, file, nil
+}
+
+// convertError 将附件业务错误转换为API错误
+func (s *AttachmentService) convertError(err error, message string) error {
+	if apiErr := convertAttachmentError(err); apiErr != nil {
+		return apiErr
+	}
+	if stderrors.Is(err, biz.ErrUserNotFound) {
+		return errors.NotFound("USER_NOT_FOUND", "用户不存在")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
+
+// convertAttachmentError 转换附件相关的业务错误，DocumentService与AttachmentService共用；非附件错误返回nil
+func convertAttachmentError(err error) error {
+	switch {
+	case stderrors.Is(err, biz.ErrAttachmentNotFound):
+		return errors.NotFound("ATTACHMENT_NOT_FOUND", "附件不存在")
+	case stderrors.Is(err, biz.ErrAttachmentEmpty):
+		return errors.BadRequest("EMPTY_FILE", "文件内容为空")
+	case stderrors.Is(err, biz.ErrAttachmentTooLarge):
+		return errors.BadRequest("FILE_TOO_LARGE", "文件超过大小上限")
+	case stderrors.Is(err, biz.ErrAttachmentTypeNotAllowed):
+		return errors.BadRequest("FILE_TYPE_NOT_ALLOWED", "不支持的文件类型")
+	case stderrors.Is(err, biz.ErrAttachmentInvalidImage):
+		return errors.BadRequest("INVALID_IMAGE", "图片无法识别或尺寸过大")
+	case stderrors.Is(err, biz.ErrAttachmentLimitExceeded):
+		return errors.Conflict("ATTACHMENT_LIMIT_EXCEEDED", "文档附件数量已达上限")
+	case stderrors.Is(err, biz.ErrAttachmentURLExpired):
+		return errors.Forbidden("ATTACHMENT_URL_EXPIRED", "下载链接已过期")
+	case stderrors.Is(err, biz.ErrAttachmentInvalidSignature):
+		return errors.Forbidden("INVALID_SIGNATURE", "下载链接无效")
+	}
+	return nil
+}
@@ -15,24 +15,27 @@ import (
 
 // AuthService 认证服务
 type AuthService struct {
-	userUc *biz.UserUsecase
-	jwtMgr *pkg.JWTManager
-	pwdMgr *pkg.PasswordManager
-	log    *log.Helper
+	userUc    *biz.UserUsecase
+	companyUc *biz.CompanyUsecase
+	jwtMgr    *pkg.JWTManager
+	pwdMgr    *pkg.PasswordManager
+	log       *log.Helper
 }
 
 // NewAuthService 创建认证服务
 func NewAuthService(
 	userUc *biz.UserUsecase,
+	companyUc *biz.CompanyUsecase,
 	jwtMgr *pkg.JWTManager,
 	pwdMgr *pkg.PasswordManager,
 	logger log.Logger,
 ) *AuthService {
 	return &AuthService{
-		userUc: userUc,
-		jwtMgr: jwtMgr,
-		pwdMgr: pwdMgr,
-		log:    log.NewHelper(logger),
+		userUc:    userUc,
+		companyUc: companyUc,
+		jwtMgr:    jwtMgr,
+		pwdMgr:    pwdMgr,
+		log:       log.NewHelper(logger),
 	}
 }
 
@@ -48,11 +51,12 @@ type LoginRequest struct {
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
-	ExpiresIn    int64     `json:"expires_in"`
-	TokenType    string    `json:"token_type"`
-	User         *UserInfo `json:"user"`
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int64        `json:"expires_in"`
+	TokenType    string       `json:"token_type"`
+	User         *UserInfo    `json:"user"`
+	Company      *biz.Company `json:"company"` // 当前公司
 }
 
 // RegisterRequest 注册请求
@@ -86,6 +90,20 @@ type RefreshTokenResponse struct {
 	TokenType   string `json:"token_type"`
 }
 
+// SwitchCompanyRequest 切换当前公司请求
+type SwitchCompanyRequest struct {
+	CompanyID int32 `json:"company_id" validate:"required"`
+}
+
+// SwitchCompanyResponse 切换当前公司响应，返回绑定新公司的访问令牌和刷新令牌
+type SwitchCompanyResponse struct {
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int64        `json:"expires_in"`
+	TokenType    string       `json:"token_type"`
+	Company      *biz.Company `json:"company"`
+}
+
 // LogoutRequest 登出请求
 type LogoutRequest struct {
 	RefreshToken string `json:"refresh_token,omitempty"`
@@ -143,6 +161,22 @@ func ToUserInfo(user *biz.User, roles []string, permissions []string) *UserInfo
 	}
 }
 
+// roleClaims 提取令牌中的角色编码，以及限时角色的截止时间（Unix秒）
+func roleClaims(roles []*biz.Role) ([]string, map[string]int64) {
+	codes := make([]string, len(roles))
+	var expiry map[string]int64
+	for i, role := range roles {
+		codes[i] = role.Code
+		if role.ValidUntil != nil {
+			if expiry == nil {
+				expiry = make(map[string]int64)
+			}
+			expiry[role.Code] = role.ValidUntil.Unix()
+		}
+	}
+	return codes, expiry
+}
+
 // Login 用户登录
 func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
 	s.log.Infof("User login attempt: %s", req.Username)
@@ -185,6 +219,13 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginRespo
 		}
 	}
 
+	// 进入用户的默认公司
+	company, err := s.companyUc.ResolveCompany(ctx, user.ID, 0)
+	if err != nil {
+		s.log.Warnf("No accessible company for user %s: %v", req.Username, err)
+		return nil, convertAuthCompanyError(err)
+	}
+
 	// 获取用户角色和权限
 	roles, err := s.userUc.GetUserRoles(ctx, user.ID)
 	if err != nil {
@@ -202,10 +243,7 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginRespo
 	sessionID := uuid.New().String()
 
 	// 转换角色和权限为字符串数组
-	roleStrs := make([]string, len(roles))
-	for i, role := range roles {
-		roleStrs[i] = role.Code
-	}
+	roleStrs, roleExpiry := roleClaims(roles)
 
 	// 生成访问令牌
 	tokenDuration := time.Hour * 2 // 2小时
@@ -215,7 +253,7 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginRespo
 
 	accessToken, err := s.jwtMgr.Generate(
 		int64(user.ID), user.Username, user.Email,
-		roleStrs, permissions, sessionID, "access",
+		roleStrs, roleExpiry, permissions, sessionID, "access", company.ID,
 	)
 	if err != nil {
 		s.log.Errorf("Failed to generate access token: %v", err)
@@ -223,7 +261,7 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginRespo
 	}
 
 	// 生成刷新令牌
-	refreshToken, err := s.jwtMgr.GenerateRefreshToken(int64(user.ID), user.Username, sessionID)
+	refreshToken, err := s.jwtMgr.GenerateRefreshToken(int64(user.ID), user.Username, sessionID, company.ID)
 	if err != nil {
 		s.log.Errorf("Failed to generate refresh token: %v", err)
 		return nil, errors.InternalServer("TOKEN_GENERATION_ERROR", "令牌生成失败")
@@ -244,6 +282,7 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*LoginRespo
 		ExpiresIn:    int64(tokenDuration.Seconds()),
 		TokenType:    "Bearer",
 		User:         ToUserInfo(user, roleStrs, permissions),
+		Company:      company,
 	}, nil
 }
 
@@ -354,6 +393,12 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 		return nil, errors.Forbidden("ACCOUNT_DISABLED", "账户已被禁用")
 	}
 
+	// 保持刷新令牌中的公司，用户已被移出该公司或公司已停用时需要重新登录
+	company, err := s.companyUc.ResolveCompany(ctx, user.ID, claims.CompanyID)
+	if err != nil {
+		return nil, convertAuthCompanyError(err)
+	}
+
 	// 获取用户角色和权限
 	roles, err := s.userUc.GetUserRoles(ctx, user.ID)
 	if err != nil {
@@ -368,16 +413,13 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 	}
 
 	// 转换角色为字符串数组
-	roleStrs := make([]string, len(roles))
-	for i, role := range roles {
-		roleStrs[i] = role.Code
-	}
+	roleStrs, roleExpiry := roleClaims(roles)
 
 	// 生成新的访问令牌
 	tokenDuration := time.Hour * 2 // 2小时
 	accessToken, err := s.jwtMgr.Generate(
 		int64(user.ID), user.Username, user.Email,
-		roleStrs, permissions, claims.SessionID, "access",
+		roleStrs, roleExpiry, permissions, claims.SessionID, "access", company.ID,
 	)
 	if err != nil {
 		s.log.Errorf("Failed to generate access token: %v", err)
@@ -391,6 +433,79 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 	}, nil
 }
 
+// SwitchCompany 切换当前公司，在同一会话中重新签发绑定新公司的访问令牌和刷新令牌
+func (s *AuthService) SwitchCompany(ctx context.Context, req *SwitchCompanyRequest) (*SwitchCompanyResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.IsAuthenticated() {
+		return nil, errors.Unauthorized("NOT_AUTHENTICATED", "用户未认证")
+	}
+	if req.CompanyID <= 0 {
+		return nil, errors.BadRequest("INVALID_PARAMETER", "公司ID无效")
+	}
+
+	user, err := s.userUc.GetUser(ctx, int32(currentUser.ID))
+	if err != nil {
+		return nil, errors.Unauthorized("USER_NOT_FOUND", "用户不存在")
+	}
+	if !user.IsActive {
+		return nil, errors.Forbidden("ACCOUNT_DISABLED", "账户已被禁用")
+	}
+
+	company, err := s.companyUc.ResolveCompany(ctx, user.ID, req.CompanyID)
+	if err != nil {
+		return nil, convertAuthCompanyError(err)
+	}
+
+	roles, err := s.userUc.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		s.log.Errorf("Failed to get user roles: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "系统错误")
+	}
+
+	permissions, err := s.userUc.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		s.log.Errorf("Failed to get user permissions: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "系统错误")
+	}
+
+	roleStrs, roleExpiry := roleClaims(roles)
+
+	tokenDuration := time.Hour * 2 // 2小时
+	accessToken, err := s.jwtMgr.Generate(
+		int64(user.ID), user.Username, user.Email,
+		roleStrs, roleExpiry, permissions, currentUser.SessionID, "access", company.ID,
+	)
+	if err != nil {
+		s.log.Errorf("Failed to generate access token: %v", err)
+		return nil, errors.InternalServer("TOKEN_GENERATION_ERROR", "令牌生成失败")
+	}
+
+	// 原刷新令牌仍绑定原公司，一并签发新的刷新令牌
+	refreshToken, err := s.jwtMgr.GenerateRefreshToken(int64(user.ID), user.Username, currentUser.SessionID, company.ID)
+	if err != nil {
+		s.log.Errorf("Failed to generate refresh token: %v", err)
+		return nil, errors.InternalServer("TOKEN_GENERATION_ERROR", "令牌生成失败")
+	}
+
+	s.log.Infof("User %s switched to company %d", user.Username, company.ID)
+
+	return &SwitchCompanyResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(tokenDuration.Seconds()),
+		TokenType:    "Bearer",
+		Company:      company,
+	}, nil
+}
+
+// convertAuthCompanyError 转换登录、刷新令牌和切换公司时确定当前公司的错误
+func convertAuthCompanyError(err error) error {
+	if err == biz.ErrCompanyForbidden {
+		return errors.Forbidden("COMPANY_FORBIDDEN", "用户不属于该公司或公司已停用")
+	}
+	return errors.InternalServer("INTERNAL_ERROR", "系统错误")
+}
+
 // Logout 用户登出
 func (s *AuthService) Logout(ctx context.Context, req *LogoutRequest) error {
 	// 获取当前用户信息
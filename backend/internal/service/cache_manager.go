@@ -261,7 +261,7 @@ func (s *CacheManagerService) warmupFieldPermissionLevels(ctx context.Context, d
 	count := 0
 	for _, docType := range targetDocTypes {
 		// 获取文档类型的字段权限级别
-		levels, err := s.permissionUc.ListFieldPermissionLevels(ctx, docType, 1, 1000)
+		levels, err := biz.ListAllFieldPermissionLevels(ctx, s.permissionUc, docType)
 		if err != nil {
 			s.log.Warnf("Failed to get field permission levels for doctype %s: %v", docType, err)
 			continue
@@ -326,8 +326,18 @@ func (s *CacheManagerService) ClearCache(ctx context.Context, req *ClearCacheReq
 		}
 	}
 
-	// 按角色清除缓存
+	// 按角色清除缓存，继承该角色的下级角色一并清除
+	roleIDs := make([]int64, 0, len(req.RoleIDs))
 	for _, roleID := range req.RoleIDs {
+		roleIDs = append(roleIDs, roleID)
+		descendants, err := s.permissionUc.GetDescendantRoleIDs(ctx, roleID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("role %d descendants: %v", roleID, err))
+			continue
+		}
+		roleIDs = append(roleIDs, descendants...)
+	}
+	for _, roleID := range roleIDs {
 		if err := s.cache.ClearRoleCache(ctx, roleID); err != nil {
 			errs = append(errs, fmt.Sprintf("role %d: %v", roleID, err))
 		} else {
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// CompanyService 公司服务
+type CompanyService struct {
+	companyUc    *biz.CompanyUsecase
+	permissionUc *biz.PermissionUsecase
+	log          *log.Helper
+}
+
+// NewCompanyService 创建公司服务
+func NewCompanyService(companyUc *biz.CompanyUsecase, permissionUc *biz.PermissionUsecase, logger log.Logger) *CompanyService {
+	return &CompanyService{
+		companyUc:    companyUc,
+		permissionUc: permissionUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// CompanyRequest 创建/更新公司请求
+type CompanyRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Code        string `json:"code" validate:"required,max=50"`
+	Description string `json:"description"`
+	IsEnabled   *bool  `json:"is_enabled"`
+	// TemplateCompanyID 仅创建时有效，以该公司的权限规则作为新公司的初始权限配置
+	TemplateCompanyID int32 `json:"template_company_id"`
+}
+
+// AddCompanyUserRequest 将用户加入公司请求
+type AddCompanyUserRequest struct {
+	UserID int32 `json:"user_id" validate:"required"`
+}
+
+// ListCompaniesResponse 公司列表响应
+type ListCompaniesResponse struct {
+	Companies []*biz.Company `json:"companies"`
+	Total     int32          `json:"total"`
+}
+
+// ListUserCompaniesResponse 用户所属公司列表响应
+type ListUserCompaniesResponse struct {
+	Companies        []*biz.UserCompany `json:"companies"`
+	CurrentCompanyID int32              `json:"current_company_id,omitempty"` // 仅查询本人时返回当前令牌的公司
+}
+
+// CreateCompany 创建公司，创建人自动加入新公司
+func (s *CompanyService) CreateCompany(ctx context.Context, req *CompanyRequest) (*biz.Company, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限创建公司")
+	}
+
+	company := &biz.Company{Name: req.Name, Code: req.Code, Description: req.Description, IsEnabled: true}
+	if req.IsEnabled != nil {
+		company.IsEnabled = *req.IsEnabled
+	}
+
+	created, err := s.companyUc.CreateCompany(ctx, company, req.TemplateCompanyID, int32(currentUser.ID))
+	if err != nil {
+		return nil, s.convertError(err, "公司创建失败")
+	}
+
+	s.log.Infof("Company created successfully: %s", created.Code)
+	return created, nil
+}
+
+// UpdateCompany 更新公司，停用的公司不能再登录或切换进入
+func (s *CompanyService) UpdateCompany(ctx context.Context, id int32, req *CompanyRequest) (*biz.Company, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改公司")
+	}
+
+	company, err := s.companyUc.GetCompany(ctx, id)
+	if err != nil {
+		return nil, s.convertError(err, "获取公司失败")
+	}
+
+	changed := *company
+	changed.Name = req.Name
+	changed.Code = req.Code
+	changed.Description = req.Description
+	if req.IsEnabled != nil {
+		changed.IsEnabled = *req.IsEnabled
+	}
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "Company", company, &changed); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.companyUc.UpdateCompany(ctx, &changed)
+	if err != nil {
+		return nil, s.convertError(err, "公司更新失败")
+	}
+
+	s.log.Infof("Company updated successfully: %d", id)
+	return updated, nil
+}
+
+// GetCompany 获取公司详情，管理员只能查看当前公司
+func (s *CompanyService) GetCompany(ctx context.Context, id int32) (*biz.Company, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !canManageCompany(ctx, currentUser, id) {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看公司")
+	}
+
+	company, err := s.companyUc.GetCompany(ctx, id)
+	if err != nil {
+		return nil, s.convertError(err, "获取公司失败")
+	}
+	return company, nil
+}
+
+// ListCompanies 获取全部公司
+func (s *CompanyService) ListCompanies(ctx context.Context) (*ListCompaniesResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看公司")
+	}
+
+	companies, err := s.companyUc.ListCompanies(ctx)
+	if err != nil {
+		return nil, s.convertError(err, "获取公司列表失败")
+	}
+
+	return &ListCompaniesResponse{
+		Companies: companies,
+		Total:     int32(len(companies)),
+	}, nil
+}
+
+// ListCompanyUsers 获取公司的用户
+func (s *CompanyService) ListCompanyUsers(ctx context.Context, companyID int32) ([]*UserInfo, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !canManageCompany(ctx, currentUser, companyID) {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看公司用户")
+	}
+
+	users, err := s.companyUc.ListCompanyUsers(ctx, companyID)
+	if err != nil {
+		return nil, s.convertError(err, "获取公司用户失败")
+	}
+
+	infos := make([]*UserInfo, len(users))
+	for i, user := range users {
+		infos[i] = ToUserInfo(user, nil, nil)
+	}
+	return infos, nil
+}
+
+// AddCompanyUser 将用户加入公司，用户没有默认公司时该公司成为默认公司
+func (s *CompanyService) AddCompanyUser(ctx context.Context, companyID int32, req *AddCompanyUserRequest) (*biz.UserCompany, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !canManageCompany(ctx, currentUser, companyID) {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限管理公司用户")
+	}
+
+	membership, err := s.companyUc.AddUser(ctx, req.UserID, companyID, int32(currentUser.ID))
+	if err != nil {
+		return nil, s.convertError(err, "用户加入公司失败")
+	}
+
+	s.log.Infof("User %d added to company %d", req.UserID, companyID)
+	return membership, nil
+}
+
+// RemoveCompanyUser 将用户移出公司
+func (s *CompanyService) RemoveCompanyUser(ctx context.Context, companyID, userID int32) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !canManageCompany(ctx, currentUser, companyID) {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限管理公司用户")
+	}
+
+	if err := s.companyUc.RemoveUser(ctx, userID, companyID); err != nil {
+		return s.convertError(err, "用户移出公司失败")
+	}
+
+	s.log.Infof("User %d removed from company %d", userID, companyID)
+	return nil
+}
+
+// ListUserCompanies 获取用户所属的公司，用户可以查询自己所属的公司
+func (s *CompanyService) ListUserCompanies(ctx context.Context, userID int32) (*ListUserCompaniesResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	self := currentUser.ID == int64(userID)
+	if !self && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看用户所属公司")
+	}
+
+	companies, err := s.companyUc.ListUserCompanies(ctx, userID)
+	if err != nil {
+		return nil, s.convertError(err, "获取用户所属公司失败")
+	}
+
+	resp := &ListUserCompaniesResponse{Companies: companies}
+	if self {
+		resp.CurrentCompanyID = biz.CompanyFromContext(ctx)
+	}
+	return resp, nil
+}
+
+// SetDefaultCompany 设置用户登录时进入的默认公司，用户可以设置自己的默认公司
+func (s *CompanyService) SetDefaultCompany(ctx context.Context, userID, companyID int32) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if currentUser.ID != int64(userID) && !canManageCompany(ctx, currentUser, companyID) {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限设置用户默认公司")
+	}
+
+	if err := s.companyUc.SetDefaultCompany(ctx, userID, companyID); err != nil {
+		return s.convertError(err, "设置默认公司失败")
+	}
+	return nil
+}
+
+// canManageCompany 超级管理员可以管理全部公司的用户，管理员只能管理当前公司的用户
+func canManageCompany(ctx context.Context, currentUser *middleware.CurrentUser, companyID int32) bool {
+	if currentUser.HasRole("SUPER_ADMIN") {
+		return true
+	}
+	return currentUser.HasRole("ADMIN") && biz.CompanyFromContext(ctx) == companyID
+}
+
+// convertError 转换公司相关的业务错误
+func (s *CompanyService) convertError(err error, message string) error {
+	switch err {
+	case biz.ErrCompanyNotFound:
+		return errors.NotFound("COMPANY_NOT_FOUND", "公司不存在")
+	case biz.ErrCompanyCodeExists:
+		return errors.BadRequest("COMPANY_CODE_EXISTS", "公司编码已存在")
+	case biz.ErrInvalidCompany:
+		return errors.BadRequest("INVALID_COMPANY", "公司名称和编码不能为空，名称不能超过100个字符，编码不能超过50个字符")
+	case biz.ErrCompanyMemberNotFound:
+		return errors.NotFound("COMPANY_MEMBER_NOT_FOUND", "用户不属于该公司")
+	case biz.ErrCompanyMemberUser:
+		return errors.BadRequest("INVALID_COMPANY_MEMBER_USER", "公司成员必须是启用的用户")
+	case biz.ErrLastUserCompany:
+		return errors.BadRequest("LAST_USER_COMPANY", "用户至少需要属于一个公司")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
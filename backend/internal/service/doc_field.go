@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// DocFieldService 文档类型字段定义服务
+type DocFieldService struct {
+	docFieldUc   *biz.DocFieldUsecase
+	permissionUc *biz.PermissionUsecase
+	log          *log.Helper
+}
+
+// NewDocFieldService 创建字段定义服务
+func NewDocFieldService(docFieldUc *biz.DocFieldUsecase, permissionUc *biz.PermissionUsecase, logger log.Logger) *DocFieldService {
+	return &DocFieldService{
+		docFieldUc:   docFieldUc,
+		permissionUc: permissionUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// DocFieldRequest 创建/更新字段定义请求
+type DocFieldRequest struct {
+	FieldName       string `json:"field_name"`
+	Label           string `json:"label"`
+	FieldType       string `json:"field_type"`
+	Options         string `json:"options"`
+	IsMandatory     bool   `json:"is_mandatory"`
+	IsUnique        bool   `json:"is_unique"`
+	DefaultValue    string `json:"default"`
+	DependsOn       string `json:"depends_on"`
+	PermissionLevel int    `json:"permission_level"`
+	Idx             int    `json:"idx"`
+}
+
+// ListDocFieldsResponse 字段定义列表响应
+type ListDocFieldsResponse struct {
+	DocType string          `json:"doc_type"`
+	Fields  []*biz.DocField `json:"fields"`
+	Total   int32           `json:"total"`
+}
+
+// SyncFieldPermissionLevelsResponse 字段权限级别同步结果
+type SyncFieldPermissionLevelsResponse struct {
+	DocType string `json:"doc_type"`
+	Synced  int    `json:"synced"`
+}
+
+// ListFields 获取文档类型的字段定义
+func (s *DocFieldService) ListFields(ctx context.Context, docType string) (*ListDocFieldsResponse, error) {
+	fields, err := s.docFieldUc.ListFields(ctx, docType)
+	if err != nil {
+		return nil, s.convertError(err, "获取字段定义列表失败")
+	}
+
+	return &ListDocFieldsResponse{
+		DocType: docType,
+		Fields:  fields,
+		Total:   int32(len(fields)),
+	}, nil
+}
+
+// GetField 获取字段定义
+func (s *DocFieldService) GetField(ctx context.Context, docType, fieldName string) (*biz.DocField, error) {
+	field, err := s.docFieldUc.GetField(ctx, docType, fieldName)
+	if err != nil {
+		return nil, s.convertError(err, "获取字段定义失败")
+	}
+	return field, nil
+}
+
+// CreateField 创建字段定义
+func (s *DocFieldService) CreateField(ctx context.Context, docType string, req *DocFieldRequest) (*biz.DocField, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限创建字段定义")
+	}
+
+	field := &biz.DocField{DocType: docType, CreatedBy: &currentUser.ID}
+	req.apply(field)
+
+	created, err := s.docFieldUc.CreateField(ctx, field)
+	if err != nil {
+		return nil, s.convertError(err, "字段定义创建失败")
+	}
+
+	s.log.Infof("Doc field created successfully: %s.%s", docType, created.FieldName)
+	return created, nil
+}
+
+// UpdateField 更新字段定义，字段名称取自路径
+func (s *DocFieldService) UpdateField(ctx context.Context, docType, fieldName string, req *DocFieldRequest) (*biz.DocField, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改字段定义")
+	}
+	if req.FieldName != "" && req.FieldName != fieldName {
+		return nil, errors.BadRequest("INVALID_PARAMETER", "字段名称不可修改")
+	}
+
+	current, err := s.docFieldUc.GetField(ctx, docType, fieldName)
+	if err != nil {
+		return nil, s.convertError(err, "获取字段定义失败")
+	}
+
+	field := &biz.DocField{DocType: docType}
+	req.apply(field)
+	field.ID, field.FieldName = current.ID, fieldName
+	if field.Idx <= 0 {
+		field.Idx = current.Idx
+	}
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "DocField", current, field); err != nil {
+		return nil, err
+	}
+	field.UpdatedBy = &currentUser.ID
+
+	updated, err := s.docFieldUc.UpdateField(ctx, field)
+	if err != nil {
+		return nil, s.convertError(err, "字段定义更新失败")
+	}
+
+	s.log.Infof("Doc field updated successfully: %s.%s", docType, fieldName)
+	return updated, nil
+}
+
+// DeleteField 删除字段定义
+func (s *DocFieldService) DeleteField(ctx context.Context, docType, fieldName string) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限删除字段定义")
+	}
+
+	if err := s.docFieldUc.DeleteField(ctx, docType, fieldName); err != nil {
+		return s.convertError(err, "字段定义删除失败")
+	}
+
+	s.log.Infof("Doc field deleted successfully: %s.%s", docType, fieldName)
+	return nil
+}
+
+// SyncFieldPermissionLevels 按字段定义重新同步字段权限级别
+func (s *DocFieldService) SyncFieldPermissionLevels(ctx context.Context, docType string) (*SyncFieldPermissionLevelsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限同步字段权限级别")
+	}
+
+	synced, err := s.docFieldUc.SyncFieldPermissionLevels(ctx, docType)
+	if err != nil {
+		return nil, s.convertError(err, "字段权限级别同步失败")
+	}
+
+	return &SyncFieldPermissionLevelsResponse{DocType: docType, Synced: synced}, nil
+}
+
+// apply 将请求内容写入字段定义
+func (req *DocFieldRequest) apply(field *biz.DocField) {
+	field.FieldName = strings.TrimSpace(req.FieldName)
+	field.Label = req.Label
+	field.FieldType = req.FieldType
+	field.Options = req.Options
+	field.IsMandatory = req.IsMandatory
+	field.IsUnique = req.IsUnique
+	field.DefaultValue = req.DefaultValue
+	field.DependsOn = req.DependsOn
+	field.PermissionLevel = req.PermissionLevel
+	field.Idx = req.Idx
+}
+
+// convertError 将字段定义业务错误转换为API错误
+func (s *DocFieldService) convertError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, biz.ErrDocTypeNotFound):
+		return errors.NotFound("DOCTYPE_NOT_FOUND", "文档类型不存在")
+	case stderrors.Is(err, biz.ErrDocFieldNotFound):
+		return errors.NotFound("DOC_FIELD_NOT_FOUND", "字段定义不存在")
+	case stderrors.Is(err, biz.ErrDocFieldExists):
+		return errors.BadRequest("DOC_FIELD_EXISTS", "字段名称已存在")
+	case stderrors.Is(err, biz.ErrDocFieldInUse):
+		return errors.Conflict("DOC_FIELD_IN_USE", err.Error())
+	case stderrors.Is(err, biz.ErrInvalidDocField):
+		return errors.BadRequest("INVALID_DOC_FIELD", err.Error())
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// DocumentService 通用文档服务，所有操作都经过文档权限、数据范围和字段权限级别检查
+type DocumentService struct {
+	documentUc   *biz.DocumentUsecase
+	workflowUc   *biz.WorkflowUsecase
+	approvalUc   *biz.ApprovalUsecase
+	attachmentUc *biz.AttachmentUsecase
+	permissionUc *biz.PermissionUsecase
+	log          *log.Helper
+}
+
+// NewDocumentService 创建通用文档服务
+func NewDocumentService(documentUc *biz.DocumentUsecase, workflowUc *biz.WorkflowUsecase, approvalUc *biz.ApprovalUsecase, attachmentUc *biz.AttachmentUsecase, permissionUc *biz.PermissionUsecase, logger log.Logger) *DocumentService {
+	return &DocumentService{
+		documentUc:   documentUc,
+		workflowUc:   workflowUc,
+		approvalUc:   approvalUc,
+		attachmentUc: attachmentUc,
+		permissionUc: permissionUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// ListDocumentsRequest 文档列表请求
+type ListDocumentsRequest struct {
+	Filters map[string]interface{} `json:"filters"`
+	Search  string                 `json:"search"`
+	OrderBy string                 `json:"order_by"`
+	Order   string                 `json:"order"`
+	Page    int32                  `json:"page"`
+	Size    int32                  `json:"size"`
+}
+
+// ListDocuments 分页获取当前用户数据范围内的文档
+func (s *DocumentService) ListDocuments(ctx context.Context, docType string, req *ListDocumentsRequest) (*biz.DocumentList, error) {
+	access, err := s.access(ctx, docType, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.documentUc.ListDocuments(ctx, docType, &biz.DocumentQuery{
+		Filters: req.Filters,
+		Search:  req.Search,
+		OrderBy: strings.TrimSpace(req.OrderBy),
+		Order:   req.Order,
+		Page:    req.Page,
+		Size:    req.Size,
+	}, access)
+	if err != nil {
+		return nil, s.convertError(err, "获取文档列表失败")
+	}
+	return list, nil
+}
+
+// CreateDocument 创建文档
+func (s *DocumentService) CreateDocument(ctx context.Context, docType string, values map[string]interface{}) (map[string]interface{}, error) {
+	access, err := s.access(ctx, docType, "create")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documentUc.CreateDocument(ctx, docType, values, access)
+	if err != nil {
+		return nil, s.convertError(err, "文档创建失败")
+	}
+
+	s.log.Infof("Document created successfully: %s %v", docType, doc["name"])
+	return doc, nil
+}
+
+// GetDocument 获取文档
+func (s *DocumentService) GetDocument(ctx context.Context, docType, name string) (map[string]interface{}, error) {
+	access, err := s.access(ctx, docType, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documentUc.GetDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, s.convertError(err, "获取文档失败")
+	}
+	return doc, nil
+}
+
+// UpdateDocument 更新文档，仅写入请求中出现且发生变化的字段
+func (s *DocumentService) UpdateDocument(ctx context.Context, docType, name string, values map[string]interface{}) (map[string]interface{}, error) {
+	access, err := s.access(ctx, docType, "write")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documentUc.UpdateDocument(ctx, docType, name, values, access)
+	if err != nil {
+		return nil, s.convertError(err, "文档更新失败")
+	}
+
+	s.log.Infof("Document updated successfully: %s %s", docType, name)
+	return doc, nil
+}
+
+// DeleteDocument 删除文档
+func (s *DocumentService) DeleteDocument(ctx context.Context, docType, name string) error {
+	access, err := s.access(ctx, docType, "delete")
+	if err != nil {
+		return err
+	}
+
+	if err := s.documentUc.DeleteDocument(ctx, docType, name, access); err != nil {
+		return s.convertError(err, "文档删除失败")
+	}
+
+	s.log.Infof("Document deleted successfully: %s %s", docType, name)
+	return nil
+}
+
+// CancelDocumentRequest 取消文档请求
+type CancelDocumentRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SubmitDocument 提交文档
+func (s *DocumentService) SubmitDocument(ctx context.Context, docType, name string) (map[string]interface{}, error) {
+	access, err := s.access(ctx, docType, "submit")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documentUc.SubmitDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, s.convertError(err, "文档提交失败")
+	}
+
+	s.log.Infof("Document submitted successfully: %s %s", docType, name)
+	return doc, nil
+}
+
+// CancelDocument 取消已提交的文档
+func (s *DocumentService) CancelDocument(ctx context.Context, docType, name string, req *CancelDocumentRequest) (map[string]interface{}, error) {
+	access, err := s.access(ctx, docType, "cancel")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documentUc.CancelDocument(ctx, docType, name, req.Reason, access)
+	if err != nil {
+		return nil, s.convertError(err, "文档取消失败")
+	}
+
+	s.log.Infof("Document cancelled successfully: %s %s", docType, name)
+	return doc, nil
+}
+
+// AmendDocument 由已取消的文档创建修订版本
+func (s *DocumentService) AmendDocument(ctx context.Context, docType, name string) (map[string]interface{}, error) {
+	access, err := s.access(ctx, docType, "amend")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documentUc.AmendDocument(ctx, docType, name, access)
+	if err != nil {
+		return nil, s.convertError(err, "文档修订失败")
+	}
+
+	s.log.Infof("Document amended successfully: %s %s -> %v", docType, name, doc["name"])
+	return doc, nil
+}
+
+// ApplyTransitionRequest 执行工作流转换请求
+type ApplyTransitionRequest struct {
+	Action  string `json:"action" validate:"required"`
+	Comment string `json:"comment"`
+}
+
+// ListTransitions 获取文档当前状态下当前用户可执行的工作流转换
+func (s *DocumentService) ListTransitions(ctx context.Context, docType, name string) (*biz.DocumentTransitions, error) {
+	access, err := s.access(ctx, docType, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	transitions, err := s.workflowUc.ListTransitions(ctx, docType, name, access)
+	if err != nil {
+		return nil, s.convertError(err, "获取工作流转换失败")
+	}
+	return transitions, nil
+}
+
+// ApplyTransition 执行工作流转换，执行权限由转换限定的角色和条件决定
+func (s *DocumentService) ApplyTransition(ctx context.Context, docType, name string, req *ApplyTransitionRequest) (map[string]interface{}, error) {
+	access, err := s.access(ctx, docType, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.workflowUc.ApplyTransition(ctx, docType, name, req.Action, req.Comment, access)
+	if err != nil {
+		return nil, s.convertError(err, "工作流转换失败")
+	}
+
+	s.log.Infof("Workflow transition applied successfully: %s %s %s", docType, name, req.Action)
+	return doc, nil
+}
+
+// ApprovalCommentRequest 发起或撤回审批请求
+type ApprovalCommentRequest struct {
+	Comment string `json:"comment"`
+}
+
+// ListApprovalsResponse 文档审批历史响应
+type ListApprovalsResponse struct {
+	Approvals []*biz.ApprovalRequest `json:"approvals"`
+	Total     int32                  `json:"total"`
+}
+
+// ListApprovals 获取文档的审批历史
+func (s *DocumentService) ListApprovals(ctx context.Context, docType, name string) (*ListApprovalsResponse, error) {
+	access, err := s.access(ctx, docType, "read")
+	if err != nil {
+		return nil, err
+	}
+
+	approvals, err := s.approvalUc.ListDocumentApprovals(ctx, docType, name, access)
+	if err != nil {
+		return nil, s.convertError(err, "获取审批历史失败")
+	}
+	return &ListApprovalsResponse{Approvals: approvals, Total: int32(len(approvals))}, nil
+}
+
+// RequestApproval 为草稿文档发起审批，审批全部通过后文档自动提交
+func (s *DocumentService) RequestApproval(ctx context.Context, docType, name string, req *ApprovalCommentRequest) (*biz.ApprovalRequest, error) {
+	access, err := s.access(ctx, docType, "submit")
+	if err != nil {
+		return nil, err
+	}
+
+	approval, err := s.approvalUc.RequestApproval(ctx, docType, name, req.Comment, access)
+	if err != nil {
+		return nil, s.convertError(err, "发起审批失败")
+	}
+
+	s.log.Infof("Approval requested successfully: %s %s", docType, name)
+	return approval, nil
+}
+
+// CancelApproval 撤回文档进行中的审批，管理员可撤回他人发起的审批
+func (s *DocumentService) CancelApproval(ctx context.Context, docType, name string, req *ApprovalCommentRequest) (*biz.ApprovalRequest, error) {
+	access, err := s.access(ctx, docType, "submit")
+	if err != nil {
+		return nil, err
+	}
+	override := middleware.GetCurrentUser(ctx).HasAnyRole("SUPER_ADMIN", "ADMIN")
+
+	approval, err := s.approvalUc.CancelApproval(ctx, docType, name, req.Comment, access, override)
+	if err != nil {
+		return nil, s.convertError(err, "撤回审批失败")
+	}
+
+	s.log.Infof("Approval cancelled successfully: %s %s", docType, name)
+	return approval, nil
+}
+
+// SyncDocumentTable 按字段定义迁移文档类型的数据表
+func (s *DocumentService) SyncDocumentTable(ctx context.Context, docType string) (*biz.DocumentTable, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限迁移文档数据表")
+	}
+
+	table, err := s.documentUc.SyncTable(ctx, docType)
+	if err != nil {
+		return nil, s.convertError(err, "文档数据表迁移失败")
+	}
+	return table, nil
+}
+
+// access 检查当前用户对文档类型的操作权限，并构造数据范围、字段写入检查和字段过滤
+func (s *DocumentService) access(ctx context.Context, docType, action string) (*biz.DocumentAccess, error) {
+	userID := middleware.GetCurrentUser(ctx).ID
+
+	// 带条件和仅创建者可访问的规则也授予操作，可访问的文档由数据范围限定
+	scope, err := s.permissionUc.GetPermissionScope(ctx, userID, docType, action, 0)
+	if err != nil {
+		s.log.Errorf("Failed to get permission scope: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "权限检查失败")
+	}
+	if scope.Denied() {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限"+documentActionLabels[action]+"文档: "+docType)
+	}
+
+	return &biz.DocumentAccess{
+		UserID: userID,
+		Scope:  scope,
+		GuardWrites: func(ctx context.Context, fields []string) ([]string, error) {
+			return s.permissionUc.GuardFieldWrites(ctx, userID, docType, fields)
+		},
+		UnreadableFields: func(ctx context.Context, fields []string) ([]string, error) {
+			return s.permissionUc.UnreadableFields(ctx, userID, docType, fields)
+		},
+		MaskFields: func(ctx context.Context, docs []map[string]interface{}) ([]map[string]interface{}, error) {
+			return s.permissionUc.FilterDocumentsByPermission(ctx, userID, docType, docs)
+		},
+	}, nil
+}
+
+// documentActionLabels 权限操作的中文名称
+var documentActionLabels = map[string]string{
+	"read":   "查看",
+	"create": "创建",
+	"write":  "修改",
+	"delete": "删除",
+	"submit": "提交",
+	"cancel": "取消",
+	"amend":  "修订",
+}
+
+// convertError 将通用文档业务错误转换为API错误
+func (s *DocumentService) convertError(err error, message string) error {
+	if apiErr := convertApprovalError(err); apiErr != nil {
+		return apiErr
+	}
+	if apiErr := convertAttachmentError(err); apiErr != nil {
+		return apiErr
+	}
+	var forbiddenErr *biz.FieldWriteForbiddenError
+	switch {
+	case stderrors.As(err, &forbiddenErr):
+		return errors.Forbidden("FIELD_WRITE_FORBIDDEN", "无权限修改字段: "+strings.Join(forbiddenErr.Fields, ", ")).
+			WithMetadata(map[string]string{"details": strings.Join(forbiddenErr.Fields, ",")})
+	case stderrors.Is(err, biz.ErrDocTypeNotFound):
+		return errors.NotFound("DOCTYPE_NOT_FOUND", "文档类型不存在")
+	case stderrors.Is(err, biz.ErrDocTypeNotStorable):
+		return errors.BadRequest("DOCTYPE_NOT_STORABLE", err.Error())
+	case stderrors.Is(err, biz.ErrDocTypeNotSubmittable):
+		return errors.BadRequest("DOCTYPE_NOT_SUBMITTABLE", err.Error())
+	case stderrors.Is(err, biz.ErrDocumentNotFound):
+		return errors.NotFound("DOCUMENT_NOT_FOUND", "文档不存在")
+	case stderrors.Is(err, biz.ErrDocumentExists):
+		return errors.Conflict("DOCUMENT_EXISTS", "文档名称已存在")
+	case stderrors.Is(err, biz.ErrDocumentForbidden):
+		return errors.Forbidden("PERMISSION_DENIED", "文档不在当前用户的数据范围内")
+	case stderrors.Is(err, biz.ErrDocumentNotEditable):
+		return errors.Conflict("DOCUMENT_NOT_EDITABLE", err.Error())
+	case stderrors.Is(err, biz.ErrInvalidDocumentTransition):
+		return errors.Conflict("INVALID_DOCUMENT_TRANSITION", err.Error())
+	case stderrors.Is(err, biz.ErrDocumentUnderWorkflow):
+		return errors.Conflict("DOCUMENT_UNDER_WORKFLOW", err.Error())
+	case stderrors.Is(err, biz.ErrWorkflowNotFound):
+		return errors.NotFound("WORKFLOW_NOT_FOUND", err.Error())
+	case stderrors.Is(err, biz.ErrWorkflowTransitionNotAllowed):
+		return errors.Forbidden("WORKFLOW_TRANSITION_NOT_ALLOWED", err.Error())
+	case stderrors.Is(err, biz.ErrInvalidDocument):
+		return errors.BadRequest("INVALID_DOCUMENT", err.Error())
+	case stderrors.Is(err, biz.ErrInvalidDocumentListQuery):
+		return errors.BadRequest("INVALID_PARAMETER", err.Error())
+	case stderrors.Is(err, biz.ErrDocumentTableConflict), stderrors.Is(err, biz.ErrDocumentTableMigration):
+		return errors.Conflict("DOCUMENT_TABLE_MIGRATION_FAILED", err.Error())
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
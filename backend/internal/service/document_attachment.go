@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"erp-system/internal/biz"
+)
+
+// UploadAttachment 上传文档附件，需要文档的修改权限且文档在当前用户的数据范围内
+func (s *DocumentService) UploadAttachment(ctx context.Context, docType, name, fileName string, file io.Reader) (*biz.Attachment, error) {
+	access, err := s.access(ctx, docType, "write")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.documentUc.GetDocument(ctx, docType, name, access); err != nil {
+		return nil, s.convertError(err, "附件上传失败")
+	}
+
+	attachment, err := s.attachmentUc.UploadAttachment(ctx, docType, name, fileName, file, access.UserID)
+	if err != nil {
+		return nil, s.convertError(err, "附件上传失败")
+	}
+
+	s.log.Infof("Attachment %d uploaded to %s %s", attachment.ID, docType, name)
+	return attachment, nil
+}
+
+// ListAttachments 获取文档附件及其签名下载链接，需要文档的查看权限
+func (s *DocumentService) ListAttachments(ctx context.Context, docType, name string) ([]*biz.Attachment, error) {
+	access, err := s.access(ctx, docType, "read")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.documentUc.GetDocument(ctx, docType, name, access); err != nil {
+		return nil, s.convertError(err, "附件列表获取失败")
+	}
+
+	attachments, err := s.attachmentUc.ListAttachments(ctx, docType, name)
+	if err != nil {
+		return nil, s.convertError(err, "附件列表获取失败")
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment 删除文档附件，需要文档的修改权限
+func (s *DocumentService) DeleteAttachment(ctx context.Context, docType, name string, id int64) error {
+	access, err := s.access(ctx, docType, "write")
+	if err != nil {
+		return err
+	}
+	if _, err := s.documentUc.GetDocument(ctx, docType, name, access); err != nil {
+		return s.convertError(err, "附件删除失败")
+	}
+
+	if err := s.attachmentUc.DeleteAttachment(ctx, docType, name, id); err != nil {
+		return s.convertError(err, "附件删除失败")
+	}
+
+	s.log.Infof("Attachment %d deleted from %s %s", id, docType, name)
+	return nil
+}
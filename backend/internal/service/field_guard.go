@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// fieldUpdates 待写入字段及其赋值函数，键为field_permission_levels中登记的字段名
+type fieldUpdates map[string]func()
+
+// applyFieldUpdates 按当前用户的字段写权限执行更新
+// 仅对实际发生变化的字段调用，越权字段根据DocType配置拒绝整个请求或被静默丢弃
+func applyFieldUpdates(ctx context.Context, permissionUc *biz.PermissionUsecase, logger *log.Helper, docType string, updates fieldUpdates) error {
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	currentUser := middleware.GetCurrentUser(ctx)
+	dropped, err := permissionUc.GuardFieldWrites(ctx, currentUser.ID, docType, fields)
+	if err != nil {
+		var forbiddenErr *biz.FieldWriteForbiddenError
+		if stderrors.As(err, &forbiddenErr) {
+			return errors.Forbidden("FIELD_WRITE_FORBIDDEN", "无权限修改字段: "+strings.Join(forbiddenErr.Fields, ", ")).
+				WithMetadata(map[string]string{"details": strings.Join(forbiddenErr.Fields, ",")})
+		}
+		logger.Errorf("Failed to check field write permission: %v", err)
+		return errors.InternalServer("INTERNAL_ERROR", "字段权限检查失败")
+	}
+
+	skip := make(map[string]bool, len(dropped))
+	for _, field := range dropped {
+		skip[field] = true
+	}
+
+	for _, field := range fields {
+		if !skip[field] {
+			updates[field]()
+		}
+	}
+
+	return nil
+}
+
+// guardIgnoredFields 由系统维护的字段，不参与字段写权限检查
+var guardIgnoredFields = map[string]bool{
+	"id": true, "version": true, "created_at": true, "updated_at": true, "created_by": true, "updated_by": true,
+}
+
+// applyFieldChanges 按当前用户的字段写权限执行整体替换式更新
+// current为更新前的记录，updated为按请求构造的新记录（同一结构体类型的指针），按JSON字段名比较出变化的字段；
+// 越权字段根据DocType配置拒绝整个请求，或在updated中恢复为原值
+func applyFieldChanges(ctx context.Context, permissionUc *biz.PermissionUsecase, logger *log.Helper, docType string, current, updated interface{}) error {
+	before := reflect.ValueOf(current).Elem()
+	after := reflect.ValueOf(updated).Elem()
+
+	updates := fieldUpdates{}
+	applied := make(map[int]bool)
+	for i := 0; i < after.NumField(); i++ {
+		name := guardFieldName(after.Type().Field(i))
+		if name == "" || reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			continue
+		}
+		index := i
+		updates[name] = func() { applied[index] = true }
+	}
+	if err := applyFieldUpdates(ctx, permissionUc, logger, docType, updates); err != nil {
+		return err
+	}
+
+	for i := 0; i < after.NumField(); i++ {
+		if name := guardFieldName(after.Type().Field(i)); updates[name] != nil && !applied[i] {
+			after.Field(i).Set(before.Field(i))
+		}
+	}
+	return nil
+}
+
+// guardFieldName 结构体字段对应的字段名，取JSON标签；不导出、不序列化和系统维护的字段返回空
+func guardFieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" || guardIgnoredFields[name] {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// NamingSeriesService 编号序列计数器服务
+type NamingSeriesService struct {
+	namingUc *biz.NamingSeriesUsecase
+	log      *log.Helper
+}
+
+// NewNamingSeriesService 创建编号序列计数器服务
+func NewNamingSeriesService(namingUc *biz.NamingSeriesUsecase, logger log.Logger) *NamingSeriesService {
+	return &NamingSeriesService{
+		namingUc: namingUc,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// SetNamingSeriesRequest 设置计数器请求
+type SetNamingSeriesRequest struct {
+	Prefix  string `json:"prefix"`
+	Current int64  `json:"current"`
+}
+
+// ListNamingSeriesResponse 计数器列表响应
+type ListNamingSeriesResponse struct {
+	Series []*biz.NamingSeries `json:"series"`
+	Total  int32               `json:"total"`
+}
+
+// ListSeries 获取以prefix开头的计数器
+func (s *NamingSeriesService) ListSeries(ctx context.Context, prefix string) (*ListNamingSeriesResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看编号序列")
+	}
+
+	series, err := s.namingUc.ListSeries(ctx, prefix)
+	if err != nil {
+		return nil, s.convertError(err, "获取编号序列失败")
+	}
+	return &ListNamingSeriesResponse{Series: series, Total: int32(len(series))}, nil
+}
+
+// SetSeriesCurrent 设置计数器的当前值
+func (s *NamingSeriesService) SetSeriesCurrent(ctx context.Context, req *SetNamingSeriesRequest) (*biz.NamingSeries, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改编号序列")
+	}
+
+	series, err := s.namingUc.SetSeriesCurrent(ctx, req.Prefix, req.Current)
+	if err != nil {
+		return nil, s.convertError(err, "编号序列修改失败")
+	}
+
+	s.log.Infof("Naming series %s set to %d by user %d", series.Prefix, series.Current, currentUser.ID)
+	return series, nil
+}
+
+// convertError 将编号序列业务错误转换为API错误
+func (s *NamingSeriesService) convertError(err error, message string) error {
+	if stderrors.Is(err, biz.ErrNamingSeriesInvalid) {
+		return errors.BadRequest("INVALID_NAMING_SERIES", err.Error())
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
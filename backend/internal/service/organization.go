@@ -13,15 +13,17 @@ import (
 
 // OrganizationService 组织服务
 type OrganizationService struct {
-	orgUc *biz.OrganizationUsecase
-	log   *log.Helper
+	orgUc        *biz.OrganizationUsecase
+	permissionUc *biz.PermissionUsecase
+	log          *log.Helper
 }
 
 // NewOrganizationService 创建组织服务
-func NewOrganizationService(orgUc *biz.OrganizationUsecase, logger log.Logger) *OrganizationService {
+func NewOrganizationService(orgUc *biz.OrganizationUsecase, permissionUc *biz.PermissionUsecase, logger log.Logger) *OrganizationService {
 	return &OrganizationService{
-		orgUc: orgUc,
-		log:   log.NewHelper(logger),
+		orgUc:        orgUc,
+		permissionUc: permissionUc,
+		log:          log.NewHelper(logger),
 	}
 }
 
@@ -45,6 +47,16 @@ type UpdateOrganizationRequest struct {
 	SortOrder   int32  `json:"sort_order"`
 }
 
+// SetOrganizationLeaderRequest 设置组织负责人请求，LeaderID为空时清除负责人
+type SetOrganizationLeaderRequest struct {
+	LeaderID *int32 `json:"leader_id"`
+}
+
+// MoveOrganizationRequest 移动组织请求，TargetParentID为空或0时移为顶级组织
+type MoveOrganizationRequest struct {
+	TargetParentID *int32 `json:"target_parent_id"`
+}
+
 // AssignUsersRequest 分配用户请求
 type AssignUsersRequest struct {
 	OrganizationID int32   `json:"organization_id" validate:"required"`
@@ -58,8 +70,13 @@ type OrganizationInfo struct {
 	Name        string              `json:"name"`
 	Code        string              `json:"code"`
 	Description string              `json:"description"`
+	OrgType     string              `json:"org_type,omitempty"`
 	IsEnabled   bool                `json:"is_enabled"`
 	SortOrder   int32               `json:"sort_order"`
+	LeaderID    *int32              `json:"leader_id,omitempty"`
+	Level       int32               `json:"level,omitempty"`
+	Path        string              `json:"path,omitempty"`
+	Depth       int32               `json:"depth,omitempty"`
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 	Children    []*OrganizationInfo `json:"children,omitempty"`
@@ -159,6 +176,7 @@ func (s *OrganizationService) GetOrganization(ctx context.Context, orgID int32)
 		Description: org.Description,
 		IsEnabled:   org.IsEnabled,
 		SortOrder:   org.SortOrder,
+		LeaderID:    org.LeaderID,
 		CreatedAt:   org.CreatedAt,
 		UpdatedAt:   org.UpdatedAt,
 		Users:       userInfos,
@@ -182,15 +200,32 @@ func (s *OrganizationService) UpdateOrganization(ctx context.Context, req *Updat
 	}
 
 	// 更新组织信息
-	org.ParentID = req.ParentID
-	org.Name = req.Name
-	org.Description = req.Description
-	org.IsEnabled = req.IsEnabled
-	org.SortOrder = req.SortOrder
+	updates := fieldUpdates{}
+	if !biz.SameParentID(req.ParentID, org.ParentID) {
+		updates["parent_id"] = func() { org.ParentID = req.ParentID }
+	}
+	if req.Name != org.Name {
+		updates["name"] = func() { org.Name = req.Name }
+	}
+	if req.Description != org.Description {
+		updates["description"] = func() { org.Description = req.Description }
+	}
+	if req.IsEnabled != org.IsEnabled {
+		updates["is_enabled"] = func() { org.IsEnabled = req.IsEnabled }
+	}
+	if req.SortOrder != org.SortOrder {
+		updates["sort_order"] = func() { org.SortOrder = req.SortOrder }
+	}
+	if err := applyFieldUpdates(ctx, s.permissionUc, s.log, "Organization", updates); err != nil {
+		return nil, err
+	}
 	org.UpdatedAt = time.Now()
 
 	updatedOrg, err := s.orgUc.UpdateOrganization(ctx, org)
 	if err != nil {
+		if treeErr := convertOrganizationError(err); treeErr != nil {
+			return nil, treeErr
+		}
 		s.log.Errorf("Failed to update organization: %v", err)
 		return nil, errors.InternalServer("INTERNAL_ERROR", "组织更新失败")
 	}
@@ -221,7 +256,7 @@ func (s *OrganizationService) DeleteOrganization(ctx context.Context, orgID int3
 	s.log.Infof("Deleting organization: %d by %s", orgID, currentUser.Username)
 
 	// 删除组织
-	if err := s.orgUc.DeleteOrganization(ctx, orgID); err != nil {
+	if err := s.orgUc.DeleteOrganization(ctx, orgID, int32(currentUser.ID)); err != nil {
 		s.log.Errorf("Failed to delete organization: %v", err)
 		if err == biz.ErrOrganizationHasChildren {
 			return errors.BadRequest("ORGANIZATION_HAS_CHILDREN", "组织存在子组织，无法删除")
@@ -271,7 +306,10 @@ func (s *OrganizationService) AssignUsers(ctx context.Context, req *AssignUsersR
 	}
 
 	// 分配用户
-	if err := s.orgUc.AssignUsers(ctx, req.OrganizationID, req.UserIDs); err != nil {
+	if err := s.orgUc.AssignUsers(ctx, req.OrganizationID, req.UserIDs, int32(currentUser.ID)); err != nil {
+		if orgErr := convertOrganizationError(err); orgErr != nil {
+			return orgErr
+		}
 		s.log.Errorf("Failed to assign users: %v", err)
 		return errors.InternalServer("INTERNAL_ERROR", "用户分配失败")
 	}
@@ -280,6 +318,109 @@ func (s *OrganizationService) AssignUsers(ctx context.Context, req *AssignUsersR
 	return nil
 }
 
+// SetOrganizationLeader 设置组织负责人
+func (s *OrganizationService) SetOrganizationLeader(ctx context.Context, orgID int32, req *SetOrganizationLeaderRequest) error {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限设置组织负责人")
+	}
+
+	s.log.Infof("Setting leader of organization: %d by %s", orgID, currentUser.Username)
+
+	if err := s.orgUc.SetLeader(ctx, orgID, req.LeaderID, int32(currentUser.ID)); err != nil {
+		if orgErr := convertOrganizationError(err); orgErr != nil {
+			return orgErr
+		}
+		s.log.Errorf("Failed to set organization leader: %v", err)
+		return errors.InternalServer("INTERNAL_ERROR", "组织负责人设置失败")
+	}
+
+	s.log.Infof("Organization leader set successfully: %d", orgID)
+	return nil
+}
+
+// MoveOrganization 将组织连同其下级组织移动到目标上级组织下
+func (s *OrganizationService) MoveOrganization(ctx context.Context, orgID int32, req *MoveOrganizationRequest) error {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限移动组织")
+	}
+
+	s.log.Infof("Moving organization: %d by %s", orgID, currentUser.Username)
+
+	targetParentID := req.TargetParentID
+	if targetParentID != nil && *targetParentID == 0 {
+		targetParentID = nil
+	}
+
+	// 移动组织即修改上级组织，遵循parent_id的字段写权限
+	move := false
+	updates := fieldUpdates{"parent_id": func() { move = true }}
+	if err := applyFieldUpdates(ctx, s.permissionUc, s.log, "Organization", updates); err != nil {
+		return err
+	}
+	if !move {
+		return nil
+	}
+
+	if err := s.orgUc.MoveOrganization(ctx, orgID, targetParentID); err != nil {
+		if treeErr := convertOrganizationError(err); treeErr != nil {
+			return treeErr
+		}
+		s.log.Errorf("Failed to move organization: %v", err)
+		return errors.InternalServer("INTERNAL_ERROR", "组织移动失败")
+	}
+
+	s.log.Infof("Organization moved successfully: %d", orgID)
+	return nil
+}
+
+// GetOrganizationPath 获取从顶级组织到该组织的路径
+func (s *OrganizationService) GetOrganizationPath(ctx context.Context, orgID int32) ([]*OrganizationInfo, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看组织")
+	}
+
+	path, err := s.orgUc.GetOrganizationPath(ctx, orgID)
+	if err != nil {
+		if treeErr := convertOrganizationError(err); treeErr != nil {
+			return nil, treeErr
+		}
+		s.log.Errorf("Failed to get organization path: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "组织路径获取失败")
+	}
+
+	return s.convertToOrganizationInfos(path), nil
+}
+
+// GetChildOrganizations 获取子组织，recursive为true时返回全部下级组织及其相对深度
+func (s *OrganizationService) GetChildOrganizations(ctx context.Context, orgID int32, recursive, onlyEnabled bool) ([]*OrganizationInfo, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看组织")
+	}
+
+	children, err := s.orgUc.GetChildOrganizations(ctx, orgID, recursive, onlyEnabled)
+	if err != nil {
+		if treeErr := convertOrganizationError(err); treeErr != nil {
+			return nil, treeErr
+		}
+		s.log.Errorf("Failed to get child organizations: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "子组织获取失败")
+	}
+
+	infos := s.convertToOrganizationInfos(children)
+	if infos == nil {
+		infos = []*OrganizationInfo{}
+	}
+	return infos, nil
+}
+
 // GetEnabledOrganizations 获取启用的组织列表
 func (s *OrganizationService) GetEnabledOrganizations(ctx context.Context) ([]*OrganizationInfo, error) {
 	// 检查权限
@@ -313,8 +454,13 @@ func (s *OrganizationService) convertToOrganizationInfos(orgs []*biz.Organizatio
 			Name:        org.Name,
 			Code:        org.Code,
 			Description: org.Description,
+			OrgType:     org.OrgType,
 			IsEnabled:   org.IsEnabled,
 			SortOrder:   org.SortOrder,
+			LeaderID:    org.LeaderID,
+			Level:       org.Level,
+			Path:        org.Path,
+			Depth:       org.Depth,
 			CreatedAt:   org.CreatedAt,
 			UpdatedAt:   org.UpdatedAt,
 			Children:    s.convertToOrganizationInfos(org.Children),
@@ -344,3 +490,34 @@ func (s *OrganizationService) convertToOrganizationInfos(orgs []*biz.Organizatio
 
 	return infos
 }
+
+// convertOrganizationError 转换组织结构和组织成员相关的业务错误，其他错误返回nil
+func convertOrganizationError(err error) error {
+	switch err {
+	case biz.ErrOrganizationNotFound:
+		return errors.NotFound("ORGANIZATION_NOT_FOUND", "组织不存在")
+	case biz.ErrParentOrgNotFound:
+		return errors.BadRequest("PARENT_ORGANIZATION_NOT_FOUND", "上级组织不存在")
+	case biz.ErrOrganizationCycle:
+		return errors.BadRequest("ORGANIZATION_CYCLE", "不能将组织移动到自身或其下级组织下")
+	case biz.ErrOrganizationTooDeep:
+		return errors.BadRequest("ORGANIZATION_TOO_DEEP", "组织层级过深")
+	case biz.ErrOrganizationForbidden:
+		return errors.Forbidden("ORGANIZATION_FORBIDDEN", "无权限访问该组织")
+	case biz.ErrInvalidOrganizationSearch:
+		return errors.BadRequest("INVALID_PARAMETER", "搜索关键字不能为空")
+	case biz.ErrOrganizationLeader:
+		return errors.BadRequest("INVALID_ORGANIZATION_LEADER", "组织负责人必须是组织中启用的成员")
+	case biz.ErrOrganizationMemberExists:
+		return errors.BadRequest("ORGANIZATION_MEMBER_EXISTS", "用户已是组织成员")
+	case biz.ErrOrganizationMemberNotFound:
+		return errors.NotFound("ORGANIZATION_MEMBER_NOT_FOUND", "组织成员不存在")
+	case biz.ErrOrganizationMemberUser:
+		return errors.BadRequest("INVALID_ORGANIZATION_MEMBER_USER", "组织成员必须是启用的用户")
+	case biz.ErrInvalidOrganizationMember:
+		return errors.BadRequest("INVALID_ORGANIZATION_MEMBER", "职位不能超过50个字符，离开原因不能超过500个字符，离开时间不能早于加入时间")
+	case biz.ErrPrimaryOrganizationRequired:
+		return errors.BadRequest("PRIMARY_ORGANIZATION_REQUIRED", "请将其他组织设为主组织来更换主组织")
+	}
+	return nil
+}
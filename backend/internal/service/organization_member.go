@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// AddOrganizationMemberRequest 添加组织成员请求，用户没有主组织时忽略IsPrimary直接设为主组织
+type AddOrganizationMemberRequest struct {
+	UserID    int32      `json:"user_id" validate:"required"`
+	Position  string     `json:"position"`
+	IsPrimary bool       `json:"is_primary"`
+	IsLeader  bool       `json:"is_leader"`
+	JoinedAt  *time.Time `json:"joined_at"` // 省略时为当前时间
+}
+
+// AssignUserOrganizationRequest 将用户加入组织的请求，与AddOrganizationMemberRequest相同但从用户一侧指定组织
+type AssignUserOrganizationRequest struct {
+	OrganizationID int32      `json:"organization_id" validate:"required"`
+	Position       string     `json:"position"`
+	IsPrimary      bool       `json:"is_primary"`
+	IsLeader       bool       `json:"is_leader"`
+	JoinedAt       *time.Time `json:"joined_at"` // 省略时为当前时间
+}
+
+// UpdateOrganizationMemberRequest 调整组织成员请求，省略的字段保持不变
+type UpdateOrganizationMemberRequest struct {
+	Position  *string `json:"position"`
+	IsPrimary *bool   `json:"is_primary"`
+	IsLeader  *bool   `json:"is_leader"`
+}
+
+// RemoveOrganizationMemberRequest 移除组织成员请求
+type RemoveOrganizationMemberRequest struct {
+	LeftAt *time.Time `json:"left_at"` // 省略时为当前时间
+	Reason string     `json:"reason"`
+}
+
+// ListOrganizationMembersResponse 组织成员列表响应
+type ListOrganizationMembersResponse struct {
+	Members []*biz.OrganizationMember `json:"members"`
+	Total   int32                     `json:"total"`
+}
+
+// ListMemberHistoryResponse 组织成员变动记录响应
+type ListMemberHistoryResponse struct {
+	History []*biz.OrganizationMemberHistory `json:"history"`
+	Total   int32                            `json:"total"`
+}
+
+// ListOrganizationMembers 获取组织成员，includeInactive为true时包含已离开的成员
+func (s *OrganizationService) ListOrganizationMembers(ctx context.Context, orgID int32, includeInactive bool) (*ListOrganizationMembersResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看组织成员")
+	}
+
+	members, err := s.orgUc.ListMembers(ctx, orgID, includeInactive)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织成员获取失败")
+	}
+
+	return &ListOrganizationMembersResponse{Members: members, Total: int32(len(members))}, nil
+}
+
+// AddOrganizationMember 添加组织成员
+func (s *OrganizationService) AddOrganizationMember(ctx context.Context, orgID int32, req *AddOrganizationMemberRequest) (*biz.OrganizationMember, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限添加组织成员")
+	}
+
+	s.log.Infof("Adding user %d to organization %d by %s", req.UserID, orgID, currentUser.Username)
+
+	member := &biz.OrganizationMember{
+		UserID:    req.UserID,
+		OrgID:     orgID,
+		Position:  req.Position,
+		IsPrimary: req.IsPrimary,
+		IsLeader:  req.IsLeader,
+	}
+	if req.JoinedAt != nil {
+		member.JoinedAt = *req.JoinedAt
+	}
+
+	added, err := s.orgUc.AddMember(ctx, member, int32(currentUser.ID))
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织成员添加失败")
+	}
+
+	s.log.Infof("Organization member added successfully: user %d, organization %d", req.UserID, orgID)
+	return added, nil
+}
+
+// AssignUserOrganization 将用户加入组织，权限和校验与添加组织成员一致
+func (s *OrganizationService) AssignUserOrganization(ctx context.Context, userID int32, req *AssignUserOrganizationRequest) (*biz.OrganizationMember, error) {
+	if req.OrganizationID <= 0 {
+		return nil, errors.BadRequest("INVALID_ORGANIZATION_ID", "组织ID无效")
+	}
+
+	return s.AddOrganizationMember(ctx, req.OrganizationID, &AddOrganizationMemberRequest{
+		UserID:    userID,
+		Position:  req.Position,
+		IsPrimary: req.IsPrimary,
+		IsLeader:  req.IsLeader,
+		JoinedAt:  req.JoinedAt,
+	})
+}
+
+// UpdateOrganizationMember 调整组织成员的职位、主组织或负责人身份
+func (s *OrganizationService) UpdateOrganizationMember(ctx context.Context, orgID, userID int32, req *UpdateOrganizationMemberRequest) (*biz.OrganizationMember, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改组织成员")
+	}
+
+	s.log.Infof("Updating member %d of organization %d by %s", userID, orgID, currentUser.Username)
+
+	update := &biz.OrganizationMemberUpdate{}
+	updates := fieldUpdates{}
+	if req.Position != nil {
+		updates["position"] = func() { update.Position = req.Position }
+	}
+	if req.IsPrimary != nil {
+		updates["is_primary"] = func() { update.IsPrimary = req.IsPrimary }
+	}
+	if req.IsLeader != nil {
+		updates["is_leader"] = func() { update.IsLeader = req.IsLeader }
+	}
+	if err := applyFieldUpdates(ctx, s.permissionUc, s.log, "Organization Member", updates); err != nil {
+		return nil, err
+	}
+
+	member, err := s.orgUc.UpdateMember(ctx, orgID, userID, update, int32(currentUser.ID))
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织成员修改失败")
+	}
+
+	s.log.Infof("Organization member updated successfully: user %d, organization %d", userID, orgID)
+	return member, nil
+}
+
+// RemoveOrganizationMember 移除组织成员，保留成员关系记录
+func (s *OrganizationService) RemoveOrganizationMember(ctx context.Context, orgID, userID int32, req *RemoveOrganizationMemberRequest) error {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限移除组织成员")
+	}
+
+	s.log.Infof("Removing member %d from organization %d by %s", userID, orgID, currentUser.Username)
+
+	var leftAt time.Time
+	if req.LeftAt != nil {
+		leftAt = *req.LeftAt
+	}
+	if err := s.orgUc.RemoveMember(ctx, orgID, userID, leftAt, req.Reason, int32(currentUser.ID)); err != nil {
+		return s.convertMemberError(err, "组织成员移除失败")
+	}
+
+	s.log.Infof("Organization member removed successfully: user %d, organization %d", userID, orgID)
+	return nil
+}
+
+// ListOrganizationMemberHistory 获取组织的成员变动记录
+func (s *OrganizationService) ListOrganizationMemberHistory(ctx context.Context, orgID int32, limit int32) (*ListMemberHistoryResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看组织成员变动记录")
+	}
+
+	return s.listMemberHistory(ctx, &biz.MemberHistoryFilter{OrgID: orgID, Limit: limit})
+}
+
+// ListUserOrganizations 获取用户所属的组织，用户本人也可查看
+func (s *OrganizationService) ListUserOrganizations(ctx context.Context, userID int32, includeInactive bool) (*ListOrganizationMembersResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if currentUser.ID != int64(userID) && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看用户所属组织")
+	}
+
+	members, err := s.orgUc.ListUserMemberships(ctx, userID, includeInactive)
+	if err != nil {
+		return nil, s.convertMemberError(err, "用户所属组织获取失败")
+	}
+
+	return &ListOrganizationMembersResponse{Members: members, Total: int32(len(members))}, nil
+}
+
+// ListUserOrganizationHistory 获取用户的组织变动记录，用户本人也可查看
+func (s *OrganizationService) ListUserOrganizationHistory(ctx context.Context, userID int32, limit int32) (*ListMemberHistoryResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if currentUser.ID != int64(userID) && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看用户组织变动记录")
+	}
+
+	return s.listMemberHistory(ctx, &biz.MemberHistoryFilter{UserID: userID, Limit: limit})
+}
+
+// listMemberHistory 按条件获取组织成员变动记录
+func (s *OrganizationService) listMemberHistory(ctx context.Context, filter *biz.MemberHistoryFilter) (*ListMemberHistoryResponse, error) {
+	history, err := s.orgUc.ListMemberHistory(ctx, filter)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织成员变动记录获取失败")
+	}
+
+	return &ListMemberHistoryResponse{History: history, Total: int32(len(history))}, nil
+}
+
+// convertMemberError 转换组织成员相关错误，未知错误记录日志后返回message
+func (s *OrganizationService) convertMemberError(err error, message string) error {
+	if orgErr := convertOrganizationError(err); orgErr != nil {
+		return orgErr
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// SearchOrganizationsRequest 组织搜索请求
+type SearchOrganizationsRequest struct {
+	Keyword     string `json:"keyword"`
+	Type        string `json:"type"`
+	OnlyEnabled bool   `json:"only_enabled"`
+	Limit       int32  `json:"limit"` // 1-100，默认20
+}
+
+// OrganizationSearchResultInfo 组织搜索结果
+type OrganizationSearchResultInfo struct {
+	Organization *OrganizationInfo         `json:"organization"`
+	Path         []*biz.OrganizationOption `json:"path"`
+	MatchedField string                    `json:"matched_field"`
+	Highlight    string                    `json:"highlight"`
+}
+
+// SearchOrganizationsResponse 组织搜索响应
+type SearchOrganizationsResponse struct {
+	Results []*OrganizationSearchResultInfo `json:"results"`
+	Total   int32                           `json:"total"`
+}
+
+// GetOrganizationOptionsRequest 组织选项请求
+type GetOrganizationOptionsRequest struct {
+	OnlyEnabled bool   `json:"only_enabled"`
+	Type        string `json:"type"`
+	ExcludeID   int32  `json:"exclude_id"` // 排除该组织及其下级组织
+}
+
+// GetOrganizationStats 获取组织人数统计，结果受用户权限中的组织限制约束
+func (s *OrganizationService) GetOrganizationStats(ctx context.Context, orgID int32, includeSubOrgs bool) (*biz.OrganizationStats, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看组织统计")
+	}
+
+	scope, err := s.orgUc.GetUserScope(ctx, currentUser.ID)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织统计获取失败")
+	}
+
+	stats, err := s.orgUc.GetStats(ctx, orgID, includeSubOrgs, scope)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织统计获取失败")
+	}
+
+	return stats, nil
+}
+
+// SearchOrganizations 按名称、编码和描述搜索组织
+func (s *OrganizationService) SearchOrganizations(ctx context.Context, req *SearchOrganizationsRequest) (*SearchOrganizationsResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "ORG_MANAGER", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限搜索组织")
+	}
+	if req.Limit < 0 || req.Limit > 100 {
+		return nil, errors.BadRequest("INVALID_PARAMETER", "返回数量必须在1到100之间")
+	}
+
+	scope, err := s.orgUc.GetUserScope(ctx, currentUser.ID)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织搜索失败")
+	}
+
+	filter := &biz.OrganizationSearchFilter{
+		Keyword:     req.Keyword,
+		OrgType:     req.Type,
+		OnlyEnabled: req.OnlyEnabled,
+		Limit:       req.Limit,
+	}
+	results, err := s.orgUc.Search(ctx, filter, scope)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织搜索失败")
+	}
+
+	resp := &SearchOrganizationsResponse{
+		Results: make([]*OrganizationSearchResultInfo, len(results)),
+		Total:   int32(len(results)),
+	}
+	for i, result := range results {
+		resp.Results[i] = &OrganizationSearchResultInfo{
+			Organization: s.convertToOrganizationInfos([]*biz.Organization{result.Organization})[0],
+			Path:         result.Path,
+			MatchedField: result.MatchedField,
+			Highlight:    result.Highlight,
+		}
+	}
+	return resp, nil
+}
+
+// GetOrganizationOptions 获取组织下拉选项树，已认证用户均可获取其权限范围内的组织
+func (s *OrganizationService) GetOrganizationOptions(ctx context.Context, req *GetOrganizationOptionsRequest) ([]*biz.OrganizationOption, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.IsAuthenticated() {
+		return nil, errors.Unauthorized("NOT_AUTHENTICATED", "用户未认证")
+	}
+
+	scope, err := s.orgUc.GetUserScope(ctx, currentUser.ID)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织选项获取失败")
+	}
+
+	filter := &biz.OrganizationOptionFilter{
+		OnlyEnabled: req.OnlyEnabled,
+		OrgType:     req.Type,
+		ExcludeID:   req.ExcludeID,
+	}
+	options, err := s.orgUc.GetOptions(ctx, filter, scope)
+	if err != nil {
+		return nil, s.convertMemberError(err, "组织选项获取失败")
+	}
+
+	return options, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"time"
 
@@ -15,13 +16,17 @@ import (
 // PermissionService 权限管理服务
 type PermissionService struct {
 	permissionUc *biz.PermissionUsecase
+	sodUc        *biz.SoDUsecase
+	docFieldUc   *biz.DocFieldUsecase
 	log          *log.Helper
 }
 
 // NewPermissionService 创建权限服务
-func NewPermissionService(permissionUc *biz.PermissionUsecase, logger log.Logger) *PermissionService {
+func NewPermissionService(permissionUc *biz.PermissionUsecase, sodUc *biz.SoDUsecase, docFieldUc *biz.DocFieldUsecase, logger log.Logger) *PermissionService {
 	return &PermissionService{
 		permissionUc: permissionUc,
+		sodUc:        sodUc,
+		docFieldUc:   docFieldUc,
 		log:          log.NewHelper(logger),
 	}
 }
@@ -109,6 +114,9 @@ func (s *PermissionService) CreateDocType(ctx context.Context, req *CreateDocTyp
 		s.log.Errorf("DocType validation failed: %v", err)
 		return nil, errors.BadRequest("INVALID_DATA", fmt.Sprintf("数据验证失败: %v", err))
 	}
+	if err := s.validateDocTypeFields(ctx, docType); err != nil {
+		return nil, err
+	}
 
 	createdDocType, err := s.permissionUc.CreateDocType(ctx, docType)
 	if err != nil {
@@ -204,6 +212,20 @@ func (s *PermissionService) UpdateDocType(ctx context.Context, req *UpdateDocTyp
 		UpdatedAt:      time.Now(),
 	}
 
+	current, err := s.permissionUc.GetDocType(ctx, req.Name)
+	if err != nil {
+		s.log.Errorf("Failed to get doctype: %v", err)
+		return nil, errors.NotFound("NOT_FOUND", "文档类型不存在")
+	}
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "DocType", current, docType); err != nil {
+		return nil, err
+	}
+
+	// 标题、搜索和排序字段须为已定义字段
+	if err := s.validateDocTypeFields(ctx, docType); err != nil {
+		return nil, err
+	}
+
 	updatedDocType, err := s.permissionUc.UpdateDocType(ctx, docType)
 	if err != nil {
 		s.log.Errorf("Failed to update doctype: %v", err)
@@ -236,6 +258,18 @@ func (s *PermissionService) UpdateDocType(ctx context.Context, req *UpdateDocTyp
 	}, nil
 }
 
+// validateDocTypeFields 检查文档类型引用的字段是否已在字段定义中登记
+func (s *PermissionService) validateDocTypeFields(ctx context.Context, docType *biz.DocType) error {
+	if err := s.docFieldUc.ValidateDocTypeFields(ctx, docType); err != nil {
+		if stderrors.Is(err, biz.ErrInvalidDocField) {
+			return errors.BadRequest("INVALID_DATA", err.Error())
+		}
+		s.log.Errorf("Failed to validate doctype fields: %v", err)
+		return errors.InternalServer("INTERNAL_ERROR", "文档类型字段校验失败")
+	}
+	return nil
+}
+
 // DeleteDocType 删除文档类型
 func (s *PermissionService) DeleteDocType(ctx context.Context, name string) error {
 	if name == "" {
@@ -332,7 +366,8 @@ type CreatePermissionRuleRequest struct {
 	CanSetUserPermissions bool `json:"can_set_user_permissions"`
 
 	// 条件权限
-	OnlyIfCreator bool `json:"only_if_creator"`
+	OnlyIfCreator bool   `json:"only_if_creator"`
+	Condition     string `json:"condition,omitempty"` // 条件表达式，如 doc.amount < 10000
 }
 
 // Validate validates the CreatePermissionRuleRequest
@@ -381,10 +416,14 @@ type PermissionRuleInfo struct {
 	CanSetUserPermissions bool `json:"can_set_user_permissions"`
 
 	// 条件权限
-	OnlyIfCreator bool `json:"only_if_creator"`
+	OnlyIfCreator bool   `json:"only_if_creator"`
+	Condition     string `json:"condition,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// 仅警告的职责分离冲突
+	Warnings []*biz.SoDConflict `json:"warnings,omitempty"`
 }
 
 // CreatePermissionRule 创建权限规则
@@ -414,6 +453,7 @@ func (s *PermissionService) CreatePermissionRule(ctx context.Context, req *Creat
 		CanReport:             req.CanReport,
 		CanSetUserPermissions: req.CanSetUserPermissions,
 		OnlyIfCreator:         req.OnlyIfCreator,
+		Condition:             req.Condition,
 		CreatedAt:             time.Now(),
 		UpdatedAt:             time.Now(),
 	}
@@ -424,8 +464,21 @@ func (s *PermissionService) CreatePermissionRule(ctx context.Context, req *Creat
 		return nil, errors.BadRequest("INVALID_DATA", fmt.Sprintf("权限规则验证失败: %v", err))
 	}
 
-	createdRule, err := s.permissionUc.CreatePermissionRule(ctx, rule)
+	// 创建规则并复核职责分离策略
+	var createdRule *biz.PermissionRule
+	warnings, err := s.sodUc.Enforce(ctx, nil, func(ctx context.Context) error {
+		var err error
+		createdRule, err = s.permissionUc.CreatePermissionRule(ctx, rule)
+		return err
+	})
 	if err != nil {
+		if sodErr := convertSoDViolation(err); sodErr != nil {
+			s.log.Warnf("Permission rule for role %d, doctype %s rejected by SoD policies", req.RoleID, req.DocType)
+			return nil, sodErr
+		}
+		if stderrors.Is(err, biz.ErrInvalidPermissionCondition) {
+			return nil, errors.BadRequest("INVALID_CONDITION", err.Error())
+		}
 		s.log.Errorf("Failed to create permission rule: %v", err)
 		return nil, errors.InternalServer("INTERNAL_ERROR", "权限规则创建失败")
 	}
@@ -451,8 +504,10 @@ func (s *PermissionService) CreatePermissionRule(ctx context.Context, req *Creat
 		CanShare:        createdRule.CanShare,
 		CanReport:       createdRule.CanReport,
 		OnlyIfCreator:   createdRule.OnlyIfCreator,
+		Condition:       createdRule.Condition,
 		CreatedAt:       createdRule.CreatedAt,
 		UpdatedAt:       createdRule.UpdatedAt,
+		Warnings:        warnings,
 	}, nil
 }
 
@@ -487,6 +542,7 @@ func (s *PermissionService) GetPermissionRule(ctx context.Context, id int64) (*P
 		CanShare:        rule.CanShare,
 		CanReport:       rule.CanReport,
 		OnlyIfCreator:   rule.OnlyIfCreator,
+		Condition:       rule.Condition,
 		CreatedAt:       rule.CreatedAt,
 		UpdatedAt:       rule.UpdatedAt,
 	}, nil
@@ -499,29 +555,52 @@ func (s *PermissionService) UpdatePermissionRule(ctx context.Context, req *Updat
 	}
 
 	rule := &biz.PermissionRule{
-		ID:              req.ID,
-		RoleID:          req.RoleID,
-		DocType:         req.DocType,
-		PermissionLevel: req.PermissionLevel,
-		CanRead:         req.CanRead,
-		CanWrite:        req.CanWrite,
-		CanCreate:       req.CanCreate,
-		CanDelete:       req.CanDelete,
-		CanSubmit:       req.CanSubmit,
-		CanCancel:       req.CanCancel,
-		CanAmend:        req.CanAmend,
-		CanPrint:        req.CanPrint,
-		CanEmail:        req.CanEmail,
-		CanImport:       req.CanImport,
-		CanExport:       req.CanExport,
-		CanShare:        req.CanShare,
-		CanReport:       req.CanReport,
-		OnlyIfCreator:   req.OnlyIfCreator,
-		UpdatedAt:       time.Now(),
+		ID:                    req.ID,
+		RoleID:                req.RoleID,
+		DocType:               req.DocType,
+		PermissionLevel:       req.PermissionLevel,
+		CanRead:               req.CanRead,
+		CanWrite:              req.CanWrite,
+		CanCreate:             req.CanCreate,
+		CanDelete:             req.CanDelete,
+		CanSubmit:             req.CanSubmit,
+		CanCancel:             req.CanCancel,
+		CanAmend:              req.CanAmend,
+		CanPrint:              req.CanPrint,
+		CanEmail:              req.CanEmail,
+		CanImport:             req.CanImport,
+		CanExport:             req.CanExport,
+		CanShare:              req.CanShare,
+		CanReport:             req.CanReport,
+		CanSetUserPermissions: req.CanSetUserPermissions,
+		OnlyIfCreator:         req.OnlyIfCreator,
+		Condition:             req.Condition,
+		UpdatedAt:             time.Now(),
+	}
+
+	current, err := s.permissionUc.GetPermissionRule(ctx, req.ID)
+	if err != nil {
+		s.log.Errorf("Failed to get permission rule: %v", err)
+		return nil, errors.NotFound("NOT_FOUND", "权限规则不存在")
+	}
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "Permission Rule", current, rule); err != nil {
+		return nil, err
 	}
 
-	updatedRule, err := s.permissionUc.UpdatePermissionRule(ctx, rule)
+	var updatedRule *biz.PermissionRule
+	warnings, err := s.sodUc.Enforce(ctx, nil, func(ctx context.Context) error {
+		var err error
+		updatedRule, err = s.permissionUc.UpdatePermissionRule(ctx, rule)
+		return err
+	})
 	if err != nil {
+		if sodErr := convertSoDViolation(err); sodErr != nil {
+			s.log.Warnf("Permission rule %d update rejected by SoD policies", req.ID)
+			return nil, sodErr
+		}
+		if stderrors.Is(err, biz.ErrInvalidPermissionCondition) {
+			return nil, errors.BadRequest("INVALID_CONDITION", err.Error())
+		}
 		s.log.Errorf("Failed to update permission rule: %v", err)
 		return nil, errors.InternalServer("INTERNAL_ERROR", "权限规则更新失败")
 	}
@@ -547,8 +626,10 @@ func (s *PermissionService) UpdatePermissionRule(ctx context.Context, req *Updat
 		CanShare:        updatedRule.CanShare,
 		CanReport:       updatedRule.CanReport,
 		OnlyIfCreator:   updatedRule.OnlyIfCreator,
+		Condition:       updatedRule.Condition,
 		CreatedAt:       updatedRule.CreatedAt,
 		UpdatedAt:       updatedRule.UpdatedAt,
+		Warnings:        warnings,
 	}, nil
 }
 
@@ -610,6 +691,7 @@ func (s *PermissionService) ListPermissionRules(ctx context.Context, req *ListPe
 			CanReport:             rule.CanReport,
 			CanSetUserPermissions: rule.CanSetUserPermissions,
 			OnlyIfCreator:         rule.OnlyIfCreator,
+			Condition:             rule.Condition,
 			CreatedAt:             rule.CreatedAt,
 			UpdatedAt:             rule.UpdatedAt,
 		})
@@ -634,6 +716,10 @@ func (s *PermissionService) BatchCreatePermissionRules(ctx context.Context, req
 
 	var rules []*biz.PermissionRule
 	for _, ruleReq := range req.Rules {
+		// 批量写入按角色、文档类型和级别覆盖，不处理条件表达式
+		if ruleReq.Condition != "" {
+			return errors.BadRequest("INVALID_PARAMETER", "带条件的权限规则需单独创建")
+		}
 		rule := &biz.PermissionRule{
 			RoleID:          ruleReq.RoleID,
 			DocType:         ruleReq.DocType,
@@ -704,6 +790,9 @@ type CheckDocumentPermissionRequest struct {
 	DocType    string `json:"doc_type" validate:"required"`
 	Permission string `json:"permission" validate:"required"`
 	DocID      *int64 `json:"doc_id,omitempty"`
+
+	// Document 文档属性，提供时按权限规则的条件表达式判断
+	Document map[string]interface{} `json:"document,omitempty"`
 }
 
 // CheckDocumentPermission 检查文档权限
@@ -714,6 +803,7 @@ func (s *PermissionService) CheckDocumentPermission(ctx context.Context, req *Ch
 		Permission:      req.Permission,
 		PermissionLevel: 0, // 默认文档级权限
 		DocID:           req.DocID,
+		Document:        req.Document,
 	}
 
 	// 验证权限检查请求
@@ -741,7 +831,9 @@ type GetUserPermissionLevelRequest struct {
 
 // GetUserPermissionLevelResponse 获取用户权限级别响应
 type GetUserPermissionLevelResponse struct {
-	PermissionLevel int `json:"permission_level"`
+	PermissionLevel int   `json:"permission_level"`
+	ReadLevels      []int `json:"read_levels"`  // 拥有读权限的级别
+	WriteLevels     []int `json:"write_levels"` // 拥有写权限的级别
 }
 
 // GetUserPermissionLevel 获取用户权限级别
@@ -752,8 +844,16 @@ func (s *PermissionService) GetUserPermissionLevel(ctx context.Context, req *Get
 		return nil, errors.InternalServer("INTERNAL_ERROR", "获取用户权限级别失败")
 	}
 
+	levelPerms, err := s.permissionUc.GetUserLevelPermissions(ctx, req.UserID, req.DocType)
+	if err != nil {
+		s.log.Errorf("Failed to get user level permissions: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "获取用户权限级别失败")
+	}
+
 	return &GetUserPermissionLevelResponse{
 		PermissionLevel: level,
+		ReadLevels:      levelPerms.ReadLevels(),
+		WriteLevels:     levelPerms.WriteLevels(),
 	}, nil
 }
 
@@ -985,10 +1085,22 @@ func (s *PermissionService) UpdateUserPermission(ctx context.Context, permission
 	}
 
 	// 更新用户权限信息
-	userPermission.UserID = req.UserID
-	userPermission.DocType = req.DocType
-	userPermission.DocName = req.DocumentName
-	userPermission.Value = req.Condition
+	updates := fieldUpdates{}
+	if req.UserID != userPermission.UserID {
+		updates["user_id"] = func() { userPermission.UserID = req.UserID }
+	}
+	if req.DocType != userPermission.DocType {
+		updates["doc_type"] = func() { userPermission.DocType = req.DocType }
+	}
+	if req.DocumentName != userPermission.DocName {
+		updates["doc_name"] = func() { userPermission.DocName = req.DocumentName }
+	}
+	if req.Condition != userPermission.Value {
+		updates["value"] = func() { userPermission.Value = req.Condition }
+	}
+	if err := applyFieldUpdates(ctx, s.permissionUc, s.log, "User Permission", updates); err != nil {
+		return nil, err
+	}
 	userPermission.UpdatedAt = time.Now()
 
 	updatedUserPermission, err := s.permissionUc.UpdateUserPermission(ctx, userPermission)
@@ -1168,12 +1280,16 @@ func (s *PermissionService) UpdateFieldPermissionLevel(ctx context.Context, leve
 	}
 
 	// 更新字段权限级别信息
-	fieldPermissionLevel.DocType = req.DocType
-	fieldPermissionLevel.FieldName = req.FieldName
-	fieldPermissionLevel.PermissionLevel = req.PermissionLevel
-	fieldPermissionLevel.UpdatedAt = time.Now()
+	updated := *fieldPermissionLevel
+	updated.DocType = req.DocType
+	updated.FieldName = req.FieldName
+	updated.PermissionLevel = req.PermissionLevel
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "Field Permission Level", fieldPermissionLevel, &updated); err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = time.Now()
 
-	updatedFieldPermissionLevel, err := s.permissionUc.UpdateFieldPermissionLevel(ctx, fieldPermissionLevel)
+	updatedFieldPermissionLevel, err := s.permissionUc.UpdateFieldPermissionLevel(ctx, &updated)
 	if err != nil {
 		s.log.Errorf("Failed to update field permission level: %v", err)
 		return nil, errors.InternalServer("INTERNAL_ERROR", "字段权限级别更新失败")
@@ -1363,14 +1479,18 @@ func (s *PermissionService) UpdateDocumentWorkflowState(ctx context.Context, sta
 	}
 
 	// 更新文档工作流状态信息
-	documentWorkflowState.DocType = req.DocType
-	documentWorkflowState.DocID = req.DocID
-	documentWorkflowState.DocName = req.DocName
-	documentWorkflowState.WorkflowState = req.WorkflowState
-	documentWorkflowState.DocStatus = req.DocStatus
-	documentWorkflowState.UpdatedAt = time.Now()
-
-	updatedDocumentWorkflowState, err := s.permissionUc.UpdateDocumentWorkflowState(ctx, documentWorkflowState)
+	updated := *documentWorkflowState
+	updated.DocType = req.DocType
+	updated.DocID = req.DocID
+	updated.DocName = req.DocName
+	updated.WorkflowState = req.WorkflowState
+	updated.DocStatus = req.DocStatus
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "Document Workflow State", documentWorkflowState, &updated); err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = time.Now()
+
+	updatedDocumentWorkflowState, err := s.permissionUc.UpdateDocumentWorkflowState(ctx, &updated)
 	if err != nil {
 		s.log.Errorf("Failed to update document workflow state: %v", err)
 		return nil, errors.InternalServer("INTERNAL_ERROR", "文档工作流状态更新失败")
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// GetPermissionScopeRequest 获取数据范围请求
+type GetPermissionScopeRequest struct {
+	UserID          int64  `json:"user_id"`
+	DocType         string `json:"doc_type" validate:"required"`
+	Action          string `json:"action" validate:"required"`
+	PermissionLevel int    `json:"permission_level"`
+}
+
+// PermissionScopeInfo 数据范围信息，附带列表查询可用的SQL条件预览
+type PermissionScopeInfo struct {
+	*biz.PermissionScope
+	Denied       bool          `json:"denied"`
+	Translatable bool          `json:"translatable"` // 条件能否全部转换为SQL
+	Clause       string        `json:"clause,omitempty"`
+	Args         []interface{} `json:"args,omitempty"`
+}
+
+// GetPermissionScope 获取用户对文档类型某操作的数据范围，未指定用户时为当前用户
+func (s *PermissionService) GetPermissionScope(ctx context.Context, req *GetPermissionScopeRequest) (*PermissionScopeInfo, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if req.UserID == 0 {
+		req.UserID = currentUser.ID
+	}
+	if req.UserID != currentUser.ID && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看其他用户的数据范围")
+	}
+
+	req.DocType = strings.TrimSpace(req.DocType)
+	req.Action = strings.TrimSpace(req.Action)
+	if req.DocType == "" || req.Action == "" {
+		return nil, errors.BadRequest("INVALID_PARAMETER", "文档类型和操作不能为空")
+	}
+	if req.PermissionLevel < 0 || req.PermissionLevel > 9 {
+		return nil, errors.BadRequest("INVALID_PARAMETER", "权限级别必须在0-9之间")
+	}
+
+	scope, err := s.permissionUc.GetPermissionScope(ctx, req.UserID, req.DocType, req.Action, req.PermissionLevel)
+	if err != nil {
+		if stderrors.Is(err, biz.ErrUnsupportedPermissionAction) {
+			return nil, errors.BadRequest("INVALID_PARAMETER", "不支持的操作: "+req.Action)
+		}
+		s.log.Errorf("Failed to get permission scope: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "获取数据范围失败")
+	}
+
+	info := &PermissionScopeInfo{PermissionScope: scope, Denied: scope.Denied()}
+	info.Clause, info.Args, info.Translatable = scope.SQL(biz.QuotedConditionColumn, 1)
+	return info, nil
+}
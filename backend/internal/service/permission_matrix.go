@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 权限矩阵文件格式
+const (
+	PermissionMatrixFormatCSV  = "csv"
+	PermissionMatrixFormatJSON = "json"
+)
+
+// maxMatrixErrorDetails 导入失败时错误详情中列出的最大行数
+const maxMatrixErrorDetails = 20
+
+// PermissionMatrixService 权限矩阵导入导出服务
+type PermissionMatrixService struct {
+	matrixUc *biz.PermissionMatrixUsecase
+	sodUc    *biz.SoDUsecase
+	log      *log.Helper
+}
+
+// NewPermissionMatrixService 创建权限矩阵服务
+func NewPermissionMatrixService(matrixUc *biz.PermissionMatrixUsecase, sodUc *biz.SoDUsecase, logger log.Logger) *PermissionMatrixService {
+	return &PermissionMatrixService{
+		matrixUc: matrixUc,
+		sodUc:    sodUc,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// PermissionMatrixDocument JSON格式的权限矩阵
+type PermissionMatrixDocument struct {
+	Rows []*biz.PermissionMatrixRow `json:"rows"`
+}
+
+// PermissionMatrixFile 导出的权限矩阵文件
+type PermissionMatrixFile struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// ExportMatrix 导出权限矩阵
+func (s *PermissionMatrixService) ExportMatrix(ctx context.Context, format string) (*PermissionMatrixFile, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限导出权限矩阵")
+	}
+
+	rows, err := s.matrixUc.Export(ctx)
+	if err != nil {
+		return nil, s.convertError(err, "导出权限矩阵失败")
+	}
+
+	file := &PermissionMatrixFile{
+		Filename: fmt.Sprintf("permission-matrix-%s.%s", time.Now().Format("20060102150405"), format),
+	}
+	var buf bytes.Buffer
+	switch format {
+	case PermissionMatrixFormatCSV:
+		file.ContentType = "text/csv; charset=utf-8"
+		err = biz.EncodePermissionMatrixCSV(&buf, rows)
+	case PermissionMatrixFormatJSON:
+		file.ContentType = "application/json"
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(&PermissionMatrixDocument{Rows: rows})
+	default:
+		return nil, errors.BadRequest("INVALID_FORMAT", "仅支持csv或json格式")
+	}
+	if err != nil {
+		return nil, s.convertError(err, "导出权限矩阵失败")
+	}
+
+	file.Content = buf.Bytes()
+	s.log.Infof("Permission matrix exported by %s: %d rows (%s)", currentUser.Username, len(rows), format)
+	return file, nil
+}
+
+// ImportMatrix 导入权限矩阵，dryRun时只返回将新增、变更和移除的规则
+func (s *PermissionMatrixService) ImportMatrix(ctx context.Context, format string, content []byte, dryRun bool) (*biz.PermissionMatrixDiff, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限导入权限矩阵")
+	}
+
+	var rows []*biz.PermissionMatrixRow
+	switch format {
+	case PermissionMatrixFormatCSV:
+		decoded, err := biz.DecodePermissionMatrixCSV(bytes.NewReader(content))
+		if err != nil {
+			if stderrors.Is(err, biz.ErrPermissionMatrixEmpty) {
+				return nil, s.convertError(err, "导入权限矩阵失败")
+			}
+			return nil, errors.BadRequest("INVALID_CSV", "CSV格式不正确").WithMetadata(map[string]string{
+				"details": err.Error(),
+			})
+		}
+		rows = decoded
+	case PermissionMatrixFormatJSON:
+		var doc PermissionMatrixDocument
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, errors.BadRequest("INVALID_JSON", "无效的JSON格式")
+		}
+		rows = doc.Rows
+	default:
+		return nil, errors.BadRequest("INVALID_FORMAT", "仅支持csv或json格式")
+	}
+
+	var diff *biz.PermissionMatrixDiff
+	importRows := func(ctx context.Context) error {
+		var err error
+		diff, err = s.matrixUc.Import(ctx, rows, dryRun)
+		return err
+	}
+	var err error
+	if dryRun {
+		err = importRows(ctx)
+	} else {
+		// 导入会改变角色的权限规则，在同一事务中复核职责分离策略
+		_, err = s.sodUc.Enforce(ctx, nil, importRows)
+	}
+	if err != nil {
+		if stderrors.Is(err, biz.ErrPermissionMatrixInvalid) {
+			return nil, matrixRowErrors(diff.Errors)
+		}
+		return nil, s.convertError(err, "导入权限矩阵失败")
+	}
+
+	if diff.Applied {
+		s.log.Infof("Permission matrix imported by %s: %v", currentUser.Username, diff.Summary)
+	}
+	return diff, nil
+}
+
+// convertError 将权限矩阵业务错误转换为API错误
+func (s *PermissionMatrixService) convertError(err error, message string) error {
+	if sodErr := convertSoDViolation(err); sodErr != nil {
+		return sodErr
+	}
+	if stderrors.Is(err, biz.ErrPermissionMatrixEmpty) {
+		return errors.BadRequest("EMPTY_PERMISSION_MATRIX", "权限矩阵没有数据行")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
+
+// matrixRowErrors 将导入行校验错误转换为API错误
+func matrixRowErrors(rowErrors []*biz.PermissionMatrixRowError) error {
+	details := make([]string, 0, maxMatrixErrorDetails)
+	for i, rowErr := range rowErrors {
+		if i == maxMatrixErrorDetails {
+			details = append(details, fmt.Sprintf("... %d more", len(rowErrors)-maxMatrixErrorDetails))
+			break
+		}
+		details = append(details, fmt.Sprintf("row %d: %s", rowErr.Row, rowErr.Message))
+	}
+	return errors.BadRequest("INVALID_PERMISSION_MATRIX", fmt.Sprintf("权限矩阵有%d行校验失败", len(rowErrors))).
+		WithMetadata(map[string]string{"details": strings.Join(details, "; ")})
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// PermissionTemplateService 权限模板服务
+type PermissionTemplateService struct {
+	templateUc   *biz.PermissionTemplateUsecase
+	permissionUc *biz.PermissionUsecase
+	sodUc        *biz.SoDUsecase
+	log          *log.Helper
+}
+
+// NewPermissionTemplateService 创建权限模板服务
+func NewPermissionTemplateService(templateUc *biz.PermissionTemplateUsecase, permissionUc *biz.PermissionUsecase, sodUc *biz.SoDUsecase, logger log.Logger) *PermissionTemplateService {
+	return &PermissionTemplateService{
+		templateUc:   templateUc,
+		permissionUc: permissionUc,
+		sodUc:        sodUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// PermissionTemplateRequest 创建/更新权限模板请求
+type PermissionTemplateRequest struct {
+	Name        string                         `json:"name" validate:"required,min=2,max=100"`
+	Description string                         `json:"description"`
+	Levels      []*biz.PermissionTemplateLevel `json:"levels" validate:"required"`
+}
+
+// ApplyPermissionTemplateRequest 应用权限模板请求
+type ApplyPermissionTemplateRequest struct {
+	RoleID   int64    `json:"role_id" validate:"required"`
+	DocTypes []string `json:"doc_types"`
+	Module   string   `json:"module"`
+}
+
+// ListPermissionTemplatesResponse 权限模板列表响应
+type ListPermissionTemplatesResponse struct {
+	Templates []*biz.PermissionTemplate `json:"templates"`
+	Total     int32                     `json:"total"`
+}
+
+// ReapplyPermissionTemplateResponse 重新应用权限模板响应
+type ReapplyPermissionTemplateResponse struct {
+	Diffs  []*biz.PermissionTemplateDiff `json:"diffs"`
+	DryRun bool                          `json:"dry_run"`
+}
+
+// CreateTemplate 创建权限模板
+func (s *PermissionTemplateService) CreateTemplate(ctx context.Context, req *PermissionTemplateRequest) (*biz.PermissionTemplate, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限创建权限模板")
+	}
+
+	template := &biz.PermissionTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		Levels:      req.Levels,
+		CreatedBy:   &currentUser.ID,
+	}
+	if err := template.Validate(); err != nil {
+		return nil, errors.BadRequest("INVALID_TEMPLATE", err.Error())
+	}
+
+	created, err := s.templateUc.CreateTemplate(ctx, template)
+	if err != nil {
+		return nil, s.convertError(err, "权限模板创建失败")
+	}
+
+	s.log.Infof("Permission template created successfully: %s", created.Name)
+	return created, nil
+}
+
+// UpdateTemplate 更新权限模板
+func (s *PermissionTemplateService) UpdateTemplate(ctx context.Context, id int64, req *PermissionTemplateRequest) (*biz.PermissionTemplate, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改权限模板")
+	}
+
+	template, err := s.templateUc.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, s.convertError(err, "获取权限模板失败")
+	}
+
+	changed := *template
+	changed.Name = req.Name
+	changed.Description = req.Description
+	changed.Levels = req.Levels
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "Permission Template", template, &changed); err != nil {
+		return nil, err
+	}
+	changed.UpdatedBy = &currentUser.ID
+	if err := changed.Validate(); err != nil {
+		return nil, errors.BadRequest("INVALID_TEMPLATE", err.Error())
+	}
+
+	updated, err := s.templateUc.UpdateTemplate(ctx, &changed)
+	if err != nil {
+		return nil, s.convertError(err, "权限模板更新失败")
+	}
+
+	s.log.Infof("Permission template updated successfully: %d (version %d)", id, updated.Version)
+	return updated, nil
+}
+
+// GetTemplate 获取权限模板详情
+func (s *PermissionTemplateService) GetTemplate(ctx context.Context, id int64) (*biz.PermissionTemplate, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看权限模板")
+	}
+
+	template, err := s.templateUc.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, s.convertError(err, "获取权限模板失败")
+	}
+	return template, nil
+}
+
+// ListTemplates 获取权限模板列表
+func (s *PermissionTemplateService) ListTemplates(ctx context.Context) (*ListPermissionTemplatesResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看权限模板列表")
+	}
+
+	templates, err := s.templateUc.ListTemplates(ctx)
+	if err != nil {
+		s.log.Errorf("Failed to list permission templates: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "获取权限模板列表失败")
+	}
+
+	return &ListPermissionTemplatesResponse{
+		Templates: templates,
+		Total:     int32(len(templates)),
+	}, nil
+}
+
+// DeleteTemplate 删除权限模板
+func (s *PermissionTemplateService) DeleteTemplate(ctx context.Context, id int64) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限删除权限模板")
+	}
+
+	if err := s.templateUc.DeleteTemplate(ctx, id); err != nil {
+		return s.convertError(err, "权限模板删除失败")
+	}
+
+	s.log.Infof("Permission template deleted successfully: %d", id)
+	return nil
+}
+
+// PreviewTemplate 预览权限模板应用到角色后的规则变更
+func (s *PermissionTemplateService) PreviewTemplate(ctx context.Context, id int64, req *ApplyPermissionTemplateRequest) (*biz.PermissionTemplateDiff, error) {
+	return s.applyTemplate(ctx, id, req, true)
+}
+
+// ApplyTemplate 将权限模板批量应用到角色的多个文档类型
+func (s *PermissionTemplateService) ApplyTemplate(ctx context.Context, id int64, req *ApplyPermissionTemplateRequest) (*biz.PermissionTemplateDiff, error) {
+	return s.applyTemplate(ctx, id, req, false)
+}
+
+func (s *PermissionTemplateService) applyTemplate(ctx context.Context, id int64, req *ApplyPermissionTemplateRequest, dryRun bool) (*biz.PermissionTemplateDiff, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限应用权限模板")
+	}
+
+	if req.RoleID <= 0 {
+		return nil, errors.BadRequest("INVALID_ROLE", "角色ID无效")
+	}
+
+	var diff *biz.PermissionTemplateDiff
+	err := s.enforceUnlessDryRun(ctx, dryRun, func(ctx context.Context) error {
+		var err error
+		diff, err = s.templateUc.ApplyTemplate(ctx, &biz.ApplyPermissionTemplateRequest{
+			TemplateID: id,
+			RoleID:     req.RoleID,
+			DocTypes:   req.DocTypes,
+			Module:     req.Module,
+			DryRun:     dryRun,
+		}, currentUser.ID)
+		return err
+	})
+	if err != nil {
+		return nil, s.convertError(err, "权限模板应用失败")
+	}
+
+	if !dryRun {
+		s.log.Infof("Permission template %d applied to role %d on %d doc types", id, req.RoleID, len(diff.DocTypes))
+	}
+	return diff, nil
+}
+
+// ReapplyTemplate 将变更后的模板重新应用到所有曾应用过的角色
+func (s *PermissionTemplateService) ReapplyTemplate(ctx context.Context, id int64, dryRun bool) (*ReapplyPermissionTemplateResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限应用权限模板")
+	}
+
+	var diffs []*biz.PermissionTemplateDiff
+	err := s.enforceUnlessDryRun(ctx, dryRun, func(ctx context.Context) error {
+		var err error
+		diffs, err = s.templateUc.ReapplyTemplate(ctx, id, dryRun, currentUser.ID)
+		return err
+	})
+	if err != nil {
+		return nil, s.convertError(err, "权限模板重新应用失败")
+	}
+
+	if !dryRun {
+		s.log.Infof("Permission template %d reapplied to %d roles", id, len(diffs))
+	}
+	return &ReapplyPermissionTemplateResponse{
+		Diffs:  diffs,
+		DryRun: dryRun,
+	}, nil
+}
+
+// enforceUnlessDryRun 应用模板时在同一事务中复核职责分离策略，预览不修改规则无需复核
+func (s *PermissionTemplateService) enforceUnlessDryRun(ctx context.Context, dryRun bool, fn func(ctx context.Context) error) error {
+	if dryRun {
+		return fn(ctx)
+	}
+	_, err := s.sodUc.Enforce(ctx, nil, fn)
+	return err
+}
+
+// convertError 将模板业务错误转换为API错误
+func (s *PermissionTemplateService) convertError(err error, message string) error {
+	if sodErr := convertSoDViolation(err); sodErr != nil {
+		return sodErr
+	}
+	switch {
+	case stderrors.Is(err, biz.ErrPermissionTemplateNotFound):
+		return errors.NotFound("TEMPLATE_NOT_FOUND", "权限模板不存在")
+	case stderrors.Is(err, biz.ErrPermissionTemplateNameExists):
+		return errors.BadRequest("TEMPLATE_NAME_EXISTS", "权限模板名称已存在")
+	case stderrors.Is(err, biz.ErrTemplateTargetRequired):
+		return errors.BadRequest("TEMPLATE_TARGET_REQUIRED", "请指定文档类型或模块")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
@@ -220,6 +220,26 @@ func (m *MockPermissionUsecase) FilterDocumentsByPermission(ctx context.Context,
 	return args.Get(0).([]map[string]interface{}), args.Error(1)
 }
 
+func (m *MockPermissionUsecase) ListEffectivePermissionRules(ctx context.Context, roleID int64, docType string) ([]*biz.EffectivePermissionRule, error) {
+	args := m.Called(ctx, roleID, docType)
+	return args.Get(0).([]*biz.EffectivePermissionRule), args.Error(1)
+}
+
+func (m *MockPermissionUsecase) GetDescendantRoleIDs(ctx context.Context, roleID int64) ([]int64, error) {
+	args := m.Called(ctx, roleID)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockPermissionUsecase) GetUserLevelPermissions(ctx context.Context, userID int64, documentType string) (*biz.LevelPermissions, error) {
+	args := m.Called(ctx, userID, documentType)
+	return args.Get(0).(*biz.LevelPermissions), args.Error(1)
+}
+
+func (m *MockPermissionUsecase) GuardFieldWrites(ctx context.Context, userID int64, documentType string, fields []string) ([]string, error) {
+	args := m.Called(ctx, userID, documentType, fields)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *MockPermissionUsecase) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).([]string), args.Error(1)
@@ -229,7 +249,7 @@ func TestNewPermissionService(t *testing.T) {
 	mockUsecase := &MockPermissionUsecase{}
 	logger := log.DefaultLogger
 
-	service := NewPermissionService(mockUsecase, logger)
+	service := NewPermissionService(mockUsecase, nil, nil, logger)
 
 	assert.NotNil(t, service)
 	assert.NotNil(t, service.permissionUc)
@@ -241,7 +261,7 @@ func TestNewPermissionService(t *testing.T) {
 func TestPermissionService_CreateDocType_Validation(t *testing.T) {
 	mockUsecase := &MockPermissionUsecase{}
 	logger := log.DefaultLogger
-	service := NewPermissionService(mockUsecase, logger)
+	service := NewPermissionService(mockUsecase, nil, nil, logger)
 	ctx := context.Background()
 
 	t.Run("valid request should pass validation", func(t *testing.T) {
@@ -275,7 +295,7 @@ func TestPermissionService_CreateDocType_Validation(t *testing.T) {
 func TestPermissionService_CreatePermissionRule_Validation(t *testing.T) {
 	mockUsecase := &MockPermissionUsecase{}
 	logger := log.DefaultLogger
-	service := NewPermissionService(mockUsecase, logger)
+	service := NewPermissionService(mockUsecase, nil, nil, logger)
 	ctx := context.Background()
 
 	t.Run("valid permission rule request", func(t *testing.T) {
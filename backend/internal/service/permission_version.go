@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// PermissionVersionService 权限配置版本服务
+type PermissionVersionService struct {
+	versionUc *biz.PermissionVersionUsecase
+	sodUc     *biz.SoDUsecase
+	log       *log.Helper
+}
+
+// NewPermissionVersionService 创建权限配置版本服务
+func NewPermissionVersionService(versionUc *biz.PermissionVersionUsecase, sodUc *biz.SoDUsecase, logger log.Logger) *PermissionVersionService {
+	return &PermissionVersionService{
+		versionUc: versionUc,
+		sodUc:     sodUc,
+		log:       log.NewHelper(logger),
+	}
+}
+
+// ListPermissionVersionsRequest 权限配置版本列表请求
+type ListPermissionVersionsRequest struct {
+	Page int32 `json:"page"`
+	Size int32 `json:"size"`
+}
+
+// ListPermissionVersionsResponse 权限配置版本列表响应
+type ListPermissionVersionsResponse struct {
+	Versions []*biz.PermissionVersion `json:"versions"`
+	Total    int32                    `json:"total"`
+	Page     int32                    `json:"page"`
+	Size     int32                    `json:"size"`
+}
+
+// RollbackPermissionVersionRequest 回滚权限配置请求
+type RollbackPermissionVersionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// RollbackPermissionVersionResponse 回滚权限配置响应
+type RollbackPermissionVersionResponse struct {
+	Message    string `json:"message"`
+	RollbackOf int64  `json:"rollback_of"`
+}
+
+// ListVersions 获取权限配置版本列表
+func (s *PermissionVersionService) ListVersions(ctx context.Context, req *ListPermissionVersionsRequest) (*ListPermissionVersionsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看权限配置版本")
+	}
+
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 || req.Size > 100 {
+		req.Size = 20
+	}
+
+	versions, total, err := s.versionUc.ListVersions(ctx, req.Page, req.Size)
+	if err != nil {
+		return nil, s.convertError(err, "权限配置版本列表获取失败")
+	}
+
+	return &ListPermissionVersionsResponse{
+		Versions: versions,
+		Total:    total,
+		Page:     req.Page,
+		Size:     req.Size,
+	}, nil
+}
+
+// GetVersion 获取权限配置版本及其快照
+func (s *PermissionVersionService) GetVersion(ctx context.Context, id int64) (*biz.PermissionVersion, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看权限配置版本")
+	}
+
+	version, err := s.versionUc.GetVersion(ctx, id)
+	if err != nil {
+		return nil, s.convertError(err, "权限配置版本获取失败")
+	}
+	return version, nil
+}
+
+// DiffVersions 比较两个权限配置版本
+func (s *PermissionVersionService) DiffVersions(ctx context.Context, from, to int64) (*biz.PermissionVersionDiff, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看权限配置版本")
+	}
+
+	diff, err := s.versionUc.DiffVersions(ctx, from, to)
+	if err != nil {
+		return nil, s.convertError(err, "权限配置版本比较失败")
+	}
+	return diff, nil
+}
+
+// Rollback 将权限配置回滚到指定版本
+func (s *PermissionVersionService) Rollback(ctx context.Context, id int64, req *RollbackPermissionVersionRequest) (*RollbackPermissionVersionResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限回滚权限配置")
+	}
+
+	changeset := &biz.PermissionChangeset{AuthorID: &currentUser.ID, Comment: req.Comment}
+	// 回滚会恢复旧的权限规则，在同一事务中复核职责分离策略
+	var target *biz.PermissionVersion
+	_, err := s.sodUc.Enforce(ctx, nil, func(ctx context.Context) error {
+		var err error
+		target, err = s.versionUc.Rollback(ctx, id, changeset)
+		return err
+	})
+	if err != nil {
+		return nil, s.convertError(err, "权限配置回滚失败")
+	}
+
+	s.log.Infof("Permission configuration rolled back to version %d by %s", target.ID, currentUser.Username)
+	return &RollbackPermissionVersionResponse{
+		Message:    "权限配置已回滚",
+		RollbackOf: target.ID,
+	}, nil
+}
+
+// convertError 将权限配置版本业务错误转换为API错误
+func (s *PermissionVersionService) convertError(err error, message string) error {
+	if sodErr := convertSoDViolation(err); sodErr != nil {
+		return sodErr
+	}
+	if stderrors.Is(err, biz.ErrPermissionVersionNotFound) {
+		return errors.NotFound("PERMISSION_VERSION_NOT_FOUND", "权限配置版本不存在")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+	"erp-system/internal/pkg"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// ProfileService 个人资料自助修改和变更审批服务
+type ProfileService struct {
+	profileUc    *biz.ProfileUsecase
+	permissionUc *biz.PermissionUsecase
+	log          *log.Helper
+}
+
+// NewProfileService 创建个人资料服务
+func NewProfileService(profileUc *biz.ProfileUsecase, permissionUc *biz.PermissionUsecase, logger log.Logger) *ProfileService {
+	return &ProfileService{
+		profileUc:    profileUc,
+		permissionUc: permissionUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// UpdateProfileRequest 修改个人资料请求，键为字段名，只修改请求中出现的字段
+type UpdateProfileRequest map[string]string
+
+// ListProfileChangeRequestsRequest 变更申请列表请求
+type ListProfileChangeRequestsRequest struct {
+	UserID int32  `json:"user_id"`
+	Status string `json:"status"`
+	Page   int32  `json:"page"`
+	Size   int32  `json:"size"`
+}
+
+// ListProfileChangeRequestsResponse 变更申请列表响应
+type ListProfileChangeRequestsResponse struct {
+	Items []*biz.ProfileChangeRequest `json:"items"`
+	Total int32                       `json:"total"`
+	Page  int32                       `json:"page"`
+	Size  int32                       `json:"size"`
+}
+
+// ReviewProfileChangeRequest 审批变更申请请求
+type ReviewProfileChangeRequest struct {
+	Comment string `json:"comment"`
+}
+
+// UpdateProfile 修改当前用户的个人资料，需要审批的字段生成变更申请，其余字段立即生效
+func (s *ProfileService) UpdateProfile(ctx context.Context, req UpdateProfileRequest) (*biz.ProfileUpdateResult, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.IsAuthenticated() {
+		return nil, errors.Unauthorized("NOT_AUTHENTICATED", "用户未认证")
+	}
+	if err := validateProfileValues(req); err != nil {
+		return nil, err
+	}
+
+	userID := int32(currentUser.ID)
+	changes, err := s.profileUc.DiffProfile(ctx, userID, req)
+	if err != nil {
+		return nil, s.convertError(err, "个人资料修改失败")
+	}
+	if len(changes) == 0 {
+		return nil, errors.BadRequest("NO_CHANGES", "个人资料没有变化")
+	}
+
+	// 字段写权限对自助修改同样生效
+	keep := make(map[string]bool, len(changes))
+	updates := fieldUpdates{}
+	for _, change := range changes {
+		field := change.Field
+		updates[field] = func() { keep[field] = true }
+	}
+	if err := applyFieldUpdates(ctx, s.permissionUc, s.log, "User", updates); err != nil {
+		return nil, err
+	}
+	allowed := make([]*biz.ProfileFieldChange, 0, len(keep))
+	for _, change := range changes {
+		if keep[change.Field] {
+			allowed = append(allowed, change)
+		}
+	}
+
+	result, err := s.profileUc.UpdateProfile(ctx, userID, currentUser.Username, allowed)
+	if err != nil {
+		return nil, s.convertError(err, "个人资料修改失败")
+	}
+
+	s.log.Infof("Profile of user %s updated", currentUser.Username)
+	return result, nil
+}
+
+// ListMyChangeRequests 获取当前用户的变更申请
+func (s *ProfileService) ListMyChangeRequests(ctx context.Context, req *ListProfileChangeRequestsRequest) (*ListProfileChangeRequestsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.IsAuthenticated() {
+		return nil, errors.Unauthorized("NOT_AUTHENTICATED", "用户未认证")
+	}
+
+	req.UserID = int32(currentUser.ID)
+	return s.listChangeRequests(ctx, req)
+}
+
+// CancelChangeRequest 撤回当前用户待审批的变更申请
+func (s *ProfileService) CancelChangeRequest(ctx context.Context, id int32) (*biz.ProfileChangeRequest, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.IsAuthenticated() {
+		return nil, errors.Unauthorized("NOT_AUTHENTICATED", "用户未认证")
+	}
+
+	cancelled, err := s.profileUc.CancelChangeRequest(ctx, id, int32(currentUser.ID), currentUser.Username)
+	if err != nil {
+		return nil, s.convertError(err, "撤回变更申请失败")
+	}
+	return cancelled, nil
+}
+
+// ListChangeRequests 获取全部用户的变更申请，用于管理员审批
+func (s *ProfileService) ListChangeRequests(ctx context.Context, req *ListProfileChangeRequestsRequest) (*ListProfileChangeRequestsResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看资料变更申请")
+	}
+	return s.listChangeRequests(ctx, req)
+}
+
+// ApproveChangeRequest 审批通过变更申请
+func (s *ProfileService) ApproveChangeRequest(ctx context.Context, id int32, req *ReviewProfileChangeRequest) (*biz.ProfileChangeRequest, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限审批资料变更申请")
+	}
+
+	approved, err := s.profileUc.ApproveChangeRequest(ctx, id, int32(currentUser.ID), currentUser.Username, req.Comment)
+	if err != nil {
+		return nil, s.convertError(err, "审批变更申请失败")
+	}
+
+	s.log.Infof("Profile change request %d approved by %s", id, currentUser.Username)
+	return approved, nil
+}
+
+// RejectChangeRequest 驳回变更申请
+func (s *ProfileService) RejectChangeRequest(ctx context.Context, id int32, req *ReviewProfileChangeRequest) (*biz.ProfileChangeRequest, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限审批资料变更申请")
+	}
+
+	rejected, err := s.profileUc.RejectChangeRequest(ctx, id, int32(currentUser.ID), currentUser.Username, req.Comment)
+	if err != nil {
+		return nil, s.convertError(err, "驳回变更申请失败")
+	}
+
+	s.log.Infof("Profile change request %d rejected by %s", id, currentUser.Username)
+	return rejected, nil
+}
+
+func (s *ProfileService) listChangeRequests(ctx context.Context, req *ListProfileChangeRequestsRequest) (*ListProfileChangeRequestsResponse, error) {
+	filter := &biz.ProfileChangeRequestFilter{UserID: req.UserID, Status: req.Status, Page: req.Page, Size: req.Size}
+	items, total, err := s.profileUc.ListChangeRequests(ctx, filter)
+	if err != nil {
+		return nil, s.convertError(err, "变更申请列表获取失败")
+	}
+	return &ListProfileChangeRequestsResponse{Items: items, Total: total, Page: filter.Page, Size: filter.Size}, nil
+}
+
+// validateProfileValues 校验个人资料字段的格式，与UpdateUser的校验规则一致
+func validateProfileValues(req UpdateProfileRequest) error {
+	for field, value := range req {
+		value = strings.TrimSpace(value)
+		switch field {
+		case biz.ProfileFieldEmail:
+			if !pkg.ValidateEmail(value) {
+				return errors.BadRequest("INVALID_EMAIL", "邮箱格式不正确")
+			}
+		case biz.ProfileFieldFirstName, biz.ProfileFieldLastName:
+			if value == "" || len([]rune(value)) > 50 {
+				return errors.BadRequest("INVALID_NAME", "姓名不能为空且不能超过50个字符")
+			}
+		case biz.ProfileFieldPhone:
+			if value != "" && !pkg.ValidatePhone(value) {
+				return errors.BadRequest("INVALID_PHONE", "手机号格式不正确")
+			}
+		case biz.ProfileFieldGender:
+			if value != "" && value != "0" && value != "1" && value != "2" {
+				return errors.BadRequest("INVALID_GENDER", "性别必须为0（未知）、1（男）或2（女）")
+			}
+		case biz.ProfileFieldBirthDate:
+			if value == "" {
+				continue
+			}
+			if birthDate, err := time.Parse("2006-01-02", value); err != nil || birthDate.After(time.Now()) {
+				return errors.BadRequest("INVALID_BIRTH_DATE", "生日格式必须为YYYY-MM-DD且不能晚于今天")
+			}
+		case biz.ProfileFieldAvatarURL:
+			if len(value) > 500 {
+				return errors.BadRequest("INVALID_AVATAR_URL", "头像地址不能超过500个字符")
+			}
+		}
+	}
+	return nil
+}
+
+// convertError 将个人资料业务错误转换为API错误
+func (s *ProfileService) convertError(err error, message string) error {
+	if apiErr := convertProfileFieldError(err); apiErr != nil {
+		return apiErr
+	}
+	switch {
+	case stderrors.Is(err, biz.ErrProfileFieldUnknown):
+		return errors.BadRequest("UNKNOWN_PROFILE_FIELD", err.Error())
+	case stderrors.Is(err, biz.ErrProfileNoChanges):
+		return errors.BadRequest("NO_CHANGES", "个人资料没有变化")
+	case stderrors.Is(err, biz.ErrUserNotFound):
+		return errors.NotFound("USER_NOT_FOUND", "用户不存在")
+	case stderrors.Is(err, biz.ErrProfileEmailExists):
+		return errors.Conflict("EMAIL_EXISTS", "邮箱已被使用")
+	case stderrors.Is(err, biz.ErrProfileChangePending):
+		return errors.Conflict("PROFILE_CHANGE_PENDING", "已有待审批的资料变更申请，请等待审批或撤回后再提交")
+	case stderrors.Is(err, biz.ErrProfileChangeRequestNotFound):
+		return errors.NotFound("PROFILE_CHANGE_REQUEST_NOT_FOUND", "变更申请不存在")
+	case stderrors.Is(err, biz.ErrProfileChangeRequestNotPending):
+		return errors.Conflict("PROFILE_CHANGE_REQUEST_NOT_PENDING", "变更申请已处理")
+	case stderrors.Is(err, biz.ErrProfileChangeStale):
+		return errors.Conflict("PROFILE_CHANGE_STALE", "资料在申请提交后已被修改，请重新提交")
+	case stderrors.Is(err, biz.ErrProfileChangeSelfReview):
+		return errors.Forbidden("SELF_REVIEW_NOT_ALLOWED", "不能审批自己的资料变更申请")
+	case stderrors.Is(err, biz.ErrInvalidProfileChangeStatus):
+		return errors.BadRequest("INVALID_STATUS", "状态必须为pending、approved、rejected或cancelled")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
+
+// convertProfileFieldError 转换不能自助修改字段的错误，ProfileService与UserService共用；其他错误返回nil
+func convertProfileFieldError(err error) error {
+	var notEditableErr *biz.ProfileFieldNotEditableError
+	if !stderrors.As(err, &notEditableErr) {
+		return nil
+	}
+	return errors.Forbidden("PROFILE_FIELD_NOT_EDITABLE", "不能自行修改字段: "+strings.Join(notEditableErr.Fields, ", ")).
+		WithMetadata(map[string]string{"details": strings.Join(notEditableErr.Fields, ",")})
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// recycleBinPurgeInterval 回收站过期条目清理任务的执行间隔
+const recycleBinPurgeInterval = time.Hour
+
+// RecycleBinService 回收站服务，管理被软删除的用户、角色和组织
+type RecycleBinService struct {
+	recycleBinUc *biz.RecycleBinUsecase
+	log          *log.Helper
+}
+
+// NewRecycleBinService 创建回收站服务
+func NewRecycleBinService(recycleBinUc *biz.RecycleBinUsecase, logger log.Logger) *RecycleBinService {
+	return &RecycleBinService{
+		recycleBinUc: recycleBinUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// ListDeletedItemsRequest 回收站列表请求，Type为user、role或organization，为空时查询全部类型
+type ListDeletedItemsRequest struct {
+	Type    string `json:"type"`
+	Keyword string `json:"keyword"`
+	Page    int32  `json:"page"`
+	Size    int32  `json:"size"`
+}
+
+// ListDeletedItemsResponse 回收站列表响应
+type ListDeletedItemsResponse struct {
+	Items []*biz.DeletedItem `json:"items"`
+	Total int64              `json:"total"`
+	Page  int32              `json:"page"`
+	Size  int32              `json:"size"`
+}
+
+// ListDeletedItems 获取回收站条目
+func (s *RecycleBinService) ListDeletedItems(ctx context.Context, req *ListDeletedItemsRequest) (*ListDeletedItemsResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看回收站")
+	}
+
+	filter := &biz.RecycleBinFilter{Type: req.Type, Keyword: req.Keyword, Page: req.Page, Size: req.Size}
+	items, total, err := s.recycleBinUc.ListDeletedItems(ctx, filter)
+	if err != nil {
+		return nil, s.convertError(err, "回收站列表获取失败")
+	}
+
+	return &ListDeletedItemsResponse{Items: items, Total: total, Page: filter.Page, Size: filter.Size}, nil
+}
+
+// RestoreItem 从回收站恢复用户、角色或组织
+func (s *RecycleBinService) RestoreItem(ctx context.Context, itemType string, id int32) error {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限恢复已删除的数据")
+	}
+
+	s.log.Infof("Restoring %s %d by %s", itemType, id, currentUser.Username)
+
+	if err := s.recycleBinUc.RestoreItem(ctx, itemType, id); err != nil {
+		return s.convertError(err, "恢复失败")
+	}
+
+	s.log.Infof("%s %d restored", itemType, id)
+	return nil
+}
+
+// StartRecycleBinPurgeTask 启动回收站过期条目清理任务，启动时立即执行一次
+func (s *RecycleBinService) StartRecycleBinPurgeTask(ctx context.Context) {
+	ticker := time.NewTicker(recycleBinPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.recycleBinUc.PurgeExpiredItems(ctx, time.Now())
+		if err != nil {
+			s.log.Errorf("Recycle bin purge error: %v", err)
+		} else if result.Purged > 0 || result.Skipped > 0 {
+			s.log.Infof("Purged %d expired recycle bin items, %d still referenced", result.Purged, result.Skipped)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("Recycle bin purge task stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// convertError 将回收站业务错误转换为API错误
+func (s *RecycleBinService) convertError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, biz.ErrInvalidRecycleBinType):
+		return errors.BadRequest("INVALID_RECYCLE_BIN_TYPE", "类型必须为user、role或organization")
+	case stderrors.Is(err, biz.ErrRecycleBinItemNotFound):
+		return errors.NotFound("RECYCLE_BIN_ITEM_NOT_FOUND", "回收站中不存在该数据")
+	case stderrors.Is(err, biz.ErrRecycleBinParentDeleted):
+		return errors.Conflict("PARENT_DELETED", "上级角色或上级组织已删除，请先恢复上级")
+	case stderrors.Is(err, biz.ErrOrganizationCodeExists):
+		return errors.Conflict("ORGANIZATION_CODE_EXISTS", "组织编码已被其他组织使用")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
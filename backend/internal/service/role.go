@@ -13,15 +13,19 @@ import (
 
 // RoleService 角色服务
 type RoleService struct {
-	roleUc *biz.RoleUsecase
-	log    *log.Helper
+	roleUc       *biz.RoleUsecase
+	permissionUc *biz.PermissionUsecase
+	sodUc        *biz.SoDUsecase
+	log          *log.Helper
 }
 
 // NewRoleService 创建角色服务
-func NewRoleService(roleUc *biz.RoleUsecase, logger log.Logger) *RoleService {
+func NewRoleService(roleUc *biz.RoleUsecase, permissionUc *biz.PermissionUsecase, sodUc *biz.SoDUsecase, logger log.Logger) *RoleService {
 	return &RoleService{
-		roleUc: roleUc,
-		log:    log.NewHelper(logger),
+		roleUc:       roleUc,
+		permissionUc: permissionUc,
+		sodUc:        sodUc,
+		log:          log.NewHelper(logger),
 	}
 }
 
@@ -32,6 +36,7 @@ type CreateRoleRequest struct {
 	Description   string  `json:"description"`
 	IsEnabled     bool    `json:"is_enabled"`
 	SortOrder     int32   `json:"sort_order"`
+	ParentRoleID  *int32  `json:"parent_role_id"`
 	PermissionIDs []int32 `json:"permission_ids"`
 }
 
@@ -42,6 +47,8 @@ type UpdateRoleRequest struct {
 	Description   string  `json:"description"`
 	IsEnabled     bool    `json:"is_enabled"`
 	SortOrder     int32   `json:"sort_order"`
+	ParentRoleID  *int32  `json:"parent_role_id"` // 为空时保持原有继承关系
+	ClearParent   bool    `json:"clear_parent"`   // 取消继承，不能与parent_role_id同时指定
 	PermissionIDs []int32 `json:"permission_ids"`
 }
 
@@ -78,9 +85,13 @@ type RoleInfo struct {
 	IsSystemRole bool              `json:"is_system_role"`
 	IsEnabled    bool              `json:"is_enabled"`
 	SortOrder    int32             `json:"sort_order"`
+	ParentRoleID *int32            `json:"parent_role_id,omitempty"`
 	CreatedAt    time.Time         `json:"created_at"`
 	UpdatedAt    time.Time         `json:"updated_at"`
 	Permissions  []*PermissionInfo `json:"permissions,omitempty"`
+
+	// PermissionRules 角色的生效权限规则，继承的规则标注来源角色
+	PermissionRules []*biz.EffectivePermissionRule `json:"permission_rules,omitempty"`
 }
 
 // PermissionInfo 权限信息
@@ -119,6 +130,7 @@ func (s *RoleService) CreateRole(ctx context.Context, req *CreateRoleRequest) (*
 		IsSystemRole: false, // 用户创建的角色都不是系统角色
 		IsEnabled:    req.IsEnabled,
 		SortOrder:    req.SortOrder,
+		ParentRoleID: req.ParentRoleID,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -126,6 +138,9 @@ func (s *RoleService) CreateRole(ctx context.Context, req *CreateRoleRequest) (*
 	createdRole, err := s.roleUc.CreateRole(ctx, role)
 	if err != nil {
 		s.log.Errorf("Failed to create role: %v", err)
+		if inheritanceErr := convertRoleInheritanceError(err); inheritanceErr != nil {
+			return nil, inheritanceErr
+		}
 		if err == biz.ErrRoleCodeExists {
 			return nil, errors.BadRequest("ROLE_CODE_EXISTS", "角色编码已存在")
 		}
@@ -155,6 +170,7 @@ func (s *RoleService) CreateRole(ctx context.Context, req *CreateRoleRequest) (*
 		IsSystemRole: createdRole.IsSystemRole,
 		IsEnabled:    createdRole.IsEnabled,
 		SortOrder:    createdRole.SortOrder,
+		ParentRoleID: createdRole.ParentRoleID,
 		CreatedAt:    createdRole.CreatedAt,
 		UpdatedAt:    createdRole.UpdatedAt,
 		Permissions:  s.convertToPermissionInfos(permissions),
@@ -178,6 +194,13 @@ func (s *RoleService) GetRole(ctx context.Context, roleID int32) (*RoleInfo, err
 	// 获取权限
 	permissions, _ := s.roleUc.GetRolePermissions(ctx, role.ID)
 
+	// 获取生效权限规则（含继承自上级角色的规则）
+	rules, err := s.permissionUc.ListEffectivePermissionRules(ctx, int64(role.ID), "")
+	if err != nil {
+		s.log.Errorf("Failed to list effective permission rules: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "角色权限规则获取失败")
+	}
+
 	return &RoleInfo{
 		ID:           role.ID,
 		Name:         role.Name,
@@ -186,9 +209,12 @@ func (s *RoleService) GetRole(ctx context.Context, roleID int32) (*RoleInfo, err
 		IsSystemRole: role.IsSystemRole,
 		IsEnabled:    role.IsEnabled,
 		SortOrder:    role.SortOrder,
+		ParentRoleID: role.ParentRoleID,
 		CreatedAt:    role.CreatedAt,
 		UpdatedAt:    role.UpdatedAt,
 		Permissions:  s.convertToPermissionInfos(permissions),
+
+		PermissionRules: rules,
 	}, nil
 }
 
@@ -214,18 +240,57 @@ func (s *RoleService) UpdateRole(ctx context.Context, req *UpdateRoleRequest) (*
 	}
 
 	// 更新角色信息
-	role.Name = req.Name
-	role.Description = req.Description
-	role.IsEnabled = req.IsEnabled
-	role.SortOrder = req.SortOrder
+	updates := fieldUpdates{}
+	if req.Name != role.Name {
+		updates["name"] = func() { role.Name = req.Name }
+	}
+	if req.Description != role.Description {
+		updates["description"] = func() { role.Description = req.Description }
+	}
+	if req.IsEnabled != role.IsEnabled {
+		updates["is_enabled"] = func() { role.IsEnabled = req.IsEnabled }
+	}
+	if req.SortOrder != role.SortOrder {
+		updates["sort_order"] = func() { role.SortOrder = req.SortOrder }
+	}
+	if req.ClearParent && req.ParentRoleID != nil {
+		return nil, errors.BadRequest("INVALID_PARENT_ROLE", "不能同时指定上级角色和取消继承")
+	}
+	parentRoleID := role.ParentRoleID
+	if req.ParentRoleID != nil {
+		parentRoleID = req.ParentRoleID
+	} else if req.ClearParent {
+		parentRoleID = nil
+	}
+	if !biz.SameParentID(parentRoleID, role.ParentRoleID) {
+		updates["parent_role_id"] = func() { role.ParentRoleID = parentRoleID }
+	}
+	if err := applyFieldUpdates(ctx, s.permissionUc, s.log, "Role", updates); err != nil {
+		return nil, err
+	}
 	role.UpdatedAt = time.Now()
 
-	updatedRole, err := s.roleUc.UpdateRole(ctx, role)
+	// 调整继承关系会改变持有该角色的用户获得的权限，需复核职责分离策略
+	var updatedRole *biz.Role
+	_, err = s.sodUc.Enforce(ctx, nil, func(ctx context.Context) error {
+		var err error
+		updatedRole, err = s.roleUc.UpdateRole(ctx, role)
+		return err
+	})
 	if err != nil {
+		if sodErr := convertSoDViolation(err); sodErr != nil {
+			return nil, sodErr
+		}
 		s.log.Errorf("Failed to update role: %v", err)
+		if inheritanceErr := convertRoleInheritanceError(err); inheritanceErr != nil {
+			return nil, inheritanceErr
+		}
 		if err == biz.ErrRoleNameExists {
 			return nil, errors.BadRequest("ROLE_NAME_EXISTS", "角色名称已存在")
 		}
+		if err == biz.ErrLastSuperAdmin {
+			return nil, errors.Conflict("LAST_SUPER_ADMIN", "系统至少需要保留一个启用的超级管理员")
+		}
 		return nil, errors.InternalServer("INTERNAL_ERROR", "角色更新失败")
 	}
 
@@ -249,6 +314,7 @@ func (s *RoleService) UpdateRole(ctx context.Context, req *UpdateRoleRequest) (*
 		IsSystemRole: updatedRole.IsSystemRole,
 		IsEnabled:    updatedRole.IsEnabled,
 		SortOrder:    updatedRole.SortOrder,
+		ParentRoleID: updatedRole.ParentRoleID,
 		CreatedAt:    updatedRole.CreatedAt,
 		UpdatedAt:    updatedRole.UpdatedAt,
 		Permissions:  s.convertToPermissionInfos(permissions),
@@ -266,7 +332,7 @@ func (s *RoleService) DeleteRole(ctx context.Context, roleID int32) error {
 	s.log.Infof("Deleting role: %d by %s", roleID, currentUser.Username)
 
 	// 删除角色
-	if err := s.roleUc.DeleteRole(ctx, roleID); err != nil {
+	if err := s.roleUc.DeleteRole(ctx, roleID, int32(currentUser.ID)); err != nil {
 		s.log.Errorf("Failed to delete role: %v", err)
 		if err == biz.ErrCannotDeleteSystemRole {
 			return errors.BadRequest("CANNOT_DELETE_SYSTEM_ROLE", "不能删除系统角色")
@@ -274,6 +340,12 @@ func (s *RoleService) DeleteRole(ctx context.Context, roleID int32) error {
 		if err == biz.ErrRoleInUse {
 			return errors.BadRequest("ROLE_IN_USE", "角色正在使用中，无法删除")
 		}
+		if err == biz.ErrRoleHasChildren {
+			return errors.BadRequest("ROLE_HAS_CHILDREN", "角色被其他角色继承，无法删除")
+		}
+		if err == biz.ErrLastSuperAdmin {
+			return errors.Conflict("LAST_SUPER_ADMIN", "系统至少需要保留一个启用的超级管理员")
+		}
 		return errors.InternalServer("INTERNAL_ERROR", "角色删除失败")
 	}
 
@@ -315,6 +387,7 @@ func (s *RoleService) ListRoles(ctx context.Context, req *RoleListRequest) (*Rol
 			IsSystemRole: role.IsSystemRole,
 			IsEnabled:    role.IsEnabled,
 			SortOrder:    role.SortOrder,
+			ParentRoleID: role.ParentRoleID,
 			CreatedAt:    role.CreatedAt,
 			UpdatedAt:    role.UpdatedAt,
 		}
@@ -379,6 +452,7 @@ func (s *RoleService) GetEnabledRoles(ctx context.Context) ([]*RoleInfo, error)
 			IsSystemRole: role.IsSystemRole,
 			IsEnabled:    role.IsEnabled,
 			SortOrder:    role.SortOrder,
+			ParentRoleID: role.ParentRoleID,
 			CreatedAt:    role.CreatedAt,
 			UpdatedAt:    role.UpdatedAt,
 		}
@@ -415,3 +489,16 @@ func (s *RoleService) convertToPermissionInfos(permissions []*biz.Permission) []
 
 	return infos
 }
+
+// convertRoleInheritanceError 转换角色继承相关的业务错误，非继承错误返回nil
+func convertRoleInheritanceError(err error) error {
+	switch err {
+	case biz.ErrParentRoleNotFound:
+		return errors.BadRequest("PARENT_ROLE_NOT_FOUND", "上级角色不存在")
+	case biz.ErrRoleInheritanceCycle:
+		return errors.BadRequest("ROLE_INHERITANCE_CYCLE", "角色继承关系不能形成循环")
+	case biz.ErrRoleInheritanceTooDeep:
+		return errors.BadRequest("ROLE_INHERITANCE_TOO_DEEP", "角色继承层级过深")
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// roleAssignmentCleanupInterval 过期角色分配清理任务的执行间隔
+const roleAssignmentCleanupInterval = 10 * time.Minute
+
+// RoleAssignmentService 限时角色分配与角色委托服务
+type RoleAssignmentService struct {
+	assignmentUc *biz.RoleAssignmentUsecase
+	userUc       *biz.UserUsecase
+	roleUc       *biz.RoleUsecase
+	sodUc        *biz.SoDUsecase
+	log          *log.Helper
+}
+
+// NewRoleAssignmentService 创建角色分配服务
+func NewRoleAssignmentService(assignmentUc *biz.RoleAssignmentUsecase, userUc *biz.UserUsecase, roleUc *biz.RoleUsecase, sodUc *biz.SoDUsecase, logger log.Logger) *RoleAssignmentService {
+	return &RoleAssignmentService{
+		assignmentUc: assignmentUc,
+		userUc:       userUc,
+		roleUc:       roleUc,
+		sodUc:        sodUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// RoleAssignmentRequest 新增限时角色分配请求
+type RoleAssignmentRequest struct {
+	RoleID     int32      `json:"role_id" validate:"required"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	Reason     string     `json:"reason"`
+}
+
+// DelegateRoleRequest 角色委托请求
+type DelegateRoleRequest struct {
+	UserID     int32      `json:"user_id" validate:"required"` // 受托人
+	RoleID     int32      `json:"role_id" validate:"required"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until" validate:"required"`
+	Reason     string     `json:"reason"`
+}
+
+// ListRoleAssignmentsResponse 角色分配列表响应
+type ListRoleAssignmentsResponse struct {
+	Assignments []*biz.RoleAssignment `json:"assignments"`
+	Total       int32                 `json:"total"`
+}
+
+// ListUserRoleAssignments 获取用户的角色分配，管理员或用户本人可查看
+func (s *RoleAssignmentService) ListUserRoleAssignments(ctx context.Context, userID int32, includeExpired bool) (*ListRoleAssignmentsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if currentUser.ID != int64(userID) && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看用户角色分配")
+	}
+
+	assignments, err := s.assignmentUc.ListUserAssignments(ctx, userID, includeExpired)
+	if err != nil {
+		return nil, s.convertError(err, "获取角色分配失败")
+	}
+
+	return &ListRoleAssignmentsResponse{
+		Assignments: assignments,
+		Total:       int32(len(assignments)),
+	}, nil
+}
+
+// CreateRoleAssignment 为用户新增限时角色分配
+func (s *RoleAssignmentService) CreateRoleAssignment(ctx context.Context, userID int32, req *RoleAssignmentRequest) (*biz.RoleAssignment, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限分配角色")
+	}
+
+	if err := s.checkTarget(ctx, userID, req.RoleID); err != nil {
+		return nil, err
+	}
+
+	var assignment *biz.RoleAssignment
+	_, err := s.sodUc.Enforce(ctx, []int32{userID}, func(ctx context.Context) error {
+		var err error
+		assignment, err = s.assignmentUc.AssignRole(ctx, &biz.RoleAssignment{
+			UserID:     userID,
+			RoleID:     req.RoleID,
+			ValidFrom:  req.ValidFrom,
+			ValidUntil: req.ValidUntil,
+			Reason:     req.Reason,
+		}, currentUser.ID)
+		return err
+	})
+	if err != nil {
+		return nil, s.convertError(err, "角色分配失败")
+	}
+
+	s.log.Infof("Role %d assigned to user %d by %s (valid %v - %v)", req.RoleID, userID, currentUser.Username, req.ValidFrom, req.ValidUntil)
+	return assignment, nil
+}
+
+// RevokeRoleAssignment 撤销用户的角色分配
+func (s *RoleAssignmentService) RevokeRoleAssignment(ctx context.Context, userID int32, id int64) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限撤销角色分配")
+	}
+
+	assignment, err := s.assignmentUc.GetAssignment(ctx, id)
+	if err != nil {
+		return s.convertError(err, "获取角色分配失败")
+	}
+	if assignment.UserID != userID {
+		return errors.NotFound("ROLE_ASSIGNMENT_NOT_FOUND", "角色分配不存在")
+	}
+
+	if _, err := s.assignmentUc.RevokeAssignment(ctx, id); err != nil {
+		return s.convertError(err, "撤销角色分配失败")
+	}
+
+	s.log.Infof("Role assignment %d of user %d revoked by %s", id, userID, currentUser.Username)
+	return nil
+}
+
+// DelegateRole 当前用户将自身持有的角色在一段时间内委托给同事
+func (s *RoleAssignmentService) DelegateRole(ctx context.Context, req *DelegateRoleRequest) (*biz.RoleAssignment, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.IsAuthenticated() {
+		return nil, errors.Unauthorized("UNAUTHORIZED", "用户未认证")
+	}
+
+	if err := s.checkTarget(ctx, req.UserID, req.RoleID); err != nil {
+		return nil, err
+	}
+
+	var assignment *biz.RoleAssignment
+	_, err := s.sodUc.Enforce(ctx, []int32{req.UserID}, func(ctx context.Context) error {
+		var err error
+		assignment, err = s.assignmentUc.DelegateRole(ctx, int32(currentUser.ID), &biz.RoleAssignment{
+			UserID:     req.UserID,
+			RoleID:     req.RoleID,
+			ValidFrom:  req.ValidFrom,
+			ValidUntil: req.ValidUntil,
+			Reason:     req.Reason,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, s.convertError(err, "角色委托失败")
+	}
+
+	s.log.Infof("Role %d delegated by %s to user %d until %v", req.RoleID, currentUser.Username, req.UserID, req.ValidUntil)
+	return assignment, nil
+}
+
+// ListMyDelegations 获取当前用户发出的角色委托
+func (s *RoleAssignmentService) ListMyDelegations(ctx context.Context) (*ListRoleAssignmentsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.IsAuthenticated() {
+		return nil, errors.Unauthorized("UNAUTHORIZED", "用户未认证")
+	}
+
+	delegations, err := s.assignmentUc.ListDelegations(ctx, int32(currentUser.ID))
+	if err != nil {
+		return nil, s.convertError(err, "获取角色委托失败")
+	}
+
+	return &ListRoleAssignmentsResponse{
+		Assignments: delegations,
+		Total:       int32(len(delegations)),
+	}, nil
+}
+
+// RevokeDelegation 撤销角色委托，委托人本人或管理员可撤销
+func (s *RoleAssignmentService) RevokeDelegation(ctx context.Context, id int64) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+
+	assignment, err := s.assignmentUc.GetAssignment(ctx, id)
+	if err != nil {
+		return s.convertError(err, "获取角色委托失败")
+	}
+	if !assignment.IsDelegated() {
+		return errors.NotFound("ROLE_DELEGATION_NOT_FOUND", "角色委托不存在")
+	}
+	if *assignment.DelegatedBy != currentUser.ID && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限撤销该角色委托")
+	}
+
+	if _, err := s.assignmentUc.RevokeAssignment(ctx, id); err != nil {
+		return s.convertError(err, "撤销角色委托失败")
+	}
+
+	s.log.Infof("Role delegation %d revoked by %s", id, currentUser.Username)
+	return nil
+}
+
+// StartRoleAssignmentCleanupTask 启动过期角色分配清理任务，启动时立即执行一次
+func (s *RoleAssignmentService) StartRoleAssignmentCleanupTask(ctx context.Context) {
+	ticker := time.NewTicker(roleAssignmentCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := s.assignmentUc.CleanupExpiredAssignments(ctx, time.Now())
+		if err != nil {
+			s.log.Errorf("Role assignment cleanup error: %v", err)
+		} else if removed > 0 {
+			s.log.Infof("Removed %d expired role assignments", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("Role assignment cleanup task stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkTarget 检查被分配的用户和角色是否存在
+func (s *RoleAssignmentService) checkTarget(ctx context.Context, userID, roleID int32) error {
+	if _, err := s.userUc.GetUser(ctx, userID); err != nil {
+		return errors.NotFound("USER_NOT_FOUND", "用户不存在")
+	}
+
+	role, err := s.roleUc.GetRole(ctx, roleID)
+	if err != nil {
+		return errors.NotFound("ROLE_NOT_FOUND", "角色不存在")
+	}
+	if !role.IsEnabled {
+		return errors.BadRequest("ROLE_DISABLED", "角色已禁用")
+	}
+	return nil
+}
+
+// convertError 将角色分配业务错误转换为API错误
+func (s *RoleAssignmentService) convertError(err error, message string) error {
+	var violation *biz.SoDViolationError
+	switch {
+	case stderrors.Is(err, biz.ErrRoleAssignmentNotFound):
+		return errors.NotFound("ROLE_ASSIGNMENT_NOT_FOUND", "角色分配不存在")
+	case stderrors.Is(err, biz.ErrInvalidAssignmentPeriod):
+		return errors.BadRequest("INVALID_ASSIGNMENT_PERIOD", "生效时间须早于失效时间，且失效时间不能早于当前时间")
+	case stderrors.Is(err, biz.ErrDelegationPeriodRequired):
+		return errors.BadRequest("DELEGATION_PERIOD_REQUIRED", "角色委托必须指定失效时间")
+	case stderrors.Is(err, biz.ErrDelegationToSelf):
+		return errors.BadRequest("DELEGATION_TO_SELF", "不能将角色委托给自己")
+	case stderrors.Is(err, biz.ErrDelegatorLacksRole):
+		return errors.Forbidden("DELEGATOR_LACKS_ROLE", "只能委托本人直接持有且当前有效的角色")
+	case stderrors.Is(err, biz.ErrDelegationExceedsGrant):
+		return errors.BadRequest("DELEGATION_EXCEEDS_GRANT", "委托期限不能超过本人角色分配的有效期")
+	case stderrors.As(err, &violation):
+		return sodConflictError(violation.Conflicts)
+	case stderrors.Is(err, biz.ErrLastSuperAdmin):
+		return errors.Conflict("LAST_SUPER_ADMIN", "系统至少需要保留一个启用的超级管理员")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubRoleRepo 仅实现更新角色所需的方法
+type stubRoleRepo struct {
+	biz.RoleRepo
+	roles map[int32]*biz.Role
+}
+
+func (r *stubRoleRepo) LockRoleHierarchy(ctx context.Context) error {
+	return nil
+}
+
+func (r *stubRoleRepo) GetRole(ctx context.Context, id int32) (*biz.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, fmt.Errorf("role not found")
+	}
+	copied := *role
+	return &copied, nil
+}
+
+func (r *stubRoleRepo) UpdateRole(ctx context.Context, role *biz.Role) (*biz.Role, error) {
+	r.roles[role.ID] = role
+	return role, nil
+}
+
+func (r *stubRoleRepo) GetRolePermissions(ctx context.Context, roleID int32) ([]*biz.Permission, error) {
+	return nil, nil
+}
+
+// stubFieldLevelPermissionRepo 未配置字段级别，不限制字段写入
+type stubFieldLevelPermissionRepo struct {
+	biz.PermissionRepo
+}
+
+func (r *stubFieldLevelPermissionRepo) ListFieldPermissionLevels(ctx context.Context, docType string, page, size int32) ([]*biz.FieldPermissionLevel, error) {
+	return nil, nil
+}
+
+func (r *stubFieldLevelPermissionRepo) ClearRolePermissionCache(ctx context.Context, roleID int64) error {
+	return nil
+}
+
+func TestRoleService_UpdateRoleParent(t *testing.T) {
+	parentID := int32(1)
+	roleRepo := &stubRoleRepo{roles: map[int32]*biz.Role{
+		1: {ID: 1, Name: "Clerk", Code: "CLERK", IsEnabled: true},
+		2: {ID: 2, Name: "Manager", Code: "MANAGER", IsEnabled: true, ParentRoleID: &parentID},
+	}}
+	permRepo := &stubFieldLevelPermissionRepo{}
+	svc := NewRoleService(biz.NewRoleUsecase(roleRepo, permRepo, stubTransaction{}, log.DefaultLogger),
+		biz.NewPermissionUsecase(permRepo, log.DefaultLogger), biz.NewSoDUsecase(&stubSoDRepo{}, stubTransaction{}, log.DefaultLogger),
+		log.DefaultLogger)
+
+	ctx := middleware.SetUserIDToContext(context.Background(), 1)
+	ctx = middleware.SetUsernameToContext(ctx, "admin")
+	ctx = middleware.SetUserRolesToContext(ctx, []string{"SUPER_ADMIN"})
+
+	// 未指定上级角色的部分更新保持原有继承关系
+	info, err := svc.UpdateRole(ctx, &UpdateRoleRequest{ID: 2, Name: "Manager", Description: "部门经理", IsEnabled: true})
+	assert.NoError(t, err)
+	if assert.NotNil(t, info.ParentRoleID) {
+		assert.Equal(t, parentID, *info.ParentRoleID)
+	}
+	assert.Equal(t, "部门经理", roleRepo.roles[2].Description)
+
+	_, err = svc.UpdateRole(ctx, &UpdateRoleRequest{ID: 2, Name: "Manager", IsEnabled: true, ParentRoleID: &parentID, ClearParent: true})
+	assert.True(t, errors.IsBadRequest(err))
+
+	// 显式取消继承
+	info, err = svc.UpdateRole(ctx, &UpdateRoleRequest{ID: 2, Name: "Manager", Description: "部门经理", IsEnabled: true, ClearParent: true})
+	assert.NoError(t, err)
+	assert.Nil(t, info.ParentRoleID)
+	assert.Nil(t, roleRepo.roles[2].ParentRoleID)
+}
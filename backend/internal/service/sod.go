@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// SoDService 职责分离策略服务
+type SoDService struct {
+	sodUc        *biz.SoDUsecase
+	permissionUc *biz.PermissionUsecase
+	log          *log.Helper
+}
+
+// NewSoDService 创建职责分离策略服务
+func NewSoDService(sodUc *biz.SoDUsecase, permissionUc *biz.PermissionUsecase, logger log.Logger) *SoDService {
+	return &SoDService{
+		sodUc:        sodUc,
+		permissionUc: permissionUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// SoDPolicyRequest 创建/更新职责分离策略请求
+type SoDPolicyRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	Description string `json:"description"`
+	PolicyType  string `json:"policy_type" validate:"required"`
+	RoleAID     *int64 `json:"role_a_id"`
+	RoleBID     *int64 `json:"role_b_id"`
+	DocType     string `json:"doc_type"`
+	ActionA     string `json:"action_a"`
+	ActionB     string `json:"action_b"`
+	Enforcement string `json:"enforcement"`
+	IsEnabled   *bool  `json:"is_enabled"`
+}
+
+// ListSoDPoliciesResponse 职责分离策略列表响应
+type ListSoDPoliciesResponse struct {
+	Policies []*biz.SoDPolicy `json:"policies"`
+	Total    int32            `json:"total"`
+}
+
+// SoDViolationReport 职责分离违规报告
+type SoDViolationReport struct {
+	Violations []*biz.SoDConflict `json:"violations"`
+	Total      int32              `json:"total"`
+}
+
+// CreatePolicy 创建职责分离策略
+func (s *SoDService) CreatePolicy(ctx context.Context, req *SoDPolicyRequest) (*biz.SoDPolicy, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限创建职责分离策略")
+	}
+
+	policy := &biz.SoDPolicy{IsEnabled: true, CreatedBy: &currentUser.ID}
+	req.apply(policy)
+	if err := policy.Validate(); err != nil {
+		return nil, errors.BadRequest("INVALID_SOD_POLICY", err.Error())
+	}
+
+	created, err := s.sodUc.CreatePolicy(ctx, policy)
+	if err != nil {
+		return nil, s.convertError(err, "职责分离策略创建失败")
+	}
+
+	s.log.Infof("SoD policy created successfully: %s", created.Name)
+	return created, nil
+}
+
+// UpdatePolicy 更新职责分离策略
+func (s *SoDService) UpdatePolicy(ctx context.Context, id int64, req *SoDPolicyRequest) (*biz.SoDPolicy, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改职责分离策略")
+	}
+
+	policy, err := s.sodUc.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, s.convertError(err, "获取职责分离策略失败")
+	}
+
+	changed := *policy
+	req.apply(&changed)
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "SoD Policy", policy, &changed); err != nil {
+		return nil, err
+	}
+	changed.UpdatedBy = &currentUser.ID
+	if err := changed.Validate(); err != nil {
+		return nil, errors.BadRequest("INVALID_SOD_POLICY", err.Error())
+	}
+
+	updated, err := s.sodUc.UpdatePolicy(ctx, &changed)
+	if err != nil {
+		return nil, s.convertError(err, "职责分离策略更新失败")
+	}
+
+	s.log.Infof("SoD policy updated successfully: %d", id)
+	return updated, nil
+}
+
+// GetPolicy 获取职责分离策略详情
+func (s *SoDService) GetPolicy(ctx context.Context, id int64) (*biz.SoDPolicy, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看职责分离策略")
+	}
+
+	policy, err := s.sodUc.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, s.convertError(err, "获取职责分离策略失败")
+	}
+	return policy, nil
+}
+
+// ListPolicies 获取职责分离策略列表
+func (s *SoDService) ListPolicies(ctx context.Context) (*ListSoDPoliciesResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看职责分离策略")
+	}
+
+	policies, err := s.sodUc.ListPolicies(ctx)
+	if err != nil {
+		return nil, s.convertError(err, "获取职责分离策略列表失败")
+	}
+
+	return &ListSoDPoliciesResponse{
+		Policies: policies,
+		Total:    int32(len(policies)),
+	}, nil
+}
+
+// DeletePolicy 删除职责分离策略
+func (s *SoDService) DeletePolicy(ctx context.Context, id int64) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限删除职责分离策略")
+	}
+
+	if err := s.sodUc.DeletePolicy(ctx, id); err != nil {
+		return s.convertError(err, "职责分离策略删除失败")
+	}
+
+	s.log.Infof("SoD policy deleted successfully: %d", id)
+	return nil
+}
+
+// ListViolations 列出当前已存在的职责分离冲突，policyID非零时仅检查该策略
+func (s *SoDService) ListViolations(ctx context.Context, policyID int64) (*SoDViolationReport, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看职责分离违规报告")
+	}
+
+	violations, err := s.sodUc.ListViolations(ctx, policyID)
+	if err != nil {
+		return nil, s.convertError(err, "获取职责分离违规报告失败")
+	}
+
+	return &SoDViolationReport{
+		Violations: violations,
+		Total:      int32(len(violations)),
+	}, nil
+}
+
+// apply 将请求内容写入策略
+func (req *SoDPolicyRequest) apply(policy *biz.SoDPolicy) {
+	policy.Name = req.Name
+	policy.Description = req.Description
+	policy.PolicyType = req.PolicyType
+	policy.RoleAID = req.RoleAID
+	policy.RoleBID = req.RoleBID
+	policy.DocType = req.DocType
+	policy.ActionA = req.ActionA
+	policy.ActionB = req.ActionB
+	policy.Enforcement = req.Enforcement
+	if policy.Enforcement == "" {
+		policy.Enforcement = biz.SoDEnforcementBlock
+	}
+	if req.IsEnabled != nil {
+		policy.IsEnabled = *req.IsEnabled
+	}
+}
+
+// convertError 将职责分离业务错误转换为API错误
+func (s *SoDService) convertError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, biz.ErrSoDPolicyNotFound):
+		return errors.NotFound("SOD_POLICY_NOT_FOUND", "职责分离策略不存在")
+	case stderrors.Is(err, biz.ErrSoDPolicyNameExists):
+		return errors.BadRequest("SOD_POLICY_EXISTS", "职责分离策略名称已存在")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
+
+// sodConflictError 将阻止操作的职责分离冲突转换为API错误
+func sodConflictError(conflicts []*biz.SoDConflict) error {
+	details := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		details = append(details, conflict.PolicyName+": "+conflict.Message)
+	}
+	return errors.Conflict("SOD_CONFLICT", "违反职责分离策略").WithMetadata(map[string]string{
+		"details": strings.Join(details, "; "),
+	})
+}
+
+// convertSoDViolation 变更被职责分离策略阻止时返回对应的API错误，其他错误返回nil
+func convertSoDViolation(err error) error {
+	var violation *biz.SoDViolationError
+	if stderrors.As(err, &violation) {
+		return sodConflictError(violation.Conflicts)
+	}
+	return nil
+}
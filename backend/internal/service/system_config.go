@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// SystemConfigService 系统配置服务
+type SystemConfigService struct {
+	configUc *biz.SystemConfigUsecase
+	log      *log.Helper
+}
+
+// NewSystemConfigService 创建系统配置服务
+func NewSystemConfigService(configUc *biz.SystemConfigUsecase, logger log.Logger) *SystemConfigService {
+	return &SystemConfigService{
+		configUc: configUc,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// SetSystemConfigRequest 设置当前公司配置值请求
+type SetSystemConfigRequest struct {
+	Value string `json:"value"`
+}
+
+// ListSystemConfigsResponse 系统配置列表响应
+type ListSystemConfigsResponse struct {
+	Configs []*biz.SystemConfig `json:"configs"`
+	Total   int32               `json:"total"`
+}
+
+// ListConfigs 获取当前公司生效的系统配置
+func (s *SystemConfigService) ListConfigs(ctx context.Context) (*ListSystemConfigsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看系统配置")
+	}
+
+	configs, err := s.configUc.ListConfigs(ctx)
+	if err != nil {
+		return nil, s.convertError(err, "获取系统配置失败")
+	}
+	return &ListSystemConfigsResponse{Configs: configs, Total: int32(len(configs))}, nil
+}
+
+// GetConfig 获取当前公司生效的系统配置
+func (s *SystemConfigService) GetConfig(ctx context.Context, key string) (*biz.SystemConfig, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看系统配置")
+	}
+
+	config, err := s.configUc.GetConfig(ctx, key)
+	if err != nil {
+		return nil, s.convertError(err, "获取系统配置失败")
+	}
+	return config, nil
+}
+
+// SetCompanyConfig 设置当前公司的配置值，覆盖全局默认值
+func (s *SystemConfigService) SetCompanyConfig(ctx context.Context, key string, req *SetSystemConfigRequest) (*biz.SystemConfig, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改系统配置")
+	}
+
+	config, err := s.configUc.SetCompanyConfig(ctx, key, req.Value, currentUser.ID)
+	if err != nil {
+		return nil, s.convertError(err, "系统配置修改失败")
+	}
+
+	s.log.Infof("System config %s of company %d set by user %d", key, biz.CompanyFromContext(ctx), currentUser.ID)
+	return config, nil
+}
+
+// ResetCompanyConfig 删除当前公司的配置值，恢复使用全局默认值
+func (s *SystemConfigService) ResetCompanyConfig(ctx context.Context, key string) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限修改系统配置")
+	}
+
+	if err := s.configUc.ResetCompanyConfig(ctx, key); err != nil {
+		return s.convertError(err, "系统配置恢复失败")
+	}
+
+	s.log.Infof("System config %s of company %d reset by user %d", key, biz.CompanyFromContext(ctx), currentUser.ID)
+	return nil
+}
+
+// convertError 将系统配置业务错误转换为API错误
+func (s *SystemConfigService) convertError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, biz.ErrSystemConfigNotFound):
+		return errors.NotFound("SYSTEM_CONFIG_NOT_FOUND", "系统配置不存在")
+	case stderrors.Is(err, biz.ErrSystemConfigInvalid):
+		return errors.BadRequest("INVALID_SYSTEM_CONFIG", err.Error())
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"erp-system/internal/biz"
@@ -14,21 +15,33 @@ import (
 
 // UserService 用户服务
 type UserService struct {
-	userUc *biz.UserUsecase
-	pwdMgr *pkg.PasswordManager
-	log    *log.Helper
+	userUc       *biz.UserUsecase
+	adminUc      *biz.UserAdminUsecase
+	profileUc    *biz.ProfileUsecase
+	permissionUc *biz.PermissionUsecase
+	sodUc        *biz.SoDUsecase
+	pwdMgr       *pkg.PasswordManager
+	log          *log.Helper
 }
 
 // NewUserService 创建用户服务
 func NewUserService(
 	userUc *biz.UserUsecase,
+	adminUc *biz.UserAdminUsecase,
+	profileUc *biz.ProfileUsecase,
+	permissionUc *biz.PermissionUsecase,
+	sodUc *biz.SoDUsecase,
 	pwdMgr *pkg.PasswordManager,
 	logger log.Logger,
 ) *UserService {
 	return &UserService{
-		userUc: userUc,
-		pwdMgr: pwdMgr,
-		log:    log.NewHelper(logger),
+		userUc:       userUc,
+		adminUc:      adminUc,
+		profileUc:    profileUc,
+		permissionUc: permissionUc,
+		sodUc:        sodUc,
+		pwdMgr:       pwdMgr,
+		log:          log.NewHelper(logger),
 	}
 }
 
@@ -83,6 +96,12 @@ type AssignRolesRequest struct {
 	RoleIDs []int32 `json:"role_ids" validate:"required"`
 }
 
+// AssignRolesResponse 分配角色响应
+type AssignRolesResponse struct {
+	Message  string             `json:"message"`
+	Warnings []*biz.SoDConflict `json:"warnings,omitempty"` // 仅警告的职责分离冲突
+}
+
 // ResetPasswordRequest 重置密码请求
 type ResetPasswordRequest struct {
 	UserID      int32  `json:"user_id" validate:"required"`
@@ -163,7 +182,13 @@ func (s *UserService) CreateUser(ctx context.Context, req *CreateUserRequest) (*
 	}
 
 	// 分配角色
-	if err := s.userUc.AssignRoles(ctx, createdUser.ID, req.RoleIDs); err != nil {
+	if _, err := s.assignRoles(ctx, createdUser.ID, req.RoleIDs); err != nil {
+		if sodErr := convertSoDViolation(err); sodErr != nil {
+			return nil, sodErr
+		}
+		if err == biz.ErrLastSuperAdmin {
+			return nil, errors.Conflict("LAST_SUPER_ADMIN", "系统至少需要保留一个启用的超级管理员")
+		}
 		s.log.Errorf("Failed to assign roles: %v", err)
 		return nil, errors.InternalServer("INTERNAL_ERROR", "角色分配失败")
 	}
@@ -239,26 +264,62 @@ func (s *UserService) UpdateUser(ctx context.Context, req *UpdateUserRequest) (*
 		return nil, errors.BadRequest("EMAIL_EXISTS", "邮箱已被使用")
 	}
 
-	// 更新用户信息
-	user.Email = req.Email
-	user.FirstName = req.FirstName
-	user.LastName = req.LastName
-	user.Phone = req.Phone
-	user.Gender = req.Gender
-	user.AvatarURL = req.AvatarURL
-	user.UpdatedAt = time.Now()
+	// 收集实际变化的字段，交由字段写权限检查
+	updates := fieldUpdates{}
+	if req.Email != user.Email {
+		updates["email"] = func() { user.Email = req.Email }
+	}
+	if req.FirstName != user.FirstName {
+		updates["first_name"] = func() { user.FirstName = req.FirstName }
+	}
+	if req.LastName != user.LastName {
+		updates["last_name"] = func() { user.LastName = req.LastName }
+	}
+	if req.Phone != user.Phone {
+		updates["phone"] = func() { user.Phone = req.Phone }
+	}
+	if req.Gender != user.Gender {
+		updates["gender"] = func() { user.Gender = req.Gender }
+	}
+	if req.AvatarURL != user.AvatarURL {
+		updates["avatar_url"] = func() { user.AvatarURL = req.AvatarURL }
+	}
 
 	// 处理生日字段
 	if req.BirthDate != "" {
-		if birthDate, err := time.Parse("2006-01-02", req.BirthDate); err == nil {
-			user.BirthDate = birthDate
+		if birthDate, err := time.Parse("2006-01-02", req.BirthDate); err == nil && !birthDate.Equal(user.BirthDate) {
+			updates["birth_date"] = func() { user.BirthDate = birthDate }
 		}
 	}
 
-	// 只有管理员可以修改状态
-	if currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+	// 只有管理员可以修改状态，状态变更与ToggleUserStatus一样不能禁用自己或最后一个启用的超级管理员
+	statusChanged := false
+	if currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") && req.IsActive != user.IsActive {
+		updates["is_enabled"] = func() { statusChanged = true }
+	}
+
+	// 普通用户修改自己时遵循个人资料的字段策略，需要审批的字段只能通过变更申请修改
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		fields := make([]string, 0, len(updates))
+		for field := range updates {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		if err := s.profileUc.CheckDirectChanges(fields); err != nil {
+			return nil, convertProfileFieldError(err)
+		}
+	}
+
+	if err := applyFieldUpdates(ctx, s.permissionUc, s.log, "User", updates); err != nil {
+		return nil, err
+	}
+	if statusChanged {
+		if err := s.adminUc.SetUserStatus(ctx, req.ID, req.IsActive, int32(currentUser.ID), currentUser.HasAnyRole("SUPER_ADMIN")); err != nil {
+			return nil, s.convertAdminError(err, "用户状态更新失败")
+		}
 		user.IsActive = req.IsActive
 	}
+	user.UpdatedAt = time.Now()
 
 	updatedUser, err := s.userUc.UpdateUser(ctx, user)
 	if err != nil {
@@ -268,8 +329,15 @@ func (s *UserService) UpdateUser(ctx context.Context, req *UpdateUserRequest) (*
 
 	// 更新角色（只有管理员可以）
 	if len(req.RoleIDs) > 0 && currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
-		if err := s.userUc.AssignRoles(ctx, updatedUser.ID, req.RoleIDs); err != nil {
+		if _, err := s.assignRoles(ctx, updatedUser.ID, req.RoleIDs); err != nil {
+			if sodErr := convertSoDViolation(err); sodErr != nil {
+				return nil, sodErr
+			}
+			if err == biz.ErrLastSuperAdmin {
+				return nil, errors.Conflict("LAST_SUPER_ADMIN", "系统至少需要保留一个启用的超级管理员")
+			}
 			s.log.Errorf("Failed to assign roles: %v", err)
+			return nil, errors.InternalServer("INTERNAL_ERROR", "角色分配失败")
 		}
 	}
 
@@ -309,10 +377,9 @@ func (s *UserService) DeleteUser(ctx context.Context, userID int32) error {
 		return errors.NotFound("USER_NOT_FOUND", "用户不存在")
 	}
 
-	// 删除用户
-	if err := s.userUc.DeleteUser(ctx, userID); err != nil {
-		s.log.Errorf("Failed to delete user: %v", err)
-		return errors.InternalServer("INTERNAL_ERROR", "用户删除失败")
+	// 删除用户，不能删除最后一个启用的超级管理员
+	if err := s.adminUc.DeleteUser(ctx, userID, int32(currentUser.ID)); err != nil {
+		return s.convertAdminError(err, "用户删除失败")
 	}
 
 	s.log.Infof("User deleted successfully: %d", userID)
@@ -365,29 +432,47 @@ func (s *UserService) ListUsers(ctx context.Context, req *UserListRequest) (*Use
 	}, nil
 }
 
-// AssignRoles 分配用户角色
-func (s *UserService) AssignRoles(ctx context.Context, req *AssignRolesRequest) error {
+// AssignRoles 分配用户角色，违反职责分离策略时拒绝分配或返回警告
+func (s *UserService) AssignRoles(ctx context.Context, req *AssignRolesRequest) (*AssignRolesResponse, error) {
 	// 检查权限
 	currentUser := middleware.GetCurrentUser(ctx)
 	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
-		return errors.Forbidden("PERMISSION_DENIED", "无权限分配角色")
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限分配角色")
 	}
 
 	s.log.Infof("Assigning roles to user: %d by %s", req.UserID, currentUser.Username)
 
 	// 检查用户是否存在
 	if _, err := s.userUc.GetUser(ctx, req.UserID); err != nil {
-		return errors.NotFound("USER_NOT_FOUND", "用户不存在")
+		return nil, errors.NotFound("USER_NOT_FOUND", "用户不存在")
 	}
 
 	// 分配角色
-	if err := s.userUc.AssignRoles(ctx, req.UserID, req.RoleIDs); err != nil {
+	warnings, err := s.assignRoles(ctx, req.UserID, req.RoleIDs)
+	if err != nil {
+		if sodErr := convertSoDViolation(err); sodErr != nil {
+			s.log.Warnf("Role assignment to user %d rejected by SoD policies", req.UserID)
+			return nil, sodErr
+		}
+		if err == biz.ErrLastSuperAdmin {
+			return nil, errors.Conflict("LAST_SUPER_ADMIN", "系统至少需要保留一个启用的超级管理员")
+		}
 		s.log.Errorf("Failed to assign roles: %v", err)
-		return errors.InternalServer("INTERNAL_ERROR", "角色分配失败")
+		return nil, errors.InternalServer("INTERNAL_ERROR", "角色分配失败")
 	}
 
 	s.log.Infof("Roles assigned successfully to user: %d", req.UserID)
-	return nil
+	return &AssignRolesResponse{
+		Message:  "角色分配成功",
+		Warnings: warnings,
+	}, nil
+}
+
+// assignRoles 替换用户的长期角色，并在同一事务中复核职责分离策略
+func (s *UserService) assignRoles(ctx context.Context, userID int32, roleIDs []int32) ([]*biz.SoDConflict, error) {
+	return s.sodUc.Enforce(ctx, []int32{userID}, func(ctx context.Context) error {
+		return s.userUc.AssignRoles(ctx, userID, roleIDs)
+	})
 }
 
 // ResetPassword 重置用户密码
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+)
+
+// BatchDeleteUsersRequest 批量删除用户请求
+type BatchDeleteUsersRequest struct {
+	IDs []int32 `json:"ids" validate:"required"`
+}
+
+// ToggleUserStatusRequest 切换用户状态请求，省略is_enabled时在启用和禁用之间切换
+type ToggleUserStatusRequest struct {
+	UserID    int32 `json:"user_id" validate:"required"`
+	IsEnabled *bool `json:"is_enabled"`
+}
+
+// ToggleUserStatusResponse 切换用户状态响应
+type ToggleUserStatusResponse struct {
+	Message   string `json:"message"`
+	IsEnabled bool   `json:"is_enabled"`
+}
+
+// UserPermissionsResponse 用户当前生效的角色和权限
+type UserPermissionsResponse struct {
+	UserID      int32       `json:"user_id"`
+	Roles       []*biz.Role `json:"roles"`
+	Permissions []string    `json:"permissions"`
+}
+
+// BatchDeleteUsers 批量删除用户，在一个事务中执行并返回每个用户的结果；任一项失败时不删除任何用户
+func (s *UserService) BatchDeleteUsers(ctx context.Context, req *BatchDeleteUsersRequest) (*biz.UserBatchResult, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限删除用户")
+	}
+
+	s.log.Infof("Batch deleting %d users by %s", len(req.IDs), currentUser.Username)
+
+	result, err := s.adminUc.BatchDeleteUsers(ctx, req.IDs, int32(currentUser.ID))
+	if err != nil {
+		return nil, s.convertAdminError(err, "批量删除用户失败")
+	}
+
+	s.log.Infof("Batch delete finished: %d deleted, %d failed", result.Succeeded, result.Failed)
+	return result, nil
+}
+
+// ToggleUserStatus 启用或禁用用户，不能禁用自己或最后一个启用的超级管理员
+func (s *UserService) ToggleUserStatus(ctx context.Context, req *ToggleUserStatusRequest) (*ToggleUserStatusResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改用户状态")
+	}
+
+	enabled := false
+	if req.IsEnabled != nil {
+		enabled = *req.IsEnabled
+	} else {
+		user, err := s.userUc.GetUser(ctx, req.UserID)
+		if err != nil {
+			return nil, errors.NotFound("USER_NOT_FOUND", "用户不存在")
+		}
+		enabled = !user.IsActive
+	}
+
+	s.log.Infof("Setting status of user %d to enabled=%v by %s", req.UserID, enabled, currentUser.Username)
+
+	if err := s.adminUc.SetUserStatus(ctx, req.UserID, enabled, int32(currentUser.ID), currentUser.HasAnyRole("SUPER_ADMIN")); err != nil {
+		return nil, s.convertAdminError(err, "用户状态更新失败")
+	}
+
+	message := "用户已禁用"
+	if enabled {
+		message = "用户已启用"
+	}
+	return &ToggleUserStatusResponse{Message: message, IsEnabled: enabled}, nil
+}
+
+// RemoveUserRole 移除用户直接持有的角色，不能移除最后一个启用的超级管理员的超级管理员角色
+func (s *UserService) RemoveUserRole(ctx context.Context, userID, roleID int32) error {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限移除角色")
+	}
+
+	s.log.Infof("Removing role %d from user %d by %s", roleID, userID, currentUser.Username)
+
+	if err := s.adminUc.RemoveUserRole(ctx, userID, roleID, currentUser.HasAnyRole("SUPER_ADMIN")); err != nil {
+		return s.convertAdminError(err, "角色移除失败")
+	}
+
+	s.log.Infof("Role %d removed from user %d", roleID, userID)
+	return nil
+}
+
+// GetUserPermissions 获取用户当前生效的角色和权限，用户本人也可查看
+func (s *UserService) GetUserPermissions(ctx context.Context, userID int32) (*UserPermissionsResponse, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if currentUser.ID != int64(userID) && !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看用户权限")
+	}
+
+	if _, err := s.userUc.GetUser(ctx, userID); err != nil {
+		return nil, errors.NotFound("USER_NOT_FOUND", "用户不存在")
+	}
+
+	roles, err := s.userUc.GetUserRoles(ctx, userID)
+	if err != nil {
+		s.log.Errorf("Failed to get user roles: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "用户角色获取失败")
+	}
+	permissions, err := s.userUc.GetUserPermissions(ctx, userID)
+	if err != nil {
+		s.log.Errorf("Failed to get user permissions: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "用户权限获取失败")
+	}
+
+	if roles == nil {
+		roles = []*biz.Role{}
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return &UserPermissionsResponse{UserID: userID, Roles: roles, Permissions: permissions}, nil
+}
+
+// GetUserStats 获取用户统计，days为按天统计登录情况的天数
+func (s *UserService) GetUserStats(ctx context.Context, days int) (*biz.UserStats, error) {
+	// 检查权限
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看用户统计")
+	}
+
+	stats, err := s.adminUc.GetUserStats(ctx, days, time.Now())
+	if err != nil {
+		return nil, s.convertAdminError(err, "用户统计获取失败")
+	}
+	return stats, nil
+}
+
+// convertAdminError 将用户管理业务错误转换为API错误
+func (s *UserService) convertAdminError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, biz.ErrUserNotFound):
+		return errors.NotFound("USER_NOT_FOUND", "用户不存在")
+	case stderrors.Is(err, biz.ErrUserRoleNotFound):
+		return errors.NotFound("USER_ROLE_NOT_FOUND", "用户未直接持有该角色")
+	case stderrors.Is(err, biz.ErrUserBatchEmpty):
+		return errors.BadRequest("EMPTY_USER_BATCH", "请指定要操作的用户")
+	case stderrors.Is(err, biz.ErrUserBatchTooLarge):
+		return errors.BadRequest("USER_BATCH_TOO_LARGE", "单次最多操作500个用户")
+	case stderrors.Is(err, biz.ErrCannotDisableSelf):
+		return errors.BadRequest("CANNOT_DISABLE_SELF", "不能禁用自己")
+	case stderrors.Is(err, biz.ErrLastSuperAdmin):
+		return errors.Conflict("LAST_SUPER_ADMIN", "系统至少需要保留一个启用的超级管理员")
+	case stderrors.Is(err, biz.ErrSuperAdminRequired):
+		return errors.Forbidden("PERMISSION_DENIED", "只有超级管理员可以变更超级管理员")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
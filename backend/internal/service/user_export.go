@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 用户导出文件格式
+const (
+	UserExportFormatCSV  = "csv"
+	UserExportFormatXLSX = "xlsx"
+	UserExportFormatJSON = "json"
+)
+
+// userFilterModule 保存的用户列表过滤器所属模块
+const userFilterModule = "users"
+
+// UserExportService 用户导出服务
+type UserExportService struct {
+	userUc       *biz.UserUsecase
+	permissionUc *biz.PermissionUsecase
+	filterUc     *biz.UserFilterUsecase
+	log          *log.Helper
+}
+
+// NewUserExportService 创建用户导出服务
+func NewUserExportService(
+	userUc *biz.UserUsecase,
+	permissionUc *biz.PermissionUsecase,
+	filterUc *biz.UserFilterUsecase,
+	logger log.Logger,
+) *UserExportService {
+	return &UserExportService{
+		userUc:       userUc,
+		permissionUc: permissionUc,
+		filterUc:     filterUc,
+		log:          log.NewHelper(logger),
+	}
+}
+
+// UserExportRequest 导出用户请求，过滤和排序条件与用户列表一致
+type UserExportRequest struct {
+	Format           string                 `json:"format"`
+	Search           string                 `json:"search"`
+	FilterConditions map[string]interface{} `json:"filter_conditions"`
+	SortConfig       map[string]interface{} `json:"sort_config"`
+	FilterID         int32                  `json:"filter_id"` // 使用保存的过滤器，请求中的条件优先
+	Fields           []string               `json:"fields"`    // 导出的列，为空时导出全部可读的列
+}
+
+// UserExport 准备好的用户导出，写入时逐行查询用户
+type UserExport struct {
+	Filename    string
+	ContentType string
+	Columns     []string
+
+	format string
+	query  *biz.UserListQuery
+}
+
+// PrepareExport 校验User文档类型的导出权限，解析过滤条件并按字段级读权限确定导出的列
+func (s *UserExportService) PrepareExport(ctx context.Context, req *UserExportRequest) (*UserExport, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	canExport, err := s.permissionUc.CheckPermission(ctx, currentUser.ID, biz.UserDocType, "export", 0)
+	if err != nil {
+		s.log.Errorf("Failed to check export permission: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "权限检查失败")
+	}
+	if !canExport {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限导出用户")
+	}
+
+	export := &UserExport{format: req.Format}
+	if export.format == "" {
+		export.format = UserExportFormatCSV
+	}
+	switch export.format {
+	case UserExportFormatCSV:
+		export.ContentType = "text/csv; charset=utf-8"
+	case UserExportFormatXLSX:
+		export.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case UserExportFormatJSON:
+		export.ContentType = "application/json"
+	default:
+		return nil, errors.BadRequest("INVALID_FORMAT", "仅支持csv、xlsx或json格式")
+	}
+	export.Filename = fmt.Sprintf("users-%s.%s", time.Now().Format("20060102150405"), export.format)
+
+	export.query, err = s.buildQuery(ctx, currentUser, req)
+	if err != nil {
+		return nil, err
+	}
+
+	readable, err := s.readableFields(ctx, currentUser.ID)
+	if err != nil {
+		s.log.Errorf("Failed to get user field permissions: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "字段权限获取失败")
+	}
+
+	// 过滤和排序只能引用可读的字段，避免通过条件推断被屏蔽的值
+	for _, field := range export.query.ReferencedFields() {
+		if !biz.IsUserListField(field) {
+			return nil, errors.BadRequest("INVALID_FILTER_FIELD", "不支持的过滤或排序字段: "+field)
+		}
+		if !readable(field) {
+			return nil, errors.Forbidden("FIELD_PERMISSION_DENIED", "无权限按字段过滤或排序: "+field)
+		}
+	}
+
+	columns := req.Fields
+	if len(columns) == 0 {
+		columns = biz.UserExportColumns
+	}
+	for _, column := range columns {
+		if !biz.IsUserListField(column) {
+			return nil, errors.BadRequest("INVALID_EXPORT_FIELD", "不支持导出的字段: "+column)
+		}
+		if readable(column) {
+			export.Columns = append(export.Columns, column)
+		}
+	}
+	if len(export.Columns) == 0 {
+		return nil, errors.Forbidden("FIELD_PERMISSION_DENIED", "没有可导出的字段")
+	}
+
+	return export, nil
+}
+
+// WriteExport 将符合条件的用户逐行写入w；响应头已发出，出错时只记录日志，调用方得到截断的文件
+func (s *UserExportService) WriteExport(ctx context.Context, export *UserExport, w io.Writer) error {
+	var encoder biz.UserExportEncoder
+	var err error
+	switch export.format {
+	case UserExportFormatXLSX:
+		encoder, err = biz.NewUserXLSXEncoder(w, export.Columns)
+	case UserExportFormatJSON:
+		encoder, err = biz.NewUserJSONEncoder(w, export.Columns)
+	default:
+		encoder, err = biz.NewUserCSVEncoder(w, export.Columns)
+	}
+	if err != nil {
+		s.log.Errorf("Failed to start user export: %v", err)
+		return err
+	}
+
+	count, err := s.userUc.ExportUsers(ctx, export.query, encoder)
+	if err != nil {
+		s.log.Errorf("User export aborted after %d users: %v", count, err)
+		return err
+	}
+
+	currentUser := middleware.GetCurrentUser(ctx)
+	s.log.Infof("Users exported by %s: %d users (%s)", currentUser.Username, count, export.format)
+	return nil
+}
+
+// buildQuery 合并保存的过滤器和请求中的条件，过滤器必须属于当前用户或已公开
+func (s *UserExportService) buildQuery(ctx context.Context, currentUser *middleware.CurrentUser, req *UserExportRequest) (*biz.UserListQuery, error) {
+	query := &biz.UserListQuery{
+		Search:           req.Search,
+		FilterConditions: req.FilterConditions,
+		SortConfig:       req.SortConfig,
+	}
+	if req.FilterID == 0 {
+		return query, nil
+	}
+
+	filter, err := s.filterUc.GetFilter(ctx, req.FilterID)
+	if err != nil {
+		if err == biz.ErrFilterNotFound {
+			return nil, errors.NotFound("FILTER_NOT_FOUND", "过滤器不存在")
+		}
+		s.log.Errorf("Failed to get user filter: %v", err)
+		return nil, errors.InternalServer("INTERNAL_ERROR", "过滤器获取失败")
+	}
+	if filter.ModuleType != userFilterModule || (int64(filter.UserID) != currentUser.ID && !filter.IsPublic) {
+		return nil, errors.NotFound("FILTER_NOT_FOUND", "过滤器不存在")
+	}
+
+	if query.FilterConditions == nil {
+		query.FilterConditions = filter.FilterConditions
+	}
+	if query.SortConfig == nil {
+		query.SortConfig = filter.SortConfig
+	}
+	return query, nil
+}
+
+// readableFields 返回判断User字段是否可读的函数，未登记权限级别的字段按文档级(0)处理
+func (s *UserExportService) readableFields(ctx context.Context, userID int64) (func(string) bool, error) {
+	levelPerms, err := s.permissionUc.GetUserLevelPermissions(ctx, userID, biz.UserDocType)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissionUc.GetAccessibleFields(ctx, &biz.FieldPermissionRequest{
+		UserID:     userID,
+		DocType:    biz.UserDocType,
+		Permission: "read",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	access := make(map[string]bool, len(resp.Fields))
+	for _, field := range resp.Fields {
+		access[field.FieldName] = field.CanAccess
+	}
+	return func(field string) bool {
+		if canAccess, ok := access[field]; ok {
+			return canAccess
+		}
+		return levelPerms.CanRead(0)
+	}, nil
+}
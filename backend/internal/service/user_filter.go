@@ -11,18 +11,21 @@ import (
 
 // UserFilterService 用户过滤器服务
 type UserFilterService struct {
-	filterUc *biz.UserFilterUsecase
-	log      *log.Helper
+	filterUc     *biz.UserFilterUsecase
+	permissionUc *biz.PermissionUsecase
+	log          *log.Helper
 }
 
 // NewUserFilterService 创建用户过滤器服务
 func NewUserFilterService(
 	filterUc *biz.UserFilterUsecase,
+	permissionUc *biz.PermissionUsecase,
 	logger log.Logger,
 ) *UserFilterService {
 	return &UserFilterService{
-		filterUc: filterUc,
-		log:      log.NewHelper(logger),
+		filterUc:     filterUc,
+		permissionUc: permissionUc,
+		log:          log.NewHelper(logger),
 	}
 }
 
@@ -130,14 +133,18 @@ func (s *UserFilterService) UpdateFilter(ctx context.Context, id int32, req *Upd
 	}
 
 	// 更新过滤器数据
-	existingFilter.FilterName = req.FilterName
-	existingFilter.FilterConditions = req.FilterConditions
-	existingFilter.SortConfig = req.SortConfig
-	existingFilter.IsDefault = req.IsDefault
-	existingFilter.IsPublic = req.IsPublic
+	changed := *existingFilter
+	changed.FilterName = req.FilterName
+	changed.FilterConditions = req.FilterConditions
+	changed.SortConfig = req.SortConfig
+	changed.IsDefault = req.IsDefault
+	changed.IsPublic = req.IsPublic
+	if err := applyFieldChanges(ctx, s.permissionUc, s.log, "User Filter", existingFilter, &changed); err != nil {
+		return nil, err
+	}
 
 	// 执行更新
-	updatedFilter, err := s.filterUc.UpdateFilter(ctx, existingFilter)
+	updatedFilter, err := s.filterUc.UpdateFilter(ctx, &changed)
 	if err != nil {
 		s.log.Errorf("failed to update filter: %v", err)
 		return nil, errors.InternalServer("UPDATE_FILTER_FAILED", "更新过滤器失败")
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"unicode/utf8"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+	"erp-system/internal/pkg"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// 用户导入文件格式
+const (
+	UserImportFormatCSV  = "csv"
+	UserImportFormatXLSX = "xlsx"
+)
+
+// 用户导入的字段限制，与CreateUserRequest的校验一致
+const (
+	maxImportNameLength = 50
+	// welcomePasswordLength 系统生成的欢迎密码长度
+	welcomePasswordLength = 12
+	// maxWelcomePasswordAttempts 生成满足密码策略的欢迎密码的最大尝试次数
+	maxWelcomePasswordAttempts = 20
+)
+
+// UserImportService 用户批量导入服务
+type UserImportService struct {
+	importUc *biz.UserImportUsecase
+	pwdMgr   *pkg.PasswordManager
+	log      *log.Helper
+}
+
+// NewUserImportService 创建用户导入服务
+func NewUserImportService(importUc *biz.UserImportUsecase, pwdMgr *pkg.PasswordManager, logger log.Logger) *UserImportService {
+	return &UserImportService{
+		importUc: importUc,
+		pwdMgr:   pwdMgr,
+		log:      log.NewHelper(logger),
+	}
+}
+
+// ImportUsers 从CSV或XLSX批量导入用户，返回逐行的校验报告；有错误的行被跳过，其余行分批创建
+// 未提供密码的行生成欢迎密码并在报告中返回，dryRun时只校验不创建
+func (s *UserImportService) ImportUsers(ctx context.Context, format string, content []byte, dryRun bool) (*biz.UserImportResult, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "USER_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限导入用户")
+	}
+
+	var rows []*biz.UserImportRow
+	var err error
+	switch format {
+	case UserImportFormatCSV:
+		rows, err = biz.DecodeUserImportCSV(bytes.NewReader(content))
+	case UserImportFormatXLSX:
+		rows, err = biz.DecodeUserImportXLSX(content)
+	default:
+		return nil, errors.BadRequest("INVALID_FORMAT", "仅支持csv或xlsx格式")
+	}
+	if err != nil {
+		if stderrors.Is(err, biz.ErrUserImportEmpty) || stderrors.Is(err, biz.ErrUserImportTooLarge) {
+			return nil, s.convertError(err, "导入用户失败")
+		}
+		return nil, errors.BadRequest("INVALID_IMPORT_FILE", "导入文件格式不正确").WithMetadata(map[string]string{
+			"details": err.Error(),
+		})
+	}
+
+	var fieldErrors []*biz.UserImportRowError
+	for _, row := range rows {
+		fieldErrors = append(fieldErrors, s.validateRow(row)...)
+	}
+
+	result, err := s.importUc.Import(ctx, rows, fieldErrors, dryRun, int32(currentUser.ID))
+	if err != nil {
+		return nil, s.convertError(err, "导入用户失败")
+	}
+
+	if !dryRun {
+		s.log.Infof("Users imported by %s: %d created, %d rows with errors", currentUser.Username, result.Created, len(result.Errors))
+	}
+	return result, nil
+}
+
+// validateRow 按创建用户的规则校验导入行的字段格式和密码策略，未提供密码时生成欢迎密码
+func (s *UserImportService) validateRow(row *biz.UserImportRow) []*biz.UserImportRowError {
+	var rowErrors []*biz.UserImportRowError
+	addError := func(field, message string) {
+		rowErrors = append(rowErrors, &biz.UserImportRowError{Row: row.Row, Field: field, Message: message})
+	}
+
+	if err := pkg.ValidateUsername(row.Username); err != nil {
+		addError("username", err.Error())
+	}
+	if !pkg.ValidateEmail(row.Email) {
+		addError("email", "invalid email format")
+	}
+	if row.Phone != "" && !pkg.ValidatePhone(row.Phone) {
+		addError("phone", "invalid phone format")
+	}
+	if row.Gender != "" && row.Gender != "MALE" && row.Gender != "FEMALE" && row.Gender != "OTHER" {
+		addError("gender", "gender must be MALE, FEMALE or OTHER")
+	}
+	if utf8.RuneCountInString(row.FirstName) > maxImportNameLength {
+		addError("first_name", "first name must not exceed 50 characters")
+	}
+	if utf8.RuneCountInString(row.LastName) > maxImportNameLength {
+		addError("last_name", "last name must not exceed 50 characters")
+	}
+
+	if row.Password == "" {
+		password, ok := s.welcomePassword()
+		if !ok {
+			addError("password", "failed to generate welcome password")
+		}
+		row.Password = password
+		row.PasswordGenerated = true
+	} else if err := s.pwdMgr.ValidatePasswordStrength(row.Password); err != nil {
+		addError("password", err.Error())
+	}
+
+	return rowErrors
+}
+
+// welcomePassword 生成满足密码策略的欢迎密码
+func (s *UserImportService) welcomePassword() (string, bool) {
+	for i := 0; i < maxWelcomePasswordAttempts; i++ {
+		password := s.pwdMgr.GenerateRandomPassword(welcomePasswordLength)
+		if s.pwdMgr.ValidatePasswordStrength(password) == nil {
+			return password, true
+		}
+	}
+	return "", false
+}
+
+// convertError 将用户导入业务错误转换为API错误
+func (s *UserImportService) convertError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, biz.ErrUserImportEmpty):
+		return errors.BadRequest("EMPTY_USER_IMPORT", "导入文件没有数据行")
+	case stderrors.Is(err, biz.ErrUserImportTooLarge):
+		return errors.BadRequest("USER_IMPORT_TOO_LARGE", "单次最多导入5000个用户")
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
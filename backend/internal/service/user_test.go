@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubUserRepo 仅实现更新用户所需的方法，超级管理员的角色分配由assignErr模拟守卫结果
+type stubUserRepo struct {
+	biz.UserRepo
+	users     map[int32]*biz.User
+	roles     map[int32][]*biz.Role
+	assignErr error
+}
+
+func (r *stubUserRepo) GetUser(ctx context.Context, id int32) (*biz.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, biz.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *stubUserRepo) GetUserByEmail(ctx context.Context, email string) (*biz.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, biz.ErrUserNotFound
+}
+
+func (r *stubUserRepo) UpdateUser(ctx context.Context, user *biz.User) (*biz.User, error) {
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *stubUserRepo) AssignRoles(ctx context.Context, userID int32, roleIDs []int32) error {
+	if r.assignErr != nil {
+		return r.assignErr
+	}
+	roles := make([]*biz.Role, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		roles = append(roles, &biz.Role{ID: roleID})
+	}
+	r.roles[userID] = roles
+	return nil
+}
+
+func (r *stubUserRepo) GetUserRoles(ctx context.Context, userID int32) ([]*biz.Role, error) {
+	return r.roles[userID], nil
+}
+
+func (r *stubUserRepo) GetUserPermissions(ctx context.Context, userID int32) ([]string, error) {
+	return nil, nil
+}
+
+// stubSoDRepo 没有启用的职责分离策略
+type stubSoDRepo struct {
+	biz.SoDRepo
+}
+
+func (r *stubSoDRepo) ListPolicies(ctx context.Context, enabledOnly bool) ([]*biz.SoDPolicy, error) {
+	return nil, nil
+}
+
+// stubTransaction 直接执行事务函数
+type stubTransaction struct{}
+
+func (stubTransaction) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestUserService_UpdateUserRoles(t *testing.T) {
+	userRepo := &stubUserRepo{
+		users: map[int32]*biz.User{1: {ID: 1, Username: "root", Email: "root@example.com", FirstName: "Root", LastName: "Admin", IsActive: true}},
+		roles: map[int32][]*biz.Role{1: {{ID: 1, Code: "SUPER_ADMIN"}}},
+	}
+	svc := NewUserService(biz.NewUserUsecase(userRepo, log.DefaultLogger), nil, nil,
+		biz.NewPermissionUsecase(nil, log.DefaultLogger), biz.NewSoDUsecase(&stubSoDRepo{}, stubTransaction{}, log.DefaultLogger),
+		nil, log.DefaultLogger)
+
+	ctx := middleware.SetUserIDToContext(context.Background(), 2)
+	ctx = middleware.SetUsernameToContext(ctx, "admin")
+	ctx = middleware.SetUserRolesToContext(ctx, []string{"SUPER_ADMIN"})
+	req := &UpdateUserRequest{ID: 1, Email: "root@example.com", FirstName: "Root", LastName: "Admin", IsActive: true, RoleIDs: []int32{2}}
+
+	// 去掉最后一个超级管理员的角色被拒绝，不能返回成功
+	userRepo.assignErr = biz.ErrLastSuperAdmin
+	_, err := svc.UpdateUser(ctx, req)
+	assert.True(t, errors.IsConflict(err))
+	assert.Equal(t, "LAST_SUPER_ADMIN", errors.Reason(err))
+	assert.Equal(t, "SUPER_ADMIN", userRepo.roles[1][0].Code)
+
+	// 其他角色分配错误同样返回失败
+	userRepo.assignErr = biz.ErrUserNotFound
+	_, err = svc.UpdateUser(ctx, req)
+	assert.True(t, errors.IsInternalServer(err))
+
+	userRepo.assignErr = nil
+	info, err := svc.UpdateUser(ctx, req)
+	assert.NoError(t, err)
+	assert.NotNil(t, info)
+	assert.Equal(t, int32(2), userRepo.roles[1][0].ID)
+}
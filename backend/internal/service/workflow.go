@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+
+	"erp-system/internal/biz"
+	"erp-system/internal/middleware"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// WorkflowService 工作流定义服务，文档的工作流转换由DocumentService提供
+type WorkflowService struct {
+	workflowUc *biz.WorkflowUsecase
+	log        *log.Helper
+}
+
+// NewWorkflowService 创建工作流定义服务
+func NewWorkflowService(workflowUc *biz.WorkflowUsecase, logger log.Logger) *WorkflowService {
+	return &WorkflowService{
+		workflowUc: workflowUc,
+		log:        log.NewHelper(logger),
+	}
+}
+
+// SaveWorkflowRequest 创建或替换工作流请求
+type SaveWorkflowRequest struct {
+	Name        string                    `json:"name" validate:"required,max=100"`
+	Description string                    `json:"description"`
+	IsActive    *bool                     `json:"is_active"` // 省略时启用
+	States      []*biz.WorkflowState      `json:"states" validate:"required"`
+	Transitions []*biz.WorkflowTransition `json:"transitions"`
+}
+
+// ListWorkflowsResponse 工作流列表响应
+type ListWorkflowsResponse struct {
+	Workflows []*biz.Workflow `json:"workflows"`
+	Total     int32           `json:"total"`
+}
+
+// ListWorkflows 获取全部工作流
+func (s *WorkflowService) ListWorkflows(ctx context.Context) (*ListWorkflowsResponse, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看工作流")
+	}
+
+	workflows, err := s.workflowUc.ListWorkflows(ctx)
+	if err != nil {
+		return nil, s.convertError(err, "获取工作流列表失败")
+	}
+	return &ListWorkflowsResponse{Workflows: workflows, Total: int32(len(workflows))}, nil
+}
+
+// GetWorkflow 获取文档类型的工作流
+func (s *WorkflowService) GetWorkflow(ctx context.Context, docType string) (*biz.Workflow, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN", "PERMISSION_MANAGER") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限查看工作流")
+	}
+
+	workflow, err := s.workflowUc.GetWorkflow(ctx, docType)
+	if err != nil {
+		return nil, s.convertError(err, "获取工作流失败")
+	}
+	return workflow, nil
+}
+
+// SaveWorkflow 创建或替换文档类型的工作流
+func (s *WorkflowService) SaveWorkflow(ctx context.Context, docType string, req *SaveWorkflowRequest) (*biz.Workflow, error) {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return nil, errors.Forbidden("PERMISSION_DENIED", "无权限修改工作流")
+	}
+
+	workflow := &biz.Workflow{
+		DocType:     docType,
+		Name:        req.Name,
+		Description: req.Description,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		States:      req.States,
+		Transitions: req.Transitions,
+		CreatedBy:   &currentUser.ID,
+		UpdatedBy:   &currentUser.ID,
+	}
+	saved, err := s.workflowUc.SaveWorkflow(ctx, workflow)
+	if err != nil {
+		return nil, s.convertError(err, "工作流保存失败")
+	}
+
+	s.log.Infof("Workflow saved successfully: %s (%s)", saved.Name, saved.DocType)
+	return saved, nil
+}
+
+// DeleteWorkflow 删除文档类型的工作流
+func (s *WorkflowService) DeleteWorkflow(ctx context.Context, docType string) error {
+	currentUser := middleware.GetCurrentUser(ctx)
+	if !currentUser.HasAnyRole("SUPER_ADMIN", "ADMIN") {
+		return errors.Forbidden("PERMISSION_DENIED", "无权限删除工作流")
+	}
+
+	if err := s.workflowUc.DeleteWorkflow(ctx, docType); err != nil {
+		return s.convertError(err, "工作流删除失败")
+	}
+
+	s.log.Infof("Workflow deleted successfully: %s", docType)
+	return nil
+}
+
+// convertError 将工作流业务错误转换为API错误
+func (s *WorkflowService) convertError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, biz.ErrDocTypeNotFound):
+		return errors.NotFound("DOCTYPE_NOT_FOUND", "文档类型不存在")
+	case stderrors.Is(err, biz.ErrWorkflowNotFound):
+		return errors.NotFound("WORKFLOW_NOT_FOUND", "工作流不存在")
+	case stderrors.Is(err, biz.ErrInvalidWorkflow):
+		return errors.BadRequest("INVALID_WORKFLOW", err.Error())
+	}
+	s.log.Errorf("%s: %v", message, err)
+	return errors.InternalServer("INTERNAL_ERROR", message)
+}
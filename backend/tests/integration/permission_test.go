@@ -90,9 +90,11 @@ func TestPermissionSystemIntegration(t *testing.T) {
 
 	// 创建Usecase
 	permissionUsecase := biz.NewPermissionUsecase(cachedPermissionRepo, logger)
+	sodUsecase := biz.NewSoDUsecase(data.NewSoDRepo(dataInstance, logger), data.NewTransaction(dataInstance), logger)
+	docFieldUsecase := biz.NewDocFieldUsecase(data.NewDocFieldRepo(dataInstance, logger), cachedPermissionRepo, logger)
 
 	// 创建Service
-	permissionService := service.NewPermissionService(permissionUsecase, logger)
+	permissionService := service.NewPermissionService(permissionUsecase, sodUsecase, docFieldUsecase, logger)
 
 	// 创建带有超级管理员权限的测试上下文
 	ctx := context.Background()
@@ -445,7 +447,9 @@ func TestPermissionSystemLoadTest(t *testing.T) {
 	cachedPermissionRepo := data.NewCachedPermissionRepo(permissionRepo, permissionCache, logger)
 
 	permissionUsecase := biz.NewPermissionUsecase(cachedPermissionRepo, logger)
-	permissionService := service.NewPermissionService(permissionUsecase, logger)
+	sodUsecase := biz.NewSoDUsecase(data.NewSoDRepo(dataInstance, logger), data.NewTransaction(dataInstance), logger)
+	docFieldUsecase := biz.NewDocFieldUsecase(data.NewDocFieldRepo(dataInstance, logger), cachedPermissionRepo, logger)
+	permissionService := service.NewPermissionService(permissionUsecase, sodUsecase, docFieldUsecase, logger)
 
 	// 创建带有超级管理员权限的测试上下文
 	ctx := context.Background()
@@ -531,7 +535,9 @@ func BenchmarkPermissionCheck(b *testing.B) {
 	cachedPermissionRepo := data.NewCachedPermissionRepo(permissionRepo, permissionCache, logger)
 
 	permissionUsecase := biz.NewPermissionUsecase(cachedPermissionRepo, logger)
-	permissionService := service.NewPermissionService(permissionUsecase, logger)
+	sodUsecase := biz.NewSoDUsecase(data.NewSoDRepo(dataInstance, logger), data.NewTransaction(dataInstance), logger)
+	docFieldUsecase := biz.NewDocFieldUsecase(data.NewDocFieldRepo(dataInstance, logger), cachedPermissionRepo, logger)
+	permissionService := service.NewPermissionService(permissionUsecase, sodUsecase, docFieldUsecase, logger)
 
 	ctx := context.Background()
 
@@ -0,0 +1,79 @@
+-- ================================================================================================
+-- 权限模板迁移脚本
+-- 1. 权限模板（Permission Templates）- 可复用的按级别权限集合
+-- 2. 模板应用记录（Template Applications）- 记录模板应用到的角色和文档类型，用于重新应用
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 权限模板表 (permission_templates)
+-- levels 为JSON数组: [{"permission_level":0,"actions":["read","write"],"only_if_creator":false}]
+-- ================================================================================================
+CREATE TABLE permission_templates (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,                       -- 模板名称 (如: Read-only, Clerk, Approver)
+    description TEXT,                                        -- 模板描述
+    levels JSONB NOT NULL DEFAULT '[]',                      -- 按权限级别定义的操作集合
+    version INTEGER NOT NULL DEFAULT 1,                      -- 模板版本，每次修改递增
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by BIGINT,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_permission_templates_created_by FOREIGN KEY (created_by) REFERENCES users(id),
+    CONSTRAINT fk_permission_templates_updated_by FOREIGN KEY (updated_by) REFERENCES users(id)
+);
+
+-- 权限模板表触发器
+CREATE TRIGGER update_permission_templates_updated_at BEFORE UPDATE ON permission_templates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- ================================================================================================
+-- 2. 模板应用记录表 (permission_template_applications)
+-- ================================================================================================
+CREATE TABLE permission_template_applications (
+    id BIGSERIAL PRIMARY KEY,
+    template_id BIGINT NOT NULL,                             -- 模板ID
+    role_id BIGINT NOT NULL,                                 -- 角色ID
+    doc_type VARCHAR(50) NOT NULL,                           -- 文档类型
+    template_version INTEGER NOT NULL,                       -- 应用时的模板版本
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    applied_by BIGINT,
+
+    CONSTRAINT fk_template_applications_template FOREIGN KEY (template_id) REFERENCES permission_templates(id) ON DELETE CASCADE,
+    CONSTRAINT fk_template_applications_role FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_template_applications_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE,
+    CONSTRAINT fk_template_applications_applied_by FOREIGN KEY (applied_by) REFERENCES users(id),
+    CONSTRAINT uk_template_applications UNIQUE(template_id, role_id, doc_type)
+);
+
+-- 模板应用记录表索引
+CREATE INDEX idx_template_applications_template ON permission_template_applications(template_id);
+CREATE INDEX idx_template_applications_role ON permission_template_applications(role_id);
+
+-- ================================================================================================
+-- 初始化数据
+-- ================================================================================================
+INSERT INTO permission_templates (name, description, levels) VALUES
+('Read-only', '只读：查看、打印和报表', '[
+    {"permission_level": 0, "actions": ["read", "print", "report"], "only_if_creator": false}
+]'),
+('Clerk', '文员：录入和维护单据，不可提交', '[
+    {"permission_level": 0, "actions": ["read", "write", "create", "print", "email", "report", "export"], "only_if_creator": false},
+    {"permission_level": 1, "actions": ["read", "write"], "only_if_creator": false}
+]'),
+('Approver', '审批人：可提交、取消和修订单据', '[
+    {"permission_level": 0, "actions": ["read", "write", "submit", "cancel", "amend", "print", "email", "report", "export"], "only_if_creator": false},
+    {"permission_level": 1, "actions": ["read", "write"], "only_if_creator": false}
+]');
+
+COMMENT ON TABLE permission_templates IS '权限模板表：可复用的按级别权限集合';
+COMMENT ON TABLE permission_template_applications IS '权限模板应用记录表：记录模板应用到的角色和文档类型';
+
+-- ================================================================================================
+-- 提交事务
+-- ================================================================================================
+COMMIT;
@@ -0,0 +1,68 @@
+-- ================================================================================================
+-- 角色继承迁移脚本
+-- 角色可指定一个上级角色（parent_role_id），继承其全部权限规则
+-- 继承链最大深度为10，循环继承由应用层在保存角色时检测
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 角色表增加上级角色字段
+-- ================================================================================================
+ALTER TABLE roles ADD COLUMN parent_role_id BIGINT;
+
+ALTER TABLE roles
+    ADD CONSTRAINT fk_roles_parent_role FOREIGN KEY (parent_role_id) REFERENCES roles(id),
+    ADD CONSTRAINT chk_roles_parent_not_self CHECK (parent_role_id IS NULL OR parent_role_id <> id);
+
+-- 上级角色索引（查询下级角色时使用）
+CREATE INDEX idx_roles_parent_role ON roles(parent_role_id) WHERE parent_role_id IS NOT NULL;
+
+-- ================================================================================================
+-- 2. 重建增强用户权限视图 - 包含沿继承链获得的权限规则
+-- ================================================================================================
+CREATE OR REPLACE VIEW enhanced_user_permissions_view AS
+WITH RECURSIVE effective_roles AS (
+    SELECT ur.user_id, ur.role_id, 0 AS depth
+    FROM user_roles ur
+    WHERE ur.is_active = TRUE
+        AND (ur.expires_at IS NULL OR ur.expires_at > CURRENT_TIMESTAMP)
+    UNION ALL
+    SELECT er.user_id, r.parent_role_id, er.depth + 1
+    FROM effective_roles er
+    JOIN roles r ON r.id = er.role_id
+    WHERE r.parent_role_id IS NOT NULL AND er.depth < 10
+)
+SELECT DISTINCT
+    er.user_id,
+    pr.doc_type,
+    pr.permission_level,
+    pr.can_read,
+    pr.can_write,
+    pr.can_create,
+    pr.can_delete,
+    pr.can_submit,
+    pr.can_cancel,
+    pr.can_amend,
+    pr.can_print,
+    pr.can_email,
+    pr.can_import,
+    pr.can_export,
+    pr.can_share,
+    pr.can_report,
+    pr.only_if_creator,
+    r.code as role_code,
+    r.name as role_name
+FROM effective_roles er
+JOIN permission_rules pr ON er.role_id = pr.role_id
+JOIN roles r ON er.role_id = r.id
+WHERE r.is_enabled = TRUE;
+
+COMMENT ON COLUMN roles.parent_role_id IS '上级角色ID：继承上级角色的全部权限规则';
+COMMENT ON VIEW enhanced_user_permissions_view IS '高级增强用户权限视图：包含沿角色继承链获得的权限规则';
+
+-- ================================================================================================
+-- 提交事务
+-- ================================================================================================
+COMMIT;
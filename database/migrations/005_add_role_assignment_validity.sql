@@ -0,0 +1,110 @@
+-- ================================================================================================
+-- 角色分配有效期与委托迁移脚本
+-- 用户角色分配支持生效时间（valid_from）与失效时间（valid_until），用于临时代岗和项目人员配置
+-- 用户可将自身持有的角色在一段时间内委托给同事（delegated_by记录委托人）
+-- 过期的分配不再授予权限，并由定时任务清理、写入操作日志
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 用户角色表增加有效期与委托字段
+-- ================================================================================================
+ALTER TABLE user_roles RENAME COLUMN expires_at TO valid_until;
+
+ALTER TABLE user_roles
+    ADD COLUMN valid_from TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN delegated_by BIGINT,
+    ADD COLUMN reason VARCHAR(255);
+
+-- 同一用户同一角色可存在多个时间段或委托分配，不再整体唯一
+ALTER TABLE user_roles DROP CONSTRAINT uk_user_roles;
+
+ALTER TABLE user_roles
+    ADD CONSTRAINT fk_user_roles_delegated_by FOREIGN KEY (delegated_by) REFERENCES users(id) ON DELETE CASCADE,
+    ADD CONSTRAINT chk_user_roles_validity CHECK (valid_from IS NULL OR valid_until IS NULL OR valid_from < valid_until),
+    ADD CONSTRAINT chk_user_roles_delegation CHECK (delegated_by IS NULL OR (valid_until IS NOT NULL AND delegated_by <> user_id));
+
+-- 永久的直接分配仍保持唯一
+CREATE UNIQUE INDEX uk_user_roles_permanent ON user_roles(user_id, role_id)
+    WHERE delegated_by IS NULL AND valid_from IS NULL AND valid_until IS NULL;
+
+ALTER INDEX idx_user_roles_expires RENAME TO idx_user_roles_valid_until;
+DROP INDEX IF EXISTS idx_user_roles_permission_check;
+CREATE INDEX idx_user_roles_permission_check ON user_roles(user_id, is_active, valid_from, valid_until);
+CREATE INDEX idx_user_roles_delegated_by ON user_roles(delegated_by, role_id) WHERE delegated_by IS NOT NULL;
+
+COMMENT ON COLUMN user_roles.valid_from IS '生效时间：为空表示立即生效';
+COMMENT ON COLUMN user_roles.valid_until IS '失效时间：为空表示长期有效';
+COMMENT ON COLUMN user_roles.delegated_by IS '委托人ID：非空表示该分配由委托人将自身角色临时委托而来';
+COMMENT ON COLUMN user_roles.reason IS '分配或委托原因';
+
+-- ================================================================================================
+-- 2. 重建用户权限视图 - 仅包含处于有效期内的角色分配
+-- ================================================================================================
+CREATE OR REPLACE VIEW user_permissions_view AS
+SELECT DISTINCT
+    ur.user_id,
+    p.id as permission_id,
+    p.code as permission_code,
+    p.name as permission_name,
+    p.resource,
+    p.action,
+    p.module,
+    r.id as role_id,
+    r.code as role_code,
+    r.name as role_name
+FROM user_roles ur
+JOIN role_permissions rp ON ur.role_id = rp.role_id AND rp.is_granted = true
+JOIN permissions p ON rp.permission_id = p.id
+JOIN roles r ON ur.role_id = r.id
+WHERE ur.is_active = true
+    AND r.is_enabled = true
+    AND (ur.valid_from IS NULL OR ur.valid_from <= CURRENT_TIMESTAMP)
+    AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP);
+
+CREATE OR REPLACE VIEW enhanced_user_permissions_view AS
+WITH RECURSIVE effective_roles AS (
+    SELECT ur.user_id, ur.role_id, 0 AS depth
+    FROM user_roles ur
+    WHERE ur.is_active = TRUE
+        AND (ur.valid_from IS NULL OR ur.valid_from <= CURRENT_TIMESTAMP)
+        AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)
+    UNION ALL
+    SELECT er.user_id, r.parent_role_id, er.depth + 1
+    FROM effective_roles er
+    JOIN roles r ON r.id = er.role_id
+    WHERE r.parent_role_id IS NOT NULL AND er.depth < 10
+)
+SELECT DISTINCT
+    er.user_id,
+    pr.doc_type,
+    pr.permission_level,
+    pr.can_read,
+    pr.can_write,
+    pr.can_create,
+    pr.can_delete,
+    pr.can_submit,
+    pr.can_cancel,
+    pr.can_amend,
+    pr.can_print,
+    pr.can_email,
+    pr.can_import,
+    pr.can_export,
+    pr.can_share,
+    pr.can_report,
+    pr.only_if_creator,
+    r.code as role_code,
+    r.name as role_name
+FROM effective_roles er
+JOIN permission_rules pr ON er.role_id = pr.role_id
+JOIN roles r ON er.role_id = r.id
+WHERE r.is_enabled = TRUE;
+
+COMMENT ON VIEW enhanced_user_permissions_view IS '高级增强用户权限视图：包含有效期内的角色分配及沿继承链获得的权限规则';
+
+-- ================================================================================================
+-- 提交事务
+-- ================================================================================================
+COMMIT;
@@ -0,0 +1,69 @@
+-- ================================================================================================
+-- 职责分离（Segregation of Duties, SoD）策略迁移脚本
+-- 1. 角色互斥（role_pair）- 同一用户不能同时持有两个角色
+-- 2. 操作互斥（action_pair）- 同一用户在同一文档类型上不能同时拥有两个操作（如制单与提交）
+-- 策略在分配角色和创建权限规则时检查，enforcement为block时拒绝，为warn时仅警告
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 职责分离策略表 (sod_policies)
+-- ================================================================================================
+CREATE TABLE sod_policies (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,                       -- 策略名称
+    description TEXT,                                        -- 策略描述
+    policy_type VARCHAR(20) NOT NULL,                        -- 策略类型: role_pair, action_pair
+
+    -- 角色互斥
+    role_a_id BIGINT,
+    role_b_id BIGINT,
+
+    -- 操作互斥
+    doc_type VARCHAR(50),
+    action_a VARCHAR(20),
+    action_b VARCHAR(20),
+
+    enforcement VARCHAR(10) NOT NULL DEFAULT 'block',        -- 处理方式: block, warn
+    is_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by BIGINT,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_sod_policies_role_a FOREIGN KEY (role_a_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_sod_policies_role_b FOREIGN KEY (role_b_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_sod_policies_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_sod_policies_created_by FOREIGN KEY (created_by) REFERENCES users(id),
+    CONSTRAINT fk_sod_policies_updated_by FOREIGN KEY (updated_by) REFERENCES users(id),
+    CONSTRAINT chk_sod_policies_type CHECK (policy_type IN ('role_pair', 'action_pair')),
+    CONSTRAINT chk_sod_policies_enforcement CHECK (enforcement IN ('block', 'warn')),
+    CONSTRAINT chk_sod_policies_target CHECK (
+        (policy_type = 'role_pair' AND role_a_id IS NOT NULL AND role_b_id IS NOT NULL AND role_a_id <> role_b_id)
+        OR (policy_type = 'action_pair' AND doc_type IS NOT NULL AND action_a IS NOT NULL AND action_b IS NOT NULL AND action_a <> action_b)
+    )
+);
+
+-- 职责分离策略表索引
+CREATE INDEX idx_sod_policies_doc_type ON sod_policies(doc_type) WHERE doc_type IS NOT NULL;
+CREATE INDEX idx_sod_policies_enabled ON sod_policies(is_enabled);
+
+-- 职责分离策略表触发器
+CREATE TRIGGER update_sod_policies_updated_at BEFORE UPDATE ON sod_policies FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE sod_policies IS '职责分离策略：互斥的角色对或同一文档类型上互斥的操作对';
+
+-- ================================================================================================
+-- 初始化数据
+-- ================================================================================================
+INSERT INTO sod_policies (name, description, policy_type, doc_type, action_a, action_b, enforcement) VALUES
+('订单制单与提交分离', '同一用户不能既创建又提交订单', 'action_pair', 'Order', 'create', 'submit', 'warn');
+
+-- ================================================================================================
+-- 提交事务
+-- ================================================================================================
+COMMIT;
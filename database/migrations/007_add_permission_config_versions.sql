@@ -0,0 +1,35 @@
+-- ================================================================================================
+-- 权限配置版本迁移脚本
+-- 权限规则、用户权限和字段权限级别的每次变更都记录为一个版本，保存变更后的完整配置快照
+-- 支持任意两个版本之间的差异比较，以及回滚到历史版本
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 权限配置版本表 (permission_config_versions)
+-- ================================================================================================
+CREATE TABLE permission_config_versions (
+    id BIGSERIAL PRIMARY KEY,                                -- 版本号
+    operation VARCHAR(50) NOT NULL,                          -- 触发版本的操作，如create_permission_rule、rollback
+    comment TEXT,                                            -- 变更说明
+    author_id BIGINT,                                        -- 变更人
+    rollback_of BIGINT,                                      -- 回滚时的目标版本
+    snapshot JSONB NOT NULL,                                 -- 变更后的完整配置快照
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_permission_config_versions_author FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_permission_config_versions_rollback FOREIGN KEY (rollback_of) REFERENCES permission_config_versions(id)
+);
+
+-- 权限配置版本表索引
+CREATE INDEX idx_permission_config_versions_created_at ON permission_config_versions(created_at DESC);
+CREATE INDEX idx_permission_config_versions_author ON permission_config_versions(author_id);
+
+COMMENT ON TABLE permission_config_versions IS '权限配置版本：权限规则、用户权限和字段权限级别每次变更后的快照';
+
+-- ================================================================================================
+-- 提交事务
+-- ================================================================================================
+COMMIT;
@@ -0,0 +1,23 @@
+-- ================================================================================================
+-- 权限规则条件迁移脚本
+-- 权限规则可附加基于文档属性和用户属性的条件表达式，例如 doc.amount < 10000、doc.org_id in user.org_ids
+-- 条件为空表示无条件授权；表达式在保存规则时由应用层解析和类型检查
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 权限规则表增加条件表达式字段
+-- ================================================================================================
+ALTER TABLE permission_rules ADD COLUMN condition TEXT;
+
+ALTER TABLE permission_rules
+    ADD CONSTRAINT chk_permission_rules_condition_length CHECK (condition IS NULL OR length(condition) <= 1000);
+
+COMMENT ON COLUMN permission_rules.condition IS '权限条件表达式，为空表示无条件授权';
+
+-- ================================================================================================
+-- 提交事务
+-- ================================================================================================
+COMMIT;
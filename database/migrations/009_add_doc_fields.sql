@@ -0,0 +1,66 @@
+-- ================================================================================================
+-- 文档类型字段元数据迁移脚本
+-- 1. 字段定义表 (doc_fields) - 每个文档类型的字段清单：类型、选项、必填、唯一、默认值、显示依赖和权限级别
+-- 2. 字段定义是字段权限级别 (field_permission_levels) 的来源，字段增删改时在同一事务中同步
+-- 3. 从已有的字段权限级别回填字段定义
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 字段定义表 (doc_fields)
+-- ================================================================================================
+CREATE TABLE doc_fields (
+    id BIGSERIAL PRIMARY KEY,
+    doc_type VARCHAR(50) NOT NULL,                           -- 文档类型
+    field_name VARCHAR(63) NOT NULL,                         -- 字段名称（小写字母、数字和下划线，作为列名使用）
+    label VARCHAR(100) NOT NULL,                             -- 字段显示名称
+    field_type VARCHAR(50) NOT NULL DEFAULT 'Data',          -- 字段类型
+    options TEXT,                                            -- 选项：Select为换行分隔的可选值，Link/Table为目标文档类型
+    is_mandatory BOOLEAN NOT NULL DEFAULT FALSE,             -- 是否必填
+    is_unique BOOLEAN NOT NULL DEFAULT FALSE,                -- 是否唯一
+    default_value TEXT,                                      -- 默认值
+    depends_on TEXT,                                         -- 显示依赖：字段名或条件表达式
+    permission_level INTEGER NOT NULL DEFAULT 0,             -- 权限级别 (0-9)
+    idx INTEGER NOT NULL DEFAULT 0,                          -- 显示顺序
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by BIGINT,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_doc_fields_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_doc_fields_created_by FOREIGN KEY (created_by) REFERENCES users(id),
+    CONSTRAINT fk_doc_fields_updated_by FOREIGN KEY (updated_by) REFERENCES users(id),
+    CONSTRAINT uk_doc_fields UNIQUE(doc_type, field_name),
+    CONSTRAINT chk_doc_fields_name CHECK (field_name ~ '^[a-z][a-z0-9_]*$'),
+    CONSTRAINT chk_doc_fields_permission_level CHECK (permission_level BETWEEN 0 AND 9)
+);
+
+-- 字段定义表索引
+CREATE INDEX idx_doc_fields_doc_type ON doc_fields(doc_type, idx);
+
+-- 字段定义表触发器
+CREATE TRIGGER update_doc_fields_updated_at BEFORE UPDATE ON doc_fields FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE doc_fields IS '文档类型字段定义，字段权限级别由此同步';
+
+-- ================================================================================================
+-- 2. 从字段权限级别回填字段定义
+-- ================================================================================================
+INSERT INTO doc_fields (doc_type, field_name, label, field_type, is_mandatory, permission_level, idx)
+SELECT fpl.doc_type, fpl.field_name, COALESCE(NULLIF(fpl.field_label, ''), fpl.field_name),
+       COALESCE(NULLIF(fpl.field_type, ''), 'Data'), COALESCE(fpl.is_mandatory, FALSE),
+       LEAST(GREATEST(COALESCE(fpl.permission_level, 0), 0), 9),
+       ROW_NUMBER() OVER (PARTITION BY fpl.doc_type ORDER BY fpl.id)
+FROM field_permission_levels fpl
+INNER JOIN doc_types dt ON dt.name = fpl.doc_type
+WHERE fpl.field_name ~ '^[a-z][a-z0-9_]*$'
+  AND length(fpl.field_name) <= 63
+  AND fpl.field_name NOT IN ('id', 'name', 'created_at', 'updated_at', 'created_by', 'updated_by')
+ON CONFLICT (doc_type, field_name) DO NOTHING;
+
+-- 提交事务
+COMMIT;
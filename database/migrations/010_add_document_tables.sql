@@ -0,0 +1,38 @@
+-- ================================================================================================
+-- 通用文档存储迁移脚本
+-- 1. 文档数据表登记表 (document_tables) - 每个文档类型的数据表名称及其字段结构签名
+-- 2. 数据表本身由文档存储按字段定义 (doc_fields) 动态创建和迁移，签名变化时补充列、调整列类型和唯一索引
+-- 3. 文档的提交状态记录在 document_workflow_states，按 (doc_type, doc_id) 关联数据表行
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 文档数据表登记表 (document_tables)
+-- ================================================================================================
+CREATE TABLE document_tables (
+    id BIGSERIAL PRIMARY KEY,
+    doc_type VARCHAR(50) NOT NULL,                           -- 文档类型
+    table_name VARCHAR(63) NOT NULL,                         -- 数据表名称
+    signature TEXT NOT NULL DEFAULT '',                      -- 最近一次迁移时的字段结构签名
+    synced_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP, -- 最近一次迁移时间
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_document_tables_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT uk_document_tables_doc_type UNIQUE(doc_type),
+    CONSTRAINT uk_document_tables_table_name UNIQUE(table_name),
+    CONSTRAINT chk_document_tables_table_name CHECK (table_name ~ '^doc_[a-z0-9_]+$')
+);
+
+-- 文档数据表登记表触发器
+CREATE TRIGGER update_document_tables_updated_at BEFORE UPDATE ON document_tables FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE document_tables IS '通用文档存储的数据表登记，数据表按字段定义动态创建';
+COMMENT ON COLUMN document_tables.signature IS '字段名称、类型和唯一性组成的签名，与当前字段定义不一致时重新迁移';
+
+-- 提交事务
+COMMIT;
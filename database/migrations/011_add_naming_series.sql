@@ -0,0 +1,32 @@
+-- ================================================================================================
+-- 文档命名编号序列迁移脚本
+-- 1. 编号序列计数器表 (naming_series) - 按前缀计数，如 PO-2024- 的当前编号
+-- 2. 计数器通过 INSERT ... ON CONFLICT DO UPDATE 原子递增，并发创建文档时不会取得相同编号
+-- 3. 文档类型的命名规则保存在 doc_types.naming_rule，格式为 规则[:选项]，如 series:PO-.YYYY.-.#####
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 编号序列计数器表 (naming_series)
+-- ================================================================================================
+CREATE TABLE naming_series (
+    prefix VARCHAR(140) PRIMARY KEY,                         -- 编号前缀（计数器之前已生成的名称部分）
+    current BIGINT NOT NULL DEFAULT 0,                       -- 当前编号，下一个名称使用 current + 1
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_naming_series_current CHECK (current >= 0)
+);
+
+-- 编号序列计数器表触发器
+CREATE TRIGGER update_naming_series_updated_at BEFORE UPDATE ON naming_series FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE naming_series IS '文档命名编号序列计数器，按前缀原子递增';
+COMMENT ON COLUMN naming_series.prefix IS '编号前缀；autoname规则使用 autoname:文档类型 作为前缀';
+
+-- 提交事务
+COMMIT;
@@ -0,0 +1,51 @@
+-- ================================================================================================
+-- 文档工作流定义迁移脚本
+-- 1. 每个文档类型最多一个工作流，定义状态及其对应的docstatus，以及状态之间的转换
+-- 2. 转换限定执行角色，可附带条件表达式（与权限规则条件语法相同），执行时写入
+--    document_workflow_states 并记录操作日志
+-- 3. doc_types.has_workflow 随工作流的启用状态同步
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 工作流表 (workflows)
+-- states 为JSON数组: [{"state":"Pending","docstatus":0}]，第一个状态为初始状态
+-- transitions 为JSON数组: [{"state":"Pending","action":"Approve","next_state":"Approved",
+--                           "allowed_role":"SALES_MANAGER","condition":"doc.amount < 10000"}]
+-- ================================================================================================
+CREATE TABLE workflows (
+    id BIGSERIAL PRIMARY KEY,
+    doc_type VARCHAR(50) NOT NULL UNIQUE,                    -- 文档类型
+    name VARCHAR(100) NOT NULL,                              -- 工作流名称
+    description TEXT,                                        -- 描述
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,                 -- 是否启用
+    states JSONB NOT NULL DEFAULT '[]',                      -- 状态定义
+    transitions JSONB NOT NULL DEFAULT '[]',                 -- 转换定义
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by BIGINT,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_workflows_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_workflows_created_by FOREIGN KEY (created_by) REFERENCES users(id),
+    CONSTRAINT fk_workflows_updated_by FOREIGN KEY (updated_by) REFERENCES users(id),
+    CONSTRAINT chk_workflows_states CHECK (jsonb_typeof(states) = 'array'),
+    CONSTRAINT chk_workflows_transitions CHECK (jsonb_typeof(transitions) = 'array')
+);
+
+-- 工作流表索引
+CREATE INDEX idx_workflows_active ON workflows(is_active);
+
+-- 工作流表触发器
+CREATE TRIGGER update_workflows_updated_at BEFORE UPDATE ON workflows FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE workflows IS '文档工作流定义：状态、docstatus映射以及按角色和条件限定的转换';
+COMMENT ON COLUMN workflows.states IS '状态定义数组，第一个状态为新建文档的初始状态';
+COMMENT ON COLUMN workflows.transitions IS '转换定义数组，同一状态下相同操作可按角色配置多条';
+
+-- 提交事务
+COMMIT;
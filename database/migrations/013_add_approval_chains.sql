@@ -0,0 +1,160 @@
+-- ================================================================================================
+-- 多级审批链迁移脚本
+-- 1. 组织增加负责人 leader_id，审批人按文档所属组织沿 parent_id 向上查找各级负责人
+-- 2. 每个文档类型最多一条审批链，步骤按顺序执行，同一步骤的多个审批人并行审批
+-- 3. 审批请求在发起时确定全部审批任务，每个决定、转交和超时升级都记录在审批任务中
+-- 4. 审批委托：委托期内分配给委托人的审批任务改由受托人处理
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 组织负责人
+-- ================================================================================================
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS leader_id BIGINT;
+ALTER TABLE organizations ADD CONSTRAINT fk_organizations_leader FOREIGN KEY (leader_id) REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_organizations_leader ON organizations(leader_id) WHERE leader_id IS NOT NULL;
+
+COMMENT ON COLUMN organizations.leader_id IS '组织负责人，审批链按组织层级向上查找负责人';
+
+-- ================================================================================================
+-- 2. 审批链表 (approval_chains)
+-- steps 为JSON数组: [{"name":"部门负责人","approver_type":"org_leader","levels":0,"mode":"any","timeout_hours":24},
+--                    {"name":"财务","approver_type":"role","role":"CFO","condition":"doc.amount > 100000"}]
+-- ================================================================================================
+CREATE TABLE approval_chains (
+    id BIGSERIAL PRIMARY KEY,
+    doc_type VARCHAR(50) NOT NULL UNIQUE,                    -- 文档类型
+    name VARCHAR(100) NOT NULL,                              -- 审批链名称
+    description TEXT,                                        -- 描述
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,                 -- 是否启用
+    org_field VARCHAR(50),                                   -- 文档中保存所属组织ID的字段，为空时取申请人的主组织
+    steps JSONB NOT NULL DEFAULT '[]',                       -- 审批步骤定义
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by BIGINT,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_approval_chains_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_approval_chains_created_by FOREIGN KEY (created_by) REFERENCES users(id),
+    CONSTRAINT fk_approval_chains_updated_by FOREIGN KEY (updated_by) REFERENCES users(id),
+    CONSTRAINT chk_approval_chains_steps CHECK (jsonb_typeof(steps) = 'array')
+);
+
+-- 审批链表触发器
+CREATE TRIGGER update_approval_chains_updated_at BEFORE UPDATE ON approval_chains FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- ================================================================================================
+-- 3. 审批请求表 (approval_requests)
+-- steps 为发起时审批链步骤的快照，审批链修改不影响进行中的请求；version 用于并发审批的乐观锁
+-- ================================================================================================
+CREATE TABLE approval_requests (
+    id BIGSERIAL PRIMARY KEY,
+    doc_type VARCHAR(50) NOT NULL,                           -- 文档类型
+    doc_name VARCHAR(100) NOT NULL,                          -- 文档名称
+    chain_id BIGINT,                                         -- 审批链
+    chain_name VARCHAR(100) NOT NULL,                        -- 发起时的审批链名称
+    steps JSONB NOT NULL DEFAULT '[]',                       -- 发起时的审批步骤快照
+    org_id BIGINT,                                           -- 文档所属组织
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',           -- 状态: pending, approved, rejected, cancelled
+    current_step INTEGER NOT NULL DEFAULT 0,                 -- 当前步骤序号
+    version INTEGER NOT NULL DEFAULT 0,                      -- 乐观锁版本
+    comment TEXT,                                            -- 申请说明
+    requested_by BIGINT NOT NULL,                            -- 申请人
+    completed_at TIMESTAMP WITH TIME ZONE,                   -- 完成时间
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_approval_requests_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_approval_requests_chain FOREIGN KEY (chain_id) REFERENCES approval_chains(id) ON DELETE SET NULL,
+    CONSTRAINT fk_approval_requests_org FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE SET NULL,
+    CONSTRAINT fk_approval_requests_requested_by FOREIGN KEY (requested_by) REFERENCES users(id),
+    CONSTRAINT chk_approval_requests_status CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled'))
+);
+
+-- 审批请求表索引：同一文档同时只能有一个进行中的请求
+CREATE UNIQUE INDEX uk_approval_requests_pending ON approval_requests(doc_type, doc_name) WHERE status = 'pending';
+CREATE INDEX idx_approval_requests_document ON approval_requests(doc_type, doc_name, created_at DESC);
+CREATE INDEX idx_approval_requests_requested_by ON approval_requests(requested_by, status);
+
+-- 审批请求表触发器
+CREATE TRIGGER update_approval_requests_updated_at BEFORE UPDATE ON approval_requests FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- ================================================================================================
+-- 4. 审批任务表 (approval_tasks)
+-- 每个审批人在每个步骤一条任务；后续步骤的任务为 waiting，步骤开始时变为 pending。
+-- 转交和升级时原任务变为 delegated/escalated，并为新审批人创建任务
+-- ================================================================================================
+CREATE TABLE approval_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    request_id BIGINT NOT NULL,                              -- 审批请求
+    step INTEGER NOT NULL,                                   -- 步骤序号
+    step_name VARCHAR(100) NOT NULL,                         -- 步骤名称
+    approver_id BIGINT NOT NULL,                             -- 审批人
+    org_id BIGINT,                                           -- 审批人所代表的组织，超时升级从此组织向上查找
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting',           -- 状态: waiting, pending, approved, rejected, skipped, delegated, escalated
+    delegated_from BIGINT,                                   -- 委托人
+    escalated_from BIGINT,                                   -- 超时升级前的任务
+    due_at TIMESTAMP WITH TIME ZONE,                         -- 超时时间，为空表示不超时
+    comment TEXT,                                            -- 审批意见
+    decided_at TIMESTAMP WITH TIME ZONE,                     -- 处理时间
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_approval_tasks_request FOREIGN KEY (request_id) REFERENCES approval_requests(id) ON DELETE CASCADE,
+    CONSTRAINT fk_approval_tasks_approver FOREIGN KEY (approver_id) REFERENCES users(id),
+    CONSTRAINT fk_approval_tasks_org FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE SET NULL,
+    CONSTRAINT fk_approval_tasks_delegated_from FOREIGN KEY (delegated_from) REFERENCES users(id),
+    CONSTRAINT fk_approval_tasks_escalated_from FOREIGN KEY (escalated_from) REFERENCES approval_tasks(id),
+    CONSTRAINT chk_approval_tasks_status CHECK (status IN ('waiting', 'pending', 'approved', 'rejected', 'skipped', 'delegated', 'escalated'))
+);
+
+-- 审批任务表索引
+CREATE INDEX idx_approval_tasks_request ON approval_tasks(request_id, step);
+CREATE INDEX idx_approval_tasks_approver ON approval_tasks(approver_id, status);
+CREATE INDEX idx_approval_tasks_due ON approval_tasks(due_at) WHERE status = 'pending' AND due_at IS NOT NULL;
+
+-- 审批任务表触发器
+CREATE TRIGGER update_approval_tasks_updated_at BEFORE UPDATE ON approval_tasks FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- ================================================================================================
+-- 5. 审批委托表 (approval_delegations)
+-- ================================================================================================
+CREATE TABLE approval_delegations (
+    id BIGSERIAL PRIMARY KEY,
+    delegator_id BIGINT NOT NULL,                            -- 委托人
+    delegate_id BIGINT NOT NULL,                             -- 受托人
+    doc_type VARCHAR(50),                                    -- 限定文档类型，为空表示全部
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,             -- 开始时间
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,               -- 结束时间
+    reason TEXT,                                             -- 委托原因
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,                 -- 是否有效，撤销后为FALSE
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_approval_delegations_delegator FOREIGN KEY (delegator_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_approval_delegations_delegate FOREIGN KEY (delegate_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_approval_delegations_doc_type FOREIGN KEY (doc_type) REFERENCES doc_types(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT chk_approval_delegations_users CHECK (delegator_id <> delegate_id),
+    CONSTRAINT chk_approval_delegations_period CHECK (ends_at > starts_at)
+);
+
+-- 审批委托表索引
+CREATE INDEX idx_approval_delegations_delegator ON approval_delegations(delegator_id, is_active, starts_at, ends_at);
+
+-- 审批委托表触发器
+CREATE TRIGGER update_approval_delegations_updated_at BEFORE UPDATE ON approval_delegations FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE approval_chains IS '审批链定义：按顺序执行的审批步骤，审批人可为组织负责人、角色或指定用户';
+COMMENT ON TABLE approval_requests IS '审批请求：文档的一次审批过程，审批通过后文档自动提交';
+COMMENT ON TABLE approval_tasks IS '审批任务：每个审批人在每个步骤的待办和决定记录';
+COMMENT ON TABLE approval_delegations IS '审批委托：委托期内委托人的审批任务由受托人处理';
+
+-- 提交事务
+COMMIT;
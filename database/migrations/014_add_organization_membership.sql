@@ -0,0 +1,70 @@
+-- ================================================================================================
+-- 组织成员管理迁移脚本
+-- 1. 每个用户最多一个有效的主组织；存在有效成员关系的用户必须有主组织
+-- 2. 移除成员为软删除：is_active 置为 FALSE 并记录 left_at，重新加入时复用原记录
+-- 3. 组织成员变动历史：加入、调整（职位、主组织、负责人）、离开时记录成员关系快照
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 主组织约束
+-- ================================================================================================
+ALTER TABLE user_organizations ALTER COLUMN is_primary SET DEFAULT FALSE;
+
+-- 已离开的成员关系不再是主组织
+UPDATE user_organizations SET is_primary = FALSE WHERE is_primary = TRUE AND is_active = FALSE;
+
+-- 同一用户存在多个主组织时保留最早加入的一个
+UPDATE user_organizations uo SET is_primary = FALSE
+WHERE uo.is_primary = TRUE
+  AND EXISTS (
+      SELECT 1 FROM user_organizations p
+      WHERE p.user_id = uo.user_id AND p.is_active = TRUE AND p.is_primary = TRUE
+        AND (COALESCE(p.joined_at, p.created_at), p.id) < (COALESCE(uo.joined_at, uo.created_at), uo.id)
+  );
+
+-- 没有主组织的用户以最早加入的有效成员关系作为主组织
+UPDATE user_organizations SET is_primary = TRUE
+WHERE id IN (
+    SELECT DISTINCT ON (uo.user_id) uo.id
+    FROM user_organizations uo
+    WHERE uo.is_active = TRUE
+      AND NOT EXISTS (
+          SELECT 1 FROM user_organizations p
+          WHERE p.user_id = uo.user_id AND p.is_active = TRUE AND p.is_primary = TRUE
+      )
+    ORDER BY uo.user_id, COALESCE(uo.joined_at, uo.created_at), uo.id
+);
+
+CREATE UNIQUE INDEX uk_user_organizations_primary ON user_organizations(user_id) WHERE is_primary = TRUE AND is_active = TRUE;
+
+-- ================================================================================================
+-- 2. 组织成员变动历史表 (user_organization_history)
+-- ================================================================================================
+CREATE TABLE user_organization_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,                                 -- 用户ID
+    org_id BIGINT NOT NULL,                                  -- 组织ID
+    action VARCHAR(20) NOT NULL,                             -- 变动类型: join, update, leave
+    position VARCHAR(50),                                    -- 变动后的职位
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,               -- 变动后是否为主组织
+    is_leader BOOLEAN NOT NULL DEFAULT FALSE,                -- 变动后是否为组织负责人
+    reason VARCHAR(500),                                     -- 变动原因
+    operator_id BIGINT,                                      -- 操作人
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user_organization_history_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_organization_history_org FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_organization_history_operator FOREIGN KEY (operator_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT chk_user_organization_history_action CHECK (action IN ('join', 'update', 'leave'))
+);
+
+CREATE INDEX idx_user_organization_history_user ON user_organization_history(user_id, created_at DESC);
+CREATE INDEX idx_user_organization_history_org ON user_organization_history(org_id, created_at DESC);
+
+COMMENT ON TABLE user_organization_history IS '组织成员变动历史表 - 每次成员关系变动后的快照';
+
+-- 提交事务
+COMMIT;
@@ -0,0 +1,129 @@
+-- ================================================================================================
+-- 多公司迁移脚本
+-- 1. 公司表 (companies)：集团下的各法人实体，同一部署为所有公司提供服务
+-- 2. 用户公司关联表 (user_companies)：用户可属于多个公司，登录时进入默认公司，可切换当前公司
+-- 3. 组织、系统配置、权限规则、用户权限和权限配置版本按公司隔离；用户和角色在公司间共享
+-- 4. 已有数据归入默认公司，已有用户均加入默认公司
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 公司表 (companies)
+-- ================================================================================================
+CREATE TABLE companies (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,                              -- 公司名称
+    code VARCHAR(50) NOT NULL,                               -- 公司编码
+    description TEXT,                                        -- 公司描述
+    is_enabled BOOLEAN NOT NULL DEFAULT TRUE,                -- 是否启用，停用的公司不能登录或切换进入
+
+    -- 审计字段
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by BIGINT,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_companies_created_by FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_companies_updated_by FOREIGN KEY (updated_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX uk_companies_code ON companies(code);
+
+CREATE TRIGGER update_companies_updated_at BEFORE UPDATE ON companies FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE companies IS '公司表 - 组织、系统配置和权限配置按公司隔离';
+
+-- 默认公司，ID为1，承接迁移前的全部数据
+INSERT INTO companies (id, name, code, description) VALUES (1, '默认公司', 'DEFAULT', '迁移前已有数据所属的公司');
+SELECT setval(pg_get_serial_sequence('companies', 'id'), 1);
+
+-- ================================================================================================
+-- 2. 用户公司关联表 (user_companies)
+-- ================================================================================================
+CREATE TABLE user_companies (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,                                 -- 用户ID
+    company_id BIGINT NOT NULL,                              -- 公司ID
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,               -- 是否为登录时进入的默认公司
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by BIGINT,
+
+    CONSTRAINT fk_user_companies_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_companies_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_companies_created_by FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT uk_user_companies UNIQUE(user_id, company_id)
+);
+
+-- 每个用户最多一个默认公司
+CREATE UNIQUE INDEX uk_user_companies_default ON user_companies(user_id) WHERE is_default = TRUE;
+CREATE INDEX idx_user_companies_company ON user_companies(company_id);
+
+COMMENT ON TABLE user_companies IS '用户公司关联表 - 用户可访问的公司';
+
+INSERT INTO user_companies (user_id, company_id, is_default)
+SELECT id, 1, TRUE FROM users WHERE deleted_at IS NULL;
+
+-- ================================================================================================
+-- 3. 组织按公司隔离：组织编码在公司内唯一，上下级组织必须属于同一公司
+-- ================================================================================================
+ALTER TABLE organizations ADD COLUMN company_id BIGINT;
+UPDATE organizations SET company_id = 1;
+ALTER TABLE organizations ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE organizations ADD CONSTRAINT fk_organizations_company FOREIGN KEY (company_id) REFERENCES companies(id);
+
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_code_key;
+CREATE UNIQUE INDEX uk_organizations_company_code ON organizations(company_id, code) WHERE deleted_at IS NULL;
+CREATE INDEX idx_organizations_company ON organizations(company_id, parent_id) WHERE deleted_at IS NULL;
+
+-- 成员关系冗余组织所属公司，主组织在每个公司内各有一个
+ALTER TABLE user_organizations ADD COLUMN company_id BIGINT;
+UPDATE user_organizations uo SET company_id = o.company_id FROM organizations o WHERE o.id = uo.org_id;
+ALTER TABLE user_organizations ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE user_organizations ADD CONSTRAINT fk_user_organizations_company FOREIGN KEY (company_id) REFERENCES companies(id);
+
+DROP INDEX uk_user_organizations_primary;
+CREATE UNIQUE INDEX uk_user_organizations_primary ON user_organizations(user_id, company_id) WHERE is_primary = TRUE AND is_active = TRUE;
+
+-- ================================================================================================
+-- 4. 系统配置按公司覆盖：company_id为NULL的配置是全局默认值，公司配置优先
+-- ================================================================================================
+ALTER TABLE system_configs ADD COLUMN company_id BIGINT;
+ALTER TABLE system_configs ADD CONSTRAINT fk_system_configs_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+
+ALTER TABLE system_configs DROP CONSTRAINT IF EXISTS system_configs_config_key_key;
+DROP INDEX IF EXISTS idx_system_configs_key;
+CREATE UNIQUE INDEX uk_system_configs_global_key ON system_configs(config_key) WHERE company_id IS NULL;
+CREATE UNIQUE INDEX uk_system_configs_company_key ON system_configs(company_id, config_key) WHERE company_id IS NOT NULL;
+
+-- ================================================================================================
+-- 5. 权限规则、用户权限和权限配置版本按公司隔离
+-- ================================================================================================
+ALTER TABLE permission_rules ADD COLUMN company_id BIGINT;
+UPDATE permission_rules SET company_id = 1;
+ALTER TABLE permission_rules ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE permission_rules ADD CONSTRAINT fk_permission_rules_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+ALTER TABLE permission_rules DROP CONSTRAINT uk_permission_rules;
+ALTER TABLE permission_rules ADD CONSTRAINT uk_permission_rules UNIQUE(company_id, role_id, doc_type, permission_level);
+DROP INDEX idx_permission_rules_role_doc;
+CREATE INDEX idx_permission_rules_role_doc ON permission_rules(company_id, role_id, doc_type);
+
+ALTER TABLE user_permissions ADD COLUMN company_id BIGINT;
+UPDATE user_permissions SET company_id = 1;
+ALTER TABLE user_permissions ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE user_permissions ADD CONSTRAINT fk_user_permissions_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+ALTER TABLE user_permissions DROP CONSTRAINT uk_user_permissions;
+ALTER TABLE user_permissions ADD CONSTRAINT uk_user_permissions UNIQUE(company_id, user_id, doc_type, doc_name, applicable_for);
+DROP INDEX idx_user_permissions_user_doc;
+CREATE INDEX idx_user_permissions_user_doc ON user_permissions(company_id, user_id, doc_type);
+
+-- 版本快照只包含所属公司的权限规则和用户权限；字段权限级别描述文档结构，在公司间共享
+ALTER TABLE permission_config_versions ADD COLUMN company_id BIGINT;
+UPDATE permission_config_versions SET company_id = 1;
+ALTER TABLE permission_config_versions ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE permission_config_versions ADD CONSTRAINT fk_permission_config_versions_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+CREATE INDEX idx_permission_config_versions_company ON permission_config_versions(company_id, id DESC);
+
+-- 提交事务
+COMMIT;
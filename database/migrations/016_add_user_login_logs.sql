@@ -0,0 +1,25 @@
+-- ================================================================================================
+-- 用户登录记录迁移脚本
+-- 1. 每次登录成功记录一条登录记录，用于按天统计登录次数和登录用户数
+-- 2. 用户删除时登录记录一并删除
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+CREATE TABLE user_login_logs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    ip_address VARCHAR(64),
+    login_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user_login_logs_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE user_login_logs IS '用户登录记录表 - 记录每次成功登录';
+
+CREATE INDEX idx_user_login_logs_login_at ON user_login_logs(login_at);
+CREATE INDEX idx_user_login_logs_user ON user_login_logs(user_id, login_at DESC);
+
+-- 提交事务
+COMMIT;
@@ -0,0 +1,27 @@
+-- ================================================================================================
+-- 用户、角色、组织软删除迁移脚本
+-- 1. 删除时记录 deleted_at 和 deleted_by，保留成员关系、角色分配和审计引用，可在回收站中恢复
+-- 2. 超过保留期的记录由清理任务永久删除，此时才按外键级联删除关联数据
+-- 3. 用户名、邮箱和角色编码在永久删除前保持占用，组织编码只在未删除的组织中唯一
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN deleted_by BIGINT;
+ALTER TABLE users ADD CONSTRAINT fk_users_deleted_by FOREIGN KEY (deleted_by) REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE roles ADD COLUMN deleted_by BIGINT;
+ALTER TABLE roles ADD CONSTRAINT fk_roles_deleted_by FOREIGN KEY (deleted_by) REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_roles_deleted_at ON roles(deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE organizations ADD COLUMN deleted_by BIGINT;
+ALTER TABLE organizations ADD CONSTRAINT fk_organizations_deleted_by FOREIGN KEY (deleted_by) REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_organizations_deleted_at ON organizations(company_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- 提交事务
+COMMIT;
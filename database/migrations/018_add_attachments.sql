@@ -0,0 +1,34 @@
+-- ================================================================================================
+-- 附件迁移脚本
+-- 1. 文档附件和用户头像的文件元数据，文件内容保存在本地文件系统或S3兼容的对象存储中
+-- 2. 用户头像以文档类型 User、文档名称为用户ID登记，缩略图与原图一同保存
+-- 3. 上传人删除时保留附件，上传人置空
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+CREATE TABLE attachments (
+    id BIGSERIAL PRIMARY KEY,
+    doc_type VARCHAR(140) NOT NULL,
+    doc_name VARCHAR(140) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    file_size BIGINT NOT NULL,
+    storage_key VARCHAR(500) NOT NULL,
+    thumbnail_key VARCHAR(500),
+    uploaded_by BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uk_attachments_storage_key UNIQUE (storage_key),
+    CONSTRAINT fk_attachments_uploaded_by FOREIGN KEY (uploaded_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+COMMENT ON TABLE attachments IS '附件表 - 文档附件和用户头像的文件元数据';
+COMMENT ON COLUMN attachments.content_type IS '根据文件内容识别的类型，不使用客户端声明的类型';
+COMMENT ON COLUMN attachments.thumbnail_key IS '缩略图的存储键，仅头像生成缩略图';
+
+CREATE INDEX idx_attachments_doc ON attachments(doc_type, doc_name, created_at);
+
+-- 提交事务
+COMMIT;
@@ -0,0 +1,34 @@
+-- ================================================================================================
+-- 个人资料变更申请迁移脚本
+-- 1. 用户自助修改个人资料时，配置为需要审批的字段（默认为邮箱）先生成变更申请，管理员审批通过后才写入用户表
+-- 2. changes 记录每个字段的修改前后值，审批时修改前的值必须与用户当前值一致，否则需要重新申请
+-- 3. 每个用户同时只能有一个待审批的申请
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- changes 为JSON数组: [{"field":"email","old":"a@example.com","new":"b@example.com"}]
+CREATE TABLE profile_change_requests (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    changes JSONB NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    comment TEXT,
+    reviewed_by BIGINT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_profile_change_requests_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_profile_change_requests_reviewer FOREIGN KEY (reviewed_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT chk_profile_change_requests_status CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled'))
+);
+
+COMMENT ON TABLE profile_change_requests IS '个人资料变更申请表 - 需要审批的个人资料修改';
+COMMENT ON COLUMN profile_change_requests.reviewed_by IS '审批人，用户撤回时为用户本人';
+
+CREATE UNIQUE INDEX uk_profile_change_requests_pending ON profile_change_requests(user_id) WHERE status = 'pending';
+CREATE INDEX idx_profile_change_requests_status ON profile_change_requests(status, created_at);
+
+-- 提交事务
+COMMIT;
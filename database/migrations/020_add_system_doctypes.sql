@@ -0,0 +1,25 @@
+-- ================================================================================================
+-- 系统配置文档类型迁移脚本
+-- 1. 登记各配置类更新接口对应的系统文档类型，使其字段写权限可以通过field_permission_levels配置
+-- 2. 未配置字段权限级别的文档类型不限制字段写入，登记本身不改变现有行为
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+INSERT INTO doc_types (name, label, module, description, is_submittable, has_workflow) VALUES
+('DocType', '文档类型', 'system', '文档类型定义', FALSE, FALSE),
+('DocField', '字段定义', 'system', '文档类型字段定义', FALSE, FALSE),
+('Permission Rule', '权限规则', 'system', '角色权限规则', FALSE, FALSE),
+('User Permission', '用户权限', 'system', '用户记录级权限', FALSE, FALSE),
+('Field Permission Level', '字段权限级别', 'system', '字段权限级别定义', FALSE, FALSE),
+('Document Workflow State', '文档工作流状态', 'system', '文档工作流状态记录', FALSE, FALSE),
+('Permission Template', '权限模板', 'system', '角色权限模板', FALSE, FALSE),
+('SoD Policy', '职责分离策略', 'system', '职责分离策略', FALSE, FALSE),
+('Organization Member', '组织成员', 'system', '组织成员关系', FALSE, FALSE),
+('Company', '公司', 'system', '公司管理', FALSE, FALSE),
+('User Filter', '用户过滤器', 'system', '保存的列表过滤器', FALSE, FALSE)
+ON CONFLICT (name) DO NOTHING;
+
+-- 提交事务
+COMMIT;
@@ -0,0 +1,17 @@
+-- ================================================================================================
+-- 权限规则设置用户权限操作迁移脚本
+-- 权限规则增加set_user_permissions操作，授予后可为其他用户设置该文档类型上的用户权限
+-- 与其他操作一样参与权限检查、权限矩阵导入导出、权限模板和权限配置版本
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+ALTER TABLE permission_rules ADD COLUMN can_set_user_permissions BOOLEAN DEFAULT FALSE;
+
+COMMENT ON COLUMN permission_rules.can_set_user_permissions IS '设置用户权限';
+
+-- ================================================================================================
+-- 提交事务
+-- ================================================================================================
+COMMIT;
@@ -0,0 +1,110 @@
+-- ================================================================================================
+-- 业务数据按公司隔离迁移脚本
+-- 1. 文档数据表、编号序列、审批请求、文档附件、操作日志和权限模板应用记录增加公司字段，已有数据归入默认公司
+-- 2. 文档名称和唯一字段在公司内唯一，编号序列每个公司分别计数，同一文档在每个公司内最多一个进行中的审批请求
+-- 3. 用户头像和没有公司上下文的操作日志不属于任何公司，company_id为NULL
+-- 4. 文档类型、工作流、审批链、审批委托、角色分配、职责分离策略和权限模板定义在公司间共享，不做调整
+-- ================================================================================================
+
+-- 开启事务
+BEGIN;
+
+-- ================================================================================================
+-- 1. 文档数据表：按document_tables登记逐表增加公司字段，名称和唯一字段索引改为公司内唯一
+-- ================================================================================================
+DO $$
+DECLARE
+    doc_table RECORD;
+    name_constraint TEXT;
+    unique_index RECORD;
+BEGIN
+    FOR doc_table IN SELECT table_name FROM document_tables LOOP
+        EXECUTE format('ALTER TABLE %I ADD COLUMN company_id BIGINT', doc_table.table_name);
+        EXECUTE format('UPDATE %I SET company_id = 1', doc_table.table_name);
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN company_id SET NOT NULL', doc_table.table_name);
+        EXECUTE format('ALTER TABLE %I ADD FOREIGN KEY (company_id) REFERENCES companies(id)', doc_table.table_name);
+
+        -- 建表时 name VARCHAR(100) NOT NULL UNIQUE 生成的单列唯一约束
+        SELECT con.conname INTO name_constraint
+        FROM pg_constraint con
+        JOIN pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = con.conkey[1]
+        WHERE con.conrelid = format('%I', doc_table.table_name)::regclass
+          AND con.contype = 'u' AND array_length(con.conkey, 1) = 1 AND att.attname = 'name';
+        IF name_constraint IS NOT NULL THEN
+            EXECUTE format('ALTER TABLE %I DROP CONSTRAINT %I', doc_table.table_name, name_constraint);
+        END IF;
+        EXECUTE format('ALTER TABLE %I ADD UNIQUE (company_id, name)', doc_table.table_name);
+
+        -- 唯一字段索引由应用以 uk_ 前缀创建
+        FOR unique_index IN
+            SELECT idx.relname AS index_name, att.attname AS column_name
+            FROM pg_index i
+            JOIN pg_class idx ON idx.oid = i.indexrelid
+            JOIN pg_attribute att ON att.attrelid = i.indrelid AND att.attnum = i.indkey[0]
+            WHERE i.indrelid = format('%I', doc_table.table_name)::regclass
+              AND i.indisunique AND i.indnatts = 1 AND idx.relname LIKE 'uk\_%'
+        LOOP
+            EXECUTE format('DROP INDEX %I', unique_index.index_name);
+            EXECUTE format('CREATE UNIQUE INDEX %I ON %I (company_id, %I)',
+                unique_index.index_name, doc_table.table_name, unique_index.column_name);
+        END LOOP;
+    END LOOP;
+END $$;
+
+-- ================================================================================================
+-- 2. 编号序列每个公司分别计数
+-- ================================================================================================
+ALTER TABLE naming_series ADD COLUMN company_id BIGINT;
+UPDATE naming_series SET company_id = 1;
+ALTER TABLE naming_series ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE naming_series ADD CONSTRAINT fk_naming_series_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+ALTER TABLE naming_series DROP CONSTRAINT naming_series_pkey;
+ALTER TABLE naming_series ADD PRIMARY KEY (company_id, prefix);
+
+-- ================================================================================================
+-- 3. 审批请求按公司隔离，审批任务通过所属请求确定公司
+-- ================================================================================================
+ALTER TABLE approval_requests ADD COLUMN company_id BIGINT;
+UPDATE approval_requests SET company_id = 1;
+ALTER TABLE approval_requests ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE approval_requests ADD CONSTRAINT fk_approval_requests_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+
+DROP INDEX uk_approval_requests_pending;
+CREATE UNIQUE INDEX uk_approval_requests_pending ON approval_requests(company_id, doc_type, doc_name) WHERE status = 'pending';
+DROP INDEX idx_approval_requests_document;
+CREATE INDEX idx_approval_requests_document ON approval_requests(company_id, doc_type, doc_name, created_at DESC);
+
+-- ================================================================================================
+-- 4. 文档附件按公司隔离，用户头像在公司间共享
+-- ================================================================================================
+ALTER TABLE attachments ADD COLUMN company_id BIGINT;
+UPDATE attachments SET company_id = 1 WHERE doc_type <> 'User';
+ALTER TABLE attachments ADD CONSTRAINT fk_attachments_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+
+DROP INDEX idx_attachments_doc;
+CREATE INDEX idx_attachments_doc ON attachments(company_id, doc_type, doc_name, created_at);
+
+COMMENT ON COLUMN attachments.company_id IS '文档附件所属公司，用户头像为NULL';
+
+-- ================================================================================================
+-- 5. 操作日志记录操作所在公司，没有公司上下文的操作为共享日志
+-- ================================================================================================
+ALTER TABLE operation_logs ADD COLUMN company_id BIGINT;
+UPDATE operation_logs SET company_id = 1;
+ALTER TABLE operation_logs ADD CONSTRAINT fk_operation_logs_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+CREATE INDEX idx_operation_logs_company ON operation_logs(company_id, created_at);
+
+COMMENT ON COLUMN operation_logs.company_id IS '操作所在公司，定时任务等没有公司上下文的操作为NULL，在各公司均可查看';
+
+-- ================================================================================================
+-- 6. 权限模板应用记录按公司隔离，与其写入的权限规则一致
+-- ================================================================================================
+ALTER TABLE permission_template_applications ADD COLUMN company_id BIGINT;
+UPDATE permission_template_applications SET company_id = 1;
+ALTER TABLE permission_template_applications ALTER COLUMN company_id SET NOT NULL;
+ALTER TABLE permission_template_applications ADD CONSTRAINT fk_template_applications_company FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+ALTER TABLE permission_template_applications DROP CONSTRAINT uk_template_applications;
+ALTER TABLE permission_template_applications ADD CONSTRAINT uk_template_applications UNIQUE(company_id, template_id, role_id, doc_type);
+
+-- 提交事务
+COMMIT;